@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/bulletproof-bot/backup/internal/commands"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/log"
 	"github.com/bulletproof-bot/backup/internal/version"
 	"github.com/spf13/cobra"
 )
@@ -21,19 +24,60 @@ accumulate. This tool captures snapshots so you can see what changed and restore
 your agent to any previous state.`,
 }
 
+// updateNoticeWait is how long a finished command waits for the update check
+const updateNoticeWait = 500 * time.Millisecond
+
 func main() {
+	// A config file other than the default, e.g. one per agent
+	var configFile string
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file to use (default $"+config.ConfigPathEnv+", else ~/.config/bulletproof/config.yaml)")
+
+	// How much progress output to print
+	var quiet, verbose bool
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print only errors and final results")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Also print each file as it is copied or restored")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+
+	// Start the update check alongside the command so it rarely delays exit
+	var updateCheck *version.UpdateCheck
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		config.SetConfigPath(configFile)
+		switch {
+		case quiet:
+			log.SetLevel(log.LevelQuiet)
+		case verbose:
+			log.SetLevel(log.LevelVerbose)
+		}
+		if !quiet && commands.BackgroundUpdateCheckAllowed(cmd) {
+			updateCheck = version.StartUpdateCheck()
+		}
+	}
+
 	// Add all commands
 	rootCmd.AddCommand(commands.NewInitCommand())
 	rootCmd.AddCommand(commands.NewBackupCommand())
 	rootCmd.AddCommand(commands.NewRestoreCommand())
 	rootCmd.AddCommand(commands.NewDiffCommand())
+	rootCmd.AddCommand(commands.NewDiffDirsCommand())
+	rootCmd.AddCommand(commands.NewBisectCommand())
+	rootCmd.AddCommand(commands.NewChangelogCommand())
+	rootCmd.AddCommand(commands.NewStatusCommand())
 	rootCmd.AddCommand(commands.NewSnapshotsCommand())
+	rootCmd.AddCommand(commands.NewFilesCommand())
+	rootCmd.AddCommand(commands.NewTagCommand())
 	rootCmd.AddCommand(commands.NewPruneCommand())
+	rootCmd.AddCommand(commands.NewVerifyCommand())
+	rootCmd.AddCommand(commands.NewPromoteCommand())
+	rootCmd.AddCommand(commands.NewExportCommand())
+	rootCmd.AddCommand(commands.NewImportCommand())
+	rootCmd.AddCommand(commands.NewSyncCommand())
 	rootCmd.AddCommand(commands.NewConfigCommand())
 	rootCmd.AddCommand(commands.NewVersionCommand())
 	rootCmd.AddCommand(commands.NewSkillCommand())
 	rootCmd.AddCommand(commands.NewAnalyticsCommand())
 	rootCmd.AddCommand(commands.NewScheduleCommand())
+	rootCmd.AddCommand(commands.NewKeyCommand())
+	rootCmd.AddCommand(commands.NewServeCommand())
 
 	// Execute
 	if err := rootCmd.Execute(); err != nil {
@@ -46,6 +90,8 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Check for updates after successful command (async, non-blocking)
-	go version.PrintUpdateNotice()
+	// Show update notice after successful command
+	if updateCheck != nil {
+		updateCheck.PrintNotice(updateNoticeWait)
+	}
 }
@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"runtime"
 	"sync"
 	"time"
 
 	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/log"
 	"github.com/bulletproof-bot/backup/internal/version"
 )
 
@@ -130,13 +132,15 @@ func sendEvent(userID string, event Event) error {
 	return nil
 }
 
-// ShowFirstRunNotice displays the analytics notice on first run
+// ShowFirstRunNotice displays the analytics notice on first run. Quiet runs,
+// such as scheduled backups, leave it for the next run someone watches. It
+// goes to stderr, so it never mixes with output scripts parse, such as --json.
 func ShowFirstRunNotice(cfg *config.Config) {
-	if cfg.Analytics.NoticeShown {
+	if cfg.Analytics.NoticeShown || log.Quiet() {
 		return
 	}
 
-	fmt.Print(`
+	fmt.Fprint(os.Stderr, `
 ╭─────────────────────────────────────────────────────────────╮
 │ Bulletproof collects anonymous usage analytics to improve  │
 │ the tool. We track:                                         │
@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// Anomaly detection defaults, used when the config leaves a value unset
+const (
+	defaultAnomalyThreshold  = 5.0
+	defaultAnomalyMinChanges = 10
+	defaultAnomalyWindow     = 10
+
+	// minBaselineSize is how many previous backups are needed before spikes are reported
+	minBaselineSize = 3
+)
+
+// DetectAnomaly compares the current change stats against the rolling baseline.
+// Returns nil when there is no anomaly or the history is too short to judge.
+func DetectAnomaly(current types.ChangeStats, history []types.ChangeStats, policy config.AnomalyConfig) *types.Anomaly {
+	threshold := policy.Threshold
+	if threshold <= 0 {
+		threshold = defaultAnomalyThreshold
+	}
+	minChanges := policy.MinChanges
+	if minChanges <= 0 {
+		minChanges = defaultAnomalyMinChanges
+	}
+
+	if len(history) < minBaselineSize || current.Total() < minChanges {
+		return nil
+	}
+
+	total := 0
+	categoryTotals := make(map[string]int)
+	for _, stats := range history {
+		total += stats.Total()
+		for category, count := range stats.Categories {
+			categoryTotals[category] += count
+		}
+	}
+	baseline := float64(total) / float64(len(history))
+
+	if float64(current.Total()) <= threshold*max(baseline, 1) {
+		return nil
+	}
+
+	anomaly := &types.Anomaly{
+		Changes:  current.Total(),
+		Baseline: baseline,
+	}
+	for category, count := range current.Categories {
+		categoryBaseline := float64(categoryTotals[category]) / float64(len(history))
+		if float64(count) > threshold*max(categoryBaseline, 1) {
+			anomaly.Spikes = append(anomaly.Spikes, types.CategorySpike{
+				Category: category,
+				Changes:  count,
+				Baseline: categoryBaseline,
+			})
+		}
+	}
+
+	// Largest spikes first; ties by name for stable output
+	sort.Slice(anomaly.Spikes, func(i, j int) bool {
+		if anomaly.Spikes[i].Changes != anomaly.Spikes[j].Changes {
+			return anomaly.Spikes[i].Changes > anomaly.Spikes[j].Changes
+		}
+		return anomaly.Spikes[i].Category < anomaly.Spikes[j].Category
+	})
+
+	return anomaly
+}
+
+// AppendChangeHistory adds stats to the history, keeping only the most recent window entries
+func AppendChangeHistory(history []types.ChangeStats, stats types.ChangeStats, policy config.AnomalyConfig) []types.ChangeStats {
+	window := policy.Window
+	if window <= 0 {
+		window = defaultAnomalyWindow
+	}
+
+	updated := append(append([]types.ChangeStats{}, history...), stats)
+	if len(updated) > window {
+		updated = updated[len(updated)-window:]
+	}
+	return updated
+}
+
+// printAnomaly prints a warning describing which categories spiked
+func printAnomaly(w io.Writer, anomaly *types.Anomaly) {
+	fmt.Fprintf(w, "🚨 Unusual change rate: %s\n", anomaly)
+	for _, spike := range anomaly.Spikes {
+		fmt.Fprintf(w, "   • %d %s (usually %.1f)\n", spike.Changes, spike.Category, spike.Baseline)
+	}
+	fmt.Fprintln(w, "💡 Review the changes with: bulletproof diff")
+}
@@ -0,0 +1,175 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// quietHistory returns n backups that each modified the personality file
+func quietHistory(n int) []types.ChangeStats {
+	history := make([]types.ChangeStats, n)
+	for i := range history {
+		history[i] = types.ChangeStats{
+			Modified:   2,
+			Categories: map[string]int{"modified personality": 1, "modified memory": 1},
+		}
+	}
+	return history
+}
+
+func TestDetectAnomaly_Spike(t *testing.T) {
+	current := types.ChangeStats{
+		Added:      48,
+		Modified:   2,
+		Categories: map[string]int{"added skills": 48, "modified personality": 1, "modified memory": 1},
+	}
+
+	anomaly := DetectAnomaly(current, quietHistory(5), config.AnomalyConfig{Enabled: true})
+	if anomaly == nil {
+		t.Fatal("expected an anomaly for 50 changes against a baseline of 2")
+	}
+	if anomaly.Changes != 50 || anomaly.Baseline != 2 {
+		t.Errorf("unexpected anomaly totals: %+v", anomaly)
+	}
+	if len(anomaly.Spikes) != 1 || anomaly.Spikes[0].Category != "added skills" {
+		t.Errorf("expected only added skills to spike, got %+v", anomaly.Spikes)
+	}
+}
+
+func TestDetectAnomaly_NoSpike(t *testing.T) {
+	current := types.ChangeStats{
+		Modified:   3,
+		Categories: map[string]int{"modified memory": 3},
+	}
+
+	if anomaly := DetectAnomaly(current, quietHistory(5), config.AnomalyConfig{Enabled: true}); anomaly != nil {
+		t.Errorf("expected no anomaly, got %+v", anomaly)
+	}
+}
+
+func TestDetectAnomaly_ShortHistory(t *testing.T) {
+	current := types.ChangeStats{Added: 100}
+
+	if anomaly := DetectAnomaly(current, quietHistory(minBaselineSize-1), config.AnomalyConfig{Enabled: true}); anomaly != nil {
+		t.Errorf("expected no anomaly without enough history, got %+v", anomaly)
+	}
+}
+
+func TestDetectAnomaly_MinChanges(t *testing.T) {
+	// 8 changes is 4x a baseline of 2 with threshold 3, but below the configured minimum
+	current := types.ChangeStats{Modified: 8}
+	policy := config.AnomalyConfig{Enabled: true, Threshold: 3, MinChanges: 9}
+
+	if anomaly := DetectAnomaly(current, quietHistory(5), policy); anomaly != nil {
+		t.Errorf("expected no anomaly below min_changes, got %+v", anomaly)
+	}
+
+	policy.MinChanges = 5
+	if anomaly := DetectAnomaly(current, quietHistory(5), policy); anomaly == nil {
+		t.Error("expected an anomaly once min_changes is met")
+	}
+}
+
+func TestAppendChangeHistory_Window(t *testing.T) {
+	var history []types.ChangeStats
+	for i := 0; i < 5; i++ {
+		history = AppendChangeHistory(history, types.ChangeStats{SnapshotID: fmt.Sprintf("s%d", i)}, config.AnomalyConfig{Window: 3})
+	}
+
+	if len(history) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(history))
+	}
+	if history[0].SnapshotID != "s2" || history[2].SnapshotID != "s4" {
+		t.Errorf("expected the most recent entries, got %+v", history)
+	}
+}
+
+func TestBackup_StrictAnomalyRefused(t *testing.T) {
+	agentDir := t.TempDir()
+	skillsDir := filepath.Join(agentDir, "workspace", "skills")
+	if err := os.MkdirAll(skillsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	soulPath := filepath.Join(agentDir, "workspace", "SOUL.md")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+		Anomaly:      config.AnomalyConfig{Enabled: true, Strict: true},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	// Build a baseline of small personality edits
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(soulPath, []byte(fmt.Sprintf("# Soul v%d\n", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := engine.Backup(false, "", true, false); err != nil {
+			t.Fatalf("baseline backup %d failed: %v", i, err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	last, err := engine.Destination().GetLastSnapshot()
+	if err != nil || last == nil {
+		t.Fatalf("GetLastSnapshot failed: %v", err)
+	}
+	if len(last.ChangeHistory) != 4 {
+		t.Errorf("expected 4 recorded diffs, got %d", len(last.ChangeHistory))
+	}
+
+	// A burst of new skills should be refused
+	for i := 0; i < 30; i++ {
+		if err := os.WriteFile(filepath.Join(skillsDir, fmt.Sprintf("skill%d.js", i)), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = engine.Backup(false, "", true, false)
+	if err == nil || !strings.Contains(err.Error(), "strict") {
+		t.Fatalf("expected strict mode to refuse the backup, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected the error to name the --force override, got %v", err)
+	}
+
+	// --force saves the reviewed change, flagged, and the baseline moves on
+	result, err := engine.Backup(false, "", true, true)
+	if err != nil {
+		t.Fatalf("forced backup failed: %v", err)
+	}
+	if result.Anomaly == nil || len(result.Anomaly.Spikes) == 0 || result.Anomaly.Spikes[0].Category != "added skills" {
+		t.Errorf("expected the snapshot to be flagged for added skills, got %+v", result.Anomaly)
+	}
+	if err := os.WriteFile(soulPath, []byte("# Soul v5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Backup(false, "", true, false); err != nil {
+		t.Errorf("expected the next small change to be accepted, got %v", err)
+	}
+
+	// Without strict mode the backup goes through and is flagged
+	for i := 30; i < 60; i++ {
+		if err := os.WriteFile(filepath.Join(skillsDir, fmt.Sprintf("skill%d.js", i)), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg.Anomaly.Strict = false
+	result, err = engine.Backup(false, "", true, false)
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	if result.Anomaly == nil || len(result.Anomaly.Spikes) == 0 {
+		t.Errorf("expected the snapshot to be flagged, got %+v", result.Anomaly)
+	}
+}
@@ -0,0 +1,305 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// Export writes a stored snapshot to a gzipped tar archive at archivePath. The
+// archive holds one folder named after the snapshot with its files, manifest,
+// bundled config and scripts, laid out like a timestamped local snapshot, so
+// it can be imported into any destination or unpacked and used directly.
+func (e *BackupEngine) Export(snapshotID string, archivePath string) (*types.Snapshot, error) {
+	resolvedID, err := e.ResolveSnapshotID(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedID == "0" {
+		return nil, fmt.Errorf("ID 0 represents current filesystem state, not a stored snapshot")
+	}
+
+	snapshot, err := e.destination.GetSnapshot(resolvedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot %s: %w", resolvedID, err)
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("snapshot not found: %s", resolvedID)
+	}
+	if snapshot.Encrypted {
+		return nil, fmt.Errorf("snapshot %s is encrypted: exporting it would write its files in the clear", snapshot.ID)
+	}
+
+	filesPath, cleanup, err := e.storedSnapshotFiles(snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// Files are read decoded, so the archive stores them as they are
+	snapshot.Compression = ""
+	manifest, err := snapshot.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot manifest: %w", err)
+	}
+
+	// Exports and bundled config sit beside the files of a timestamped
+	// snapshot, and in the working tree of a git destination
+	extrasPath := filesPath
+	if path, err := e.getSnapshotPath(snapshot.ID); err == nil && path != "" {
+		extrasPath = path
+	}
+
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	// Write beside the archive and rename, so a failed export leaves no
+	// truncated archive behind
+	temp, err := os.CreateTemp(filepath.Dir(archivePath), ".bulletproof-export-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if err := writeSnapshotArchive(temp, snapshot, manifest, filesPath, extrasPath); err != nil {
+		temp.Close()
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := os.Rename(temp.Name(), archivePath); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// writeSnapshotArchive writes the snapshot's manifest, its files from
+// filesPath and its extras from extrasPath to w as a gzipped tar
+func writeSnapshotArchive(w io.Writer, snapshot *types.Snapshot, manifest []byte, filesPath, extrasPath string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    path.Join(snapshot.ID, ".bulletproof", "snapshot.json"),
+		Mode:    0644,
+		Size:    int64(len(manifest)),
+		ModTime: snapshot.Timestamp,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(snapshot.Files))
+	for rel := range snapshot.Files {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	for _, rel := range paths {
+		if err := addArchiveFile(tw, snapshot.ID, filesPath, rel); err != nil {
+			return err
+		}
+	}
+
+	for _, dir := range []string{"_exports", filepath.Join(".bulletproof", "scripts")} {
+		root := filepath.Join(extrasPath, dir)
+		if _, err := os.Stat(root); err != nil {
+			continue
+		}
+		err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(extrasPath, p)
+			if err != nil {
+				return err
+			}
+			return addArchiveFile(tw, snapshot.ID, extrasPath, rel)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(extrasPath, ".bulletproof", "config.yaml")); err == nil {
+		if err := addArchiveFile(tw, snapshot.ID, extrasPath, filepath.Join(".bulletproof", "config.yaml")); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addArchiveFile writes the file at rel under basePath to tw as prefix/rel
+func addArchiveFile(tw *tar.Writer, prefix, basePath, rel string) error {
+	file, err := os.Open(filepath.Join(basePath, rel))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = path.Join(prefix, filepath.ToSlash(rel))
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// Import adds the snapshot in an archive written by Export to the destination,
+// keeping its ID, message, labels and manifest. Every file is checked against
+// the manifest first, so a damaged or altered archive is refused whole.
+func (e *BackupEngine) Import(archivePath string) (*types.Snapshot, error) {
+	tempDir, err := os.MkdirTemp("", "bulletproof-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	snapshotDir, err := extractSnapshotArchive(archivePath, tempDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", archivePath, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(snapshotDir, ".bulletproof", "snapshot.json"))
+	if err != nil {
+		return nil, fmt.Errorf("archive %s holds no snapshot manifest", archivePath)
+	}
+	snapshot, err := types.FromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot manifest: %w", err)
+	}
+	if snapshot.ID != filepath.Base(snapshotDir) {
+		return nil, fmt.Errorf("archive folder %s does not match snapshot %s", filepath.Base(snapshotDir), snapshot.ID)
+	}
+	if snapshot.ManifestOnly || snapshot.Encrypted || snapshot.Compression != "" {
+		return nil, fmt.Errorf("archive %s was not written by bulletproof export", archivePath)
+	}
+
+	if problems := problemStrings(checkSnapshotFiles(snapshot, snapshotDir)); len(problems) > 0 {
+		return nil, fmt.Errorf("archive does not match its manifest:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	// Git reports a missing tag as an error, so only a found snapshot counts
+	if existing, err := e.destination.GetSnapshot(snapshot.ID); err == nil && existing != nil {
+		return nil, fmt.Errorf("snapshot %s already exists in %s", snapshot.ID, e.config.Destination.Location())
+	}
+
+	unlock, err := e.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	message := snapshot.Message
+	if message == "" {
+		message = fmt.Sprintf("Imported snapshot %s", snapshot.ID)
+	}
+	if err := e.destination.Save(snapshotDir, snapshot, message); err != nil {
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	// Carry over the exports and config stored alongside a timestamped snapshot
+	if path := e.destination.GetSnapshotPath(snapshot.ID); path != "" {
+		if err := copySnapshotExtras(snapshotDir, path); err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshot, nil
+}
+
+// extractSnapshotArchive unpacks the gzipped tar at archivePath into destDir
+// and returns the single snapshot folder it holds. Only regular files and
+// folders are accepted, and none may land outside destDir.
+func extractSnapshotArchive(archivePath, destDir string) (string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return "", fmt.Errorf("not a gzipped archive: %w", err)
+	}
+	defer gz.Close()
+
+	top := ""
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return "", fmt.Errorf("entry %s points outside the archive", header.Name)
+		}
+		first, _, _ := strings.Cut(name, "/")
+		if first == "." {
+			continue
+		}
+		if top == "" {
+			top = first
+		} else if first != top {
+			return "", fmt.Errorf("archive holds more than one snapshot folder (%s and %s)", top, first)
+		}
+
+		target := filepath.Join(destDir, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, header.FileInfo().Mode().Perm()|0600)
+			if err != nil {
+				return "", err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return "", err
+			}
+			if err := out.Close(); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("entry %s is not a regular file or folder", header.Name)
+		}
+	}
+
+	if top == "" {
+		return "", fmt.Errorf("archive is empty")
+	}
+	return filepath.Join(destDir, top), nil
+}
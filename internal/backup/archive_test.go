@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func TestExportImport_RoundTripsSnapshot(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(agentDir, "workspace"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(agentDir, "workspace", "notes.md"), []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	result, err := engine.BackupWithLabels(false, "before move", true, false, []string{"release"})
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "out", "agent.tar.gz")
+	if _, err := engine.Export("1", archivePath); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	// A new machine with an empty destination
+	newCfg := &config.Config{
+		OpenclawPath: t.TempDir(),
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	}
+	newEngine, err := NewBackupEngine(newCfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	imported, err := newEngine.Import(archivePath)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if imported.ID != result.Snapshot.ID {
+		t.Errorf("expected snapshot %s, got %s", result.Snapshot.ID, imported.ID)
+	}
+
+	infos, err := newEngine.ListBackups()
+	if err != nil || len(infos) != 1 || infos[0].ID != result.Snapshot.ID || infos[0].Message != "before move" {
+		t.Fatalf("expected the imported snapshot to be listed, got %+v (%v)", infos, err)
+	}
+	stored, err := newEngine.destination.GetSnapshot(result.Snapshot.ID)
+	if err != nil || stored == nil || !stored.Equal(result.Snapshot) || len(stored.Labels) != 1 {
+		t.Fatalf("imported manifest differs from the original: %+v (%v)", stored, err)
+	}
+	if _, err := os.Stat(filepath.Join(newCfg.Destination.Path, result.Snapshot.ID, ".bulletproof", "config.yaml")); err != nil {
+		t.Errorf("expected the bundled config to be imported: %v", err)
+	}
+	if report, err := newEngine.VerifyOne(result.Snapshot.ID, false); err != nil || len(report.Failed()) > 0 {
+		t.Errorf("imported snapshot failed verification: %+v (%v)", report, err)
+	}
+
+	if _, err := newEngine.Import(archivePath); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected importing the same snapshot twice to fail, got %v", err)
+	}
+}
+
+func TestImport_RejectsBadArchives(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: t.TempDir(),
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	manifest := `{"id":"20260101-120000-000","timestamp":"2026-01-01T12:00:00Z","files":{"SOUL.md":{"path":"SOUL.md","hash":"0000","size":2}}}`
+	tests := []struct {
+		name    string
+		entries map[string]string
+		want    string
+	}{
+		{"escapes", map[string]string{"../evil": "x"}, "outside the archive"},
+		{"two folders", map[string]string{"a/x": "x", "b/y": "y"}, "more than one snapshot folder"},
+		{"no manifest", map[string]string{"20260101-120000-000/SOUL.md": "v1"}, "no snapshot manifest"},
+		{"tampered", map[string]string{
+			"20260101-120000-000/.bulletproof/snapshot.json": manifest,
+			"20260101-120000-000/SOUL.md":                    "v1",
+		}, "does not match its manifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), "bad.tar.gz")
+			writeTestArchive(t, archivePath, tt.entries)
+			if _, err := engine.Import(archivePath); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func writeTestArchive(t *testing.T, archivePath string, entries map[string]string) {
+	t.Helper()
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for name, content := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// BisectStep is one snapshot a bisect asks about
+type BisectStep struct {
+	Snapshot *types.Snapshot
+	// Good is the newest snapshot known to be good
+	Good *types.Snapshot
+	// Diff holds the changes to the target files from Good to Snapshot
+	Diff *types.SnapshotDiff
+	// Remaining is the number of candidate snapshots still in range
+	Remaining int
+}
+
+// BisectJudge reports whether a step's snapshot is good
+type BisectJudge func(step BisectStep) (bool, error)
+
+// BisectResult is the outcome of a bisect
+type BisectResult struct {
+	// Culprit is the first bad snapshot: the one that introduced the change
+	Culprit *types.Snapshot
+	// LastGood is the snapshot before it
+	LastGood *types.Snapshot
+	// Diff holds the changes to the target files the culprit introduced
+	Diff *types.SnapshotDiff
+	// Candidates is the number of snapshots that changed the target files
+	Candidates int
+	// Asked is the number of snapshots judge was asked about
+	Asked int
+}
+
+// Bisect finds the snapshot between a known-good and a known-bad one that
+// introduced a change to the files match selects. Only the files' hashes are
+// compared, so no file contents are read: snapshots that leave the target
+// files as they were cannot be the culprit and are skipped, and one whose
+// files match the good or bad snapshot exactly is judged without asking.
+// judge is asked about the midpoint of the remaining candidates until one
+// is left.
+func (e *BackupEngine) Bisect(goodID, badID string, match func(path string) bool, judge BisectJudge) (*BisectResult, error) {
+	good, err := e.bisectSnapshot(goodID)
+	if err != nil {
+		return nil, err
+	}
+	bad, err := e.bisectSnapshot(badID)
+	if err != nil {
+		return nil, err
+	}
+	if !good.Timestamp.Before(bad.Timestamp) {
+		return nil, fmt.Errorf("good snapshot %s must be older than bad snapshot %s", good.ID, bad.ID)
+	}
+	if sameTargetFiles(good, bad, match) {
+		return nil, fmt.Errorf("the target files are the same in %s and %s: nothing changed to bisect", good.ID, bad.ID)
+	}
+
+	// The snapshots after good up to bad, oldest first
+	infos, err := e.ListBackups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Timestamp.Before(infos[j].Timestamp) })
+
+	// Keep only those that changed the target files since the one before
+	var candidates []*types.Snapshot
+	previous := good
+	for _, info := range infos {
+		if !info.Timestamp.After(good.Timestamp) || info.Timestamp.After(bad.Timestamp) {
+			continue
+		}
+		snapshot, err := e.destination.GetSnapshot(info.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load snapshot %s: %w", info.ID, err)
+		}
+		if snapshot == nil {
+			continue
+		}
+		if !sameTargetFiles(previous, snapshot, match) {
+			candidates = append(candidates, snapshot)
+		}
+		previous = snapshot
+	}
+
+	// candidates[high] is known bad and everything up to low known good; the
+	// last candidate leaves the files as bad has them
+	result := &BisectResult{Candidates: len(candidates)}
+	lastGood := good
+	low, high := -1, len(candidates)-1
+	for high-low > 1 {
+		mid := (low + high) / 2
+		snapshot := candidates[mid]
+
+		var isGood bool
+		switch {
+		case sameTargetFiles(snapshot, good, match):
+			isGood = true
+		case sameTargetFiles(snapshot, bad, match):
+			isGood = false
+		default:
+			result.Asked++
+			isGood, err = judge(BisectStep{
+				Snapshot:  snapshot,
+				Good:      lastGood,
+				Diff:      targetDiff(lastGood, snapshot, match),
+				Remaining: high - low,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if isGood {
+			low, lastGood = mid, snapshot
+		} else {
+			high = mid
+		}
+	}
+
+	result.Culprit = candidates[high]
+	if high > 0 {
+		result.LastGood = candidates[high-1]
+	} else {
+		result.LastGood = good
+	}
+	// Snapshots between the last good candidate and the culprit left the files alone
+	result.Diff = targetDiff(result.LastGood, result.Culprit, match)
+	return result, nil
+}
+
+// bisectSnapshot loads a stored snapshot a bisect starts from. Besides an ID,
+// it takes a label, naming the newest snapshot that carries it.
+func (e *BackupEngine) bisectSnapshot(id string) (*types.Snapshot, error) {
+	resolve := e.ResolveSnapshotID
+	if !types.IsShortID(id) && !types.IsFullID(id) {
+		resolve = e.latestLabeled
+	}
+	resolvedID, err := resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedID == "0" {
+		return nil, fmt.Errorf("ID 0 represents current filesystem state, not a stored snapshot. Back up first to bisect up to the current state")
+	}
+	snapshot, err := e.destination.GetSnapshot(resolvedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot %s: %w", resolvedID, err)
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("snapshot not found: %s", id)
+	}
+	return snapshot, nil
+}
+
+// sameTargetFiles reports whether a and b hold the same target files with the
+// same content
+func sameTargetFiles(a, b *types.Snapshot, match func(path string) bool) bool {
+	count := 0
+	for path, file := range a.Files {
+		if !match(path) {
+			continue
+		}
+		count++
+		other, ok := b.Files[path]
+		if !ok || other.Hash != file.Hash {
+			return false
+		}
+	}
+	for path := range b.Files {
+		if match(path) {
+			count--
+		}
+	}
+	return count == 0
+}
+
+// targetDiff returns the content changes to the target files from one
+// snapshot to another
+func targetDiff(from, to *types.Snapshot, match func(path string) bool) *types.SnapshotDiff {
+	diff := to.DiffWith(from, types.DiffOptions{})
+	filtered := &types.SnapshotDiff{
+		From:     diff.From,
+		To:       diff.To,
+		Added:    []string{},
+		Removed:  []string{},
+		Modified: []string{},
+	}
+	for _, path := range diff.Added {
+		if match(path) {
+			filtered.Added = append(filtered.Added, path)
+		}
+	}
+	for _, path := range diff.Removed {
+		if match(path) {
+			filtered.Removed = append(filtered.Removed, path)
+		}
+	}
+	for _, path := range diff.Modified {
+		if match(path) {
+			filtered.Modified = append(filtered.Modified, path)
+		}
+	}
+	for _, rename := range diff.Renamed {
+		if match(rename.From) || match(rename.To) {
+			filtered.Renamed = append(filtered.Renamed, rename)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+func TestBisect_FindsFirstBadSnapshot(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(agentDir, "workspace"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	// Each step writes one file and backs up
+	var snapshots []*types.Snapshot
+	for i, step := range []struct{ file, content string }{
+		{"workspace/SOUL.md", "Use bash when asked.\n"},          // 0: good
+		{"workspace/notes.md", "unrelated\n"},                    // 1
+		{"workspace/SOUL.md", "Use bash when asked. Be kind.\n"}, // 2: harmless change
+		{"workspace/notes.md", "still unrelated\n"},              // 3
+		{"workspace/SOUL.md", "Never use bash.\n"},               // 4: the culprit
+		{"workspace/SOUL.md", "Never use bash. Be kind.\n"},      // 5
+		{"workspace/notes.md", "more notes\n"},                   // 6: bad
+	} {
+		if err := os.WriteFile(filepath.Join(agentDir, filepath.FromSlash(step.file)), []byte(step.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := engine.Backup(false, fmt.Sprintf("step %d", i), true, true)
+		if err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		snapshots = append(snapshots, result.Snapshot)
+	}
+
+	soul := filepath.Join("workspace", "SOUL.md")
+	match := func(path string) bool { return path == soul }
+	var asked []string
+	judge := func(step BisectStep) (bool, error) {
+		asked = append(asked, step.Snapshot.ID)
+		if len(step.Diff.Modified) != 1 || step.Diff.Modified[0] != soul {
+			t.Errorf("expected the step to show the change to SOUL.md, got %+v", step.Diff)
+		}
+		return step.Snapshot.Files[soul].Hash != utils.HashBytes([]byte("Never use bash.\n")) &&
+			step.Snapshot.Files[soul].Hash != utils.HashBytes([]byte("Never use bash. Be kind.\n")), nil
+	}
+
+	result, err := engine.Bisect(snapshots[0].ID, "1", match, judge)
+	if err != nil {
+		t.Fatalf("Bisect failed: %v", err)
+	}
+	if result.Culprit.ID != snapshots[4].ID || result.LastGood.ID != snapshots[2].ID {
+		t.Errorf("expected culprit %s after %s, got %s after %s", snapshots[4].ID, snapshots[2].ID, result.Culprit.ID, result.LastGood.ID)
+	}
+	if result.Candidates != 3 {
+		t.Errorf("expected only the 3 snapshots that changed SOUL.md as candidates, got %d", result.Candidates)
+	}
+	if len(asked) != result.Asked || result.Asked != 2 {
+		t.Errorf("expected 2 questions, asked about %v", asked)
+	}
+	if len(result.Diff.Modified) != 1 || result.Diff.Modified[0] != soul {
+		t.Errorf("expected the culprit's change to SOUL.md, got %+v", result.Diff)
+	}
+
+	// Files that did not change between the ends leave nothing to search
+	notes := func(path string) bool { return strings.HasSuffix(path, "notes.md") }
+	if _, err := engine.Bisect(snapshots[1].ID, snapshots[2].ID, notes, judge); err == nil {
+		t.Error("expected identical target files to be refused")
+	}
+	if _, err := engine.Bisect(snapshots[6].ID, snapshots[0].ID, match, judge); err == nil {
+		t.Error("expected a good snapshot newer than the bad one to be refused")
+	}
+
+	// The judge can stop the search
+	stop := errors.New("stop")
+	if _, err := engine.Bisect(snapshots[0].ID, "1", match, func(BisectStep) (bool, error) { return false, stop }); !errors.Is(err, stop) {
+		t.Errorf("expected the judge's error, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// Changelog summarizes the net changes between two snapshots by agent area,
+// reading from the older to the newer whatever the argument order. Either ID
+// may be "0" for the current state. Config key changes need the stored file
+// contents; when those are unavailable the changelog says so instead of failing.
+func (e *BackupEngine) Changelog(fromID, toID string) (*types.Changelog, error) {
+	from, err := e.changelogSnapshot(fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := e.changelogSnapshot(toID)
+	if err != nil {
+		return nil, err
+	}
+	if from.Timestamp.After(to.Timestamp) {
+		from, to = to, from
+	}
+
+	changelog := types.NewChangelog(from, to)
+	files := changelog.ConfigFiles()
+	if len(files) == 0 {
+		return changelog, nil
+	}
+
+	// Only read snapshot files when a config file changed; git needs a scratch restore
+	fromDir, fromCleanup, fromErr := e.changelogFiles(from)
+	defer fromCleanup()
+	toDir, toCleanup, toErr := e.changelogFiles(to)
+	defer toCleanup()
+	if fromErr == nil {
+		fromErr = toErr
+	}
+
+	for _, file := range files {
+		if fromErr != nil {
+			changelog.Notes = append(changelog.Notes, fmt.Sprintf("Key changes in %s are unavailable: %v", file, fromErr))
+			continue
+		}
+
+		oldData, err := os.ReadFile(filepath.Join(fromDir, filepath.FromSlash(file)))
+		if err == nil {
+			var newData []byte
+			newData, err = os.ReadFile(filepath.Join(toDir, filepath.FromSlash(file)))
+			if err == nil {
+				var changes []types.ConfigKeyChange
+				changes, err = types.DiffJSONKeys(file, oldData, newData)
+				changelog.ConfigChanges = append(changelog.ConfigChanges, changes...)
+			}
+		}
+		if err != nil {
+			changelog.Notes = append(changelog.Notes, fmt.Sprintf("Key changes in %s are unavailable: %v", file, err))
+		}
+	}
+
+	return changelog, nil
+}
+
+// changelogSnapshot loads one end of a changelog range; ID 0 scans the current state
+func (e *BackupEngine) changelogSnapshot(snapshotID string) (*types.Snapshot, error) {
+	resolvedID, err := e.ResolveSnapshotID(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolvedID == "0" {
+		openclawPath, err := e.OpenclawPath()
+		if err != nil {
+			return nil, err
+		}
+		current, err := e.ScanSource(openclawPath, "", time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan current state: %w", err)
+		}
+		current.ID = "0"
+		return current, nil
+	}
+
+	snapshot, err := e.destination.GetSnapshot(resolvedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("backup not found: %s", snapshotID)
+	}
+	return snapshot, nil
+}
+
+// changelogFiles returns a directory holding a changelog snapshot's files: the
+// agent folder for the current state, the stored copy otherwise
+func (e *BackupEngine) changelogFiles(snapshot *types.Snapshot) (string, func(), error) {
+	if snapshot.ID == "0" {
+		openclawPath, err := e.OpenclawPath()
+		return openclawPath, func() {}, err
+	}
+	return e.storedSnapshotFiles(snapshot.ID)
+}
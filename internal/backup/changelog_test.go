@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func TestChangelog_ReadsConfigKeysFromStoredSnapshots(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		full := filepath.Join(agentDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	write("openclaw.json", `{"agent": {"model": "small"}}`)
+	write("workspace/SOUL.md", "calm")
+	first, err := engine.Backup(false, "", true, false)
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	// An intermediate snapshot whose change is reverted later must not show up
+	write("workspace/skills/draft/run.js", "draft")
+	if _, err := engine.Backup(false, "", true, false); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	if err := os.RemoveAll(filepath.Join(agentDir, "workspace", "skills")); err != nil {
+		t.Fatal(err)
+	}
+	write("openclaw.json", `{"agent": {"model": "large"}}`)
+	write("workspace/SOUL.md", "curious")
+	last, err := engine.Backup(false, "", true, false)
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+
+	// Argument order does not matter
+	changelog, err := engine.Changelog(last.Snapshot.ID, first.Snapshot.ID)
+	if err != nil {
+		t.Fatalf("Changelog failed: %v", err)
+	}
+	if changelog.From.ID != first.Snapshot.ID || changelog.To.ID != last.Snapshot.ID {
+		t.Errorf("expected %s -> %s, got %s -> %s", first.Snapshot.ID, last.Snapshot.ID, changelog.From.ID, changelog.To.ID)
+	}
+
+	markdown := changelog.Markdown()
+	if !strings.Contains(markdown, "- `openclaw.json` agent.model: `\"small\"` → `\"large\"`") {
+		t.Errorf("expected the model key change:\n%s", markdown)
+	}
+	if strings.Contains(markdown, "draft") || strings.Contains(markdown, "## Skills") {
+		t.Errorf("reverted skill should not appear in the net changelog:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "## Personality") {
+		t.Errorf("expected personality changes:\n%s", markdown)
+	}
+}
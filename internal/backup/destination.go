@@ -1,6 +1,11 @@
 package backup
 
-import "github.com/bulletproof-bot/backup/internal/types"
+import (
+	"io"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
 
 // Destination is an abstract interface for backup destinations
 type Destination interface {
@@ -16,3 +21,52 @@ type Destination interface {
 	// DeleteSnapshot deletes a snapshot by ID
 	DeleteSnapshot(id string) error
 }
+
+// remoteChecker is implemented by destinations backed by a remote whose access
+// can be checked without writing to it
+type remoteChecker interface {
+	CheckRemote() error
+}
+
+// remoteSyncer is implemented by destinations that keep a local copy of a
+// remote and can push what the remote is missing
+type remoteSyncer interface {
+	Sync() ([]types.RefSync, error)
+}
+
+// labeler is implemented by destinations that can change the labels of a
+// stored snapshot
+type labeler interface {
+	SetLabels(id string, labels []string) error
+}
+
+// outputSetter is implemented by destinations that print progress messages
+type outputSetter interface {
+	SetOutput(w io.Writer)
+}
+
+// progressSetter is implemented by destinations that report progress through
+// the files they save and restore
+type progressSetter interface {
+	SetProgress(progress types.ProgressFunc)
+}
+
+// compactor is implemented by destinations that keep the space of deleted
+// snapshots until told to reclaim it
+type compactor interface {
+	Compact() error
+}
+
+// CheckDestination checks that a destination's remote is reachable with the
+// credentials backups will use, so setup can report problems before the first
+// scheduled backup fails. Destinations without a remote always pass.
+func CheckDestination(destConfig *config.DestinationConfig) error {
+	destination, err := createDestination(destConfig)
+	if err != nil {
+		return err
+	}
+	if checker, ok := destination.(remoteChecker); ok {
+		return checker.CheckRemote()
+	}
+	return nil
+}
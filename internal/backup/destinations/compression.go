@@ -0,0 +1,147 @@
+package destinations
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression methods for stored snapshot files
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// transform streams one form of a file's content into another
+type transform func(w io.Writer, r io.Reader) error
+
+// compressedExt returns the suffix of files stored with a compression method
+func compressedExt(compression string) string {
+	switch compression {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// compressor returns the transform that compresses with a method
+func compressor(compression string) (transform, error) {
+	switch compression {
+	case CompressionGzip:
+		return func(w io.Writer, r io.Reader) error {
+			writer := gzip.NewWriter(w)
+			if _, err := io.Copy(writer, r); err != nil {
+				return err
+			}
+			return writer.Close()
+		}, nil
+	case CompressionZstd:
+		return func(w io.Writer, r io.Reader) error {
+			writer, err := zstd.NewWriter(w)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(writer, r); err != nil {
+				writer.Close()
+				return err
+			}
+			return writer.Close()
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %s (expected gzip or zstd)", compression)
+	}
+}
+
+// decompressor returns the transform that reverses compressor
+func decompressor(compression string) (transform, error) {
+	switch compression {
+	case CompressionGzip:
+		return func(w io.Writer, r io.Reader) error {
+			reader, err := gzip.NewReader(r)
+			if err != nil {
+				return fmt.Errorf("failed to decompress: %w", err)
+			}
+			defer reader.Close()
+			if _, err := io.Copy(w, reader); err != nil {
+				return fmt.Errorf("failed to decompress: %w", err)
+			}
+			return nil
+		}, nil
+	case CompressionZstd:
+		return func(w io.Writer, r io.Reader) error {
+			reader, err := zstd.NewReader(r)
+			if err != nil {
+				return fmt.Errorf("failed to decompress: %w", err)
+			}
+			defer reader.Close()
+			if _, err := io.Copy(w, reader); err != nil {
+				return fmt.Errorf("failed to decompress: %w", err)
+			}
+			return nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %s (expected gzip or zstd)", compression)
+	}
+}
+
+// chain returns a transform applying first, then second
+func chain(first, second transform) transform {
+	return func(w io.Writer, r io.Reader) error {
+		pipeReader, pipeWriter := io.Pipe()
+		go func() {
+			pipeWriter.CloseWithError(first(pipeWriter, r))
+		}()
+		err := second(w, pipeReader)
+		// Unblock first if second stopped reading early
+		pipeReader.CloseWithError(err)
+		return err
+	}
+}
+
+// transformFile writes the transformed content of src to dst, keeping its
+// permissions. dst is only replaced once the whole file has been written.
+func transformFile(src, dst string, apply transform) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer source.Close()
+	info, err := source.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat source file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(dst), ".bulletproof-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	writer := bufio.NewWriter(temp)
+	if err := apply(writer, source); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write destination file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write destination file: %w", err)
+	}
+	if err := os.Chmod(temp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	return os.Rename(temp.Name(), dst)
+}
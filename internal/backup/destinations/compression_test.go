@@ -0,0 +1,123 @@
+package destinations
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+func TestLocalDestination_CompressesFiles(t *testing.T) {
+	memory := []byte(strings.Repeat("## Memory\nThe user prefers short answers.\n", 500))
+	source := t.TempDir()
+	for name, content := range map[string][]byte{
+		"workspace/memory.md": memory,
+		"workspace/notes.gz":  []byte("not really gzip"),
+		"workspace/empty.md":  {},
+	} {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	timestamp := time.Now()
+	save := func(dest *LocalDestination) *types.Snapshot {
+		t.Helper()
+		timestamp = timestamp.Add(time.Second)
+		snapshot, err := types.FromDirectoryWithTimestamp(source, nil, "", timestamp)
+		if err != nil {
+			t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+		}
+		if err := dest.Save(source, snapshot, ""); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		return snapshot
+	}
+	restoredMemory := func(dest *LocalDestination, snapshot *types.Snapshot) []byte {
+		t.Helper()
+		target := t.TempDir()
+		if err := dest.Restore(snapshot.ID, target); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+		restored, err := types.FromDirectoryWithTimestamp(target, nil, "", time.Now())
+		if err != nil {
+			t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+		}
+		if diff := snapshot.Diff(restored); !diff.IsEmpty() {
+			t.Errorf("restored tree differs from the snapshot: %+v", diff)
+		}
+		content, err := os.ReadFile(filepath.Join(target, "workspace", "memory.md"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return content
+	}
+
+	for _, tc := range []struct {
+		compression string
+		encrypted   bool
+	}{
+		{CompressionGzip, false},
+		{CompressionZstd, false},
+		{CompressionZstd, true},
+	} {
+		dest := NewLocalDestination(t.TempDir(), true)
+		dest.Compression = tc.compression
+		if tc.encrypted {
+			dest.Encryption = &Encryption{Passphrase: "correct horse", PassphraseEnv: "BULLETPROOF_PASSPHRASE"}
+		}
+		snapshot := save(dest)
+		if snapshot.Compression != tc.compression {
+			t.Errorf("%s: snapshot records compression %q", tc.compression, snapshot.Compression)
+		}
+
+		// Files are stored under a suffixed name, smaller than the original
+		stored := filepath.Join(dest.snapshotPath(snapshot.ID), "workspace", "memory.md"+compressedExt(tc.compression))
+		info, err := os.Stat(stored)
+		if err != nil {
+			t.Fatalf("%s: expected the compressed file: %v", tc.compression, err)
+		}
+		if info.Size() >= int64(len(memory))/4 {
+			t.Errorf("%s: stored %d bytes of %d", tc.compression, info.Size(), len(memory))
+		}
+		if _, err := os.Stat(filepath.Join(dest.snapshotPath(snapshot.ID), "workspace", "memory.md")); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no uncompressed copy", tc.compression)
+		}
+
+		if content := restoredMemory(dest, snapshot); !bytes.Equal(content, memory) {
+			t.Errorf("%s: restored file differs from the original", tc.compression)
+		}
+		content, err := dest.ReadSnapshotFile(snapshot.ID, filepath.Join("workspace", "notes.gz"))
+		if err != nil || string(content) != "not really gzip" {
+			t.Errorf("%s: ReadSnapshotFile = %q (%v)", tc.compression, content, err)
+		}
+
+		// Switching compression off leaves older snapshots restorable
+		dest.Compression = ""
+		plain := save(dest)
+		if _, err := os.Stat(filepath.Join(dest.snapshotPath(plain.ID), "workspace", "memory.md")); err != nil {
+			t.Errorf("%s: expected the next snapshot to be stored uncompressed: %v", tc.compression, err)
+		}
+		if content := restoredMemory(dest, snapshot); !bytes.Equal(content, memory) {
+			t.Errorf("%s: older compressed snapshot no longer restores", tc.compression)
+		}
+	}
+
+	// Unchanged compressed files are linked to the previous snapshot
+	dest := NewLocalDestination(t.TempDir(), true)
+	dest.Compression = CompressionGzip
+	dest.Deduplicate = true
+	first, second := save(dest), save(dest)
+	a, _ := os.Stat(filepath.Join(dest.snapshotPath(first.ID), "workspace", "memory.md.gz"))
+	b, _ := os.Stat(filepath.Join(dest.snapshotPath(second.ID), "workspace", "memory.md.gz"))
+	if a == nil || b == nil || !os.SameFile(a, b) {
+		t.Error("expected the unchanged compressed file to be linked")
+	}
+}
@@ -1,4 +1,5 @@
 // Package destinations implements storage backends for backups.
 // It provides LocalDestination for timestamped folders, GitDestination
-// for git repositories with tags, and SyncDestination for cloud sync services.
+// for git repositories with tags, SyncDestination for cloud sync services,
+// S3Destination for S3 buckets and SFTPDestination for SFTP servers.
 package destinations
@@ -0,0 +1,322 @@
+package destinations
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/bulletproof-bot/backup/internal/keys"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// Encryption encrypts snapshot files at rest with AES-256-GCM. The key is read
+// from KeyFile, or derived from Passphrase with scrypt using the salt kept in
+// the destination's .bulletproof/encryption.json. That file also holds a value
+// sealed with the key, so a wrong passphrase is reported before any file is
+// read instead of producing garbage.
+//
+// Files are encrypted in chunks, each sealed with its own nonce and marked
+// when it is the last, so a truncated or reordered file fails to decrypt.
+type Encryption struct {
+	Passphrase string
+	// PassphraseEnv names the variable Passphrase was read from, for messages
+	PassphraseEnv string
+	KeyFile       string
+
+	aead cipher.AEAD
+}
+
+// encryptionParams is the content of .bulletproof/encryption.json
+type encryptionParams struct {
+	Cipher string `json:"cipher"`
+	KDF    string `json:"kdf"` // "scrypt", or "none" for a key file
+	Salt   []byte `json:"salt,omitempty"`
+	N      int    `json:"n,omitempty"`
+	R      int    `json:"r,omitempty"`
+	P      int    `json:"p,omitempty"`
+	Check  []byte `json:"check"`
+}
+
+const (
+	encryptionFile = "encryption.json"
+	encryptedMagic = "BPENC1"
+	// encryptedChunkSize is the plaintext size of every chunk but the last
+	encryptedChunkSize = 64 * 1024
+	noncePrefixSize    = 8
+	checkPlaintext     = "bulletproof encryption check"
+)
+
+// Unlock loads the key for the destination whose metadata lives in metaDir
+// and checks it against encryption.json. With create set, a destination
+// without encryption settings gets new ones.
+func (e *Encryption) Unlock(metaDir string, create bool) error {
+	if e.aead != nil {
+		return nil
+	}
+
+	paramsFile := filepath.Join(metaDir, encryptionFile)
+	data, err := os.ReadFile(paramsFile)
+	if os.IsNotExist(err) {
+		if !create {
+			return fmt.Errorf("no encryption settings found in %s", paramsFile)
+		}
+		return e.initialize(paramsFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read encryption settings: %w", err)
+	}
+
+	var params encryptionParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", paramsFile, err)
+	}
+	switch {
+	case params.Cipher != "aes-256-gcm":
+		return fmt.Errorf("unsupported cipher in %s: %s", paramsFile, params.Cipher)
+	case params.KDF == "scrypt" && e.KeyFile != "":
+		return fmt.Errorf("this destination is encrypted with a passphrase, not a key file")
+	case params.KDF == "none" && e.KeyFile == "":
+		return fmt.Errorf("this destination is encrypted with a key file, not a passphrase")
+	case params.KDF != "scrypt" && params.KDF != "none":
+		return fmt.Errorf("unsupported key derivation in %s: %s", paramsFile, params.KDF)
+	}
+
+	key, err := e.key(params)
+	if err != nil {
+		return err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	if len(params.Check) < aead.NonceSize() {
+		return fmt.Errorf("invalid check value in %s", paramsFile)
+	}
+	nonce, sealed := params.Check[:aead.NonceSize()], params.Check[aead.NonceSize():]
+	if plain, err := aead.Open(nil, nonce, sealed, nil); err != nil || string(plain) != checkPlaintext {
+		if e.KeyFile != "" {
+			return fmt.Errorf("wrong encryption key: %s does not match the key this destination was encrypted with", e.KeyFile)
+		}
+		return fmt.Errorf("wrong encryption passphrase: $%s does not match the passphrase this destination was encrypted with", e.PassphraseEnv)
+	}
+
+	e.aead = aead
+	return nil
+}
+
+// initialize writes new encryption settings for a destination
+func (e *Encryption) initialize(paramsFile string) error {
+	params := encryptionParams{Cipher: "aes-256-gcm", KDF: "none"}
+	if e.KeyFile == "" {
+		params.KDF = "scrypt"
+		params.Salt = make([]byte, 16)
+		if _, err := rand.Read(params.Salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		params.N, params.R, params.P = 1<<15, 8, 1
+	}
+
+	key, err := e.key(params)
+	if err != nil {
+		return err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	params.Check = aead.Seal(nonce, nonce, []byte(checkPlaintext), nil)
+
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encryption settings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(paramsFile), 0755); err != nil {
+		return fmt.Errorf("failed to create metadata directory: %w", err)
+	}
+	if err := os.WriteFile(paramsFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write encryption settings: %w", err)
+	}
+
+	e.aead = aead
+	return nil
+}
+
+// key returns the key file's material, or derives a key from the passphrase
+func (e *Encryption) key(params encryptionParams) ([]byte, error) {
+	if e.KeyFile != "" {
+		path, err := utils.ExpandPath(e.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return keys.Load(path)
+	}
+
+	if e.Passphrase == "" {
+		return nil, fmt.Errorf("encryption is enabled but no passphrase is set: export %s", e.PassphraseEnv)
+	}
+	key, err := scrypt.Key([]byte(e.Passphrase), params.Salt, params.N, params.R, params.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptFile writes an encrypted copy of src to dst, keeping its permissions
+func (e *Encryption) EncryptFile(src, dst string) error {
+	if e.aead == nil {
+		return errors.New("encryption is not unlocked")
+	}
+	return transformFile(src, dst, e.encrypt)
+}
+
+// DecryptFile writes the plaintext of the encrypted file src to dst, keeping
+// its permissions. dst is only replaced once the whole file has decrypted.
+func (e *Encryption) DecryptFile(src, dst string) error {
+	if e.aead == nil {
+		return errors.New("encryption is not unlocked")
+	}
+	return transformFile(src, dst, e.decrypt)
+}
+
+// Decrypt returns the plaintext of an encrypted file's content
+func (e *Encryption) Decrypt(data []byte) ([]byte, error) {
+	var plain bytes.Buffer
+	if err := e.decrypt(&plain, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return plain.Bytes(), nil
+}
+
+// encrypt writes the magic, a random nonce prefix and the sealed chunks of r
+func (e *Encryption) encrypt(w io.Writer, r io.Reader) error {
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := io.WriteString(w, encryptedMagic); err != nil {
+		return err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(r, encryptedChunkSize)
+	plain := make([]byte, encryptedChunkSize)
+	sealed := make([]byte, 0, encryptedChunkSize+e.aead.Overhead())
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(reader, plain)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		final, err := lastChunk(reader, err)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+
+		sealed = e.aead.Seal(sealed[:0], chunkNonce(prefix, counter), plain[:n], chunkAAD(final))
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// decrypt checks and opens the chunks written by encrypt
+func (e *Encryption) decrypt(w io.Writer, r io.Reader) error {
+	header := make([]byte, len(encryptedMagic)+noncePrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(encryptedMagic)]) != encryptedMagic {
+		return errors.New("file is not encrypted by bulletproof")
+	}
+	prefix := header[len(encryptedMagic):]
+
+	reader := bufio.NewReaderSize(r, encryptedChunkSize+e.aead.Overhead())
+	sealed := make([]byte, encryptedChunkSize+e.aead.Overhead())
+	plain := make([]byte, 0, encryptedChunkSize)
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(reader, sealed)
+		if err == io.EOF {
+			return errors.New("encrypted file is truncated")
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		final, err := lastChunk(reader, err)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+
+		plain, err = e.aead.Open(plain[:0], chunkNonce(prefix, counter), sealed[:n], chunkAAD(final))
+		if err != nil {
+			return errors.New("encrypted file is corrupted or was encrypted with another key")
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// lastChunk reports whether the chunk just read with io.ReadFull is the last:
+// it was short, or nothing follows it
+func lastChunk(reader *bufio.Reader, readErr error) (bool, error) {
+	if readErr != nil {
+		return true, nil
+	}
+	if _, err := reader.Peek(1); err == io.EOF {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+func chunkNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, noncePrefixSize+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	return nonce
+}
+
+func chunkAAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// encryptedSize returns the stored size of an encrypted file of size bytes
+func encryptedSize(size int64) int64 {
+	const overhead = 16 // GCM tag
+	chunks := size/encryptedChunkSize + 1
+	if size > 0 && size%encryptedChunkSize == 0 {
+		chunks--
+	}
+	return int64(len(encryptedMagic)+noncePrefixSize) + size + chunks*overhead
+}
@@ -0,0 +1,174 @@
+package destinations
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+func TestLocalDestination_EncryptsFilesAtRest(t *testing.T) {
+	source := t.TempDir()
+	large := make([]byte, 3*encryptedChunkSize+100)
+	rand.Read(large)
+	files := map[string][]byte{
+		"workspace/memory/log.md": []byte("secret conversation\n"),
+		"workspace/large.bin":     large,
+		"workspace/chunk.bin":     bytes.Repeat([]byte{'x'}, encryptedChunkSize),
+		"workspace/empty.md":      {},
+	}
+	for name, content := range files {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newSnapshot := func(timestamp time.Time) *types.Snapshot {
+		t.Helper()
+		snapshot, err := types.FromDirectoryWithTimestamp(source, nil, "", timestamp)
+		if err != nil {
+			t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+		}
+		return snapshot
+	}
+
+	dest := NewLocalDestination(t.TempDir(), true)
+	dest.Deduplicate = true
+	dest.Encryption = &Encryption{Passphrase: "correct horse", PassphraseEnv: "BULLETPROOF_PASSPHRASE"}
+	snapshot := newSnapshot(time.Now())
+	if err := dest.Save(source, snapshot, ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest.metadataPath(), encryptionFile)); err != nil {
+		t.Fatalf("expected encryption settings to be written: %v", err)
+	}
+
+	// Stored files are ciphertext; the manifest hashes the plaintext
+	stored, err := dest.GetSnapshot(snapshot.ID)
+	if err != nil || stored == nil || !stored.Encrypted {
+		t.Fatalf("expected the stored snapshot to be marked encrypted, got %+v (%v)", stored, err)
+	}
+	for name, content := range files {
+		rel := filepath.FromSlash(name)
+		data, err := os.ReadFile(filepath.Join(dest.snapshotPath(snapshot.ID), rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(data)) != encryptedSize(int64(len(content))) {
+			t.Errorf("%s: stored %d bytes, expected %d", name, len(data), encryptedSize(int64(len(content))))
+		}
+		if len(content) > 0 && bytes.Contains(data, content[:min(len(content), 64)]) {
+			t.Errorf("%s: stored file contains plaintext", name)
+		}
+		if stored.Files[rel].Hash != utils.HashBytes(content) {
+			t.Errorf("%s: manifest hash is not of the plaintext", name)
+		}
+	}
+
+	content, err := dest.ReadSnapshotFile(snapshot.ID, filepath.Join("workspace", "memory", "log.md"))
+	if err != nil || string(content) != "secret conversation\n" {
+		t.Errorf("ReadSnapshotFile = %q (%v)", content, err)
+	}
+
+	target := t.TempDir()
+	if err := dest.Restore(snapshot.ID, target); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if diff := snapshot.Diff(newSnapshot(time.Now())); !diff.IsEmpty() {
+		t.Fatalf("source changed: %+v", diff)
+	}
+	restored, err := types.FromDirectoryWithTimestamp(target, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+	}
+	if diff := snapshot.Diff(restored); !diff.IsEmpty() {
+		t.Errorf("restored tree differs from the snapshot: %+v", diff)
+	}
+
+	// Unchanged encrypted files are still linked to the previous snapshot
+	second := newSnapshot(time.Now().Add(time.Second))
+	if err := dest.Save(source, second, ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	first, _ := os.Stat(filepath.Join(dest.snapshotPath(snapshot.ID), "workspace", "large.bin"))
+	linked, _ := os.Stat(filepath.Join(dest.snapshotPath(second.ID), "workspace", "large.bin"))
+	if first == nil || linked == nil || !os.SameFile(first, linked) {
+		t.Error("expected the unchanged encrypted file to be linked")
+	}
+
+	// A wrong passphrase fails before the target is touched
+	os.WriteFile(filepath.Join(target, "workspace", "extra.md"), []byte("keep"), 0644)
+	wrong := NewLocalDestination(dest.BasePath, true)
+	wrong.Encryption = &Encryption{Passphrase: "wrong", PassphraseEnv: "BULLETPROOF_PASSPHRASE"}
+	if err := wrong.Restore(snapshot.ID, target); err == nil || !strings.Contains(err.Error(), "wrong encryption passphrase") {
+		t.Errorf("expected a wrong passphrase to be reported, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "workspace", "extra.md")); err != nil {
+		t.Error("expected a failed restore to leave the target alone")
+	}
+	if err := wrong.Save(source, newSnapshot(time.Now().Add(2*time.Second)), ""); err == nil {
+		t.Error("expected saving with a wrong passphrase to fail")
+	}
+
+	// Without encryption configured, encrypted snapshots cannot be read
+	plain := NewLocalDestination(dest.BasePath, true)
+	if err := plain.Restore(snapshot.ID, t.TempDir()); err == nil || !strings.Contains(err.Error(), "is encrypted") {
+		t.Errorf("expected restoring without a passphrase to fail, got %v", err)
+	}
+}
+
+func TestEncryption_DetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	encryption := &Encryption{Passphrase: "correct horse", PassphraseEnv: "BULLETPROOF_PASSPHRASE"}
+	if err := encryption.Unlock(dir, true); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+
+	plainFile := filepath.Join(dir, "plain")
+	if err := os.WriteFile(plainFile, bytes.Repeat([]byte("memory "), encryptedChunkSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+	encryptedFile := filepath.Join(dir, "encrypted")
+	if err := encryption.EncryptFile(plainFile, encryptedFile); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+	data, err := os.ReadFile(encryptedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunk := encryptedChunkSize + 16
+	header := len(encryptedMagic) + noncePrefixSize
+	flipped := bytes.Clone(data)
+	flipped[header+10] ^= 1
+	for name, tampered := range map[string][]byte{
+		"truncated at a chunk boundary": data[:header+2*chunk],
+		"truncated mid-chunk":           data[:len(data)-5],
+		"modified":                      flipped,
+		"not encrypted":                 []byte("plain text"),
+	} {
+		if _, err := encryption.Decrypt(tampered); err == nil {
+			t.Errorf("%s: expected decryption to fail", name)
+		}
+	}
+
+	// A failed decryption leaves an existing file in place
+	os.WriteFile(encryptedFile, flipped, 0644)
+	restored := filepath.Join(dir, "restored")
+	os.WriteFile(restored, []byte("previous"), 0644)
+	if err := encryption.DecryptFile(encryptedFile, restored); err == nil {
+		t.Error("expected DecryptFile to fail")
+	}
+	if content, _ := os.ReadFile(restored); string(content) != "previous" {
+		t.Errorf("expected the target to be left alone, got %q", content)
+	}
+}
@@ -2,17 +2,27 @@ package destinations
 
 import (
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/bulletproof-bot/backup/internal/errors"
 	"github.com/bulletproof-bot/backup/internal/types"
 	"github.com/bulletproof-bot/backup/internal/utils"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
 )
 
 // GitDestination stores backups as commits in a git repository.
@@ -20,21 +30,37 @@ import (
 // Automatically handles initializing repo if needed, committing changes,
 // and pushing to remote if configured.
 type GitDestination struct {
-	RepoPath  string
+	RepoPath string
+	// PushBranch pushes the current branch along with the snapshot tags, so a
+	// fresh clone checks out the latest backup
+	PushBranch bool
+	// AuthorName and AuthorEmail identify backup commits and tags. Empty ones
+	// come from the repository's user.name and user.email, then the defaults.
+	AuthorName  string
+	AuthorEmail string
+	// Retries is how often a clone, pull or push that failed on a network
+	// error is tried again, waiting twice as long each time
+	Retries int
+
 	isRemote  bool
 	validated bool
 	repo      *git.Repository
+
+	messages
 }
 
 // NewGitDestination creates a new git destination
 func NewGitDestination(repoPath string) *GitDestination {
 	isRemote := strings.HasPrefix(repoPath, "git@") ||
 		strings.HasPrefix(repoPath, "https://") ||
+		strings.HasPrefix(repoPath, "http://") ||
 		strings.HasPrefix(repoPath, "ssh://")
 
 	return &GitDestination{
-		RepoPath: repoPath,
-		isRemote: isRemote,
+		RepoPath:   repoPath,
+		PushBranch: true,
+		Retries:    DefaultGitRetries,
+		isRemote:   isRemote,
 	}
 }
 
@@ -78,6 +104,129 @@ func (d *GitDestination) Validate() error {
 	return nil
 }
 
+// CheckRemote lists the remote's references, as `git ls-remote` does, to check
+// that it is reachable with the credentials clone and push will use. Nothing is
+// cloned or written. An empty remote passes, since the first backup fills it;
+// local repositories have nothing to check.
+func (d *GitDestination) CheckRemote() error {
+	if !d.isRemote {
+		return nil
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{d.RepoPath},
+	})
+	_, err := remote.List(&git.ListOptions{})
+	if err == nil || stderrors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return nil
+	}
+	return remoteAccessError(d.RepoPath, err)
+}
+
+// remoteAccessError explains a failed remote check with the likely causes and
+// what to try, which differ between SSH and HTTPS remotes
+func remoteAccessError(url string, cause error) error {
+	ssh := !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://")
+	message := cause.Error()
+
+	switch {
+	case stderrors.Is(cause, transport.ErrRepositoryNotFound):
+		return errors.NewActionableError("reach git remote "+url, cause, []string{
+			"The repository URL is misspelled",
+			"The repository is private and these credentials have no access to it",
+		}, "Check the URL, and that the repository exists and your account can read it", "")
+
+	case ssh && (strings.Contains(message, "unable to authenticate") || strings.Contains(message, "SSH agent") || strings.Contains(message, "SSH_AUTH_SOCK")):
+		return errors.NewActionableError("authenticate to git remote "+url, cause, []string{
+			"No SSH agent is running, or it holds no key",
+			"The key is not registered with the git host",
+		}, "Load your key and test it against the host:\nssh-add ~/.ssh/id_ed25519\nssh -T git@github.com", "")
+
+	case stderrors.Is(cause, transport.ErrAuthenticationRequired) || stderrors.Is(cause, transport.ErrAuthorizationFailed):
+		return errors.NewActionableError("authenticate to git remote "+url, cause, []string{
+			"The repository is private and the URL carries no credentials",
+			"The token in the URL is wrong, expired, or lacks repository access",
+		}, "Use an SSH URL (git@github.com:user/repo.git) with a key in your SSH agent,\nor put an access token in the URL: https://<token>@github.com/user/repo.git", "")
+
+	default:
+		return errors.NewActionableError("reach git remote "+url, cause, []string{
+			"No network connection, or the host name is wrong",
+			"A firewall or proxy blocks the connection",
+		}, "Check the URL and your network, then run init again", "")
+	}
+}
+
+// DefaultGitRetries is how often remote operations are retried unless
+// configured otherwise
+const DefaultGitRetries = 3
+
+// gitRetryDelay is the wait before the first retry; each later one waits twice
+// as long as the one before
+var gitRetryDelay = time.Second
+
+// withRetry runs a remote operation, trying it again up to d.Retries times
+// while it fails on a transient network error. Other errors, such as failed
+// authentication, are returned at once, since trying again cannot help.
+func (d *GitDestination) withRetry(operation string, fn func() error) error {
+	delay := gitRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > d.Retries || !isTransientGitError(err) {
+			return err
+		}
+		fmt.Fprintf(d.output(), "  ⚠️  %s failed, retrying in %s (%d/%d): %v\n", operation, delay, attempt, d.Retries, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// transientGitMessages are parts of error messages that report a network
+// problem likely to pass, for errors that carry no typed cause
+var transientGitMessages = []string{
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+	"unexpected eof",
+	"timeout",
+	"timed out",
+	"temporary failure",
+	"network is unreachable",
+	"no route to host",
+	"status code: 5", // HTTP 5xx from the git host
+}
+
+// isTransientGitError reports whether a remote operation failed on a network
+// problem worth retrying, rather than on credentials, a missing repository or
+// a rejected push
+func isTransientGitError(err error) bool {
+	switch {
+	case err == git.NoErrAlreadyUpToDate,
+		stderrors.Is(err, transport.ErrAuthenticationRequired),
+		stderrors.Is(err, transport.ErrAuthorizationFailed),
+		stderrors.Is(err, transport.ErrRepositoryNotFound),
+		stderrors.Is(err, transport.ErrEmptyRemoteRepository),
+		stderrors.Is(err, git.ErrForceNeeded):
+		return false
+	}
+
+	var netErr net.Error
+	if stderrors.As(err, &netErr) || stderrors.Is(err, io.ErrUnexpectedEOF) ||
+		stderrors.Is(err, syscall.ECONNRESET) || stderrors.Is(err, syscall.ECONNREFUSED) || stderrors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, "unable to authenticate") {
+		return false
+	}
+	for _, part := range transientGitMessages {
+		if strings.Contains(message, part) {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *GitDestination) ensureCloned() error {
 	localPath := d.localPath()
 
@@ -85,25 +234,36 @@ func (d *GitDestination) ensureCloned() error {
 	if repo, err := git.PlainOpen(localPath); err == nil {
 		d.repo = repo
 		// Pull latest
-		fmt.Println("  Pulling latest from remote...")
+		fmt.Fprintln(d.output(), "  Pulling latest from remote...")
 		worktree, err := repo.Worktree()
 		if err != nil {
 			return fmt.Errorf("failed to get worktree: %w", err)
 		}
-		if err := worktree.Pull(&git.PullOptions{}); err != nil && err != git.NoErrAlreadyUpToDate {
-			return fmt.Errorf("failed to pull: %w", err)
+		// Keep working from the local clone when offline, so backups pile up
+		// locally until `bulletproof sync` pushes them
+		err = d.withRetry("Pull", func() error {
+			return worktree.Pull(&git.PullOptions{})
+		})
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			fmt.Fprintf(d.output(), "  ⚠️  Could not pull, using the local copy: %v\n", err)
 		}
 		return nil
 	}
 
 	// Clone the repository
-	fmt.Println("  Cloning repository...")
+	fmt.Fprintln(d.output(), "  Cloning repository...")
 	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	repo, err := git.PlainClone(localPath, false, &git.CloneOptions{
-		URL: d.RepoPath,
+	// A failed clone removes what it wrote, so it can simply be tried again
+	var repo *git.Repository
+	err := d.withRetry("Clone", func() error {
+		var err error
+		repo, err = git.PlainClone(localPath, false, &git.CloneOptions{
+			URL: d.RepoPath,
+		})
+		return err
 	})
 	if err != nil {
 		return fmt.Errorf("failed to clone repository: %w", err)
@@ -121,7 +281,7 @@ func (d *GitDestination) initRepo() error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	fmt.Println("  Initializing git repository...")
+	fmt.Fprintln(d.output(), "  Initializing git repository...")
 	repo, err := git.PlainInit(localPath, false)
 	if err != nil {
 		return fmt.Errorf("failed to initialize repository: %w", err)
@@ -146,10 +306,7 @@ func (d *GitDestination) initRepo() error {
 	}
 
 	if _, err := worktree.Commit("Initial commit", &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  "Bulletproof Backup",
-			Email: "backup@bulletproof.bot",
-		},
+		Author: d.signature(repo, time.Now()),
 	}); err != nil {
 		return fmt.Errorf("failed to create initial commit: %w", err)
 	}
@@ -157,6 +314,37 @@ func (d *GitDestination) initRepo() error {
 	return nil
 }
 
+// Default identity of backup commits when neither the config nor the
+// repository names one, so no git identity needs to be configured
+const (
+	defaultGitAuthorName  = "Bulletproof Backup"
+	defaultGitAuthorEmail = "backup@bulletproof.bot"
+)
+
+// signature returns the identity to commit and tag as: the configured author,
+// then the repository's user.name and user.email, then the defaults, field by
+// field
+func (d *GitDestination) signature(repo *git.Repository, when time.Time) *object.Signature {
+	signature := &object.Signature{Name: d.AuthorName, Email: d.AuthorEmail, When: when}
+	if signature.Name == "" || signature.Email == "" {
+		if cfg, err := repo.Config(); err == nil {
+			if signature.Name == "" {
+				signature.Name = cfg.User.Name
+			}
+			if signature.Email == "" {
+				signature.Email = cfg.User.Email
+			}
+		}
+	}
+	if signature.Name == "" {
+		signature.Name = defaultGitAuthorName
+	}
+	if signature.Email == "" {
+		signature.Email = defaultGitAuthorEmail
+	}
+	return signature
+}
+
 // Save saves a backup to the git repository
 func (d *GitDestination) Save(sourcePath string, snapshot *types.Snapshot, message string) error {
 	if err := d.Validate(); err != nil {
@@ -166,7 +354,7 @@ func (d *GitDestination) Save(sourcePath string, snapshot *types.Snapshot, messa
 	localPath := d.localPath()
 
 	// Sync files
-	fmt.Println("  Copying files to backup repository...")
+	fmt.Fprintln(d.output(), "  Copying files to backup repository...")
 	if err := d.syncFiles(sourcePath, localPath, snapshot); err != nil {
 		return err
 	}
@@ -203,16 +391,19 @@ func (d *GitDestination) Save(sourcePath string, snapshot *types.Snapshot, messa
 	}
 
 	if status.IsClean() {
-		fmt.Println("  No changes to commit.")
+		fmt.Fprintln(d.output(), "  No changes to commit.")
 		return nil
 	}
 
-	// Commit
+	// The commit is dated when the snapshot was taken, which lists promoted
+	// snapshots in order too
+	when := snapshot.Timestamp
+	if when.IsZero() {
+		when = time.Now()
+	}
+	signature := d.signature(d.repo, when)
 	commitHash, err := worktree.Commit(message, &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  "Bulletproof Backup",
-			Email: "backup@bulletproof.bot",
-		},
+		Author: signature,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to commit: %w", err)
@@ -220,25 +411,288 @@ func (d *GitDestination) Save(sourcePath string, snapshot *types.Snapshot, messa
 
 	// Tag with snapshot ID
 	if _, err := d.repo.CreateTag(snapshot.ID, commitHash, &git.CreateTagOptions{
+		Tagger:  signature,
 		Message: message,
 	}); err != nil {
 		return fmt.Errorf("failed to create tag: %w", err)
 	}
 
-	// Push if remote
+	// Tag the commit once more per label so labeled checkpoints show up in git too
+	for _, label := range snapshot.Labels {
+		if _, err := d.repo.CreateTag(labelTagName(label, snapshot.ID), commitHash, &git.CreateTagOptions{
+			Tagger:  signature,
+			Message: message,
+		}); err != nil {
+			return fmt.Errorf("failed to create label tag: %w", err)
+		}
+	}
+
+	// Push this backup along with any that failed to push earlier. The backup
+	// is safe in the local clone, so a failed push only warns.
 	if d.isRemote {
-		fmt.Println("  Pushing to remote...")
-		refSpec := config.RefSpec("refs/tags/*:refs/tags/*")
-		if err := d.repo.Push(&git.PushOptions{
-			RefSpecs: []config.RefSpec{refSpec},
-		}); err != nil && err != git.NoErrAlreadyUpToDate {
-			return fmt.Errorf("failed to push: %w", err)
+		fmt.Fprintln(d.output(), "  Pushing to remote...")
+		results, err := d.Sync()
+		if err != nil {
+			fmt.Fprintf(d.output(), "  ⚠️  Backup kept locally, push failed: %v\n", err)
+			fmt.Fprintln(d.output(), "  💡 Run 'bulletproof sync' once the remote is reachable")
+		} else if failed := countUnsynced(results); failed > 0 {
+			fmt.Fprintf(d.output(), "  ⚠️  Backup kept locally, %d ref(s) not pushed\n", failed)
+			fmt.Fprintln(d.output(), "  💡 Run 'bulletproof sync' once the remote is reachable")
 		}
 	}
 
 	return nil
 }
 
+// pushLockStale is how old a push lock may get before it is taken to be left
+// over from a crashed run
+const pushLockStale = 15 * time.Minute
+
+// Sync pushes every local snapshot tag the remote lacks, oldest first, plus the
+// branch when PushBranch is set and the remote can fast-forward to it. Tags are pushed one at a time
+// so an interrupted sync keeps what it pushed and the next one resumes. Pushes
+// never force: a remote that moved on is caught up with when it is only ahead,
+// and reported as diverged otherwise, while the snapshot tags are still pushed
+// since each carries its own commits. Results are printed as they happen and
+// returned per ref; refs already in step are left out.
+func (d *GitDestination) Sync() ([]types.RefSync, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	remote, err := d.repo.Remote("origin")
+	if err != nil {
+		return nil, fmt.Errorf("git repository %s has no remote to sync with", d.RepoPath)
+	}
+	url := remote.Config().URLs[0]
+
+	unlock, err := d.lockPush()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var remoteRefs map[plumbing.ReferenceName]plumbing.Hash
+	err = d.withRetry("Listing the remote", func() error {
+		var err error
+		remoteRefs, err = listRemoteRefs(remote)
+		return err
+	})
+	if err != nil {
+		return nil, remoteAccessError(url, err)
+	}
+
+	var results []types.RefSync
+	if d.PushBranch {
+		if branch, ok := d.syncBranch(remote, remoteRefs, true); ok {
+			results = append(results, branch)
+		}
+	}
+
+	pending, rejected, err := d.pendingTags(remoteRefs)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, rejected...)
+	for _, r := range rejected {
+		fmt.Fprintf(d.output(), "  ❌ %s\n", r)
+	}
+
+	for i, name := range pending {
+		result := types.RefSync{Ref: name.Short(), Status: types.RefPushed}
+		if err := d.push(name); err != nil && err != git.NoErrAlreadyUpToDate {
+			result = types.RefSync{Ref: name.Short(), Status: types.RefFailed, Detail: err.Error()}
+		}
+		fmt.Fprintf(d.output(), "  [%d/%d] %s\n", i+1, len(pending), result)
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// listRemoteRefs returns the hash of each of the remote's references. An empty
+// remote has none.
+func listRemoteRefs(remote *git.Remote) (map[plumbing.ReferenceName]plumbing.Hash, error) {
+	listed, err := remote.List(&git.ListOptions{})
+	if err != nil && !stderrors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return nil, err
+	}
+	refs := make(map[plumbing.ReferenceName]plumbing.Hash)
+	for _, ref := range listed {
+		refs[ref.Name()] = ref.Hash()
+	}
+	return refs, nil
+}
+
+// syncBranch brings the current branch in step with the remote's copy of it.
+// It reports false when the two already match. A push the remote rejects
+// because its branch moved since remoteRefs was listed is retried once, after
+// listing again, when retry is set.
+func (d *GitDestination) syncBranch(remote *git.Remote, remoteRefs map[plumbing.ReferenceName]plumbing.Hash, retry bool) (types.RefSync, bool) {
+	head, err := d.repo.Head()
+	if err != nil || !head.Name().IsBranch() {
+		return types.RefSync{}, false
+	}
+	name := head.Name()
+	result := types.RefSync{Ref: name.Short()}
+
+	remoteHash, onRemote := remoteRefs[name]
+	if onRemote && remoteHash == head.Hash() {
+		return result, false
+	}
+
+	if onRemote {
+		// The branch moved on the remote; fetch it to see how the two relate
+		err := d.withRetry("Fetch", func() error {
+			return d.repo.Fetch(&git.FetchOptions{RemoteName: "origin", Tags: git.NoTags})
+		})
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			result.Status, result.Detail = types.RefFailed, "fetch failed: "+err.Error()
+			fmt.Fprintf(d.output(), "  ❌ %s\n", result)
+			return result, true
+		}
+
+		local, localErr := d.repo.CommitObject(head.Hash())
+		theirs, theirsErr := d.repo.CommitObject(remoteHash)
+		if localErr != nil || theirsErr != nil {
+			result.Status, result.Detail = types.RefFailed, "could not compare with the remote branch"
+			fmt.Fprintf(d.output(), "  ❌ %s\n", result)
+			return result, true
+		}
+
+		if behind, _ := local.IsAncestor(theirs); behind {
+			result.Status = types.RefPulled
+			if worktree, err := d.repo.Worktree(); err != nil {
+				result.Status, result.Detail = types.RefFailed, err.Error()
+			} else if err := d.withRetry("Pull", func() error {
+				return worktree.Pull(&git.PullOptions{RemoteName: "origin"})
+			}); err != nil && err != git.NoErrAlreadyUpToDate {
+				result.Status, result.Detail = types.RefFailed, "pull failed: "+err.Error()
+			}
+			fmt.Fprintf(d.output(), "  🔄 %s\n", result)
+			return result, true
+		}
+
+		if ahead, _ := theirs.IsAncestor(local); !ahead {
+			result.Status = types.RefDiverged
+			result.Detail = "the remote branch has commits this clone lacks; snapshot tags are pushed, the branch is left as is"
+			fmt.Fprintf(d.output(), "  ⚠️  %s\n", result)
+			return result, true
+		}
+	}
+
+	result.Status = types.RefPushed
+	err = d.push(name)
+	if stderrors.Is(err, git.ErrForceNeeded) && retry {
+		// Another clone pushed in the meantime; catch up with it and try again
+		if refs, listErr := listRemoteRefs(remote); listErr == nil {
+			return d.syncBranch(remote, refs, false)
+		}
+	}
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		result.Status, result.Detail = types.RefFailed, err.Error()
+	}
+	fmt.Fprintf(d.output(), "  🔄 %s\n", result)
+	return result, true
+}
+
+// push pushes one local ref to the same name on the remote, retrying on
+// network errors
+func (d *GitDestination) push(name plumbing.ReferenceName) error {
+	refSpec := config.RefSpec(name.String() + ":" + name.String())
+	return d.withRetry("Pushing "+name.Short(), func() error {
+		return d.repo.Push(&git.PushOptions{
+			RemoteName: "origin",
+			RefSpecs:   []config.RefSpec{refSpec},
+		})
+	})
+}
+
+// pendingTags returns the local tags the remote lacks, ordered by commit time
+// and then name, and the tags the remote holds a different version of
+func (d *GitDestination) pendingTags(remoteRefs map[plumbing.ReferenceName]plumbing.Hash) ([]plumbing.ReferenceName, []types.RefSync, error) {
+	tags, err := d.repo.Tags()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	type pendingTag struct {
+		name plumbing.ReferenceName
+		when time.Time
+	}
+	var pending []pendingTag
+	var rejected []types.RefSync
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		remoteHash, onRemote := remoteRefs[ref.Name()]
+		switch {
+		case !onRemote:
+			tag := pendingTag{name: ref.Name()}
+			if commit, err := d.tagCommit(ref); err == nil {
+				tag.when = commit.Committer.When
+			}
+			pending = append(pending, tag)
+		case remoteHash != ref.Hash():
+			rejected = append(rejected, types.RefSync{
+				Ref:    ref.Name().Short(),
+				Status: types.RefRejected,
+				Detail: "the remote has a different tag of this name",
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read tags: %w", err)
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].when.Equal(pending[j].when) {
+			return pending[i].when.Before(pending[j].when)
+		}
+		return pending[i].name < pending[j].name
+	})
+	names := make([]plumbing.ReferenceName, len(pending))
+	for i, tag := range pending {
+		names[i] = tag.name
+	}
+	return names, rejected, nil
+}
+
+// lockPush keeps two runs from pushing from the same clone at once, e.g. a
+// scheduled backup and a manual sync. It returns a function that releases it.
+func (d *GitDestination) lockPush() (func(), error) {
+	lockPath := filepath.Join(d.localPath(), ".git", "bulletproof-push.lock")
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock repository for pushing: %w", err)
+		}
+
+		// A lock this old was left behind by a run that crashed
+		info, statErr := os.Stat(lockPath)
+		if statErr != nil || time.Since(info.ModTime()) < pushLockStale {
+			break
+		}
+		os.Remove(lockPath)
+	}
+	return nil, fmt.Errorf("another push to the remote is in progress (remove %s if it is not)", lockPath)
+}
+
+// countUnsynced returns how many refs are not in step with the remote
+func countUnsynced(results []types.RefSync) int {
+	count := 0
+	for _, r := range results {
+		if !r.OK() {
+			count++
+		}
+	}
+	return count
+}
+
 func (d *GitDestination) syncFiles(sourcePath, destPath string, snapshot *types.Snapshot) error {
 	// Clear existing files (except .git and .bulletproof)
 	entries, err := os.ReadDir(destPath)
@@ -259,6 +713,8 @@ func (d *GitDestination) syncFiles(sourcePath, destPath string, snapshot *types.
 	}
 
 	// Copy all files from snapshot
+	done := 0
+	progress := d.startProgress("Copied")
 	for filePath := range snapshot.Files {
 		sourceFile := filepath.Join(sourcePath, filePath)
 		destFile := filepath.Join(destPath, filePath)
@@ -266,60 +722,129 @@ func (d *GitDestination) syncFiles(sourcePath, destPath string, snapshot *types.
 		if err := utils.CopyFile(sourceFile, destFile); err != nil {
 			return fmt.Errorf("failed to copy file %s: %w", filePath, err)
 		}
+		done++
+		progress(done, len(snapshot.Files), filePath)
 	}
 
 	return nil
 }
 
-// GetLastSnapshot returns the most recent snapshot
+// GetLastSnapshot returns the most recent snapshot.
+// It reads the metadata from the newest snapshot tag by commit time rather than
+// from the working tree, which may be checked out at an older snapshot.
 func (d *GitDestination) GetLastSnapshot() (*types.Snapshot, error) {
 	if err := d.Validate(); err != nil {
 		return nil, err
 	}
 
-	localPath := d.localPath()
-	metaFile := filepath.Join(localPath, ".bulletproof", "snapshot.json")
+	commit, err := d.newestSnapshotCommit()
+	if err != nil {
+		return nil, err
+	}
+	if commit == nil {
+		return nil, nil
+	}
+
+	return snapshotFromCommit(commit)
+}
 
-	data, err := os.ReadFile(metaFile)
+// newestSnapshotCommit returns the commit of the most recent snapshot tag, or nil
+// if there are no snapshots. Commits made within the same second are ordered by
+// tag name, since snapshot IDs are millisecond timestamps.
+func (d *GitDestination) newestSnapshotCommit() (*object.Commit, error) {
+	tags, err := d.repo.Tags()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+		return nil, fmt.Errorf("failed to get tags: %w", err)
 	}
 
-	snapshot, err := types.FromJSON(data)
+	var newest *object.Commit
+	var newestName string
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		if _, _, ok := parseLabelTagName(name); ok {
+			return nil
+		}
+
+		commit, err := d.tagCommit(ref)
+		if err != nil {
+			// Not a snapshot tag (e.g. a tag on a non-commit object)
+			return nil
+		}
+
+		when := commit.Committer.When
+		if newest == nil || when.After(newest.Committer.When) || (when.Equal(newest.Committer.When) && name > newestName) {
+			newest = commit
+			newestName = name
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+		return nil, fmt.Errorf("failed to read tags: %w", err)
 	}
 
-	return snapshot, nil
+	return newest, nil
+}
+
+// tagCommit resolves an annotated or lightweight tag to its commit
+func (d *GitDestination) tagCommit(ref *plumbing.Reference) (*object.Commit, error) {
+	if tag, err := d.repo.TagObject(ref.Hash()); err == nil {
+		return tag.Commit()
+	}
+	return d.repo.CommitObject(ref.Hash())
 }
 
-// GetSnapshot returns a specific snapshot by ID
+// GetSnapshot returns a specific snapshot by ID.
+// The metadata is read from the tagged commit, leaving the working tree untouched.
 func (d *GitDestination) GetSnapshot(id string) (*types.Snapshot, error) {
 	if err := d.Validate(); err != nil {
 		return nil, err
 	}
 
-	// Checkout the tag
-	worktree, err := d.repo.Worktree()
+	tagRef, err := d.repo.Tag(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get worktree: %w", err)
+		return nil, fmt.Errorf("snapshot not found: %s", id)
 	}
 
-	tagRef, err := d.repo.Tag(id)
+	commit, err := d.tagCommit(tagRef)
 	if err != nil {
-		return nil, fmt.Errorf("snapshot not found: %s", id)
+		return nil, fmt.Errorf("failed to resolve tag %s: %w", id, err)
 	}
 
-	if err := worktree.Checkout(&git.CheckoutOptions{
-		Branch: tagRef.Name(),
-	}); err != nil {
-		return nil, fmt.Errorf("failed to checkout tag: %w", err)
+	snapshot, err := snapshotFromCommit(commit)
+	if err != nil || snapshot == nil {
+		return snapshot, err
+	}
+	// Labels can change after the commit, so the label tags are authoritative
+	snapshot.Labels = nil
+	for _, tagName := range d.labelTags(id) {
+		label, _, _ := parseLabelTagName(tagName)
+		snapshot.Labels = append(snapshot.Labels, label)
+	}
+	return snapshot, nil
+}
+
+// snapshotFromCommit reads the snapshot metadata stored in a commit.
+// It returns nil if the commit has no metadata.
+func snapshotFromCommit(commit *object.Commit) (*types.Snapshot, error) {
+	file, err := commit.File(".bulletproof/snapshot.json")
+	if err != nil {
+		if err == object.ErrFileNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+
+	snapshot, err := types.FromJSON([]byte(contents))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
 	}
 
-	return d.GetLastSnapshot()
+	return snapshot, nil
 }
 
 // ListSnapshots returns all available snapshots
@@ -335,16 +860,51 @@ func (d *GitDestination) ListSnapshots() ([]*types.SnapshotInfo, error) {
 	}
 
 	snapshots := []*types.SnapshotInfo{}
+	labelsByID := make(map[string][]string)
 	tags.ForEach(func(ref *plumbing.Reference) error {
-		snapshots = append(snapshots, &types.SnapshotInfo{
-			ID: ref.Name().Short(),
-		})
+		name := ref.Name().Short()
+		if label, id, ok := parseLabelTagName(name); ok {
+			labelsByID[id] = append(labelsByID[id], label)
+			return nil
+		}
+		// The commit date is when the snapshot was taken, to the second; reading
+		// it is much cheaper than reading the manifest
+		info := &types.SnapshotInfo{
+			ID:        name,
+			SizeBytes: -1,
+		}
+		if commit, err := d.tagCommit(ref); err == nil {
+			info.Timestamp = commit.Author.When
+		}
+		snapshots = append(snapshots, info)
 		return nil
 	})
 
+	for _, snapshot := range snapshots {
+		snapshot.Labels = labelsByID[snapshot.ID]
+		sort.Strings(snapshot.Labels)
+	}
+
 	return snapshots, nil
 }
 
+// labelTagPrefix namespaces label tags so they are not listed as snapshots
+const labelTagPrefix = "labels/"
+
+// labelTagName returns the tag marking snapshot id with label
+func labelTagName(label, id string) string {
+	return labelTagPrefix + label + "/" + id
+}
+
+// parseLabelTagName splits a label tag into its label and snapshot ID
+func parseLabelTagName(name string) (label, id string, ok bool) {
+	rest, found := strings.CutPrefix(name, labelTagPrefix)
+	if !found {
+		return "", "", false
+	}
+	return strings.Cut(rest, "/")
+}
+
 // Restore restores files from a snapshot to the target path
 func (d *GitDestination) Restore(snapshotID string, targetPath string) error {
 	if err := d.Validate(); err != nil {
@@ -369,6 +929,12 @@ func (d *GitDestination) Restore(snapshotID string, targetPath string) error {
 		return fmt.Errorf("snapshot not found: %s", snapshotID)
 	}
 
+	// Git only tracks the executable bit, so modes come from the manifest
+	manifest, err := d.GetSnapshot(snapshotID)
+	if err != nil {
+		return err
+	}
+
 	if err := worktree.Checkout(&git.CheckoutOptions{
 		Branch: tagRef.Name(),
 	}); err != nil {
@@ -436,6 +1002,8 @@ func (d *GitDestination) Restore(snapshotID string, targetPath string) error {
 	}
 
 	// Copy files from repo to target
+	done := 0
+	progress := d.startProgress("Restored")
 	err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -457,7 +1025,18 @@ func (d *GitDestination) Restore(snapshotID string, targetPath string) error {
 
 		// Copy file
 		destFile := filepath.Join(targetPath, relativePath)
-		return utils.CopyFile(path, destFile)
+		if err := utils.CopyFile(path, destFile); err != nil {
+			return err
+		}
+		if err := manifest.RestoreMode(targetPath, relativePath); err != nil {
+			return fmt.Errorf("failed to restore mode of %s: %w", relativePath, err)
+		}
+		if err := utils.MatchPathCase(targetPath, relativePath); err != nil {
+			return err
+		}
+		done++
+		progress(done, len(snapshotFiles), relativePath)
+		return nil
 	})
 
 	// Checkout back to original branch before returning
@@ -477,13 +1056,42 @@ func (d *GitDestination) Restore(snapshotID string, targetPath string) error {
 	return err
 }
 
+// ReadSnapshotFile reads one file of a snapshot from its tagged commit, leaving
+// the working tree untouched
+func (d *GitDestination) ReadSnapshotFile(snapshotID, path string) ([]byte, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	tagRef, err := d.repo.Tag(snapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+	commit, err := d.tagCommit(tagRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve tag %s: %w", snapshotID, err)
+	}
+
+	file, err := commit.File(filepath.ToSlash(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s in snapshot %s: %w", path, snapshotID, err)
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return []byte(contents), nil
+}
+
 // GetSnapshotPath returns empty string for git destinations (files in git repo)
 // TODO: Could implement by checking out tag to temp directory
 func (d *GitDestination) GetSnapshotPath(id string) string {
 	return ""
 }
 
-// DeleteSnapshot deletes a snapshot by removing its tag
+// DeleteSnapshot deletes a snapshot by removing its tag, here and on the
+// remote. Its commits stay in the branch history, so their files still take
+// up space; pruning bounds the list of snapshots, not the repository size.
 func (d *GitDestination) DeleteSnapshot(id string) error {
 	// Ensure repo is validated
 	if err := d.Validate(); err != nil {
@@ -491,7 +1099,7 @@ func (d *GitDestination) DeleteSnapshot(id string) error {
 	}
 
 	// Delete the local tag
-	tagName := "backup-" + id
+	tagName := id
 	if err := d.repo.DeleteTag(tagName); err != nil {
 		return fmt.Errorf("failed to delete tag %s: %w", tagName, err)
 	}
@@ -506,9 +1114,140 @@ func (d *GitDestination) DeleteSnapshot(id string) error {
 			RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
 		}); err != nil {
 			// Don't fail if remote deletion fails (might not have permissions)
-			fmt.Printf("Warning: failed to delete remote tag %s: %v\n", tagName, err)
+			fmt.Fprintf(d.output(), "Warning: failed to delete remote tag %s: %v\n", tagName, err)
+		}
+	}
+
+	d.deleteLabelTags(id)
+
+	return nil
+}
+
+// Compact runs git gc on the local repository, packing loose objects and
+// removing unreachable ones. It needs git installed.
+func (d *GitDestination) Compact() error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return fmt.Errorf("git gc needs the git command: %w", err)
+	}
+
+	cmd := exec.Command(gitPath, "-C", d.localPath(), "gc", "--quiet")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git gc failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// labelTags returns the names of a snapshot's label tags, sorted
+func (d *GitDestination) labelTags(id string) []string {
+	tags, err := d.repo.Tags()
+	if err != nil {
+		return nil
+	}
+
+	var labelTags []string
+	tags.ForEach(func(ref *plumbing.Reference) error {
+		if _, taggedID, ok := parseLabelTagName(ref.Name().Short()); ok && taggedID == id {
+			labelTags = append(labelTags, ref.Name().Short())
+		}
+		return nil
+	})
+	sort.Strings(labelTags)
+	return labelTags
+}
+
+// SetLabels replaces the label tags of a snapshot. With a remote, the tags are
+// pushed or deleted there too; a failed push is reported, and the next sync
+// pushes new labels.
+func (d *GitDestination) SetLabels(id string, labels []string) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	tagRef, err := d.repo.Tag(id)
+	if err != nil {
+		return fmt.Errorf("snapshot not found: %s", id)
+	}
+	commit, err := d.tagCommit(tagRef)
+	if err != nil {
+		return fmt.Errorf("failed to resolve tag %s: %w", id, err)
+	}
+
+	current := make(map[string]bool)
+	for _, tagName := range d.labelTags(id) {
+		label, _, _ := parseLabelTagName(tagName)
+		current[label] = true
+	}
+	wanted := make(map[string]bool)
+	remote, _ := d.repo.Remote("origin")
+
+	signature := d.signature(d.repo, time.Now())
+	for _, label := range labels {
+		wanted[label] = true
+		if current[label] {
+			continue
+		}
+		tagName := labelTagName(label, id)
+		if _, err := d.repo.CreateTag(tagName, commit.Hash, &git.CreateTagOptions{
+			Tagger:  signature,
+			Message: commit.Message,
+		}); err != nil {
+			return fmt.Errorf("failed to create label tag: %w", err)
+		}
+		if remote != nil {
+			refSpec := fmt.Sprintf("refs/tags/%s:refs/tags/%s", tagName, tagName)
+			if err := d.repo.Push(&git.PushOptions{
+				RemoteName: "origin",
+				RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
+			}); err != nil && err != git.NoErrAlreadyUpToDate {
+				fmt.Fprintf(d.output(), "Warning: failed to push tag %s: %v\n", tagName, err)
+			}
 		}
 	}
 
+	for label := range current {
+		if wanted[label] {
+			continue
+		}
+		tagName := labelTagName(label, id)
+		if err := d.repo.DeleteTag(tagName); err != nil {
+			return fmt.Errorf("failed to delete label tag %s: %w", tagName, err)
+		}
+		if remote != nil {
+			refSpec := fmt.Sprintf(":refs/tags/%s", tagName)
+			if err := d.repo.Push(&git.PushOptions{
+				RemoteName: "origin",
+				RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
+			}); err != nil {
+				fmt.Fprintf(d.output(), "Warning: failed to delete remote tag %s: %v\n", tagName, err)
+			}
+		}
+	}
 	return nil
 }
+
+// deleteLabelTags removes the label tags of a deleted snapshot.
+// Failures only leave a stale label behind, so they are reported but not returned.
+func (d *GitDestination) deleteLabelTags(id string) {
+	labelTags := d.labelTags(id)
+
+	remote, _ := d.repo.Remote("origin")
+	for _, tagName := range labelTags {
+		if err := d.repo.DeleteTag(tagName); err != nil {
+			fmt.Fprintf(d.output(), "Warning: failed to delete label tag %s: %v\n", tagName, err)
+			continue
+		}
+		if remote != nil {
+			refSpec := fmt.Sprintf(":refs/tags/%s", tagName)
+			if err := d.repo.Push(&git.PushOptions{
+				RemoteName: "origin",
+				RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
+			}); err != nil {
+				fmt.Fprintf(d.output(), "Warning: failed to delete remote tag %s: %v\n", tagName, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package destinations
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+func TestIsTransientGitError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("push: %w", syscall.ECONNRESET), true},
+		{fmt.Errorf("fetch: %w", io.ErrUnexpectedEOF), true},
+		{stderrors.New("dial tcp 140.82.121.4:443: i/o timeout"), true},
+		{stderrors.New("unexpected client error: unexpected requesting \"https://github.com/a/b\" status code: 503"), true},
+		{transport.ErrAuthenticationRequired, false},
+		{fmt.Errorf("clone: %w", transport.ErrRepositoryNotFound), false},
+		{stderrors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey]"), false},
+		{git.ErrForceNeeded, false},
+		{git.NoErrAlreadyUpToDate, false},
+	}
+	for _, tt := range tests {
+		if got := isTransientGitError(tt.err); got != tt.want {
+			t.Errorf("isTransientGitError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestGitDestination_WithRetry(t *testing.T) {
+	delay := gitRetryDelay
+	gitRetryDelay = 0
+	t.Cleanup(func() { gitRetryDelay = delay })
+
+	d := NewGitDestination("https://example.com/backups.git")
+	d.Retries = 2
+
+	// A network error that clears up is retried until it succeeds
+	calls := 0
+	err := d.withRetry("Push", func() error {
+		calls++
+		if calls < 3 {
+			return syscall.ECONNRESET
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success on the third try, got %v after %d tries", err, calls)
+	}
+
+	// One that persists is given up on after the retries
+	calls = 0
+	err = d.withRetry("Push", func() error {
+		calls++
+		return syscall.ECONNRESET
+	})
+	if !stderrors.Is(err, syscall.ECONNRESET) || calls != 3 {
+		t.Errorf("expected the error after 3 tries, got %v after %d tries", err, calls)
+	}
+
+	// Failed authentication is not retried
+	calls = 0
+	err = d.withRetry("Push", func() error {
+		calls++
+		return transport.ErrAuthorizationFailed
+	})
+	if !stderrors.Is(err, transport.ErrAuthorizationFailed) || calls != 1 {
+		t.Errorf("expected no retry for failed authentication, got %v after %d tries", err, calls)
+	}
+}
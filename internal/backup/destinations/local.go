@@ -1,10 +1,13 @@
 package destinations
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,9 +19,21 @@ import (
 // It can operate in two modes:
 // - timestamped: Each backup creates a new folder (default)
 // - overwrite: Overwrites the same folder (for sync services)
+//
+// With Deduplicate set, a timestamped backup hardlinks files whose content is
+// unchanged since the previous snapshot instead of copying them again.
+//
+// With Compression or Encryption set, the files of new snapshots are stored
+// compressed (under their name plus a suffix such as .gz) and then encrypted,
+// and restored to their original form. Their manifests stay readable.
 type LocalDestination struct {
 	BasePath    string
 	Timestamped bool
+	Deduplicate bool
+	Compression string // CompressionGzip, CompressionZstd, or empty for none
+	Encryption  *Encryption
+
+	messages
 }
 
 // NewLocalDestination creates a new local destination
@@ -62,22 +77,56 @@ func (d *LocalDestination) Save(sourcePath string, snapshot *types.Snapshot, mes
 		if err := os.MkdirAll(targetPath, 0755); err != nil {
 			return fmt.Errorf("failed to create snapshot directory: %w", err)
 		}
-	} else {
-		// Clear existing files for sync mode
-		if err := d.clearExistingFiles(targetPath); err != nil {
+	}
+
+	if err := d.PrepareSave(snapshot); err != nil {
+		return err
+	}
+
+	// Sync mode only rewrites what changed, so the sync client does not
+	// upload unchanged files again
+	var synced map[string]bool
+	if !d.Timestamped {
+		synced = d.syncedFiles(snapshot)
+		if err := removeStaleFiles(targetPath, snapshot); err != nil {
 			return fmt.Errorf("failed to clear existing files: %w", err)
 		}
 	}
 
-	// Copy files
-	fmt.Printf("  Copying %d files...\n", len(snapshot.Files))
-	for filePath := range snapshot.Files {
+	// Copy files, unless only the manifest is kept
+	files := snapshot.Files
+	if snapshot.ManifestOnly {
+		files = nil
+		fmt.Fprintf(d.output(), "  Recording manifest of %d files...\n", len(snapshot.Files))
+	} else if len(synced) > 0 {
+		fmt.Fprintf(d.output(), "  Copying %d changed files, leaving %d unchanged...\n", len(snapshot.Files)-len(synced), len(synced))
+	} else {
+		fmt.Fprintf(d.output(), "  Copying %d files...\n", len(snapshot.Files))
+	}
+	unchanged := d.unchangedFiles(snapshot)
+	linked, done := 0, 0
+	progress := d.startProgress("Copied")
+	for filePath, file := range files {
 		sourceFile := filepath.Join(sourcePath, filePath)
 		destFile := filepath.Join(targetPath, filePath)
 
-		if err := utils.CopyFile(sourceFile, destFile); err != nil {
+		done++
+		if synced[filePath] {
+			progress(done, len(files), filePath)
+			continue
+		}
+		if previous, ok := unchanged[file.Hash]; ok && linkFile(previous, destFile+compressedExt(snapshot.Compression), storedSize(snapshot, file), file.Mode) {
+			linked++
+			progress(done, len(files), filePath)
+			continue
+		}
+		if err := d.StoreFile(snapshot, sourceFile, destFile); err != nil {
 			return fmt.Errorf("failed to copy file %s: %w", filePath, err)
 		}
+		progress(done, len(files), filePath)
+	}
+	if linked > 0 {
+		fmt.Fprintf(d.output(), "  Linked %d unchanged files to the previous snapshot\n", linked)
 	}
 
 	// Create .bulletproof directory within snapshot for self-contained structure
@@ -129,40 +178,282 @@ func (d *LocalDestination) Save(sourcePath string, snapshot *types.Snapshot, mes
 		return fmt.Errorf("failed to update index: %w", err)
 	}
 
-	fmt.Printf("  Backup saved to: %s\n", targetPath)
+	fmt.Fprintf(d.output(), "  Backup saved to: %s\n", targetPath)
 	return nil
 }
 
-func (d *LocalDestination) clearExistingFiles(targetPath string) error {
-	entries, err := os.ReadDir(targetPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
+// PrepareSave records how a snapshot about to be saved stores its files,
+// following this destination's compression and encryption, and unlocks the
+// key. Manifest-only snapshots store no files, so they record neither.
+func (d *LocalDestination) PrepareSave(snapshot *types.Snapshot) error {
+	snapshot.Encrypted = d.Encryption != nil && !snapshot.ManifestOnly
+	snapshot.Compression = ""
+	if !snapshot.ManifestOnly {
+		snapshot.Compression = d.Compression
+	}
+	if !snapshot.Encrypted {
+		return nil
+	}
+	if err := d.Encryption.Unlock(d.metadataPath(), true); err != nil {
+		return fmt.Errorf("failed to set up encryption: %w", err)
+	}
+	return nil
+}
+
+// StoreFile writes a source file into the snapshot being saved at destFile,
+// compressing and encrypting it as the snapshot records
+func (d *LocalDestination) StoreFile(snapshot *types.Snapshot, sourceFile, destFile string) error {
+	if snapshot.StoredVerbatim() {
+		return utils.CopyFile(sourceFile, destFile)
+	}
+
+	var encode transform
+	if snapshot.Compression != "" {
+		compress, err := compressor(snapshot.Compression)
+		if err != nil {
+			return err
 		}
-		return err
+		encode = compress
 	}
+	if snapshot.Encrypted {
+		if encode == nil {
+			encode = d.Encryption.encrypt
+		} else {
+			encode = chain(encode, d.Encryption.encrypt)
+		}
+	}
+	return transformFile(sourceFile, destFile+compressedExt(snapshot.Compression), encode)
+}
 
-	for _, entry := range entries {
-		if entry.Name() == ".bulletproof" {
+// decoder returns the transform that turns a stored file of snapshot back
+// into the original: decrypting, then decompressing
+func (d *LocalDestination) decoder(snapshot *types.Snapshot) (transform, error) {
+	var decode transform
+	if snapshot.Encrypted {
+		decode = d.Encryption.decrypt
+	}
+	if snapshot.Compression != "" {
+		decompress, err := decompressor(snapshot.Compression)
+		if err != nil {
+			return nil, err
+		}
+		if decode == nil {
+			decode = decompress
+		} else {
+			decode = chain(decode, decompress)
+		}
+	}
+	return decode, nil
+}
+
+// storedSize returns the size of file as stored in snapshot, or -1 when it
+// depends on how well the content compresses
+func storedSize(snapshot *types.Snapshot, file *types.FileSnapshot) int64 {
+	switch {
+	case snapshot.Compression != "":
+		return -1
+	case snapshot.Encrypted:
+		return encryptedSize(file.Size)
+	default:
+		return file.Size
+	}
+}
+
+// unchangedFiles maps the content hashes of the previous snapshot's stored
+// files to their paths, so a deduplicating backup can link files it already
+// holds. It is empty unless deduplication applies.
+func (d *LocalDestination) unchangedFiles(snapshot *types.Snapshot) map[string]string {
+	if !d.Deduplicate || !d.Timestamped || snapshot.ManifestOnly {
+		return nil
+	}
+	previous, err := d.GetLastSnapshot()
+	if err != nil || previous == nil || previous.ID == snapshot.ID || previous.ManifestOnly {
+		return nil
+	}
+	// Stored copies of a file differ between compression methods and encryption
+	if previous.Encrypted != snapshot.Encrypted || previous.Compression != snapshot.Compression {
+		return nil
+	}
+
+	paths := make(map[string]string, len(previous.Files))
+	for filePath, file := range previous.Files {
+		paths[file.Hash] = filepath.Join(d.snapshotPath(previous.ID), filePath+compressedExt(previous.Compression))
+	}
+	return paths
+}
+
+// linkFile hardlinks a previous snapshot's copy of a file to destFile. It
+// reports false, leaving the caller to copy, when the previous copy does not
+// have the expected stored size (unless it is -1) and mode or the filesystem
+// cannot link it, e.g. across devices.
+func linkFile(previous, destFile string, size int64, mode os.FileMode) bool {
+	info, err := os.Stat(previous)
+	if err != nil || (size >= 0 && info.Size() != size) || (mode != 0 && info.Mode().Perm() != mode.Perm()) {
+		return false
+	}
+	if err := os.MkdirAll(filepath.Dir(destFile), 0755); err != nil {
+		return false
+	}
+	return os.Link(previous, destFile) == nil
+}
+
+// syncedFiles returns the files of a snapshot about to be saved in sync mode
+// whose stored copy from the previous snapshot already holds the same
+// content, stored the same way, so they can be left alone
+func (d *LocalDestination) syncedFiles(snapshot *types.Snapshot) map[string]bool {
+	if d.Timestamped || snapshot.ManifestOnly {
+		return nil
+	}
+	previous, err := d.GetLastSnapshot()
+	if err != nil || previous == nil || previous.ID == snapshot.ID || previous.ManifestOnly {
+		return nil
+	}
+	if previous.Encrypted != snapshot.Encrypted || previous.Compression != snapshot.Compression {
+		return nil
+	}
+
+	synced := make(map[string]bool)
+	for filePath, file := range snapshot.Files {
+		if old, ok := previous.Files[filePath]; !ok || old.Hash != file.Hash {
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(d.BasePath, filePath+compressedExt(snapshot.Compression)))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if size := storedSize(snapshot, file); size >= 0 && info.Size() != size {
 			continue
 		}
+		if file.Mode != 0 && info.Mode().Perm() != file.Mode.Perm() {
+			continue
+		}
+		synced[filePath] = true
+	}
+	return synced
+}
+
+// removeStaleFiles deletes everything in a sync folder, apart from its
+// .bulletproof metadata, that is not a stored file of the snapshot about to
+// be saved there, along with the folders this leaves empty. Files the
+// snapshot keeps are overwritten in place or left alone.
+func removeStaleFiles(targetPath string, snapshot *types.Snapshot) error {
+	stored := make(map[string]bool, len(snapshot.Files))
+	if !snapshot.ManifestOnly {
+		for filePath := range snapshot.Files {
+			stored[filePath+compressedExt(snapshot.Compression)] = true
+		}
+	}
 
-		path := filepath.Join(targetPath, entry.Name())
-		if err := os.RemoveAll(path); err != nil {
+	var dirs []string
+	err := filepath.WalkDir(targetPath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if path == targetPath {
+			return nil
+		}
+		rel, err := filepath.Rel(targetPath, path)
+		if err != nil {
+			return err
+		}
+		if rel == ".bulletproof" {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		if stored[rel] {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
 			return fmt.Errorf("failed to remove %s: %w", path, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	// Deepest first, so parents empty out as their children go
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+			if err := os.Remove(dir); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", dir, err)
+			}
+		}
+	}
 	return nil
 }
 
+// removeFilesNotInSnapshot deletes the agent files in targetPath, openclaw.json
+// and the workspace, that a snapshot about to be downloaded there lacks
+func removeFilesNotInSnapshot(targetPath string, snapshot *types.Snapshot) error {
+	return filepath.Walk(targetPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil // Skip errors on walk
+		}
+
+		relativePath, err := filepath.Rel(targetPath, path)
+		if err != nil {
+			return nil
+		}
+
+		// Keep OpenClaw config files
+		if relativePath == "openclaw.json" || strings.HasPrefix(relativePath, "workspace") {
+			if _, ok := snapshot.Files[relativePath]; !ok {
+				if err := os.Remove(path); err != nil {
+					return fmt.Errorf("failed to remove file %s: %w", relativePath, err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// writeFileFrom writes everything r holds to a local file, creating its folder
+func writeFileFrom(filePath string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
 func (d *LocalDestination) updateIndex(snapshot *types.Snapshot, message string) error {
 	indexFile := filepath.Join(d.metadataPath(), "index.json")
 
-	var index []map[string]interface{}
+	// A missing or unreadable index starts fresh
+	data, _ := os.ReadFile(indexFile)
+	indexJSON, err := prependIndexEntry(data, snapshot, message)
+	if err != nil {
+		return err
+	}
 
-	// Read existing index
-	if data, err := os.ReadFile(indexFile); err == nil {
+	if err := os.WriteFile(indexFile, indexJSON, 0644); err != nil {
+		return fmt.Errorf("failed to write index file: %w", err)
+	}
+
+	return nil
+}
+
+// prependIndexEntry adds a snapshot to the front of an index.json document,
+// keeping the last 100 entries. Unparseable data starts a fresh index.
+func prependIndexEntry(data []byte, snapshot *types.Snapshot, message string) ([]byte, error) {
+	var index []map[string]interface{}
+	if len(data) > 0 {
 		if err := json.Unmarshal(data, &index); err != nil {
 			// Ignore parse errors, start fresh
 			index = []map[string]interface{}{}
@@ -175,6 +466,13 @@ func (d *LocalDestination) updateIndex(snapshot *types.Snapshot, message string)
 		"timestamp": snapshot.Timestamp,
 		"message":   message,
 		"fileCount": len(snapshot.Files),
+		"sizeBytes": snapshot.TotalSize(),
+	}
+	if len(snapshot.Labels) > 0 {
+		newEntry["labels"] = snapshot.Labels
+	}
+	if snapshot.ManifestOnly {
+		newEntry["manifestOnly"] = true
 	}
 	index = append([]map[string]interface{}{newEntry}, index...)
 
@@ -183,20 +481,64 @@ func (d *LocalDestination) updateIndex(snapshot *types.Snapshot, message string)
 		index = index[:100]
 	}
 
-	// Write index
 	indexJSON, err := json.MarshalIndent(index, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal index: %w", err)
+		return nil, fmt.Errorf("failed to marshal index: %w", err)
 	}
+	return indexJSON, nil
+}
 
-	if err := os.WriteFile(indexFile, indexJSON, 0644); err != nil {
-		return fmt.Errorf("failed to write index file: %w", err)
+// removeIndexEntry drops a snapshot from an index.json document. The
+// remaining entries keep their order, newest first.
+func removeIndexEntry(data []byte, id string) ([]byte, error) {
+	var index []map[string]interface{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal index: %w", err)
 	}
 
-	return nil
+	kept := make([]map[string]interface{}, 0, len(index))
+	for _, entry := range index {
+		if entryID, _ := entry["id"].(string); entryID != id {
+			kept = append(kept, entry)
+		}
+	}
+
+	indexJSON, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal index: %w", err)
+	}
+	return indexJSON, nil
+}
+
+// setIndexLabels replaces the labels of a snapshot's entry in an index.json
+// document. Other entries are left as they are.
+func setIndexLabels(data []byte, id string, labels []string) ([]byte, error) {
+	var index []map[string]interface{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal index: %w", err)
+	}
+
+	for _, entry := range index {
+		if entryID, _ := entry["id"].(string); entryID != id {
+			continue
+		}
+		if len(labels) > 0 {
+			entry["labels"] = labels
+		} else {
+			delete(entry, "labels")
+		}
+	}
+
+	indexJSON, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal index: %w", err)
+	}
+	return indexJSON, nil
 }
 
-// GetLastSnapshot returns the most recent snapshot
+// GetLastSnapshot returns the most recent snapshot.
+// If the latest pointer refers to a snapshot folder that no longer exists, it
+// falls back to the newest snapshot still present and repairs the pointer.
 func (d *LocalDestination) GetLastSnapshot() (*types.Snapshot, error) {
 	latestFile := filepath.Join(d.metadataPath(), "latest")
 	data, err := os.ReadFile(latestFile)
@@ -208,7 +550,81 @@ func (d *LocalDestination) GetLastSnapshot() (*types.Snapshot, error) {
 	}
 
 	latestID := strings.TrimSpace(string(data))
-	return d.GetSnapshot(latestID)
+	if !d.Timestamped {
+		return d.GetSnapshot(latestID)
+	}
+
+	if info, err := os.Stat(d.snapshotPath(latestID)); err == nil && info.IsDir() {
+		snapshot, err := d.GetSnapshot(latestID)
+		if err != nil || snapshot != nil {
+			return snapshot, err
+		}
+	}
+
+	return d.repairLatest(latestID)
+}
+
+// repairLatest points the latest file at the newest snapshot still present
+// after the snapshot it named went missing
+func (d *LocalDestination) repairLatest(staleID string) (*types.Snapshot, error) {
+	latestFile := filepath.Join(d.metadataPath(), "latest")
+
+	newest, err := d.newestPresentSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	if newest == nil {
+		fmt.Fprintf(d.output(), "⚠️  Latest snapshot %s no longer exists and no other snapshots remain; clearing the latest pointer\n", staleID)
+		if err := os.Remove(latestFile); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(d.output(), "⚠️  Warning: failed to clear latest pointer: %v\n", err)
+		}
+		return nil, nil
+	}
+
+	fmt.Fprintf(d.output(), "⚠️  Latest snapshot %s no longer exists; repaired latest pointer to %s\n", staleID, newest.ID)
+	if err := os.WriteFile(latestFile, []byte(newest.ID), 0644); err != nil {
+		// The fallback is still correct; the repair is retried on the next read
+		fmt.Fprintf(d.output(), "⚠️  Warning: failed to repair latest pointer: %v\n", err)
+	}
+	return newest, nil
+}
+
+// newestPresentSnapshot returns the newest snapshot whose folder still exists,
+// or nil if there is none. Snapshot IDs are timestamps, so they sort by age.
+func (d *LocalDestination) newestPresentSnapshot() (*types.Snapshot, error) {
+	entries, err := os.ReadDir(d.BasePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	for _, id := range ids {
+		if snapshot, err := d.GetSnapshot(id); err == nil && snapshot != nil {
+			return snapshot, nil
+		}
+
+		// Fall back to the copy of the metadata kept inside the snapshot folder
+		data, err := os.ReadFile(filepath.Join(d.snapshotPath(id), ".bulletproof", "snapshot.json"))
+		if err != nil {
+			continue
+		}
+		if snapshot, err := types.FromJSON(data); err == nil && snapshot.ID == id {
+			return snapshot, nil
+		}
+	}
+
+	return nil, nil
 }
 
 // GetSnapshot returns a specific snapshot by ID
@@ -241,6 +657,30 @@ func (d *LocalDestination) ListSnapshots() ([]*types.SnapshotInfo, error) {
 		return nil, fmt.Errorf("failed to read index file: %w", err)
 	}
 
+	snapshots, err := parseIndex(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older index entries have no size; add it up from the snapshot's manifest
+	for _, info := range snapshots {
+		if info.SizeBytes >= 0 {
+			continue
+		}
+		snapshot, err := d.GetSnapshot(info.ID)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			info.SizeBytes = snapshot.TotalSize()
+		}
+	}
+
+	return snapshots, nil
+}
+
+// parseIndex reads the snapshot listing from an index.json document
+func parseIndex(data []byte) ([]*types.SnapshotInfo, error) {
 	var index []map[string]interface{}
 	if err := json.Unmarshal(data, &index); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal index: %w", err)
@@ -252,17 +692,35 @@ func (d *LocalDestination) ListSnapshots() ([]*types.SnapshotInfo, error) {
 		timestamp, _ := entry["timestamp"].(string)
 		message, _ := entry["message"].(string)
 		fileCount, _ := entry["fileCount"].(float64)
+		sizeBytes, hasSize := entry["sizeBytes"].(float64)
+		rawLabels, _ := entry["labels"].([]interface{})
+		manifestOnly, _ := entry["manifestOnly"].(bool)
 
 		parsedTimestamp, err := parseTimestamp(timestamp)
 		if err != nil {
 			return nil, err
 		}
 
+		// Entries written before sizes were recorded leave the size unknown
+		if !hasSize {
+			sizeBytes = -1
+		}
+
+		var labels []string
+		for _, rawLabel := range rawLabels {
+			if label, ok := rawLabel.(string); ok {
+				labels = append(labels, label)
+			}
+		}
+
 		snapshots = append(snapshots, &types.SnapshotInfo{
-			ID:        id,
-			Timestamp: parsedTimestamp,
-			Message:   message,
-			FileCount: int(fileCount),
+			ID:           id,
+			Timestamp:    parsedTimestamp,
+			Message:      message,
+			FileCount:    int(fileCount),
+			SizeBytes:    int64(sizeBytes),
+			Labels:       labels,
+			ManifestOnly: manifestOnly,
 		})
 	}
 
@@ -289,9 +747,25 @@ func (d *LocalDestination) Restore(snapshotID string, targetPath string) error {
 		return fmt.Errorf("snapshot not found: %s", snapshotID)
 	}
 
+	// Check the key before touching the target
+	snapshot, err := d.transformedSnapshot(snapshotID)
+	if err != nil {
+		return err
+	}
+	manifest, err := d.GetSnapshot(snapshotID)
+	if err != nil {
+		return err
+	}
+	var decode transform
+	if snapshot != nil {
+		if decode, err = d.decoder(snapshot); err != nil {
+			return err
+		}
+	}
+
 	// First, collect all files that should exist after restore
 	snapshotFiles := make(map[string]bool)
-	err := filepath.Walk(snapshotPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(snapshotPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -310,7 +784,8 @@ func (d *LocalDestination) Restore(snapshotID string, targetPath string) error {
 			return nil
 		}
 
-		snapshotFiles[relativePath] = true
+		originalPath, _ := originalPath(snapshot, relativePath)
+		snapshotFiles[originalPath] = true
 		return nil
 	})
 	if err != nil {
@@ -349,6 +824,8 @@ func (d *LocalDestination) Restore(snapshotID string, targetPath string) error {
 	}
 
 	// Now copy all files from snapshot to target
+	done := 0
+	progress := d.startProgress("Restored")
 	return filepath.Walk(snapshotPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -369,12 +846,25 @@ func (d *LocalDestination) Restore(snapshotID string, targetPath string) error {
 			return nil
 		}
 
-		// Copy file
-		targetFile := filepath.Join(targetPath, relativePath)
-		if err := utils.CopyFile(path, targetFile); err != nil {
+		// Copy file, turning the ones the manifest lists back into the original
+		originalPath, transformed := originalPath(snapshot, relativePath)
+		targetFile := filepath.Join(targetPath, originalPath)
+		if transformed {
+			if err := transformFile(path, targetFile, decode); err != nil {
+				return fmt.Errorf("failed to restore file %s: %w", originalPath, err)
+			}
+		} else if err := utils.CopyFile(path, targetFile); err != nil {
 			return fmt.Errorf("failed to copy file %s: %w", relativePath, err)
 		}
+		if err := manifest.RestoreMode(targetPath, originalPath); err != nil {
+			return fmt.Errorf("failed to restore mode of %s: %w", originalPath, err)
+		}
+		if err := utils.MatchPathCase(targetPath, originalPath); err != nil {
+			return fmt.Errorf("failed to restore name of %s: %w", relativePath, err)
+		}
 
+		done++
+		progress(done, len(snapshotFiles), originalPath)
 		return nil
 	})
 }
@@ -387,7 +877,123 @@ func (d *LocalDestination) GetSnapshotPath(id string) string {
 	return d.BasePath
 }
 
-// DeleteSnapshot deletes a snapshot by ID
+// ReadSnapshotFile reads one stored file of a snapshot. Non-timestamped
+// destinations only hold the latest snapshot's files.
+func (d *LocalDestination) ReadSnapshotFile(snapshotID, path string) ([]byte, error) {
+	if !d.Timestamped {
+		latest, err := os.ReadFile(filepath.Join(d.metadataPath(), "latest"))
+		if err != nil || strings.TrimSpace(string(latest)) != snapshotID {
+			return nil, fmt.Errorf("files of snapshot %s are no longer stored", snapshotID)
+		}
+	}
+
+	snapshot, err := d.transformedSnapshot(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return os.ReadFile(filepath.Join(d.GetSnapshotPath(snapshotID), path))
+	}
+
+	decode, err := d.decoder(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(d.GetSnapshotPath(snapshotID), path+compressedExt(snapshot.Compression)))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var content bytes.Buffer
+	if err := decode(&content, file); err != nil {
+		return nil, err
+	}
+	return content.Bytes(), nil
+}
+
+// originalPath returns the manifest path of a file stored in snapshot, and
+// whether it is stored compressed or encrypted. Files the manifest does not
+// list, such as script exports, are stored as is.
+func originalPath(snapshot *types.Snapshot, storedPath string) (string, bool) {
+	if snapshot == nil || snapshot.StoredVerbatim() {
+		return storedPath, false
+	}
+	path := strings.TrimSuffix(storedPath, compressedExt(snapshot.Compression))
+	if snapshot.Files[path] == nil {
+		return storedPath, false
+	}
+	return path, true
+}
+
+// transformedSnapshot returns the snapshot if its files are stored compressed
+// or encrypted, with the key unlocked to read them, or nil if they are stored
+// as is
+func (d *LocalDestination) transformedSnapshot(snapshotID string) (*types.Snapshot, error) {
+	snapshot, err := d.GetSnapshot(snapshotID)
+	if err != nil || snapshot == nil || snapshot.StoredVerbatim() {
+		return nil, err
+	}
+	if !snapshot.Encrypted {
+		return snapshot, nil
+	}
+	if d.Encryption == nil {
+		return nil, fmt.Errorf("snapshot %s is encrypted: enable options.encryption with its passphrase or key file to read it", snapshotID)
+	}
+	if err := d.Encryption.Unlock(d.metadataPath(), false); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// SetLabels replaces the labels of a stored snapshot in its metadata, the copy
+// kept in its folder, and its index entry
+func (d *LocalDestination) SetLabels(id string, labels []string) error {
+	snapshot, err := d.GetSnapshot(id)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("snapshot not found: %s", id)
+	}
+	snapshot.Labels = labels
+
+	snapshotJSON, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.metadataPath(), id+".json"), snapshotJSON, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if d.Timestamped {
+		ownCopy := filepath.Join(d.snapshotPath(id), ".bulletproof", "snapshot.json")
+		if _, err := os.Stat(ownCopy); err == nil {
+			if err := os.WriteFile(ownCopy, snapshotJSON, 0644); err != nil {
+				return fmt.Errorf("failed to write snapshot file: %w", err)
+			}
+		}
+	}
+
+	indexFile := filepath.Join(d.metadataPath(), "index.json")
+	data, err := os.ReadFile(indexFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	indexJSON, err := setIndexLabels(data, id, labels)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(indexFile, indexJSON, 0644); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	return nil
+}
+
+// DeleteSnapshot deletes a snapshot by ID: its folder, its metadata and its
+// index entry. If it was the latest snapshot, the latest pointer moves to the
+// newest one left.
 func (d *LocalDestination) DeleteSnapshot(id string) error {
 	if !d.Timestamped {
 		return fmt.Errorf("cannot delete snapshots in sync mode (non-timestamped destination)")
@@ -402,5 +1008,41 @@ func (d *LocalDestination) DeleteSnapshot(id string) error {
 		return fmt.Errorf("failed to delete snapshot directory: %w", err)
 	}
 
+	metaDir := d.metadataPath()
+	if err := os.Remove(filepath.Join(metaDir, id+".json")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete snapshot metadata: %w", err)
+	}
+
+	indexFile := filepath.Join(metaDir, "index.json")
+	if data, err := os.ReadFile(indexFile); err == nil {
+		indexJSON, err := removeIndexEntry(data, id)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(indexFile, indexJSON, 0644); err != nil {
+			return fmt.Errorf("failed to write index file: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read index file: %w", err)
+	}
+
+	latestFile := filepath.Join(metaDir, "latest")
+	latest, err := os.ReadFile(latestFile)
+	if err != nil || strings.TrimSpace(string(latest)) != id {
+		return nil
+	}
+	newest, err := d.newestPresentSnapshot()
+	if err != nil {
+		return err
+	}
+	if newest == nil {
+		if err := os.Remove(latestFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear latest file: %w", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(latestFile, []byte(newest.ID), 0644); err != nil {
+		return fmt.Errorf("failed to write latest file: %w", err)
+	}
 	return nil
 }
@@ -1,8 +1,13 @@
 package destinations
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/bulletproof-bot/backup/internal/types"
 )
 
 func TestParseTimestamp_Valid(t *testing.T) {
@@ -63,3 +68,250 @@ func TestParseTimestamp_Valid(t *testing.T) {
 		})
 	}
 }
+
+func TestLocalDestination_DeduplicateLinksUnchangedFiles(t *testing.T) {
+	source := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(source, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save := func(dest *LocalDestination, timestamp time.Time) *types.Snapshot {
+		t.Helper()
+		snapshot, err := types.FromDirectoryWithTimestamp(source, nil, "", timestamp)
+		if err != nil {
+			t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+		}
+		if err := dest.Save(source, snapshot, ""); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		return snapshot
+	}
+	sameFile := func(a, b string) bool {
+		t.Helper()
+		infoA, errA := os.Stat(a)
+		infoB, errB := os.Stat(b)
+		if errA != nil || errB != nil {
+			t.Fatalf("stat failed: %v, %v", errA, errB)
+		}
+		return os.SameFile(infoA, infoB)
+	}
+
+	write("workspace/SOUL.md", "# Soul\n")
+	write("workspace/memory.json", `{"v":1}`)
+
+	dest := NewLocalDestination(t.TempDir(), true)
+	dest.Deduplicate = true
+	first := save(dest, time.Now())
+
+	write("workspace/memory.json", `{"v":2}`)
+	second := save(dest, time.Now().Add(time.Second))
+
+	firstDir, secondDir := dest.snapshotPath(first.ID), dest.snapshotPath(second.ID)
+	if !sameFile(filepath.Join(firstDir, "workspace/SOUL.md"), filepath.Join(secondDir, "workspace/SOUL.md")) {
+		t.Error("expected the unchanged file to share an inode with the previous snapshot")
+	}
+	if sameFile(filepath.Join(firstDir, "workspace/memory.json"), filepath.Join(secondDir, "workspace/memory.json")) {
+		t.Error("expected the changed file to be copied")
+	}
+
+	// Both snapshots still restore their own contents
+	for _, snapshot := range []*types.Snapshot{first, second} {
+		target := t.TempDir()
+		if err := dest.Restore(snapshot.ID, target); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+		restored, err := types.FromDirectoryWithTimestamp(target, nil, "", time.Now())
+		if err != nil {
+			t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+		}
+		if diff := snapshot.Diff(restored); !diff.IsEmpty() {
+			t.Errorf("restore of %s differs from its snapshot: %+v", snapshot.ID, diff)
+		}
+	}
+
+	// Deleting the older snapshot leaves the newer one's linked copy intact
+	if err := dest.DeleteSnapshot(first.ID); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(secondDir, "workspace/SOUL.md")); err != nil || string(data) != "# Soul\n" {
+		t.Errorf("expected the linked file to survive, got %q (%v)", data, err)
+	}
+
+	// Without deduplication every file is copied
+	plain := NewLocalDestination(t.TempDir(), true)
+	a := save(plain, time.Now())
+	b := save(plain, time.Now().Add(time.Second))
+	if sameFile(filepath.Join(plain.snapshotPath(a.ID), "workspace/SOUL.md"), filepath.Join(plain.snapshotPath(b.ID), "workspace/SOUL.md")) {
+		t.Error("expected no links without Deduplicate")
+	}
+}
+
+func TestSyncDestination_RewritesOnlyChangedFiles(t *testing.T) {
+	source := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dest := NewSyncDestination(t.TempDir())
+	save := func(timestamp time.Time) *types.Snapshot {
+		t.Helper()
+		snapshot, err := types.FromDirectoryWithTimestamp(source, nil, "", timestamp)
+		if err != nil {
+			t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+		}
+		if err := dest.Save(source, snapshot, ""); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		return snapshot
+	}
+
+	write("workspace/SOUL.md", "# Soul\n")
+	write("workspace/memory.json", `{"v":1}`)
+	write("workspace/skills/old/SKILL.md", "old skill")
+	save(time.Now())
+
+	// Age the stored copies, so any rewrite shows in their modification time
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, name := range []string{"workspace/SOUL.md", "workspace/memory.json"} {
+		if err := os.Chtimes(filepath.Join(dest.BasePath, filepath.FromSlash(name)), past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("workspace/memory.json", `{"v":2}`)
+	write("workspace/skills/new/SKILL.md", "new skill")
+	if err := os.RemoveAll(filepath.Join(source, "workspace", "skills", "old")); err != nil {
+		t.Fatal(err)
+	}
+	second := save(time.Now().Add(time.Second))
+
+	info, err := os.Stat(filepath.Join(dest.BasePath, "workspace", "SOUL.md"))
+	if err != nil || !info.ModTime().Equal(past) {
+		t.Errorf("expected the unchanged file to be left alone, got %v (%v)", info, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest.BasePath, "workspace", "memory.json")); string(data) != `{"v":2}` {
+		t.Errorf("expected the modified file to be rewritten, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dest.BasePath, "workspace", "skills", "old")); !os.IsNotExist(err) {
+		t.Errorf("expected the removed skill folder to be deleted, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest.BasePath, ".bulletproof", "latest")); err != nil {
+		t.Errorf("expected the metadata to be kept: %v", err)
+	}
+
+	target := t.TempDir()
+	if err := dest.Restore(second.ID, target); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	restored, err := types.FromDirectoryWithTimestamp(target, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+	}
+	if diff := second.Diff(restored); !diff.IsEmpty() {
+		t.Errorf("restore differs from the snapshot: %+v", diff)
+	}
+}
+
+func TestLocalDestination_ListSnapshotsReportsSize(t *testing.T) {
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "SOUL.md"), []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "TOOLS.md"), []byte("01234"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := types.FromDirectoryWithTimestamp(source, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+	}
+	dest := NewLocalDestination(t.TempDir(), true)
+	if err := dest.Save(source, snapshot, ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	infos, err := dest.ListSnapshots()
+	if err != nil || len(infos) != 1 || infos[0].SizeBytes != 15 {
+		t.Fatalf("expected one snapshot of 15 bytes, got %+v (%v)", infos, err)
+	}
+
+	// Index entries written before sizes were recorded are filled in from the manifest
+	indexFile := filepath.Join(dest.metadataPath(), "index.json")
+	data, err := os.ReadFile(indexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index []map[string]interface{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	delete(index[0], "sizeBytes")
+	if data, err = json.Marshal(index); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(indexFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	infos, err = dest.ListSnapshots()
+	if err != nil || len(infos) != 1 || infos[0].SizeBytes != 15 {
+		t.Errorf("expected the size to be backfilled, got %+v (%v)", infos, err)
+	}
+}
+
+func TestLocalDestination_DeleteSnapshot(t *testing.T) {
+	source := t.TempDir()
+	dest := NewLocalDestination(t.TempDir(), true)
+	timestamp := time.Now()
+	var snapshots []*types.Snapshot
+	for _, content := range []string{"first", "second"} {
+		if err := os.WriteFile(filepath.Join(source, "SOUL.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		timestamp = timestamp.Add(time.Second)
+		snapshot, err := types.FromDirectoryWithTimestamp(source, nil, content, timestamp)
+		if err != nil {
+			t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+		}
+		if err := dest.Save(source, snapshot, content); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	// Deleting the latest snapshot moves the latest pointer back
+	if err := dest.DeleteSnapshot(snapshots[1].ID); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	if snapshot, err := dest.GetSnapshot(snapshots[1].ID); err != nil || snapshot != nil {
+		t.Errorf("expected the deleted snapshot's metadata to be gone, got %v (%v)", snapshot, err)
+	}
+	infos, err := dest.ListSnapshots()
+	if err != nil || len(infos) != 1 || infos[0].ID != snapshots[0].ID {
+		t.Errorf("expected only %s listed, got %+v (%v)", snapshots[0].ID, infos, err)
+	}
+	latest, err := os.ReadFile(filepath.Join(dest.metadataPath(), "latest"))
+	if err != nil || string(latest) != snapshots[0].ID {
+		t.Errorf("expected latest to point at %s, got %q (%v)", snapshots[0].ID, latest, err)
+	}
+
+	if err := dest.DeleteSnapshot(snapshots[0].ID); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	if last, err := dest.GetLastSnapshot(); err != nil || last != nil {
+		t.Errorf("expected no snapshots left, got %v (%v)", last, err)
+	}
+	if err := dest.DeleteSnapshot(snapshots[0].ID); err == nil {
+		t.Error("expected deleting a missing snapshot to fail")
+	}
+}
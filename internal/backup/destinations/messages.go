@@ -0,0 +1,48 @@
+package destinations
+
+import (
+	"io"
+
+	"github.com/bulletproof-bot/backup/internal/log"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// messages sends a destination's progress messages and warnings to a writer,
+// stdout unless one was set or --quiet silenced it, and reports progress
+// through the files it copies
+type messages struct {
+	out      io.Writer
+	progress types.ProgressFunc
+}
+
+// SetOutput sends the destination's messages to w instead of stdout
+func (m *messages) SetOutput(w io.Writer) {
+	m.out = w
+}
+
+// SetProgress reports progress through the files of saves and restores to
+// progress instead of printing a counter
+func (m *messages) SetProgress(progress types.ProgressFunc) {
+	m.progress = progress
+}
+
+// output returns where messages go
+func (m *messages) output() io.Writer {
+	if m.out == nil {
+		return log.Writer()
+	}
+	return m.out
+}
+
+// startProgress returns the progress reporter for one save or restore: the
+// one set, or a counter printed to the output with verb, e.g. "Copied", which
+// --verbose turns into a line per file
+func (m *messages) startProgress(verb string) types.ProgressFunc {
+	if m.progress != nil {
+		return m.progress
+	}
+	if log.Verbose() {
+		return types.FilePrinter(m.output(), verb)
+	}
+	return types.ProgressPrinter(m.output(), verb)
+}
@@ -0,0 +1,355 @@
+package destinations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// S3Destination stores backups in an S3 bucket, laid out like a timestamped
+// LocalDestination: each snapshot's files under <prefix>/<id>/, and the
+// metadata, latest pointer and index under <prefix>/.bulletproof/.
+type S3Destination struct {
+	URL    string
+	Bucket string
+	Prefix string
+
+	client *s3Client
+
+	messages
+}
+
+// NewS3Destination creates a destination for an s3://bucket/prefix URL
+func NewS3Destination(location string) (*S3Destination, error) {
+	rest, ok := strings.CutPrefix(location, "s3://")
+	if !ok {
+		return nil, fmt.Errorf("invalid S3 location %q: expected s3://bucket/prefix", location)
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid S3 location %q: no bucket", location)
+	}
+
+	return &S3Destination{
+		URL:    location,
+		Bucket: bucket,
+		Prefix: strings.Trim(prefix, "/"),
+	}, nil
+}
+
+// key returns the object key of a slash-separated path below the prefix
+func (d *S3Destination) key(parts ...string) string {
+	return path.Join(append([]string{d.Prefix}, parts...)...)
+}
+
+func (d *S3Destination) metadataKey(name string) string {
+	return d.key(".bulletproof", name)
+}
+
+// Validate sets up the S3 client from the AWS environment
+func (d *S3Destination) Validate() error {
+	if d.client != nil {
+		return nil
+	}
+	client, err := newS3Client(d.Bucket)
+	if err != nil {
+		return err
+	}
+	d.client = client
+	return nil
+}
+
+// CheckRemote checks that the bucket can be listed with the configured credentials
+func (d *S3Destination) CheckRemote() error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	if _, err := d.client.listObjects(d.key(".bulletproof")+"/", 1); err != nil {
+		return fmt.Errorf("cannot access %s: %w", d.URL, err)
+	}
+	return nil
+}
+
+// Save uploads a backup to the bucket
+func (d *S3Destination) Save(sourcePath string, snapshot *types.Snapshot, message string) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	// Upload files, unless only the manifest is kept
+	files := snapshot.Files
+	if snapshot.ManifestOnly {
+		files = nil
+		fmt.Fprintf(d.output(), "  Recording manifest of %d files...\n", len(snapshot.Files))
+	} else {
+		fmt.Fprintf(d.output(), "  Uploading %d files...\n", len(snapshot.Files))
+	}
+	done := 0
+	progress := d.startProgress("Uploaded")
+	for filePath := range files {
+		key := d.key(snapshot.ID, filepath.ToSlash(filePath))
+		if err := d.client.putFile(key, filepath.Join(sourcePath, filePath)); err != nil {
+			return fmt.Errorf("failed to upload file %s: %w", filePath, err)
+		}
+		done++
+		progress(done, len(files), filePath)
+	}
+
+	snapshotJSON, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	// Keep the snapshot folder self-contained, as local destinations do
+	if err := d.client.putObject(d.key(snapshot.ID, ".bulletproof", "snapshot.json"), snapshotJSON); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+
+	// Also save metadata in central location for quick lookups
+	if err := d.client.putObject(d.metadataKey(snapshot.ID+".json"), snapshotJSON); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := d.client.putObject(d.metadataKey("latest"), []byte(snapshot.ID)); err != nil {
+		return fmt.Errorf("failed to write latest file: %w", err)
+	}
+
+	// Update index
+	data, err := d.client.readObject(d.metadataKey("index.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	indexJSON, err := prependIndexEntry(data, snapshot, message)
+	if err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	if err := d.client.putObject(d.metadataKey("index.json"), indexJSON); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+
+	fmt.Fprintf(d.output(), "  Backup saved to: %s\n", d.GetSnapshotURL(snapshot.ID))
+	return nil
+}
+
+// GetLastSnapshot returns the most recent snapshot
+func (d *S3Destination) GetLastSnapshot() (*types.Snapshot, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	data, err := d.client.readObject(d.metadataKey("latest"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read latest file: %w", err)
+	}
+
+	return d.GetSnapshot(strings.TrimSpace(string(data)))
+}
+
+// GetSnapshot returns a specific snapshot by ID
+func (d *S3Destination) GetSnapshot(id string) (*types.Snapshot, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	data, err := d.client.readObject(d.metadataKey(id + ".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+
+	snapshot, err := types.FromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// ListSnapshots returns all available snapshots from the index, without
+// listing the bucket
+func (d *S3Destination) ListSnapshots() ([]*types.SnapshotInfo, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	data, err := d.client.readObject(d.metadataKey("index.json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*types.SnapshotInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to read index file: %w", err)
+	}
+
+	return parseIndex(data)
+}
+
+// Restore downloads the files of a snapshot to the target path
+func (d *S3Destination) Restore(snapshotID string, targetPath string) error {
+	snapshot, err := d.GetSnapshot(snapshotID)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+
+	if err := removeFilesNotInSnapshot(targetPath, snapshot); err != nil {
+		return fmt.Errorf("failed to clean target directory: %w", err)
+	}
+
+	// Now download all files of the snapshot
+	fmt.Fprintf(d.output(), "  Downloading %d files...\n", len(snapshot.Files))
+	done := 0
+	progress := d.startProgress("Downloaded")
+	for relativePath := range snapshot.Files {
+		if err := d.download(d.key(snapshotID, filepath.ToSlash(relativePath)), filepath.Join(targetPath, relativePath)); err != nil {
+			return fmt.Errorf("failed to download file %s: %w", relativePath, err)
+		}
+		if err := snapshot.RestoreMode(targetPath, relativePath); err != nil {
+			return fmt.Errorf("failed to restore mode of %s: %w", relativePath, err)
+		}
+		if err := utils.MatchPathCase(targetPath, relativePath); err != nil {
+			return fmt.Errorf("failed to restore name of %s: %w", relativePath, err)
+		}
+		done++
+		progress(done, len(snapshot.Files), relativePath)
+	}
+
+	return nil
+}
+
+// download writes the object at key to a local file
+func (d *S3Destination) download(key, filePath string) error {
+	body, err := d.client.getObject(key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return writeFileFrom(filePath, body)
+}
+
+// ReadSnapshotFile reads one stored file of a snapshot
+func (d *S3Destination) ReadSnapshotFile(snapshotID, path string) ([]byte, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d.client.readObject(d.key(snapshotID, filepath.ToSlash(path)))
+}
+
+// GetSnapshotPath returns empty string since S3 snapshots are not on the filesystem
+func (d *S3Destination) GetSnapshotPath(id string) string {
+	return ""
+}
+
+// GetSnapshotURL returns the s3:// URL of a snapshot's folder
+func (d *S3Destination) GetSnapshotURL(id string) string {
+	return "s3://" + d.Bucket + "/" + d.key(id) + "/"
+}
+
+// SetLabels replaces the labels of a stored snapshot in its metadata and its
+// index entry
+func (d *S3Destination) SetLabels(id string, labels []string) error {
+	snapshot, err := d.GetSnapshot(id)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("snapshot not found: %s", id)
+	}
+	snapshot.Labels = labels
+
+	snapshotJSON, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := d.client.putObject(d.metadataKey(id+".json"), snapshotJSON); err != nil {
+		return fmt.Errorf("failed to write snapshot metadata: %w", err)
+	}
+
+	data, err := d.client.readObject(d.metadataKey("index.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	indexJSON, err := setIndexLabels(data, id, labels)
+	if err != nil {
+		return err
+	}
+	if err := d.client.putObject(d.metadataKey("index.json"), indexJSON); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	return nil
+}
+
+// DeleteSnapshot deletes a snapshot by ID: its files, its metadata and its
+// index entry. If it was the latest snapshot, the latest pointer moves to the
+// newest one left in the index.
+func (d *S3Destination) DeleteSnapshot(id string) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	keys, err := d.client.listObjects(d.key(id)+"/", 0)
+	if err != nil {
+		return fmt.Errorf("failed to list snapshot files: %w", err)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("snapshot does not exist: %s", id)
+	}
+
+	for _, key := range keys {
+		if err := d.client.deleteObject(key); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+	if err := d.client.deleteObject(d.metadataKey(id + ".json")); err != nil {
+		return fmt.Errorf("failed to delete snapshot metadata: %w", err)
+	}
+
+	data, err := d.client.readObject(d.metadataKey("index.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	indexJSON, err := removeIndexEntry(data, id)
+	if err != nil {
+		return err
+	}
+	if err := d.client.putObject(d.metadataKey("index.json"), indexJSON); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+
+	latest, err := d.client.readObject(d.metadataKey("latest"))
+	if err != nil || strings.TrimSpace(string(latest)) != id {
+		return nil
+	}
+	remaining, err := parseIndex(indexJSON)
+	if err != nil {
+		return err
+	}
+	if len(remaining) == 0 {
+		if err := d.client.deleteObject(d.metadataKey("latest")); err != nil {
+			return fmt.Errorf("failed to clear latest file: %w", err)
+		}
+		return nil
+	}
+	if err := d.client.putObject(d.metadataKey("latest"), []byte(remaining[0].ID)); err != nil {
+		return fmt.Errorf("failed to write latest file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,436 @@
+package destinations
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unsignedPayload lets file uploads stream instead of being hashed up front;
+// TLS protects them in transit
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// s3Credentials is an AWS access key, optionally temporary
+type s3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// s3Client speaks the small part of the S3 REST API that backups need, signing
+// requests with AWS Signature Version 4
+type s3Client struct {
+	bucket   string
+	region   string
+	endpoint *url.URL // custom S3-compatible endpoint; nil = AWS
+	creds    s3Credentials
+	http     *http.Client
+}
+
+// newS3Client creates a client for bucket with credentials, region and endpoint
+// from the standard AWS environment: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and AWS_SESSION_TOKEN, else the shared credentials file for AWS_PROFILE;
+// AWS_REGION, AWS_DEFAULT_REGION or the shared config file; and
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL
+func newS3Client(bucket string) (*s3Client, error) {
+	creds, err := loadS3Credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &s3Client{
+		bucket: bucket,
+		region: loadS3Region(),
+		creds:  creds,
+		http:   &http.Client{Timeout: 5 * time.Minute},
+	}
+
+	for _, name := range []string{"AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"} {
+		if raw := os.Getenv(name); raw != "" {
+			endpoint, err := url.Parse(raw)
+			if err != nil || endpoint.Host == "" {
+				return nil, fmt.Errorf("invalid %s: %s", name, raw)
+			}
+			client.endpoint = endpoint
+			break
+		}
+	}
+
+	return client, nil
+}
+
+func awsProfile() string {
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+func awsSharedFile(envName, name string) string {
+	if path := os.Getenv(envName); path != "" {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".aws", name)
+}
+
+func loadS3Credentials() (s3Credentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return s3Credentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	profile := awsProfile()
+	values := readINISection(awsSharedFile("AWS_SHARED_CREDENTIALS_FILE", "credentials"), profile)
+	if values["aws_access_key_id"] != "" && values["aws_secret_access_key"] != "" {
+		return s3Credentials{
+			AccessKeyID:     values["aws_access_key_id"],
+			SecretAccessKey: values["aws_secret_access_key"],
+			SessionToken:    values["aws_session_token"],
+		}, nil
+	}
+
+	return s3Credentials{}, fmt.Errorf("no AWS credentials found: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or add profile %q to ~/.aws/credentials", profile)
+}
+
+func loadS3Region() string {
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(name); region != "" {
+			return region
+		}
+	}
+
+	// The shared config file names profiles other than default "profile <name>"
+	section := "default"
+	if profile := awsProfile(); profile != "default" {
+		section = "profile " + profile
+	}
+	if region := readINISection(awsSharedFile("AWS_CONFIG_FILE", "config"), section)["region"]; region != "" {
+		return region
+	}
+	return "us-east-1"
+}
+
+// readINISection returns the key/value pairs of one section of an AWS shared
+// config or credentials file, or nil if the file or section is missing
+func readINISection(path, section string) map[string]string {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var values map[string]string
+	inSection := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = strings.TrimSpace(line[1:len(line)-1]) == section
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && inSection {
+			if values == nil {
+				values = make(map[string]string)
+			}
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+// objectURL returns the URL of key in the bucket. AWS is addressed
+// virtual-hosted style; custom endpoints path style, which every
+// S3-compatible server supports.
+func (c *s3Client) objectURL(key string, query url.Values) *url.URL {
+	u := &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", c.bucket, c.region), Path: "/" + key}
+	if c.endpoint != nil {
+		u = &url.URL{Scheme: c.endpoint.Scheme, Host: c.endpoint.Host, Path: strings.TrimSuffix(c.endpoint.Path, "/") + "/" + c.bucket}
+		if key != "" {
+			u.Path += "/" + key
+		}
+	}
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	return u
+}
+
+// do sends a signed request. payloadHash is the hex SHA-256 of body, or
+// unsignedPayload. Error statuses are returned as *s3Error.
+func (c *s3Client) do(method, key string, query url.Values, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	u := c.objectURL(key, query)
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	c.sign(req, payloadHash, time.Now().UTC())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, newS3Error(method, key, resp)
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (c *s3Client) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+c.creds.SecretAccessKey), date)
+	for _, part := range []string{c.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// s3Escape percent-encodes s as SigV4 requires: everything but unreserved
+// characters, and slashes too unless they separate path segments
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes query parameters sorted by name, as SigV4 requires
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// putObject uploads data to key
+func (c *s3Client) putObject(key string, data []byte) error {
+	resp, err := c.do(http.MethodPut, key, nil, bytes.NewReader(data), int64(len(data)), hashHex(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// putFile streams a local file to key
+func (c *s3Client) putFile(key, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(http.MethodPut, key, nil, file, info.Size(), unsignedPayload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// getObject opens key for reading. A missing key is an error matching os.ErrNotExist.
+func (c *s3Client) getObject(key string) (io.ReadCloser, error) {
+	resp, err := c.do(http.MethodGet, key, nil, nil, 0, hashHex(nil))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// readObject returns the contents of key
+func (c *s3Client) readObject(key string) ([]byte, error) {
+	body, err := c.getObject(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// deleteObject removes key; deleting a missing key succeeds
+func (c *s3Client) deleteObject(key string) error {
+	resp, err := c.do(http.MethodDelete, key, nil, nil, 0, hashHex(nil))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listObjects returns the keys starting with prefix, at most limit of them
+// (0 = all), following continuation tokens
+func (c *s3Client) listObjects(prefix string, limit int) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if limit > 0 {
+			query.Set("max-keys", fmt.Sprint(limit))
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do(http.MethodGet, "", query, nil, 0, hashHex(nil))
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bucket listing: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" || (limit > 0 && len(keys) >= limit) {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// s3Error is an error response from S3
+type s3Error struct {
+	Method  string
+	Key     string
+	Status  int
+	Code    string
+	Message string
+	Region  string // the bucket's region, when S3 reports it
+}
+
+func newS3Error(method, key string, resp *http.Response) *s3Error {
+	e := &s3Error{Method: method, Key: key, Status: resp.StatusCode, Region: resp.Header.Get("X-Amz-Bucket-Region")}
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024)); err == nil && xml.Unmarshal(data, &body) == nil {
+		e.Code, e.Message = body.Code, body.Message
+	}
+	if e.Code == "" {
+		e.Code = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
+
+func (e *s3Error) Error() string {
+	msg := fmt.Sprintf("s3 %s /%s: %s (HTTP %d)", e.Method, e.Key, e.Code, e.Status)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Region != "" && (e.Status == http.StatusMovedPermanently || e.Code == "AuthorizationHeaderMalformed") {
+		msg += fmt.Sprintf("; the bucket is in region %s, set AWS_REGION=%s", e.Region, e.Region)
+	}
+	return msg
+}
+
+// Is makes a missing key match os.ErrNotExist
+func (e *s3Error) Is(target error) bool {
+	return target == os.ErrNotExist && (e.Code == "NoSuchKey" || (e.Status == http.StatusNotFound && e.Code != "NoSuchBucket"))
+}
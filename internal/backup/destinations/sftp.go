@@ -0,0 +1,385 @@
+package destinations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// SFTPDestination stores backups on a server reached over SFTP, laid out like
+// a timestamped LocalDestination: each snapshot's files under <path>/<id>/,
+// and the metadata, latest pointer and index under <path>/.bulletproof/.
+type SFTPDestination struct {
+	URL  string
+	User string
+	Host string
+	Port string
+	Path string // remote folder; relative paths start at the login directory
+
+	client *sftpClient
+
+	messages
+}
+
+// NewSFTPDestination creates a destination for an sftp://user@host:port/path
+// URL. The user defaults to the local one and the port to 22. The path is
+// absolute; leave it out, or start it with /~/, for the login directory.
+func NewSFTPDestination(location string) (*SFTPDestination, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "sftp" {
+		return nil, fmt.Errorf("invalid SFTP location %q: expected sftp://user@host:port/path", location)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid SFTP location %q: no host", location)
+	}
+
+	dest := &SFTPDestination{
+		URL:  location,
+		Host: u.Hostname(),
+		Port: u.Port(),
+		Path: strings.TrimSuffix(u.Path, "/"),
+	}
+	if dest.Port == "" {
+		dest.Port = "22"
+	}
+	if rest, ok := strings.CutPrefix(dest.Path, "/~"); ok {
+		dest.Path = strings.TrimPrefix(rest, "/")
+	}
+	if u.User != nil {
+		dest.User = u.User.Username()
+	} else if current, err := user.Current(); err == nil {
+		dest.User = current.Username
+	}
+	if dest.User == "" {
+		return nil, fmt.Errorf("invalid SFTP location %q: no user", location)
+	}
+	return dest, nil
+}
+
+// remotePath returns the remote path of a slash-separated path below the folder
+func (d *SFTPDestination) remotePath(parts ...string) string {
+	return path.Join(append([]string{d.Path}, parts...)...)
+}
+
+func (d *SFTPDestination) metadataPath(name string) string {
+	return d.remotePath(".bulletproof", name)
+}
+
+// Validate connects to the server, or reconnects if the connection was lost
+func (d *SFTPDestination) Validate() error {
+	if d.client != nil {
+		if d.client.ping() == nil {
+			return nil
+		}
+		d.client.close()
+		d.client = nil
+	}
+	client, err := dialSFTP(d.User, net.JoinHostPort(d.Host, d.Port))
+	if err != nil {
+		return sftpAccessError(d.URL, d.User, d.Host, d.Port, err)
+	}
+	d.client = client
+	return nil
+}
+
+// CheckRemote checks that the server accepts the configured key and that the
+// folder can be created
+func (d *SFTPDestination) CheckRemote() error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	if err := d.client.sftp.MkdirAll(d.remotePath(".bulletproof")); err != nil {
+		return fmt.Errorf("cannot write to %s: %w", d.URL, err)
+	}
+	return nil
+}
+
+// Save uploads a backup to the server
+func (d *SFTPDestination) Save(sourcePath string, snapshot *types.Snapshot, message string) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	// Upload files, unless only the manifest is kept
+	files := snapshot.Files
+	if snapshot.ManifestOnly {
+		files = nil
+		fmt.Fprintf(d.output(), "  Recording manifest of %d files...\n", len(snapshot.Files))
+	} else {
+		fmt.Fprintf(d.output(), "  Uploading %d files...\n", len(snapshot.Files))
+	}
+	done := 0
+	progress := d.startProgress("Uploaded")
+	for filePath := range files {
+		remote := d.remotePath(snapshot.ID, filepath.ToSlash(filePath))
+		if err := d.client.putFile(remote, filepath.Join(sourcePath, filePath)); err != nil {
+			return fmt.Errorf("failed to upload file %s: %w", filePath, err)
+		}
+		done++
+		progress(done, len(files), filePath)
+	}
+
+	snapshotJSON, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	// Keep the snapshot folder self-contained, as local destinations do
+	if err := d.client.putObject(d.remotePath(snapshot.ID, ".bulletproof", "snapshot.json"), snapshotJSON); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+
+	// Also save metadata in central location for quick lookups
+	if err := d.client.putObject(d.metadataPath(snapshot.ID+".json"), snapshotJSON); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := d.client.putObject(d.metadataPath("latest"), []byte(snapshot.ID)); err != nil {
+		return fmt.Errorf("failed to write latest file: %w", err)
+	}
+
+	// Update index
+	data, err := d.client.readObject(d.metadataPath("index.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	indexJSON, err := prependIndexEntry(data, snapshot, message)
+	if err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	if err := d.client.putObject(d.metadataPath("index.json"), indexJSON); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+
+	fmt.Fprintf(d.output(), "  Backup saved to: %s\n", d.GetSnapshotURL(snapshot.ID))
+	return nil
+}
+
+// GetLastSnapshot returns the most recent snapshot
+func (d *SFTPDestination) GetLastSnapshot() (*types.Snapshot, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	data, err := d.client.readObject(d.metadataPath("latest"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read latest file: %w", err)
+	}
+
+	return d.GetSnapshot(strings.TrimSpace(string(data)))
+}
+
+// GetSnapshot returns a specific snapshot by ID
+func (d *SFTPDestination) GetSnapshot(id string) (*types.Snapshot, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	data, err := d.client.readObject(d.metadataPath(id + ".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+
+	snapshot, err := types.FromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// ListSnapshots returns all available snapshots from the index, without
+// listing the remote folder
+func (d *SFTPDestination) ListSnapshots() ([]*types.SnapshotInfo, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	data, err := d.client.readObject(d.metadataPath("index.json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*types.SnapshotInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to read index file: %w", err)
+	}
+
+	return parseIndex(data)
+}
+
+// Restore downloads the files of a snapshot to the target path
+func (d *SFTPDestination) Restore(snapshotID string, targetPath string) error {
+	snapshot, err := d.GetSnapshot(snapshotID)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+
+	if err := removeFilesNotInSnapshot(targetPath, snapshot); err != nil {
+		return fmt.Errorf("failed to clean target directory: %w", err)
+	}
+
+	// Now download all files of the snapshot
+	fmt.Fprintf(d.output(), "  Downloading %d files...\n", len(snapshot.Files))
+	done := 0
+	progress := d.startProgress("Downloaded")
+	for relativePath := range snapshot.Files {
+		if err := d.download(d.remotePath(snapshotID, filepath.ToSlash(relativePath)), filepath.Join(targetPath, relativePath)); err != nil {
+			return fmt.Errorf("failed to download file %s: %w", relativePath, err)
+		}
+		if err := snapshot.RestoreMode(targetPath, relativePath); err != nil {
+			return fmt.Errorf("failed to restore mode of %s: %w", relativePath, err)
+		}
+		if err := utils.MatchPathCase(targetPath, relativePath); err != nil {
+			return fmt.Errorf("failed to restore name of %s: %w", relativePath, err)
+		}
+		done++
+		progress(done, len(snapshot.Files), relativePath)
+	}
+
+	return nil
+}
+
+// download writes the remote file to a local file
+func (d *SFTPDestination) download(remotePath, filePath string) error {
+	body, err := d.client.getObject(remotePath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return writeFileFrom(filePath, body)
+}
+
+// ReadSnapshotFile reads one stored file of a snapshot
+func (d *SFTPDestination) ReadSnapshotFile(snapshotID, path string) ([]byte, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d.client.readObject(d.remotePath(snapshotID, filepath.ToSlash(path)))
+}
+
+// GetSnapshotPath returns empty string since SFTP snapshots are not on the
+// local filesystem
+func (d *SFTPDestination) GetSnapshotPath(id string) string {
+	return ""
+}
+
+// GetSnapshotURL returns the sftp:// URL of a snapshot's folder
+func (d *SFTPDestination) GetSnapshotURL(id string) string {
+	folder := d.remotePath(id)
+	if !path.IsAbs(folder) {
+		folder = "/~/" + folder
+	}
+	return "sftp://" + d.User + "@" + net.JoinHostPort(d.Host, d.Port) + folder + "/"
+}
+
+// SetLabels replaces the labels of a stored snapshot in its metadata and its
+// index entry
+func (d *SFTPDestination) SetLabels(id string, labels []string) error {
+	snapshot, err := d.GetSnapshot(id)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("snapshot not found: %s", id)
+	}
+	snapshot.Labels = labels
+
+	snapshotJSON, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := d.client.putObject(d.metadataPath(id+".json"), snapshotJSON); err != nil {
+		return fmt.Errorf("failed to write snapshot metadata: %w", err)
+	}
+
+	data, err := d.client.readObject(d.metadataPath("index.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	indexJSON, err := setIndexLabels(data, id, labels)
+	if err != nil {
+		return err
+	}
+	if err := d.client.putObject(d.metadataPath("index.json"), indexJSON); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	return nil
+}
+
+// DeleteSnapshot deletes a snapshot by ID: its folder, its metadata and its
+// index entry. If it was the latest snapshot, the latest pointer moves to the
+// newest one left in the index.
+func (d *SFTPDestination) DeleteSnapshot(id string) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	exists, err := d.client.exists(d.remotePath(id))
+	if err != nil {
+		return fmt.Errorf("failed to check snapshot folder: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("snapshot does not exist: %s", id)
+	}
+
+	if err := d.client.removeAll(d.remotePath(id)); err != nil {
+		return fmt.Errorf("failed to delete snapshot folder: %w", err)
+	}
+	if err := d.client.remove(d.metadataPath(id + ".json")); err != nil {
+		return fmt.Errorf("failed to delete snapshot metadata: %w", err)
+	}
+
+	data, err := d.client.readObject(d.metadataPath("index.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	indexJSON, err := removeIndexEntry(data, id)
+	if err != nil {
+		return err
+	}
+	if err := d.client.putObject(d.metadataPath("index.json"), indexJSON); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+
+	latest, err := d.client.readObject(d.metadataPath("latest"))
+	if err != nil || strings.TrimSpace(string(latest)) != id {
+		return nil
+	}
+	remaining, err := parseIndex(indexJSON)
+	if err != nil {
+		return err
+	}
+	if len(remaining) == 0 {
+		if err := d.client.remove(d.metadataPath("latest")); err != nil {
+			return fmt.Errorf("failed to clear latest file: %w", err)
+		}
+		return nil
+	}
+	if err := d.client.putObject(d.metadataPath("latest"), []byte(remaining[0].ID)); err != nil {
+		return fmt.Errorf("failed to write latest file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package destinations
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/pkg/sftp"
+	"github.com/skeema/knownhosts"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestNewSFTPDestination_ParsesURL(t *testing.T) {
+	dest, err := NewSFTPDestination("sftp://backup@nas.local:2222/srv/backups/agent/")
+	if err != nil {
+		t.Fatalf("NewSFTPDestination failed: %v", err)
+	}
+	if dest.User != "backup" || dest.Host != "nas.local" || dest.Port != "2222" || dest.Path != "/srv/backups/agent" {
+		t.Errorf("got user %q host %q port %q path %q", dest.User, dest.Host, dest.Port, dest.Path)
+	}
+	if got := dest.remotePath("20260101-120000-000", "workspace/SOUL.md"); got != "/srv/backups/agent/20260101-120000-000/workspace/SOUL.md" {
+		t.Errorf("remotePath = %s", got)
+	}
+
+	// The port defaults to 22, and /~/ starts at the login directory
+	dest, err = NewSFTPDestination("sftp://backup@nas.local/~/agent-backups")
+	if err != nil {
+		t.Fatalf("NewSFTPDestination failed: %v", err)
+	}
+	if dest.Port != "22" || dest.metadataPath("latest") != "agent-backups/.bulletproof/latest" {
+		t.Errorf("got port %q metadata path %q", dest.Port, dest.metadataPath("latest"))
+	}
+	if got := dest.GetSnapshotURL("20260101-120000-000"); got != "sftp://backup@nas.local:22/~/agent-backups/20260101-120000-000/" {
+		t.Errorf("GetSnapshotURL = %s", got)
+	}
+
+	for _, bad := range []string{"nas.local/backups", "s3://bucket/prefix", "sftp:///backups"} {
+		if _, err := NewSFTPDestination(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+// testSFTPServer is an SSH server on localhost that serves SFTP from the local
+// filesystem to one authorized key
+type testSFTPServer struct {
+	addr    string
+	hostKey ssh.PublicKey
+}
+
+func newTestSFTPServer(t *testing.T, authorized ssh.PublicKey) *testSFTPServer {
+	t.Helper()
+
+	_, hostPrivate, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSFTP(conn, config)
+		}
+	}()
+
+	return &testSFTPServer{addr: listener.Addr().String(), hostKey: hostSigner.PublicKey()}
+}
+
+func serveTestSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer serverConn.Close()
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "sessions only")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range channelRequests {
+				isSFTP := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(isSFTP, nil)
+				if !isSFTP {
+					continue
+				}
+				if server, err := sftp.NewServer(channel); err == nil {
+					server.Serve()
+					server.Close()
+				}
+				return
+			}
+		}()
+	}
+}
+
+// newSFTPClientHome makes a home directory with an unencrypted key in ~/.ssh
+// and no SSH agent, returning the key's public half
+func newSFTPClientHome(t *testing.T) (string, ssh.PublicKey) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "id_ed25519"), pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	return home, signer.PublicKey()
+}
+
+func trustSFTPServer(t *testing.T, home string, server *testSFTPServer) {
+	t.Helper()
+	line := knownhosts.Line([]string{knownhosts.Normalize(server.addr)}, server.hostKey)
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSFTPDestination_SaveRestoreList(t *testing.T) {
+	home, key := newSFTPClientHome(t)
+	server := newTestSFTPServer(t, key)
+	trustSFTPServer(t, home, server)
+
+	source := t.TempDir()
+	for name, content := range map[string]string{
+		"openclaw.json":             "{}",
+		"workspace/SOUL.md":         "# Soul\n",
+		"workspace/skills/a b.md":   "skill with a space\n",
+		"workspace/memory/notes.md": "remember\n",
+	} {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot, err := types.FromDirectoryWithTimestamp(source, nil, "first", time.Now())
+	if err != nil {
+		t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+	}
+
+	remote := filepath.Join(t.TempDir(), "agents", "main")
+	dest, err := NewSFTPDestination("sftp://tester@" + server.addr + filepath.ToSlash(remote))
+	if err != nil {
+		t.Fatalf("NewSFTPDestination failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if dest.client != nil {
+			dest.client.close()
+		}
+	})
+	if err := dest.CheckRemote(); err != nil {
+		t.Fatalf("CheckRemote failed: %v", err)
+	}
+	if last, err := dest.GetLastSnapshot(); err != nil || last != nil {
+		t.Fatalf("expected no snapshots yet, got %v (%v)", last, err)
+	}
+	if err := dest.Save(source, snapshot, "first"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	for _, file := range []string{
+		snapshot.ID + "/workspace/skills/a b.md",
+		snapshot.ID + "/.bulletproof/snapshot.json",
+		".bulletproof/" + snapshot.ID + ".json",
+		".bulletproof/index.json",
+		".bulletproof/latest",
+	} {
+		if _, err := os.Stat(filepath.Join(remote, filepath.FromSlash(file))); err != nil {
+			t.Errorf("expected remote file %s: %v", file, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(remote, ".bulletproof", "index.json.tmp")); !os.IsNotExist(err) {
+		t.Error("expected the temporary index to be renamed into place")
+	}
+
+	infos, err := dest.ListSnapshots()
+	if err != nil || len(infos) != 1 || infos[0].ID != snapshot.ID || infos[0].Message != "first" || infos[0].FileCount != 4 {
+		t.Fatalf("unexpected listing %+v (%v)", infos, err)
+	}
+	last, err := dest.GetLastSnapshot()
+	if err != nil || last == nil || last.ID != snapshot.ID {
+		t.Fatalf("expected last snapshot %s, got %v (%v)", snapshot.ID, last, err)
+	}
+	content, err := dest.ReadSnapshotFile(snapshot.ID, filepath.Join("workspace", "SOUL.md"))
+	if err != nil || string(content) != "# Soul\n" {
+		t.Errorf("ReadSnapshotFile = %q (%v)", content, err)
+	}
+
+	// A lost connection is reopened on the next call
+	dest.client.conn.Close()
+	if infos, err := dest.ListSnapshots(); err != nil || len(infos) != 1 {
+		t.Fatalf("expected listing to reconnect, got %+v (%v)", infos, err)
+	}
+
+	// Restore replaces changed files and removes ones the snapshot lacks
+	target := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(target, "workspace"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(target, "workspace", "SOUL.md"), []byte("tampered"), 0644)
+	os.WriteFile(filepath.Join(target, "workspace", "extra.md"), []byte("extra"), 0644)
+	if err := dest.Restore(snapshot.ID, target); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	restored, err := types.FromDirectoryWithTimestamp(target, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+	}
+	if diff := snapshot.Diff(restored); !diff.IsEmpty() {
+		t.Errorf("restored tree differs from the snapshot: %+v", diff)
+	}
+
+	if err := dest.DeleteSnapshot(snapshot.ID); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	if _, err := dest.ReadSnapshotFile(snapshot.ID, filepath.Join("workspace", "SOUL.md")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the snapshot's files to be deleted, got %v", err)
+	}
+	if infos, err := dest.ListSnapshots(); err != nil || len(infos) != 0 {
+		t.Errorf("expected the deleted snapshot to be unlisted, got %+v (%v)", infos, err)
+	}
+	if last, err := dest.GetLastSnapshot(); err != nil || last != nil {
+		t.Errorf("expected no last snapshot after deleting the only one, got %v (%v)", last, err)
+	}
+	if err := dest.DeleteSnapshot(snapshot.ID); err == nil {
+		t.Error("expected deleting a missing snapshot to fail")
+	}
+}
+
+func TestSFTPDestination_RefusesUnknownHostsAndKeys(t *testing.T) {
+	home, key := newSFTPClientHome(t)
+	server := newTestSFTPServer(t, key)
+	_, port, _ := net.SplitHostPort(server.addr)
+
+	dest, err := NewSFTPDestination("sftp://tester@" + server.addr + "/backups")
+	if err != nil {
+		t.Fatalf("NewSFTPDestination failed: %v", err)
+	}
+
+	// A server missing from known_hosts is not trusted
+	err = dest.CheckRemote()
+	if err == nil || !strings.Contains(err.Error(), "ssh -p "+port+" tester@127.0.0.1") {
+		t.Errorf("expected an unknown host to be refused with the ssh command to trust it, got %v", err)
+	}
+
+	// A key the server does not accept
+	trustSFTPServer(t, home, server)
+	other := newTestSFTPServer(t, server.hostKey)
+	trustSFTPServer(t, home, other)
+	dest, err = NewSFTPDestination("sftp://tester@" + other.addr + "/backups")
+	if err != nil {
+		t.Fatalf("NewSFTPDestination failed: %v", err)
+	}
+	if err := dest.CheckRemote(); err == nil || !strings.Contains(err.Error(), "ssh-add") {
+		t.Errorf("expected a rejected key to suggest loading one, got %v", err)
+	}
+}
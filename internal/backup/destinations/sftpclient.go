@@ -0,0 +1,236 @@
+package destinations
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/errors"
+	"github.com/pkg/sftp"
+	"github.com/skeema/knownhosts"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// sftpDialTimeout bounds connecting to the server and the SSH handshake
+const sftpDialTimeout = 30 * time.Second
+
+// sftpKeyFiles are the private keys in ~/.ssh tried after the agent's, in the
+// order ssh tries them
+var sftpKeyFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// sftpClient is an SFTP session over an SSH connection, with the few file
+// operations backups need on slash-separated remote paths
+type sftpClient struct {
+	conn *ssh.Client
+	sftp *sftp.Client
+}
+
+// dialSFTP connects to addr (host:port) as user. It authenticates with the keys
+// in the SSH agent at SSH_AUTH_SOCK, then with the unencrypted default keys in
+// ~/.ssh, and checks the server's host key against ~/.ssh/known_hosts as ssh
+// does, so a server never connected to before is refused.
+func dialSFTP(user, addr string) (*sftpClient, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find home directory: %w", err)
+	}
+
+	// Without a known_hosts file every server is unknown
+	var knownHostsFiles []string
+	knownHostsFile := filepath.Join(home, ".ssh", "known_hosts")
+	if _, err := os.Stat(knownHostsFile); err == nil {
+		knownHostsFiles = append(knownHostsFiles, knownHostsFile)
+	}
+	hosts, err := knownhosts.NewDB(knownHostsFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known hosts: %w", err)
+	}
+
+	auth, closeAgent := sshAuthMethods(home)
+	defer closeAgent()
+
+	conn, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:              user,
+		Auth:              auth,
+		HostKeyCallback:   hosts.HostKeyCallback(),
+		HostKeyAlgorithms: hosts.HostKeyAlgorithms(addr),
+		Timeout:           sftpDialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SFTP session: %w", err)
+	}
+	return &sftpClient{conn: conn, sftp: client}, nil
+}
+
+// sshAuthMethods returns the SSH agent's keys, if an agent is running, and the
+// default key files that can be read without a passphrase. The returned func
+// closes the agent connection once the handshake is over.
+func sshAuthMethods(home string) ([]ssh.AuthMethod, func()) {
+	var methods []ssh.AuthMethod
+	closeAgent := func() {}
+
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			closeAgent = func() { conn.Close() }
+		}
+	}
+
+	var signers []ssh.Signer
+	for _, name := range sftpKeyFiles {
+		data, err := os.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		// Keys behind a passphrase need the agent
+		if signer, err := ssh.ParsePrivateKey(data); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+
+	return methods, closeAgent
+}
+
+// sftpAccessError explains a failed connection with the likely causes and what
+// to try
+func sftpAccessError(location, user, host, port string, cause error) error {
+	login := fmt.Sprintf("ssh -p %s %s@%s", port, user, host)
+
+	switch {
+	case knownhosts.IsHostUnknown(cause):
+		return errors.NewActionableError("verify SFTP server "+location, cause, []string{
+			"This machine has not connected to the server before, so its host key is not in ~/.ssh/known_hosts",
+		}, "Connect once with ssh, check the key fingerprint and accept it:\n"+login, "")
+
+	case knownhosts.IsHostKeyChanged(cause):
+		return errors.NewActionableError("verify SFTP server "+location, cause, []string{
+			"The server was reinstalled or its keys were rotated",
+			"Something is intercepting the connection",
+		}, "Confirm the new key with the server's administrator, then replace its entry:\nssh-keygen -R "+host, "")
+
+	case strings.Contains(cause.Error(), "unable to authenticate"):
+		return errors.NewActionableError("authenticate to SFTP server "+location, cause, []string{
+			"No SSH agent is running, or it holds no key",
+			"The key has a passphrase and is not loaded in the agent",
+			"The key is not in the server's authorized_keys for " + user,
+		}, "Load your key and test it against the server:\nssh-add ~/.ssh/id_ed25519\n"+login, "")
+
+	default:
+		return errors.NewActionableError("reach SFTP server "+location, cause, []string{
+			"No network connection, or the host name or port is wrong",
+			"A firewall blocks the connection",
+			"The server does not offer the SFTP subsystem",
+		}, "Check the URL and that this works:\n"+login, "")
+	}
+}
+
+// ping checks that the session is still open
+func (c *sftpClient) ping() error {
+	_, err := c.sftp.Getwd()
+	return err
+}
+
+// close ends the session and its connection
+func (c *sftpClient) close() {
+	c.sftp.Close()
+	c.conn.Close()
+}
+
+// putFile uploads a local file to remotePath, creating its parent directories
+func (c *sftpClient) putFile(remotePath, localPath string) error {
+	local, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer local.Close()
+	return c.put(remotePath, local)
+}
+
+// putObject writes data to remotePath through a temporary file renamed into
+// place, so readers never see a half-written index or pointer
+func (c *sftpClient) putObject(remotePath string, data []byte) error {
+	temp := remotePath + ".tmp"
+	if err := c.put(temp, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	if err := c.sftp.PosixRename(temp, remotePath); err != nil {
+		// Servers without the posix-rename extension refuse to replace a file
+		c.sftp.Remove(remotePath)
+		if err := c.sftp.Rename(temp, remotePath); err != nil {
+			c.sftp.Remove(temp)
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *sftpClient) put(remotePath string, r io.Reader) error {
+	if err := c.sftp.MkdirAll(path.Dir(remotePath)); err != nil {
+		return err
+	}
+	remote, err := c.sftp.Create(remotePath)
+	if err != nil {
+		return err
+	}
+	if _, err := remote.ReadFrom(r); err != nil {
+		remote.Close()
+		return err
+	}
+	return remote.Close()
+}
+
+// getObject opens a remote file for reading. A missing file is os.ErrNotExist.
+func (c *sftpClient) getObject(remotePath string) (io.ReadCloser, error) {
+	return c.sftp.Open(remotePath)
+}
+
+// readObject reads a whole remote file. A missing file is os.ErrNotExist.
+func (c *sftpClient) readObject(remotePath string) ([]byte, error) {
+	remote, err := c.sftp.Open(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer remote.Close()
+	return io.ReadAll(remote)
+}
+
+// exists reports whether a remote file or directory exists
+func (c *sftpClient) exists(remotePath string) (bool, error) {
+	_, err := c.sftp.Stat(remotePath)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// removeAll removes a remote directory and everything in it
+func (c *sftpClient) removeAll(remotePath string) error {
+	return c.sftp.RemoveAll(remotePath)
+}
+
+// remove removes a remote file, if it exists
+func (c *sftpClient) remove(remotePath string) error {
+	if err := c.sftp.Remove(remotePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
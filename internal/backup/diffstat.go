@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// DiffStat returns the change stats between two stored snapshots.
+// Snapshots are immutable, so results are cached on disk per destination and
+// reused by later calls; a missing or unreadable cache is silently rebuilt.
+func (e *BackupEngine) DiffStat(fromID, toID string) (types.ChangeStats, error) {
+	cachePath := e.diffStatCachePath(fromID, toID)
+	if cachePath != "" {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			var stats types.ChangeStats
+			if err := json.Unmarshal(data, &stats); err == nil {
+				return stats, nil
+			}
+		}
+	}
+
+	from, err := e.destination.GetSnapshot(fromID)
+	if err != nil {
+		return types.ChangeStats{}, fmt.Errorf("failed to get snapshot %s: %w", fromID, err)
+	}
+	to, err := e.destination.GetSnapshot(toID)
+	if err != nil {
+		return types.ChangeStats{}, fmt.Errorf("failed to get snapshot %s: %w", toID, err)
+	}
+	if from == nil || to == nil {
+		return types.ChangeStats{}, fmt.Errorf("snapshot not found: %s..%s", fromID, toID)
+	}
+
+	stats := to.Diff(from).Stats()
+
+	if cachePath != "" {
+		// Caching is best effort; a failure only costs a recomputation next time
+		if data, err := json.Marshal(stats); err == nil {
+			if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err == nil {
+				os.WriteFile(cachePath, data, 0644)
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// diffStatCachePath returns where the stats for a snapshot pair are cached,
+// or "" if no cache directory is available
+func (e *BackupEngine) diffStatCachePath(fromID, toID string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	// Snapshot IDs are only unique within a destination
+	destKey := utils.HashString(e.config.Destination.Type + ":" + e.config.Destination.Location())[:16]
+	return filepath.Join(homeDir, ".cache", "bulletproof", "diffs", destKey, fromID+".."+toID+".json")
+}
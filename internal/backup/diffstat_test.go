@@ -0,0 +1,71 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func TestDiffStat_ComputesAndCaches(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	soulPath := filepath.Join(agentDir, "SOUL.md")
+	if err := os.WriteFile(soulPath, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	first, err := engine.Backup(false, "", true, false)
+	if err != nil {
+		t.Fatalf("first backup failed: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	if err := os.WriteFile(soulPath, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(agentDir, "NEW.md"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second, err := engine.Backup(false, "", true, false)
+	if err != nil {
+		t.Fatalf("second backup failed: %v", err)
+	}
+
+	stats, err := engine.DiffStat(first.Snapshot.ID, second.Snapshot.ID)
+	if err != nil {
+		t.Fatalf("DiffStat failed: %v", err)
+	}
+	if stats.Added != 1 || stats.Modified != 1 || stats.Removed != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	cachePath := engine.diffStatCachePath(first.Snapshot.ID, second.Snapshot.ID)
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("expected stats to be cached at %s: %v", cachePath, err)
+	}
+
+	// Served from cache even once the snapshot metadata is gone
+	if err := os.Remove(filepath.Join(cfg.Destination.Path, ".bulletproof", second.Snapshot.ID+".json")); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := engine.DiffStat(first.Snapshot.ID, second.Snapshot.ID)
+	if err != nil {
+		t.Fatalf("cached DiffStat failed: %v", err)
+	}
+	if cached.Total() != stats.Total() {
+		t.Errorf("cached stats %+v differ from computed %+v", cached, stats)
+	}
+}
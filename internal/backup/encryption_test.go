@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/keys"
+)
+
+func TestBackup_Encrypted(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("BULLETPROOF_PASSPHRASE", "correct horse")
+
+	agentDir := t.TempDir()
+	backupDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(agentDir, "workspace"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(agentDir, "workspace", "log.md"), []byte("secret conversation\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: backupDir},
+	}
+	cfg.Options.Encryption.Enabled = true
+	cfg.Options.StoreContent = true
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	result, err := engine.Backup(false, "Encrypted", true, false)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if !result.Snapshot.Encrypted {
+		t.Error("expected the snapshot to be marked encrypted")
+	}
+	if result.Snapshot.Files[filepath.Join("workspace", "log.md")].Content != nil {
+		t.Error("expected no plaintext content in an encrypted snapshot's manifest")
+	}
+	stored, err := os.ReadFile(filepath.Join(backupDir, result.Snapshot.ID, "workspace", "log.md"))
+	if err != nil || strings.Contains(string(stored), "secret") {
+		t.Fatalf("expected the stored file to be encrypted, got %q (%v)", stored, err)
+	}
+
+	// Verify and single-file restores read through the key
+	report, err := engine.Verify(false, 0, false)
+	if err != nil || len(report.Failed()) != 0 {
+		t.Errorf("expected verify to pass, got %+v (%v)", report, err)
+	}
+	target := t.TempDir()
+	if err := engine.RestorePaths(result.Snapshot.ID, []string{"workspace/log.md"}, target, false, true, true, false); err != nil {
+		t.Fatalf("RestorePaths failed: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(target, "workspace", "log.md")); err != nil || string(content) != "secret conversation\n" {
+		t.Errorf("restored %q (%v)", content, err)
+	}
+
+	// Encrypted files are never handed to a destination that stores them in the clear
+	if _, err := engine.Promote(result.Snapshot.ID, &config.DestinationConfig{Type: "git", Path: t.TempDir()}); err == nil || !strings.Contains(err.Error(), "in the clear") {
+		t.Errorf("expected promoting to git to be refused, got %v", err)
+	}
+
+	// A wrong passphrase is reported instead of restoring garbage
+	t.Setenv("BULLETPROOF_PASSPHRASE", "wrong")
+	engine, err = NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	if err := engine.RestoreToTarget(result.Snapshot.ID, t.TempDir(), false, true, true); err == nil || !strings.Contains(err.Error(), "wrong encryption passphrase") {
+		t.Errorf("expected the wrong passphrase to be reported, got %v", err)
+	}
+
+	cfg.Destination = &config.DestinationConfig{Type: "git", Path: t.TempDir()}
+	if _, err := NewBackupEngine(cfg); err == nil {
+		t.Error("expected encryption with a git destination to be refused")
+	}
+}
+
+func TestBackup_EncryptedWithRecordedKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("BULLETPROOF_PASSPHRASE", "")
+
+	agentDir := t.TempDir()
+	backupDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(agentDir, "workspace"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(agentDir, "workspace", "log.md"), []byte("secret conversation\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key, err := keys.Generate(t.TempDir(), keys.Encryption)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without key_file, the key recorded by `bulletproof key` is used
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: backupDir},
+		Keys:         config.KeysConfig{EncryptionKey: key.Path},
+	}
+	cfg.Options.Encryption.Enabled = true
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	result, err := engine.Backup(false, "Encrypted", true, false)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	params, err := os.ReadFile(filepath.Join(backupDir, ".bulletproof", "encryption.json"))
+	if err != nil || !strings.Contains(string(params), `"kdf": "none"`) {
+		t.Errorf("expected the destination to be encrypted with the key file, got %s (%v)", params, err)
+	}
+
+	// key_file takes precedence over the recorded key
+	other, err := keys.Generate(t.TempDir(), keys.Encryption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Options.Encryption.KeyFile = other.Path
+	engine, err = NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	if err := engine.RestoreToTarget(result.Snapshot.ID, t.TempDir(), false, true, true); err == nil || !strings.Contains(err.Error(), other.Path) {
+		t.Errorf("expected key_file to be used and reported as wrong, got %v", err)
+	}
+}
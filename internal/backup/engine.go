@@ -3,14 +3,21 @@ package backup
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bulletproof-bot/backup/internal/backup/destinations"
 	"github.com/bulletproof-bot/backup/internal/backup/scripts"
 	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/log"
 	"github.com/bulletproof-bot/backup/internal/types"
 	"github.com/bulletproof-bot/backup/internal/utils"
 )
@@ -19,6 +26,154 @@ import (
 type BackupEngine struct {
 	config      *config.Config
 	destination Destination
+
+	// messagePrompt asks for a message when a backup without one is about to be saved
+	messagePrompt MessagePrompt
+
+	// manifestOnly makes backups store the manifest without copying files
+	manifestOnly bool
+
+	// out receives progress messages and warnings; nil means stdout
+	out io.Writer
+
+	// progress receives progress through copied files; nil prints a counter to out
+	progress types.ProgressFunc
+
+	// previewRestore selects the files whose content diffs restores print
+	// before asking for confirmation; nil prints none
+	previewRestore func(path string) bool
+
+	// skipRestoreVerify makes restores copy stored files without first
+	// checking them against their recorded hashes
+	skipRestoreVerify bool
+
+	// waitForLock makes operations wait for another one holding the
+	// destination's lock instead of failing after lockTimeout
+	waitForLock bool
+
+	// lockHeld is set while this engine holds the destination's lock
+	lockHeld bool
+
+	// notifications tracks webhook requests still being sent
+	notifications sync.WaitGroup
+}
+
+// snapshotClock hands out snapshot timestamps at least a millisecond apart.
+// IDs have millisecond resolution, so two backups in the same millisecond,
+// such as a backup and the safety backup of a restore right after it, would
+// otherwise share an ID and the second would overwrite the first.
+var snapshotClock struct {
+	sync.Mutex
+	last time.Time
+}
+
+// nextSnapshotTime returns the current time, moved past the previous
+// snapshot's millisecond if needed
+func nextSnapshotTime() time.Time {
+	snapshotClock.Lock()
+	defer snapshotClock.Unlock()
+
+	now := time.Now()
+	if next := snapshotClock.last.Truncate(time.Millisecond).Add(time.Millisecond); now.Before(next) {
+		now = next
+	}
+	snapshotClock.last = now
+	return now
+}
+
+// MessagePrompt returns the message for a backup about to be saved. diff holds
+// the changes since the last backup, or nil for the first one.
+type MessagePrompt func(diff *types.SnapshotDiff) (string, error)
+
+// SetMessagePrompt makes backups without a message ask prompt for one once the
+// changes are known. Skipped backups and dry runs never prompt.
+func (e *BackupEngine) SetMessagePrompt(prompt MessagePrompt) {
+	e.messagePrompt = prompt
+}
+
+// SetManifestOnly makes backups record only the manifest (hashes, sizes and
+// times) without copying file contents. Such checkpoints support diffs and drift
+// detection but cannot be restored. Only local destinations support them.
+func (e *BackupEngine) SetManifestOnly(manifestOnly bool) {
+	e.manifestOnly = manifestOnly
+}
+
+// SetOutput sends the engine's progress messages, warnings and confirmation
+// prompts to w instead of stdout; io.Discard silences them. Programs embedding
+// the engine should then pass force to restores, as nobody sees the prompts.
+func (e *BackupEngine) SetOutput(w io.Writer) {
+	e.out = w
+	e.forwardReporting(e.destination)
+}
+
+// SetRestorePreview makes restores that ask for confirmation first print
+// unified diffs of what they would change in the files for which match is
+// true. A nil match turns the preview off.
+func (e *BackupEngine) SetRestorePreview(match func(path string) bool) {
+	e.previewRestore = match
+}
+
+// SetSkipRestoreVerify makes restores skip checking the stored files against
+// their recorded hashes before changing anything. Restores from remote or
+// compressed destinations then read the files once instead of twice.
+func (e *BackupEngine) SetSkipRestoreVerify(skip bool) {
+	e.skipRestoreVerify = skip
+}
+
+// SetWaitForLock makes backups, restores and prunes wait for as long as
+// another bulletproof operation on the destination takes, instead of failing
+// with ErrLocked
+func (e *BackupEngine) SetWaitForLock(wait bool) {
+	e.waitForLock = wait
+}
+
+// SetProgress reports progress through the files backups and restores copy to
+// progress, e.g. to drive a progress bar, instead of printing a counter
+func (e *BackupEngine) SetProgress(progress types.ProgressFunc) {
+	e.progress = progress
+	e.forwardReporting(e.destination)
+}
+
+// startProgress returns the progress reporter for one copy: the one set, or a
+// counter printed to the output with verb, e.g. "Copied", which --verbose
+// turns into a line per file
+func (e *BackupEngine) startProgress(verb string) types.ProgressFunc {
+	if e.progress != nil {
+		return e.progress
+	}
+	if log.Verbose() {
+		return types.FilePrinter(e.output(), verb)
+	}
+	return types.ProgressPrinter(e.output(), verb)
+}
+
+// output returns where progress messages go: the writer set, or stdout
+// unless --quiet silenced it
+func (e *BackupEngine) output() io.Writer {
+	if e.out == nil {
+		return log.Writer()
+	}
+	return e.out
+}
+
+// resultOutput returns where final results and confirmation prompts go,
+// which --quiet leaves on
+func (e *BackupEngine) resultOutput() io.Writer {
+	if e.out == nil {
+		return os.Stdout
+	}
+	return e.out
+}
+
+// forwardReporting sends a destination's messages and progress where the
+// engine's go
+func (e *BackupEngine) forwardReporting(dest Destination) {
+	if setter, ok := dest.(outputSetter); ok {
+		setter.SetOutput(e.out)
+	}
+	if setter, ok := dest.(progressSetter); ok {
+		setter.SetProgress(e.progress)
+	}
 }
 
 // NewBackupEngine creates a new backup engine
@@ -31,6 +186,9 @@ func NewBackupEngine(cfg *config.Config) (*BackupEngine, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create destination: %w", err)
 	}
+	if err := applyStorageOptions(destination, cfg); err != nil {
+		return nil, err
+	}
 
 	return &BackupEngine{
 		config:      cfg,
@@ -39,24 +197,84 @@ func NewBackupEngine(cfg *config.Config) (*BackupEngine, error) {
 }
 
 func createDestination(destConfig *config.DestinationConfig) (Destination, error) {
-	switch destConfig.Type {
-	case "git":
-		return destinations.NewGitDestination(destConfig.Path), nil
-	case "local":
-		return destinations.NewLocalDestination(destConfig.Path, true), nil
-	case "sync":
+	// Work on a copy so the caller's config keeps the form it was written in
+	typed := *destConfig
+	if err := typed.Normalize(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case typed.Type == "git" && typed.Git != nil:
+		dest := destinations.NewGitDestination(typed.Git.URL)
+		dest.PushBranch = typed.Git.BranchPushEnabled()
+		if author := typed.Git.Author; author != nil {
+			dest.AuthorName, dest.AuthorEmail = author.Name, author.Email
+		}
+		if retries := typed.Git.Retries; retries != nil {
+			dest.Retries = *retries
+		}
+		return dest, nil
+	case typed.Type == "local" && typed.Local != nil:
+		dest := destinations.NewLocalDestination(typed.Local.Path, true)
+		dest.Deduplicate = typed.Local.Deduplicate
+		return dest, nil
+	case typed.Type == "sync" && typed.Sync != nil:
 		// Sync destinations work like local - just copy files
 		// The sync client (Dropbox/GDrive) handles the rest
-		return destinations.NewSyncDestination(destConfig.Path), nil
+		return destinations.NewSyncDestination(typed.Sync.Path), nil
+	case typed.Type == "s3" && typed.S3 != nil:
+		dest, err := destinations.NewS3Destination(typed.S3.URL)
+		if err != nil {
+			return nil, err
+		}
+		return dest, nil
+	case typed.Type == "sftp" && typed.SFTP != nil:
+		dest, err := destinations.NewSFTPDestination(typed.SFTP.URL)
+		if err != nil {
+			return nil, err
+		}
+		return dest, nil
+	case typed.Type == "git" || typed.Type == "local" || typed.Type == "sync" || typed.Type == "s3" || typed.Type == "sftp":
+		return nil, fmt.Errorf("%s destination has no location configured", typed.Type)
 	default:
-		return nil, fmt.Errorf("unknown destination type: %s", destConfig.Type)
+		return nil, fmt.Errorf("unknown destination type: %s", typed.Type)
+	}
+}
+
+// applyStorageOptions makes a local or sync destination compress and encrypt
+// the files it stores as options.compression and options.encryption say.
+// Other destinations are refused when either is set, since they would store
+// the files as is.
+func applyStorageOptions(destination Destination, cfg *config.Config) error {
+	compression, encryption := cfg.Options.CompressionMethod(), cfg.Options.Encryption
+	if compression == "" && !encryption.Enabled {
+		return nil
+	}
+	local, ok := localDestination(destination)
+	if !ok {
+		if encryption.Enabled {
+			return fmt.Errorf("encryption needs a local or sync destination")
+		}
+		return fmt.Errorf("compression needs a local or sync destination")
 	}
+
+	local.Compression = compression
+	if !encryption.Enabled {
+		return nil
+	}
+	variable := encryption.PassphraseVariable()
+	local.Encryption = &destinations.Encryption{
+		Passphrase:    os.Getenv(variable),
+		PassphraseEnv: variable,
+		KeyFile:       cfg.EncryptionKeyFile(),
+	}
+	return nil
 }
 
 // OpenclawPath returns the OpenClaw root path
 func (e *BackupEngine) OpenclawPath() (string, error) {
-	if e.config.OpenclawPath != "" {
-		return e.config.OpenclawPath, nil
+	if path := e.config.AgentPath(); path != "" {
+		return path, nil
 	}
 
 	detected := config.DetectInstallation()
@@ -106,6 +324,24 @@ func (e *BackupEngine) getSourcePaths() ([]string, error) {
 		}
 	}
 
+	// A glob such as ~/projects/* can match the destination itself. Sources that
+	// contain the destination skip it while scanning, but a source inside it
+	// would copy every backup into the next one, so drop it after expansion.
+	if destDir := e.destinationDir(); destDir != "" {
+		kept := expandedSources[:0]
+		for _, source := range expandedSources {
+			if pathWithin(source, destDir) {
+				fmt.Fprintf(e.output(), "⚠️  Skipping source %s: it is inside the backup destination %s\n", source, destDir)
+				continue
+			}
+			kept = append(kept, source)
+		}
+		if len(kept) == 0 {
+			return nil, fmt.Errorf("every source is inside the backup destination %s", destDir)
+		}
+		expandedSources = kept
+	}
+
 	// Validate that all paths exist and are directories
 	for _, source := range expandedSources {
 		info, err := os.Stat(source)
@@ -120,6 +356,20 @@ func (e *BackupEngine) getSourcePaths() ([]string, error) {
 		}
 	}
 
+	// Catch prefix conflicts before anything is scanned. Sources sharing a base
+	// name still get distinct prefixes, but those change as sources come and go.
+	if len(expandedSources) > 1 {
+		prefixes, err := e.config.SourcePrefixes()
+		if err != nil {
+			return nil, err
+		}
+		for _, mapping := range types.SourceMappingsWithPrefixes(expandedSources, prefixes) {
+			if prefixes[mapping.Path] == "" && !mapping.IsBaseName() {
+				fmt.Fprintf(e.output(), "⚠️  Source %s shares its name with another source and is stored as %s. Give it a prefix in the config to keep its name stable\n", mapping.Path, mapping.Prefix)
+			}
+		}
+	}
+
 	return expandedSources, nil
 }
 
@@ -141,6 +391,29 @@ func (e *BackupEngine) ResolveSnapshotID(id string) (string, error) {
 	return types.ResolveID(id, snapshots)
 }
 
+// SnapshotAt returns the newest snapshot taken at or before t
+func (e *BackupEngine) SnapshotAt(t time.Time) (*types.SnapshotInfo, error) {
+	snapshots, err := e.ListBackups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	info := types.SnapshotAt(t, snapshots)
+	if info == nil {
+		if len(snapshots) == 0 {
+			return nil, fmt.Errorf("no backups found")
+		}
+		oldest := snapshots[0]
+		for _, snapshot := range snapshots {
+			if snapshot.Timestamp.Before(oldest.Timestamp) {
+				oldest = snapshot
+			}
+		}
+		return nil, fmt.Errorf("no snapshot at or before %s; the oldest is %s from %s",
+			t.Local().Format("2006-01-02 15:04:05"), oldest.ID, oldest.Timestamp.Local().Format("2006-01-02 15:04:05"))
+	}
+	return info, nil
+}
+
 // Config returns the backup engine's configuration
 func (e *BackupEngine) Config() *config.Config {
 	return e.config
@@ -169,6 +442,26 @@ func (e *BackupEngine) GetSnapshot(id string) (*types.Snapshot, error) {
 
 // Backup runs a backup operation
 func (e *BackupEngine) Backup(dryRun bool, message string, noScripts bool, force bool) (*types.BackupResult, error) {
+	return e.BackupWithLabels(dryRun, message, noScripts, force, nil)
+}
+
+// BackupWithLabels runs a backup operation and attaches labels to the created
+// snapshot. Unless it is a dry run, the outcome is sent to the configured
+// notification webhook.
+func (e *BackupEngine) BackupWithLabels(dryRun bool, message string, noScripts bool, force bool, labels []string) (*types.BackupResult, error) {
+	result, err := e.backupWithLabels(dryRun, message, noScripts, force, labels)
+	if !dryRun {
+		e.notifyBackup(result, err)
+	}
+	return result, err
+}
+
+func (e *BackupEngine) backupWithLabels(dryRun bool, message string, noScripts bool, force bool, labels []string) (*types.BackupResult, error) {
+	labels, err := types.NormalizeLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get all source paths (supports multi-source backups)
 	sources, err := e.getSourcePaths()
 	if err != nil {
@@ -179,24 +472,49 @@ func (e *BackupEngine) Backup(dryRun bool, message string, noScripts bool, force
 		return nil, errors.New("no source paths configured. Run: bulletproof config set openclaw_path /path/to/.openclaw")
 	}
 
+	return e.backupSources(sources, dryRun, message, labels, noScripts, force, true)
+}
+
+// backupSources backs up the given source directories.
+// With trackChanges false the backup is left out of the change-rate baseline
+// and never checked for anomalies, for snapshots of something other than the agent.
+func (e *BackupEngine) backupSources(sources []string, dryRun bool, message string, labels []string, noScripts bool, force bool, trackChanges bool) (*types.BackupResult, error) {
+	var err error
+
+	if !dryRun {
+		unlock, err := e.lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	// Git commits every file and a sync folder holds only its latest snapshot,
+	// so a manifest without files only makes sense as its own snapshot folder
+	if e.manifestOnly {
+		if local, ok := e.destination.(*destinations.LocalDestination); !ok || !local.Timestamped {
+			return nil, fmt.Errorf("manifest-only backups need a local destination, not %s", e.config.Destination.Type)
+		}
+	}
+
 	// Display sources being backed up
 	if len(sources) == 1 {
-		fmt.Printf("🔍 Scanning source at: %s\n", sources[0])
+		fmt.Fprintf(e.output(), "🔍 Scanning source at: %s\n", sources[0])
 	} else {
-		fmt.Printf("🔍 Scanning %d sources:\n", len(sources))
+		fmt.Fprintf(e.output(), "🔍 Scanning %d sources:\n", len(sources))
 		for _, source := range sources {
-			fmt.Printf("  • %s\n", source)
+			fmt.Fprintf(e.output(), "  • %s\n", source)
 		}
 	}
 
 	// Generate snapshot ID early so it's available to pre-backup scripts
-	snapshotTimestamp := time.Now()
+	snapshotTimestamp := nextSnapshotTime()
 	snapshotID := types.GenerateID(snapshotTimestamp)
 
-	// Execute pre-backup scripts (unless disabled)
+	// Execute pre-backup scripts (unless disabled, or nothing would store their exports)
 	var exportsDir string
-	if !noScripts && len(e.config.Scripts.PreBackup) > 0 {
-		fmt.Println("\n📜 Executing pre-backup scripts...")
+	if !noScripts && !e.manifestOnly && len(e.config.Scripts.PreBackup) > 0 {
+		fmt.Fprintln(e.output(), "\n📜 Executing pre-backup scripts...")
 
 		// Create _exports directory
 		configDir, err := config.ConfigDir()
@@ -208,14 +526,28 @@ func (e *BackupEngine) Backup(dryRun bool, message string, noScripts bool, force
 			return nil, fmt.Errorf("failed to create exports directory: %w", err)
 		}
 
+		scriptsDir, err := e.config.ScriptsDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get scripts directory: %w", err)
+		}
+
+		// Tell scripts what this backup will change, so they can skip
+		// expensive exports when nothing they depend on did
+		changedFiles, err := e.changedFiles(sources, snapshotTimestamp)
+		if err != nil {
+			return nil, err
+		}
+
 		// Execute scripts (use first source as OpenClawPath for backward compatibility)
 		executor := scripts.NewExecutor(
 			convertScriptConfigs(e.config.Scripts.PreBackup),
 			scripts.ExecutionContext{
 				SnapshotID:   snapshotID,
 				OpenClawPath: sources[0],
-				BackupDir:    e.config.Destination.Path,
+				BackupDir:    e.config.Destination.Location(),
 				ExportsDir:   exportsDir,
+				ScriptsDir:   scriptsDir,
+				ChangedFiles: changedFiles,
 			},
 		)
 
@@ -223,46 +555,33 @@ func (e *BackupEngine) Backup(dryRun bool, message string, noScripts bool, force
 			return nil, fmt.Errorf("pre-backup script failed: %w", err)
 		}
 
-		fmt.Println("✅ Pre-backup scripts completed")
+		fmt.Fprintln(e.output(), "✅ Pre-backup scripts completed")
 	}
 
 	// Create snapshots for each source (use the same timestamp for consistency)
-	var snapshot *types.Snapshot
-	if len(sources) == 1 {
-		// Single source - create snapshot directly
-		snapshot, err = types.FromDirectoryWithTimestamp(
-			sources[0],
-			e.config.Options.Exclude,
-			message,
-			snapshotTimestamp,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create snapshot: %w", err)
-		}
-	} else {
-		// Multiple sources - create individual snapshots and merge
-		snapshots := make([]*types.Snapshot, len(sources))
-		for i, source := range sources {
-			s, err := types.FromDirectoryWithTimestamp(
-				source,
-				e.config.Options.Exclude,
-				"",
-				snapshotTimestamp,
-			)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create snapshot for %s: %w", source, err)
-			}
-			snapshots[i] = s
-		}
-
-		// Merge all snapshots into one
-		snapshot, err = types.MergeWithSources(snapshots, sources, message, snapshotTimestamp)
-		if err != nil {
-			return nil, fmt.Errorf("failed to merge snapshots: %w", err)
-		}
+	snapshot, err := e.scanSources(sources, message, snapshotTimestamp)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Printf("📦 Found %d files to back up\n", len(snapshot.Files))
+	fmt.Fprintf(e.output(), "📦 Found %d files to back up\n", len(snapshot.Files))
+	if len(snapshot.Oversized) > 0 {
+		printOversized(e.output(), snapshot.Oversized, e.config.Options.MaxFileSize)
+	}
+	if len(snapshot.Unreadable) > 0 {
+		printUnreadable(e.output(), snapshot.Unreadable)
+	}
+	if e.manifestOnly {
+		snapshot.ManifestOnly = true
+		fmt.Fprintln(e.output(), "📇 Manifest only: file contents will not be copied")
+	}
+	if len(labels) > 0 {
+		snapshot.Labels = labels
+		fmt.Fprintf(e.output(), "🏷️  Labels: %s\n", strings.Join(labels, ", "))
+	}
+	if collisions := snapshot.PathCollisions(); len(collisions) > 0 {
+		printPathCollisions(e.output(), collisions, "Restoring this snapshot on macOS or Windows would keep only one file of each group")
+	}
 
 	// Get last snapshot for comparison
 	lastSnapshot, err := e.destination.GetLastSnapshot()
@@ -270,47 +589,112 @@ func (e *BackupEngine) Backup(dryRun bool, message string, noScripts bool, force
 		return nil, fmt.Errorf("failed to get last snapshot: %w", err)
 	}
 
+	// Refuse to let a vanished source become the latest snapshot
+	if trackChanges && !force {
+		if reasons := checkSourceSize(snapshot, lastSnapshot, e.config.Options); len(reasons) > 0 {
+			fmt.Fprintln(e.output(), "🛑 The source looks unexpectedly empty:")
+			for _, reason := range reasons {
+				fmt.Fprintf(e.output(), "   • %s\n", reason)
+			}
+			fmt.Fprintln(e.output(), "💡 Check that the agent folder is mounted and intact. Use --force to back it up anyway")
+			if !dryRun {
+				return nil, fmt.Errorf("backup refused: %w (%s)", ErrSourceLooksEmpty, strings.Join(reasons, "; "))
+			}
+		}
+	}
+
 	var diff *types.SnapshotDiff
 	if lastSnapshot != nil {
-		diff = snapshot.Diff(lastSnapshot)
-		fmt.Printf("📊 Changes since last backup: %s\n", diff.String())
-
-		if diff.IsEmpty() && !force {
-			fmt.Println("✨ No changes detected. Backup skipped.")
-			fmt.Println("💡 Use --force flag to create backup anyway")
+		// A manifest-only checkpoint holds no files, so it does not make a full
+		// backup of the same state redundant
+		unchanged := snapshot.Equal(lastSnapshot) && (snapshot.ManifestOnly || !lastSnapshot.ManifestOnly)
+		if unchanged && !force {
+			// Skip building a diff of what is known to be identical
+			diff = &types.SnapshotDiff{
+				From:     lastSnapshot.ID,
+				To:       snapshot.ID,
+				Added:    []string{},
+				Removed:  []string{},
+				Modified: []string{},
+			}
+			fmt.Fprintf(e.output(), "📊 Changes since last backup: %s\n", diff.String())
+			fmt.Fprintln(e.resultOutput(), "✨ No changes detected. Backup skipped.")
+			fmt.Fprintln(e.output(), "💡 Use --force flag to create backup anyway")
 			return &types.BackupResult{
-				Snapshot: snapshot,
-				Diff:     diff,
-				Skipped:  true,
+				Snapshot:     snapshot,
+				Diff:         diff,
+				Skipped:      true,
+				LastSnapshot: lastSnapshot,
+				Unreadable:   snapshot.Unreadable,
 			}, nil
 		}
-		if diff.IsEmpty() && force {
-			fmt.Println("⚠️  No changes detected, but --force specified. Creating backup anyway.")
+
+		diff = snapshot.Diff(lastSnapshot)
+		fmt.Fprintf(e.output(), "📊 Changes since last backup: %s\n", diff.String())
+
+		if unchanged {
+			fmt.Fprintln(e.output(), "⚠️  No changes detected, but --force specified. Creating backup anyway.")
 		}
 	} else {
-		fmt.Println("📝 First backup - no previous snapshot found")
+		fmt.Fprintln(e.output(), "📝 First backup - no previous snapshot found")
+	}
+
+	if lastSnapshot != nil {
+		snapshot.Parent = lastSnapshot.ID
+	}
+
+	// Record the change rate and compare it against the baseline of recent backups
+	if diff != nil && !trackChanges {
+		snapshot.ChangeHistory = lastSnapshot.ChangeHistory
+	} else if diff != nil {
+		stats := diff.Stats()
+		if e.config.Anomaly.Enabled {
+			snapshot.Anomaly = DetectAnomaly(stats, lastSnapshot.ChangeHistory, e.config.Anomaly)
+			if snapshot.Anomaly != nil {
+				printAnomaly(e.output(), snapshot.Anomaly)
+				// Strict mode refuses the spike until someone reviews it; --force
+				// saves it once accepted, so the baseline moves on
+				if e.config.Anomaly.Strict && !dryRun && !force {
+					return nil, fmt.Errorf("backup refused in strict mode: unusual change rate, %s. Review with: bulletproof diff, then save it with: bulletproof backup --force", snapshot.Anomaly)
+				}
+				if e.config.Anomaly.Strict && force {
+					fmt.Fprintln(e.output(), "⚠️  Strict mode, but --force specified. Saving the backup anyway.")
+				}
+			}
+		}
+		snapshot.ChangeHistory = AppendChangeHistory(lastSnapshot.ChangeHistory, stats, e.config.Anomaly)
 	}
 
 	if dryRun {
-		fmt.Println("\n🔍 Dry run - no changes made")
+		fmt.Fprintln(e.output(), "\n🔍 Dry run - no changes made")
 		if diff != nil {
-			diff.PrintDetailed()
+			diff.PrintDetailed(e.resultOutput())
 		}
 		return &types.BackupResult{
-			Snapshot: snapshot,
-			Diff:     diff,
-			DryRun:   true,
+			Snapshot:     snapshot,
+			Diff:         diff,
+			DryRun:       true,
+			Anomaly:      snapshot.Anomaly,
+			LastSnapshot: lastSnapshot,
+			Unreadable:   snapshot.Unreadable,
 		}, nil
 	}
 
-	// Perform the backup
-	fmt.Printf("\n💾 Backing up to: %s\n", e.config.Destination.Path)
-
 	backupMessage := message
+	if backupMessage == "" && e.messagePrompt != nil {
+		backupMessage, err = e.messagePrompt(diff)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Message = backupMessage
+	}
 	if backupMessage == "" {
 		backupMessage = "Backup " + snapshot.ID
 	}
 
+	// Perform the backup
+	fmt.Fprintf(e.output(), "\n💾 Backing up to: %s\n", e.config.Destination.Location())
+
 	// Save based on number of sources
 	if len(sources) == 1 {
 		// Single source - use traditional Save method
@@ -328,13 +712,15 @@ func (e *BackupEngine) Backup(dryRun bool, message string, noScripts bool, force
 	// Copy config to snapshot for self-contained backups
 	if err := e.copyConfigToSnapshot(snapshot.ID); err != nil {
 		// Non-fatal - log but continue
-		fmt.Printf("⚠️  Warning: failed to copy config to snapshot: %v\n", err)
+		fmt.Fprintf(e.output(), "⚠️  Warning: failed to copy config to snapshot: %v\n", err)
 	}
 
-	// Copy scripts to snapshot for self-contained backups
-	if err := e.copyScriptsToSnapshot(snapshot.ID); err != nil {
-		// Non-fatal - log but continue
-		fmt.Printf("⚠️  Warning: failed to copy scripts to snapshot: %v\n", err)
+	// Copy scripts to snapshot for self-contained backups; a manifest cannot be restored, so it needs none
+	if !snapshot.ManifestOnly {
+		if err := e.copyScriptsToSnapshot(snapshot.ID); err != nil {
+			// Non-fatal - log but continue
+			fmt.Fprintf(e.output(), "⚠️  Warning: failed to copy scripts to snapshot: %v\n", err)
+		}
 	}
 
 	// Copy exports directory to snapshot if scripts were executed
@@ -342,16 +728,33 @@ func (e *BackupEngine) Backup(dryRun bool, message string, noScripts bool, force
 		snapshotPath, err := e.getSnapshotPath(snapshot.ID)
 		if err == nil && snapshotPath != "" {
 			if err := scripts.CopyExportsToSnapshot(exportsDir, snapshotPath); err != nil {
-				fmt.Printf("⚠️  Warning: failed to copy exports to snapshot: %v\n", err)
+				fmt.Fprintf(e.output(), "⚠️  Warning: failed to copy exports to snapshot: %v\n", err)
 			}
 		}
 	}
 
-	fmt.Printf("✅ Backup complete: %s\n", snapshot.ID)
+	// Cloud sync clients upload in the background; check the backup actually landed
+	if e.config.Destination.SyncVerify().Enabled {
+		fmt.Fprintln(e.output(), "🔍 Verifying sync destination...")
+		if problems := e.verifySyncWrite(snapshot); len(problems) > 0 {
+			fmt.Fprintln(e.output(), "⚠️  Warning: sync destination does not reflect this backup yet:")
+			for _, problem := range problems {
+				fmt.Fprintf(e.output(), "    %s\n", problem)
+			}
+			fmt.Fprintln(e.output(), "💡 The cloud copy may be incomplete. Check your sync client, then run: bulletproof verify")
+		} else {
+			fmt.Fprintln(e.output(), "  Sync destination verified")
+		}
+	}
+
+	fmt.Fprintf(e.resultOutput(), "✅ Backup complete: %s\n", snapshot.ID)
 
 	return &types.BackupResult{
-		Snapshot: snapshot,
-		Diff:     diff,
+		Snapshot:     snapshot,
+		Diff:         diff,
+		Anomaly:      snapshot.Anomaly,
+		LastSnapshot: lastSnapshot,
+		Unreadable:   snapshot.Unreadable,
 	}, nil
 }
 
@@ -403,11 +806,10 @@ func (e *BackupEngine) copyConfigToSnapshot(snapshotID string) error {
 // copyScriptsToSnapshot copies the scripts directory to the snapshot's .bulletproof/scripts directory
 func (e *BackupEngine) copyScriptsToSnapshot(snapshotID string) error {
 	// Determine scripts source path
-	configDir, err := config.ConfigDir()
+	scriptsDir, err := e.config.ScriptsDir()
 	if err != nil {
-		return fmt.Errorf("failed to get config directory: %w", err)
+		return fmt.Errorf("failed to get scripts directory: %w", err)
 	}
-	scriptsDir := filepath.Join(configDir, "scripts")
 
 	// Check if scripts directory exists
 	if _, err := os.Stat(scriptsDir); os.IsNotExist(err) {
@@ -432,6 +834,25 @@ func (e *BackupEngine) ListBackups() ([]*types.SnapshotInfo, error) {
 	return e.destination.ListSnapshots()
 }
 
+// ContentReader returns a reader for stored snapshot files, or nil when the
+// destination cannot read single files back
+func (e *BackupEngine) ContentReader() types.DestinationContentReader {
+	if reader, ok := e.destination.(types.DestinationContentReader); ok {
+		return reader
+	}
+	return nil
+}
+
+// SyncRemote pushes the backups the destination's remote does not have yet,
+// e.g. those saved while offline, and reports the outcome per ref
+func (e *BackupEngine) SyncRemote() ([]types.RefSync, error) {
+	syncer, ok := e.destination.(remoteSyncer)
+	if !ok {
+		return nil, fmt.Errorf("%s destinations have no remote to sync with", e.config.Destination.Type)
+	}
+	return syncer.Sync()
+}
+
 // ShowDiff shows the diff between current state and last backup
 func (e *BackupEngine) ShowDiff() (*types.SnapshotDiff, error) {
 	openclawPath, err := e.OpenclawPath()
@@ -439,11 +860,7 @@ func (e *BackupEngine) ShowDiff() (*types.SnapshotDiff, error) {
 		return nil, err
 	}
 
-	current, err := types.FromDirectory(
-		openclawPath,
-		e.config.Options.Exclude,
-		"",
-	)
+	current, err := e.ScanSource(openclawPath, "", time.Now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create current snapshot: %w", err)
 	}
@@ -454,7 +871,7 @@ func (e *BackupEngine) ShowDiff() (*types.SnapshotDiff, error) {
 	}
 
 	if last == nil {
-		fmt.Println("No previous backup found.")
+		fmt.Fprintln(e.output(), "No previous backup found.")
 		return nil, nil
 	}
 
@@ -464,236 +881,496 @@ func (e *BackupEngine) ShowDiff() (*types.SnapshotDiff, error) {
 // RestoreToTarget restores from a specific backup to a target location
 // If target is empty, restores to the configured OpenClaw path
 func (e *BackupEngine) RestoreToTarget(snapshotID string, target string, dryRun bool, noScripts bool, force bool) error {
+	_, err := e.RestoreWithResult(snapshotID, target, dryRun, noScripts, force)
+	return err
+}
+
+// RestoreWithResult restores from a specific backup like RestoreToTarget and
+// reports what it did: files restored and removed, the safety backup and the
+// post-restore scripts run. If target is empty, restores to the configured
+// OpenClaw path.
+func (e *BackupEngine) RestoreWithResult(snapshotID string, target string, dryRun bool, noScripts bool, force bool) (*types.RestoreResult, error) {
 	// Resolve short IDs to full timestamp IDs
 	resolvedID, err := e.ResolveSnapshotID(snapshotID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Special case: ID 0 means current state (nothing to restore)
 	if resolvedID == "0" {
-		return fmt.Errorf("cannot restore to ID 0 (current filesystem state)")
+		return nil, fmt.Errorf("cannot restore to ID 0 (current filesystem state)")
 	}
 
 	// Determine restore target
 	var openclawPath string
 	if target != "" {
 		openclawPath = target
-		fmt.Printf("🎯 Restoring to alternative location: %s\n", target)
+		fmt.Fprintf(e.output(), "🎯 Restoring to alternative location: %s\n", target)
 	} else {
 		openclawPath, err = e.OpenclawPath()
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// Show both short and full ID if they differ
 	if snapshotID != resolvedID {
-		fmt.Printf("🔍 Looking for backup: %s (ID %s)\n", resolvedID, snapshotID)
+		fmt.Fprintf(e.output(), "🔍 Looking for backup: %s (ID %s)\n", resolvedID, snapshotID)
 	} else {
-		fmt.Printf("🔍 Looking for backup: %s\n", resolvedID)
+		fmt.Fprintf(e.output(), "🔍 Looking for backup: %s\n", resolvedID)
 	}
 
 	snapshot, err := e.destination.GetSnapshot(resolvedID)
 	if err != nil {
-		return fmt.Errorf("failed to get snapshot: %w", err)
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
 	}
 
 	if snapshot == nil {
-		return fmt.Errorf("backup not found: %s", snapshotID)
+		return nil, fmt.Errorf("backup not found: %s", snapshotID)
+	}
+
+	if snapshot.ManifestOnly {
+		return nil, manifestOnlyError(snapshot.ID)
 	}
 
-	fmt.Printf("📦 Found backup with %d files\n", len(snapshot.Files))
+	result := &types.RestoreResult{
+		SnapshotID:    resolvedID,
+		Target:        openclawPath,
+		DryRun:        dryRun,
+		FilesRestored: len(snapshot.Files),
+	}
+
+	fmt.Fprintf(e.output(), "📦 Found backup with %d files\n", len(snapshot.Files))
+	printPlatformMismatch(e.output(), snapshot, runtime.GOOS)
+	if collisions := snapshot.PathCollisions(); len(collisions) > 0 {
+		printPathCollisions(e.output(), collisions, "On a case-insensitive or Unicode-normalizing filesystem only one file of each group survives the restore")
+	}
 
 	if dryRun {
-		fmt.Println("\n🔍 Dry run - would restore these files:")
+		fmt.Fprintln(e.output(), "\n🔍 Dry run - would restore these files:")
 		count := 0
 		for file := range snapshot.Files {
 			if count < 20 {
-				fmt.Printf("  %s\n", file)
+				fmt.Fprintf(e.output(), "  %s\n", file)
 			}
 			count++
 		}
 		if count > 20 {
-			fmt.Printf("  ... and %d more\n", count-20)
+			fmt.Fprintf(e.output(), "  ... and %d more\n", count-20)
+		}
+		return result, nil
+	}
+
+	// A new or empty --target has nothing to overwrite, so there is nothing to confirm
+	fresh := false
+	if target != "" {
+		if fresh, err = isFreshTarget(openclawPath); err != nil {
+			return nil, err
 		}
-		return nil
+	}
+
+	// A damaged backup fails before the target is touched
+	if err := e.verifyRestoreSource(snapshot); err != nil {
+		return nil, err
 	}
 
 	// Show changes and ask for confirmation (unless force is set)
-	if !force {
+	if !force && !fresh {
 		// Create current snapshot to diff against
-		currentSnapshot, err := types.FromDirectory(openclawPath, e.config.Options.Exclude, "")
+		currentSnapshot, err := e.ScanSource(openclawPath, "", time.Now())
 		if err != nil {
-			return fmt.Errorf("failed to create current snapshot for comparison: %w", err)
+			return nil, fmt.Errorf("failed to create current snapshot for comparison: %w", err)
 		}
 
-		// Calculate diff
-		diff := snapshot.Diff(currentSnapshot)
+		if !snapshot.Equal(currentSnapshot) {
+			// Restoring goes from the current state to the backup, so "+" files
+			// exist only in the backup and "-" files only in the current state
+			diff := snapshot.Diff(currentSnapshot)
 
-		if !diff.IsEmpty() {
-			fmt.Println("\n📋 Changes that will be applied:")
+			fmt.Fprintln(e.output(), "\n📋 Changes that will be applied:")
 			if len(diff.Added) > 0 {
-				fmt.Printf("  + %d files will be removed (currently exist, not in backup)\n", len(diff.Added))
-			}
-			if len(diff.Removed) > 0 {
-				fmt.Printf("  + %d files will be added (in backup, don't exist currently)\n", len(diff.Removed))
+				fmt.Fprintf(e.output(), "  + %d files will be added (in backup, don't exist currently)\n", len(diff.Added))
 			}
 			if len(diff.Modified) > 0 {
-				fmt.Printf("  ~ %d files will be modified\n", len(diff.Modified))
+				fmt.Fprintf(e.output(), "  ~ %d files will be modified\n", len(diff.Modified))
+			}
+			if len(diff.Renamed) > 0 {
+				fmt.Fprintf(e.output(), "  ~ %d files will be renamed (name differs only in case)\n", len(diff.Renamed))
 			}
-
-			// Show sample files
-			fmt.Println()
-			sampleCount := 0
-			maxSamples := 10
-
 			if len(diff.Removed) > 0 {
-				fmt.Println("Files to be added:")
-				for _, filePath := range diff.Removed {
-					if sampleCount >= maxSamples {
-						fmt.Printf("  ... and %d more\n", len(diff.Removed)-maxSamples)
-						break
-					}
-					fmt.Printf("  + %s\n", filePath)
-					sampleCount++
-				}
-				fmt.Println()
+				fmt.Fprintf(e.output(), "  - %d files will be removed (currently exist, not in backup)\n", len(diff.Removed))
 			}
 
-			sampleCount = 0
-			if len(diff.Modified) > 0 {
-				fmt.Println("Files to be modified:")
-				for _, filePath := range diff.Modified {
-					if sampleCount >= maxSamples {
-						fmt.Printf("  ... and %d more\n", len(diff.Modified)-maxSamples)
-						break
-					}
-					fmt.Printf("  ~ %s\n", filePath)
-					sampleCount++
-				}
-				fmt.Println()
+			// Show sample files
+			fmt.Fprintln(e.output())
+			printRestoreSample(e.output(), "Files to be added:", "+", diff.Added)
+			printRestoreSample(e.output(), "Files to be modified:", "~", diff.Modified)
+			renamed := make([]string, len(diff.Renamed))
+			for i, rename := range diff.Renamed {
+				renamed[i] = rename.String()
 			}
+			printRestoreSample(e.output(), "Files to be renamed:", "~", renamed)
+			printRestoreSample(e.output(), "Files to be removed:", "-", diff.Removed)
 
-			sampleCount = 0
-			if len(diff.Added) > 0 {
-				fmt.Println("Files to be removed:")
-				for _, filePath := range diff.Added {
-					if sampleCount >= maxSamples {
-						fmt.Printf("  ... and %d more\n", len(diff.Added)-maxSamples)
-						break
-					}
-					fmt.Printf("  - %s\n", filePath)
-					sampleCount++
-				}
-				fmt.Println()
+			if e.previewRestore != nil {
+				e.printRestorePreview(diff.Filter(e.previewRestore), openclawPath, currentSnapshot, snapshot)
 			}
 
-			fmt.Print("⚠️  This will overwrite your current files. Are you sure? [y/N]: ")
+			fmt.Fprint(e.resultOutput(), "⚠️  This will overwrite your current files. Are you sure? [y/N]: ")
 			var response string
 			fmt.Scanln(&response)
 			if response != "y" && response != "Y" {
-				fmt.Println("❌ Restore cancelled.")
-				fmt.Println("💡 Use --force flag to skip this confirmation prompt")
-				return nil
+				fmt.Fprintln(e.resultOutput(), "❌ Restore cancelled.")
+				fmt.Fprintln(e.resultOutput(), "💡 Use --force flag to skip this confirmation prompt")
+				result.Cancelled = true
+				return result, nil
 			}
 		} else {
-			fmt.Println("\n✨ No changes detected - current state matches backup exactly.")
-			fmt.Println("💡 Proceeding with restore anyway to ensure consistency.")
+			fmt.Fprintln(e.resultOutput(), "\n✨ No changes detected - current state matches backup exactly.")
+			fmt.Fprintln(e.output(), "💡 Proceeding with restore anyway to ensure consistency.")
 		}
 	}
 
+	unlock, err := e.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	// Create backup of current state before restore
-	fmt.Println("\n⚠️  Creating safety backup before restore...")
-	safetyBackup, err := e.Backup(false, "Pre-restore safety backup", noScripts, false)
+	safetyBackup, err := e.safetyBackup(target, openclawPath, noScripts)
 	if err != nil {
-		return fmt.Errorf("failed to create safety backup: %w", err)
+		return nil, fmt.Errorf("failed to create safety backup: %w", err)
 	}
 
-	if !safetyBackup.Skipped {
-		fmt.Printf("📝 Safety backup created: %s\n", safetyBackup.Snapshot.ID)
+	if safetyBackup != nil && !safetyBackup.Skipped {
+		fmt.Fprintf(e.output(), "📝 Safety backup created: %s\n", safetyBackup.Snapshot.ID)
+		result.SafetyBackupID = safetyBackup.Snapshot.ID
 	}
 
-	// Perform restore
-	fmt.Printf("\n🔄 Restoring from %s...\n", snapshotID)
-	err = e.destination.Restore(resolvedID, openclawPath)
+	// Note what is there, to count what the restore removes
+	before, err := listFiles(openclawPath)
 	if err != nil {
-		return fmt.Errorf("failed to restore: %w", err)
+		return nil, fmt.Errorf("failed to list restore target: %w", err)
 	}
 
-	fmt.Println("✅ Restore complete!")
-	if !safetyBackup.Skipped {
-		fmt.Printf("💡 If something went wrong, restore from: %s\n", safetyBackup.Snapshot.ID)
+	// Perform restore. The agent ends up either fully restored or as it was.
+	fmt.Fprintf(e.output(), "\n🔄 Restoring from %s...\n", snapshotID)
+	if touched, err := e.restoreStaged(snapshot, openclawPath); err != nil {
+		if touched {
+			return nil, e.rollBackRestore(openclawPath, safetyBackup, err)
+		}
+		return nil, fmt.Errorf("failed to restore, %s was left unchanged: %w", openclawPath, err)
+	}
+	for _, file := range before {
+		if _, err := os.Lstat(file); os.IsNotExist(err) {
+			result.FilesRemoved++
+		}
+	}
+
+	fmt.Fprintln(e.resultOutput(), "✅ Restore complete!")
+	if safetyBackup != nil && !safetyBackup.Skipped {
+		fmt.Fprintf(e.output(), "💡 If something went wrong, restore from it: %s\n", safetyBackup.restoreCommand())
 	}
 
 	// Execute post-restore scripts (unless disabled)
 	if !noScripts && len(e.config.Scripts.PostRestore) > 0 {
-		// Show security warning unless force is enabled
-		if !force {
-			fmt.Println("\n⚠️  SECURITY WARNING")
-			fmt.Println("╭─────────────────────────────────────────────────────────────╮")
-			fmt.Println("│ This backup contains post-restore scripts that will execute │")
-			fmt.Println("│ with your system permissions. Scripts from untrusted        │")
-			fmt.Println("│ sources can:                                                │")
-			fmt.Println("│   • Access your files and data                              │")
-			fmt.Println("│   • Execute arbitrary commands                              │")
-			fmt.Println("│   • Install backdoors or malware                            │")
-			fmt.Println("│                                                              │")
-			fmt.Println("│ Scripts to be executed:                                     │")
-			for _, script := range e.config.Scripts.PostRestore {
-				fmt.Printf("│   • %s: %s\n", script.Name, script.Command)
-			}
-			fmt.Println("│                                                              │")
-			fmt.Println("│ Safety options:                                             │")
-			fmt.Println("│   • Use --no-scripts to skip script execution               │")
-			fmt.Println("│   • Review scripts in .bulletproof/scripts/ first           │")
-			fmt.Println("│   • Only use --force for verified trusted backups           │")
-			fmt.Println("╰─────────────────────────────────────────────────────────────╯")
-			fmt.Print("\nDo you want to proceed with script execution? [y/N]: ")
+		executed, err := e.runPostRestoreScripts(resolvedID, openclawPath, "", force, "❌ Script execution cancelled. Restore completed without scripts.")
+		if err != nil {
+			return nil, err
+		}
+		result.ScriptsExecuted = executed
+	}
 
-			var response string
-			fmt.Scanln(&response)
-			if response != "y" && response != "Y" {
-				fmt.Println("❌ Script execution cancelled. Restore completed without scripts.")
-				fmt.Println("💡 Use --no-scripts flag to skip scripts automatically")
-				return nil
-			}
+	return result, nil
+}
+
+// runPostRestoreScripts runs the configured post-restore scripts for a
+// restored snapshot, after a security warning unless force is set. Scripts
+// write to and read from exportsDir, the config directory's _exports when
+// empty. It returns the names of the scripts run, none if the user declined,
+// in which case cancelled is printed.
+func (e *BackupEngine) runPostRestoreScripts(resolvedID string, openclawPath string, exportsDir string, force bool, cancelled string) ([]string, error) {
+	// Show security warning unless force is enabled
+	if !force {
+		fmt.Fprintln(e.resultOutput(), "\n⚠️  SECURITY WARNING")
+		fmt.Fprintln(e.resultOutput(), "╭─────────────────────────────────────────────────────────────╮")
+		fmt.Fprintln(e.resultOutput(), "│ This backup contains post-restore scripts that will execute │")
+		fmt.Fprintln(e.resultOutput(), "│ with your system permissions. Scripts from untrusted        │")
+		fmt.Fprintln(e.resultOutput(), "│ sources can:                                                │")
+		fmt.Fprintln(e.resultOutput(), "│   • Access your files and data                              │")
+		fmt.Fprintln(e.resultOutput(), "│   • Execute arbitrary commands                              │")
+		fmt.Fprintln(e.resultOutput(), "│   • Install backdoors or malware                            │")
+		fmt.Fprintln(e.resultOutput(), "│                                                              │")
+		fmt.Fprintln(e.resultOutput(), "│ Scripts to be executed:                                     │")
+		for _, script := range e.config.Scripts.PostRestore {
+			fmt.Fprintf(e.resultOutput(), "│   • %s: %s\n", script.Name, script.Command)
 		}
+		fmt.Fprintln(e.resultOutput(), "│                                                              │")
+		fmt.Fprintln(e.resultOutput(), "│ Safety options:                                             │")
+		fmt.Fprintln(e.resultOutput(), "│   • Use --no-scripts to skip script execution               │")
+		fmt.Fprintln(e.resultOutput(), "│   • Review scripts in .bulletproof/scripts/ first           │")
+		fmt.Fprintln(e.resultOutput(), "│   • Only use --force for verified trusted backups           │")
+		fmt.Fprintln(e.resultOutput(), "╰─────────────────────────────────────────────────────────────╯")
+		fmt.Fprint(e.resultOutput(), "\nDo you want to proceed with script execution? [y/N]: ")
+
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Fprintln(e.resultOutput(), cancelled)
+			fmt.Fprintln(e.output(), "💡 Use --no-scripts flag to skip scripts automatically")
+			return nil, nil
+		}
+	}
 
-		fmt.Println("\n📜 Executing post-restore scripts...")
+	fmt.Fprintln(e.output(), "\n📜 Executing post-restore scripts...")
 
-		// Create _exports directory
+	// Create _exports directory
+	if exportsDir == "" {
 		configDir, err := config.ConfigDir()
 		if err != nil {
-			return fmt.Errorf("failed to get config directory: %w", err)
+			return nil, fmt.Errorf("failed to get config directory: %w", err)
 		}
-		exportsDir, err := scripts.CreateExportsDir(configDir)
+		exportsDir, err = scripts.CreateExportsDir(configDir)
 		if err != nil {
-			return fmt.Errorf("failed to create exports directory: %w", err)
+			return nil, fmt.Errorf("failed to create exports directory: %w", err)
 		}
+	}
 
-		// Get snapshot directory path (where _exports is located)
-		snapshotDir := filepath.Join(e.config.Destination.Path, resolvedID)
+	// Get snapshot directory path (where _exports is located)
+	snapshotDir := filepath.Join(e.config.Destination.Location(), resolvedID)
 
-		// Execute scripts
-		executor := scripts.NewExecutor(
-			convertScriptConfigs(e.config.Scripts.PostRestore),
-			scripts.ExecutionContext{
-				SnapshotID:   resolvedID,
-				OpenClawPath: openclawPath,
-				BackupDir:    snapshotDir,
-				ExportsDir:   exportsDir,
-			},
-		)
+	scriptsDir, err := e.config.ScriptsDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scripts directory: %w", err)
+	}
+	postRestoreScripts := convertScriptConfigs(e.config.Scripts.PostRestore)
 
-		if err := executor.Execute(); err != nil {
-			return fmt.Errorf("post-restore script failed: %w", err)
+	// Run the snapshot's bundled scripts from an isolated copy so they
+	// never overwrite the configured scripts directory
+	bundlePath, err := e.getSnapshotPath(resolvedID)
+	if err != nil {
+		return nil, err
+	}
+	if bundlePath != "" {
+		isolatedDir, cleanup, err := scripts.IsolateBundledScripts(bundlePath)
+		if err != nil {
+			return nil, err
 		}
+		defer cleanup()
 
-		fmt.Println("✅ Post-restore scripts completed")
+		if isolatedDir != "" {
+			postRestoreScripts = scripts.RebaseScripts(postRestoreScripts, scriptsDir, isolatedDir)
+			scriptsDir = isolatedDir
+			fmt.Fprintf(e.output(), "🔒 Using bundled scripts from isolated directory: %s\n", isolatedDir)
+		}
 	}
 
-	return nil
+	// Execute scripts
+	executor := scripts.NewExecutor(
+		postRestoreScripts,
+		scripts.ExecutionContext{
+			SnapshotID:   resolvedID,
+			OpenClawPath: openclawPath,
+			BackupDir:    snapshotDir,
+			ExportsDir:   exportsDir,
+			ScriptsDir:   scriptsDir,
+		},
+	)
+
+	if err := executor.Execute(); err != nil {
+		return nil, fmt.Errorf("post-restore script failed: %w", err)
+	}
+
+	fmt.Fprintln(e.output(), "✅ Post-restore scripts completed")
+	executed := make([]string, 0, len(postRestoreScripts))
+	for _, script := range postRestoreScripts {
+		executed = append(executed, script.Name)
+	}
+	return executed, nil
+}
+
+// safetyBackup snapshots whatever a restore is about to overwrite.
+// For the configured sources this is a regular backup. For an alternate target
+// only the target directory is captured, into the target's own store (see
+// targetStore) and without pre-backup scripts since they export the configured
+// agent rather than the target. Returns nil if an alternate target does not
+// exist yet, as there is nothing to overwrite.
+func (e *BackupEngine) safetyBackup(target string, targetPath string, noScripts bool) (*safetyBackupResult, error) {
+	if target == "" {
+		fmt.Fprintln(e.output(), "\n⚠️  Creating safety backup before restore...")
+		// Part of the restore rather than a backup of its own, so not notified
+		result, err := e.backupWithLabels(false, "Pre-restore safety backup", noScripts, false, nil)
+		if errors.Is(err, ErrSourceLooksEmpty) {
+			// Whatever is left is still worth keeping before it is overwritten
+			fmt.Fprintln(e.output(), "💡 Saving what is there anyway, so the restore can go ahead")
+			result, err = e.backupWithLabels(false, "Pre-restore safety backup", noScripts, true, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &safetyBackupResult{BackupResult: result, destination: e.destination}, nil
+	}
+
+	fresh, err := isFreshTarget(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	if fresh {
+		fmt.Fprintln(e.output(), "\n💡 Fresh restore into empty target — no safety backup needed.")
+		return nil, nil
+	}
+
+	store, configPath, err := e.targetStore(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(e.output(), "\n⚠️  Creating safety backup of %s before restore, kept apart from the agent's snapshots...\n", targetPath)
+	result, err := store.backupSources([]string{targetPath}, false, "Pre-restore safety backup of "+targetPath, nil, true, false, false)
+	if err != nil {
+		return nil, err
+	}
+	return &safetyBackupResult{BackupResult: result, destination: store.destination, configPath: configPath}, nil
+}
+
+// isFreshTarget reports whether a restore target does not exist yet or is an
+// empty directory, leaving nothing to protect or remove
+func isFreshTarget(targetPath string) (bool, error) {
+	dir, err := os.Open(targetPath)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check restore target: %w", err)
+	}
+	defer dir.Close()
+
+	info, err := dir.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to check restore target: %w", err)
+	}
+	if !info.IsDir() {
+		return false, nil
+	}
+	if _, err := dir.Readdirnames(1); err != io.EOF {
+		if err != nil {
+			return false, fmt.Errorf("failed to check restore target: %w", err)
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// listFiles returns the paths of the files below root, or none if root does
+// not exist
+func listFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// PlanRestore computes what restoring a snapshot to target would change, without
+// printing, creating a safety backup, or writing to the target.
+// If target is empty, the configured OpenClaw path is used.
+func (e *BackupEngine) PlanRestore(snapshotID string, target string) (*types.RestorePlan, error) {
+	snapshot, current, target, err := e.restoreSides(snapshotID, target)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewRestorePlan(snapshot, current, target), nil
+}
+
+// CompareRestore prints how target differs from a snapshot, as the changes a
+// restore would make, with content diffs for modified files. Nothing is
+// written: no safety backup is made and no scripts run. It returns the diff
+// from the current state to the snapshot.
+// If target is empty, the configured OpenClaw path is used.
+func (e *BackupEngine) CompareRestore(snapshotID string, target string) (*types.SnapshotDiff, error) {
+	snapshot, current, target, err := e.restoreSides(snapshotID, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restoring goes from the current state to the backup, so "+" files exist
+	// only in the backup and "-" files only in the current state
+	diff := snapshot.Diff(current)
+	if diff.IsEmpty() {
+		fmt.Fprintf(e.output(), "✨ %s matches backup %s exactly.\n", target, snapshot.ID)
+		return diff, nil
+	}
+
+	fmt.Fprintf(e.output(), "📋 Restoring %s to %s would change: %s\n\n", snapshot.ID, target, diff.String())
+	diff.PrintUnifiedWithReaders(types.DirContentReader(target), e.ContentReader(), current, snapshot)
+	return diff, nil
+}
+
+// printRestorePreview prints the unified diff of a restore's changes to the
+// target, read from the target's files and the snapshot's stored content
+func (e *BackupEngine) printRestorePreview(diff *types.SnapshotDiff, target string, current, snapshot *types.Snapshot) {
+	if diff.IsEmpty() {
+		fmt.Fprintln(e.output(), "🔍 Nothing to preview: no changed file matches.")
+		fmt.Fprintln(e.output())
+		return
+	}
+	fmt.Fprintln(e.output(), "🔍 Preview of the changes:")
+	fmt.Fprintln(e.output())
+	diff.PrintUnifiedWithReaders(types.DirContentReader(target), e.ContentReader(), current, snapshot)
+	fmt.Fprintln(e.output())
+}
+
+// restoreSides loads the two sides of a restore: the snapshot and a scan of the
+// target, which is empty when the target does not exist yet. It returns the
+// target path actually used.
+func (e *BackupEngine) restoreSides(snapshotID string, target string) (*types.Snapshot, *types.Snapshot, string, error) {
+	resolvedID, err := e.ResolveSnapshotID(snapshotID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if resolvedID == "0" {
+		return nil, nil, "", fmt.Errorf("cannot restore to ID 0 (current filesystem state)")
+	}
+
+	if target == "" {
+		target, err = e.OpenclawPath()
+		if err != nil {
+			return nil, nil, "", err
+		}
+	}
+
+	snapshot, err := e.destination.GetSnapshot(resolvedID)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	if snapshot == nil {
+		return nil, nil, "", fmt.Errorf("backup not found: %s", snapshotID)
+	}
+	if snapshot.ManifestOnly {
+		return nil, nil, "", manifestOnlyError(snapshot.ID)
+	}
+
+	// A target that does not exist yet would receive every file
+	current := &types.Snapshot{Files: map[string]*types.FileSnapshot{}}
+	if _, err := os.Stat(target); err == nil {
+		current, err = e.ScanSource(target, "", time.Now())
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("failed to create current snapshot for comparison: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, nil, "", fmt.Errorf("failed to check restore target: %w", err)
+	}
+
+	return snapshot, current, target, nil
 }
 
 // Restore restores from a specific backup to the configured OpenClaw path
@@ -709,11 +1386,238 @@ func convertScriptConfigs(configs []config.ScriptConfig) []scripts.ScriptConfig
 			Name:    cfg.Name,
 			Command: cfg.Command,
 			Timeout: cfg.Timeout,
+			Shell:   cfg.Shell,
 		}
 	}
 	return result
 }
 
+// printPathCollisions warns about files whose names differ only by case or
+// Unicode normalization, since folding filesystems would merge them
+func printPathCollisions(w io.Writer, collisions [][]string, consequence string) {
+	fmt.Fprintf(w, "⚠️  %d group(s) of file names differ only by case or Unicode normalization:\n", len(collisions))
+	for _, group := range collisions {
+		fmt.Fprintf(w, "   • %s\n", strings.Join(quotePaths(group), ", "))
+	}
+	fmt.Fprintf(w, "💡 %s\n", consequence)
+}
+
+// printPlatformMismatch warns when a snapshot was taken on another operating
+// system than goos, since paths in its config files may not work here
+func printPlatformMismatch(w io.Writer, snapshot *types.Snapshot, goos string) {
+	if snapshot.OS == "" || snapshot.OS == goos {
+		return
+	}
+	origin := types.PlatformName(snapshot.OS)
+	if snapshot.Hostname != "" {
+		origin = fmt.Sprintf("%s (%s)", snapshot.Hostname, origin)
+	}
+	fmt.Fprintf(w, "⚠️  This backup was taken on %s and is being restored on %s\n", origin, types.PlatformName(goos))
+	fmt.Fprintln(w, "💡 Paths in openclaw.json and other config files may need adjusting for this platform")
+}
+
+// printRestoreSample lists up to ten of the files a restore will change
+func printRestoreSample(w io.Writer, header, marker string, files []string) {
+	const maxSamples = 10
+	if len(files) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, header)
+	for i, filePath := range files {
+		if i >= maxSamples {
+			fmt.Fprintf(w, "  ... and %d more\n", len(files)-maxSamples)
+			break
+		}
+		fmt.Fprintf(w, "  %s %s\n", marker, filePath)
+	}
+	fmt.Fprintln(w)
+}
+
+// quotePaths quotes paths so names differing only in invisible code points are distinguishable
+func quotePaths(paths []string) []string {
+	quoted := make([]string, len(paths))
+	for i, path := range paths {
+		quoted[i] = strconv.QuoteToASCII(path)
+	}
+	return quoted
+}
+
+// scanSources snapshots the current state of all sources as one snapshot.
+// A single source is scanned as is; several are merged under their prefixes.
+func (e *BackupEngine) scanSources(sources []string, message string, timestamp time.Time) (*types.Snapshot, error) {
+	if len(sources) == 1 {
+		snapshot, err := e.ScanSource(sources[0], message, timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create snapshot: %w", err)
+		}
+		return snapshot, nil
+	}
+
+	prefixes, err := e.config.SourcePrefixes()
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]*types.Snapshot, len(sources))
+	for i, source := range sources {
+		s, err := e.ScanSource(source, "", timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create snapshot for %s: %w", source, err)
+		}
+		snapshots[i] = s
+	}
+
+	snapshot, err := types.MergeWithPrefixes(snapshots, sources, prefixes, message, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge snapshots: %w", err)
+	}
+	return snapshot, nil
+}
+
+// changedFiles lists the paths added, modified, removed or renamed in the
+// sources since the last snapshot, sorted. Before the first backup every file
+// counts as added.
+func (e *BackupEngine) changedFiles(sources []string, timestamp time.Time) ([]string, error) {
+	current, err := e.scanSources(sources, "", timestamp)
+	if err != nil {
+		return nil, err
+	}
+	last, err := e.destination.GetLastSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last snapshot: %w", err)
+	}
+	if last == nil {
+		last = &types.Snapshot{Files: map[string]*types.FileSnapshot{}}
+	}
+
+	diff := current.Diff(last)
+	changed := append(append(append([]string{}, diff.Added...), diff.Modified...), diff.Removed...)
+	for _, rename := range diff.Renamed {
+		changed = append(changed, rename.From, rename.To)
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// ScanSource snapshots the current state of a source directory, picking up
+// files as a backup would: include and exclude patterns, including the
+// source's own excludes, and options.include_hidden apply, and a destination
+// folder inside the source is never scanned
+func (e *BackupEngine) ScanSource(path string, message string, timestamp time.Time) (*types.Snapshot, error) {
+	opts := types.ScanOptions{
+		Include:        e.config.Options.Include,
+		Exclude:        e.config.ExcludesFor(path),
+		ExcludeHidden:  !e.config.Options.IncludeHiddenFiles(),
+		ContentLimit:   e.config.Options.ContentLimit(),
+		MaxFileSize:    e.config.Options.MaxFileSize,
+		SkipUnreadable: e.config.Options.SkipUnreadable(),
+	}
+	if destDir := e.destinationDir(); destDir != "" {
+		opts.SkipPaths = append(opts.SkipPaths, destDir)
+	}
+	snapshot, err := types.ScanDirectory(path, opts, message, timestamp)
+	var readErr *types.ReadError
+	if errors.As(err, &readErr) {
+		return nil, fmt.Errorf("%w (set options.on_read_error to skip to back up the rest)", err)
+	}
+	return snapshot, err
+}
+
+// destinationDir returns the folder backups are written to, or "" if it is not
+// on this filesystem
+func (e *BackupEngine) destinationDir() string {
+	var destPath string
+	if dest, ok := localDestination(e.destination); ok {
+		destPath = dest.BasePath
+	} else if dest, ok := e.destination.(*destinations.GitDestination); ok {
+		destPath = dest.RepoPath
+	}
+	expanded, err := utils.ExpandPath(destPath)
+	if err != nil {
+		return ""
+	}
+	return expanded
+}
+
+// pathWithin reports whether path is dir or lies beneath it
+func pathWithin(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// localDestination returns the local destination behind d, including the one
+// a sync destination wraps
+func localDestination(d Destination) (*destinations.LocalDestination, bool) {
+	switch dest := d.(type) {
+	case *destinations.LocalDestination:
+		return dest, true
+	case *destinations.SyncDestination:
+		return dest.LocalDestination, true
+	default:
+		return nil, false
+	}
+}
+
+// storedSnapshotFiles returns a directory holding a stored snapshot's files as
+// recorded in its manifest. Destinations without a per-snapshot directory (git)
+// and compressed or encrypted snapshots are read through a scratch restore,
+// which cleanup removes.
+func (e *BackupEngine) storedSnapshotFiles(snapshotID string) (string, func(), error) {
+	noop := func() {}
+
+	if local, ok := localDestination(e.destination); ok && !local.Timestamped {
+		// Non-timestamped destinations only keep the latest snapshot's files
+		latest, err := e.destination.GetLastSnapshot()
+		if err != nil {
+			return "", noop, fmt.Errorf("failed to get latest snapshot: %w", err)
+		}
+		if latest == nil || latest.ID != snapshotID {
+			return "", noop, fmt.Errorf("files of snapshot %s are no longer stored: this destination only keeps the latest snapshot", snapshotID)
+		}
+		if latest.StoredVerbatim() {
+			return local.BasePath, noop, nil
+		}
+	}
+
+	snapshot, err := e.destination.GetSnapshot(snapshotID)
+	if err == nil && snapshot != nil && snapshot.ManifestOnly {
+		return "", noop, manifestOnlyError(snapshotID)
+	}
+	transformed := err == nil && snapshot != nil && !snapshot.StoredVerbatim()
+
+	if path := e.destination.GetSnapshotPath(snapshotID); path != "" && !transformed {
+		return path, noop, nil
+	}
+
+	tempDir, err := os.MkdirTemp("", "bulletproof-snapshot-*")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tempDir) }
+
+	if err := e.destination.Restore(snapshotID, tempDir); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("failed to read snapshot files: %w", err)
+	}
+	return tempDir, cleanup, nil
+}
+
+// manifestOnlyError explains that a snapshot holds no files to read or restore
+func manifestOnlyError(snapshotID string) error {
+	return fmt.Errorf("snapshot %s is manifest-only: it records file hashes but stores no files", snapshotID)
+}
+
 // getSnapshotPath returns the filesystem path for a snapshot ID
 func (e *BackupEngine) getSnapshotPath(snapshotID string) (string, error) {
 	switch dest := e.destination.(type) {
@@ -741,6 +1645,7 @@ func (e *BackupEngine) getSnapshotPath(snapshotID string) (string, error) {
 func (e *BackupEngine) saveMultiSource(sources []string, snapshot *types.Snapshot, message string) error {
 	// Get the destination path where we'll save files
 	var destBasePath string
+	storeFile := func(src, dst string) error { return utils.CopyFile(src, dst) }
 	switch dest := e.destination.(type) {
 	case *destinations.LocalDestination:
 		if dest.Timestamped {
@@ -748,6 +1653,10 @@ func (e *BackupEngine) saveMultiSource(sources []string, snapshot *types.Snapsho
 		} else {
 			destBasePath = dest.BasePath
 		}
+		if err := dest.PrepareSave(snapshot); err != nil {
+			return err
+		}
+		storeFile = func(src, dst string) error { return dest.StoreFile(snapshot, src, dst) }
 	case *destinations.GitDestination:
 		destBasePath = dest.RepoPath
 		if strings.HasPrefix(dest.RepoPath, "git@") || strings.HasPrefix(dest.RepoPath, "https://") {
@@ -764,37 +1673,34 @@ func (e *BackupEngine) saveMultiSource(sources []string, snapshot *types.Snapsho
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	// Validate no duplicate source basenames (would cause wrong file restoration)
-	basenames := make(map[string]string)
-	for _, src := range sources {
-		base := filepath.Base(src)
-		if existing, ok := basenames[base]; ok {
-			return fmt.Errorf("source directories %s and %s share the same basename %q; multi-source backups use the directory basename as a prefix to organize files, so each source must have a unique basename (rename or symlink one of the directories to resolve this)", existing, src, base)
-		}
-		basenames[base] = src
+	// Look up each prefix's source directory from the snapshot's recorded mapping
+	sourceByPrefix := make(map[string]string, len(snapshot.Sources))
+	for _, source := range snapshot.Sources {
+		sourceByPrefix[source.Prefix] = source.Path
 	}
 
 	// Copy files from each source
-	fmt.Printf("  Copying %d files from %d sources...\n", len(snapshot.Files), len(sources))
-	for _, fileSnapshot := range snapshot.Files {
-		// Extract source index from path prefix (e.g., "openclaw/file.txt" -> "openclaw")
+	files := snapshot.Files
+	if snapshot.ManifestOnly {
+		files = nil
+		fmt.Fprintf(e.output(), "  Recording manifest of %d files from %d sources...\n", len(snapshot.Files), len(snapshot.Sources))
+	} else {
+		fmt.Fprintf(e.output(), "  Copying %d files from %d sources...\n", len(snapshot.Files), len(snapshot.Sources))
+	}
+	done := 0
+	progress := e.startProgress("Copied")
+	for _, fileSnapshot := range files {
+		// Split the source prefix from the path (e.g., ".openclaw/file.txt" -> ".openclaw")
 		parts := strings.SplitN(fileSnapshot.Path, string(filepath.Separator), 2)
 		if len(parts) != 2 {
 			return fmt.Errorf("invalid file path format: %s", fileSnapshot.Path)
 		}
-		sourceBaseName := parts[0]
+		prefix := parts[0]
 		relativeFilePath := parts[1]
 
-		// Find the source path that matches this base name
-		var sourcePath string
-		for _, src := range sources {
-			if filepath.Base(src) == sourceBaseName {
-				sourcePath = src
-				break
-			}
-		}
-		if sourcePath == "" {
-			return fmt.Errorf("could not find source for base name: %s", sourceBaseName)
+		sourcePath, ok := sourceByPrefix[prefix]
+		if !ok {
+			return fmt.Errorf("could not find source for prefix: %s", prefix)
 		}
 
 		// Copy the file
@@ -807,9 +1713,11 @@ func (e *BackupEngine) saveMultiSource(sources []string, snapshot *types.Snapsho
 		}
 
 		// Copy file
-		if err := utils.CopyFile(sourceFile, destFile); err != nil {
+		if err := storeFile(sourceFile, destFile); err != nil {
 			return fmt.Errorf("failed to copy file %s: %w", fileSnapshot.Path, err)
 		}
+		done++
+		progress(done, len(files), fileSnapshot.Path)
 	}
 
 	// Save snapshot metadata
@@ -839,3 +1747,22 @@ func (e *BackupEngine) saveSnapshotMetadata(basePath string, snapshot *types.Sna
 
 	return nil
 }
+
+// printOversized lists the files a backup left out for their size, with a summary
+func printOversized(w io.Writer, files []types.SkippedFile, limit int64) {
+	var total int64
+	fmt.Fprintf(w, "⚠️  Skipping %d file(s) larger than max_file_size (%d bytes):\n", len(files), limit)
+	for _, file := range files {
+		fmt.Fprintf(w, "   • %s (%d bytes)\n", file.Path, file.Size)
+		total += file.Size
+	}
+	fmt.Fprintf(w, "   %d file(s), %d bytes not backed up\n", len(files), total)
+}
+
+// printUnreadable lists the files a backup left out because they could not be read
+func printUnreadable(w io.Writer, files []types.UnreadableFile) {
+	fmt.Fprintf(w, "⚠️  Skipping %d file(s) that could not be read:\n", len(files))
+	for _, file := range files {
+		fmt.Fprintf(w, "   • %s: %s\n", file.Path, file.Error)
+	}
+}
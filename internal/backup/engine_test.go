@@ -0,0 +1,108 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+func TestNextSnapshotTime_UniqueIDs(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := types.GenerateID(nextSnapshotTime())
+		if seen[id] {
+			t.Fatalf("snapshot ID %s handed out twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestRestore_PreservesFileModes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	modes := map[string]os.FileMode{
+		filepath.Join("workspace", "skills", "run.sh"): 0755,
+		filepath.Join("workspace", "secrets.yaml"):     0600,
+		filepath.Join("workspace", "SOUL.md"):          0644,
+	}
+	agentDir := t.TempDir()
+	for path, mode := range modes {
+		full := filepath.Join(agentDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(full, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkModes := func(destType, root string) {
+		t.Helper()
+		for path, mode := range modes {
+			info, err := os.Stat(filepath.Join(root, path))
+			if err != nil {
+				t.Errorf("%s: %v", destType, err)
+				continue
+			}
+			if info.Mode().Perm() != mode {
+				t.Errorf("%s: %s restored with mode %o, want %o", destType, path, info.Mode().Perm(), mode)
+			}
+		}
+	}
+
+	for _, destType := range []string{"local", "git"} {
+		engine, err := NewBackupEngine(&config.Config{
+			OpenclawPath: agentDir,
+			Destination:  &config.DestinationConfig{Type: destType, Path: t.TempDir()},
+		})
+		if err != nil {
+			t.Fatalf("%s: NewBackupEngine failed: %v", destType, err)
+		}
+		result, err := engine.Backup(false, "modes", true, true)
+		if err != nil {
+			t.Fatalf("%s: Backup failed: %v", destType, err)
+		}
+		if got := result.Snapshot.Files[filepath.Join("workspace", "skills", "run.sh")].Mode; got != 0755 {
+			t.Errorf("%s: snapshot recorded mode %o for the script", destType, got)
+		}
+
+		target := t.TempDir()
+		if err := engine.RestoreToTarget(result.Snapshot.ID, target, false, true, true); err != nil {
+			t.Fatalf("%s: RestoreToTarget failed: %v", destType, err)
+		}
+		checkModes(destType, target)
+
+		target = t.TempDir()
+		var paths []string
+		for path := range modes {
+			paths = append(paths, path)
+		}
+		if err := engine.RestorePaths(result.Snapshot.ID, paths, target, false, true, true, false); err != nil {
+			t.Fatalf("%s: RestorePaths failed: %v", destType, err)
+		}
+		checkModes(destType, target)
+	}
+}
+
+func TestPrintPlatformMismatch(t *testing.T) {
+	var out strings.Builder
+	printPlatformMismatch(&out, &types.Snapshot{Hostname: "laptop", OS: "linux"}, "windows")
+	if !strings.Contains(out.String(), "taken on laptop (Linux) and is being restored on Windows") {
+		t.Errorf("expected a warning naming both platforms, got %q", out.String())
+	}
+
+	// Same platform, or a snapshot from before the OS was recorded
+	for _, snapshot := range []*types.Snapshot{{OS: "windows"}, {}} {
+		out.Reset()
+		printPlatformMismatch(&out, snapshot, "windows")
+		if out.Len() != 0 {
+			t.Errorf("expected no warning for OS %q, got %q", snapshot.OS, out.String())
+		}
+	}
+}
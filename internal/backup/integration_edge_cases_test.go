@@ -1,12 +1,18 @@
 package backup
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
 )
 
 // TestEdgeCase_EmptyAgent tests backing up an empty OpenClaw installation
@@ -151,6 +157,62 @@ func TestEdgeCase_UnicodeFilenames(t *testing.T) {
 	}
 }
 
+// TestEdgeCase_UnicodeNormalizationAndRawBytes tests that names differing only in
+// Unicode normalization, and names that are not valid UTF-8, survive the manifest
+// round trip and a restore
+func TestEdgeCase_UnicodeNormalizationAndRawBytes(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("normalization-agent")
+	backupDir := helper.createBackupDestination("normalization")
+
+	names := []string{
+		"caf\u00e9.txt",  // precomposed é (NFC)
+		"cafe\u0301.txt", // e + combining accent (NFD)
+		"caf\xe9.txt",    // Latin-1, not valid UTF-8
+	}
+	for i, name := range names {
+		helper.writeFile(filepath.Join(agentDir, "workspace", name), fmt.Sprintf("content %d", i))
+	}
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	result, err := engine.Backup(false, "Normalization", true, false)
+	helper.assertNoError(err, "Backup failed")
+
+	if collisions := result.Snapshot.PathCollisions(); len(collisions) != 1 || len(collisions[0]) != 2 {
+		t.Errorf("expected the NFC and NFD names to be reported as colliding, got %q", collisions)
+	}
+
+	// The stored manifest keeps every name byte for byte
+	stored, err := engine.GetSnapshot(result.Snapshot.ID)
+	helper.assertNoError(err, "GetSnapshot failed")
+	for _, name := range names {
+		if _, ok := stored.Files[filepath.Join("workspace", name)]; !ok {
+			t.Errorf("manifest lost %q", name)
+		}
+	}
+
+	// A restore writes every name back with its own content
+	targetDir := t.TempDir()
+	err = engine.RestoreToTarget(result.Snapshot.ID, targetDir, false, true, true)
+	helper.assertNoError(err, "Restore failed")
+	for i, name := range names {
+		if got := helper.readFile(filepath.Join(targetDir, "workspace", name)); got != fmt.Sprintf("content %d", i) {
+			t.Errorf("restored %q has content %q", name, got)
+		}
+	}
+}
+
 // TestEdgeCase_DeepDirectoryNesting tests very deep directory structures
 func TestEdgeCase_DeepDirectoryNesting(t *testing.T) {
 	helper := newTestDataHelper(t)
@@ -300,12 +362,63 @@ func TestEdgeCase_ReadOnlyFiles(t *testing.T) {
 
 // TestEdgeCase_ConcurrentBackups tests behavior when multiple backups run simultaneously
 func TestEdgeCase_ConcurrentBackups(t *testing.T) {
-	t.Skip("Concurrent backup handling not yet tested - would require locking mechanism")
+	helper := newTestDataHelper(t)
 
-	// This test would verify:
-	// 1. Only one backup can run at a time
-	// 2. Concurrent backup attempts wait or fail gracefully
-	// 3. No data corruption from concurrent operations
+	agentDir := helper.createOpenClawAgent("concurrent-agent")
+	backupDir := helper.createBackupDestination("concurrent")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+
+	// One engine holds the lock, as a scheduled backup in progress would
+	running, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+	unlock, err := running.lock()
+	helper.assertNoError(err, "lock failed")
+	helper.assertFileExists(filepath.Join(backupDir, ".bulletproof", "lock"))
+
+	// A second backup waits for it with SetWaitForLock instead of failing
+	waiting, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+	waiting.SetOutput(io.Discard)
+	waiting.SetWaitForLock(true)
+	done := make(chan error, 1)
+	go func() {
+		_, err := waiting.Backup(false, "Manual backup", false, false)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected the backup to wait for the lock, it returned %v", err)
+	case <-time.After(3 * lockPoll):
+	}
+
+	unlock()
+	select {
+	case err := <-done:
+		helper.assertNoError(err, "Backup after the lock was released failed")
+	case <-time.After(10 * time.Second):
+		t.Fatal("backup did not go ahead once the lock was released")
+	}
+	release, err := acquireLock(filepath.Join(backupDir, ".bulletproof", "lock"), 0, nil)
+	helper.assertNoError(err, "expected the backup to release the lock")
+	release()
+
+	snapshots, err := waiting.ListBackups()
+	helper.assertNoError(err, "ListBackups failed")
+	if len(snapshots) != 1 {
+		t.Errorf("expected one snapshot in the index, got %d", len(snapshots))
+	}
+
+	// A restore shares the lock with its own safety backup
+	_, err = waiting.RestoreWithResult(snapshots[0].ID, "", false, true, true)
+	helper.assertNoError(err, "Restore failed")
 }
 
 // TestEdgeCase_DiskSpaceHandling tests behavior when disk is full
@@ -358,6 +471,59 @@ func TestEdgeCase_CorruptedSnapshot(t *testing.T) {
 	}
 }
 
+// TestEdgeCase_CorruptedStoredFile tests that a restore checks the stored
+// files against their hashes and changes nothing when one is damaged
+func TestEdgeCase_CorruptedStoredFile(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("damaged-agent")
+	backupDir := helper.createBackupDestination("damaged")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+	engine.SetOutput(io.Discard)
+
+	result, err := engine.Backup(false, "Original backup", false, false)
+	helper.assertNoError(err, "Backup failed")
+
+	// Bit rot in one stored file, and local changes a restore would undo
+	helper.writeFile(filepath.Join(backupDir, result.Snapshot.ID, "workspace", "SOUL.md"), "# Rotten\n")
+	helper.modifyAgentPersonality(agentDir, "# Changed since\n")
+	helper.writeFile(filepath.Join(agentDir, "workspace", "new.md"), "new\n")
+
+	err = engine.RestoreToTarget(result.Snapshot.ID, "", false, true, true)
+	if err == nil || !strings.Contains(err.Error(), "corrupted: "+filepath.Join("workspace", "SOUL.md")) || !strings.Contains(err.Error(), "--skip-verify") {
+		t.Fatalf("expected the restore to fail naming the corrupted file, got %v", err)
+	}
+	helper.assertFileContains(filepath.Join(agentDir, "workspace", "SOUL.md"), "# Changed since")
+	helper.assertFileExists(filepath.Join(agentDir, "workspace", "new.md"))
+
+	// Restoring only files that are intact still works
+	err = engine.RestorePaths(result.Snapshot.ID, []string{"openclaw.json", filepath.Join("workspace", "SOUL.md")}, "", false, true, true, false)
+	if err == nil || !strings.Contains(err.Error(), "corrupted") {
+		t.Fatalf("expected restoring the corrupted file to fail, got %v", err)
+	}
+	os.Remove(filepath.Join(agentDir, "openclaw.json"))
+	err = engine.RestorePaths(result.Snapshot.ID, []string{"openclaw.json"}, "", false, true, true, false)
+	helper.assertNoError(err, "RestorePaths of an intact file failed")
+	helper.assertFileExists(filepath.Join(agentDir, "openclaw.json"))
+
+	// --skip-verify restores the backup as it is
+	engine.SetSkipRestoreVerify(true)
+	err = engine.RestoreToTarget(result.Snapshot.ID, "", false, true, true)
+	helper.assertNoError(err, "Restore with verification skipped failed")
+	helper.assertFileContains(filepath.Join(agentDir, "workspace", "SOUL.md"), "# Rotten")
+	helper.assertFileNotExists(filepath.Join(agentDir, "workspace", "new.md"))
+}
+
 // TestEdgeCase_InvalidSnapshotID tests handling of invalid snapshot IDs
 func TestEdgeCase_InvalidSnapshotID(t *testing.T) {
 	helper := newTestDataHelper(t)
@@ -519,15 +685,24 @@ func TestEdgeCase_PermissionErrors(t *testing.T) {
 	engine, err := NewBackupEngine(cfg)
 	helper.assertNoError(err, "NewBackupEngine failed")
 
-	// Backup should handle permission error gracefully
+	// By default the backup fails, naming the file
 	_, err = engine.Backup(false, "Backup with permission errors", false, false)
+	if err == nil || !strings.Contains(err.Error(), "unreadable.txt") {
+		t.Fatalf("expected the backup to fail naming the unreadable file, got %v", err)
+	}
 
-	// Implementation may choose to:
-	// 1. Skip unreadable files and continue
-	// 2. Fail the entire backup
-	// Either behavior is acceptable, but error should be clear
-	if err != nil {
-		t.Logf("Backup with permission errors: %v", err)
+	// With on_read_error: skip it leaves the file out and reports it
+	cfg.Options.OnReadError = "skip"
+	engine, err = NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+	result, err := engine.Backup(false, "Backup with permission errors", false, false)
+	helper.assertNoError(err, "Backup with on_read_error skip failed")
+	unreadable := filepath.Join("workspace", "unreadable.txt")
+	if len(result.Unreadable) != 1 || result.Unreadable[0].Path != unreadable {
+		t.Errorf("expected %s to be reported unreadable, got %+v", unreadable, result.Unreadable)
+	}
+	if _, ok := result.Snapshot.Files[unreadable]; ok {
+		t.Error("expected the unreadable file to be left out of the snapshot")
 	}
 }
 
@@ -613,3 +788,238 @@ func TestEdgeCase_BackupOfBackups(t *testing.T) {
 	snapshotPath := filepath.Join(backupDir, result.Snapshot.ID)
 	helper.assertFileNotExists(filepath.Join(snapshotPath, "backups"))
 }
+
+// TestEdgeCase_MultiSourceSameBaseName tests that sources sharing a directory name
+// are backed up under distinct prefixes instead of being rejected
+func TestEdgeCase_MultiSourceSameBaseName(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	root := t.TempDir()
+	sourceA := filepath.Join(root, "a", "data")
+	sourceB := filepath.Join(root, "b", "data")
+	for _, dir := range []string{sourceA, sourceB} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	helper.writeFile(filepath.Join(sourceA, "file.txt"), "from a")
+	helper.writeFile(filepath.Join(sourceB, "file.txt"), "from b")
+	backupDir := helper.createBackupDestination("same-basename")
+
+	cfg := &config.Config{
+		Sources: config.NewSources(sourceB, sourceA),
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	result, err := engine.Backup(false, "Same base names", true, false)
+	helper.assertNoError(err, "Backup with shared base names failed")
+
+	if len(result.Snapshot.Sources) != 2 {
+		t.Fatalf("expected 2 recorded sources, got %+v", result.Snapshot.Sources)
+	}
+
+	snapshotPath := filepath.Join(backupDir, result.Snapshot.ID)
+	for _, source := range result.Snapshot.Sources {
+		content := helper.readFile(filepath.Join(snapshotPath, source.Prefix, "file.txt"))
+		if content != helper.readFile(filepath.Join(source.Path, "file.txt")) {
+			t.Errorf("prefix %s holds content from the wrong source: %q", source.Prefix, content)
+		}
+	}
+
+	// Reordering the sources must produce a comparable snapshot
+	cfg.Sources = config.NewSources(sourceA, sourceB)
+	again, err := engine.Backup(true, "Reordered sources", true, false)
+	helper.assertNoError(err, "Dry run with reordered sources failed")
+	if diff := again.Snapshot.Diff(result.Snapshot); !diff.IsEmpty() {
+		t.Errorf("expected no changes after reordering sources, got %s", diff)
+	}
+}
+
+// TestEdgeCase_MultiSourcePrefixes tests that sources given their own prefix are
+// stored under it, and that sources sharing a name without one are pointed out
+func TestEdgeCase_MultiSourcePrefixes(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	root := t.TempDir()
+	desktop := filepath.Join(root, "desktop", ".openclaw")
+	laptop := filepath.Join(root, "laptop", ".openclaw")
+	for _, dir := range []string{desktop, laptop} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	helper.writeFile(filepath.Join(desktop, "SOUL.md"), "desktop soul")
+	helper.writeFile(filepath.Join(laptop, "SOUL.md"), "laptop soul")
+	backupDir := helper.createBackupDestination("prefixes")
+
+	cfg := &config.Config{
+		Sources: config.NewSources(desktop, laptop),
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	output := captureStdout(t, func() {
+		_, err = engine.Backup(true, "Shared names", true, false)
+	})
+	helper.assertNoError(err, "Dry run failed")
+	if !strings.Contains(output, "Source "+laptop+" shares its name") {
+		t.Errorf("expected a warning about the shared name, got:\n%s", output)
+	}
+
+	cfg.Sources = []config.SourceConfig{{Path: desktop, Prefix: "desktop"}, {Path: laptop, Prefix: "laptop"}}
+	var result *types.BackupResult
+	output = captureStdout(t, func() {
+		result, err = engine.Backup(false, "Chosen prefixes", true, false)
+	})
+	helper.assertNoError(err, "Backup with prefixes failed")
+	if strings.Contains(output, "shares its name") {
+		t.Errorf("expected no warning once prefixes are chosen, got:\n%s", output)
+	}
+	snapshotPath := filepath.Join(backupDir, result.Snapshot.ID)
+	helper.assertFileContains(filepath.Join(snapshotPath, "desktop", "SOUL.md"), "desktop soul")
+	helper.assertFileContains(filepath.Join(snapshotPath, "laptop", "SOUL.md"), "laptop soul")
+
+	// Two sources with the same prefix are refused before scanning
+	cfg.Sources[1].Prefix = "desktop"
+	if _, err := engine.Backup(true, "Same prefix", true, false); err == nil || !strings.Contains(err.Error(), "both use the prefix") {
+		t.Errorf("expected a duplicate prefix to be refused, got %v", err)
+	}
+}
+
+// TestEdgeCase_MultiSourceExcludes tests that each source's own exclude
+// patterns apply to it alone, on top of the global ones
+func TestEdgeCase_MultiSourceExcludes(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	root := t.TempDir()
+	agent := filepath.Join(root, "agent")
+	app := filepath.Join(root, "app")
+	for _, source := range []string{agent, app} {
+		if err := os.MkdirAll(filepath.Join(source, "node_modules"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		helper.writeFile(filepath.Join(source, "run.log"), "log")
+		helper.writeFile(filepath.Join(source, "node_modules", "dep.js"), "dep")
+		helper.writeFile(filepath.Join(source, "notes.tmp"), "tmp")
+		helper.writeFile(filepath.Join(source, "SOUL.md"), "soul")
+	}
+	backupDir := helper.createBackupDestination("source-excludes")
+
+	cfg := &config.Config{
+		Sources: []config.SourceConfig{
+			{Path: agent, Exclude: []string{"*.log"}},
+			{Path: app, Exclude: []string{"node_modules/"}},
+		},
+		Destination: &config.DestinationConfig{Type: "local", Path: backupDir},
+		Options:     config.BackupOptions{Exclude: []string{"*.tmp"}},
+	}
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	var result *types.BackupResult
+	captureStdout(t, func() {
+		result, err = engine.Backup(false, "Per-source excludes", true, false)
+	})
+	helper.assertNoError(err, "Backup failed")
+
+	expected := []string{"agent/SOUL.md", "agent/node_modules/dep.js", "app/SOUL.md", "app/run.log"}
+	var got []string
+	for path := range result.Snapshot.Files {
+		got = append(got, filepath.ToSlash(path))
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("backed up %v, want %v", got, expected)
+	}
+}
+
+// TestEdgeCase_GlobSourceMatchesDestination tests that a glob source expanding to
+// the destination itself is dropped with a warning, so backups are not copied
+// into the next backup
+func TestEdgeCase_GlobSourceMatchesDestination(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	projects := filepath.Join(t.TempDir(), "projects")
+	backupDir := filepath.Join(projects, "backups")
+	for _, dir := range []string{"alpha", "beta", "backups"} {
+		if err := os.MkdirAll(filepath.Join(projects, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	helper.writeFile(filepath.Join(projects, "alpha", "notes.md"), "# alpha")
+	helper.writeFile(filepath.Join(projects, "beta", "notes.md"), "# beta")
+
+	cfg := &config.Config{
+		Sources: config.NewSources(filepath.Join(projects, "*")),
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	// Back up twice, so the second run would find the first backup in the destination
+	for i := 0; i < 2; i++ {
+		var result *types.BackupResult
+		output := captureStdout(t, func() {
+			result, err = engine.Backup(false, "Glob sources", true, true)
+		})
+		helper.assertNoError(err, "Backup with glob sources failed")
+
+		if !strings.Contains(output, "Skipping source "+backupDir) {
+			t.Errorf("expected a warning about skipping the destination, got:\n%s", output)
+		}
+		if len(result.Snapshot.Sources) != 2 {
+			t.Fatalf("expected only alpha and beta as sources, got %+v", result.Snapshot.Sources)
+		}
+		for _, source := range result.Snapshot.Sources {
+			if source.Path == backupDir {
+				t.Errorf("destination was backed up as a source: %+v", source)
+			}
+		}
+		if len(result.Snapshot.Files) != 2 {
+			t.Errorf("expected 2 files, got %d", len(result.Snapshot.Files))
+		}
+	}
+
+	// A glob matching nothing but the destination leaves nothing to back up
+	cfg.Sources = config.NewSources(filepath.Join(projects, "back*"))
+	if _, err := engine.Backup(true, "Only the destination", true, false); err == nil || !strings.Contains(err.Error(), "inside the backup destination") {
+		t.Errorf("expected an error when every source is the destination, got %v", err)
+	}
+}
+
+// captureStdout returns what fn prints to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
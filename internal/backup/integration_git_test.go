@@ -1,13 +1,20 @@
 package backup
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bulletproof-bot/backup/internal/config"
+	bperrors "github.com/bulletproof-bot/backup/internal/errors"
+	"github.com/bulletproof-bot/backup/internal/types"
 	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
@@ -331,6 +338,98 @@ func TestGitBackup_CommitMessages(t *testing.T) {
 	}
 }
 
+func TestGitBackup_CommitAuthor(t *testing.T) {
+	tests := []struct {
+		name      string
+		author    *config.GitAuthorConfig
+		repoUser  *[2]string // user.name and user.email in the repository's config
+		wantName  string
+		wantEmail string
+	}{
+		{
+			name:      "default",
+			wantName:  "Bulletproof Backup",
+			wantEmail: "backup@bulletproof.bot",
+		},
+		{
+			name:      "repository user",
+			repoUser:  &[2]string{"Repo User", "repo@example.com"},
+			wantName:  "Repo User",
+			wantEmail: "repo@example.com",
+		},
+		{
+			name:      "configured author",
+			author:    &config.GitAuthorConfig{Name: "Ops Team", Email: "ops@example.com"},
+			repoUser:  &[2]string{"Repo User", "repo@example.com"},
+			wantName:  "Ops Team",
+			wantEmail: "ops@example.com",
+		},
+		{
+			name:      "configured name only",
+			author:    &config.GitAuthorConfig{Name: "Ops Team"},
+			repoUser:  &[2]string{"Repo User", "repo@example.com"},
+			wantName:  "Ops Team",
+			wantEmail: "repo@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := newTestDataHelper(t)
+			agentDir := helper.createOpenClawAgent("author-agent")
+			backupDir := filepath.Join(t.TempDir(), "repo")
+
+			// Without a repository user, bulletproof creates the repository
+			// itself, initial commit included
+			if tt.repoUser != nil {
+				repo, err := gogit.PlainInit(backupDir, false)
+				helper.assertNoError(err, "Failed to initialize git repository")
+				repoConfig, err := repo.Config()
+				helper.assertNoError(err, "Failed to read repository config")
+				repoConfig.User.Name, repoConfig.User.Email = tt.repoUser[0], tt.repoUser[1]
+				helper.assertNoError(repo.SetConfig(repoConfig), "Failed to write repository config")
+			}
+
+			cfg := &config.Config{
+				OpenclawPath: agentDir,
+				Destination: &config.DestinationConfig{
+					Type: "git",
+					Git:  &config.GitDestinationConfig{URL: backupDir, Author: tt.author},
+				},
+			}
+			engine, err := NewBackupEngine(cfg)
+			helper.assertNoError(err, "NewBackupEngine failed")
+			result, err := engine.Backup(false, "authored", false, false)
+			helper.assertNoError(err, "Backup failed")
+
+			repo, err := gogit.PlainOpen(backupDir)
+			helper.assertNoError(err, "Failed to open git repository")
+			commits, err := repo.Log(&gogit.LogOptions{})
+			helper.assertNoError(err, "Failed to read the log")
+			count := 0
+			helper.assertNoError(commits.ForEach(func(commit *object.Commit) error {
+				count++
+				if commit.Author.Name != tt.wantName || commit.Author.Email != tt.wantEmail {
+					t.Errorf("commit %q authored by %s <%s>, want %s <%s>", strings.TrimSpace(commit.Message),
+						commit.Author.Name, commit.Author.Email, tt.wantName, tt.wantEmail)
+				}
+				return nil
+			}), "Failed to walk the log")
+			if tt.repoUser == nil && count != 2 {
+				t.Errorf("expected the initial and backup commits, got %d commits", count)
+			}
+
+			ref, err := repo.Tag(result.Snapshot.ID)
+			helper.assertNoError(err, "Snapshot tag not found")
+			tag, err := repo.TagObject(ref.Hash())
+			helper.assertNoError(err, "Failed to read the snapshot tag")
+			if tag.Tagger.Name != tt.wantName || tag.Tagger.Email != tt.wantEmail {
+				t.Errorf("tagged by %s <%s>, want %s <%s>", tag.Tagger.Name, tag.Tagger.Email, tt.wantName, tt.wantEmail)
+			}
+		})
+	}
+}
+
 // getDirSize calculates the total size of a directory
 func (h *testDataHelper) getDirSize(path string) int64 {
 	var size int64
@@ -432,3 +531,327 @@ func TestGitBackup_RemotePushBehavior(t *testing.T) {
 		t.Error("Tag should not be nil")
 	}
 }
+
+// TestGitDestination_BackupLabels tests that labels become namespaced tags that
+// are reported as labels rather than as snapshots
+func TestGitDestination_BackupLabels(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("labeled-git-agent")
+	backupDir := helper.createBackupDestination("labeled-git")
+
+	_, err := gogit.PlainInit(backupDir, false)
+	helper.assertNoError(err, "Failed to initialize git repository")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "git",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	result, err := engine.BackupWithLabels(false, "v2 release", true, false, []string{"release", "stable"})
+	helper.assertNoError(err, "Labeled backup failed")
+
+	repo, err := gogit.PlainOpen(backupDir)
+	helper.assertNoError(err, "Failed to open git repository")
+	for _, label := range []string{"release", "stable"} {
+		if _, err := repo.Tag("labels/" + label + "/" + result.Snapshot.ID); err != nil {
+			t.Errorf("expected label tag for %q: %v", label, err)
+		}
+	}
+
+	snapshots, err := engine.ListBackups()
+	helper.assertNoError(err, "ListBackups failed")
+	if len(snapshots) != 1 {
+		t.Fatalf("expected label tags not to be listed as snapshots, got %d snapshots", len(snapshots))
+	}
+	if !snapshots[0].HasLabels([]string{"release", "stable"}) {
+		t.Errorf("expected snapshot to carry its labels, got %v", snapshots[0].Labels)
+	}
+}
+
+// TestGitDestination_LastSnapshotIgnoresWorkingTree tests that the last snapshot
+// comes from the newest tag even when the working tree is at an older one
+func TestGitDestination_LastSnapshotIgnoresWorkingTree(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("stale-git-agent")
+	backupDir := helper.createBackupDestination("stale-git")
+
+	_, err := gogit.PlainInit(backupDir, false)
+	helper.assertNoError(err, "Failed to initialize git repository")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "git",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	first, err := engine.Backup(false, "first", true, false)
+	helper.assertNoError(err, "First backup failed")
+	time.Sleep(2 * time.Millisecond)
+
+	helper.modifyAgentPersonality(agentDir, "changed personality")
+	second, err := engine.Backup(false, "second", true, false)
+	helper.assertNoError(err, "Second backup failed")
+
+	// Move the working tree back to the first snapshot
+	repo, err := gogit.PlainOpen(backupDir)
+	helper.assertNoError(err, "Failed to open git repository")
+	worktree, err := repo.Worktree()
+	helper.assertNoError(err, "Failed to get worktree")
+	tagRef, err := repo.Tag(first.Snapshot.ID)
+	helper.assertNoError(err, "Failed to find first tag")
+	err = worktree.Checkout(&gogit.CheckoutOptions{Branch: tagRef.Name()})
+	helper.assertNoError(err, "Failed to check out first tag")
+
+	last, err := engine.Destination().GetLastSnapshot()
+	helper.assertNoError(err, "GetLastSnapshot failed")
+	if last == nil || last.ID != second.Snapshot.ID {
+		t.Fatalf("expected last snapshot %s, got %v", second.Snapshot.ID, last)
+	}
+
+	// Reading an older snapshot returns its own metadata
+	older, err := engine.GetSnapshot(first.Snapshot.ID)
+	helper.assertNoError(err, "GetSnapshot failed")
+	if older == nil || older.ID != first.Snapshot.ID {
+		t.Errorf("expected snapshot %s, got %v", first.Snapshot.ID, older)
+	}
+}
+
+func TestGitDestination_ReadSnapshotFile(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("content-git-agent")
+	backupDir := helper.createBackupDestination("content-git")
+
+	_, err := gogit.PlainInit(backupDir, false)
+	helper.assertNoError(err, "Failed to initialize git repository")
+
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "git", Path: backupDir},
+	})
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	helper.modifyAgentPersonality(agentDir, "first personality\n")
+	first, err := engine.Backup(false, "first", true, false)
+	helper.assertNoError(err, "First backup failed")
+	time.Sleep(2 * time.Millisecond)
+
+	helper.modifyAgentPersonality(agentDir, "second personality\n")
+	_, err = engine.Backup(false, "second", true, false)
+	helper.assertNoError(err, "Second backup failed")
+
+	// An older snapshot's content comes from its tag, not the working tree
+	content, err := engine.ContentReader().ReadSnapshotFile(first.Snapshot.ID, filepath.Join("workspace", "SOUL.md"))
+	helper.assertNoError(err, "ReadSnapshotFile failed")
+	if string(content) != "first personality\n" {
+		t.Errorf("expected the first snapshot's SOUL.md, got %q", content)
+	}
+
+	if _, err := engine.ContentReader().ReadSnapshotFile(first.Snapshot.ID, "missing.md"); err == nil {
+		t.Error("expected an error for a file the snapshot does not hold")
+	}
+}
+
+func TestCheckDestination_GitRemote(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/private.git") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{server.URL + "/private.git", "authenticate to git remote"},
+		{server.URL + "/missing.git", "reach git remote"},
+	}
+	for _, tt := range tests {
+		err := CheckDestination(&config.DestinationConfig{Type: "git", Path: tt.url})
+		var actionable *bperrors.ActionableError
+		if !errors.As(err, &actionable) {
+			t.Fatalf("%s: expected an actionable error, got %v", tt.url, err)
+		}
+		if !strings.HasPrefix(actionable.Operation, tt.want) || actionable.Remediation == "" {
+			t.Errorf("%s: got operation %q, remediation %q", tt.url, actionable.Operation, actionable.Remediation)
+		}
+	}
+
+	// Local repositories and other destinations have nothing to check
+	if err := CheckDestination(&config.DestinationConfig{Type: "git", Path: t.TempDir()}); err != nil {
+		t.Errorf("local repository should pass: %v", err)
+	}
+	if err := CheckDestination(&config.DestinationConfig{Type: "local", Path: t.TempDir()}); err != nil {
+		t.Errorf("local destination should pass: %v", err)
+	}
+}
+
+// TestSyncRemote_PushesAccumulatedBackups tests that sync pushes the backups a
+// remote is missing, resumes from what is already there, and reports a branch
+// that diverged on the remote while still pushing the snapshot tags
+func TestSyncRemote_PushesAccumulatedBackups(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("sync-agent")
+	backupDir := helper.createBackupDestination("git-sync")
+	remoteDir := helper.createBackupDestination("git-remote")
+
+	remoteRepo, err := gogit.PlainInit(remoteDir, true)
+	helper.assertNoError(err, "Failed to initialize remote repository")
+	repo, err := gogit.PlainInit(backupDir, false)
+	helper.assertNoError(err, "Failed to initialize git repository")
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	helper.assertNoError(err, "Failed to add remote")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "git",
+			Path: backupDir,
+		},
+	}
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	// Two backups pile up without reaching the remote
+	var ids []string
+	for i := 0; i < 2; i++ {
+		helper.writeFile(filepath.Join(agentDir, "workspace", "offline.txt"), strings.Repeat("x", i+1))
+		result, err := engine.Backup(false, "Offline backup", true, false)
+		helper.assertNoError(err, "Backup failed")
+		ids = append(ids, result.Snapshot.ID)
+	}
+
+	results, err := engine.SyncRemote()
+	helper.assertNoError(err, "SyncRemote failed")
+	if len(results) != 3 {
+		t.Fatalf("expected the branch and 2 tags to be synced, got %v", results)
+	}
+	if results[0].Ref != "master" || results[1].Ref != ids[0] || results[2].Ref != ids[1] {
+		t.Errorf("expected the branch first, then tags oldest first, got %v", results)
+	}
+	for _, result := range results {
+		if !result.OK() {
+			t.Errorf("expected %s to be pushed", result)
+		}
+	}
+	for _, id := range ids {
+		if _, err := remoteRepo.Tag(id); err != nil {
+			t.Errorf("expected tag %s on the remote: %v", id, err)
+		}
+	}
+
+	// Nothing left to push
+	results, err = engine.SyncRemote()
+	helper.assertNoError(err, "Second SyncRemote failed")
+	if len(results) != 0 {
+		t.Errorf("expected nothing to sync, got %v", results)
+	}
+
+	// Another clone moves the remote branch on while a new backup is made here
+	otherDir := helper.createBackupDestination("git-other")
+	other, err := gogit.PlainClone(otherDir, false, &gogit.CloneOptions{URL: remoteDir})
+	helper.assertNoError(err, "Failed to clone remote")
+	helper.writeFile(filepath.Join(otherDir, "elsewhere.txt"), "elsewhere")
+	worktree, err := other.Worktree()
+	helper.assertNoError(err, "Failed to get worktree")
+	_, err = worktree.Add("elsewhere.txt")
+	helper.assertNoError(err, "Failed to stage file")
+	_, err = worktree.Commit("Elsewhere", &gogit.CommitOptions{Author: &object.Signature{Name: "Other", Email: "other@example.com", When: time.Now()}})
+	helper.assertNoError(err, "Failed to commit")
+	helper.assertNoError(other.Push(&gogit.PushOptions{}), "Failed to push from other clone")
+
+	helper.writeFile(filepath.Join(agentDir, "workspace", "offline.txt"), "xyz")
+	result, err := engine.Backup(false, "Backup after remote moved", true, false)
+	helper.assertNoError(err, "Backup failed")
+
+	results, err = engine.SyncRemote()
+	helper.assertNoError(err, "SyncRemote after divergence failed")
+	if len(results) != 2 || results[0].Status != types.RefDiverged {
+		t.Fatalf("expected a diverged branch and one tag, got %v", results)
+	}
+	if results[1].Ref != result.Snapshot.ID || !results[1].OK() {
+		t.Errorf("expected the new tag to be pushed despite the divergence, got %s", results[1])
+	}
+	if _, err := remoteRepo.Tag(result.Snapshot.ID); err != nil {
+		t.Errorf("expected tag %s on the remote: %v", result.Snapshot.ID, err)
+	}
+}
+
+// TestSyncRemote_PushBranchOff tests that with push_branch off only the
+// snapshot tags reach the remote
+func TestSyncRemote_PushBranchOff(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	backupDir := helper.createBackupDestination("git-tags-only")
+	remoteDir := helper.createBackupDestination("git-tags-remote")
+	remoteRepo, err := gogit.PlainInit(remoteDir, true)
+	helper.assertNoError(err, "Failed to initialize remote repository")
+	repo, err := gogit.PlainInit(backupDir, false)
+	helper.assertNoError(err, "Failed to initialize git repository")
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	helper.assertNoError(err, "Failed to add remote")
+
+	pushBranch := false
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: helper.createOpenClawAgent("tags-only-agent"),
+		Destination: &config.DestinationConfig{
+			Type: "git",
+			Git:  &config.GitDestinationConfig{URL: backupDir, PushBranch: &pushBranch},
+		},
+	})
+	helper.assertNoError(err, "NewBackupEngine failed")
+	result, err := engine.Backup(false, "Tags only", true, false)
+	helper.assertNoError(err, "Backup failed")
+
+	results, err := engine.SyncRemote()
+	helper.assertNoError(err, "SyncRemote failed")
+	if len(results) != 1 || results[0].Ref != result.Snapshot.ID || !results[0].OK() {
+		t.Fatalf("expected only the snapshot tag to be pushed, got %v", results)
+	}
+	if _, err := remoteRepo.Reference(plumbing.NewBranchReferenceName("master"), false); err == nil {
+		t.Error("expected the branch to stay off the remote")
+	}
+}
+
+// TestSyncRemote_RefusesConcurrentPush tests that a sync does not run while
+// another push from the same clone holds the lock
+func TestSyncRemote_RefusesConcurrentPush(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	backupDir := helper.createBackupDestination("git-locked")
+	repo, err := gogit.PlainInit(backupDir, false)
+	helper.assertNoError(err, "Failed to initialize git repository")
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{t.TempDir()}})
+	helper.assertNoError(err, "Failed to add remote")
+
+	helper.writeFile(filepath.Join(backupDir, ".git", "bulletproof-push.lock"), "1\n")
+
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: helper.createOpenClawAgent("locked-agent"),
+		Destination:  &config.DestinationConfig{Type: "git", Path: backupDir},
+	})
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	if _, err := engine.SyncRemote(); err == nil || !strings.Contains(err.Error(), "in progress") {
+		t.Errorf("expected a concurrent push to be refused, got %v", err)
+	}
+}
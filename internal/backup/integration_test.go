@@ -1,12 +1,15 @@
 package backup
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
 )
 
 // TestBackupRestore_LocalDestination_EndToEnd tests complete backup and restore cycle with local destination
@@ -34,6 +37,7 @@ func TestBackupRestore_LocalDestination_EndToEnd(t *testing.T) {
 	helper.assertNoError(err, "NewBackupEngine failed")
 
 	// Test 1: Initial backup
+	var initialSnapshot *types.Snapshot
 	t.Run("InitialBackup", func(t *testing.T) {
 		result, err := engine.Backup(false, "Initial backup of test agent", false, false)
 		helper.assertNoError(err, "Initial backup failed")
@@ -49,6 +53,10 @@ func TestBackupRestore_LocalDestination_EndToEnd(t *testing.T) {
 		if len(result.Snapshot.Files) == 0 {
 			t.Error("Snapshot should contain files")
 		}
+		if result.LastSnapshot != nil {
+			t.Error("First backup should have no last snapshot")
+		}
+		initialSnapshot = result.Snapshot
 
 		// Verify backup structure
 		snapshotPath := filepath.Join(backupDir, result.Snapshot.ID)
@@ -66,6 +74,17 @@ func TestBackupRestore_LocalDestination_EndToEnd(t *testing.T) {
 		if !result.Skipped {
 			t.Error("No-change backup should be skipped")
 		}
+
+		// Monitoring needs to know what the unchanged state matches and since when
+		if result.LastSnapshot == nil || result.LastSnapshot.ID != initialSnapshot.ID {
+			t.Fatalf("Skipped result should report the last snapshot %s, got %+v", initialSnapshot.ID, result.LastSnapshot)
+		}
+		if !result.LastSnapshot.Timestamp.Equal(initialSnapshot.Timestamp) {
+			t.Errorf("Last snapshot timestamp = %v, want %v", result.LastSnapshot.Timestamp, initialSnapshot.Timestamp)
+		}
+		if result.Diff == nil || result.Diff.From != initialSnapshot.ID || !result.Diff.IsEmpty() {
+			t.Errorf("Skipped result should carry an empty diff from the last snapshot, got %+v", result.Diff)
+		}
 	})
 
 	// Test 3: Backup after changes
@@ -703,3 +722,644 @@ func TestDiff_DetectChanges(t *testing.T) {
 		}
 	})
 }
+
+// TestPlanRestore_NoSideEffects tests that planning a restore reports the full change set
+// without creating a safety backup or modifying the target
+func TestPlanRestore_NoSideEffects(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("test-agent")
+	backupDir := helper.createBackupDestination("local")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	result, err := engine.Backup(false, "Baseline", true, false)
+	helper.assertNoError(err, "Backup failed")
+
+	// Drift away from the snapshot: one added, one modified, one removed
+	helper.addSkill(agentDir, "new-skill.js", "// new")
+	helper.modifyAgentPersonality(agentDir, "# Changed personality\n")
+	helper.removeSkill(agentDir, "analysis.js")
+	soulBefore := helper.readFile(filepath.Join(agentDir, "workspace", "SOUL.md"))
+
+	plan, err := engine.PlanRestore("1", "")
+	helper.assertNoError(err, "PlanRestore failed")
+
+	if plan.SnapshotID != result.Snapshot.ID || plan.Target != agentDir {
+		t.Errorf("unexpected plan header: %s -> %s", plan.SnapshotID, plan.Target)
+	}
+	if len(plan.Add) != 1 || plan.Add[0].Path != "workspace/skills/analysis.js" || plan.Add[0].Size == 0 {
+		t.Errorf("expected analysis.js to be re-added, got %+v", plan.Add)
+	}
+	if len(plan.Modify) != 1 || plan.Modify[0].Path != "workspace/SOUL.md" || plan.Modify[0].CurrentSize == nil {
+		t.Errorf("expected SOUL.md to be modified, got %+v", plan.Modify)
+	}
+	if len(plan.Remove) != 1 || plan.Remove[0].Path != "workspace/skills/new-skill.js" {
+		t.Errorf("expected new-skill.js to be removed, got %+v", plan.Remove)
+	}
+	if plan.Changes != 3 {
+		t.Errorf("expected 3 changes, got %d", plan.Changes)
+	}
+
+	// No safety backup and no changes to the target
+	snapshots, err := engine.ListBackups()
+	helper.assertNoError(err, "ListBackups failed")
+	if len(snapshots) != 1 {
+		t.Errorf("expected no safety backup, found %d snapshots", len(snapshots))
+	}
+	if helper.readFile(filepath.Join(agentDir, "workspace", "SOUL.md")) != soulBefore {
+		t.Error("planning a restore must not modify the target")
+	}
+}
+
+// TestCompareRestore_NoSideEffects tests that comparing a restore reports the
+// changes without a safety backup or writing to the target
+func TestCompareRestore_NoSideEffects(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("test-agent")
+	backupDir := helper.createBackupDestination("local")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	_, err = engine.Backup(false, "Baseline", true, false)
+	helper.assertNoError(err, "Backup failed")
+
+	helper.addSkill(agentDir, "new-skill.js", "// new")
+	helper.modifyAgentPersonality(agentDir, "# Changed personality\n")
+	helper.removeSkill(agentDir, "analysis.js")
+	soulBefore := helper.readFile(filepath.Join(agentDir, "workspace", "SOUL.md"))
+
+	diff, err := engine.CompareRestore("1", "")
+	helper.assertNoError(err, "CompareRestore failed")
+
+	if len(diff.Added) != 1 || diff.Added[0] != filepath.Join("workspace", "skills", "analysis.js") {
+		t.Errorf("expected analysis.js to be re-added, got %v", diff.Added)
+	}
+	if len(diff.Modified) != 1 || diff.Modified[0] != filepath.Join("workspace", "SOUL.md") {
+		t.Errorf("expected SOUL.md to be modified, got %v", diff.Modified)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != filepath.Join("workspace", "skills", "new-skill.js") {
+		t.Errorf("expected new-skill.js to be removed, got %v", diff.Removed)
+	}
+
+	snapshots, err := engine.ListBackups()
+	helper.assertNoError(err, "ListBackups failed")
+	if len(snapshots) != 1 {
+		t.Errorf("expected no safety backup, found %d snapshots", len(snapshots))
+	}
+	if helper.readFile(filepath.Join(agentDir, "workspace", "SOUL.md")) != soulBefore {
+		t.Error("comparing a restore must not modify the target")
+	}
+	helper.assertFileExists(filepath.Join(agentDir, "workspace", "skills", "new-skill.js"))
+
+	if _, err := engine.CompareRestore("0", ""); err == nil {
+		t.Error("expected comparing against ID 0 to fail")
+	}
+}
+
+// TestRestoreToTarget_SafetyBackupCapturesTarget tests that restoring to an alternate
+// target takes the safety backup of that target, not of the configured agent,
+// and keeps it out of the agent's snapshots
+func TestRestoreToTarget_SafetyBackupCapturesTarget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("test-agent")
+	backupDir := helper.createBackupDestination("local")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	baseline, err := engine.Backup(false, "Baseline", true, false)
+	helper.assertNoError(err, "Backup failed")
+
+	// An unrelated directory that is about to be overwritten
+	targetDir := t.TempDir()
+	helper.writeFile(filepath.Join(targetDir, "existing.txt"), "precious target data")
+
+	result, err := engine.RestoreWithResult(baseline.Snapshot.ID, targetDir, false, true, true)
+	helper.assertNoError(err, "RestoreWithResult failed")
+
+	// The agent's history is untouched: the baseline is still the latest
+	// snapshot and short ID 1
+	snapshots, err := engine.ListBackups()
+	helper.assertNoError(err, "ListBackups failed")
+	if len(snapshots) != 1 {
+		t.Fatalf("expected only the baseline among the agent's snapshots, got %d", len(snapshots))
+	}
+	latest, err := engine.GetSnapshot("1")
+	helper.assertNoError(err, "GetSnapshot failed")
+	if latest.ID != baseline.Snapshot.ID {
+		t.Errorf("expected short ID 1 to stay the baseline %s, got %s", baseline.Snapshot.ID, latest.ID)
+	}
+
+	// The target's store holds the safety backup, with only the target's files
+	store, configPath, err := engine.targetStore(targetDir)
+	helper.assertNoError(err, "targetStore failed")
+	helper.assertFileExists(configPath)
+	safety, err := store.GetSnapshot(result.SafetyBackupID)
+	helper.assertNoError(err, "GetSnapshot in the target's store failed")
+	if safety == nil || !strings.Contains(safety.Message, targetDir) {
+		t.Fatalf("safety backup should name the target, got %+v", safety)
+	}
+	if _, ok := safety.Files["existing.txt"]; !ok || len(safety.Files) != 1 {
+		t.Errorf("safety backup should contain only the target's files, got %d files", len(safety.Files))
+	}
+
+	// The target's original content is recoverable from its store
+	restored := t.TempDir()
+	helper.assertNoError(store.RestoreToTarget(safety.ID, restored, false, true, true), "restoring the safety backup failed")
+	helper.assertFileContains(filepath.Join(restored, "existing.txt"), "precious target data")
+
+	// The next agent backup still compares against the previous agent snapshot
+	helper.writeFile(filepath.Join(agentDir, "workspace", "SOUL.md"), "# Changed soul\n")
+	next, err := engine.Backup(false, "After restore", true, false)
+	helper.assertNoError(err, "Backup failed")
+	if next.LastSnapshot == nil || next.LastSnapshot.ID != baseline.Snapshot.ID || next.Snapshot.Parent != baseline.Snapshot.ID {
+		t.Errorf("expected the next backup to follow the baseline %s, got %+v", baseline.Snapshot.ID, next.LastSnapshot)
+	}
+	if next.Diff == nil || len(next.Diff.Added) != 0 || len(next.Diff.Removed) != 0 || len(next.Diff.Modified) != 1 {
+		t.Errorf("expected only SOUL.md to change since the baseline, got %v", next.Diff)
+	}
+}
+
+// TestRestoreToTarget_NewTargetNeedsNoSafetyBackup tests restoring into a directory that does not exist yet
+func TestRestoreToTarget_NewTargetNeedsNoSafetyBackup(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("test-agent")
+	backupDir := helper.createBackupDestination("local")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	result, err := engine.Backup(false, "Baseline", true, false)
+	helper.assertNoError(err, "Backup failed")
+
+	targetDir := filepath.Join(t.TempDir(), "fresh-target")
+	err = engine.RestoreToTarget(result.Snapshot.ID, targetDir, false, true, true)
+	helper.assertNoError(err, "RestoreToTarget failed")
+
+	snapshots, err := engine.ListBackups()
+	helper.assertNoError(err, "ListBackups failed")
+	if len(snapshots) != 1 {
+		t.Errorf("expected no safety backup for a new target, got %d snapshots", len(snapshots))
+	}
+	helper.assertFileExists(filepath.Join(targetDir, "workspace", "SOUL.md"))
+}
+
+// TestRestoreToTarget_EmptyTargetNeedsNoSafetyBackup tests restoring into an existing
+// but empty directory: nothing is backed up first and there is nothing to confirm
+func TestRestoreToTarget_EmptyTargetNeedsNoSafetyBackup(t *testing.T) {
+	helper := newTestDataHelper(t)
+
+	agentDir := helper.createOpenClawAgent("test-agent")
+	backupDir := helper.createBackupDestination("local")
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: backupDir,
+		},
+	}
+
+	engine, err := NewBackupEngine(cfg)
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	result, err := engine.Backup(false, "Baseline", true, false)
+	helper.assertNoError(err, "Backup failed")
+
+	// Without --force, a target with files would ask before overwriting them
+	targetDir := t.TempDir()
+	err = engine.RestoreToTarget(result.Snapshot.ID, targetDir, false, true, false)
+	helper.assertNoError(err, "RestoreToTarget failed")
+
+	snapshots, err := engine.ListBackups()
+	helper.assertNoError(err, "ListBackups failed")
+	if len(snapshots) != 1 {
+		t.Errorf("expected no safety backup for an empty target, got %d snapshots", len(snapshots))
+	}
+	helper.assertFileExists(filepath.Join(targetDir, "workspace", "SOUL.md"))
+
+	// Once the target has files it is protected again
+	empty, err := isFreshTarget(targetDir)
+	helper.assertNoError(err, "isFreshTarget failed")
+	if empty {
+		t.Error("expected a restored target not to count as fresh")
+	}
+}
+
+func TestRestoreWithResult_ReportsQuietly(t *testing.T) {
+	helper := newTestDataHelper(t)
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := helper.createOpenClawAgent("test-agent")
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: helper.createBackupDestination("local")},
+	})
+	helper.assertNoError(err, "NewBackupEngine failed")
+	var output strings.Builder
+	engine.SetOutput(&output)
+
+	backup, err := engine.Backup(false, "Baseline", true, false)
+	helper.assertNoError(err, "Backup failed")
+	if err := os.WriteFile(filepath.Join(agentDir, "workspace", "extra.md"), []byte("added later\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var result *types.RestoreResult
+	stdout := captureStdout(t, func() {
+		result, err = engine.RestoreWithResult("1", "", false, true, true)
+	})
+	helper.assertNoError(err, "RestoreWithResult failed")
+
+	if result.SnapshotID != backup.Snapshot.ID || result.Target != agentDir || result.Cancelled {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.FilesRestored != len(backup.Snapshot.Files) || result.FilesRemoved != 1 {
+		t.Errorf("expected %d files restored and 1 removed, got %d and %d", len(backup.Snapshot.Files), result.FilesRestored, result.FilesRemoved)
+	}
+	if result.SafetyBackupID == "" || result.SafetyBackupID == backup.Snapshot.ID {
+		t.Errorf("expected a new safety backup, got %q", result.SafetyBackupID)
+	}
+	if len(result.ScriptsExecuted) != 0 {
+		t.Errorf("expected no scripts with noScripts, got %v", result.ScriptsExecuted)
+	}
+	helper.assertFileNotExists(filepath.Join(agentDir, "workspace", "extra.md"))
+
+	// Everything went to the writer, nothing to stdout
+	if stdout != "" {
+		t.Errorf("expected no stdout output, got %q", stdout)
+	}
+	if !strings.Contains(output.String(), "Restore complete") {
+		t.Errorf("expected the progress messages in the writer, got %q", output.String())
+	}
+
+	// A dry run counts without changing anything
+	result, err = engine.RestoreWithResult(backup.Snapshot.ID, t.TempDir(), true, true, true)
+	helper.assertNoError(err, "RestoreWithResult dry run failed")
+	if !result.DryRun || result.FilesRestored != len(backup.Snapshot.Files) || result.SafetyBackupID != "" {
+		t.Errorf("unexpected dry run result %+v", result)
+	}
+}
+
+func TestSetProgress_BackupAndRestore(t *testing.T) {
+	helper := newTestDataHelper(t)
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := helper.createOpenClawAgent("test-agent")
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: helper.createBackupDestination("local")},
+	})
+	helper.assertNoError(err, "NewBackupEngine failed")
+
+	var calls, lastDone, lastTotal int
+	engine.SetProgress(func(done, total int, currentPath string) {
+		calls++
+		if done != lastDone+1 || currentPath == "" {
+			t.Errorf("expected progress one file at a time, got %d after %d (%q)", done, lastDone, currentPath)
+		}
+		lastDone, lastTotal = done, total
+	})
+
+	result, err := engine.Backup(false, "Baseline", true, false)
+	helper.assertNoError(err, "Backup failed")
+	files := len(result.Snapshot.Files)
+	if calls != files || lastDone != files || lastTotal != files {
+		t.Errorf("expected %d progress reports for the backup, got %d ending at %d/%d", files, calls, lastDone, lastTotal)
+	}
+
+	calls, lastDone = 0, 0
+	helper.assertNoError(engine.RestoreToTarget(result.Snapshot.ID, t.TempDir(), false, true, true), "RestoreToTarget failed")
+	if calls != files || lastDone != files || lastTotal != files {
+		t.Errorf("expected %d progress reports for the restore, got %d ending at %d/%d", files, calls, lastDone, lastTotal)
+	}
+}
+
+func TestBackupWithLabels_LocalDestination(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	if _, err := engine.BackupWithLabels(false, "", true, false, []string{"bad label"}); err == nil {
+		t.Error("expected an invalid label to be rejected")
+	}
+
+	result, err := engine.BackupWithLabels(false, "v2 release", true, false, []string{"release", "release"})
+	if err != nil {
+		t.Fatalf("labeled backup failed: %v", err)
+	}
+	if len(result.Snapshot.Labels) != 1 || result.Snapshot.Labels[0] != "release" {
+		t.Errorf("expected snapshot labels [release], got %v", result.Snapshot.Labels)
+	}
+
+	stored, err := engine.GetSnapshot(result.Snapshot.ID)
+	if err != nil {
+		t.Fatalf("GetSnapshot failed: %v", err)
+	}
+	if len(stored.Labels) != 1 || stored.Labels[0] != "release" {
+		t.Errorf("expected stored labels [release], got %v", stored.Labels)
+	}
+
+	snapshots, err := engine.ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(snapshots) != 1 || !snapshots[0].HasLabels([]string{"release"}) {
+		t.Errorf("expected the listed snapshot to be labeled release, got %+v", snapshots)
+	}
+}
+
+func TestLocalDestination_RepairsDanglingLatest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	soulPath := filepath.Join(agentDir, "SOUL.md")
+	backupDir := t.TempDir()
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: backupDir},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	var ids []string
+	for _, content := range []string{"v1", "v2"} {
+		if err := os.WriteFile(soulPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := engine.Backup(false, "", true, false)
+		if err != nil {
+			t.Fatalf("backup failed: %v", err)
+		}
+		ids = append(ids, result.Snapshot.ID)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// Deleting the newest snapshot leaves the latest pointer dangling
+	if err := engine.Destination().DeleteSnapshot(ids[1]); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+
+	last, err := engine.Destination().GetLastSnapshot()
+	if err != nil {
+		t.Fatalf("GetLastSnapshot failed: %v", err)
+	}
+	if last == nil || last.ID != ids[0] {
+		t.Fatalf("expected fallback to %s, got %v", ids[0], last)
+	}
+
+	latest, err := os.ReadFile(filepath.Join(backupDir, ".bulletproof", "latest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(latest) != ids[0] {
+		t.Errorf("expected latest pointer repaired to %s, got %s", ids[0], latest)
+	}
+
+	// With every snapshot gone there is no last snapshot
+	if err := engine.Destination().DeleteSnapshot(ids[0]); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	last, err = engine.Destination().GetLastSnapshot()
+	if err != nil || last != nil {
+		t.Errorf("expected no last snapshot, got %v (err %v)", last, err)
+	}
+}
+
+func TestBackup_DestinationInsideSourceAndHiddenFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	for path, content := range map[string]string{
+		"SOUL.md":               "soul",
+		".env":                  "SECRET=1",
+		".vscode/settings.json": "{}",
+	} {
+		full := filepath.Join(agentDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The destination sits inside the source, as with `path: ~/.openclaw/backups`
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: filepath.Join(agentDir, "backups")},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	first, err := engine.Backup(false, "", true, false)
+	if err != nil {
+		t.Fatalf("first backup failed: %v", err)
+	}
+	if len(first.Snapshot.Files) != 3 {
+		t.Errorf("expected SOUL.md and the hidden files, got %d files", len(first.Snapshot.Files))
+	}
+
+	// The second backup must not pick up the first snapshot or its .bulletproof metadata
+	time.Sleep(2 * time.Millisecond)
+	second, err := engine.Backup(false, "", true, false)
+	if err != nil {
+		t.Fatalf("second backup failed: %v", err)
+	}
+	if !second.Skipped {
+		t.Errorf("expected no changes, got %s", second.Diff)
+	}
+
+	hidden := false
+	cfg.Options.IncludeHidden = &hidden
+	current, err := engine.ScanSource(agentDir, "", time.Now())
+	if err != nil {
+		t.Fatalf("ScanSource failed: %v", err)
+	}
+	if len(current.Files) != 1 || current.Files["SOUL.md"] == nil {
+		t.Errorf("expected only SOUL.md with include_hidden: false, got %v", current.Files)
+	}
+}
+
+func TestBackup_MessagePrompt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	soulPath := filepath.Join(agentDir, "SOUL.md")
+	if err := os.WriteFile(soulPath, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	var prompts []*types.SnapshotDiff
+	reply, replyErr := "Initial soul", error(nil)
+	engine.SetMessagePrompt(func(diff *types.SnapshotDiff) (string, error) {
+		prompts = append(prompts, diff)
+		return reply, replyErr
+	})
+
+	first, err := engine.Backup(false, "", true, false)
+	if err != nil {
+		t.Fatalf("first backup failed: %v", err)
+	}
+	if len(prompts) != 1 || prompts[0] != nil {
+		t.Fatalf("expected one prompt without a diff for the first backup, got %v", prompts)
+	}
+	stored, err := engine.GetSnapshot(first.Snapshot.ID)
+	if err != nil || stored == nil || stored.Message != "Initial soul" {
+		t.Fatalf("expected the prompted message to be stored, got %+v (%v)", stored, err)
+	}
+
+	// Skipped backups, dry runs and explicit messages never prompt
+	if _, err := engine.Backup(false, "", true, false); err != nil {
+		t.Fatalf("skipped backup failed: %v", err)
+	}
+	if err := os.WriteFile(soulPath, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Backup(true, "", true, false); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if len(prompts) != 1 {
+		t.Fatalf("expected no prompt for skipped backups or dry runs, got %d", len(prompts))
+	}
+
+	// A failed prompt aborts the backup without storing anything
+	replyErr = errors.New("empty message")
+	if _, err := engine.Backup(false, "", true, false); err == nil {
+		t.Fatal("expected the backup to abort when the prompt fails")
+	}
+	if len(prompts) != 2 || prompts[1] == nil || len(prompts[1].Modified) != 1 {
+		t.Fatalf("expected the prompt to see the pending changes, got %v", prompts)
+	}
+	backups, err := engine.ListBackups()
+	if err != nil || len(backups) != 1 {
+		t.Errorf("expected only the first backup to be stored, got %d (%v)", len(backups), err)
+	}
+
+	replyErr = nil
+	if _, err := engine.Backup(false, "Explicit", true, false); err != nil {
+		t.Fatalf("backup with message failed: %v", err)
+	}
+	if len(prompts) != 2 {
+		t.Errorf("expected no prompt when a message is given, got %d", len(prompts))
+	}
+}
+
+func TestRestoreWithResult_PreviewShowsDiffsBeforePrompt(t *testing.T) {
+	helper := newTestDataHelper(t)
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := helper.createOpenClawAgent("test-agent")
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: helper.createBackupDestination("local")},
+	})
+	helper.assertNoError(err, "NewBackupEngine failed")
+	var output strings.Builder
+	engine.SetOutput(&output)
+
+	_, err = engine.Backup(false, "Baseline", true, false)
+	helper.assertNoError(err, "Backup failed")
+	soulPath := filepath.Join(agentDir, "workspace", "SOUL.md")
+	helper.writeFile(soulPath, "tampered\n")
+	helper.writeFile(filepath.Join(agentDir, "workspace", "extra.md"), "added later\n")
+
+	// Decline the prompt
+	stdin := os.Stdin
+	t.Cleanup(func() { os.Stdin = stdin })
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("n\n")
+	w.Close()
+	os.Stdin = r
+
+	engine.SetRestorePreview(func(path string) bool { return strings.HasSuffix(path, "SOUL.md") })
+	var result *types.RestoreResult
+	stdout := captureStdout(t, func() {
+		result, err = engine.RestoreWithResult("1", "", false, true, false)
+	})
+	helper.assertNoError(err, "RestoreWithResult failed")
+
+	if !result.Cancelled {
+		t.Fatalf("expected the declined restore to be cancelled, got %+v", result)
+	}
+	if !strings.Contains(stdout, "--- a/workspace/SOUL.md") || !strings.Contains(stdout, "-tampered") {
+		t.Errorf("expected the SOUL.md diff in the preview, got:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "extra.md") {
+		t.Errorf("expected files outside the pattern to be left out of the preview, got:\n%s", stdout)
+	}
+	if !strings.Contains(output.String(), "Preview of the changes") {
+		t.Errorf("expected the preview to be announced, got:\n%s", output.String())
+	}
+	if content, _ := os.ReadFile(soulPath); string(content) != "tampered\n" {
+		t.Errorf("expected a cancelled restore to leave SOUL.md alone, got %q", content)
+	}
+}
@@ -0,0 +1,85 @@
+package backup
+
+import (
+	"fmt"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// LabelSnapshot adds labels to a stored snapshot and removes others, leaving
+// its files and message untouched. It returns the snapshot with its new labels.
+func (e *BackupEngine) LabelSnapshot(snapshotID string, add, remove []string) (*types.Snapshot, error) {
+	add, err := types.NormalizeLabels(add)
+	if err != nil {
+		return nil, err
+	}
+	remove, err = types.NormalizeLabels(remove)
+	if err != nil {
+		return nil, err
+	}
+
+	dest, ok := e.destination.(labeler)
+	if !ok {
+		return nil, fmt.Errorf("this destination does not support changing labels")
+	}
+
+	resolvedID, err := e.ResolveSnapshotID(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedID == "0" {
+		return nil, fmt.Errorf("ID 0 represents current filesystem state, not a stored snapshot")
+	}
+	snapshot, err := e.destination.GetSnapshot(resolvedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot %s: %w", resolvedID, err)
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+
+	removed := make(map[string]bool)
+	for _, label := range remove {
+		removed[label] = true
+	}
+	var labels []string
+	for _, label := range append(append([]string{}, snapshot.Labels...), add...) {
+		if !removed[label] {
+			labels = append(labels, label)
+		}
+	}
+	if labels, err = types.NormalizeLabels(labels); err != nil {
+		return nil, err
+	}
+
+	if err := dest.SetLabels(resolvedID, labels); err != nil {
+		return nil, fmt.Errorf("failed to update labels of %s: %w", resolvedID, err)
+	}
+	snapshot.Labels = labels
+	return snapshot, nil
+}
+
+// latestLabeled returns the ID of the newest snapshot carrying label. IDs are
+// formed from the backup time, so they order snapshots whose destination does
+// not list timestamps.
+func (e *BackupEngine) latestLabeled(label string) (string, error) {
+	infos, err := e.ListBackups()
+	if err != nil {
+		return "", fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var latest *types.SnapshotInfo
+	for _, info := range infos {
+		if !info.HasLabels([]string{label}) {
+			continue
+		}
+		if latest == nil || info.Timestamp.After(latest.Timestamp) ||
+			(info.Timestamp.Equal(latest.Timestamp) && info.ID > latest.ID) {
+			latest = info
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no snapshot is labeled %s", label)
+	}
+	return latest.ID, nil
+}
@@ -0,0 +1,111 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func TestLabelSnapshot(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for _, destType := range []string{"local", "git"} {
+		agentDir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(agentDir, "workspace"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		engine, err := NewBackupEngine(&config.Config{
+			OpenclawPath: agentDir,
+			Destination:  &config.DestinationConfig{Type: destType, Path: t.TempDir()},
+		})
+		if err != nil {
+			t.Fatalf("%s: NewBackupEngine failed: %v", destType, err)
+		}
+
+		var ids []string
+		for i, content := range []string{"one", "two", "three"} {
+			if err := os.WriteFile(filepath.Join(agentDir, "workspace", "SOUL.md"), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			result, err := engine.BackupWithLabels(false, content, true, false, []string{"nightly"}[:i%2])
+			if err != nil {
+				t.Fatalf("%s: Backup failed: %v", destType, err)
+			}
+			ids = append(ids, result.Snapshot.ID)
+			time.Sleep(2 * time.Millisecond)
+		}
+
+		// Labels are added to those given at backup time, by short or full ID
+		if _, err := engine.LabelSnapshot("2", []string{"known-good", "nightly"}, nil); err != nil {
+			t.Fatalf("%s: LabelSnapshot failed: %v", destType, err)
+		}
+		if _, err := engine.LabelSnapshot(ids[0], []string{"known-good"}, nil); err != nil {
+			t.Fatalf("%s: LabelSnapshot failed: %v", destType, err)
+		}
+		snapshot, err := engine.GetSnapshot(ids[1])
+		if err != nil {
+			t.Fatalf("%s: GetSnapshot failed: %v", destType, err)
+		}
+		if want := []string{"known-good", "nightly"}; !reflect.DeepEqual(sortedLabels(snapshot.Labels), want) {
+			t.Errorf("%s: stored labels %v, want %v", destType, snapshot.Labels, want)
+		}
+		if labeled := labeledIDs(t, engine, "known-good"); !reflect.DeepEqual(labeled, map[string]bool{ids[0]: true, ids[1]: true}) {
+			t.Errorf("%s: listed known-good snapshots %v", destType, labeled)
+		}
+		if snapshot.Message != "two" || len(snapshot.Files) != 1 {
+			t.Errorf("%s: expected the message and files to be untouched, got %q with %d files", destType, snapshot.Message, len(snapshot.Files))
+		}
+
+		// A bisect can start from the newest snapshot with a label
+		good, err := engine.bisectSnapshot("known-good")
+		if err != nil || good.ID != ids[1] {
+			t.Errorf("%s: expected known-good to name %s, got %v (%v)", destType, ids[1], good, err)
+		}
+
+		// Removing leaves the other labels
+		snapshot, err = engine.LabelSnapshot(ids[1], nil, []string{"known-good"})
+		if err != nil {
+			t.Fatalf("%s: LabelSnapshot remove failed: %v", destType, err)
+		}
+		if !reflect.DeepEqual(snapshot.Labels, []string{"nightly"}) {
+			t.Errorf("%s: expected only nightly left, got %v", destType, snapshot.Labels)
+		}
+		if labeled := labeledIDs(t, engine, "known-good"); !reflect.DeepEqual(labeled, map[string]bool{ids[0]: true}) {
+			t.Errorf("%s: listed known-good snapshots %v after removing", destType, labeled)
+		}
+
+		if _, err := engine.LabelSnapshot("1", []string{"bad label"}, nil); err == nil {
+			t.Errorf("%s: expected an invalid label to be refused", destType)
+		}
+		if _, err := engine.bisectSnapshot("no-such-label"); err == nil {
+			t.Errorf("%s: expected an unknown label to be refused", destType)
+		}
+	}
+}
+
+// labeledIDs returns the IDs of the listed snapshots carrying label
+func labeledIDs(t *testing.T, engine *BackupEngine, label string) map[string]bool {
+	t.Helper()
+	infos, err := engine.ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	ids := make(map[string]bool)
+	for _, info := range infos {
+		if info.HasLabels([]string{label}) {
+			ids[info.ID] = true
+		}
+	}
+	return ids
+}
+
+func sortedLabels(labels []string) []string {
+	sorted := append([]string{}, labels...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// ErrParentCycle is returned when following parents leads back to a snapshot
+// already visited, which only happens when snapshot metadata was edited by hand
+var ErrParentCycle = errors.New("snapshot parents form a cycle")
+
+// Lineage is a snapshot and its ancestors, newest first
+type Lineage struct {
+	Snapshots []*types.Snapshot
+
+	// MissingParent is the parent the chain stops at because it is no longer
+	// stored, e.g. after pruning. Empty when the chain reaches the first backup.
+	MissingParent string
+
+	// NewerThanLatest is the newest stored snapshot when it is newer than the
+	// one the destination's latest pointer names. Only checked for the lineage
+	// of the latest snapshot.
+	NewerThanLatest string
+}
+
+// Lineage follows a snapshot's parents back to the first backup. With an
+// empty ID it starts from the destination's latest snapshot and checks that
+// no stored snapshot is newer.
+func (e *BackupEngine) Lineage(snapshotID string) (*Lineage, error) {
+	var start *types.Snapshot
+	var err error
+	if snapshotID == "" {
+		start, err = e.destination.GetLastSnapshot()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last snapshot: %w", err)
+		}
+		if start == nil {
+			return nil, fmt.Errorf("no snapshots found")
+		}
+	} else {
+		start, err = e.GetSnapshot(snapshotID)
+		if err != nil {
+			return nil, err
+		}
+		if start == nil {
+			return nil, fmt.Errorf("snapshot not found: %s", snapshotID)
+		}
+	}
+
+	lineage, err := walkParents(start, e.destination.GetSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshotID == "" {
+		backups, err := e.destination.ListSnapshots()
+		if err != nil {
+			return nil, err
+		}
+		newest := start.Timestamp
+		for _, b := range backups {
+			if b.Timestamp.After(newest) {
+				newest = b.Timestamp
+				lineage.NewerThanLatest = b.ID
+			}
+		}
+	}
+	return lineage, nil
+}
+
+// walkParents collects start and its ancestors, loading each parent by ID.
+// A parent that load reports as missing (nil) ends the chain.
+func walkParents(start *types.Snapshot, load func(id string) (*types.Snapshot, error)) (*Lineage, error) {
+	lineage := &Lineage{}
+	seen := make(map[string]bool)
+	for snapshot := start; snapshot != nil; {
+		if seen[snapshot.ID] {
+			return nil, fmt.Errorf("%w: %s is its own ancestor", ErrParentCycle, snapshot.ID)
+		}
+		seen[snapshot.ID] = true
+		lineage.Snapshots = append(lineage.Snapshots, snapshot)
+
+		if snapshot.Parent == "" {
+			break
+		}
+		parent, err := load(snapshot.Parent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load parent %s of %s: %w", snapshot.Parent, snapshot.ID, err)
+		}
+		if parent == nil {
+			lineage.MissingParent = snapshot.Parent
+		}
+		snapshot = parent
+	}
+	return lineage, nil
+}
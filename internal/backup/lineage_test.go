@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+func TestLineage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(agentDir, "workspace"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	var ids []string
+	for _, content := range []string{"one", "two", "three"} {
+		if err := os.WriteFile(filepath.Join(agentDir, "workspace", "SOUL.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := engine.Backup(false, content, true, false)
+		if err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		ids = append(ids, result.Snapshot.ID)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// Each backup records the previous latest snapshot in its metadata
+	stored, err := engine.GetSnapshot(ids[2])
+	if err != nil {
+		t.Fatalf("GetSnapshot failed: %v", err)
+	}
+	if stored.Parent != ids[1] {
+		t.Errorf("parent of %s is %q, want %s", ids[2], stored.Parent, ids[1])
+	}
+
+	lineage, err := engine.Lineage("")
+	if err != nil {
+		t.Fatalf("Lineage failed: %v", err)
+	}
+	if got, want := lineageIDs(lineage), []string{ids[2], ids[1], ids[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lineage %v, want %v", got, want)
+	}
+	if lineage.MissingParent != "" || lineage.NewerThanLatest != "" {
+		t.Errorf("unexpected lineage problems: %+v", lineage)
+	}
+
+	lineage, err = engine.Lineage("2")
+	if err != nil {
+		t.Fatalf("Lineage failed: %v", err)
+	}
+	if got, want := lineageIDs(lineage), []string{ids[1], ids[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lineage of short ID 2 %v, want %v", got, want)
+	}
+}
+
+func TestWalkParents(t *testing.T) {
+	stored := map[string]*types.Snapshot{
+		"c": {ID: "c", Parent: "b"},
+		"b": {ID: "b", Parent: "a"},
+		"a": {ID: "a", Parent: "pruned"},
+	}
+	load := func(id string) (*types.Snapshot, error) {
+		return stored[id], nil
+	}
+
+	lineage, err := walkParents(stored["c"], load)
+	if err != nil {
+		t.Fatalf("walkParents failed: %v", err)
+	}
+	if got := lineageIDs(lineage); !reflect.DeepEqual(got, []string{"c", "b", "a"}) {
+		t.Errorf("lineage %v", got)
+	}
+	if lineage.MissingParent != "pruned" {
+		t.Errorf("missing parent %q, want pruned", lineage.MissingParent)
+	}
+
+	// A hand-edited parent pointing back down the chain is reported, not followed forever
+	stored["a"].Parent = "c"
+	if _, err := walkParents(stored["c"], load); !errors.Is(err, ErrParentCycle) {
+		t.Errorf("expected ErrParentCycle, got %v", err)
+	}
+	stored["a"].Parent = "a"
+	if _, err := walkParents(stored["a"], load); !errors.Is(err, ErrParentCycle) {
+		t.Errorf("expected ErrParentCycle for a self-parent, got %v", err)
+	}
+}
+
+func lineageIDs(lineage *Lineage) []string {
+	var ids []string
+	for _, snapshot := range lineage.Snapshots {
+		ids = append(ids, snapshot.ID)
+	}
+	return ids
+}
@@ -0,0 +1,130 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+const (
+	// lockTimeout is how long an operation waits for another one on the same
+	// destination to finish before giving up, unless told to wait
+	lockTimeout = 5 * time.Second
+
+	// lockPoll is how often a busy lock is tried again
+	lockPoll = 200 * time.Millisecond
+)
+
+// ErrLocked is returned when another bulletproof operation holds the
+// destination's lock
+var ErrLocked = errors.New("another bulletproof operation is in progress")
+
+// errLockBusy is returned by lockFile when another open file holds the lock
+var errLockBusy = errors.New("lock is held")
+
+// lockPath returns the lock file guarding the destination. Local destinations
+// keep it in their .bulletproof directory, beside the index it protects;
+// others use the user cache so it is never committed into a backup repository.
+func (e *BackupEngine) lockPath() (string, error) {
+	if dest, ok := localDestination(e.destination); ok {
+		return filepath.Join(dest.BasePath, ".bulletproof", "lock"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	destKey := utils.HashString(e.config.Destination.Type + ":" + e.config.Destination.Location())[:16]
+	return filepath.Join(homeDir, ".cache", "bulletproof", "locks", destKey+".lock"), nil
+}
+
+// lock takes the destination's lock for an operation that writes to it, so a
+// scheduled backup never runs into a manual one. It waits up to lockTimeout
+// for another operation to finish, or as long as it takes after
+// SetWaitForLock. The returned function releases the lock. Operations nested
+// in one that holds it, such as the safety backup of a restore, share it.
+func (e *BackupEngine) lock() (func(), error) {
+	if e.lockHeld {
+		return func() {}, nil
+	}
+
+	path, err := e.lockPath()
+	if err != nil {
+		return nil, err
+	}
+	timeout := lockTimeout
+	if e.waitForLock {
+		timeout = -1
+	}
+	release, err := acquireLock(path, timeout, func(pid string) {
+		fmt.Fprintf(e.output(), "⏳ Waiting for another bulletproof operation (pid %s) to finish...\n", pid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	e.lockHeld = true
+	return func() {
+		e.lockHeld = false
+		release()
+	}, nil
+}
+
+// acquireLock takes an exclusive lock on the file at path, with flock or
+// LockFileEx, and writes this process's ID into it. While another process
+// holds the lock, it retries for up to timeout, forever if timeout is
+// negative, calling waiting once with the ID the holder wrote. The operating
+// system releases the lock when its holder exits, so a run that crashed never
+// leaves one behind, and the file itself is never removed. The returned
+// function releases the lock.
+func acquireLock(path string, timeout time.Duration, waiting func(pid string)) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock %s: %w", path, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	notified := false
+	for {
+		err := lockFile(file)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errLockBusy) {
+			file.Close()
+			return nil, fmt.Errorf("failed to take lock %s: %w", path, err)
+		}
+
+		pid := "unknown"
+		if data, err := os.ReadFile(path); err == nil && strings.TrimSpace(string(data)) != "" {
+			pid = strings.TrimSpace(string(data))
+		}
+		if timeout >= 0 && time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%w (pid %s holds %s): run again once it finishes, or use --wait to wait for it", ErrLocked, pid, path)
+		}
+		if !notified && waiting != nil {
+			waiting(pid)
+			notified = true
+		}
+		time.Sleep(lockPoll)
+	}
+
+	// The ID only serves the messages of runs waiting for this one
+	file.Truncate(0)
+	file.WriteAt([]byte(fmt.Sprintf("%d\n", os.Getpid())), 0)
+
+	return func() {
+		file.Truncate(0)
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,97 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".bulletproof", "lock")
+
+	release, err := acquireLock(path, 0, nil)
+	if err != nil {
+		t.Fatalf("acquireLock failed: %v", err)
+	}
+
+	// A second run gives up after the timeout, naming the holder and the file
+	var waitedFor string
+	_, err = acquireLock(path, 2*lockPoll, func(pid string) { waitedFor = pid })
+	if !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), "--wait") || !strings.Contains(err.Error(), path) {
+		t.Errorf("expected ErrLocked suggesting --wait, got %v", err)
+	}
+	if pid, _ := os.ReadFile(path); waitedFor != strings.TrimSpace(string(pid)) || waitedFor == "" {
+		t.Errorf("waiting called with pid %q, lock holds %q", waitedFor, pid)
+	}
+
+	// However old the file looks, a held lock is never taken over
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acquireLock(path, 0, nil); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected a held lock with an old file to stay held, got %v", err)
+	}
+
+	release()
+	release, err = acquireLock(path, 0, nil)
+	if err != nil {
+		t.Fatalf("expected a released lock to be free, got %v", err)
+	}
+	release()
+
+	// A lock file left behind by a run that crashed holds no lock
+	if err := os.WriteFile(path, []byte("999999\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	release, err = acquireLock(path, 0, nil)
+	if err != nil {
+		t.Fatalf("expected a leftover lock file to be taken, got %v", err)
+	}
+	if pid, _ := os.ReadFile(path); strings.TrimSpace(string(pid)) == "999999" {
+		t.Error("expected the lock file to name this process")
+	}
+	release()
+}
+
+func TestAcquireLock_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".bulletproof", "lock")
+
+	// Runs that want the lock at once take turns holding it
+	var holders, maxHolders atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			release, err := acquireLock(path, -1, nil)
+			if err != nil {
+				t.Errorf("acquireLock failed: %v", err)
+				return
+			}
+			n := holders.Add(1)
+			for {
+				highest := maxHolders.Load()
+				if n <= highest || maxHolders.CompareAndSwap(highest, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			holders.Add(-1)
+			release()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if got := maxHolders.Load(); got != 1 {
+		t.Errorf("%d runs held the lock at once, want 1", got)
+	}
+}
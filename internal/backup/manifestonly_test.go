@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func TestBackup_ManifestOnly(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	backupDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte("# Soul\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: backupDir},
+	})
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	engine.SetManifestOnly(true)
+	checkpoint, err := engine.Backup(false, "Checkpoint", true, false)
+	if err != nil {
+		t.Fatalf("manifest-only backup failed: %v", err)
+	}
+	if !checkpoint.Snapshot.ManifestOnly {
+		t.Error("expected the snapshot to be marked manifest-only")
+	}
+
+	// The manifest is stored, the file is not
+	snapshotDir := filepath.Join(backupDir, checkpoint.Snapshot.ID)
+	if _, err := os.Stat(filepath.Join(snapshotDir, "SOUL.md")); !os.IsNotExist(err) {
+		t.Errorf("expected SOUL.md not to be copied, got %v", err)
+	}
+	stored, err := engine.GetSnapshot(checkpoint.Snapshot.ID)
+	if err != nil || stored == nil || !stored.ManifestOnly || stored.Files["SOUL.md"] == nil {
+		t.Fatalf("expected the stored manifest to list SOUL.md, got %+v (%v)", stored, err)
+	}
+
+	backups, err := engine.ListBackups()
+	if err != nil || len(backups) != 1 || !backups[0].ManifestOnly {
+		t.Errorf("expected the listing to mark the snapshot manifest-only, got %+v (%v)", backups, err)
+	}
+
+	// It cannot be restored, and verify has no files to miss
+	if err := engine.RestoreToTarget("1", t.TempDir(), false, true, true); err == nil || !strings.Contains(err.Error(), "manifest-only") {
+		t.Errorf("expected restore to be refused, got %v", err)
+	}
+	if _, err := engine.PlanRestore("1", ""); err == nil {
+		t.Error("expected planning a restore to be refused")
+	}
+	report, err := engine.Verify(false, 0, false)
+	if err != nil || len(report.Failed()) != 0 {
+		t.Errorf("expected verify to pass, got %+v (%v)", report, err)
+	}
+
+	// A full backup of the unchanged state is not skipped, since the
+	// checkpoint holds nothing to restore
+	engine.SetManifestOnly(false)
+	full, err := engine.Backup(false, "Full", true, false)
+	if err != nil {
+		t.Fatalf("full backup failed: %v", err)
+	}
+	if full.Skipped || full.Snapshot.ManifestOnly {
+		t.Fatalf("expected a full backup, got skipped=%v manifestOnly=%v", full.Skipped, full.Snapshot.ManifestOnly)
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, full.Snapshot.ID, "SOUL.md")); err != nil {
+		t.Errorf("expected the full backup to copy SOUL.md: %v", err)
+	}
+}
+
+func TestBackup_ManifestOnlyNeedsLocalDestination(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: t.TempDir(),
+		Destination:  &config.DestinationConfig{Type: "sync", Path: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	engine.SetManifestOnly(true)
+	if _, err := engine.Backup(false, "Checkpoint", true, false); err == nil || !strings.Contains(err.Error(), "local destination") {
+		t.Errorf("expected a sync destination to be refused, got %v", err)
+	}
+}
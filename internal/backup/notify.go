@@ -0,0 +1,119 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// notifyTimeout bounds a webhook request, so a slow endpoint never holds up
+// the backup it reports on for long
+const notifyTimeout = 5 * time.Second
+
+// BackupNotification is the JSON body POSTed to the notification webhook
+// after a backup. Text and Content repeat the outcome as one line, the field
+// Slack and Discord incoming webhooks display respectively.
+type BackupNotification struct {
+	Status     string    `json:"status"` // "created", "skipped" or "failed"
+	SnapshotID string    `json:"snapshot_id,omitempty"`
+	FileCount  int       `json:"file_count,omitempty"`
+	Changes    string    `json:"changes,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	Host       string    `json:"host,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Text       string    `json:"text"`
+	Content    string    `json:"content"`
+}
+
+// newBackupNotification describes the outcome of a backup, err being its error
+func newBackupNotification(result *types.BackupResult, err error, now time.Time) BackupNotification {
+	n := BackupNotification{Status: "created", Timestamp: now.UTC()}
+	n.Host, _ = os.Hostname()
+
+	switch {
+	case err != nil:
+		n.Status = "failed"
+		n.Error = err.Error()
+	case result.Skipped:
+		n.Status = "skipped"
+	}
+	// A skipped backup stores nothing, so it reports the snapshot it matched
+	if err == nil && result.Skipped && result.LastSnapshot != nil {
+		n.SnapshotID = result.LastSnapshot.ID
+		n.FileCount = len(result.LastSnapshot.Files)
+	} else if err == nil && result.Snapshot != nil {
+		n.SnapshotID = result.Snapshot.ID
+		n.FileCount = len(result.Snapshot.Files)
+	}
+	if err == nil && result.Diff != nil {
+		n.Changes = result.Diff.String()
+	}
+
+	host := ""
+	if n.Host != "" {
+		host = " on " + n.Host
+	}
+	switch n.Status {
+	case "failed":
+		n.Text = fmt.Sprintf("❌ Bulletproof backup%s failed: %s", host, n.Error)
+	case "skipped":
+		n.Text = fmt.Sprintf("✨ Bulletproof backup%s skipped: no changes since %s", host, n.SnapshotID)
+	case "created":
+		n.Text = fmt.Sprintf("✅ Bulletproof backup%s complete: %s (%d files)", host, n.SnapshotID, n.FileCount)
+		if n.Changes != "" {
+			n.Text += ": " + n.Changes
+		}
+	}
+	n.Content = n.Text
+	return n
+}
+
+// notifyBackup POSTs the outcome of a backup to the configured webhook in the
+// background. A notification that cannot be sent is reported as a warning and
+// never changes the backup's outcome; WaitForNotifications waits for it.
+func (e *BackupEngine) notifyBackup(result *types.BackupResult, err error) {
+	url := e.config.Notifications.WebhookURL
+	if url == "" {
+		return
+	}
+
+	body, marshalErr := json.Marshal(newBackupNotification(result, err, time.Now()))
+	if marshalErr != nil {
+		return
+	}
+
+	e.notifications.Add(1)
+	go func() {
+		defer e.notifications.Done()
+		if err := postWebhook(url, body); err != nil {
+			fmt.Fprintf(e.output(), "⚠️  Failed to send backup notification: %v\n", err)
+		}
+	}()
+}
+
+// WaitForNotifications waits for backup notifications still being sent,
+// each of which gives up after a few seconds. Commands call it before exiting,
+// since the process ending would cut them off.
+func (e *BackupEngine) WaitForNotifications() {
+	e.notifications.Wait()
+}
+
+// postWebhook POSTs a JSON body to url
+func postWebhook(url string, body []byte) error {
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func TestBackup_PostsNotifications(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var mu sync.Mutex
+	var received []BackupNotification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n BackupNotification
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&n) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, n)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	agentDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(agentDir, "workspace"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(agentDir, "workspace", "SOUL.md"), []byte("# Soul\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		OpenclawPath:  agentDir,
+		Destination:   &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+		Notifications: config.NotificationsConfig{WebhookURL: server.URL},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	engine.SetOutput(&bytes.Buffer{})
+
+	// Notifications are sent in the background, so each is waited for to keep
+	// them in order
+	result, err := engine.Backup(false, "first", true, false)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	engine.WaitForNotifications()
+	if _, err := engine.Backup(false, "", true, false); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	engine.WaitForNotifications()
+	// Dry runs store nothing and are not reported
+	if _, err := engine.Backup(true, "", true, false); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	// A failed backup is reported with its error
+	os.RemoveAll(agentDir)
+	if _, err := engine.Backup(false, "", true, false); err == nil {
+		t.Fatal("expected a backup of a missing agent to fail")
+	}
+	engine.WaitForNotifications()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 3 {
+		t.Fatalf("expected 3 notifications, got %d: %+v", len(received), received)
+	}
+	created := received[0]
+	if created.Status != "created" || created.SnapshotID != result.Snapshot.ID || created.FileCount != 1 || created.Changes != "" || created.Timestamp.IsZero() {
+		t.Errorf("unexpected notification for a created snapshot: %+v", created)
+	}
+	if created.Text == "" || created.Content != created.Text || !strings.Contains(created.Text, result.Snapshot.ID) {
+		t.Errorf("expected a one-line message naming the snapshot, got %q", created.Text)
+	}
+	// The first backup has nothing to compare against, so only later ones
+	// carry a change summary
+	if skipped := received[1]; skipped.Status != "skipped" || skipped.SnapshotID != result.Snapshot.ID {
+		t.Errorf("unexpected notification for a skipped backup: %+v", skipped)
+	}
+	if failed := received[2]; failed.Status != "failed" || failed.Error == "" || failed.SnapshotID != "" {
+		t.Errorf("unexpected notification for a failed backup: %+v", failed)
+	}
+}
+
+func TestBackup_UnreachableWebhookDoesNotFailBackup(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	agentDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(agentDir, "openclaw.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath:  agentDir,
+		Destination:   &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+		Notifications: config.NotificationsConfig{WebhookURL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	var out bytes.Buffer
+	engine.SetOutput(&out)
+
+	if _, err := engine.Backup(false, "first", true, false); err != nil {
+		t.Fatalf("expected the backup to succeed despite the webhook, got %v", err)
+	}
+	engine.WaitForNotifications()
+	if !strings.Contains(out.String(), "Failed to send backup notification") {
+		t.Errorf("expected a warning about the failed notification, got:\n%s", out.String())
+	}
+}
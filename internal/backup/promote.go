@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// Promote copies a stored snapshot into another destination, keeping its ID,
+// message, labels and manifest. Files are read from the current destination, so
+// the live agent is not scanned. This lets frequent cheap snapshots go to a fast
+// local destination while selected ones are promoted to a durable one.
+func (e *BackupEngine) Promote(snapshotID string, target *config.DestinationConfig) (*types.Snapshot, error) {
+	resolvedID, err := e.ResolveSnapshotID(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedID == "0" {
+		return nil, fmt.Errorf("ID 0 represents current filesystem state, not a stored snapshot")
+	}
+
+	snapshot, err := e.destination.GetSnapshot(resolvedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot %s: %w", resolvedID, err)
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("snapshot not found: %s", resolvedID)
+	}
+
+	targetDest, err := createDestination(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create target destination: %w", err)
+	}
+	e.forwardReporting(targetDest)
+	if err := targetDest.Validate(); err != nil {
+		return nil, fmt.Errorf("target destination is not usable: %w", err)
+	}
+
+	// Files are read decoded, so only a destination that encrypts them again
+	// may take an encrypted snapshot; others store them uncompressed
+	if _, ok := localDestination(targetDest); ok {
+		if err := applyStorageOptions(targetDest, e.config); err != nil {
+			return nil, err
+		}
+	} else if snapshot.Encrypted {
+		return nil, fmt.Errorf("snapshot %s is encrypted: promoting it to a %s destination would store its files in the clear", snapshot.ID, target.Type)
+	} else {
+		snapshot.Compression = ""
+	}
+
+	// Git reports a missing tag as an error, so only a found snapshot counts
+	if existing, err := targetDest.GetSnapshot(snapshot.ID); err == nil && existing != nil {
+		return nil, fmt.Errorf("snapshot %s already exists in %s", snapshot.ID, target.Location())
+	}
+
+	filesPath, cleanup, err := e.storedSnapshotFiles(snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	message := snapshot.Message
+	if message == "" {
+		message = fmt.Sprintf("Promoted snapshot %s", snapshot.ID)
+	}
+
+	if err := targetDest.Save(filesPath, snapshot, message); err != nil {
+		return nil, fmt.Errorf("failed to save snapshot to target: %w", err)
+	}
+
+	// Carry over the exports and config stored alongside a timestamped snapshot
+	if path := targetDest.GetSnapshotPath(snapshot.ID); path != "" {
+		if err := copySnapshotExtras(filesPath, path); err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshot, nil
+}
+
+// copySnapshotExtras copies the parts of a snapshot folder that are not in its
+// manifest: script exports and the .bulletproof config and scripts
+func copySnapshotExtras(sourcePath, targetPath string) error {
+	for _, dir := range []string{"_exports", filepath.Join(".bulletproof", "scripts")} {
+		source := filepath.Join(sourcePath, dir)
+		if _, err := os.Stat(source); err != nil {
+			continue
+		}
+		if err := utils.CopyDirectory(source, filepath.Join(targetPath, dir), nil); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", dir, err)
+		}
+	}
+
+	configFile := filepath.Join(sourcePath, ".bulletproof", "config.yaml")
+	if _, err := os.Stat(configFile); err == nil {
+		if err := utils.CopyFile(configFile, filepath.Join(targetPath, ".bulletproof", "config.yaml")); err != nil {
+			return fmt.Errorf("failed to copy config: %w", err)
+		}
+	}
+
+	return nil
+}
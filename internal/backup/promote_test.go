@@ -0,0 +1,164 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func TestPromote_CopiesStoredSnapshotToAnotherDestination(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	soulPath := filepath.Join(agentDir, "SOUL.md")
+	if err := os.MkdirAll(filepath.Join(agentDir, "workspace"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(agentDir, "workspace", "notes.md"), []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(soulPath, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	result, err := engine.BackupWithLabels(false, "before upgrade", true, false, []string{"release"})
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	promotedID := result.Snapshot.ID
+	time.Sleep(2 * time.Millisecond)
+
+	// The live agent moves on; promoting must use the stored files, not these
+	if err := os.WriteFile(soulPath, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Backup(false, "", true, false); err != nil {
+		t.Fatalf("second backup failed: %v", err)
+	}
+
+	t.Run("Local", func(t *testing.T) {
+		target := &config.DestinationConfig{Type: "local", Path: t.TempDir()}
+		if _, err := engine.Promote(promotedID, target); err != nil {
+			t.Fatalf("Promote failed: %v", err)
+		}
+
+		promoted, err := createDestination(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, err := promoted.GetSnapshot(promotedID)
+		if err != nil || stored == nil {
+			t.Fatalf("promoted snapshot not found: %v", err)
+		}
+		if stored.Message != "before upgrade" || len(stored.Labels) != 1 || stored.Labels[0] != "release" {
+			t.Errorf("metadata not preserved: message %q, labels %v", stored.Message, stored.Labels)
+		}
+		if !stored.Equal(result.Snapshot) {
+			t.Error("promoted manifest differs from the original")
+		}
+
+		content, err := os.ReadFile(filepath.Join(target.Path, promotedID, "SOUL.md"))
+		if err != nil {
+			t.Fatalf("promoted file missing: %v", err)
+		}
+		if string(content) != "v1" {
+			t.Errorf("expected stored content v1, got %q", content)
+		}
+		if _, err := os.Stat(filepath.Join(target.Path, promotedID, ".bulletproof", "config.yaml")); err != nil {
+			t.Errorf("expected snapshot config to be carried over: %v", err)
+		}
+
+		if _, err := engine.Promote(promotedID, target); err == nil {
+			t.Error("expected promoting the same snapshot twice to fail")
+		}
+	})
+
+	t.Run("Git", func(t *testing.T) {
+		repoDir := t.TempDir()
+		if _, err := gogit.PlainInit(repoDir, false); err != nil {
+			t.Fatal(err)
+		}
+		target := &config.DestinationConfig{Type: "git", Path: repoDir}
+		if _, err := engine.Promote(promotedID, target); err != nil {
+			t.Fatalf("Promote failed: %v", err)
+		}
+
+		promoted, err := createDestination(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last, err := promoted.GetLastSnapshot()
+		if err != nil || last == nil {
+			t.Fatalf("promoted snapshot not found: %v", err)
+		}
+		if last.ID != promotedID || !last.Equal(result.Snapshot) {
+			t.Errorf("expected snapshot %s with the original manifest, got %s", promotedID, last.ID)
+		}
+
+		restoreDir := t.TempDir()
+		if err := promoted.Restore(promotedID, restoreDir); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+		content, err := os.ReadFile(filepath.Join(restoreDir, "SOUL.md"))
+		if err != nil || string(content) != "v1" {
+			t.Errorf("expected restored content v1, got %q (%v)", content, err)
+		}
+	})
+}
+
+func TestPromote_SyncDestinationOnlyHasLatest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	soulPath := filepath.Join(agentDir, "SOUL.md")
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "sync", Path: t.TempDir()},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	var ids []string
+	for _, content := range []string{"v1", "v2"} {
+		if err := os.WriteFile(soulPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := engine.Backup(false, "", true, false)
+		if err != nil {
+			t.Fatalf("backup failed: %v", err)
+		}
+		ids = append(ids, result.Snapshot.ID)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	target := &config.DestinationConfig{Type: "local", Path: t.TempDir()}
+	if _, err := engine.Promote(ids[0], target); err == nil {
+		t.Error("expected promoting an overwritten sync snapshot to fail")
+	}
+	if _, err := engine.Promote(ids[1], target); err != nil {
+		t.Fatalf("promoting the latest sync snapshot failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(target.Path, ids[1], "SOUL.md"))
+	if err != nil || string(content) != "v2" {
+		t.Errorf("expected promoted content v2, got %q (%v)", content, err)
+	}
+}
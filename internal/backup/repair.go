@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// FileRepair records a damaged snapshot file replaced by an intact copy
+type FileRepair struct {
+	Path string
+	// Source names where the copy came from: "snapshot <id>" or "current state"
+	Source string
+	// SourcePath is the copied file's path within its source
+	SourcePath string
+}
+
+func (r FileRepair) String() string {
+	if r.SourcePath == r.Path {
+		return fmt.Sprintf("%s (from %s)", r.Path, r.Source)
+	}
+	return fmt.Sprintf("%s (from %s in %s)", r.Path, r.SourcePath, r.Source)
+}
+
+// repairDonor is a file that may hold the content a damaged file needs
+type repairDonor struct {
+	source string
+	dir    string
+	path   string
+}
+
+// repairDonors indexes the files of every snapshot and of the current state by
+// hash. The index is built on first use, since most verify runs find nothing
+// to repair.
+type repairDonors struct {
+	engine    *BackupEngine
+	snapshots map[string]*types.Snapshot
+	byHash    map[string][]repairDonor
+	cleanups  []func()
+}
+
+// find returns the files recorded with hash, newest snapshot first and the
+// current state last. Donors may themselves be damaged, so callers re-hash them.
+func (d *repairDonors) find(hash string) []repairDonor {
+	if d.byHash == nil {
+		d.index()
+	}
+	return d.byHash[hash]
+}
+
+func (d *repairDonors) index() {
+	d.byHash = make(map[string][]repairDonor)
+	add := func(source, dir string, snapshot *types.Snapshot) {
+		paths := make([]string, 0, len(snapshot.Files))
+		for path := range snapshot.Files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			hash := snapshot.Files[path].Hash
+			d.byHash[hash] = append(d.byHash[hash], repairDonor{source: source, dir: dir, path: path})
+		}
+	}
+
+	ids := make([]string, 0, len(d.snapshots))
+	for id, snapshot := range d.snapshots {
+		if snapshot != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	for _, id := range ids {
+		dir, cleanup, err := d.engine.storedSnapshotFiles(id)
+		if err != nil {
+			continue
+		}
+		d.cleanups = append(d.cleanups, cleanup)
+		add("snapshot "+id, dir, d.snapshots[id])
+	}
+
+	openclawPath, err := d.engine.OpenclawPath()
+	if err != nil {
+		return
+	}
+	current, err := d.engine.ScanSource(openclawPath, "", time.Now())
+	if err != nil {
+		return
+	}
+	add("current state", openclawPath, current)
+}
+
+// cleanup removes any scratch copies made while indexing
+func (d *repairDonors) cleanup() {
+	for _, cleanup := range d.cleanups {
+		cleanup()
+	}
+}
+
+// repairSnapshot verifies a snapshot's stored files and replaces each damaged
+// one with an intact copy of the same content found among donors. It returns
+// the repairs made and the problems that remain.
+func (e *BackupEngine) repairSnapshot(snapshot *types.Snapshot, donors *repairDonors) ([]FileRepair, []string) {
+	if snapshot.ManifestOnly {
+		return nil, nil
+	}
+
+	// A compressed or encrypted snapshot is checked through a decoded scratch
+	// copy, so repairs there would not reach the stored files
+	if !snapshot.StoredVerbatim() {
+		problems, err := e.verifySnapshot(snapshot)
+		if err != nil {
+			return nil, []string{err.Error()}
+		}
+		return nil, problems
+	}
+
+	filesPath, cleanup, err := e.storedSnapshotFiles(snapshot.ID)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	defer cleanup()
+
+	problems := checkSnapshotFiles(snapshot, filesPath)
+	var repairs []FileRepair
+	for _, problem := range problems {
+		// Files the manifest does not list have no intact copy to restore
+		if problem.reason == "unexpected" {
+			continue
+		}
+		hash := snapshot.Files[problem.path].Hash
+		target := filepath.Join(filesPath, problem.path)
+		for _, donor := range donors.find(hash) {
+			source := filepath.Join(donor.dir, donor.path)
+			if source == target {
+				continue
+			}
+			if donorHash, err := utils.HashFile(source); err != nil || donorHash != hash {
+				continue
+			}
+			if err := utils.CopyFile(source, target); err != nil {
+				continue
+			}
+			repairs = append(repairs, FileRepair{Path: problem.path, Source: donor.source, SourcePath: donor.path})
+			break
+		}
+	}
+
+	if len(repairs) == 0 {
+		return nil, problemStrings(problems)
+	}
+	return repairs, problemStrings(checkSnapshotFiles(snapshot, filesPath))
+}
@@ -0,0 +1,297 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bulletproof-bot/backup/internal/backup/destinations"
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// RestorePaths restores only the listed files from a snapshot. Every other file
+// in the target is left alone and nothing is deleted. Paths are relative to the
+// snapshot root, as `diff` prints them. Paths the snapshot does not contain are
+// an error unless ignoreMissing is set, in which case they are skipped with a
+// warning. Post-restore scripts are not run, since they expect a full restore.
+// If target is empty, the configured OpenClaw path is used.
+func (e *BackupEngine) RestorePaths(snapshotID string, paths []string, target string, dryRun bool, noScripts bool, force bool, ignoreMissing bool) error {
+	resolvedID, err := e.ResolveSnapshotID(snapshotID)
+	if err != nil {
+		return err
+	}
+	if resolvedID == "0" {
+		return fmt.Errorf("cannot restore to ID 0 (current filesystem state)")
+	}
+
+	targetPath := target
+	if target != "" {
+		fmt.Fprintf(e.output(), "🎯 Restoring to alternative location: %s\n", target)
+	} else {
+		targetPath, err = e.OpenclawPath()
+		if err != nil {
+			return err
+		}
+	}
+
+	snapshot, err := e.destination.GetSnapshot(resolvedID)
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	if snapshot == nil {
+		return fmt.Errorf("backup not found: %s", snapshotID)
+	}
+	if snapshot.ManifestOnly {
+		return manifestOnlyError(snapshot.ID)
+	}
+
+	found, missing, err := selectSnapshotPaths(snapshot, paths)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		if !ignoreMissing {
+			return fmt.Errorf("%d path(s) not in backup %s: %s (use --ignore-missing to skip them)", len(missing), resolvedID, strings.Join(missing, ", "))
+		}
+		fmt.Fprintf(e.output(), "⚠️  Skipping %d path(s) not in backup %s:\n", len(missing), resolvedID)
+		for _, p := range missing {
+			fmt.Fprintf(e.output(), "   • %s\n", p)
+		}
+	}
+
+	// Only files that differ from the target need copying
+	var added, modified []string
+	for _, p := range found {
+		hash, err := utils.HashFile(filepath.Join(targetPath, p))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			added = append(added, p)
+		case err != nil || hash != snapshot.Files[p].Hash:
+			modified = append(modified, p)
+		}
+	}
+
+	fmt.Fprintf(e.output(), "\n📋 Restoring %d listed file(s) from %s:\n", len(found), resolvedID)
+	printRestoreSample(e.output(), "Files to be added:", "+", added)
+	printRestoreSample(e.output(), "Files to be modified:", "~", modified)
+	if unchanged := len(found) - len(added) - len(modified); unchanged > 0 {
+		fmt.Fprintf(e.output(), "  %d file(s) already match the backup\n\n", unchanged)
+	}
+
+	changes := append(added, modified...)
+	if len(changes) == 0 {
+		fmt.Fprintln(e.output(), "✨ Nothing to restore - the listed files already match the backup.")
+		return nil
+	}
+	if dryRun {
+		fmt.Fprintln(e.output(), "🔍 Dry run - no files were changed")
+		return nil
+	}
+
+	filesPath, cleanup, err := e.storedSnapshotFiles(resolvedID)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Only the files about to be copied need to be intact
+	if !e.skipRestoreVerify {
+		var problems []fileProblem
+		for _, p := range changes {
+			if problem, ok := checkStoredFile(snapshot, filesPath, p); !ok {
+				problems = append(problems, problem)
+			}
+		}
+		if err := restoreVerifyError(resolvedID, problemStrings(problems)); err != nil {
+			return err
+		}
+	}
+
+	if !force {
+		fmt.Fprintf(e.resultOutput(), "⚠️  This will overwrite %d file(s). Are you sure? [y/N]: ", len(changes))
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Fprintln(e.resultOutput(), "❌ Restore cancelled.")
+			fmt.Fprintln(e.output(), "💡 Use --force flag to skip this confirmation prompt")
+			return nil
+		}
+	}
+
+	unlock, err := e.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	safetyBackup, err := e.safetyBackup(target, targetPath, noScripts)
+	if err != nil {
+		return fmt.Errorf("failed to create safety backup: %w", err)
+	}
+	if safetyBackup != nil && !safetyBackup.Skipped {
+		fmt.Fprintf(e.output(), "📝 Safety backup created: %s\n", safetyBackup.Snapshot.ID)
+	}
+
+	fmt.Fprintf(e.output(), "\n🔄 Restoring %d file(s) from %s...\n", len(changes), snapshotID)
+	for _, p := range changes {
+		if err := utils.CopyFile(filepath.Join(filesPath, p), filepath.Join(targetPath, p)); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", p, err)
+		}
+		if err := snapshot.RestoreMode(targetPath, p); err != nil {
+			return fmt.Errorf("failed to restore mode of %s: %w", p, err)
+		}
+		if err := utils.MatchPathCase(targetPath, p); err != nil {
+			return fmt.Errorf("failed to restore name of %s: %w", p, err)
+		}
+	}
+
+	fmt.Fprintln(e.resultOutput(), "✅ Restore complete!")
+	if safetyBackup != nil && !safetyBackup.Skipped {
+		fmt.Fprintf(e.output(), "💡 If something went wrong, restore from it: %s\n", safetyBackup.restoreCommand())
+	}
+	return nil
+}
+
+// selectSnapshotPaths cleans and de-duplicates a list of snapshot-relative
+// paths and splits it into those the snapshot holds and those it does not,
+// both sorted. Blank entries are ignored; paths that leave the snapshot root
+// are an error.
+func selectSnapshotPaths(snapshot *types.Snapshot, paths []string) ([]string, []string, error) {
+	seen := make(map[string]bool)
+	var found, missing []string
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		clean := filepath.Clean(filepath.FromSlash(p))
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return nil, nil, fmt.Errorf("path %q is not relative to the backup root", p)
+		}
+		if seen[clean] {
+			continue
+		}
+		seen[clean] = true
+
+		if _, ok := snapshot.Files[clean]; ok {
+			found = append(found, clean)
+		} else {
+			missing = append(missing, clean)
+		}
+	}
+
+	sort.Strings(found)
+	sort.Strings(missing)
+	return found, missing, nil
+}
+
+// RestoreFile restores the files of a snapshot matching pattern, a relative
+// path or a glob such as skills/*.js, as RestorePaths does: other files are
+// left untouched and nothing is deleted. A pattern matching a folder, such as
+// workspace/skills/*, restores the whole subtree.
+func (e *BackupEngine) RestoreFile(snapshotID string, pattern string, target string, dryRun bool, noScripts bool, force bool) error {
+	resolvedID, err := e.ResolveSnapshotID(snapshotID)
+	if err != nil {
+		return err
+	}
+	if resolvedID == "0" {
+		return fmt.Errorf("cannot restore to ID 0 (current filesystem state)")
+	}
+
+	snapshot, err := e.destination.GetSnapshot(resolvedID)
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	if snapshot == nil {
+		return fmt.Errorf("backup not found: %s", snapshotID)
+	}
+
+	if err := types.ValidatePattern(pattern); err != nil {
+		return err
+	}
+	paths := snapshot.MatchPaths(pattern)
+	if len(paths) == 0 {
+		return fmt.Errorf("no files in backup %s match %s", resolvedID, pattern)
+	}
+	fmt.Fprintf(e.output(), "🔎 %d file(s) in backup %s match %s\n", len(paths), resolvedID, pattern)
+	return e.RestorePaths(resolvedID, paths, target, dryRun, noScripts, force, false)
+}
+
+// RestoreExports re-imports a snapshot's external data without restoring any
+// of its files: it only runs the post-restore scripts, with EXPORTS_DIR set to
+// the _exports folder stored with the snapshot, e.g. to reload a Neo4j dump
+// after the database was damaged while the agent files are fine. BACKUP_DIR
+// stays the snapshot folder, as in a full restore, so the same scripts serve
+// both. Only timestamped local destinations keep exports per snapshot.
+func (e *BackupEngine) RestoreExports(snapshotID string, dryRun bool, force bool) error {
+	resolvedID, err := e.ResolveSnapshotID(snapshotID)
+	if err != nil {
+		return err
+	}
+	if resolvedID == "0" {
+		return fmt.Errorf("cannot restore to ID 0 (current filesystem state)")
+	}
+	if len(e.config.Scripts.PostRestore) == 0 {
+		return fmt.Errorf("no post-restore scripts are configured to import the exports: add them under scripts.post_restore in the config")
+	}
+
+	snapshot, err := e.destination.GetSnapshot(resolvedID)
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	if snapshot == nil {
+		return fmt.Errorf("backup not found: %s", snapshotID)
+	}
+
+	// Only a timestamped local destination keeps each snapshot's exports; a
+	// git working tree holds at most the latest backup's, whichever snapshot
+	// is asked for
+	local, ok := e.destination.(*destinations.LocalDestination)
+	if !ok || !local.Timestamped {
+		return fmt.Errorf("exports-only restore needs a timestamped local destination, where each snapshot's exports are stored beside it; %s destinations do not keep them per snapshot", e.config.Destination.Type)
+	}
+	snapshotPath := local.GetSnapshotPath(resolvedID)
+	exportsDir := filepath.Join(snapshotPath, "_exports")
+	exports, err := listFiles(exportsDir)
+	if err != nil {
+		return fmt.Errorf("failed to read exports of %s: %w", resolvedID, err)
+	}
+	if len(exports) == 0 {
+		return fmt.Errorf("backup %s has no stored _exports to import", resolvedID)
+	}
+
+	openclawPath, err := e.OpenclawPath()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.output(), "📦 Found %d exported file(s) in backup %s; agent files are left untouched\n", len(exports), resolvedID)
+	if dryRun {
+		fmt.Fprintln(e.output(), "\n🔍 Dry run - would run these post-restore scripts:")
+		for _, script := range e.config.Scripts.PostRestore {
+			fmt.Fprintf(e.output(), "  %s: %s\n", script.Name, script.Command)
+		}
+		fmt.Fprintf(e.output(), "with EXPORTS_DIR=%s\n", exportsDir)
+		return nil
+	}
+
+	unlock, err := e.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	executed, err := e.runPostRestoreScripts(resolvedID, openclawPath, exportsDir, force, "❌ Script execution cancelled. Nothing was imported.")
+	if err != nil {
+		return err
+	}
+	if len(executed) > 0 {
+		fmt.Fprintln(e.resultOutput(), "✅ Exports imported!")
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+func TestRestorePaths_RestoresOnlyListedFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(agentDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readFile := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(agentDir, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	writeFile("SOUL.md", "good soul")
+	writeFile("skills/weather/SKILL.md", "good skill")
+	writeFile("notes.md", "good notes")
+	result, err := engine.Backup(false, "", true, true)
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	id := result.Snapshot.ID
+
+	// Simulate a compromise touching two files, plus unrelated later work
+	writeFile("SOUL.md", "evil soul")
+	if err := os.Remove(filepath.Join(agentDir, "skills", "weather", "SKILL.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile("notes.md", "newer notes")
+	writeFile("new.md", "new file")
+
+	err = engine.RestorePaths(id, []string{"SOUL.md", "./skills/weather/SKILL.md", "nope.md"}, "", false, true, true, false)
+	if err == nil || !strings.Contains(err.Error(), "nope.md") {
+		t.Fatalf("expected an error naming the missing path, got %v", err)
+	}
+	if got := readFile("SOUL.md"); got != "evil soul" {
+		t.Errorf("nothing should be restored when a path is missing, SOUL.md = %q", got)
+	}
+
+	if err := engine.RestorePaths(id, []string{"SOUL.md", "./skills/weather/SKILL.md", "nope.md"}, "", false, true, true, true); err != nil {
+		t.Fatalf("RestorePaths failed: %v", err)
+	}
+	for name, want := range map[string]string{
+		"SOUL.md":                 "good soul",
+		"skills/weather/SKILL.md": "good skill",
+		"notes.md":                "newer notes",
+		"new.md":                  "new file",
+	} {
+		if got := readFile(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRestoreFile_RestoresMatchingFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	files := []string{"SOUL.md", "skills/a.js", "skills/b.js", "skills/notes.md"}
+	write := func(content string) {
+		t.Helper()
+		for _, name := range files {
+			path := filepath.Join(agentDir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content+" "+name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write("good")
+	result, err := engine.Backup(false, "", true, true)
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	write("drifted")
+
+	if err := engine.RestoreFile(result.Snapshot.ID, "skills/*.js", "", false, true, true); err != nil {
+		t.Fatalf("RestoreFile failed: %v", err)
+	}
+	for _, name := range files {
+		want := "drifted " + name
+		if strings.HasSuffix(name, ".js") {
+			want = "good " + name
+		}
+		if data, _ := os.ReadFile(filepath.Join(agentDir, filepath.FromSlash(name))); string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+
+	if err := engine.RestoreFile(result.Snapshot.ID, "skills/*.py", "", false, true, true); err == nil {
+		t.Error("expected a pattern matching nothing to fail")
+	}
+
+	// A folder glob restores the whole subtree, nested files included
+	write("drifted")
+	if err := engine.RestoreFile(result.Snapshot.ID, "skills/*", "", false, true, true); err != nil {
+		t.Fatalf("RestoreFile failed: %v", err)
+	}
+	for _, name := range files {
+		want := "drifted " + name
+		if strings.HasPrefix(name, "skills/") {
+			want = "good " + name
+		}
+		if data, _ := os.ReadFile(filepath.Join(agentDir, filepath.FromSlash(name))); string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+}
+
+func TestSelectSnapshotPaths(t *testing.T) {
+	snapshot := &types.Snapshot{Files: map[string]*types.FileSnapshot{
+		"SOUL.md": {Path: "SOUL.md"},
+		filepath.Join("skills", "weather", "a.md"): {Path: filepath.Join("skills", "weather", "a.md")},
+	}}
+
+	found, missing, err := selectSnapshotPaths(snapshot, []string{"  SOUL.md ", "", "skills/weather/a.md", "./SOUL.md", "gone.md"})
+	if err != nil {
+		t.Fatalf("selectSnapshotPaths failed: %v", err)
+	}
+	if want := []string{"SOUL.md", filepath.Join("skills", "weather", "a.md")}; !reflect.DeepEqual(found, want) {
+		t.Errorf("found = %v, want %v", found, want)
+	}
+	if want := []string{"gone.md"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing = %v, want %v", missing, want)
+	}
+
+	for _, escaping := range []string{"../etc/passwd", "/etc/passwd", "skills/../../x"} {
+		if _, _, err := selectSnapshotPaths(snapshot, []string{escaping}); err == nil {
+			t.Errorf("expected %q to be rejected", escaping)
+		}
+	}
+}
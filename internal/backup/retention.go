@@ -14,6 +14,8 @@ type PruneResult struct {
 	SnapshotsToKeep   []*types.SnapshotInfo
 	SnapshotsToDelete []*types.SnapshotInfo
 	TotalSnapshots    int
+	// DeletedBytes adds up the sizes of the snapshots to delete
+	DeletedBytes int64
 }
 
 // CalculatePruneTargets determines which snapshots to keep and which to delete based on retention policy
@@ -30,16 +32,28 @@ func CalculatePruneTargets(snapshots []*types.SnapshotInfo, policy config.Retent
 		}, nil
 	}
 
-	// Sort snapshots by timestamp (newest first)
+	// Sort snapshots by timestamp (newest first). Git destinations date
+	// snapshots to the second, so ties fall back to the IDs, which sort by time.
 	sortedSnapshots := make([]*types.SnapshotInfo, len(snapshots))
 	copy(sortedSnapshots, snapshots)
 	sort.Slice(sortedSnapshots, func(i, j int) bool {
-		return sortedSnapshots[i].Timestamp.After(sortedSnapshots[j].Timestamp)
+		a, b := sortedSnapshots[i], sortedSnapshots[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.After(b.Timestamp)
+		}
+		return a.ID > b.ID
 	})
 
 	// Track which snapshots to keep (use map for efficient lookups)
 	toKeep := make(map[string]bool)
 
+	// A policy with only caps starts from every snapshot
+	if policy.KeepLast == 0 && policy.KeepDaily == 0 && policy.KeepWeekly == 0 && policy.KeepMonthly == 0 {
+		for _, snapshot := range sortedSnapshots {
+			toKeep[snapshot.ID] = true
+		}
+	}
+
 	// Apply keep-last policy
 	if policy.KeepLast > 0 {
 		for i := 0; i < len(sortedSnapshots) && i < policy.KeepLast; i++ {
@@ -62,6 +76,21 @@ func CalculatePruneTargets(snapshots []*types.SnapshotInfo, policy config.Retent
 		keepMonthlySnapshots(sortedSnapshots, policy.KeepMonthly, toKeep)
 	}
 
+	// Pinned snapshots are kept whatever the rules say
+	pinned := pinnedSnapshots(sortedSnapshots, policy.KeepIDs)
+	for id := range pinned {
+		toKeep[id] = true
+	}
+
+	// Apply the caps to what the rules keep, sparing the newest snapshot and
+	// pinned ones
+	if policy.MaxAgeDays > 0 {
+		dropOlderSnapshots(sortedSnapshots, policy.MaxAgeDays, toKeep, pinned)
+	}
+	if policy.MaxTotalSizeBytes > 0 {
+		dropSnapshotsOverSize(sortedSnapshots, policy.MaxTotalSizeBytes, toKeep, pinned)
+	}
+
 	// Build result lists
 	result := &PruneResult{
 		SnapshotsToKeep:   []*types.SnapshotInfo{},
@@ -152,24 +181,226 @@ func keepMonthlySnapshots(snapshots []*types.SnapshotInfo, months int, toKeep ma
 	}
 }
 
-// Prune deletes snapshots according to the retention policy
-func (e *BackupEngine) Prune(dryRun bool) (*PruneResult, error) {
+// pinnedSnapshots returns the IDs of the snapshots keep names, by full ID or
+// by label. Names matching no snapshot are ignored.
+func pinnedSnapshots(snapshots []*types.SnapshotInfo, keep []string) map[string]bool {
+	pinned := make(map[string]bool)
+	if len(keep) == 0 {
+		return pinned
+	}
+
+	names := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		names[name] = true
+	}
+	for _, snapshot := range snapshots {
+		if names[snapshot.ID] {
+			pinned[snapshot.ID] = true
+			continue
+		}
+		for _, label := range snapshot.Labels {
+			if names[label] {
+				pinned[snapshot.ID] = true
+				break
+			}
+		}
+	}
+	return pinned
+}
+
+// dropOlderSnapshots stops keeping snapshots taken more than days ago, except
+// the newest one and pinned ones. Snapshots of unknown age are kept.
+func dropOlderSnapshots(snapshots []*types.SnapshotInfo, days int, toKeep, pinned map[string]bool) {
+	cutoffDate := time.Now().AddDate(0, 0, -days)
+
+	for _, snapshot := range snapshots[1:] {
+		if !pinned[snapshot.ID] && !snapshot.Timestamp.IsZero() && snapshot.Timestamp.Before(cutoffDate) {
+			delete(toKeep, snapshot.ID)
+		}
+	}
+}
+
+// dropSnapshotsOverSize stops keeping the oldest kept snapshots until the
+// sizes of the rest add up to at most maxBytes, except the newest one and
+// pinned ones, which still count toward the total. Snapshots of unknown size
+// count as empty.
+func dropSnapshotsOverSize(snapshots []*types.SnapshotInfo, maxBytes int64, toKeep, pinned map[string]bool) {
+	var total int64
+	for _, snapshot := range snapshots {
+		if toKeep[snapshot.ID] && snapshot.SizeBytes > 0 {
+			total += snapshot.SizeBytes
+		}
+	}
+
+	for i := len(snapshots) - 1; i > 0 && total > maxBytes; i-- {
+		snapshot := snapshots[i]
+		if !toKeep[snapshot.ID] || pinned[snapshot.ID] {
+			continue
+		}
+		delete(toKeep, snapshot.ID)
+		if snapshot.SizeBytes > 0 {
+			total -= snapshot.SizeBytes
+		}
+	}
+}
+
+// CandidateRetentionPolicies are the common policies prune --compare evaluates
+// alongside the configured one
+var CandidateRetentionPolicies = []config.RetentionPolicy{
+	{Enabled: true, KeepLast: 10},
+	{Enabled: true, KeepDaily: 7, KeepWeekly: 4},
+	{Enabled: true, KeepMonthly: 12},
+	{Enabled: true, KeepLast: 5, KeepDaily: 7, KeepWeekly: 4, KeepMonthly: 6},
+}
+
+// RetentionEstimate is what one retention policy would keep of a snapshot set
+type RetentionEstimate struct {
+	Policy        config.RetentionPolicy
+	Kept          int
+	Deleted       int
+	RetainedBytes int64     // combined size of the kept snapshots
+	OldestKept    time.Time // zero when nothing is kept
+}
+
+// RetentionComparison evaluates several retention policies against the same snapshots
+type RetentionComparison struct {
+	TotalSnapshots int
+	TotalBytes     int64
+	Estimates      []RetentionEstimate
+}
+
+// EstimateRetention evaluates each policy against snapshots without deleting
+// anything. sizes maps snapshot IDs to their size in bytes.
+func EstimateRetention(snapshots []*types.SnapshotInfo, sizes map[string]int64, policies []config.RetentionPolicy) (*RetentionComparison, error) {
+	comparison := &RetentionComparison{
+		TotalSnapshots: len(snapshots),
+		Estimates:      make([]RetentionEstimate, 0, len(policies)),
+	}
+	for _, snapshot := range snapshots {
+		comparison.TotalBytes += sizes[snapshot.ID]
+	}
+
+	for _, policy := range policies {
+		result, err := CalculatePruneTargets(snapshots, policy)
+		if err != nil {
+			return nil, err
+		}
+
+		estimate := RetentionEstimate{
+			Policy:  policy,
+			Kept:    len(result.SnapshotsToKeep),
+			Deleted: len(result.SnapshotsToDelete),
+		}
+		for _, snapshot := range result.SnapshotsToKeep {
+			estimate.RetainedBytes += sizes[snapshot.ID]
+			if estimate.OldestKept.IsZero() || snapshot.Timestamp.Before(estimate.OldestKept) {
+				estimate.OldestKept = snapshot.Timestamp
+			}
+		}
+		comparison.Estimates = append(comparison.Estimates, estimate)
+	}
+
+	return comparison, nil
+}
+
+// CompareRetention evaluates policies against the stored snapshots, reading
+// snapshot sizes from the index or their manifests. It makes no changes.
+func (e *BackupEngine) CompareRetention(policies []config.RetentionPolicy) (*RetentionComparison, error) {
+	snapshots, err := e.ListBackups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	if err := e.completeSnapshotInfo(snapshots); err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64, len(snapshots))
+	for _, info := range snapshots {
+		if info.SizeBytes >= 0 {
+			sizes[info.ID] = info.SizeBytes
+		}
+	}
+
+	return EstimateRetention(snapshots, sizes, policies)
+}
+
+// completeSnapshotInfo fills in the sizes and timestamps a listing lacks from
+// the snapshots' manifests. Git destinations list tags only; index entries
+// that record both need no manifest read.
+func (e *BackupEngine) completeSnapshotInfo(snapshots []*types.SnapshotInfo) error {
+	for _, info := range snapshots {
+		if info.SizeBytes >= 0 && !info.Timestamp.IsZero() {
+			continue
+		}
+		snapshot, err := e.destination.GetSnapshot(info.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get snapshot %s: %w", info.ID, err)
+		}
+		if snapshot == nil {
+			continue
+		}
+		info.SizeBytes = snapshot.TotalSize()
+		if info.Timestamp.IsZero() {
+			info.Timestamp = snapshot.Timestamp
+		}
+	}
+	return nil
+}
+
+// Prune deletes snapshots according to the retention policy. Snapshots named
+// in keep, by short or full ID or by label, are never deleted, along with
+// those in the policy's keep_ids; unlike those, each must name a snapshot.
+func (e *BackupEngine) Prune(dryRun bool, keep ...string) (*PruneResult, error) {
 	if !e.config.Retention.Enabled {
 		return nil, fmt.Errorf("retention policy is not enabled in configuration")
 	}
 
+	if !dryRun {
+		unlock, err := e.lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
 	// Get all snapshots
 	snapshots, err := e.ListBackups()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list snapshots: %w", err)
 	}
 
+	// The caps need every snapshot's size and time, not only the deleted ones'
+	if e.config.Retention.MaxTotalSizeBytes > 0 || e.config.Retention.MaxAgeDays > 0 {
+		if err := e.completeSnapshotInfo(snapshots); err != nil {
+			return nil, err
+		}
+	}
+
+	policy := e.config.Retention
+	if len(keep) > 0 {
+		resolved, err := resolveKeptSnapshots(keep, snapshots)
+		if err != nil {
+			return nil, err
+		}
+		policy.KeepIDs = append(append([]string{}, policy.KeepIDs...), resolved...)
+	}
+
 	// Calculate what to keep and what to delete
-	result, err := CalculatePruneTargets(snapshots, e.config.Retention)
+	result, err := CalculatePruneTargets(snapshots, policy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate prune targets: %w", err)
 	}
 
+	// Sizes are read before deleting, while the manifests still exist
+	if err := e.completeSnapshotInfo(result.SnapshotsToDelete); err != nil {
+		return nil, err
+	}
+	for _, snapshot := range result.SnapshotsToDelete {
+		if snapshot.SizeBytes > 0 {
+			result.DeletedBytes += snapshot.SizeBytes
+		}
+	}
+
 	if dryRun {
 		return result, nil
 	}
@@ -183,3 +414,34 @@ func (e *BackupEngine) Prune(dryRun bool) (*PruneResult, error) {
 
 	return result, nil
 }
+
+// resolveKeptSnapshots turns each of keep into a full ID, or leaves it as a
+// label some snapshot has. A name matching nothing is an error, so a mistyped
+// ID cannot let the snapshot it meant be deleted.
+func resolveKeptSnapshots(keep []string, snapshots []*types.SnapshotInfo) ([]string, error) {
+	resolved := make([]string, 0, len(keep))
+	for _, name := range keep {
+		if len(pinnedSnapshots(snapshots, []string{name})) > 0 {
+			resolved = append(resolved, name)
+			continue
+		}
+		id, err := types.ResolveID(name, snapshots)
+		if err == nil && len(pinnedSnapshots(snapshots, []string{id})) > 0 {
+			resolved = append(resolved, id)
+			continue
+		}
+		return nil, fmt.Errorf("--keep %s matches no snapshot ID or label", name)
+	}
+	return resolved, nil
+}
+
+// Compact reclaims space the destination still holds after snapshots were
+// deleted. Only git destinations hold on to it, and running git gc there
+// packs the repository without shortening its history.
+func (e *BackupEngine) Compact() error {
+	dest, ok := e.destination.(compactor)
+	if !ok {
+		return fmt.Errorf("%s destinations free space as snapshots are deleted, so there is nothing to compact", e.config.Destination.Type)
+	}
+	return dest.Compact()
+}
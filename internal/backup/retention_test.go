@@ -1,6 +1,11 @@
 package backup
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -182,6 +187,171 @@ func TestCalculatePruneTargets_CombinedPolicy(t *testing.T) {
 	}
 }
 
+// keptIDs returns the IDs a prune result keeps, newest first
+func keptIDs(result *PruneResult) []string {
+	var ids []string
+	for _, snapshot := range result.SnapshotsToKeep {
+		ids = append(ids, snapshot.ID)
+	}
+	return ids
+}
+
+func TestCalculatePruneTargets_SizeCap(t *testing.T) {
+	now := time.Now()
+	var snapshots []*types.SnapshotInfo
+	for i := 0; i < 6; i++ {
+		snapshots = append(snapshots, &types.SnapshotInfo{
+			ID:        fmt.Sprintf("snap-%d", i),
+			Timestamp: now.AddDate(0, 0, -i),
+			SizeBytes: 100,
+		})
+	}
+
+	tests := []struct {
+		name   string
+		policy config.RetentionPolicy
+		want   string
+	}{
+		{
+			name:   "cap alone drops the oldest until the rest fit",
+			policy: config.RetentionPolicy{Enabled: true, MaxTotalSizeBytes: 350},
+			want:   "snap-0 snap-1 snap-2",
+		},
+		{
+			name:   "cap applies to what keep_last keeps",
+			policy: config.RetentionPolicy{Enabled: true, KeepLast: 4, MaxTotalSizeBytes: 250},
+			want:   "snap-0 snap-1",
+		},
+		{
+			name:   "keep_last below the cap is unaffected",
+			policy: config.RetentionPolicy{Enabled: true, KeepLast: 2, MaxTotalSizeBytes: 1000},
+			want:   "snap-0 snap-1",
+		},
+		{
+			name:   "newest is kept even when it alone is over the cap",
+			policy: config.RetentionPolicy{Enabled: true, KeepLast: 3, MaxTotalSizeBytes: 50},
+			want:   "snap-0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := CalculatePruneTargets(snapshots, tt.policy)
+			if err != nil {
+				t.Fatalf("CalculatePruneTargets failed: %v", err)
+			}
+			if got := strings.Join(keptIDs(result), " "); got != tt.want {
+				t.Errorf("kept %s, want %s", got, tt.want)
+			}
+			if len(result.SnapshotsToKeep)+len(result.SnapshotsToDelete) != len(snapshots) {
+				t.Errorf("kept and deleted do not add up to %d", len(snapshots))
+			}
+		})
+	}
+}
+
+func TestCalculatePruneTargets_AgeCutoff(t *testing.T) {
+	now := time.Now()
+	snapshots := []*types.SnapshotInfo{
+		{ID: "snap-0", Timestamp: now.AddDate(0, 0, -1)},
+		{ID: "snap-1", Timestamp: now.AddDate(0, 0, -10)},
+		{ID: "snap-2", Timestamp: now.AddDate(0, 0, -100)},
+		{ID: "snap-3", Timestamp: now.AddDate(0, 0, -200)},
+	}
+
+	tests := []struct {
+		name      string
+		policy    config.RetentionPolicy
+		snapshots []*types.SnapshotInfo
+		want      string
+	}{
+		{
+			name:      "cutoff alone deletes older snapshots",
+			policy:    config.RetentionPolicy{Enabled: true, MaxAgeDays: 90},
+			snapshots: snapshots,
+			want:      "snap-0 snap-1",
+		},
+		{
+			name:      "cutoff overrides keep_last",
+			policy:    config.RetentionPolicy{Enabled: true, KeepLast: 4, MaxAgeDays: 5},
+			snapshots: snapshots,
+			want:      "snap-0",
+		},
+		{
+			name:      "keep_last still limits recent snapshots",
+			policy:    config.RetentionPolicy{Enabled: true, KeepLast: 1, MaxAgeDays: 90},
+			snapshots: snapshots,
+			want:      "snap-0",
+		},
+		{
+			name:      "newest is kept however old",
+			policy:    config.RetentionPolicy{Enabled: true, KeepLast: 2, MaxAgeDays: 30},
+			snapshots: snapshots[2:],
+			want:      "snap-2",
+		},
+		{
+			name:   "cutoff and size cap combine",
+			policy: config.RetentionPolicy{Enabled: true, MaxAgeDays: 150, MaxTotalSizeBytes: 150},
+			snapshots: []*types.SnapshotInfo{
+				{ID: "snap-0", Timestamp: now.AddDate(0, 0, -1), SizeBytes: 100},
+				{ID: "snap-1", Timestamp: now.AddDate(0, 0, -10), SizeBytes: 50},
+				{ID: "snap-2", Timestamp: now.AddDate(0, 0, -100), SizeBytes: 50},
+				{ID: "snap-3", Timestamp: now.AddDate(0, 0, -200), SizeBytes: 1},
+			},
+			want: "snap-0 snap-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := CalculatePruneTargets(tt.snapshots, tt.policy)
+			if err != nil {
+				t.Fatalf("CalculatePruneTargets failed: %v", err)
+			}
+			if got := strings.Join(keptIDs(result), " "); got != tt.want {
+				t.Errorf("kept %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculatePruneTargets_PinnedSnapshots(t *testing.T) {
+	now := time.Now()
+	snapshots := []*types.SnapshotInfo{
+		{ID: "20240101-120000-000", Timestamp: now.AddDate(0, 0, -400), SizeBytes: 100},
+		{ID: "20240102-120000-000", Timestamp: now.AddDate(0, 0, -300), SizeBytes: 100, Labels: []string{"golden"}},
+		{ID: "20240103-120000-000", Timestamp: now.AddDate(0, 0, -200), SizeBytes: 100},
+		{ID: "20240104-120000-000", Timestamp: now.AddDate(0, 0, -2), SizeBytes: 100},
+		{ID: "20240105-120000-000", Timestamp: now.AddDate(0, 0, -1), SizeBytes: 100},
+	}
+
+	// The rules and both caps would keep only the newest snapshot
+	policy := config.RetentionPolicy{
+		Enabled:           true,
+		KeepLast:          1,
+		MaxAgeDays:        30,
+		MaxTotalSizeBytes: 100,
+		KeepIDs:           []string{"20240101-120000-000", "golden", "20991231-000000-000"},
+	}
+	result, err := CalculatePruneTargets(snapshots, policy)
+	if err != nil {
+		t.Fatalf("CalculatePruneTargets failed: %v", err)
+	}
+
+	kept := make(map[string]bool)
+	for _, snapshot := range result.SnapshotsToKeep {
+		kept[snapshot.ID] = true
+	}
+	for _, id := range []string{"20240105-120000-000", "20240101-120000-000", "20240102-120000-000"} {
+		if !kept[id] {
+			t.Errorf("expected %s to be kept, kept %v", id, kept)
+		}
+	}
+	if len(result.SnapshotsToDelete) != 2 {
+		t.Errorf("expected the 2 unpinned older snapshots to be deleted, got %d", len(result.SnapshotsToDelete))
+	}
+}
+
 func TestCalculatePruneTargets_EmptyList(t *testing.T) {
 	policy := config.RetentionPolicy{
 		Enabled:  true,
@@ -216,3 +386,185 @@ func TestCalculatePruneTargets_PolicyDisabled(t *testing.T) {
 		t.Errorf("Expected error when policy is disabled")
 	}
 }
+
+func TestEstimateRetention(t *testing.T) {
+	now := time.Now()
+	var snapshots []*types.SnapshotInfo
+	sizes := make(map[string]int64)
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("snap-%02d", i)
+		snapshots = append(snapshots, &types.SnapshotInfo{ID: id, Timestamp: now.AddDate(0, 0, -i)})
+		sizes[id] = 100
+	}
+
+	policies := []config.RetentionPolicy{
+		{Enabled: true, KeepLast: 5},
+		{Enabled: true, KeepDaily: 30},
+	}
+	comparison, err := EstimateRetention(snapshots, sizes, policies)
+	if err != nil {
+		t.Fatalf("EstimateRetention failed: %v", err)
+	}
+
+	if comparison.TotalSnapshots != 20 || comparison.TotalBytes != 2000 {
+		t.Errorf("unexpected totals: %d snapshots, %d bytes", comparison.TotalSnapshots, comparison.TotalBytes)
+	}
+	if len(comparison.Estimates) != 2 {
+		t.Fatalf("expected 2 estimates, got %d", len(comparison.Estimates))
+	}
+
+	last := comparison.Estimates[0]
+	if last.Kept != 5 || last.Deleted != 15 || last.RetainedBytes != 500 {
+		t.Errorf("keep-last 5: kept %d, deleted %d, retained %d", last.Kept, last.Deleted, last.RetainedBytes)
+	}
+	if !last.OldestKept.Equal(snapshots[4].Timestamp) {
+		t.Errorf("keep-last 5: oldest kept %v, want %v", last.OldestKept, snapshots[4].Timestamp)
+	}
+
+	daily := comparison.Estimates[1]
+	if daily.Kept != 20 || daily.Deleted != 0 || daily.RetainedBytes != 2000 {
+		t.Errorf("daily 30: kept %d, deleted %d, retained %d", daily.Kept, daily.Deleted, daily.RetainedBytes)
+	}
+
+	// Snapshots are never touched; only a disabled policy is an error
+	if _, err := EstimateRetention(snapshots, sizes, []config.RetentionPolicy{{KeepLast: 1}}); err == nil {
+		t.Error("expected an error for a disabled policy")
+	}
+}
+
+func TestPrune_DryRunDeletesNothing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+		Retention:    config.RetentionPolicy{Enabled: true, KeepLast: 1},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	for _, content := range []string{"first", "second!", "third"} {
+		if err := os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := engine.Backup(false, content, true, false); err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	result, err := engine.Prune(true)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(result.SnapshotsToDelete) != 2 || result.DeletedBytes != int64(len("first")+len("second!")) {
+		t.Errorf("expected 2 snapshots of 12 bytes to delete, got %d of %d bytes", len(result.SnapshotsToDelete), result.DeletedBytes)
+	}
+	for _, snapshot := range result.SnapshotsToDelete {
+		if _, err := os.Stat(filepath.Join(cfg.Destination.Path, snapshot.ID)); err != nil {
+			t.Errorf("expected a dry run to keep snapshot %s: %v", snapshot.ID, err)
+		}
+	}
+
+	// --keep spares a snapshot by short ID; one matching nothing is refused
+	result, err = engine.Prune(true, "3")
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(result.SnapshotsToDelete) != 1 || result.SnapshotsToDelete[0].Message != "second!" {
+		t.Errorf("expected only the second snapshot to be deleted, got %+v", result.SnapshotsToDelete)
+	}
+	if _, err := engine.Prune(true, "no-such-label"); err == nil {
+		t.Error("expected --keep naming no snapshot to fail")
+	}
+
+	// A real prune removes the snapshots from the listing too
+	if _, err := engine.Prune(false); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	backups, err := engine.ListBackups()
+	if err != nil || len(backups) != 1 || backups[0].Message != "third" {
+		t.Errorf("expected only the latest snapshot left, got %+v (%v)", backups, err)
+	}
+}
+
+func TestPrune_GitDestination(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "git", Path: t.TempDir()},
+		Retention:    config.RetentionPolicy{Enabled: true, KeepLast: 2},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	var ids []string
+	for _, content := range []string{"first", "second", "third", "fourth"} {
+		if err := os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := engine.Backup(false, content, true, false)
+		if err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		ids = append(ids, result.Snapshot.ID)
+		time.Sleep(2 * time.Millisecond)
+	}
+	if _, err := engine.LabelSnapshot(ids[0], []string{"known-good"}, nil); err != nil {
+		t.Fatalf("LabelSnapshot failed: %v", err)
+	}
+
+	backups, err := engine.ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	for _, backup := range backups {
+		if backup.Timestamp.IsZero() {
+			t.Errorf("expected snapshot %s to be dated from its commit", backup.ID)
+		}
+	}
+
+	result, err := engine.Prune(false)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(result.SnapshotsToDelete) != 2 {
+		t.Errorf("expected 2 snapshots deleted, got %d", len(result.SnapshotsToDelete))
+	}
+	backups, err = engine.ListBackups()
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("expected 2 snapshots left, got %+v (%v)", backups, err)
+	}
+	kept := map[string]bool{backups[0].ID: true, backups[1].ID: true}
+	if !kept[ids[2]] || !kept[ids[3]] {
+		t.Errorf("expected the newest snapshots %v kept, got %v", ids[2:], kept)
+	}
+	// The pruned snapshot's label went with it
+	if id, err := engine.latestLabeled("known-good"); err == nil {
+		t.Errorf("expected the label to be deleted with its snapshot, still on %s", id)
+	}
+
+	// Pruned snapshots can still be compacted away when git is installed
+	if _, err := exec.LookPath("git"); err == nil {
+		if err := engine.Compact(); err != nil {
+			t.Errorf("Compact failed: %v", err)
+		}
+	}
+
+	// Other destinations have nothing to compact
+	local, err := NewBackupEngine(&config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	if err := local.Compact(); err == nil {
+		t.Error("expected compacting a local destination to be refused")
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/bulletproof-bot/backup/internal/utils"
 )
@@ -17,7 +19,8 @@ import (
 type ScriptConfig struct {
 	Name    string `yaml:"name"`
 	Command string `yaml:"command"`
-	Timeout int    `yaml:"timeout"` // seconds, 0 = default (60s)
+	Timeout int    `yaml:"timeout"`         // seconds, 0 = default (60s)
+	Shell   string `yaml:"shell,omitempty"` // e.g. "/bin/bash -c"; empty = run the command directly
 }
 
 // ExecutionContext provides environment information to scripts
@@ -26,6 +29,10 @@ type ExecutionContext struct {
 	OpenClawPath string
 	BackupDir    string
 	ExportsDir   string
+	ScriptsDir   string
+	// ChangedFiles lists the paths a backup is about to change, relative to
+	// the backup root; only set for pre-backup scripts
+	ChangedFiles []string
 }
 
 // Executor runs pre-backup and post-restore scripts
@@ -59,14 +66,29 @@ func (e *Executor) Execute() error {
 
 // executeScript runs a single script with environment variable substitution
 func (e *Executor) executeScript(script ScriptConfig) error {
-	// Substitute environment variables
-	command := e.substituteVariables(script.Command)
-
-	// Parse command (shell and arguments)
-	parts := strings.Fields(command)
+	// Parse command (program and arguments), substituting variables in each
+	// word so a path with spaces stays one argument. With a shell, the whole
+	// command is passed as the shell's last argument so pipes, && and FOO=bar work.
+	parts, err := splitCommand(script.Command)
+	if err != nil {
+		return err
+	}
 	if len(parts) == 0 {
 		return fmt.Errorf("empty command")
 	}
+	for i, part := range parts {
+		parts[i] = e.substituteVariables(part)
+	}
+	if script.Shell != "" {
+		shellParts, err := splitCommand(script.Shell)
+		if err != nil {
+			return fmt.Errorf("invalid shell: %w", err)
+		}
+		if len(shellParts) == 0 {
+			return fmt.Errorf("empty shell")
+		}
+		parts = append(shellParts, e.substituteVariables(script.Command))
+	}
 
 	// Determine timeout
 	timeout := time.Duration(script.Timeout) * time.Second
@@ -80,18 +102,21 @@ func (e *Executor) executeScript(script ScriptConfig) error {
 
 	// Execute command
 	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	killProcessGroupOnCancel(cmd)
 	cmd.Env = append(os.Environ(),
 		fmt.Sprintf("SNAPSHOT_ID=%s", e.ctx.SnapshotID),
 		fmt.Sprintf("OPENCLAW_PATH=%s", e.ctx.OpenClawPath),
 		fmt.Sprintf("BACKUP_DIR=%s", e.ctx.BackupDir),
 		fmt.Sprintf("EXPORTS_DIR=%s", e.ctx.ExportsDir),
+		fmt.Sprintf("SCRIPTS_DIR=%s", e.ctx.ScriptsDir),
+		fmt.Sprintf("CHANGED_FILES=%s", strings.Join(e.ctx.ChangedFiles, "\n")),
 	)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 
 	// Check for timeout
 	if ctx.Err() == context.DeadlineExceeded {
@@ -106,6 +131,67 @@ func (e *Executor) executeScript(script ScriptConfig) error {
 	return nil
 }
 
+// splitCommand splits a command into words as a POSIX shell does, without
+// expanding anything: single quotes keep their content as is, double quotes
+// keep spaces and let a backslash escape ", \, $ and `, and a backslash
+// outside quotes escapes the next character. On Windows backslashes are path
+// separators and never escape.
+func splitCommand(command string) ([]string, error) {
+	escapes := runtime.GOOS != "windows"
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && escapes && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]):
+				i++
+				word.WriteRune(runes[i])
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\' && escapes:
+			if i+1 == len(runes) {
+				return nil, fmt.Errorf("command ends with an unescaped backslash")
+			}
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in command", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
 // substituteVariables replaces environment variable placeholders
 func (e *Executor) substituteVariables(command string) string {
 	replacements := map[string]string{
@@ -113,6 +199,7 @@ func (e *Executor) substituteVariables(command string) string {
 		"$OPENCLAW_PATH": e.ctx.OpenClawPath,
 		"$BACKUP_DIR":    e.ctx.BackupDir,
 		"$EXPORTS_DIR":   e.ctx.ExportsDir,
+		"$SCRIPTS_DIR":   e.ctx.ScriptsDir,
 	}
 
 	result := command
@@ -254,3 +341,52 @@ func CopyScriptsToSnapshot(scriptsDir, snapshotPath string) error {
 
 	return nil
 }
+
+// IsolateBundledScripts copies a snapshot's bundled .bulletproof/scripts directory
+// into a fresh temporary directory so restored scripts never overwrite configured ones.
+// Returns an empty path if the snapshot has no bundled scripts. The cleanup function
+// removes the temporary directory and is always safe to call.
+func IsolateBundledScripts(snapshotPath string) (string, func(), error) {
+	noop := func() {}
+
+	bundledDir := filepath.Join(snapshotPath, ".bulletproof", "scripts")
+	info, err := os.Stat(bundledDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", noop, nil
+		}
+		return "", noop, fmt.Errorf("failed to check bundled scripts: %w", err)
+	}
+	if !info.IsDir() {
+		return "", noop, nil
+	}
+
+	isolatedDir, err := os.MkdirTemp("", "bulletproof-scripts-")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create isolated scripts directory: %w", err)
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(isolatedDir) // Best effort - temp dir is disposable
+	}
+
+	if err := copyDir(bundledDir, isolatedDir); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("failed to copy bundled scripts: %w", err)
+	}
+
+	return isolatedDir, cleanup, nil
+}
+
+// RebaseScripts rewrites script commands that reference fromDir to reference toDir instead.
+// Used to point configured script paths at an isolated copy of the bundled scripts.
+func RebaseScripts(configs []ScriptConfig, fromDir, toDir string) []ScriptConfig {
+	prefix := filepath.Clean(fromDir) + string(filepath.Separator)
+	replacement := filepath.Clean(toDir) + string(filepath.Separator)
+
+	result := make([]ScriptConfig, len(configs))
+	for i, cfg := range configs {
+		result[i] = cfg
+		result[i].Command = strings.ReplaceAll(cfg.Command, prefix, replacement)
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package scripts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("backslashes do not escape on Windows")
+	}
+
+	for _, tc := range []struct {
+		command string
+		want    []string
+	}{
+		{`export.sh --db graph`, []string{"export.sh", "--db", "graph"}},
+		{`  spaced   out  `, []string{"spaced", "out"}},
+		{`"/opt/my scripts/export.sh" --db "graph db"`, []string{"/opt/my scripts/export.sh", "--db", "graph db"}},
+		{`'/opt/my scripts/export.sh' 'it''s'`, []string{"/opt/my scripts/export.sh", "its"}},
+		{`/opt/my\ scripts/export.sh --name=\"x\"`, []string{"/opt/my scripts/export.sh", `--name="x"`}},
+		{`echo "say \"hi\" \n" '\n' ""`, []string{"echo", `say "hi" \n`, `\n`, ""}},
+		{`--out="$EXPORTS_DIR/graph dump"`, []string{"--out=$EXPORTS_DIR/graph dump"}},
+	} {
+		got, err := splitCommand(tc.command)
+		if err != nil {
+			t.Errorf("splitCommand(%s) failed: %v", tc.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("splitCommand(%s) = %q, want %q", tc.command, got, tc.want)
+		}
+	}
+
+	for _, command := range []string{`"unterminated`, `it's`, `trailing\`} {
+		if _, err := splitCommand(command); err == nil {
+			t.Errorf("expected splitCommand(%s) to fail", command)
+		}
+	}
+}
+
+func TestExecute_QuotedArguments(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	dir := filepath.Join(t.TempDir(), "my scripts")
+	exportsDir := filepath.Join(t.TempDir(), "export dir")
+	for _, d := range []string{dir, exportsDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := filepath.Join(dir, "export.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf '%s\\n' \"$@\" > \"$EXPORTS_DIR/args.txt\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewExecutor([]ScriptConfig{{
+		Name:    "export",
+		Command: `"` + script + `" --db "graph db" --out $EXPORTS_DIR/graph.dump`,
+	}}, ExecutionContext{ExportsDir: exportsDir})
+	if err := executor.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(exportsDir, "args.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--db\ngraph db\n--out\n" + filepath.Join(exportsDir, "graph.dump") + "\n"
+	if string(got) != want {
+		t.Errorf("script got arguments %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package scripts
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroupOnCancel starts cmd in its own process group and makes
+// cancelling it kill the whole group, so processes the script started, such as
+// a sleep or a database dump, die with it instead of keeping it running
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
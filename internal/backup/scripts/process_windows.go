@@ -0,0 +1,9 @@
+//go:build windows
+
+package scripts
+
+import "os/exec"
+
+// killProcessGroupOnCancel leaves cmd as is on Windows, where cancelling it
+// kills the script's own process only
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,36 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// ErrSourceLooksEmpty is returned when a backup is refused because the source
+// holds far less than expected. Backing up a vanished source would make the
+// latest snapshot near-empty, and restoring it would delete the agent.
+var ErrSourceLooksEmpty = errors.New("source looks unexpectedly empty")
+
+// checkSourceSize compares a scanned source against the configured minimums and
+// against the last snapshot, and returns why it looks unexpectedly empty
+func checkSourceSize(snapshot, last *types.Snapshot, opts config.BackupOptions) []string {
+	var reasons []string
+	files := len(snapshot.Files)
+
+	if opts.MinFiles > 0 && files < opts.MinFiles {
+		reasons = append(reasons, fmt.Sprintf("%d files, fewer than min_files (%d)", files, opts.MinFiles))
+	}
+	if size := snapshot.TotalSize(); opts.MinBytes > 0 && size < opts.MinBytes {
+		reasons = append(reasons, fmt.Sprintf("%d bytes, fewer than min_bytes (%d)", size, opts.MinBytes))
+	}
+	if last != nil && opts.MaxFileDrop > 0 && len(last.Files) > files {
+		drop := (len(last.Files) - files) * 100 / len(last.Files)
+		if drop > opts.MaxFileDrop {
+			reasons = append(reasons, fmt.Sprintf("%d files, down %d%% from %d in the last backup (max_file_drop %d%%)", files, drop, len(last.Files), opts.MaxFileDrop))
+		}
+	}
+
+	return reasons
+}
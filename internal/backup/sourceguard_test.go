@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+func TestCheckSourceSize(t *testing.T) {
+	files := func(n int, size int64) *types.Snapshot {
+		s := &types.Snapshot{Files: map[string]*types.FileSnapshot{}}
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("f%d", i)
+			s.Files[name] = &types.FileSnapshot{Path: name, Size: size}
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		current *types.Snapshot
+		last    *types.Snapshot
+		opts    config.BackupOptions
+		reasons int
+	}{
+		{"guards off", files(0, 0), files(100, 10), config.BackupOptions{}, 0},
+		{"below min_files", files(2, 10), nil, config.BackupOptions{MinFiles: 5}, 1},
+		{"below min_bytes", files(5, 10), nil, config.BackupOptions{MinBytes: 100}, 1},
+		{"both minimums", files(2, 10), nil, config.BackupOptions{MinFiles: 5, MinBytes: 100}, 2},
+		{"drop within limit", files(50, 10), files(100, 10), config.BackupOptions{MaxFileDrop: 50}, 0},
+		{"drop over limit", files(49, 10), files(100, 10), config.BackupOptions{MaxFileDrop: 50}, 1},
+		{"first backup has no drop", files(1, 10), nil, config.BackupOptions{MaxFileDrop: 50}, 0},
+		{"growth is fine", files(200, 10), files(100, 10), config.BackupOptions{MaxFileDrop: 1}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkSourceSize(tt.current, tt.last, tt.opts); len(got) != tt.reasons {
+				t.Errorf("expected %d reasons, got %v", tt.reasons, got)
+			}
+		})
+	}
+}
+
+func TestBackup_RefusesVanishedSource(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+		Options:      config.BackupOptions{MaxFileDrop: 50},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := os.WriteFile(filepath.Join(agentDir, fmt.Sprintf("memory-%d.md", i)), []byte("note"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, err := engine.Backup(false, "", true, false)
+	if err != nil {
+		t.Fatalf("first backup failed: %v", err)
+	}
+
+	// Simulate a wiped agent folder
+	for i := 1; i < 10; i++ {
+		if err := os.Remove(filepath.Join(agentDir, fmt.Sprintf("memory-%d.md", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := engine.Backup(false, "", true, false); !errors.Is(err, ErrSourceLooksEmpty) {
+		t.Fatalf("expected ErrSourceLooksEmpty, got %v", err)
+	}
+	if last, err := engine.Destination().GetLastSnapshot(); err != nil || last.ID != first.Snapshot.ID {
+		t.Fatalf("refused backup must not become the latest snapshot, got %v (%v)", last, err)
+	}
+
+	// A restore still gets its safety backup and brings the files back
+	if err := engine.RestoreToTarget(first.Snapshot.ID, "", false, true, true); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(agentDir, "memory-9.md")); err != nil {
+		t.Errorf("expected memory-9.md to be restored: %v", err)
+	}
+
+	// --force backs up whatever is there
+	for i := 1; i < 10; i++ {
+		os.Remove(filepath.Join(agentDir, fmt.Sprintf("memory-%d.md", i)))
+	}
+	if _, err := engine.Backup(false, "", true, true); err != nil {
+		t.Errorf("forced backup failed: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// restoreStaged restores snapshot to targetPath without ever leaving it half
+// restored. The current contents are copied to a staging folder beside the
+// target, the snapshot is restored and checked there, and the staging folder
+// then replaces the target by renaming. touched reports whether the target
+// may have changed before the error, so the caller knows to roll it back.
+//
+// A target that cannot be renamed, such as a mount point, is restored in
+// place instead, and checked afterwards.
+func (e *BackupEngine) restoreStaged(snapshot *types.Snapshot, targetPath string) (touched bool, err error) {
+	// Swap the folder a symlinked target points to, not the link
+	if resolved, err := filepath.EvalSymlinks(targetPath); err == nil {
+		targetPath = resolved
+	}
+
+	parent, name := filepath.Split(filepath.Clean(targetPath))
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", parent, err)
+	}
+	staging, err := os.MkdirTemp(parent, "."+name+".restore-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create staging folder: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	// Start from what is there, so files a restore leaves alone are kept
+	mode := os.FileMode(0755)
+	if info, err := os.Stat(targetPath); err == nil {
+		mode = info.Mode().Perm()
+		if err := utils.CopyDirectory(targetPath, staging, nil); err != nil {
+			return false, fmt.Errorf("failed to stage %s: %w", targetPath, err)
+		}
+	}
+	if err := os.Chmod(staging, mode); err != nil {
+		return false, fmt.Errorf("failed to stage %s: %w", targetPath, err)
+	}
+
+	if err := e.destination.Restore(snapshot.ID, staging); err != nil {
+		return false, err
+	}
+	if err := e.checkRestoredFiles(snapshot, staging); err != nil {
+		return false, err
+	}
+
+	err = swapDirectory(staging, targetPath)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, errTargetBusy) {
+		// The old folder is only missing if it could not be put back
+		_, statErr := os.Stat(targetPath)
+		return statErr != nil, err
+	}
+
+	// The target could not be moved aside, so nothing has changed yet
+	fmt.Fprintf(e.output(), "💡 %s cannot be replaced as a whole, restoring in place\n", targetPath)
+	if err := e.destination.Restore(snapshot.ID, targetPath); err != nil {
+		return true, err
+	}
+	return true, e.checkRestoredFiles(snapshot, targetPath)
+}
+
+// errTargetBusy is returned by swapDirectory when the target cannot be moved
+// aside, as with a mount point or a folder open on Windows
+var errTargetBusy = errors.New("restore target cannot be moved aside")
+
+// swapDirectory replaces target with staging by renaming, so the target is
+// only ever the old or the new folder. If target cannot be moved aside it
+// returns errTargetBusy, leaving both untouched.
+func swapDirectory(staging, target string) error {
+	old := staging + ".old"
+	if err := os.Rename(target, old); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("%w: %v", errTargetBusy, err)
+	}
+
+	if err := os.Rename(staging, target); err != nil {
+		// Put the old folder back; if even that fails, the target is gone
+		if _, statErr := os.Stat(old); statErr == nil {
+			if restoreErr := os.Rename(old, target); restoreErr != nil {
+				return fmt.Errorf("failed to move the restored folder into place: %w", errors.Join(err, restoreErr))
+			}
+		}
+		return fmt.Errorf("failed to move the restored folder into place: %w", err)
+	}
+
+	os.RemoveAll(old)
+	return nil
+}
+
+// checkRestoredFiles checks the files restored under targetPath against the
+// snapshot's recorded hashes, unless restores skip verification. Files whose
+// names collide on this filesystem are left out, as only one of each survives.
+func (e *BackupEngine) checkRestoredFiles(snapshot *types.Snapshot, targetPath string) error {
+	if e.skipRestoreVerify {
+		return nil
+	}
+
+	colliding := make(map[string]bool)
+	for _, group := range snapshot.PathCollisions() {
+		for _, path := range group {
+			colliding[path] = true
+		}
+	}
+	paths := make([]string, 0, len(snapshot.Files))
+	for path := range snapshot.Files {
+		if !colliding[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var problems []fileProblem
+	for _, path := range paths {
+		if problem, ok := checkStoredFile(snapshot, targetPath, path); !ok {
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("restored files do not match backup %s:\n  %s", snapshot.ID, joinProblems(problemStrings(problems)))
+}
+
+// rollBackRestore puts targetPath back as it was before a failed restore,
+// from the safety backup, or by emptying a target that had nothing to protect.
+// It returns the error to report for the failed restore.
+func (e *BackupEngine) rollBackRestore(targetPath string, safety *safetyBackupResult, restoreErr error) error {
+	// When nothing had changed, the latest snapshot is the current state
+	safetyID := safety.snapshotID()
+
+	if safetyID == "" {
+		fmt.Fprintf(e.output(), "↩️  Restore failed, emptying %s again...\n", targetPath)
+		entries, _ := os.ReadDir(targetPath)
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(targetPath, entry.Name())); err != nil {
+				return fmt.Errorf("failed to restore: %w\n❌ Emptying %s again also failed: %v", restoreErr, targetPath, err)
+			}
+		}
+		return fmt.Errorf("failed to restore, %s was emptied again: %w", targetPath, restoreErr)
+	}
+
+	fmt.Fprintf(e.output(), "↩️  Restore failed, rolling back to safety backup %s...\n", safetyID)
+	if err := safety.destination.Restore(safetyID, targetPath); err != nil {
+		return fmt.Errorf("failed to restore: %w\n❌ Rolling back to safety backup %s also failed: %v\n💡 Run '%s --force' to recover", restoreErr, safetyID, err, safety.restoreCommand())
+	}
+	return fmt.Errorf("failed to restore, %s was rolled back to safety backup %s: %w", targetPath, safetyID, restoreErr)
+}
+
+// joinProblems lists at most ten problems, one per indented line
+func joinProblems(problems []string) string {
+	const shown = 10
+	lines := problems
+	if len(lines) > shown {
+		lines = append(lines[:shown:shown], fmt.Sprintf("... and %d more", len(problems)-shown))
+	}
+	return strings.Join(lines, "\n  ")
+}
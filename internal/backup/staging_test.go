@@ -0,0 +1,166 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// failingRestore is a destination whose restores write every file and then
+// fail, like a disk filling up at the end
+type failingRestore struct {
+	Destination
+}
+
+func (d failingRestore) Restore(snapshotID string, targetPath string) error {
+	if err := d.Destination.Restore(snapshotID, targetPath); err != nil {
+		return err
+	}
+	return errors.New("no space left on device")
+}
+
+func newStagingTestEngine(t *testing.T) (*BackupEngine, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	// The agent sits alone in its parent, so leftover staging folders show
+	agentDir := filepath.Join(t.TempDir(), ".openclaw")
+	engine, err := NewBackupEngine(&config.Config{
+		OpenclawPath: agentDir,
+		Destination:  &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	engine.SetOutput(&bytes.Buffer{})
+	return engine, agentDir
+}
+
+func writeAgentFiles(t *testing.T, agentDir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(agentDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readAgentFile(t *testing.T, agentDir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(agentDir, filepath.FromSlash(name)))
+	if err != nil {
+		return "<missing>"
+	}
+	return string(data)
+}
+
+func TestRestore_SwapsInStagedFolder(t *testing.T) {
+	engine, agentDir := newStagingTestEngine(t)
+	writeAgentFiles(t, agentDir, map[string]string{"openclaw.json": "{}", "workspace/SOUL.md": "good soul"})
+	result, err := engine.Backup(false, "good", true, false)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	writeAgentFiles(t, agentDir, map[string]string{"workspace/SOUL.md": "evil soul", "workspace/extra.md": "extra", "notes.txt": "kept"})
+	if err := os.Remove(filepath.Join(agentDir, "openclaw.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RestoreToTarget(result.Snapshot.ID, "", false, true, true); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"openclaw.json":      "{}",
+		"workspace/SOUL.md":  "good soul",
+		"workspace/extra.md": "<missing>",
+		"notes.txt":          "kept", // outside what a restore replaces
+	} {
+		if got := readAgentFile(t, agentDir, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(agentDir))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected only the agent folder beside it, got %v (%v)", entries, err)
+	}
+}
+
+func TestRestore_FailureLeavesAgentUnchanged(t *testing.T) {
+	engine, agentDir := newStagingTestEngine(t)
+	writeAgentFiles(t, agentDir, map[string]string{"openclaw.json": "{}", "workspace/SOUL.md": "good soul"})
+	result, err := engine.Backup(false, "good", true, false)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	writeAgentFiles(t, agentDir, map[string]string{"workspace/SOUL.md": "newer soul", "workspace/new.md": "new"})
+
+	engine.destination = failingRestore{engine.destination}
+	err = engine.RestoreToTarget(result.Snapshot.ID, "", false, true, true)
+	if err == nil || !strings.Contains(err.Error(), "left unchanged") || !strings.Contains(err.Error(), "no space left") {
+		t.Fatalf("expected the restore to fail leaving the agent unchanged, got %v", err)
+	}
+	if got := readAgentFile(t, agentDir, "workspace/SOUL.md"); got != "newer soul" {
+		t.Errorf("SOUL.md = %q, want the agent untouched", got)
+	}
+	if got := readAgentFile(t, agentDir, "workspace/new.md"); got != "new" {
+		t.Errorf("new.md = %q, want the agent untouched", got)
+	}
+	entries, err := os.ReadDir(filepath.Dir(agentDir))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected the staging folder to be removed, got %v (%v)", entries, err)
+	}
+}
+
+func TestRollBackRestore(t *testing.T) {
+	engine, agentDir := newStagingTestEngine(t)
+	writeAgentFiles(t, agentDir, map[string]string{"openclaw.json": "{}", "workspace/SOUL.md": "current soul"})
+	result, err := engine.Backup(false, "safety", true, false)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	safety := &safetyBackupResult{BackupResult: result, destination: engine.destination}
+
+	// A restore in place that stopped halfway
+	writeAgentFiles(t, agentDir, map[string]string{"workspace/SOUL.md": "half restored", "workspace/stray.md": "stray"})
+	err = engine.rollBackRestore(agentDir, safety, errors.New("disk full"))
+	if err == nil || !strings.Contains(err.Error(), "rolled back to safety backup "+safety.Snapshot.ID) {
+		t.Fatalf("expected the error to report the rollback, got %v", err)
+	}
+	if got := readAgentFile(t, agentDir, "workspace/SOUL.md"); got != "current soul" {
+		t.Errorf("SOUL.md = %q after rollback", got)
+	}
+	if got := readAgentFile(t, agentDir, "workspace/stray.md"); got != "<missing>" {
+		t.Errorf("stray.md = %q after rollback, want it removed", got)
+	}
+
+	// A skipped safety backup rolls back to the snapshot it matched
+	skipped := &safetyBackupResult{BackupResult: &types.BackupResult{Skipped: true, LastSnapshot: safety.Snapshot}, destination: engine.destination}
+	writeAgentFiles(t, agentDir, map[string]string{"workspace/SOUL.md": "half restored"})
+	if err := engine.rollBackRestore(agentDir, skipped, errors.New("disk full")); !strings.Contains(err.Error(), safety.Snapshot.ID) {
+		t.Errorf("expected a rollback to %s, got %v", safety.Snapshot.ID, err)
+	}
+	if got := readAgentFile(t, agentDir, "workspace/SOUL.md"); got != "current soul" {
+		t.Errorf("SOUL.md = %q after rollback", got)
+	}
+
+	// A fresh target had nothing to protect, so it is emptied again
+	fresh := t.TempDir()
+	writeAgentFiles(t, fresh, map[string]string{"workspace/SOUL.md": "partial"})
+	if err := engine.rollBackRestore(fresh, nil, errors.New("disk full")); !strings.Contains(err.Error(), "emptied again") {
+		t.Errorf("expected the fresh target to be emptied, got %v", err)
+	}
+	if entries, _ := os.ReadDir(fresh); len(entries) != 0 {
+		t.Errorf("expected the fresh target to be empty, got %v", entries)
+	}
+}
@@ -0,0 +1,77 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/backup/scripts"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// Sync verification defaults, used when the config leaves a value unset
+const (
+	defaultSyncVerifyAttempts = 3
+	defaultSyncVerifyInterval = 5 * time.Second
+)
+
+// syncVerifySleep waits between sync verification attempts; tests replace it
+var syncVerifySleep = time.Sleep
+
+// verifySyncWrite checks that a sync destination holds the snapshot just saved.
+// The sync client uploads in the background, so files can be missing or stale
+// right after Save. Each attempt re-reads the files and compares them against
+// the manifest, then runs the configured provider check. It returns the problems
+// still outstanding after the last attempt, or nil once everything matches.
+func (e *BackupEngine) verifySyncWrite(snapshot *types.Snapshot) []string {
+	policy := e.config.Destination.SyncVerify()
+
+	attempts := policy.Attempts
+	if attempts <= 0 {
+		attempts = defaultSyncVerifyAttempts
+	}
+	interval := time.Duration(policy.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultSyncVerifyInterval
+	}
+
+	var problems []string
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			syncVerifySleep(interval)
+		}
+
+		var err error
+		problems, err = e.verifySnapshot(snapshot)
+		if err != nil {
+			problems = []string{err.Error()}
+		}
+		if len(problems) == 0 && policy.Check != nil {
+			if err := e.runSyncCheck(snapshot.ID); err != nil {
+				problems = []string{fmt.Sprintf("sync provider not done: %v", err)}
+			}
+		}
+		if len(problems) == 0 {
+			return nil
+		}
+	}
+
+	return problems
+}
+
+// runSyncCheck runs the provider's completion check, which exits 0 once the
+// sync client reports everything uploaded (e.g. `dropbox status`)
+func (e *BackupEngine) runSyncCheck(snapshotID string) error {
+	check := convertScriptConfigs([]config.ScriptConfig{*e.config.Destination.SyncVerify().Check})
+	if check[0].Name == "" {
+		check[0].Name = "sync check"
+	}
+
+	openclawPath, _ := e.OpenclawPath()
+	executor := scripts.NewExecutor(check, scripts.ExecutionContext{
+		SnapshotID:   snapshotID,
+		OpenClawPath: openclawPath,
+		BackupDir:    e.config.Destination.Location(),
+	})
+	return executor.Execute()
+}
@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func TestVerifySyncWrite_RetriesUntilProviderReportsDone(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	agentDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	marker := filepath.Join(t.TempDir(), "synced")
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "sync",
+			Sync: &config.SyncDestinationConfig{
+				Path: t.TempDir(),
+				Verify: config.SyncVerifyConfig{
+					Enabled:  true,
+					Attempts: 3,
+					Check:    &config.ScriptConfig{Command: "test -f " + marker, Shell: "/bin/sh -c"},
+				},
+			},
+		},
+	}
+	engine, err := NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	// The provider finishes uploading while we wait before the second attempt
+	sleeps := 0
+	syncVerifySleep = func(time.Duration) {
+		sleeps++
+		if err := os.WriteFile(marker, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() { syncVerifySleep = time.Sleep }()
+
+	result, err := engine.Backup(false, "", true, false)
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	if sleeps != 1 {
+		t.Errorf("expected one retry before the provider check passed, got %d", sleeps)
+	}
+
+	// Files that no longer match the manifest are reported after the last attempt
+	if err := os.WriteFile(filepath.Join(cfg.Destination.Sync.Path, "SOUL.md"), []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sleeps = 0
+	problems := engine.verifySyncWrite(result.Snapshot)
+	if len(problems) != 1 || problems[0] != "corrupted: SOUL.md" {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+	if sleeps != 2 {
+		t.Errorf("expected %d retries, got %d", 2, sleeps)
+	}
+
+	// A failing provider check is reported once the files match
+	if err := os.WriteFile(filepath.Join(cfg.Destination.Sync.Path, "SOUL.md"), []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Destination.Sync.Verify.Check.Command = "false"
+	problems = engine.verifySyncWrite(result.Snapshot)
+	if len(problems) != 1 || !strings.HasPrefix(problems[0], "sync provider not done") {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
@@ -0,0 +1,96 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// targetBackupsDir is the folder under the config directory holding the
+// safety backups of alternate restore targets
+const targetBackupsDir = "target-backups"
+
+// safetyBackupResult is a pre-restore safety backup and the destination that
+// holds it
+type safetyBackupResult struct {
+	*types.BackupResult
+
+	destination Destination
+
+	// configPath is the config file reaching the destination when it is not
+	// the agent's own, i.e. for an alternate target's store
+	configPath string
+}
+
+// snapshotID returns the ID of the snapshot holding the state before the
+// restore: the new safety backup, or the latest one when nothing had changed.
+// It is empty when there is none.
+func (s *safetyBackupResult) snapshotID() string {
+	switch {
+	case s == nil:
+		return ""
+	case !s.Skipped:
+		return s.Snapshot.ID
+	case s.LastSnapshot != nil:
+		return s.LastSnapshot.ID
+	}
+	return ""
+}
+
+// restoreCommand returns the command that restores the safety backup
+func (s *safetyBackupResult) restoreCommand() string {
+	if s.configPath != "" {
+		return fmt.Sprintf("bulletproof --config %s restore %s", s.configPath, s.snapshotID())
+	}
+	return "bulletproof restore " + s.snapshotID()
+}
+
+// targetStore returns an engine backing up targetPath into a store of its
+// own: a local destination and a config file in a folder named after the
+// target under the config directory. Safety backups of an alternate restore
+// target go there, so the unrelated folder never becomes the agent's latest
+// snapshot, takes its short IDs or joins its anomaly baseline. The config
+// names the target as its agent folder, so --config with it lists and
+// restores the target's snapshots like any other.
+func (e *BackupEngine) targetStore(targetPath string) (*BackupEngine, string, error) {
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		return nil, "", err
+	}
+	absTarget, err := filepath.Abs(targetPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve restore target: %w", err)
+	}
+	dir := filepath.Join(configDir, targetBackupsDir, filepath.Base(absTarget)+"-"+utils.HashString(absTarget)[:8])
+
+	cfg := &config.Config{
+		OpenclawPath: absTarget,
+		Destination:  config.NewDestinationConfig("local", filepath.Join(dir, "snapshots")),
+		Options:      e.config.Options,
+		Analytics:    e.config.Analytics,
+		Keys:         e.config.Keys,
+	}
+	configPath := filepath.Join(dir, "config.yaml")
+	data, err := cfg.Marshal()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return nil, "", fmt.Errorf("failed to write %s: %w", configPath, err)
+	}
+
+	store, err := NewBackupEngine(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	store.SetOutput(e.out)
+	store.SetProgress(e.progress)
+	return store, configPath, nil
+}
@@ -0,0 +1,463 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// DefaultVerifySample is how many unchanged snapshots an incremental verify re-checks
+const DefaultVerifySample = 5
+
+// VerifyRecord is the persisted outcome of the last verification of a snapshot
+type VerifyRecord struct {
+	// Fingerprint is the hash of the snapshot metadata that was verified.
+	// A different fingerprint means the snapshot was amended or re-imported since.
+	Fingerprint string    `json:"fingerprint"`
+	VerifiedAt  time.Time `json:"verified_at"`
+	OK          bool      `json:"ok"`
+	Problems    []string  `json:"problems,omitempty"`
+}
+
+// VerifyState is the verify history persisted alongside the destination metadata
+type VerifyState struct {
+	LastFullVerify time.Time                `json:"last_full_verify,omitempty"`
+	Snapshots      map[string]*VerifyRecord `json:"snapshots"`
+}
+
+// VerifyResult is the outcome of verifying one snapshot in a run
+type VerifyResult struct {
+	SnapshotID string
+	Reason     string // "new", "changed", "failed before", "sampled", "full", or "requested"
+	// Problems lists the files still damaged, after any repairs
+	Problems []string
+	// Repairs lists the damaged files restored from an intact copy
+	Repairs []FileRepair
+}
+
+// OK reports whether the snapshot's stored files matched its metadata
+func (r *VerifyResult) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// VerifyReport summarizes a verify run
+type VerifyReport struct {
+	Results        []*VerifyResult
+	TotalSnapshots int
+	LastFullVerify time.Time
+	// Unverified lists snapshots whose current metadata has not passed verification
+	Unverified []string
+}
+
+// Failed returns the results of snapshots that failed verification in this run
+func (r *VerifyReport) Failed() []*VerifyResult {
+	var failed []*VerifyResult
+	for _, result := range r.Results {
+		if !result.OK() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Verify checks that stored snapshot files still match their recorded hashes.
+// A full verify checks every snapshot. An incremental verify checks snapshots that
+// are new, changed, or failed last time, plus sampleSize of the least recently
+// verified others, so that repeated runs eventually cover every snapshot.
+// Results are persisted so later incremental runs know what was already checked.
+// With repair, damaged files are replaced by intact copies with the same hash
+// from other snapshots or the current state where one exists.
+func (e *BackupEngine) Verify(incremental bool, sampleSize int, repair bool) (*VerifyReport, error) {
+	return e.verify(repair, func(ids []string, fingerprints map[string]string, state *VerifyState) (map[string]string, error) {
+		if incremental {
+			return selectForIncrementalVerify(ids, fingerprints, state, sampleSize, rand.New(rand.NewSource(time.Now().UnixNano()))), nil
+		}
+		selected := make(map[string]string, len(ids))
+		for _, id := range ids {
+			selected[id] = "full"
+		}
+		return selected, nil
+	})
+}
+
+// VerifyOne checks a single snapshot the way Verify checks each snapshot it
+// selects, and records the result in the same history
+func (e *BackupEngine) VerifyOne(snapshotID string, repair bool) (*VerifyReport, error) {
+	resolvedID, err := e.ResolveSnapshotID(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedID == "0" {
+		return nil, fmt.Errorf("ID 0 represents current filesystem state, not a stored snapshot")
+	}
+
+	return e.verify(repair, func(ids []string, _ map[string]string, _ *VerifyState) (map[string]string, error) {
+		for _, id := range ids {
+			if id == resolvedID {
+				return map[string]string{id: "requested"}, nil
+			}
+		}
+		return nil, fmt.Errorf("files of snapshot %s are not stored in this destination", resolvedID)
+	})
+}
+
+// verifySelector picks the snapshots a verify run checks, mapped to the reason
+type verifySelector func(ids []string, fingerprints map[string]string, state *VerifyState) (map[string]string, error)
+
+func (e *BackupEngine) verify(repair bool, selectSnapshots verifySelector) (*VerifyReport, error) {
+	if _, ok := localDestination(e.destination); repair && !ok {
+		return nil, fmt.Errorf("repair is only supported for local destinations: %s snapshots cannot be rewritten in place", e.config.Destination.Type)
+	}
+
+	ids, err := e.verifiableSnapshotIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	statePath := e.verifyStatePath()
+	state := loadVerifyState(statePath)
+
+	// Forget snapshots that no longer exist (e.g. pruned)
+	known := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		known[id] = true
+	}
+	for id := range state.Snapshots {
+		if !known[id] {
+			delete(state.Snapshots, id)
+		}
+	}
+
+	snapshots := make(map[string]*types.Snapshot, len(ids))
+	fingerprints := make(map[string]string, len(ids))
+	for _, id := range ids {
+		snapshot, err := e.destination.GetSnapshot(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get snapshot %s: %w", id, err)
+		}
+		snapshots[id] = snapshot
+		fingerprints[id] = snapshotFingerprint(snapshot)
+	}
+
+	selected, err := selectSnapshots(ids, fingerprints, state)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	report := &VerifyReport{TotalSnapshots: len(ids)}
+	donors := &repairDonors{engine: e, snapshots: snapshots}
+	defer donors.cleanup()
+	for _, id := range ids {
+		reason, ok := selected[id]
+		if !ok {
+			continue
+		}
+
+		result := &VerifyResult{SnapshotID: id, Reason: reason}
+		switch {
+		case snapshots[id] == nil:
+			result.Problems = []string{"snapshot metadata not found"}
+		case repair:
+			result.Repairs, result.Problems = e.repairSnapshot(snapshots[id], donors)
+		default:
+			problems, err := e.verifySnapshot(snapshots[id])
+			if err != nil {
+				problems = []string{err.Error()}
+			}
+			result.Problems = problems
+		}
+		report.Results = append(report.Results, result)
+
+		state.Snapshots[id] = &VerifyRecord{
+			Fingerprint: fingerprints[id],
+			VerifiedAt:  now,
+			OK:          result.OK(),
+			Problems:    result.Problems,
+		}
+	}
+
+	// An incremental run that happened to check everything counts as a full verify
+	if len(selected) == len(ids) {
+		state.LastFullVerify = now
+	}
+
+	for _, id := range ids {
+		record := state.Snapshots[id]
+		if record == nil || record.Fingerprint != fingerprints[id] || !record.OK {
+			report.Unverified = append(report.Unverified, id)
+		}
+	}
+	report.LastFullVerify = state.LastFullVerify
+
+	if err := state.save(statePath); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// selectForIncrementalVerify picks the snapshots an incremental verify checks and why.
+// Unchanged snapshots are sampled stalest first; snapshots verified in the same
+// earlier run are equally stale and sampled in random order.
+func selectForIncrementalVerify(ids []string, fingerprints map[string]string, state *VerifyState, sampleSize int, rng *rand.Rand) map[string]string {
+	selected := make(map[string]string)
+	var unchanged []string
+	for _, id := range ids {
+		record := state.Snapshots[id]
+		switch {
+		case record == nil:
+			selected[id] = "new"
+		case record.Fingerprint != fingerprints[id]:
+			selected[id] = "changed"
+		case !record.OK:
+			selected[id] = "failed before"
+		default:
+			unchanged = append(unchanged, id)
+		}
+	}
+
+	rng.Shuffle(len(unchanged), func(i, j int) {
+		unchanged[i], unchanged[j] = unchanged[j], unchanged[i]
+	})
+	sort.SliceStable(unchanged, func(i, j int) bool {
+		return state.Snapshots[unchanged[i]].VerifiedAt.Before(state.Snapshots[unchanged[j]].VerifiedAt)
+	})
+	if sampleSize > len(unchanged) {
+		sampleSize = len(unchanged)
+	}
+	for _, id := range unchanged[:max(sampleSize, 0)] {
+		selected[id] = "sampled"
+	}
+
+	return selected
+}
+
+// fileProblem is a stored file that does not match its manifest entry
+type fileProblem struct {
+	path   string
+	reason string // "missing", "unreadable", "corrupted" or "unexpected"
+	err    error
+}
+
+func (p fileProblem) String() string {
+	if p.err != nil {
+		return fmt.Sprintf("%s: %s (%v)", p.reason, p.path, p.err)
+	}
+	return p.reason + ": " + p.path
+}
+
+// verifySnapshot compares a snapshot's stored files against its recorded hashes
+// and returns the problems found, sorted by path
+func (e *BackupEngine) verifySnapshot(snapshot *types.Snapshot) ([]string, error) {
+	// Manifest-only snapshots store no files that could go missing
+	if snapshot.ManifestOnly {
+		return nil, nil
+	}
+
+	filesPath, cleanup, err := e.storedSnapshotFiles(snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	return problemStrings(checkSnapshotFiles(snapshot, filesPath)), nil
+}
+
+// checkSnapshotFiles hashes each file of snapshot stored under filesPath and
+// returns those that do not match, sorted by path, followed by stored files
+// the manifest does not list. Restores copy those too, so an added file is
+// as much a sign of tampering as a changed one.
+func checkSnapshotFiles(snapshot *types.Snapshot, filesPath string) []fileProblem {
+	paths := make([]string, 0, len(snapshot.Files))
+	for path := range snapshot.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var problems []fileProblem
+	for _, path := range paths {
+		if problem, ok := checkStoredFile(snapshot, filesPath, path); !ok {
+			problems = append(problems, problem)
+		}
+	}
+
+	return append(problems, unexpectedSnapshotFiles(snapshot, filesPath)...)
+}
+
+// checkStoredFile hashes one file of snapshot stored under filesPath and
+// reports whether it matches its manifest entry, or else the problem
+func checkStoredFile(snapshot *types.Snapshot, filesPath, path string) (fileProblem, bool) {
+	hash, err := utils.HashFile(filepath.Join(filesPath, path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fileProblem{path: path, reason: "missing"}, false
+		}
+		return fileProblem{path: path, reason: "unreadable", err: err}, false
+	}
+	if hash != snapshot.Files[path].Hash {
+		return fileProblem{path: path, reason: "corrupted"}, false
+	}
+	return fileProblem{}, true
+}
+
+// verifyRestoreSource checks the stored files of snapshot against their
+// recorded hashes before a full restore changes anything, so a damaged backup
+// fails as a whole instead of leaving the agent half restored
+func (e *BackupEngine) verifyRestoreSource(snapshot *types.Snapshot) error {
+	if e.skipRestoreVerify {
+		return nil
+	}
+	fmt.Fprintf(e.output(), "🔐 Verifying %d stored files...\n", len(snapshot.Files))
+	problems, err := e.verifySnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	return restoreVerifyError(snapshot.ID, problems)
+}
+
+// restoreVerifyError explains which stored files of a snapshot failed
+// verification before a restore, or returns nil when none did
+func restoreVerifyError(snapshotID string, problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("backup %s failed verification, so nothing was restored:\n  %s\nRun 'bulletproof verify --repair %s' to fix it from intact copies, restore another snapshot, or use --skip-verify to restore it as it is",
+		snapshotID, joinProblems(problems), snapshotID)
+}
+
+// unexpectedSnapshotFiles returns the files stored under filesPath that the
+// snapshot's manifest does not list, sorted by path. The .bulletproof metadata
+// and the _exports of pre-backup scripts are stored alongside by design.
+func unexpectedSnapshotFiles(snapshot *types.Snapshot, filesPath string) []fileProblem {
+	var problems []fileProblem
+	filepath.WalkDir(filesPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(filesPath, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		if entry.IsDir() {
+			if rel == ".bulletproof" || rel == "_exports" || rel == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := snapshot.Files[rel]; !ok {
+			problems = append(problems, fileProblem{path: rel, reason: "unexpected"})
+		}
+		return nil
+	})
+	return problems
+}
+
+// problemStrings renders file problems for reports
+func problemStrings(problems []fileProblem) []string {
+	if len(problems) == 0 {
+		return nil
+	}
+	lines := make([]string, len(problems))
+	for i, problem := range problems {
+		lines[i] = problem.String()
+	}
+	return lines
+}
+
+// verifiableSnapshotIDs lists the snapshots whose files the destination keeps.
+// A non-timestamped local destination only holds the latest snapshot's files.
+func (e *BackupEngine) verifiableSnapshotIDs() ([]string, error) {
+	if dest, ok := localDestination(e.destination); ok && !dest.Timestamped {
+		latest, err := e.destination.GetLastSnapshot()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest snapshot: %w", err)
+		}
+		if latest == nil {
+			return nil, nil
+		}
+		return []string{latest.ID}, nil
+	}
+
+	infos, err := e.destination.ListSnapshots()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	ids := make([]string, 0, len(infos))
+	for _, info := range infos {
+		ids = append(ids, info.ID)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// verifyStatePath returns where verify results are persisted. Local destinations
+// keep them in their .bulletproof directory; others use the user cache so the
+// state file is never committed into a backup repository.
+func (e *BackupEngine) verifyStatePath() string {
+	if dest, ok := localDestination(e.destination); ok {
+		return filepath.Join(dest.BasePath, ".bulletproof", "verify.json")
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	destKey := utils.HashString(e.config.Destination.Type + ":" + e.config.Destination.Location())[:16]
+	return filepath.Join(homeDir, ".cache", "bulletproof", "verify", destKey+".json")
+}
+
+// snapshotFingerprint hashes a snapshot's metadata to detect amended snapshots
+func snapshotFingerprint(snapshot *types.Snapshot) string {
+	if snapshot == nil {
+		return ""
+	}
+	data, err := snapshot.ToJSON()
+	if err != nil {
+		return ""
+	}
+	return utils.HashBytes(data)
+}
+
+// loadVerifyState reads the persisted verify state; a missing or unreadable
+// file starts a fresh history
+func loadVerifyState(path string) *VerifyState {
+	state := &VerifyState{}
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, state)
+		}
+	}
+	if state.Snapshots == nil {
+		state.Snapshots = make(map[string]*VerifyRecord)
+	}
+	return state
+}
+
+// save writes the verify state to path
+func (s *VerifyState) save(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal verify state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create verify state directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write verify state: %w", err)
+	}
+	return nil
+}
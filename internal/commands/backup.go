@@ -1,9 +1,18 @@
 package commands
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/bulletproof-bot/backup/internal/analytics"
 	"github.com/bulletproof-bot/backup/internal/backup"
 	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/log"
+	"github.com/bulletproof-bot/backup/internal/types"
 	"github.com/spf13/cobra"
 )
 
@@ -13,25 +22,67 @@ func NewBackupCommand() *cobra.Command {
 	var message string
 	var noScripts bool
 	var force bool
+	var scriptsDir string
+	var strict bool
+	var labels []string
+	var jsonOutput bool
+	var stdinMessage bool
+	var messageFile string
+	var manifestOnly bool
+	var wait bool
 
 	cmd := &cobra.Command{
 		Use:   "backup",
 		Short: "Create a backup snapshot",
-		Long:  "Create a backup snapshot of your OpenClaw installation.",
+		Long: `Create a backup snapshot of your OpenClaw installation.
+
+Without -m, the message is read from stdin when it is piped (or with
+--stdin-message), and otherwise $EDITOR opens with the changes listed as
+comments, as with git commit. An empty message from the editor or from
+--stdin-message aborts the backup. Backups with no terminal attached, such as
+scheduled ones, and those given an empty pipe, as cron does, use
+"Backup <id>".
+
+--message-file reads the whole message from a file, or from stdin with "-",
+as CI pipelines generating multi-line messages need. Unlike the editor, it
+keeps lines starting with '#'. Listings show a message's first line;
+"bulletproof snapshots <id>" shows all of it.
+
+With --manifest-only, only the manifest (hashes, sizes and times of every file)
+is stored, not the files themselves. Such checkpoints are cheap records for
+diff and drift detection when the files are kept in durable storage elsewhere,
+but cannot be restored. They need a local destination.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runBackup(dryRun, message, noScripts, force)
+			if messageFile != "" {
+				if message != "" || stdinMessage {
+					return fmt.Errorf("--message-file cannot be combined with -m or --stdin-message")
+				}
+				var err error
+				if message, err = readMessageFile(messageFile); err != nil {
+					return err
+				}
+			}
+			return runBackup(cmd.OutOrStdout(), dryRun, message, noScripts, force, scriptsDir, strict, labels, jsonOutput, stdinMessage, manifestOnly, wait)
 		},
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be backed up without making changes")
 	cmd.Flags().StringVarP(&message, "message", "m", "", "Backup message")
 	cmd.Flags().BoolVar(&noScripts, "no-scripts", false, "Skip pre-backup script execution")
-	cmd.Flags().BoolVar(&force, "force", false, "Force backup even if no changes detected")
+	cmd.Flags().BoolVar(&force, "force", false, "Force backup even if no changes detected or strict mode flags a spike")
+	cmd.Flags().StringVar(&scriptsDir, "scripts-dir", "", "Read and bundle scripts from this directory instead of the configured one")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Refuse to back up when the change rate spikes above the recent baseline")
+	cmd.Flags().StringArrayVar(&labels, "tag", nil, "Label the snapshot (repeatable), e.g. --tag release")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the result as JSON; progress goes to stderr")
+	cmd.Flags().BoolVar(&stdinMessage, "stdin-message", false, "Read the backup message from stdin")
+	cmd.Flags().StringVar(&messageFile, "message-file", "", "Read the backup message from a file, or from stdin with -")
+	cmd.Flags().BoolVar(&manifestOnly, "manifest-only", false, "Store only file hashes and metadata, not file contents (cannot be restored)")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for another bulletproof operation on the destination to finish instead of failing")
 
 	return cmd
 }
 
-func runBackup(dryRun bool, message string, noScripts bool, force bool) error {
+func runBackup(out io.Writer, dryRun bool, message string, noScripts bool, force bool, scriptsDir string, strict bool, labels []string, jsonOutput bool, stdinMessage bool, manifestOnly bool, wait bool) error {
 	// Track analytics
 	flags := make(map[string]string)
 	if dryRun {
@@ -43,6 +94,27 @@ func runBackup(dryRun bool, message string, noScripts bool, force bool) error {
 	if force {
 		flags["force"] = "true"
 	}
+	if scriptsDir != "" {
+		flags["scripts-dir"] = "true"
+	}
+	if strict {
+		flags["strict"] = "true"
+	}
+	if len(labels) > 0 {
+		flags["tag"] = "true"
+	}
+	if jsonOutput {
+		flags["json"] = "true"
+	}
+	if stdinMessage {
+		flags["stdin-message"] = "true"
+	}
+	if manifestOnly {
+		flags["manifest-only"] = "true"
+	}
+	if wait {
+		flags["wait"] = "true"
+	}
 	analytics.TrackCommand("backup", flags)
 
 	// Load config
@@ -51,13 +123,114 @@ func runBackup(dryRun bool, message string, noScripts bool, force bool) error {
 		return err
 	}
 
+	if err := applyScriptsDir(cfg, scriptsDir); err != nil {
+		return err
+	}
+
+	// --strict turns on anomaly detection for this run even if it is not configured
+	if strict {
+		cfg.Anomaly.Enabled = true
+		cfg.Anomaly.Strict = true
+	}
+
 	// Create backup engine
 	engine, err := backup.NewBackupEngine(cfg)
 	if err != nil {
 		return err
 	}
 
+	// Progress output goes to stderr so stdout carries only the JSON result
+	if jsonOutput {
+		progress := io.Writer(os.Stderr)
+		if log.Quiet() {
+			progress = io.Discard
+		}
+		engine.SetOutput(progress)
+	}
+	engine.SetManifestOnly(manifestOnly)
+	engine.SetWaitForLock(wait)
+	if message == "" {
+		engine.SetMessagePrompt(backupMessagePrompt(stdinMessage, jsonOutput))
+	}
+
 	// Run backup
-	_, err = engine.Backup(dryRun, message, noScripts, force)
-	return err
+	result, err := engine.BackupWithLabels(dryRun, message, noScripts, force, labels)
+	engine.WaitForNotifications()
+	if err != nil || !jsonOutput {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(newBackupResultJSON(result, time.Now()))
+}
+
+// backupResultJSON is the --json form of a backup result. When a backup is
+// skipped, last_snapshot tells a scheduler how long the agent has been unchanged,
+// so it can alert on an agent that is suspiciously static.
+type backupResultJSON struct {
+	Status       string                 `json:"status"` // "created", "skipped" or "dry_run"
+	SnapshotID   string                 `json:"snapshot_id,omitempty"`
+	Timestamp    string                 `json:"timestamp"`
+	FileCount    int                    `json:"file_count"`
+	Labels       []string               `json:"labels,omitempty"`
+	ManifestOnly bool                   `json:"manifest_only,omitempty"`
+	Diff         *types.SnapshotDiff    `json:"diff,omitempty"`
+	Anomaly      *types.Anomaly         `json:"anomaly,omitempty"`
+	Unreadable   []types.UnreadableFile `json:"unreadable,omitempty"`
+	LastSnapshot *lastSnapshotJSON      `json:"last_snapshot,omitempty"`
+}
+
+type lastSnapshotJSON struct {
+	ID         string `json:"id"`
+	Timestamp  string `json:"timestamp"`
+	AgeSeconds int64  `json:"age_seconds"`
+}
+
+func newBackupResultJSON(result *types.BackupResult, now time.Time) backupResultJSON {
+	out := backupResultJSON{
+		Status:       "created",
+		Timestamp:    result.Snapshot.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		FileCount:    len(result.Snapshot.Files),
+		Labels:       result.Snapshot.Labels,
+		ManifestOnly: result.Snapshot.ManifestOnly,
+		Diff:         result.Diff,
+		Anomaly:      result.Anomaly,
+		Unreadable:   result.Unreadable,
+	}
+
+	// A skipped run stores nothing, so there is no snapshot ID to report
+	switch {
+	case result.Skipped:
+		out.Status = "skipped"
+	case result.DryRun:
+		out.Status = "dry_run"
+		out.SnapshotID = result.Snapshot.ID
+	default:
+		out.SnapshotID = result.Snapshot.ID
+	}
+
+	if last := result.LastSnapshot; last != nil {
+		out.LastSnapshot = &lastSnapshotJSON{
+			ID:         last.ID,
+			Timestamp:  last.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+			AgeSeconds: int64(now.Sub(last.Timestamp).Seconds()),
+		}
+	}
+
+	return out
+}
+
+// applyScriptsDir overrides the configured scripts directory for this run
+func applyScriptsDir(cfg *config.Config, scriptsDir string) error {
+	if scriptsDir == "" {
+		return nil
+	}
+
+	absDir, err := filepath.Abs(scriptsDir)
+	if err != nil {
+		return fmt.Errorf("invalid scripts directory: %w", err)
+	}
+	cfg.Scripts.Dir = absDir
+	return nil
 }
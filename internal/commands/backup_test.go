@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+func TestBackupResultJSON_Skipped(t *testing.T) {
+	last := &types.Snapshot{
+		ID:        "20260101-030000-000",
+		Timestamp: time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC),
+		Files:     map[string]*types.FileSnapshot{"SOUL.md": {Path: "SOUL.md", Hash: "h"}},
+	}
+	now := last.Timestamp.Add(72 * time.Hour)
+	result := &types.BackupResult{
+		Snapshot: &types.Snapshot{ID: "20260104-030000-000", Timestamp: now, Files: last.Files},
+		Diff: &types.SnapshotDiff{
+			From: last.ID, To: "20260104-030000-000",
+			Added: []string{}, Removed: []string{}, Modified: []string{},
+		},
+		Skipped:      true,
+		LastSnapshot: last,
+	}
+
+	data, err := json.Marshal(newBackupResultJSON(result, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Status       string `json:"status"`
+		SnapshotID   string `json:"snapshot_id"`
+		FileCount    int    `json:"file_count"`
+		Diff         *types.SnapshotDiff
+		LastSnapshot *struct {
+			ID         string `json:"id"`
+			Timestamp  string `json:"timestamp"`
+			AgeSeconds int64  `json:"age_seconds"`
+		} `json:"last_snapshot"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Status != "skipped" || got.SnapshotID != "" || got.FileCount != 1 {
+		t.Errorf("unexpected result: %s", data)
+	}
+	if got.Diff == nil || got.Diff.From != last.ID || !got.Diff.IsEmpty() {
+		t.Errorf("expected an empty diff from %s: %s", last.ID, data)
+	}
+	if got.LastSnapshot == nil {
+		t.Fatalf("expected last_snapshot: %s", data)
+	}
+	if got.LastSnapshot.ID != last.ID || got.LastSnapshot.Timestamp != "2026-01-01T03:00:00Z" {
+		t.Errorf("unexpected last snapshot: %+v", got.LastSnapshot)
+	}
+	if got.LastSnapshot.AgeSeconds != 72*60*60 {
+		t.Errorf("age_seconds = %d, want %d", got.LastSnapshot.AgeSeconds, 72*60*60)
+	}
+}
+
+func TestBackupCommand_JSONOnlyOnStdout(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(config.ConfigPathEnv, "")
+
+	agentDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte("# Soul\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.OpenclawPath = agentDir
+	cfg.Destination = config.NewDestinationConfig("local", t.TempDir())
+	cfg.Analytics = config.AnalyticsConfig{Enabled: false, NoticeShown: false}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Progress and the first-run notice must stay off stdout, which is
+	// neither replaced nor written to
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	var out bytes.Buffer
+	cmd := NewBackupCommand()
+	cmd.SetArgs([]string{"--json", "-m", "first"})
+	cmd.SetOut(&out)
+	runErr := cmd.Execute()
+	os.Stdout = stdout
+	w.Close()
+	leaked, _ := io.ReadAll(r)
+
+	if runErr != nil {
+		t.Fatalf("backup --json failed: %v", runErr)
+	}
+	if len(leaked) > 0 {
+		t.Errorf("expected nothing on stdout, got %q", leaked)
+	}
+	var got struct {
+		Status     string `json:"status"`
+		SnapshotID string `json:"snapshot_id"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("expected only JSON on the command's output: %v\n%s", err, out.String())
+	}
+	if got.Status != "created" || got.SnapshotID == "" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"strings"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// errBisectQuit stops a bisect the user quit
+var errBisectQuit = errors.New("bisect stopped")
+
+// NewBisectCommand creates the bisect command
+func NewBisectCommand() *cobra.Command {
+	var good, bad string
+
+	cmd := &cobra.Command{
+		Use:   "bisect <pattern> --good <id> --bad <id>",
+		Short: "Find the snapshot that introduced a change to a file",
+		Long: `Binary search the snapshots between a known-good and a known-bad one for
+the snapshot that introduced a change to the files matching a pattern.
+
+Only snapshots that changed the target files are candidates, since the others
+cannot have introduced anything; files are compared by hash, so no contents
+are needed. At each step the changes since the last good snapshot are shown
+and you answer good or bad. Snapshots whose target files match the good or
+bad snapshot exactly are judged automatically. The search ends at the first
+bad snapshot, with its ID and message.
+
+The pattern works as in diff: a path, a file name, a folder or a glob, with
+the syntax of options.include. --good and --bad also take a label, naming
+the newest snapshot with it, e.g. one marked with 'bulletproof tag 12
+known-good'.
+
+Examples:
+  bulletproof bisect SOUL.md --good 50 --bad 1
+  bulletproof bisect 'skills/*.js' --good 20250101-030000-000
+  bulletproof bisect workspace/TOOLS.md --good 12 --bad 3
+  bulletproof bisect SOUL.md --good known-good`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBisect(args[0], good, bad, os.Stdin)
+		},
+	}
+
+	cmd.Flags().StringVar(&good, "good", "", "Snapshot or label known to be good (required)")
+	cmd.Flags().StringVar(&bad, "bad", "1", "Snapshot or label known to be bad")
+	cmd.MarkFlagRequired("good")
+
+	return cmd
+}
+
+func runBisect(pattern, goodID, badID string, input io.Reader) error {
+	// Track analytics
+	analytics.TrackCommand("bisect", nil)
+
+	if err := types.ValidatePattern(pattern); err != nil {
+		return err
+	}
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(input)
+	content := engine.ContentReader()
+	judge := func(step backup.BisectStep) (bool, error) {
+		fmt.Printf("\n🔍 Testing %s (%d candidates left, about %d more steps)\n", describeSnapshot(step.Snapshot), step.Remaining, bits.Len(uint(step.Remaining-1)))
+		fmt.Printf("📊 Changes since good snapshot %s: %s\n", step.Good.ID, step.Diff.String())
+		step.Diff.PrintUnifiedWithReaders(content, content, step.Good, step.Snapshot)
+		return askBisectVerdict(reader)
+	}
+
+	match := func(path string) bool { return types.MatchPath(path, pattern) }
+	result, err := engine.Bisect(goodID, badID, match, judge)
+	if errors.Is(err, errBisectQuit) {
+		fmt.Println("❌ Bisect stopped.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n🎯 First bad snapshot: %s\n", describeSnapshot(result.Culprit))
+	fmt.Printf("   Last good snapshot: %s\n", describeSnapshot(result.LastGood))
+	fmt.Printf("   Found among %d snapshots that changed %s, asking %d question(s)\n\n", result.Candidates, pattern, result.Asked)
+	result.Diff.PrintUnifiedWithReaders(content, content, result.LastGood, result.Culprit)
+	fmt.Printf("💡 Review it again with: bulletproof diff %s %s '%s'\n", result.LastGood.ID, result.Culprit.ID, pattern)
+	return nil
+}
+
+// describeSnapshot names a snapshot by ID, time and message
+func describeSnapshot(snapshot *types.Snapshot) string {
+	description := fmt.Sprintf("%s (%s)", snapshot.ID, snapshot.Timestamp.Local().Format("2006-01-02 15:04:05"))
+	if snapshot.Message != "" {
+		description += " - " + messageSubject(snapshot.Message)
+	}
+	return description
+}
+
+// askBisectVerdict asks whether the snapshot just shown is good or bad until
+// it gets an answer. End of input quits.
+func askBisectVerdict(reader *bufio.Reader) (bool, error) {
+	for {
+		fmt.Print("Is this snapshot good or bad? [good/bad/quit]: ")
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Println()
+			return false, errBisectQuit
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "g", "good":
+			return true, nil
+		case "b", "bad":
+			return false, nil
+		case "q", "quit":
+			return false, errBisectQuit
+		}
+		fmt.Println("Please answer good, bad or quit.")
+	}
+}
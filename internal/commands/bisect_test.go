@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAskBisectVerdict(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("maybe\nGood\nb\nq\n"))
+	if good, err := askBisectVerdict(reader); err != nil || !good {
+		t.Errorf("expected good after re-asking, got %v (%v)", good, err)
+	}
+	if good, err := askBisectVerdict(reader); err != nil || good {
+		t.Errorf("expected bad, got %v (%v)", good, err)
+	}
+	if _, err := askBisectVerdict(reader); !errors.Is(err, errBisectQuit) {
+		t.Errorf("expected quit, got %v", err)
+	}
+	if _, err := askBisectVerdict(reader); !errors.Is(err, errBisectQuit) {
+		t.Errorf("expected end of input to quit, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// NewChangelogCommand creates the changelog command
+func NewChangelogCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "changelog <from> <to>",
+		Short: "Summarize agent changes between two snapshots as markdown",
+		Long: `Summarize the net changes between two snapshots as a markdown changelog,
+grouped by area: personality, skills, agent definitions, configuration, memory
+and other files.
+
+Skills are listed by name as added, removed or updated. Changes to JSON config
+files such as openclaw.json are listed key by key. Only the net change across
+the range is shown, so a file edited and reverted in between does not appear.
+The range always reads from the older snapshot to the newer one.
+
+Examples:
+  bulletproof changelog 10 1                  # Last ten snapshots
+  bulletproof changelog 5 0                   # Snapshot 5 to the current state
+  bulletproof changelog 20250101-030000-000 1 -o CHANGELOG.md`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runChangelog(args[0], args[1], output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the changelog to a file instead of stdout")
+
+	return cmd
+}
+
+func runChangelog(fromID, toID string, output string) error {
+	// Track analytics
+	analytics.TrackCommand("changelog", map[string]string{"output": fmt.Sprintf("%t", output != "")})
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	changelog, err := engine.Changelog(fromID, toID)
+	if err != nil {
+		return err
+	}
+
+	markdown := changelog.Markdown()
+	if output == "" {
+		fmt.Print(markdown)
+		return nil
+	}
+
+	if err := os.WriteFile(output, []byte(markdown), 0644); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	fmt.Printf("✅ Changelog written to %s\n", output)
+	return nil
+}
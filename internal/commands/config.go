@@ -1,7 +1,13 @@
 package commands
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/bulletproof-bot/backup/internal/config"
 	"github.com/spf13/cobra"
@@ -30,11 +36,39 @@ func NewConfigCommand() *cobra.Command {
 	setCmd := &cobra.Command{
 		Use:   "set <key> <value>",
 		Short: "Set a configuration value",
-		Args:  cobra.ExactArgs(2),
-		RunE:  runConfigSet,
+		Long: `Set a configuration value. The new value is checked together with the
+settings it affects before the config is saved, so a value that would make
+the next backup fail is refused right away.
+
+Keys:
+  openclaw_path      The OpenClaw folder to back up
+  destination.type   local, git, sync, s3 or sftp; the destination keeps its location
+  destination.path   Where backups go: a folder, a git URL, or an s3:// or sftp:// URL
+
+Examples:
+  bulletproof config set openclaw_path ~/.openclaw
+  bulletproof config set destination.path /mnt/backups/openclaw
+  bulletproof config set destination.type sync`,
+		Args: cobra.ExactArgs(2),
+		RunE: runConfigSet,
 	}
 	cmd.AddCommand(setCmd)
 
+	cmd.AddCommand(&cobra.Command{
+		Use:   "edit",
+		Short: "Edit the configuration file in $EDITOR",
+		Long: `Open the configuration file in $VISUAL or $EDITOR (vi if neither is set)
+to change any setting, including nested blocks such as scripts and
+retention that config set does not cover.
+
+The edit is made on a copy. When the editor exits, the copy is parsed and
+validated like at the start of a backup, and only replaces the config file
+if it passes. Otherwise the problem is shown and the editor reopens on your
+changes, so nothing is lost; answer n to discard them instead.`,
+		Args: cobra.NoArgs,
+		RunE: runConfigEdit,
+	})
+
 	return cmd
 }
 
@@ -58,6 +92,94 @@ func runConfigPath(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runConfigEdit(cmd *cobra.Command, args []string) error {
+	return editConfig(os.Stdin, os.Stdout)
+}
+
+// editConfig lets the user edit a copy of the config file and saves it once
+// it parses and validates, reopening the editor on the copy until then
+func editConfig(in io.Reader, out io.Writer) error {
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		return err
+	}
+
+	original, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		cfg, loadErr := config.Load()
+		if loadErr != nil {
+			return loadErr
+		}
+		original, err = cfg.Marshal()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Edit a copy beside the config file, so saving is a rename
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	file, err := os.CreateTemp(filepath.Dir(configPath), ".config-edit-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to create a copy to edit: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(original)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create a copy to edit: %w", err)
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		if err := runEditor(file.Name()); err != nil {
+			return err
+		}
+		edited, err := os.ReadFile(file.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read the edited config: %w", err)
+		}
+		if bytes.Equal(edited, original) {
+			fmt.Fprintln(out, "No changes made.")
+			return nil
+		}
+
+		invalid := validateConfigData(edited)
+		if invalid == nil {
+			break
+		}
+		fmt.Fprintf(out, "❌ The edited config is invalid:\n%v\n", invalid)
+		fmt.Fprint(out, "Edit it again? [Y/n]: ")
+		scanner.Scan()
+		response := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if response != "" && response != "y" && response != "yes" {
+			return fmt.Errorf("config not saved: %w", invalid)
+		}
+	}
+
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to save config file: %w", err)
+	}
+	if err := os.Rename(file.Name(), configPath); err != nil {
+		return fmt.Errorf("failed to save config file: %w", err)
+	}
+	fmt.Fprintf(out, "✅ Saved %s\n", configPath)
+	return nil
+}
+
+// validateConfigData checks the contents of a config file the way a backup
+// would on loading it
+func validateConfigData(data []byte) error {
+	cfg, err := config.Parse(data)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
 func runConfigSet(cmd *cobra.Command, args []string) error {
 	key := args[0]
 	value := args[1]
@@ -67,22 +189,49 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if err := applyConfigSetting(cfg, key, value); err != nil {
+		return err
+	}
+
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Set %s = %s\n", key, value)
+	return nil
+}
+
+// applyConfigSetting changes one setting and validates the part of the config
+// it affects. Only that part is checked, so a problem elsewhere, which may be
+// the next thing to fix, does not block the change.
+func applyConfigSetting(cfg *config.Config, key, value string) error {
 	switch key {
 	case "openclaw_path":
 		// Validate the path
 		if err := config.Validate(value); err != nil {
 			return fmt.Errorf("invalid OpenClaw path: %w", err)
 		}
-		cfg.OpenclawPath = value
+		cfg.SetAgentPath(value)
+		return cfg.ValidateSources()
 
-	default:
-		return fmt.Errorf("unknown config key: %s", key)
-	}
+	case "destination.type":
+		if cfg.Destination == nil {
+			cfg.Destination = &config.DestinationConfig{}
+		}
+		if err := cfg.Destination.SetType(value); err != nil {
+			return err
+		}
+		return cfg.ValidateDestination()
 
-	if err := cfg.Save(); err != nil {
-		return err
-	}
+	case "destination.path", "destination":
+		if cfg.Destination == nil {
+			// Reports that there is no destination to change
+			return cfg.ValidateDestination()
+		}
+		cfg.Destination.SetLocation(value)
+		return cfg.ValidateDestination()
 
-	fmt.Printf("✅ Set %s = %s\n", key, value)
-	return nil
+	default:
+		return fmt.Errorf("unknown config key: %s (supported: openclaw_path, destination.type, destination.path)", key)
+	}
 }
@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func TestApplyConfigSetting(t *testing.T) {
+	newConfig := func() *config.Config {
+		return &config.Config{
+			OpenclawPath: t.TempDir(),
+			Destination: &config.DestinationConfig{
+				Type:  "local",
+				Local: &config.LocalDestinationConfig{Path: t.TempDir(), Deduplicate: true},
+			},
+		}
+	}
+
+	// A destination folder that cannot exist is refused
+	notDir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notDir, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigSetting(newConfig(), "destination.path", notDir); err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Errorf("expected a file as destination to be refused, got %v", err)
+	}
+
+	cfg := newConfig()
+	target := filepath.Join(t.TempDir(), "backups")
+	if err := applyConfigSetting(cfg, "destination.path", target); err != nil {
+		t.Fatalf("expected a new destination folder to be accepted: %v", err)
+	}
+	if cfg.Destination.Location() != target || !cfg.Destination.Local.Deduplicate {
+		t.Errorf("expected the location changed and other settings kept, got %+v", cfg.Destination.Local)
+	}
+
+	// Only known types, and the location moves to the new type's block
+	if err := applyConfigSetting(cfg, "destination.type", "ftp"); err == nil {
+		t.Error("expected an unknown destination type to be refused")
+	}
+	if err := applyConfigSetting(cfg, "destination.type", "sync"); err != nil {
+		t.Fatalf("expected switching to sync to be accepted: %v", err)
+	}
+	if cfg.Destination.Sync == nil || cfg.Destination.Sync.Path != target || cfg.Destination.Local != nil {
+		t.Errorf("expected the location moved to the sync block, got %+v", cfg.Destination)
+	}
+
+	// Options the new type does not support are reported
+	cfg = newConfig()
+	cfg.Options.Encryption.Enabled = true
+	if err := applyConfigSetting(cfg, "destination.type", "git"); err == nil || !strings.Contains(err.Error(), "encryption") {
+		t.Errorf("expected encryption with git to be refused, got %v", err)
+	}
+
+	// A broken destination does not block fixing the sources
+	cfg = newConfig()
+	cfg.Destination.Local.Path = notDir
+	openclaw := t.TempDir()
+	if err := os.WriteFile(filepath.Join(openclaw, "openclaw.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigSetting(cfg, "openclaw_path", openclaw); err != nil {
+		t.Errorf("expected the sources to be settable while the destination is broken: %v", err)
+	}
+
+	if err := applyConfigSetting(newConfig(), "destination.colour", "blue"); err == nil {
+		t.Error("expected an unknown key to be refused")
+	}
+}
+
+func TestEditConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	t.Setenv(config.ConfigPathEnv, configPath)
+	cfg := &config.Config{
+		OpenclawPath: t.TempDir(),
+		Destination: &config.DestinationConfig{
+			Type:  "local",
+			Local: &config.LocalDestinationConfig{Path: t.TempDir()},
+		},
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first edit enables retention without rules, the second adds one
+	count := filepath.Join(dir, "count")
+	editor := filepath.Join(dir, "editor.sh")
+	script := "#!/bin/sh\n" +
+		"if [ -f " + count + " ]; then printf '  keep_last: 5\\n' >> \"$1\"; exit 0; fi\n" +
+		"touch " + count + "\n" +
+		"printf 'retention:\\n  enabled: true\\n' >> \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	var out bytes.Buffer
+	if err := editConfig(strings.NewReader("\n"), &out); err != nil {
+		t.Fatalf("editConfig failed: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "no retention rules configured") || !strings.Contains(out.String(), "✅ Saved") {
+		t.Errorf("expected the invalid edit reported, then saved:\n%s", out.String())
+	}
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !saved.Retention.Enabled || saved.Retention.KeepLast != 5 {
+		t.Errorf("expected the fixed retention saved, got %+v", saved.Retention)
+	}
+
+	// Declining to edit again leaves the config file untouched
+	before, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(count); err != nil {
+		t.Fatal(err)
+	}
+	script = "#!/bin/sh\nprintf 'options:\\n  compression: rar\\n' >> \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := editConfig(strings.NewReader("n\n"), &out); err == nil || !strings.Contains(err.Error(), "config not saved") {
+		t.Errorf("expected the invalid config refused, got %v", err)
+	}
+	after, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Errorf("config file changed by a refused edit:\n%s", after)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, ".config-edit-*")); len(leftovers) > 0 {
+		t.Errorf("edit copies left behind: %v", leftovers)
+	}
+}
@@ -2,7 +2,9 @@ package commands
 
 import (
 	"fmt"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bulletproof-bot/backup/internal/backup"
 	"github.com/bulletproof-bot/backup/internal/config"
@@ -12,27 +14,101 @@ import (
 
 // NewDiffCommand creates the diff command
 func NewDiffCommand() *cobra.Command {
-	return &cobra.Command{
+	var reverse bool
+	var ignore []string
+	var noRenames bool
+	var since string
+	var stat bool
+
+	cmd := &cobra.Command{
 		Use:   "diff [snapshot1] [snapshot2] [pattern]",
 		Short: "Show changes between snapshots",
 		Long: `Show changes between snapshots or current state.
 
+Changes always read from the older side to the newer side, whatever the
+argument order: "+" files exist only in the newer side, "-" files only in the
+older side. Use --reverse to read from newer to older instead.
+
 Usage:
-  bulletproof diff                    # Compare current state to last backup
-  bulletproof diff 5                  # Compare current state to snapshot 5
-  bulletproof diff 10 5               # Compare snapshot 10 to snapshot 5
+  bulletproof diff                    # Changes since the last backup
+  bulletproof diff 5                  # Changes since snapshot 5
+  bulletproof diff 10 5               # Changes from snapshot 10 to snapshot 5
   bulletproof diff 10 5 SOUL.md       # Compare specific file between snapshots
   bulletproof diff 10 5 'skills/*.js' # Compare files matching pattern
+  bulletproof diff 5 --reverse        # What restoring snapshot 5 would undo
+  bulletproof diff --ignore mode      # Also count files whose mtime changed
+  bulletproof diff --since 24h SOUL.md          # What changed in SOUL.md in a day
+  bulletproof diff --since 2026-02-03T00:00:00  # Changes since a point in time
+  bulletproof diff 10 5 --stat        # Lines changed per file, without the diff
+
+The pattern is a path, a file name or a glob, with the syntax of
+options.include: 'skills/*.js' matches at any depth, '**' spans folders, and
+a folder matches every file under it.
+
+--stat prints one line per changed file with how many lines it gained and
+lost, as git diff --stat does, then the totals. Binary files, and files whose
+content neither snapshot stored, show their size before and after instead.
+
+--since compares the current state with the newest snapshot taken at or
+before the given time. It accepts an age such as 90m, 24h or 7d, or a local
+date and time such as 2026-02-03, 2026-02-03 15:04 or 2026-02-03T15:04:05
+(RFC 3339 with a time zone also works). With --since, the only argument is an
+optional pattern.
+
+A file counts as modified when its content changed. --ignore lists what does
+not count, and replaces the default of "mtime,mode":
+  mtime       Modification time changes
+  mode        Permission changes
+  size-only   Content changes that keep the size (compare sizes, not hashes)
+Pass --ignore= to count every recorded change. Mode and mtime are only
+compared when both snapshots recorded them.
+
+A file removed on one side and added under another name with identical
+content is shown as a rename, e.g. skills/analysis.js -> skills/analyzer.js.
+Use --no-renames to list such files as removed and added instead.
 
 Snapshot IDs:
   0           Current filesystem state
   1, 2, 3...  Short IDs (1=latest, 2=second-latest, etc.)
   yyyyMMdd-HHmmss  Full timestamp IDs also accepted`,
-		RunE: runDiff,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := parseDiffIgnore(ignore)
+			if err != nil {
+				return err
+			}
+			opts.Renames = !noRenames
+			if since != "" {
+				at, err := parseSince(since, time.Now())
+				if err != nil {
+					return err
+				}
+				return runDiffSince(at, args, reverse, opts, stat)
+			}
+			return runDiff(args, reverse, opts, stat)
+		},
 	}
+
+	cmd.Flags().BoolVar(&reverse, "reverse", false, "Show changes from the newer side to the older side")
+	cmd.Flags().StringSliceVar(&ignore, "ignore", []string{"mtime", "mode"}, "Changes that do not count as modifications: mtime, mode, size-only")
+	cmd.Flags().BoolVar(&noRenames, "no-renames", false, "List files moved with unchanged content as removed and added")
+	cmd.Flags().BoolVar(&stat, "stat", false, "Summarize lines changed per file instead of showing the diff")
+	cmd.Flags().StringVar(&since, "since", "", "Compare the current state with the newest snapshot at or before this time or age (e.g. 24h, 2026-02-03)")
+
+	return cmd
+}
+
+// diffSide is one side of a comparison: a snapshot and where its file contents
+// can be read, or nil when only the manifest is available
+type diffSide struct {
+	snapshot *types.Snapshot
+	content  types.DestinationContentReader
 }
 
-func runDiff(cmd *cobra.Command, args []string) error {
+func runDiff(args []string, reverse bool, opts types.DiffOptions, stat bool) error {
+	if len(args) > 3 {
+		return fmt.Errorf("too many arguments (expected 0-3, got %d)", len(args))
+	}
+
 	// Load config
 	cfg, err := config.Load()
 	if err != nil {
@@ -46,237 +122,209 @@ func runDiff(cmd *cobra.Command, args []string) error {
 	}
 
 	// Parse arguments based on count
-	var diff *types.SnapshotDiff
-	var from, to *types.Snapshot
+	var from, to *diffSide
 	var pattern string
 
 	switch len(args) {
 	case 0:
-		// No args: current vs last backup
-		diff, from, to, err = diffCurrentVsLast(engine)
+		// No args: last backup vs current
+		last, err := engine.Destination().GetLastSnapshot()
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to get last snapshot: %w", err)
 		}
+		if last == nil {
+			fmt.Println("No previous backup found.")
+			return nil
+		}
+		from = &diffSide{snapshot: last, content: engine.ContentReader()}
+		to, err = loadDiffSide(engine, "0")
 
 	case 1:
-		// 1 arg: current (ID 0) vs specified snapshot
-		diff, from, to, err = diffCurrentVsSnapshotWithSnapshots(engine, args[0])
-		if err != nil {
-			return err
+		// 1 arg: specified snapshot vs current (ID 0)
+		if from, err = loadDiffSide(engine, args[0]); err == nil {
+			to, err = loadDiffSide(engine, "0")
 		}
 
-	case 2:
-		// 2 args: snapshot1 vs snapshot2
-		diff, from, to, err = diffSnapshotVsSnapshotWithSnapshots(engine, args[0], args[1])
-		if err != nil {
-			return err
+	default:
+		// 2 args: snapshot1 vs snapshot2, optionally filtered by a pattern
+		if from, err = loadDiffSide(engine, args[0]); err == nil {
+			to, err = loadDiffSide(engine, args[1])
 		}
-
-	case 3:
-		// 3 args: snapshot1 vs snapshot2 with pattern filter
-		diff, from, to, err = diffSnapshotVsSnapshotWithSnapshots(engine, args[0], args[1])
-		if err != nil {
-			return err
+		if len(args) == 3 {
+			pattern = args[2]
 		}
-		pattern = args[2]
-
-	default:
-		return fmt.Errorf("too many arguments (expected 0-3, got %d)", len(args))
 	}
-
-	if diff == nil {
-		fmt.Println("No differences found.")
-		return nil
-	}
-
-	// Apply pattern filter if specified
-	if pattern != "" {
-		diff = filterDiffByPattern(diff, pattern)
+	if err != nil {
+		return err
 	}
 
-	// Get snapshot paths for content-based diff (if available)
-	fromPath := engine.Destination().GetSnapshotPath(from.ID)
-	toPath := engine.Destination().GetSnapshotPath(to.ID)
-
-	// Display diff in unified format
-	if fromPath != "" && toPath != "" {
-		// Use content-based diff when paths are available
-		diff.PrintUnifiedWithContent(fromPath, toPath, from, to)
-	} else {
-		// Fall back to metadata-only diff
-		diff.PrintUnified(from, to)
+	if err := types.ValidatePattern(pattern); err != nil {
+		return err
 	}
-
+	printDiff(from, to, pattern, reverse, opts, stat)
 	return nil
 }
 
-// diffCurrentVsLast compares current state to last backup
-func diffCurrentVsLast(engine *backup.BackupEngine) (*types.SnapshotDiff, *types.Snapshot, *types.Snapshot, error) {
-	openclawPath, err := engine.OpenclawPath()
-	if err != nil {
-		return nil, nil, nil, err
-	}
-
-	current, err := types.FromDirectory(
-		openclawPath,
-		engine.Config().Options.Exclude,
-		"",
-	)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to scan current state: %w", err)
+// runDiffSince compares the current state with the newest snapshot taken at or
+// before a point in time, optionally filtered by a pattern
+func runDiffSince(at time.Time, args []string, reverse bool, opts types.DiffOptions, stat bool) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments with --since (expected an optional pattern, got %d arguments)", len(args))
 	}
 
-	last, err := engine.Destination().GetLastSnapshot()
+	cfg, err := config.Load()
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get last snapshot: %w", err)
-	}
-
-	if last == nil {
-		fmt.Println("No previous backup found.")
-		return nil, nil, nil, nil
+		return err
 	}
-
-	return current.Diff(last), current, last, nil
-}
-
-// diffCurrentVsSnapshotWithSnapshots compares current filesystem state to a specific snapshot
-func diffCurrentVsSnapshotWithSnapshots(engine *backup.BackupEngine, snapshotID string) (*types.SnapshotDiff, *types.Snapshot, *types.Snapshot, error) {
-	// Resolve short ID to full ID
-	resolvedID, err := engine.ResolveSnapshotID(snapshotID)
+	engine, err := backup.NewBackupEngine(cfg)
 	if err != nil {
-		return nil, nil, nil, err
+		return err
 	}
 
-	// Get OpenClaw path
-	openclawPath, err := engine.OpenclawPath()
+	info, err := engine.SnapshotAt(at)
 	if err != nil {
-		return nil, nil, nil, err
+		return err
 	}
-
-	// Create snapshot of current state
-	current, err := types.FromDirectory(
-		openclawPath,
-		engine.Config().Options.Exclude,
-		"",
-	)
+	from, err := loadDiffSide(engine, info.ID)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to scan current state: %w", err)
+		return err
 	}
-
-	// Get the target snapshot
-	snapshot, err := engine.GetSnapshot(resolvedID)
+	to, err := loadDiffSide(engine, "0")
 	if err != nil {
-		return nil, nil, nil, err
+		return err
 	}
 
-	return current.Diff(snapshot), current, snapshot, nil
-}
+	fmt.Printf("Comparing with snapshot %s\n\n", describeSnapshot(from.snapshot))
 
-// diffSnapshotVsSnapshotWithSnapshots compares two snapshots
-func diffSnapshotVsSnapshotWithSnapshots(engine *backup.BackupEngine, id1, id2 string) (*types.SnapshotDiff, *types.Snapshot, *types.Snapshot, error) {
-	// Resolve short IDs to full IDs
-	resolvedID1, err := engine.ResolveSnapshotID(id1)
-	if err != nil {
-		return nil, nil, nil, err
+	var pattern string
+	if len(args) == 1 {
+		pattern = args[0]
 	}
-
-	resolvedID2, err := engine.ResolveSnapshotID(id2)
-	if err != nil {
-		return nil, nil, nil, err
+	if err := types.ValidatePattern(pattern); err != nil {
+		return err
 	}
+	printDiff(from, to, pattern, reverse, opts, stat)
+	return nil
+}
 
-	// Special case: ID 0 means current state
-	var snapshot1 *types.Snapshot
-	if resolvedID1 == "0" {
-		openclawPath, err := engine.OpenclawPath()
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		snapshot1, err = types.FromDirectory(
-			openclawPath,
-			engine.Config().Options.Exclude,
-			"",
-		)
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("failed to scan current state: %w", err)
-		}
-	} else {
-		snapshot1, err = engine.GetSnapshot(resolvedID1)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-	}
+// printDiff orders two sides, diffs them and prints the changes matching
+// pattern, or with stat a summary of them
+func printDiff(from, to *diffSide, pattern string, reverse bool, opts types.DiffOptions, stat bool) {
+	from, to = orderDiffSides(from, to, reverse)
+	diff := to.snapshot.DiffWith(from.snapshot, opts)
 
-	// Get second snapshot (cannot be ID 0)
-	if resolvedID2 == "0" {
-		return nil, nil, nil, fmt.Errorf("second snapshot ID cannot be 0 (current state)")
+	// Apply pattern filter if specified
+	if pattern != "" {
+		diff = filterDiffByPattern(diff, pattern)
 	}
 
-	snapshot2, err := engine.GetSnapshot(resolvedID2)
-	if err != nil {
-		return nil, nil, nil, err
+	if stat {
+		diff.PrintStat(from.content, to.content, from.snapshot, to.snapshot)
+		return
 	}
 
-	return snapshot1.Diff(snapshot2), snapshot1, snapshot2, nil
+	// Display diff in unified format; files whose content cannot be read show metadata
+	diff.PrintUnifiedWithReaders(from.content, to.content, from.snapshot, to.snapshot)
 }
 
-// filterDiffByPattern filters diff results to only include files matching pattern
-func filterDiffByPattern(diff *types.SnapshotDiff, pattern string) *types.SnapshotDiff {
-	filtered := &types.SnapshotDiff{
-		From:     diff.From,
-		To:       diff.To,
-		Added:    []string{},
-		Removed:  []string{},
-		Modified: []string{},
-	}
+// sinceLayouts are the date and time formats --since accepts, read in local time
+var sinceLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
 
-	// Filter added files
-	for _, path := range diff.Added {
-		if matchesPattern(path, pattern) {
-			filtered.Added = append(filtered.Added, path)
+// parseSince turns a --since value into a point in time: an age before now
+// such as "24h" or "7d", an RFC 3339 timestamp, or a local date and time
+func parseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
 		}
 	}
-
-	// Filter removed files
-	for _, path := range diff.Removed {
-		if matchesPattern(path, pattern) {
-			filtered.Removed = append(filtered.Removed, path)
+	if age, err := time.ParseDuration(value); err == nil && age >= 0 {
+		return now.Add(-age), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range sinceLayouts {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
 		}
 	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q (expected an age such as 24h or 7d, or a date such as 2026-02-03T15:04:05)", value)
+}
 
-	// Filter modified files
-	for _, path := range diff.Modified {
-		if matchesPattern(path, pattern) {
-			filtered.Modified = append(filtered.Modified, path)
+// parseDiffIgnore turns the --ignore list into diff options: mtime and mode
+// count unless ignored, and size-only swaps content hashes for sizes
+func parseDiffIgnore(ignore []string) (types.DiffOptions, error) {
+	opts := types.DiffOptions{Mtime: true, Mode: true}
+	for _, criterion := range ignore {
+		switch strings.TrimSpace(criterion) {
+		case "mtime":
+			opts.Mtime = false
+		case "mode":
+			opts.Mode = false
+		case "size-only":
+			opts.SizeOnly = true
+		case "":
+		default:
+			return opts, fmt.Errorf("unknown --ignore value %q (expected mtime, mode or size-only)", criterion)
 		}
 	}
-
-	return filtered
+	return opts, nil
 }
 
-// matchesPattern checks if a file path matches the given pattern (glob or exact match)
-func matchesPattern(path, pattern string) bool {
-	// Try exact match first
-	if path == pattern {
-		return true
+// loadDiffSide loads a snapshot by short or full ID. ID 0 scans the current
+// filesystem state, whose files are read from the agent folder itself.
+func loadDiffSide(engine *backup.BackupEngine, snapshotID string) (*diffSide, error) {
+	// Resolve short ID to full ID
+	resolvedID, err := engine.ResolveSnapshotID(snapshotID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Try glob match
-	matched, err := filepath.Match(pattern, filepath.Base(path))
-	if err == nil && matched {
-		return true
+	if resolvedID == "0" {
+		openclawPath, err := engine.OpenclawPath()
+		if err != nil {
+			return nil, err
+		}
+		current, err := engine.ScanSource(openclawPath, "", time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan current state: %w", err)
+		}
+		return &diffSide{snapshot: current, content: types.DirContentReader(openclawPath)}, nil
 	}
 
-	// Try full path glob match
-	matched, err = filepath.Match(pattern, path)
-	if err == nil && matched {
-		return true
+	snapshot, err := engine.GetSnapshot(resolvedID)
+	if err != nil {
+		return nil, err
 	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+	return &diffSide{snapshot: snapshot, content: engine.ContentReader()}, nil
+}
 
-	// Try substring match (e.g., "SOUL.md" matches "workspace/SOUL.md")
-	if filepath.Base(path) == pattern {
-		return true
+// orderDiffSides puts the older side first so "+" always means present in the
+// newer side. reverse swaps the result.
+func orderDiffSides(a, b *diffSide, reverse bool) (from, to *diffSide) {
+	from, to = a, b
+	if from.snapshot.Timestamp.After(to.snapshot.Timestamp) {
+		from, to = to, from
+	}
+	if reverse {
+		from, to = to, from
 	}
+	return from, to
+}
 
-	return false
+// filterDiffByPattern filters diff results to only include files matching
+// pattern, with the syntax of options include
+func filterDiffByPattern(diff *types.SnapshotDiff, pattern string) *types.SnapshotDiff {
+	return diff.Filter(func(path string) bool { return types.MatchPath(path, pattern) })
 }
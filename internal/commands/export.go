@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/log"
+	"github.com/bulletproof-bot/backup/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewExportCommand creates the export command
+func NewExportCommand() *cobra.Command {
+	var format string
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export <snapshot-id> --format tar -o <archive>",
+		Short: "Package a stored snapshot into a portable archive",
+		Long: `Package a stored snapshot into a single .tar.gz archive: its files, its
+manifest (.bulletproof/snapshot.json), and the config and scripts bundled with
+it. Files are read from the configured destination, not the live agent.
+
+Copy the archive to another machine and add it to that machine's destination
+with 'bulletproof import'. The archive unpacks to a folder named after the
+snapshot, which 'bulletproof init --from-backup' also accepts.
+
+Encrypted snapshots cannot be exported, since the archive would hold their
+files in the clear.
+
+Examples:
+  bulletproof export 1 --format tar -o agent.tar.gz
+  bulletproof export 20250115-120000-000 --format tar -o /media/usb/agent.tar.gz`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(args[0], format, output)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "tar", "Archive format (tar: a gzipped tarball)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Path of the archive to write (required)")
+	cmd.MarkFlagRequired("output")
+
+	return cmd
+}
+
+func runExport(snapshotID string, format string, output string) error {
+	// Track analytics
+	analytics.TrackCommand("export", map[string]string{"format": format})
+
+	if format != "tar" {
+		return fmt.Errorf("unsupported format %q: only tar is supported", format)
+	}
+
+	archivePath, err := utils.ExpandPath(output)
+	if err != nil {
+		return fmt.Errorf("invalid archive path: %w", err)
+	}
+	archivePath, err = filepath.Abs(archivePath)
+	if err != nil {
+		return fmt.Errorf("invalid archive path: %w", err)
+	}
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("📦 Exporting snapshot %s...\n", snapshotID)
+
+	snapshot, err := engine.Export(snapshotID, archivePath)
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+
+	log.Println()
+	fmt.Printf("✅ Exported snapshot %s (%d files) to %s\n", snapshot.ID, len(snapshot.Files), archivePath)
+	fmt.Println("   Add it to another destination with: bulletproof import " + archivePath)
+	return nil
+}
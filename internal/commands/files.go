@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// fileHashPrefixLength is how much of each hash the text listing shows
+const fileHashPrefixLength = 12
+
+// NewFilesCommand creates the files command
+func NewFilesCommand() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:     "files <snapshot-id> [pattern]",
+		Aliases: []string{"list-files"},
+		Short:   "List the files in a snapshot",
+		Long: `List the files a stored snapshot contains, with each file's size, hash
+prefix and modification time, without restoring it. Use it to confirm that
+an export such as _exports/graph.dump made it into a backup, or that a
+sensitive file was excluded.
+
+The pattern is a path, file name or glob as in diff, e.g. "*.dump" or
+"workspace/skills/". The list is read from the snapshot's manifest, so no
+files are fetched from the destination.
+
+With --json, only a JSON array of the files is printed, for scripts to parse.
+
+Examples:
+  bulletproof files 1
+  bulletproof files 1 '_exports/*'
+  bulletproof files 20250203-120000-000 .env --json`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pattern := ""
+			if len(args) > 1 {
+				pattern = args[1]
+			}
+			return runFiles(args[0], pattern, jsonOutput)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the files as a JSON array")
+
+	return cmd
+}
+
+func runFiles(snapshotID, pattern string, jsonOutput bool) error {
+	// Track analytics
+	analytics.TrackCommand("files", map[string]string{
+		"pattern": fmt.Sprintf("%t", pattern != ""),
+		"json":    fmt.Sprintf("%t", jsonOutput),
+	})
+
+	if err := types.ValidatePattern(pattern); err != nil {
+		return err
+	}
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := engine.GetSnapshot(snapshotID)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+
+	files := snapshotFiles(snapshot, pattern)
+	if jsonOutput {
+		return outputFilesJSON(os.Stdout, files)
+	}
+	printSnapshotFiles(os.Stdout, snapshot, files, pattern)
+	return nil
+}
+
+// snapshotFiles returns the snapshot's files matching pattern, or all of them,
+// sorted by path
+func snapshotFiles(snapshot *types.Snapshot, pattern string) []*types.FileSnapshot {
+	var paths []string
+	if pattern != "" {
+		paths = snapshot.MatchPaths(pattern)
+	} else {
+		for path := range snapshot.Files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+	}
+
+	files := make([]*types.FileSnapshot, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, snapshot.Files[path])
+	}
+	return files
+}
+
+// printSnapshotFiles lists files like ls -l: size, hash prefix and
+// modification time, then the path
+func printSnapshotFiles(w io.Writer, snapshot *types.Snapshot, files []*types.FileSnapshot, pattern string) {
+	if len(files) == 0 {
+		if pattern != "" {
+			fmt.Fprintf(w, "No files in %s match %s.\n", describeSnapshot(snapshot), pattern)
+		} else {
+			fmt.Fprintf(w, "%s has no files.\n", describeSnapshot(snapshot))
+		}
+		return
+	}
+
+	var total int64
+	for _, file := range files {
+		total += file.Size
+	}
+	noun := "files"
+	if len(files) == 1 {
+		noun = "file"
+	}
+	fmt.Fprintf(w, "📄 %d %s (%s) in %s\n", len(files), noun, formatBytes(total), describeSnapshot(snapshot))
+	if snapshot.Parent != "" {
+		fmt.Fprintf(w, "   parent: %s\n", snapshot.Parent)
+	}
+	fmt.Fprintln(w)
+
+	for _, file := range files {
+		hash := file.Hash
+		if len(hash) > fileHashPrefixLength {
+			hash = hash[:fileHashPrefixLength]
+		}
+		fmt.Fprintf(w, "  %10s  %-*s  %s  %s\n", formatBytes(file.Size), fileHashPrefixLength, hash,
+			file.Modified.Local().Format("2006-01-02 15:04:05"), file.Path)
+	}
+}
+
+// fileListing is one file in the JSON output of the files command
+type fileListing struct {
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	Hash        string    `json:"hash"`
+	Modified    time.Time `json:"modified"`
+	Mode        string    `json:"mode,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+}
+
+// outputFilesJSON prints the files as a JSON array with full hashes
+func outputFilesJSON(w io.Writer, files []*types.FileSnapshot) error {
+	listings := make([]fileListing, 0, len(files))
+	for _, file := range files {
+		listing := fileListing{
+			Path:        file.Path,
+			Size:        file.Size,
+			Hash:        file.Hash,
+			Modified:    file.Modified,
+			ContentType: file.ContentType,
+		}
+		if file.Mode != 0 {
+			listing.Mode = fmt.Sprintf("%04o", file.Mode.Perm())
+		}
+		listings = append(listings, listing)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(listings)
+}
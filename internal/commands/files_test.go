@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+func TestSnapshotFiles(t *testing.T) {
+	modified := time.Date(2026, 1, 1, 3, 0, 0, 0, time.Local)
+	snapshot := &types.Snapshot{
+		ID:        "20260101-030000-000",
+		Timestamp: modified,
+		Files: map[string]*types.FileSnapshot{
+			"openclaw.json":                         {Path: "openclaw.json", Hash: "0123456789abcdef0123", Size: 512, Modified: modified, Mode: 0600},
+			filepath.Join("_exports", "graph.dump"): {Path: "_exports/graph.dump", Hash: "fedcba9876543210fedc", Size: 2048, Modified: modified},
+			filepath.Join("workspace", "SOUL.md"):   {Path: "workspace/SOUL.md", Hash: "abc", Size: 10, Modified: modified},
+		},
+	}
+
+	var paths []string
+	for _, file := range snapshotFiles(snapshot, "") {
+		paths = append(paths, file.Path)
+	}
+	if strings.Join(paths, " ") != "_exports/graph.dump openclaw.json workspace/SOUL.md" {
+		t.Errorf("all files = %v", paths)
+	}
+
+	files := snapshotFiles(snapshot, "*.dump")
+	var out bytes.Buffer
+	printSnapshotFiles(&out, snapshot, files, "*.dump")
+	want := "📄 1 file (2.0 KB) in 20260101-030000-000 (2026-01-01 03:00:00)\n\n" +
+		"      2.0 KB  fedcba987654  2026-01-01 03:00:00  _exports/graph.dump\n"
+	if out.String() != want {
+		t.Errorf("listing:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	printSnapshotFiles(&out, snapshot, snapshotFiles(snapshot, ".env"), ".env")
+	if !strings.Contains(out.String(), "No files in 20260101-030000-000") {
+		t.Errorf("expected no matches to be reported, got %q", out.String())
+	}
+
+	out.Reset()
+	if err := outputFilesJSON(&out, snapshotFiles(snapshot, "openclaw.json")); err != nil {
+		t.Fatalf("outputFilesJSON failed: %v", err)
+	}
+	var listings []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &listings); err != nil {
+		t.Fatalf("invalid JSON %s: %v", out.String(), err)
+	}
+	if len(listings) != 1 || listings[0]["hash"] != "0123456789abcdef0123" || listings[0]["mode"] != "0600" || listings[0]["size"] != float64(512) {
+		t.Errorf("unexpected JSON %s", out.String())
+	}
+}
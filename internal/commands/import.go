@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/log"
+	"github.com/bulletproof-bot/backup/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewImportCommand creates the import command
+func NewImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Add a snapshot archive to the configured destination",
+		Long: `Add a snapshot archive written by 'bulletproof export' to the configured
+destination, keeping its ID, message, labels and file manifest. It is then
+listed by 'bulletproof snapshots' and can be restored like any other.
+
+Every file in the archive is checked against the manifest first, so a damaged
+or altered archive is refused as a whole. Importing a snapshot the destination
+already holds fails.
+
+Examples:
+  bulletproof import agent.tar.gz
+  bulletproof import /media/usb/agent.tar.gz`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImport(args[0])
+		},
+	}
+
+	return cmd
+}
+
+func runImport(archive string) error {
+	// Track analytics
+	analytics.TrackCommand("import", nil)
+
+	archivePath, err := utils.ExpandPath(archive)
+	if err != nil {
+		return fmt.Errorf("invalid archive path: %w", err)
+	}
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("📥 Importing %s...\n", archivePath)
+
+	snapshot, err := engine.Import(archivePath)
+	if err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	log.Println()
+	fmt.Printf("✅ Imported %s (%d files)\n", describeSnapshot(snapshot), len(snapshot.Files))
+	fmt.Println("   Restore it with: bulletproof restore " + snapshot.ID)
+	return nil
+}
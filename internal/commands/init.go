@@ -2,39 +2,55 @@ package commands
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
 
+	"github.com/bulletproof-bot/backup/internal/backup"
 	"github.com/bulletproof-bot/backup/internal/config"
 	"github.com/bulletproof-bot/backup/internal/platform"
+	"github.com/bulletproof-bot/backup/internal/types"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 // NewInitCommand creates the init command
 func NewInitCommand() *cobra.Command {
 	var fromBackup string
+	var dryRun bool
+	var gitRemote string
 
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize bulletproof configuration",
-		Long:  "Interactive setup wizard to configure OpenClaw path and backup destination.",
+		Long: `Interactive setup wizard to configure OpenClaw path and backup destination.
+
+A git remote destination is checked right away: its references are listed
+with the credentials backups will use, and setup stops with the likely fix if
+that fails, instead of the first scheduled backup failing unnoticed.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runInit(fromBackup)
+			if fromBackup != "" && gitRemote != "" {
+				return fmt.Errorf("--git-remote cannot be combined with --from-backup")
+			}
+			return runInit(fromBackup, dryRun, gitRemote)
 		},
 	}
 
 	cmd.Flags().StringVar(&fromBackup, "from-backup", "", "Initialize from existing backup path")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the config and scheduling action without writing anything")
+	cmd.Flags().StringVar(&gitRemote, "git-remote", "", "Back up to this git remote URL, skipping the destination prompt")
 
 	return cmd
 }
 
-func runInit(fromBackup string) error {
+func runInit(fromBackup string, dryRun bool, gitRemote string) error {
 	// If initializing from backup, load config from backup
 	if fromBackup != "" {
-		return runInitFromBackup(fromBackup)
+		return runInitFromBackup(fromBackup, dryRun)
 	}
 
 	scanner := bufio.NewScanner(os.Stdin)
@@ -44,14 +60,25 @@ func runInit(fromBackup string) error {
 
 	// Detect OpenClaw path
 	var openclawPath string
-	detected := config.DetectInstallation()
-	if detected != "" {
-		fmt.Printf("Detected OpenClaw installation at: %s\n", detected)
+	detected := config.DetectInstallations()
+	switch {
+	case len(detected) == 1:
+		fmt.Printf("Detected OpenClaw installation at: %s\n", detected[0])
 		fmt.Print("Use this path? [Y/n]: ")
 		scanner.Scan()
 		response := strings.ToLower(strings.TrimSpace(scanner.Text()))
 		if response == "" || response == "y" || response == "yes" {
-			openclawPath = detected
+			openclawPath = detected[0]
+		}
+	case len(detected) > 1:
+		fmt.Println("Detected several OpenClaw installations:")
+		for i, path := range detected {
+			fmt.Printf("  %d. %s\n", i+1, path)
+		}
+		fmt.Printf("Choose [1-%d], or press Enter to type another path: ", len(detected))
+		scanner.Scan()
+		if n, err := strconv.Atoi(strings.TrimSpace(scanner.Text())); err == nil && n >= 1 && n <= len(detected) {
+			openclawPath = detected[n-1]
 		}
 	}
 
@@ -74,17 +101,27 @@ func runInit(fromBackup string) error {
 	}
 
 	// Choose destination type
-	fmt.Println()
-	fmt.Println("Where should backups be stored?")
-	fmt.Println("  1. Local directory")
-	fmt.Println("  2. Git repository")
-	fmt.Println("  3. Cloud sync folder (Dropbox/Google Drive)")
-	fmt.Print("Choose [1-3]: ")
-	scanner.Scan()
-	choice := strings.TrimSpace(scanner.Text())
+	choice := "git-remote"
+	if gitRemote == "" {
+		fmt.Println()
+		fmt.Println("Where should backups be stored?")
+		fmt.Println("  1. Local directory")
+		fmt.Println("  2. Git repository")
+		fmt.Println("  3. Cloud sync folder (Dropbox/Google Drive)")
+		fmt.Print("Choose [1-3]: ")
+		scanner.Scan()
+		choice = strings.TrimSpace(scanner.Text())
+	}
 
 	var destType, destPath string
 	switch choice {
+	case "git-remote":
+		if !isRemoteURL(gitRemote) {
+			return fmt.Errorf("--git-remote must be a remote URL (https://, ssh:// or git@), got %q", gitRemote)
+		}
+		destType = "git"
+		destPath = gitRemote
+
 	case "1":
 		destType = "local"
 		fmt.Print("Enter local directory path: ")
@@ -112,8 +149,7 @@ func runInit(fromBackup string) error {
 
 	// Convert destination path to absolute (critical: avoid CWD dependency)
 	// Only convert if it's not a URL (git remotes can be URLs)
-	if !strings.HasPrefix(destPath, "http://") && !strings.HasPrefix(destPath, "https://") &&
-		!strings.HasPrefix(destPath, "git@") && !strings.HasPrefix(destPath, "ssh://") {
+	if !isRemoteURL(destPath) {
 		absDestPath, err := filepath.Abs(destPath)
 		if err != nil {
 			return fmt.Errorf("invalid destination path: %w", err)
@@ -123,11 +159,8 @@ func runInit(fromBackup string) error {
 
 	// Create config with scheduling enabled by default
 	cfg := &config.Config{
-		OpenclawPath: openclawPath,
-		Destination: &config.DestinationConfig{
-			Type: destType,
-			Path: destPath,
-		},
+		Sources:     config.NewSources(openclawPath),
+		Destination: config.NewDestinationConfig(destType, destPath),
 		Schedule: config.ScheduleConfig{
 			Enabled: true,
 			Time:    "03:00",
@@ -135,9 +168,23 @@ func runInit(fromBackup string) error {
 		Options: config.BackupOptions{
 			IncludeAuth: false,
 			Exclude:     []string{"*.log", "node_modules/", ".git/"},
+			// Refuse backups that lost most files at once, e.g. an unmounted agent folder
+			MaxFileDrop: 50,
 		},
 	}
 
+	if err := checkGitRemote(cfg.Destination); err != nil {
+		return err
+	}
+
+	if dryRun {
+		if err := printInitDryRun(cfg); err != nil {
+			return err
+		}
+		fmt.Println("⏰ Would set up automatic daily backups at 03:00")
+		return nil
+	}
+
 	// Save config
 	if err := cfg.Save(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
@@ -169,7 +216,31 @@ func runInit(fromBackup string) error {
 	return nil
 }
 
-func runInitFromBackup(backupPath string) error {
+// isRemoteURL reports whether a destination is a git remote URL rather than
+// a path
+func isRemoteURL(dest string) bool {
+	return strings.HasPrefix(dest, "http://") || strings.HasPrefix(dest, "https://") ||
+		strings.HasPrefix(dest, "git@") || strings.HasPrefix(dest, "ssh://")
+}
+
+// checkGitRemote checks that a git remote destination is reachable before the
+// config is saved, so credential problems show up during setup rather than at
+// the first scheduled backup
+func checkGitRemote(dest *config.DestinationConfig) error {
+	if !dest.IsGit() || !isRemoteURL(dest.Location()) {
+		return nil
+	}
+
+	fmt.Printf("\n🔌 Checking access to %s...\n", dest.Location())
+	if err := backup.CheckDestination(dest); err != nil {
+		fmt.Println("❌ Could not access the git remote - configuration not saved")
+		return err
+	}
+	fmt.Println("✅ Git remote is reachable")
+	return nil
+}
+
+func runInitFromBackup(backupPath string, dryRun bool) error {
 	scanner := bufio.NewScanner(os.Stdin)
 
 	fmt.Println("🚀 Bulletproof Setup from Backup")
@@ -183,23 +254,55 @@ func runInitFromBackup(backupPath string) error {
 		return fmt.Errorf("failed to read config from backup: %w", err)
 	}
 
-	// Parse config
-	var cfg config.Config
-	if err := yaml.Unmarshal(configData, &cfg); err != nil {
+	// Parse config, upgrading one written by an older version
+	cfg, err := config.Parse(configData)
+	if err != nil {
 		return fmt.Errorf("failed to parse config from backup: %w", err)
 	}
 
+	// The snapshot manifest records the absolute path the files were taken from;
+	// use it when the config leaves the path unset, and to spot platform changes
+	originalRoot := cfg.AgentPath()
+	manifest := readBackupManifest(backupPath)
+	if manifest != nil && manifest.OriginalRoot != "" {
+		originalRoot = manifest.OriginalRoot
+		if cfg.AgentPath() == "" {
+			cfg.SetAgentPath(originalRoot)
+		}
+	}
+
 	// Prompt for new OpenClaw path (may be different on new machine)
-	fmt.Printf("Original OpenClaw path: %s\n", cfg.OpenclawPath)
+	fmt.Printf("Original OpenClaw path: %s\n", cfg.AgentPath())
+	if originalRoot != cfg.AgentPath() {
+		fmt.Printf("Backup taken from: %s\n", originalRoot)
+	}
+	// Newer manifests record the OS; older ones only hint at it through the path
+	from := pathPlatform(originalRoot)
+	if manifest != nil && manifest.OS != "" {
+		from = manifest.OS
+	}
+	if from != "" && from != runtime.GOOS {
+		fmt.Printf("⚠️  This backup was taken on %s; paths and scripts may need adjusting for %s\n", types.PlatformName(from), types.PlatformName(runtime.GOOS))
+	}
 
 	detected := config.DetectInstallation()
-	if detected != "" && detected != cfg.OpenclawPath {
-		fmt.Printf("Detected OpenClaw installation at: %s\n", detected)
-		fmt.Print("Use detected path instead? [Y/n]: ")
+	suggestion := "Detected OpenClaw installation at"
+	if detected == "" {
+		// Nothing installed yet: suggest the same place under this machine's home
+		if home, err := os.UserHomeDir(); err == nil {
+			if relocated, ok := relocateHomePath(originalRoot, home); ok {
+				detected = relocated
+				suggestion = "Suggested OpenClaw path on this machine"
+			}
+		}
+	}
+	if detected != "" && detected != cfg.AgentPath() {
+		fmt.Printf("%s: %s\n", suggestion, detected)
+		fmt.Print("Use this path instead? [Y/n]: ")
 		scanner.Scan()
 		response := strings.ToLower(strings.TrimSpace(scanner.Text()))
 		if response == "" || response == "y" || response == "yes" {
-			cfg.OpenclawPath = detected
+			cfg.SetAgentPath(detected)
 		}
 	} else {
 		fmt.Print("Update OpenClaw path? [y/N]: ")
@@ -215,14 +318,14 @@ func runInitFromBackup(backupPath string) error {
 				if err != nil {
 					return fmt.Errorf("invalid path: %w", err)
 				}
-				cfg.OpenclawPath = absPath
+				cfg.SetAgentPath(absPath)
 			}
 		}
 	}
 
 	// Prompt for new backup destination (likely different on new machine)
 	fmt.Println()
-	fmt.Printf("Original backup destination: %s (%s)\n", cfg.Destination.Path, cfg.Destination.Type)
+	fmt.Printf("Original backup destination: %s (%s)\n", cfg.Destination.Location(), cfg.Destination.Type)
 	fmt.Print("Update backup destination? [Y/n]: ")
 	scanner.Scan()
 	response := strings.ToLower(strings.TrimSpace(scanner.Text()))
@@ -233,18 +336,25 @@ func runInitFromBackup(backupPath string) error {
 		if newDest != "" {
 			// Convert to absolute path (critical: avoid CWD dependency)
 			// Only convert if it's not a URL (git remotes can be URLs)
-			if !strings.HasPrefix(newDest, "http://") && !strings.HasPrefix(newDest, "https://") &&
-				!strings.HasPrefix(newDest, "git@") && !strings.HasPrefix(newDest, "ssh://") {
+			if !isRemoteURL(newDest) {
 				absPath, err := filepath.Abs(newDest)
 				if err != nil {
 					return fmt.Errorf("invalid destination path: %w", err)
 				}
 				newDest = absPath
 			}
-			cfg.Destination.Path = newDest
+			cfg.Destination.SetLocation(newDest)
 		}
 	}
 
+	if err := checkGitRemote(cfg.Destination); err != nil {
+		return err
+	}
+
+	if dryRun {
+		return printInitDryRun(cfg)
+	}
+
 	// Save config
 	if err := cfg.Save(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
@@ -260,3 +370,71 @@ func runInitFromBackup(backupPath string) error {
 
 	return nil
 }
+
+// printInitDryRun prints the config init would write, without writing it
+func printInitDryRun(cfg *config.Config) error {
+	data, err := cfg.Marshal()
+	if err != nil {
+		return err
+	}
+
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("🔍 Dry run - no changes made")
+	fmt.Printf("📄 Would write configuration to: %s\n", configPath)
+	fmt.Println()
+	fmt.Print(string(data))
+	fmt.Println()
+	return nil
+}
+
+// readBackupManifest reads the snapshot manifest stored in a backup folder, or
+// returns nil if the folder has none
+func readBackupManifest(backupPath string) *types.Snapshot {
+	data, err := os.ReadFile(filepath.Join(backupPath, types.MetadataDirName, "snapshot.json"))
+	if err != nil {
+		return nil
+	}
+	var snapshot types.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+	return &snapshot
+}
+
+// homePathPattern matches the home directory prefix of an absolute path on
+// Linux (/home/<user>, /root), macOS (/Users/<user>) and Windows (C:\Users\<user>)
+var homePathPattern = regexp.MustCompile(`^(/home/[^/]+|/root|/Users/[^/]+|[A-Za-z]:[\\/]Users[\\/][^\\/]+)([\\/].*)?$`)
+
+// windowsPathPattern matches an absolute path starting with a drive letter
+var windowsPathPattern = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// pathPlatform guesses the GOOS an absolute path was recorded on from its
+// layout, or returns "" when the path gives no hint
+func pathPlatform(path string) string {
+	switch {
+	case windowsPathPattern.MatchString(path):
+		return "windows"
+	case strings.HasPrefix(path, "/Users/"):
+		return "darwin"
+	case strings.HasPrefix(path, "/home/") || path == "/root" || strings.HasPrefix(path, "/root/"):
+		return "linux"
+	}
+	return ""
+}
+
+// relocateHomePath maps a path under another machine's home directory to the
+// same place under home, e.g. /home/alice/.openclaw -> /Users/bob/.openclaw.
+// It reports false when path is not under a home directory.
+func relocateHomePath(path, home string) (string, bool) {
+	match := homePathPattern.FindStringSubmatch(path)
+	if match == nil {
+		return "", false
+	}
+	rest := strings.ReplaceAll(match[2], "\\", "/")
+	return filepath.Join(home, filepath.FromSlash(rest)), true
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRelocateHomePath(t *testing.T) {
+	home := filepath.FromSlash("/Users/bob")
+	tests := []struct {
+		path     string
+		platform string
+		want     string
+		ok       bool
+	}{
+		{"/home/alice/.openclaw", "linux", filepath.FromSlash("/Users/bob/.openclaw"), true},
+		{"/root/.openclaw", "linux", filepath.FromSlash("/Users/bob/.openclaw"), true},
+		{"/Users/alice/agents/openclaw", "darwin", filepath.FromSlash("/Users/bob/agents/openclaw"), true},
+		{`C:\Users\alice\.openclaw`, "windows", filepath.FromSlash("/Users/bob/.openclaw"), true},
+		{"/data/.openclaw", "", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := pathPlatform(tt.path); got != tt.platform {
+			t.Errorf("pathPlatform(%q) = %q, want %q", tt.path, got, tt.platform)
+		}
+		got, ok := relocateHomePath(tt.path, home)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("relocateHomePath(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
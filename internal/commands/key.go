@@ -0,0 +1,243 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/keys"
+	"github.com/bulletproof-bot/backup/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewKeyCommand creates the key command
+func NewKeyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "key",
+		Short: "Manage encryption keys",
+		Long: `Generate, import, or rotate the key used for encryption.
+
+Keys are stored in owner-only files under ~/.config/bulletproof/keys.
+The configuration records where the encryption key lives, never the key
+itself, and options.encryption uses that key unless key_file names another.
+
+Key types:
+  encryption  256-bit key for encrypting snapshot data
+
+Snapshots are not signed, and rotation does not re-encrypt existing
+snapshots; both are out of scope.`,
+	}
+
+	cmd.AddCommand(NewKeyGenerateCommand())
+	cmd.AddCommand(NewKeyImportCommand())
+	cmd.AddCommand(NewKeyRotateCommand())
+
+	return cmd
+}
+
+// NewKeyGenerateCommand creates the key generate command
+func NewKeyGenerateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "generate encryption",
+		Short: "Generate a new key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runKeyGenerate(args[0])
+		},
+	}
+}
+
+// NewKeyImportCommand creates the key import command
+func NewKeyImportCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import encryption <file>",
+		Short: "Import a hex-encoded key from a file",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runKeyImport(args[0], args[1])
+		},
+	}
+}
+
+// NewKeyRotateCommand creates the key rotate command
+func NewKeyRotateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate encryption",
+		Short: "Replace a key, keeping the old one as retired",
+		Long: `Replace the active key with a newly generated one.
+
+The new key is written first, then the previous one is kept next to it with
+a .retired suffix so that snapshots protected by it remain readable.
+
+An encryption key that options.encryption uses is not rotated: bulletproof
+does not re-encrypt existing snapshots, so the destination would become
+unreadable with the new key. Back up to a new destination with a new key
+instead.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runKeyRotate(args[0])
+		},
+	}
+}
+
+func runKeyGenerate(kindArg string) error {
+	kind, err := keys.ParseKind(kindArg)
+	if err != nil {
+		return err
+	}
+
+	dir, err := keyDir()
+	if err != nil {
+		return err
+	}
+
+	if err := checkPassphraseEncryption(); err != nil {
+		return err
+	}
+
+	key, err := keys.Generate(dir, kind)
+	if err != nil {
+		return err
+	}
+
+	if err := saveKeyReference(key); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Generated %s key\n", key.Kind)
+	printKey(key)
+	return nil
+}
+
+func runKeyImport(kindArg string, srcPath string) error {
+	kind, err := keys.ParseKind(kindArg)
+	if err != nil {
+		return err
+	}
+
+	dir, err := keyDir()
+	if err != nil {
+		return err
+	}
+
+	if err := checkPassphraseEncryption(); err != nil {
+		return err
+	}
+
+	key, err := keys.Import(dir, kind, srcPath)
+	if err != nil {
+		return err
+	}
+
+	if err := saveKeyReference(key); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Imported %s key\n", key.Kind)
+	printKey(key)
+	return nil
+}
+
+func runKeyRotate(kindArg string) error {
+	kind, err := keys.ParseKind(kindArg)
+	if err != nil {
+		return err
+	}
+
+	dir, err := keyDir()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if encryptionUsesKey(cfg, keys.Path(dir, kind)) {
+		return fmt.Errorf("the encryption key %s is used by options.encryption: rotating it would leave the encrypted destination unreadable, as existing snapshots are not re-encrypted", keys.Path(dir, kind))
+	}
+
+	key, retiredPath, err := keys.Rotate(dir, kind)
+	if err != nil {
+		return err
+	}
+
+	if err := saveKeyReference(key); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Rotated %s key\n", key.Kind)
+	printKey(key)
+	if retiredPath != "" {
+		fmt.Printf("   Retired key: %s\n", retiredPath)
+	}
+	return nil
+}
+
+// keyDir returns the directory where key files are stored
+func keyDir() (string, error) {
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return keys.Dir(configDir), nil
+}
+
+// encryptionUsesKey reports whether encryption is enabled with the key file
+// at keyPath
+func encryptionUsesKey(cfg *config.Config, keyPath string) bool {
+	keyFile := cfg.EncryptionKeyFile()
+	if !cfg.Options.Encryption.Enabled || keyFile == "" {
+		return false
+	}
+	return samePath(keyFile, keyPath)
+}
+
+// checkPassphraseEncryption refuses to record a first encryption key while
+// encryption uses a passphrase: the key would become the default key_file and
+// the destination would no longer unlock
+func checkPassphraseEncryption() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.Options.Encryption.Enabled && cfg.EncryptionKeyFile() == "" {
+		return fmt.Errorf("options.encryption uses a passphrase: a recorded encryption key would become its key_file and leave the destination unreadable; move to a new destination first")
+	}
+	return nil
+}
+
+// samePath reports whether two paths, which may start with ~, name the same file
+func samePath(a, b string) bool {
+	var abs [2]string
+	for i, p := range []string{a, b} {
+		expanded, err := utils.ExpandPath(p)
+		if err != nil {
+			return false
+		}
+		if abs[i], err = filepath.Abs(expanded); err != nil {
+			return false
+		}
+	}
+	return abs[0] == abs[1]
+}
+
+// saveKeyReference records the path of an encryption key in the
+// configuration
+func saveKeyReference(key *keys.Key) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cfg.Keys.EncryptionKey = key.Path
+
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
+
+func printKey(key *keys.Key) {
+	fmt.Printf("   Path: %s\n", key.Path)
+	fmt.Printf("   Fingerprint: %s\n", key.Fingerprint)
+}
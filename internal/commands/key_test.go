@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/keys"
+)
+
+func TestKeyRotate_RefusesKeyInUse(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(config.ConfigPathEnv, "")
+
+	if err := runKeyGenerate("encryption"); err != nil {
+		t.Fatalf("key generate failed: %v", err)
+	}
+	dir, err := keyDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := keys.Path(dir, keys.Encryption)
+
+	// Not used by encryption yet, so it can be rotated
+	if err := runKeyRotate("encryption"); err != nil {
+		t.Fatalf("rotating an unused key failed: %v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Keys.EncryptionKey != active {
+		t.Errorf("expected keys.encryption_key %s, got %q", active, cfg.Keys.EncryptionKey)
+	}
+	cfg.Options.Encryption = config.EncryptionConfig{Enabled: true, KeyFile: "~" + strings.TrimPrefix(active, home)}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(active)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runKeyRotate("encryption"); err == nil || !strings.Contains(err.Error(), "unreadable") {
+		t.Errorf("expected rotating the key in use to be refused, got %v", err)
+	}
+	if after, _ := os.ReadFile(active); string(after) != string(before) {
+		t.Error("the key in use was replaced")
+	}
+	if retired, _ := filepath.Glob(filepath.Join(dir, "encryption-*.retired")); len(retired) != 1 {
+		t.Errorf("expected only the first rotation to retire a key, got %v", retired)
+	}
+}
+
+func TestKeyGenerate_RefusesWhilePassphraseEncrypts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(config.ConfigPathEnv, "")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Options.Encryption.Enabled = true
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runKeyGenerate("encryption"); err == nil || !strings.Contains(err.Error(), "passphrase") {
+		t.Errorf("expected generating an encryption key to be refused, got %v", err)
+	}
+	dir, err := keyDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(keys.Path(dir, keys.Encryption)); !os.IsNotExist(err) {
+		t.Errorf("expected no key to be written, got %v", err)
+	}
+}
+
+func TestKeyGenerate_RejectsSigning(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(config.ConfigPathEnv, "")
+
+	if err := runKeyGenerate("signing"); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Errorf("expected signing keys to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// maxMessageTemplateFiles caps how many changed files the editor template lists
+const maxMessageTemplateFiles = 50
+
+// errEmptyMessage aborts a backup whose message was left empty, as git does
+var errEmptyMessage = errors.New("aborting backup due to empty message")
+
+// backupMessagePrompt picks where a backup without -m gets its message: stdin
+// when asked or when it is piped, $EDITOR when running in a terminal. It returns
+// nil when there is nobody to ask (cron, systemd, --json), so the default
+// "Backup <id>" message is used. Cron hands jobs an empty pipe, so a piped
+// stdin left empty also gets the default; only --stdin-message requires a
+// message.
+func backupMessagePrompt(stdinMessage bool, jsonOutput bool) backup.MessagePrompt {
+	stdin, err := os.Stdin.Stat()
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case stdinMessage:
+		return func(*types.SnapshotDiff) (string, error) {
+			return readMessage(os.Stdin)
+		}
+	case stdin.Mode()&os.ModeNamedPipe != 0:
+		return func(*types.SnapshotDiff) (string, error) {
+			message, err := readMessage(os.Stdin)
+			if errors.Is(err, errEmptyMessage) {
+				return "", nil
+			}
+			return message, err
+		}
+	case !jsonOutput && isTerminal(stdin):
+		return editMessage
+	}
+	return nil
+}
+
+// isTerminal reports whether stdin is an interactive terminal. /dev/null is a
+// character device too, so it is ruled out explicitly.
+func isTerminal(stdin os.FileInfo) bool {
+	if stdin.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	if devNull, err := os.Stat(os.DevNull); err == nil && os.SameFile(stdin, devNull) {
+		return false
+	}
+	return true
+}
+
+// readMessage reads a backup message, ignoring '#' comment lines
+func readMessage(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read message: %w", err)
+	}
+	message := cleanMessage(string(data))
+	if message == "" {
+		return "", errEmptyMessage
+	}
+	return message, nil
+}
+
+// readMessageFile reads a backup message from a file, or from stdin when name
+// is "-". The text is kept as written apart from surrounding blank space, so
+// '#' lines such as markdown headings survive.
+func readMessageFile(name string) (string, error) {
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read message file: %w", err)
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	message := strings.TrimSpace(strings.Join(lines, "\n"))
+	if message == "" {
+		return "", errEmptyMessage
+	}
+	return message, nil
+}
+
+// messageSubject returns the first line of a message, for listings that show
+// one line per snapshot
+func messageSubject(message string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(subject)
+}
+
+// editMessage opens $VISUAL or $EDITOR (vi if neither is set) on a template
+// listing the changes, and returns what the user wrote
+func editMessage(diff *types.SnapshotDiff) (string, error) {
+	file, err := os.CreateTemp("", "bulletproof-message-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create message file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(messageTemplate(diff))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write message file: %w", err)
+	}
+
+	if err := runEditor(file.Name()); err != nil {
+		return "", err
+	}
+
+	message, err := os.Open(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read message file: %w", err)
+	}
+	defer message.Close()
+	return readMessage(message)
+}
+
+// runEditor opens $VISUAL or $EDITOR (vi if neither is set) on a file and
+// waits for it to exit
+func runEditor(name string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// The editor setting may carry arguments, e.g. "code --wait"
+	args := append(strings.Fields(editor), name)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
+
+// messageTemplate is the editor's starting text: a blank line for the message,
+// then the changes as comments
+func messageTemplate(diff *types.SnapshotDiff) string {
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString("# Describe this backup. Lines starting with '#' are ignored,\n")
+	b.WriteString("# and an empty message aborts the backup.\n")
+	b.WriteString("#\n")
+
+	if diff == nil {
+		b.WriteString("# First backup - no previous snapshot found\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "# Changes since last backup: %s\n", diff.String())
+	renamed := make([]string, len(diff.Renamed))
+	for i, rename := range diff.Renamed {
+		renamed[i] = rename.String()
+	}
+
+	listed := 0
+	for _, group := range []struct {
+		marker string
+		files  []string
+	}{{"+", diff.Added}, {"~", diff.Modified}, {"~", renamed}, {"-", diff.Removed}} {
+		for _, file := range group.files {
+			if listed == maxMessageTemplateFiles {
+				fmt.Fprintf(&b, "#   ... and %d more\n", diff.TotalChanges()-listed)
+				return b.String()
+			}
+			fmt.Fprintf(&b, "#   %s %s\n", group.marker, file)
+			listed++
+		}
+	}
+	return b.String()
+}
+
+// cleanMessage drops comment lines and surrounding blank space
+func cleanMessage(text string) string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
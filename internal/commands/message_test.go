@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+func TestReadMessage(t *testing.T) {
+	message, err := readMessage(strings.NewReader("\n# comment\nTighten safety rules\n\nAlso trims memory.\n# trailing\n\n"))
+	if err != nil {
+		t.Fatalf("readMessage failed: %v", err)
+	}
+	if message != "Tighten safety rules\n\nAlso trims memory." {
+		t.Errorf("unexpected message %q", message)
+	}
+
+	if _, err := readMessage(strings.NewReader("# only comments\n\n")); !errors.Is(err, errEmptyMessage) {
+		t.Errorf("expected errEmptyMessage, got %v", err)
+	}
+}
+
+func TestBackupMessagePrompt_EmptyPipe(t *testing.T) {
+	stdin := os.Stdin
+	t.Cleanup(func() { os.Stdin = stdin })
+	emptyPipe := func() {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		t.Cleanup(func() { r.Close() })
+		os.Stdin = r
+	}
+
+	// Cron runs jobs with an empty pipe on stdin: the default message is used
+	emptyPipe()
+	prompt := backupMessagePrompt(false, false)
+	if prompt == nil {
+		t.Fatal("expected piped stdin to be read")
+	}
+	if message, err := prompt(nil); err != nil || message != "" {
+		t.Errorf("expected an empty pipe to leave the default message, got %q (%v)", message, err)
+	}
+
+	// --stdin-message still requires one
+	emptyPipe()
+	if _, err := backupMessagePrompt(true, false)(nil); !errors.Is(err, errEmptyMessage) {
+		t.Errorf("expected errEmptyMessage with --stdin-message, got %v", err)
+	}
+}
+
+func TestEditMessage_UsesEditorWithChangesTemplate(t *testing.T) {
+	dir := t.TempDir()
+	seen := filepath.Join(dir, "template.txt")
+	editor := filepath.Join(dir, "editor.sh")
+	script := "#!/bin/sh\ncp \"$1\" " + seen + "\nprintf 'Updated persona\\n' >> \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	diff := &types.SnapshotDiff{Added: []string{"skills/new.js"}, Modified: []string{"SOUL.md"}, Removed: []string{}}
+	message, err := editMessage(diff)
+	if err != nil {
+		t.Fatalf("editMessage failed: %v", err)
+	}
+	if message != "Updated persona" {
+		t.Errorf("unexpected message %q", message)
+	}
+
+	template, err := os.ReadFile(seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Changes since last backup: +1 added, ~1 modified", "#   + skills/new.js", "#   ~ SOUL.md"} {
+		if !strings.Contains(string(template), want) {
+			t.Errorf("template missing %q:\n%s", want, template)
+		}
+	}
+
+	// Leaving the template untouched aborts, as with git commit
+	t.Setenv("EDITOR", "true")
+	if _, err := editMessage(nil); !errors.Is(err, errEmptyMessage) {
+		t.Errorf("expected errEmptyMessage for an untouched template, got %v", err)
+	}
+}
+
+func TestReadMessageFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "message.txt")
+	text := "\r\nCI build 412 (abc123)  \r\n\r\n# Changelog\r\n- Tighten safety rules\r\n\r\n"
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := "CI build 412 (abc123)\n\n# Changelog\n- Tighten safety rules"
+	if message, err := readMessageFile(path); err != nil || message != want {
+		t.Errorf("readMessageFile() = %q, %v, want %q", message, err, want)
+	}
+
+	// "-" reads stdin
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin })
+	w.WriteString("From stdin\nsecond line\n")
+	w.Close()
+	if message, err := readMessageFile("-"); err != nil || message != "From stdin\nsecond line" {
+		t.Errorf("readMessageFile(-) = %q, %v", message, err)
+	}
+
+	if err := os.WriteFile(path, []byte("\n  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readMessageFile(path); !errors.Is(err, errEmptyMessage) {
+		t.Errorf("expected errEmptyMessage, got %v", err)
+	}
+	if _, err := readMessageFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected a missing file to fail")
+	}
+}
+
+func TestMessageSubject(t *testing.T) {
+	for message, want := range map[string]string{
+		"":                              "",
+		"Weekly checkpoint":             "Weekly checkpoint",
+		"CI build 412\n\n# Changelog\n": "CI build 412",
+		"\n  Leading blank\nrest":       "Leading blank",
+	} {
+		if got := messageSubject(message); got != want {
+			t.Errorf("messageSubject(%q) = %q, want %q", message, got, want)
+		}
+	}
+}
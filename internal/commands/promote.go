@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/log"
+	"github.com/bulletproof-bot/backup/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewPromoteCommand creates the promote command
+func NewPromoteCommand() *cobra.Command {
+	var to string
+
+	cmd := &cobra.Command{
+		Use:   "promote <snapshot-id> --to <destination>",
+		Short: "Copy a stored snapshot to another destination",
+		Long: `Copy a stored snapshot to another destination, keeping its ID, message,
+labels and file manifest.
+
+Files are read from the configured destination, not the live agent, so you can
+take frequent cheap local snapshots and promote selected ones to a durable
+destination later.
+
+The target is a path or git URL. Git URLs (https://, ssh://, git@, *.git) are
+treated as git destinations and anything else as a local folder. Prefix the
+target with local:, git: or sync: to choose the type explicitly.
+
+Examples:
+  bulletproof promote 3 --to git@github.com:me/agent-backups.git
+  bulletproof promote 20250115-120000-000 --to git:~/durable-backups
+  bulletproof promote 1 --to /mnt/nas/bulletproof`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPromote(args[0], to)
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "Destination to copy the snapshot to (required)")
+	cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+func runPromote(snapshotID string, to string) error {
+	target, err := parseDestinationSpec(to)
+	if err != nil {
+		return err
+	}
+
+	// Track analytics
+	analytics.TrackCommand("promote", map[string]string{"to_type": target.Type})
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Destination != nil && cfg.Destination.Type == target.Type && cfg.Destination.Location() == target.Location() {
+		return fmt.Errorf("target is the configured destination; choose a different --to")
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("📤 Promoting snapshot %s to %s destination %s...\n", snapshotID, target.Type, target.Location())
+
+	snapshot, err := engine.Promote(snapshotID, target)
+	if err != nil {
+		return fmt.Errorf("promote failed: %w", err)
+	}
+
+	log.Println()
+	fmt.Printf("✅ Promoted snapshot %s (%d files)\n", snapshot.ID, len(snapshot.Files))
+	return nil
+}
+
+// parseDestinationSpec turns a --to value into a destination config. An explicit
+// "local:", "git:" or "sync:" prefix selects the type; otherwise git URLs are git
+// destinations, s3:// URLs are S3 buckets, sftp:// URLs are SFTP folders and
+// anything else is a local folder.
+func parseDestinationSpec(spec string) (*config.DestinationConfig, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("--to must name a destination")
+	}
+	if strings.HasPrefix(spec, "s3://") {
+		return config.NewDestinationConfig("s3", spec), nil
+	}
+	if strings.HasPrefix(spec, "sftp://") {
+		return config.NewDestinationConfig("sftp", spec), nil
+	}
+
+	destType := ""
+	for _, prefix := range []string{"local", "git", "sync"} {
+		if rest, ok := strings.CutPrefix(spec, prefix+":"); ok {
+			destType, spec = prefix, rest
+			break
+		}
+	}
+
+	isURL := strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") ||
+		strings.HasPrefix(spec, "git@") || strings.HasPrefix(spec, "ssh://")
+	if destType == "" {
+		destType = "local"
+		if isURL || strings.HasSuffix(spec, ".git") {
+			destType = "git"
+		}
+	}
+	if isURL && destType != "git" {
+		return nil, fmt.Errorf("%s destinations must be a folder path, not a URL", destType)
+	}
+
+	// Convert local paths to absolute, as init does (git remotes can be URLs)
+	if !isURL {
+		expanded, err := utils.ExpandPath(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination path: %w", err)
+		}
+		spec, err = filepath.Abs(expanded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination path: %w", err)
+		}
+	}
+
+	return config.NewDestinationConfig(destType, spec), nil
+}
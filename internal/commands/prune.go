@@ -2,9 +2,12 @@ package commands
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/bulletproof-bot/backup/internal/backup"
 	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/log"
 	"github.com/bulletproof-bot/backup/internal/types"
 	"github.com/spf13/cobra"
 )
@@ -12,6 +15,11 @@ import (
 // NewPruneCommand creates the prune command
 func NewPruneCommand() *cobra.Command {
 	var dryRun bool
+	var gc bool
+	var wait bool
+	var compare bool
+	var policies []string
+	var keep []string
 
 	cmd := &cobra.Command{
 		Use:   "prune",
@@ -23,24 +31,60 @@ The retention policy is configured in config.yaml and can include:
   - keep_daily: Keep one snapshot per day for N days
   - keep_weekly: Keep one snapshot per week for N weeks
   - keep_monthly: Keep one snapshot per month for N months
+  - max_age_days: Delete snapshots older than N days
+  - max_total_size_bytes: Delete the oldest snapshots until the rest fit
 
-Use --dry-run to see what would be deleted without actually deleting anything.`,
+The two caps apply to what the keep rules keep, or on their own to every
+snapshot, and never delete the newest snapshot.
+
+Snapshots listed in keep_ids, by full ID or label, are never deleted,
+whatever the rules and caps say. Add more for one run with --keep, by short
+or full ID or label:
+
+  bulletproof tag 3 golden
+  bulletproof prune --keep golden --keep 20260101-020000-000
+
+Use --dry-run to see what would be deleted without actually deleting anything:
+each snapshot's ID, time, message and size, and the space it would free.
+
+On git destinations, pruning deletes the snapshots' tags (and label tags),
+locally and on the remote, which keeps 'bulletproof snapshots' short. The
+commits stay in the branch history, so their files still take up space; add
+--gc to run git gc afterwards, which packs the repository.
+
+Use --compare to evaluate several candidate policies against your current
+snapshots, showing how many each keeps, the disk space retained and the
+oldest snapshot kept. It deletes nothing and works without a configured
+policy. Add your own candidates with --policy:
+
+  bulletproof prune --compare --policy keep_last=20 --policy keep_daily=14,keep_weekly=8`,
 		RunE: func(c *cobra.Command, args []string) error {
-			return runPrune(dryRun)
+			if compare {
+				return runPruneCompare(policies)
+			}
+			return runPrune(dryRun, gc, wait, keep)
 		},
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be deleted without actually deleting")
+	cmd.Flags().BoolVar(&gc, "gc", false, "On git destinations, run git gc after pruning")
+	cmd.Flags().BoolVar(&compare, "compare", false, "Compare candidate retention policies without deleting anything")
+	cmd.Flags().StringArrayVar(&policies, "policy", nil, "Extra policy to compare, e.g. keep_last=10,keep_daily=7 (repeatable)")
+	cmd.Flags().StringArrayVar(&keep, "keep", nil, "Never delete this snapshot, by ID or label (repeatable)")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for another bulletproof operation on the destination to finish instead of failing")
 
 	return cmd
 }
 
-func runPrune(dryRun bool) error {
+func runPrune(dryRun bool, gc bool, wait bool, keep []string) error {
 	// Load config
 	cfg, err := config.Load()
 	if err != nil {
 		return err
 	}
+	if gc && (cfg.Destination == nil || cfg.Destination.Type != "git") {
+		return fmt.Errorf("--gc only applies to git destinations; others free space as snapshots are deleted")
+	}
 
 	// Check if retention policy is enabled
 	if !cfg.Retention.Enabled {
@@ -70,42 +114,47 @@ func runPrune(dryRun bool) error {
 		return err
 	}
 
+	engine.SetWaitForLock(wait)
+
 	// Run prune
 	if dryRun {
-		fmt.Println("🔍 Dry run - showing what would be deleted...")
-		fmt.Println()
+		log.Println("🔍 DRY RUN — nothing deleted. Showing what would be deleted...")
+		log.Println()
 	} else {
-		fmt.Println("🗑️  Pruning old snapshots...")
-		fmt.Println()
+		log.Println("🗑️  Pruning old snapshots...")
+		log.Println()
 	}
 
-	result, err := engine.Prune(dryRun)
+	result, err := engine.Prune(dryRun, keep...)
 	if err != nil {
 		return err
 	}
 
 	// Display retention policy
-	fmt.Println("📋 Retention Policy:")
+	log.Println("📋 Retention Policy:")
 	if cfg.Retention.KeepLast > 0 {
-		fmt.Printf("  • Keep last %d snapshots\n", cfg.Retention.KeepLast)
+		log.Printf("  • Keep last %d snapshots\n", cfg.Retention.KeepLast)
 	}
 	if cfg.Retention.KeepDaily > 0 {
-		fmt.Printf("  • Keep daily snapshots for %d days\n", cfg.Retention.KeepDaily)
+		log.Printf("  • Keep daily snapshots for %d days\n", cfg.Retention.KeepDaily)
 	}
 	if cfg.Retention.KeepWeekly > 0 {
-		fmt.Printf("  • Keep weekly snapshots for %d weeks\n", cfg.Retention.KeepWeekly)
+		log.Printf("  • Keep weekly snapshots for %d weeks\n", cfg.Retention.KeepWeekly)
 	}
 	if cfg.Retention.KeepMonthly > 0 {
-		fmt.Printf("  • Keep monthly snapshots for %d months\n", cfg.Retention.KeepMonthly)
+		log.Printf("  • Keep monthly snapshots for %d months\n", cfg.Retention.KeepMonthly)
 	}
-	fmt.Println()
+	if pinned := append(append([]string{}, cfg.Retention.KeepIDs...), keep...); len(pinned) > 0 {
+		log.Printf("  • Never delete: %s\n", strings.Join(pinned, ", "))
+	}
+	log.Println()
 
 	// Display results
-	fmt.Printf("📊 Summary:\n")
-	fmt.Printf("  Total snapshots: %d\n", result.TotalSnapshots)
-	fmt.Printf("  Snapshots to keep: %d\n", len(result.SnapshotsToKeep))
-	fmt.Printf("  Snapshots to delete: %d\n", len(result.SnapshotsToDelete))
-	fmt.Println()
+	log.Printf("📊 Summary:\n")
+	log.Printf("  Total snapshots: %d\n", result.TotalSnapshots)
+	log.Printf("  Snapshots to keep: %d\n", len(result.SnapshotsToKeep))
+	log.Printf("  Snapshots to delete: %d\n", len(result.SnapshotsToDelete))
+	log.Println()
 
 	if len(result.SnapshotsToDelete) == 0 {
 		fmt.Println("✨ No snapshots to delete - all snapshots match retention policy")
@@ -123,18 +172,187 @@ func runPrune(dryRun bool) error {
 			shortID := shortIDs[snapshot.ID]
 			msg := ""
 			if snapshot.Message != "" {
-				msg = fmt.Sprintf(" - %s", snapshot.Message)
+				msg = fmt.Sprintf(" - %s", messageSubject(snapshot.Message))
+			}
+			size := ""
+			if snapshot.SizeBytes >= 0 {
+				size = ", " + formatBytes(snapshot.SizeBytes)
 			}
-			fmt.Printf("  [%d] %s%s (%d files)\n", shortID, snapshot.Timestamp.Format("2006-01-02 15:04:05"), msg, snapshot.FileCount)
+			fmt.Printf("  [%d] %s %s%s (%d files%s)\n", shortID, snapshot.ID, snapshot.Timestamp.Format("2006-01-02 15:04:05"), msg, snapshot.FileCount, size)
 		}
 		fmt.Println()
 	}
 
+	isGit := cfg.Destination.Type == "git"
+	switch {
+	case dryRun && isGit:
+		fmt.Println("🏷️  Would delete these snapshots' tags; their commits stay in the git history")
+	case dryRun:
+		fmt.Printf("💾 Would free up to %s (unchanged files shared with kept snapshots stay on disk)\n", formatBytes(result.DeletedBytes))
+	case isGit:
+		fmt.Println("🏷️  Deleted these snapshots' tags; their commits stay in the git history")
+	default:
+		fmt.Printf("💾 Freed up to %s (unchanged files shared with kept snapshots stay on disk)\n", formatBytes(result.DeletedBytes))
+	}
+
 	if dryRun {
+		fmt.Println()
+		fmt.Println("🔍 DRY RUN — nothing deleted")
 		fmt.Println("💡 Run without --dry-run to actually delete these snapshots")
-	} else {
-		fmt.Println("✅ Prune complete!")
+		return nil
 	}
 
+	if gc {
+		log.Println("🧹 Running git gc...")
+		if err := engine.Compact(); err != nil {
+			return err
+		}
+	}
+	fmt.Println("✅ Prune complete!")
 	return nil
 }
+
+func runPruneCompare(specs []string) error {
+	// Candidates: the configured policy first, then the built-in ones, then --policy
+	var policies []config.RetentionPolicy
+	var names []string
+	seen := make(map[string]bool)
+	add := func(policy config.RetentionPolicy, note string) {
+		name := describeRetentionPolicy(policy)
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		policies = append(policies, policy)
+		names = append(names, name+note)
+	}
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Retention.Enabled {
+		add(cfg.Retention, " (configured)")
+	}
+	for _, policy := range backup.CandidateRetentionPolicies {
+		add(policy, "")
+	}
+	for _, spec := range specs {
+		policy, err := parseRetentionPolicy(spec)
+		if err != nil {
+			return err
+		}
+		add(policy, "")
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	comparison, err := engine.CompareRetention(policies)
+	if err != nil {
+		return err
+	}
+
+	if comparison.TotalSnapshots == 0 {
+		fmt.Println("No backups found.")
+		return nil
+	}
+
+	fmt.Printf("📊 Comparing %d retention policies against %d snapshots (%s total)\n", len(policies), comparison.TotalSnapshots, formatBytes(comparison.TotalBytes))
+	fmt.Println("🔍 Estimate only - no snapshots are deleted")
+
+	for i, estimate := range comparison.Estimates {
+		fmt.Println()
+		fmt.Printf("📋 %s\n", names[i])
+		fmt.Printf("  Keeps %d, deletes %d\n", estimate.Kept, estimate.Deleted)
+		fmt.Printf("  Retains %s\n", formatBytes(estimate.RetainedBytes))
+		if estimate.OldestKept.IsZero() {
+			fmt.Println("  Oldest kept: none")
+		} else {
+			fmt.Printf("  Oldest kept: %s\n", estimate.OldestKept.Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("💡 Sizes add up each snapshot's files; destinations that share unchanged files (git) use less")
+	return nil
+}
+
+// retentionPolicyKeys are the --policy keys, matching the config file
+var retentionPolicyKeys = []string{"keep_last", "keep_daily", "keep_weekly", "keep_monthly", "max_total_size_bytes", "max_age_days"}
+
+// parseRetentionPolicy parses a --policy value such as "keep_last=10,keep_daily=7"
+func parseRetentionPolicy(spec string) (config.RetentionPolicy, error) {
+	policy := config.RetentionPolicy{Enabled: true}
+	for _, part := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if !ok || err != nil || n <= 0 {
+			return policy, fmt.Errorf("invalid --policy %q: expected key=N with N > 0, e.g. keep_last=10", spec)
+		}
+
+		switch strings.TrimSpace(key) {
+		case "keep_last":
+			policy.KeepLast = int(n)
+		case "keep_daily":
+			policy.KeepDaily = int(n)
+		case "keep_weekly":
+			policy.KeepWeekly = int(n)
+		case "keep_monthly":
+			policy.KeepMonthly = int(n)
+		case "max_total_size_bytes":
+			policy.MaxTotalSizeBytes = n
+		case "max_age_days":
+			policy.MaxAgeDays = int(n)
+		default:
+			return policy, fmt.Errorf("invalid --policy %q: unknown key %q (expected %s)", spec, key, strings.Join(retentionPolicyKeys, ", "))
+		}
+	}
+	return policy, nil
+}
+
+// describeRetentionPolicy renders a policy compactly, e.g. "last 10 + daily 7"
+func describeRetentionPolicy(policy config.RetentionPolicy) string {
+	var parts []string
+	if policy.KeepLast > 0 {
+		parts = append(parts, fmt.Sprintf("last %d", policy.KeepLast))
+	}
+	if policy.KeepDaily > 0 {
+		parts = append(parts, fmt.Sprintf("daily %d", policy.KeepDaily))
+	}
+	if policy.KeepWeekly > 0 {
+		parts = append(parts, fmt.Sprintf("weekly %d", policy.KeepWeekly))
+	}
+	if policy.KeepMonthly > 0 {
+		parts = append(parts, fmt.Sprintf("monthly %d", policy.KeepMonthly))
+	}
+	if policy.MaxAgeDays > 0 {
+		parts = append(parts, fmt.Sprintf("max %d days old", policy.MaxAgeDays))
+	}
+	if policy.MaxTotalSizeBytes > 0 {
+		parts = append(parts, fmt.Sprintf("max %s", formatBytes(policy.MaxTotalSizeBytes)))
+	}
+	if len(policy.KeepIDs) > 0 {
+		parts = append(parts, fmt.Sprintf("%d pinned", len(policy.KeepIDs)))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 MB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
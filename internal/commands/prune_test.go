@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	policy, err := parseRetentionPolicy("keep_last=10, keep_weekly=4")
+	if err != nil {
+		t.Fatalf("parseRetentionPolicy failed: %v", err)
+	}
+	want := config.RetentionPolicy{Enabled: true, KeepLast: 10, KeepWeekly: 4}
+	if !reflect.DeepEqual(policy, want) {
+		t.Errorf("got %+v, want %+v", policy, want)
+	}
+	if got := describeRetentionPolicy(policy); got != "last 10 + weekly 4" {
+		t.Errorf("describeRetentionPolicy = %q", got)
+	}
+
+	policy, err = parseRetentionPolicy("keep_last=5,max_age_days=90,max_total_size_bytes=10737418240")
+	if err != nil {
+		t.Fatalf("parseRetentionPolicy failed: %v", err)
+	}
+	want = config.RetentionPolicy{Enabled: true, KeepLast: 5, MaxAgeDays: 90, MaxTotalSizeBytes: 10 << 30}
+	if !reflect.DeepEqual(policy, want) {
+		t.Errorf("got %+v, want %+v", policy, want)
+	}
+	if got := describeRetentionPolicy(policy); got != "last 5 + max 90 days old + max 10.0 GB" {
+		t.Errorf("describeRetentionPolicy = %q", got)
+	}
+
+	for _, spec := range []string{"", "keep_last", "keep_last=0", "keep_yearly=2", "keep_daily=x", "max_age_days=-1"} {
+		if _, err := parseRetentionPolicy(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KB",
+		5 * 1024 * 1024: "5.0 MB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -1,11 +1,18 @@
 package commands
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/bulletproof-bot/backup/internal/analytics"
 	"github.com/bulletproof-bot/backup/internal/backup"
 	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
 	"github.com/spf13/cobra"
 )
 
@@ -15,14 +22,111 @@ func NewRestoreCommand() *cobra.Command {
 	var noScripts bool
 	var force bool
 	var target string
+	var scriptsDir string
+	var jsonOutput bool
+	var pathsFrom string
+	var file string
+	var ignoreMissing bool
+	var compareOnly bool
+	var preview bool
+	var previewPattern string
+	var skipVerify bool
+	var wait bool
+	var exportsOnly bool
 
 	cmd := &cobra.Command{
 		Use:   "restore <snapshot-id>",
 		Short: "Restore from a backup snapshot",
-		Long:  "Restore your OpenClaw installation from a specific backup snapshot.",
-		Args:  cobra.ExactArgs(1),
+		Long: `Restore your OpenClaw installation from a specific backup snapshot.
+
+With --paths-from, only the files listed in the given file (one relative path
+per line, "-" for stdin) are restored, and nothing is deleted. This suits
+targeted rollbacks of files known to be affected, e.g. after a compromise.
+
+With --file, only the files matching a path or glob are restored the same way,
+e.g. --file workspace/SOUL.md or --file 'skills/*.js'. A pattern matching a
+folder, such as --file 'workspace/skills/*', restores the whole subtree.
+
+With --compare-only, the full add/modify/remove plan is printed together with
+unified content diffs of modified files, and the command exits without
+creating a safety backup or changing anything.
+
+With --preview, the restore still asks for confirmation, but first prints
+unified content diffs of every file it would add, change or remove, so you can
+review exactly what it will overwrite, e.g. a SOUL.md that may have been
+tampered with in the backup. --preview-pattern limits the diffs to files
+matching a path or glob, as in diff; the restore itself is not limited.
+
+Before changing anything, the files stored for the snapshot are checked
+against the hashes recorded when it was taken. If any is missing or corrupted
+the restore stops and leaves the target as it was. --skip-verify skips the
+check, e.g. to restore a backup known to be damaged, and saves reading the
+files twice from remote, compressed or encrypted destinations.
+
+With --exports-only, no files are restored. Only the post-restore scripts run,
+with EXPORTS_DIR set to the _exports folder stored with the snapshot, to
+re-import external data such as a Neo4j or Pinecone dump when the agent files
+are fine but the database is not. BACKUP_DIR is the snapshot folder, as in a
+full restore, so import scripts written for restore work unchanged and
+$BACKUP_DIR/_exports is the same folder as $EXPORTS_DIR. It needs a
+timestamped local destination, the only kind that keeps each snapshot's
+exports.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runRestore(args[0], dryRun, noScripts, force, target)
+			if exportsOnly {
+				if compareOnly || preview || file != "" || pathsFrom != "" || jsonOutput || target != "" || noScripts {
+					return errors.New("--exports-only cannot be combined with --compare-only, --preview, --file, --paths-from, --json, --target or --no-scripts")
+				}
+				return runRestoreExports(args[0], dryRun, force, scriptsDir, wait)
+			}
+			if compareOnly {
+				if pathsFrom != "" || file != "" || jsonOutput {
+					return errors.New("--compare-only cannot be combined with --paths-from, --file or --json")
+				}
+				return runRestoreCompare(args[0], target)
+			}
+			if previewPattern != "" && !preview {
+				return errors.New("--preview-pattern requires --preview")
+			}
+			if preview && (dryRun || force || jsonOutput || pathsFrom != "" || file != "") {
+				return errors.New("--preview cannot be combined with --dry-run, --force, --json, --paths-from or --file (use --compare-only to see the diffs without restoring)")
+			}
+			if file != "" {
+				if pathsFrom != "" || jsonOutput || ignoreMissing {
+					return errors.New("--file cannot be combined with --paths-from, --json or --ignore-missing")
+				}
+				return runRestoreFile(args[0], file, dryRun, noScripts, force, target, skipVerify, wait)
+			}
+			if ignoreMissing && pathsFrom == "" {
+				return errors.New("--ignore-missing requires --paths-from")
+			}
+			if pathsFrom != "" {
+				if jsonOutput {
+					return errors.New("--json cannot be combined with --paths-from")
+				}
+				paths, err := readPathList(pathsFrom)
+				if err != nil {
+					return err
+				}
+				return runRestorePaths(args[0], paths, dryRun, noScripts, force, target, ignoreMissing, skipVerify, wait)
+			}
+			if jsonOutput {
+				if !dryRun {
+					return errors.New("--json requires --dry-run")
+				}
+				return runRestorePlan(args[0], target)
+			}
+			var previewMatch func(path string) bool
+			if preview {
+				previewMatch = func(string) bool { return true }
+				if previewPattern != "" {
+					if err := types.ValidatePattern(previewPattern); err != nil {
+						return err
+					}
+					previewMatch = func(path string) bool { return types.MatchPath(path, previewPattern) }
+				}
+			}
+			return runRestore(args[0], dryRun, noScripts, force, target, scriptsDir, previewMatch, skipVerify, wait)
 		},
 	}
 
@@ -30,11 +134,22 @@ func NewRestoreCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&noScripts, "no-scripts", false, "Skip post-restore script execution")
 	cmd.Flags().BoolVar(&force, "force", false, "Skip confirmation prompts")
 	cmd.Flags().StringVar(&target, "target", "", "Restore to alternative location instead of OpenClaw path")
+	cmd.Flags().StringVar(&scriptsDir, "scripts-dir", "", "Scripts directory that configured script commands refer to")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "With --dry-run, print the restore plan as JSON")
+	cmd.Flags().StringVar(&pathsFrom, "paths-from", "", "Restore only the files listed in this file, one per line (- for stdin)")
+	cmd.Flags().StringVar(&file, "file", "", "Restore only the files matching this path or glob, e.g. 'skills/*.js'")
+	cmd.Flags().BoolVar(&compareOnly, "compare-only", false, "Print what a restore would change, with content diffs, and exit without changing anything")
+	cmd.Flags().BoolVar(&preview, "preview", false, "Print content diffs of what the restore would change before asking for confirmation")
+	cmd.Flags().StringVar(&previewPattern, "preview-pattern", "", "With --preview, only show diffs of files matching this path or glob")
+	cmd.Flags().BoolVar(&ignoreMissing, "ignore-missing", false, "With --paths-from, skip listed paths the backup does not contain instead of failing")
+	cmd.Flags().BoolVar(&skipVerify, "skip-verify", false, "Restore without first checking the stored files against their recorded hashes")
+	cmd.Flags().BoolVar(&exportsOnly, "exports-only", false, "Restore no files, only run the post-restore scripts on the snapshot's _exports")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for another bulletproof operation on the destination to finish instead of failing")
 
 	return cmd
 }
 
-func runRestore(snapshotID string, dryRun bool, noScripts bool, force bool, target string) error {
+func runRestore(snapshotID string, dryRun bool, noScripts bool, force bool, target string, scriptsDir string, previewMatch func(path string) bool, skipVerify bool, wait bool) error {
 	// Track analytics
 	flags := make(map[string]string)
 	if dryRun {
@@ -49,6 +164,18 @@ func runRestore(snapshotID string, dryRun bool, noScripts bool, force bool, targ
 	if target != "" {
 		flags["target"] = "true"
 	}
+	if scriptsDir != "" {
+		flags["scripts-dir"] = "true"
+	}
+	if previewMatch != nil {
+		flags["preview"] = "true"
+	}
+	if skipVerify {
+		flags["skip-verify"] = "true"
+	}
+	if wait {
+		flags["wait"] = "true"
+	}
 	analytics.TrackCommand("restore", flags)
 
 	// Load config
@@ -57,12 +184,20 @@ func runRestore(snapshotID string, dryRun bool, noScripts bool, force bool, targ
 		return err
 	}
 
+	if err := applyScriptsDir(cfg, scriptsDir); err != nil {
+		return err
+	}
+
 	// Create backup engine
 	engine, err := backup.NewBackupEngine(cfg)
 	if err != nil {
 		return err
 	}
 
+	engine.SetRestorePreview(previewMatch)
+	engine.SetSkipRestoreVerify(skipVerify)
+	engine.SetWaitForLock(wait)
+
 	// Run restore (force flag controls script execution warnings)
 	if err := engine.RestoreToTarget(snapshotID, target, dryRun, noScripts, force); err != nil {
 		return fmt.Errorf("restore failed: %w", err)
@@ -70,3 +205,202 @@ func runRestore(snapshotID string, dryRun bool, noScripts bool, force bool, targ
 
 	return nil
 }
+
+// runRestorePlan prints the restore plan as JSON.
+// It has no side effects: analytics are not tracked because the first-run
+// notice would write to stdout and the config file.
+func runRestorePlan(snapshotID string, target string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	plan, err := engine.PlanRestore(snapshotID, target)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
+
+func runRestoreCompare(snapshotID string, target string) error {
+	// Track analytics
+	flags := map[string]string{"compare-only": "true"}
+	if target != "" {
+		flags["target"] = "true"
+	}
+	analytics.TrackCommand("restore", flags)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	if _, err := engine.CompareRestore(snapshotID, target); err != nil {
+		return fmt.Errorf("compare failed: %w", err)
+	}
+	return nil
+}
+
+func runRestorePaths(snapshotID string, paths []string, dryRun bool, noScripts bool, force bool, target string, ignoreMissing bool, skipVerify bool, wait bool) error {
+	// Track analytics
+	flags := map[string]string{"paths-from": "true"}
+	if dryRun {
+		flags["dry-run"] = "true"
+	}
+	if noScripts {
+		flags["no-scripts"] = "true"
+	}
+	if force {
+		flags["force"] = "true"
+	}
+	if target != "" {
+		flags["target"] = "true"
+	}
+	if ignoreMissing {
+		flags["ignore-missing"] = "true"
+	}
+	if skipVerify {
+		flags["skip-verify"] = "true"
+	}
+	if wait {
+		flags["wait"] = "true"
+	}
+	analytics.TrackCommand("restore", flags)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	engine.SetSkipRestoreVerify(skipVerify)
+	engine.SetWaitForLock(wait)
+	if err := engine.RestorePaths(snapshotID, paths, target, dryRun, noScripts, force, ignoreMissing); err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return nil
+}
+
+func runRestoreFile(snapshotID string, pattern string, dryRun bool, noScripts bool, force bool, target string, skipVerify bool, wait bool) error {
+	// Track analytics
+	flags := map[string]string{"file": "true"}
+	if dryRun {
+		flags["dry-run"] = "true"
+	}
+	if noScripts {
+		flags["no-scripts"] = "true"
+	}
+	if force {
+		flags["force"] = "true"
+	}
+	if target != "" {
+		flags["target"] = "true"
+	}
+	if skipVerify {
+		flags["skip-verify"] = "true"
+	}
+	if wait {
+		flags["wait"] = "true"
+	}
+	analytics.TrackCommand("restore", flags)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	engine.SetSkipRestoreVerify(skipVerify)
+	engine.SetWaitForLock(wait)
+	if err := engine.RestoreFile(snapshotID, pattern, target, dryRun, noScripts, force); err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return nil
+}
+
+func runRestoreExports(snapshotID string, dryRun bool, force bool, scriptsDir string, wait bool) error {
+	// Track analytics
+	flags := map[string]string{"exports-only": "true"}
+	if dryRun {
+		flags["dry-run"] = "true"
+	}
+	if force {
+		flags["force"] = "true"
+	}
+	if scriptsDir != "" {
+		flags["scripts-dir"] = "true"
+	}
+	if wait {
+		flags["wait"] = "true"
+	}
+	analytics.TrackCommand("restore", flags)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if err := applyScriptsDir(cfg, scriptsDir); err != nil {
+		return err
+	}
+
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	engine.SetWaitForLock(wait)
+	if err := engine.RestoreExports(snapshotID, dryRun, force); err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return nil
+}
+
+// readPathList reads newline-separated paths from a file, or from stdin when
+// name is "-". Blank lines are skipped.
+func readPathList(name string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open path list: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read path list: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("path list %s is empty", name)
+	}
+	return paths, nil
+}
@@ -62,6 +62,13 @@ func NewScheduleStatusCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "status",
 		Short: "Show schedule status",
+		Long: `Show the configured schedule and check it against the scheduled job
+actually installed on this machine.
+
+A systemd timer, launchd agent or scheduled task can be removed or fail to
+load without the config knowing, leaving backups silently stopped. status
+reports whether the job exists, is enabled and runs at the configured time,
+and what to run if it does not.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runScheduleStatus()
 		},
@@ -131,6 +138,9 @@ func runScheduleStatus() error {
 	} else {
 		fmt.Println("Status: ❌ Disabled")
 	}
+	if health := scheduleHealth(cfg.Schedule, platform.VerifyAutoBackup(cfg.Schedule.Time)); health != "" {
+		fmt.Printf("Scheduled job: %s\n", health)
+	}
 
 	fmt.Println("\nTo change schedule settings:")
 	fmt.Println("  bulletproof schedule enable --time HH:MM")
@@ -139,6 +149,27 @@ func runScheduleStatus() error {
 	return nil
 }
 
+// scheduleHealth compares the configured schedule with the scheduled job
+// installed on this machine, returning nothing when both are off
+func scheduleHealth(schedule config.ScheduleConfig, job platform.AutoBackupStatus) string {
+	switch {
+	case !schedule.Enabled && job.Installed:
+		return fmt.Sprintf("⚠️  config says disabled but a %s is still installed - run 'bulletproof schedule disable'", job.Kind)
+	case !schedule.Enabled:
+		return ""
+	case !job.Installed:
+		return fmt.Sprintf("⚠️  config says enabled but no timer found - run 'bulletproof schedule enable --time %s'", schedule.Time)
+	case !job.Active:
+		return fmt.Sprintf("⚠️  %s is installed but not enabled - run 'bulletproof schedule enable --time %s'", job.Kind, schedule.Time)
+	case job.Time == "":
+		return fmt.Sprintf("⚠️  %s is installed but its time could not be read - run 'bulletproof schedule enable --time %s' to reinstall it", job.Kind, schedule.Time)
+	case !job.MatchesConfig:
+		return fmt.Sprintf("⚠️  %s runs at %s but config says %s - run 'bulletproof schedule enable --time %s'", job.Kind, job.Time, schedule.Time, schedule.Time)
+	default:
+		return fmt.Sprintf("✅ %s installed and matches config", job.Kind)
+	}
+}
+
 // isValidTime validates HH:MM format
 func isValidTime(timeStr string) bool {
 	if len(timeStr) != 5 {
@@ -3,9 +3,11 @@ package commands
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/platform"
 )
 
 // setupTestConfig creates a temporary HOME directory for testing
@@ -253,3 +255,34 @@ func TestScheduleStatus(t *testing.T) {
 		t.Errorf("runScheduleStatus failed: %v", err)
 	}
 }
+
+func TestScheduleHealth(t *testing.T) {
+	enabled := config.ScheduleConfig{Enabled: true, Time: "03:00"}
+	disabled := config.ScheduleConfig{Time: "03:00"}
+	timer := platform.AutoBackupStatus{Installed: true, Kind: "systemd timer", Active: true, Time: "03:00", MatchesConfig: true}
+	moved := timer
+	moved.Time, moved.MatchesConfig = "14:00", false
+	stopped := timer
+	stopped.Active = false
+
+	tests := []struct {
+		name     string
+		schedule config.ScheduleConfig
+		job      platform.AutoBackupStatus
+		want     string
+	}{
+		{"matching", enabled, timer, "installed and matches config"},
+		{"missing", enabled, platform.AutoBackupStatus{}, "config says enabled but no timer found"},
+		{"different time", enabled, moved, "runs at 14:00 but config says 03:00"},
+		{"not enabled", enabled, stopped, "installed but not enabled"},
+		{"left installed", disabled, timer, "config says disabled but a systemd timer is still installed"},
+	}
+	for _, tt := range tests {
+		if got := scheduleHealth(tt.schedule, tt.job); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: scheduleHealth() = %q, want it to contain %q", tt.name, got, tt.want)
+		}
+	}
+	if got := scheduleHealth(disabled, platform.AutoBackupStatus{}); got != "" {
+		t.Errorf("scheduleHealth() = %q for a disabled schedule with no job, want nothing", got)
+	}
+}
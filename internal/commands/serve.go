@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/daemon"
+	"github.com/bulletproof-bot/backup/internal/platform"
+	"github.com/spf13/cobra"
+)
+
+// NewServeCommand creates the serve command
+func NewServeCommand() *cobra.Command {
+	var listen string
+	var noSchedule bool
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run as a daemon with a local API",
+		Long: `Run bulletproof as a long-lived daemon.
+
+Unless --no-schedule is given, the daemon runs the configured backup schedule
+itself. When the platform job installed by "bulletproof schedule enable" is
+active, the daemon leaves the schedule to it so backups do not run twice. It
+also exposes a local HTTP API so other processes can request
+backups or query drift:
+
+  POST /backup      Trigger a backup (JSON body: message, force, no_scripts)
+  GET  /status      Daemon status, last backup, and next scheduled run
+  GET  /snapshots   List snapshots
+  GET  /diff        Changed files (query: from, to; defaults 1 and 0)
+
+By default the API listens on a Unix socket at ~/.config/bulletproof/bulletproof.sock.
+Use --listen with a path, unix:<path>, or host:port to change it. An existing
+socket is only replaced when no daemon answers on it. Addresses other than
+loopback are refused unless $BULLETPROOF_API_TOKEN is set; every request must
+then send it as "Authorization: Bearer <token>".
+
+On SIGINT or SIGTERM the daemon stops accepting requests and waits for any
+running backup to finish before exiting.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(listen, noSchedule)
+		},
+	}
+
+	cmd.Flags().StringVar(&listen, "listen", "", "Socket path or host:port to listen on")
+	cmd.Flags().BoolVar(&noSchedule, "no-schedule", false, "Only serve the API; leave scheduled backups to the platform scheduler")
+
+	return cmd
+}
+
+func runServe(listen string, noSchedule bool) error {
+	flags := make(map[string]string)
+	if noSchedule {
+		flags["no-schedule"] = "true"
+	}
+	analytics.TrackCommand("serve", flags)
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	if listen == "" {
+		configDir, err := config.ConfigDir()
+		if err != nil {
+			return err
+		}
+		listen = filepath.Join(configDir, "bulletproof.sock")
+	}
+
+	token := os.Getenv(daemon.TokenEnv)
+	listener, err := daemon.Listen(listen, token)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("🛡️  Bulletproof daemon listening on %s\n", listen)
+	schedule := !noSchedule && cfg.Schedule.Enabled
+	if schedule {
+		var note string
+		schedule, note = serveSchedule(cfg.Schedule, platform.VerifyAutoBackup(cfg.Schedule.Time))
+		fmt.Println(note)
+	}
+
+	server := daemon.NewServer(engine, schedule)
+	server.SetToken(token)
+	if err := server.Serve(ctx, listener); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Daemon stopped")
+	return nil
+}
+
+// serveSchedule decides whether the daemon runs the enabled schedule itself.
+// An active platform job from schedule enable already runs it, and both
+// would back up twice a day.
+func serveSchedule(schedule config.ScheduleConfig, job platform.AutoBackupStatus) (bool, string) {
+	if job.Installed && job.Active {
+		return false, fmt.Sprintf("⏰ The %s installed by 'bulletproof schedule enable' runs the daily backups; the daemon only serves the API (run 'bulletproof schedule disable' to let the daemon run them)", job.Kind)
+	}
+	return true, fmt.Sprintf("⏰ Daily backups at %s", schedule.Time)
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/platform"
+)
+
+func TestServeSchedule(t *testing.T) {
+	schedule := config.ScheduleConfig{Enabled: true, Time: "03:00"}
+
+	tests := []struct {
+		name    string
+		job     platform.AutoBackupStatus
+		wantRun bool
+		want    string
+	}{
+		{"no job", platform.AutoBackupStatus{}, true, "Daily backups at 03:00"},
+		{"inactive timer", platform.AutoBackupStatus{Installed: true, Kind: "systemd timer"}, true, "Daily backups at 03:00"},
+		{"active cron job", platform.AutoBackupStatus{Installed: true, Kind: "cron job", Active: true, Time: "03:00"}, false, "cron job"},
+	}
+	for _, tt := range tests {
+		run, note := serveSchedule(schedule, tt.job)
+		if run != tt.wantRun || !strings.Contains(note, tt.want) {
+			t.Errorf("%s: serveSchedule() = %v, %q, want %v and a note containing %q", tt.name, run, note, tt.wantRun, tt.want)
+		}
+	}
+}
@@ -471,9 +471,8 @@ Moving your agent backup to a new machine or platform.
   # Create final backup with scripts
   bulletproof backup -m "Pre-migration backup"
 
-  # Copy backup to portable drive or cloud
-  # For local destination:
-  cp -r /path/to/backups/20260204-143000 /media/usb/agent-backup/
+  # Package the snapshot into one archive on a portable drive
+  bulletproof export 1 --format tar -o /media/usb/agent.tar.gz
 
   # For git destination (already remote):
   git push  # Backup is already in cloud
@@ -483,19 +482,21 @@ Moving your agent backup to a new machine or platform.
   # Install Bulletproof
   curl -sSL https://bulletproof-bot.github.io/install.sh | bash
 
-  # Copy backup from portable drive
-  cp -r /media/usb/agent-backup/20260204-143000 /home/newuser/backups/
+  # Unpack the archive; it holds one folder named after the snapshot
+  tar xzf /media/usb/agent.tar.gz -C /home/newuser/
 
   # Initialize from backup
-  bulletproof init --from-backup /home/newuser/backups/20260204-143000
+  bulletproof init --from-backup /home/newuser/20260204-143000
 
   # This will:
   # - Read .bulletproof/config.yaml from the backup
   # - Copy .bulletproof/scripts/ to new config location
-  # - Prompt to adjust paths for new machine
+  # - Prompt to adjust paths for new machine, suggesting the original
+  #   path under your new home directory
   # - Validate agent destination exists
 
-  # Restore agent files
+  # Add the snapshot to the new destination and restore agent files
+  bulletproof import /media/usb/agent.tar.gz
   bulletproof restore 1
 
   # Verify agent functionality
@@ -547,7 +548,7 @@ Configure scheduled backups using platform-specific tools.
   [Service]
   Type=oneshot
   User=openclaw
-  ExecStart=/usr/local/bin/bulletproof backup
+  ExecStart=/usr/local/bin/bulletproof backup --quiet
   StandardOutput=journal
   StandardError=journal
 
@@ -586,6 +587,7 @@ Configure scheduled backups using platform-specific tools.
       <array>
           <string>/usr/local/bin/bulletproof</string>
           <string>backup</string>
+          <string>--quiet</string>
       </array>
       <key>StartCalendarInterval</key>
       <dict>
@@ -646,8 +648,8 @@ Configure scheduled backups using platform-specific tools.
   # Initialize configuration
   bulletproof init
 
-  # Create backup
-  bulletproof backup
+  # Create backup (always pass -m when running non-interactively;
+  # without it the message is read from a piped stdin or $EDITOR)
   bulletproof backup -m "Custom message"
   bulletproof backup --force          # Force backup even if no changes
 
@@ -688,6 +690,11 @@ Configure scheduled backups using platform-specific tools.
 6. Found it: bulletproof diff <bad> <good> shows exact change
 7. Restore: bulletproof restore <good>
 
+Line-level diffs need the file contents of both snapshots. Local and git
+destinations read them back, but sync folders keep only the latest snapshot's
+files and manifest-only snapshots keep none. Set options.store_content: true to
+store small text files in each manifest so their diffs always work.
+
 ### Snapshot ID Types
 
 - 0 - Current filesystem state (not an actual snapshot)
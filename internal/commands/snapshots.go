@@ -4,7 +4,10 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/bulletproof-bot/backup/internal/backup"
 	"github.com/bulletproof-bot/backup/internal/config"
@@ -15,22 +18,105 @@ import (
 // NewSnapshotsCommand creates the snapshots command
 func NewSnapshotsCommand() *cobra.Command {
 	var format string
+	var jsonOutput bool
+	var diffStat bool
+	var limit int
+	var labels, labelFilter []string
+	var wide bool
+	var tree bool
+	var depth int
+	var lineage bool
 
 	cmd := &cobra.Command{
-		Use:   "snapshots",
+		Use:   "snapshots [snapshot-id]",
 		Short: "List all backup snapshots",
-		Long:  "List all available backup snapshots with timestamps and file counts.",
+		Long: `List all available backup snapshots with timestamps and file counts.
+
+With --diff-stat, each snapshot also shows how much it changed relative to
+the snapshot before it as +added ~modified -removed. Stats are only computed
+for the listed snapshots and are cached, so use -n to keep long lists fast.
+
+With --label (or --tag), only snapshots with that label are listed, whether
+it was given at backup time (bulletproof backup --tag) or later (bulletproof
+tag); repeat it to require several labels. Short IDs stay the same as in the
+unfiltered list.
+
+With --wide, each snapshot also shows where it was taken: the
+absolute path, or the sources of a multi-source snapshot, and the machine,
+operating system and bulletproof version. Snapshots taken before this was
+recorded show only what they have.
+
+With --json (short for --format json), only a JSON array of the snapshots
+is printed, with RFC3339 timestamps, for scripts to parse.
+
+With a snapshot ID, that snapshot's details are shown, including the whole
+of a multi-line message, of which the listing only shows the first line.
+
+With a snapshot ID and --tree, that snapshot's files are shown as a directory
+tree with file counts and sizes per folder. The tree is read from the
+snapshot's manifest, so no files are fetched from the destination. Use
+--depth to collapse folders below a level.
+
+With --lineage, a snapshot's chain of parents is listed back to the first
+backup: each snapshot records the one that was latest when it was taken.
+Without an ID, the chain starts at the destination's latest snapshot, and a
+warning is shown if a newer snapshot is stored that the latest pointer
+misses.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(c *cobra.Command, args []string) error {
-			return runSnapshots(format, args)
+			format, err := snapshotsFormat(format, jsonOutput, c.Flags().Changed("format"))
+			if err != nil {
+				return err
+			}
+			if lineage {
+				if tree {
+					return fmt.Errorf("--lineage cannot be combined with --tree")
+				}
+				snapshotID := ""
+				if len(args) > 0 {
+					snapshotID = args[0]
+				}
+				return runSnapshotLineage(snapshotID, format)
+			}
+			if tree {
+				if len(args) != 1 {
+					return fmt.Errorf("--tree needs a snapshot ID, e.g. bulletproof snapshots 1 --tree")
+				}
+				return runSnapshotTree(args[0], format, depth)
+			}
+			if len(args) > 0 {
+				return runSnapshotDetail(args[0], format)
+			}
+			return runSnapshots(format, diffStat, limit, append(labels, labelFilter...), wide)
 		},
 	}
 
 	cmd.Flags().StringVar(&format, "format", "text", "Output format: text, json, or csv")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the snapshots as a JSON array (same as --format json)")
+	cmd.Flags().BoolVar(&diffStat, "diff-stat", false, "Show changes relative to the previous snapshot")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 0, "Only list the N most recent snapshots (0 = all)")
+	cmd.Flags().StringArrayVar(&labelFilter, "label", nil, "Only list snapshots with this label (repeatable)")
+	cmd.Flags().StringArrayVar(&labels, "tag", nil, "Same as --label")
+	cmd.Flags().BoolVar(&wide, "wide", false, "Show the path, machine and OS each snapshot was taken on")
+	cmd.Flags().BoolVar(&tree, "tree", false, "Show one snapshot's files as a directory tree")
+	cmd.Flags().IntVar(&depth, "depth", 0, "With --tree, only descend this many levels (0 = all)")
+	cmd.Flags().BoolVar(&lineage, "lineage", false, "Follow a snapshot's parents back to the first backup")
 
 	return cmd
 }
 
-func runSnapshots(format string, args []string) error {
+// snapshotsFormat resolves --json against an explicit --format
+func snapshotsFormat(format string, jsonOutput, formatSet bool) (string, error) {
+	if !jsonOutput {
+		return format, nil
+	}
+	if formatSet && format != "json" {
+		return "", fmt.Errorf("--json conflicts with --format %s", format)
+	}
+	return "json", nil
+}
+
+func runSnapshots(format string, diffStat bool, limit int, labels []string, wide bool) error {
 	// Load config
 	cfg, err := config.Load()
 	if err != nil {
@@ -57,7 +143,7 @@ func runSnapshots(format string, args []string) error {
 		} else if format == "csv" {
 			// Output CSV header even if empty
 			w := csv.NewWriter(os.Stdout)
-			w.Write([]string{"short_id", "full_id", "timestamp", "message", "file_count"})
+			w.Write(csvHeader(diffStat, wide))
 			w.Flush()
 		}
 		return nil
@@ -66,20 +152,395 @@ func runSnapshots(format string, args []string) error {
 	// Assign short IDs (1=latest, 2=second-latest, etc.)
 	shortIDs := types.AssignShortIDs(backups)
 
+	// Order newest first so each snapshot's predecessor is the next entry
+	ordered := make([]*types.SnapshotInfo, len(backups))
+	copy(ordered, backups)
+	sort.Slice(ordered, func(i, j int) bool {
+		return shortIDs[ordered[i].ID] < shortIDs[ordered[j].ID]
+	})
+
+	listed := ordered
+	if len(labels) > 0 {
+		listed = nil
+		for _, b := range ordered {
+			if b.HasLabels(labels) {
+				listed = append(listed, b)
+			}
+		}
+	}
+	if len(listed) == 0 && format == "text" {
+		fmt.Printf("No backups labeled %s.\n", strings.Join(labels, ", "))
+		return nil
+	}
+	if limit > 0 && limit < len(listed) {
+		listed = listed[:limit]
+	}
+
+	// Only compute stats for listed snapshots; the oldest snapshot has none.
+	// A snapshot's predecessor is the next older one, whether listed or not.
+	var stats map[string]*types.ChangeStats
+	if diffStat {
+		position := make(map[string]int, len(ordered))
+		for i, b := range ordered {
+			position[b.ID] = i
+		}
+		stats = make(map[string]*types.ChangeStats)
+		for _, b := range listed {
+			i := position[b.ID]
+			if i+1 >= len(ordered) {
+				continue
+			}
+			s, err := engine.DiffStat(ordered[i+1].ID, b.ID)
+			if err != nil {
+				return err
+			}
+			stats[b.ID] = &s
+		}
+	}
+
+	// Origins come from each listed snapshot's manifest, so only read them when asked
+	var origins map[string]snapshotProvenance
+	if wide {
+		origins = make(map[string]snapshotProvenance, len(listed))
+		for _, b := range listed {
+			snapshot, err := engine.GetSnapshot(b.ID)
+			if err != nil {
+				return err
+			}
+			origins[b.ID] = provenanceOf(snapshot)
+		}
+	}
+
 	// Output based on format
 	switch format {
 	case "json":
-		return outputJSON(backups, shortIDs)
+		return outputJSON(listed, shortIDs, stats, origins)
 	case "csv":
-		return outputCSV(backups, shortIDs)
+		return outputCSV(listed, shortIDs, stats, origins)
 	case "text":
 		fallthrough
 	default:
-		return outputText(backups, shortIDs)
+		return outputText(listed, shortIDs, stats, origins)
+	}
+}
+
+// runSnapshotDetail shows one snapshot with its full message
+func runSnapshotDetail(snapshotID string, format string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := engine.GetSnapshot(snapshotID)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+
+	switch format {
+	case "json":
+		provenance := provenanceOf(snapshot)
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			FullID       string   `json:"full_id"`
+			Timestamp    string   `json:"timestamp"`
+			Message      string   `json:"message,omitempty"`
+			Parent       string   `json:"parent,omitempty"`
+			FileCount    int      `json:"file_count"`
+			SizeBytes    int64    `json:"size_bytes"`
+			Labels       []string `json:"labels,omitempty"`
+			Origin       string   `json:"original_root,omitempty"`
+			Hostname     string   `json:"hostname,omitempty"`
+			OS           string   `json:"os,omitempty"`
+			CreatedBy    string   `json:"created_by,omitempty"`
+			ManifestOnly bool     `json:"manifest_only,omitempty"`
+		}{
+			FullID:       snapshot.ID,
+			Timestamp:    snapshot.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+			Message:      snapshot.Message,
+			Parent:       snapshot.Parent,
+			FileCount:    len(snapshot.Files),
+			SizeBytes:    snapshot.TotalSize(),
+			Labels:       snapshot.Labels,
+			Origin:       provenance.Origin,
+			Hostname:     provenance.Hostname,
+			OS:           provenance.OS,
+			CreatedBy:    provenance.CreatedBy,
+			ManifestOnly: snapshot.ManifestOnly,
+		})
+	case "text":
+		printSnapshotDetail(os.Stdout, snapshot)
+		return nil
+	default:
+		return fmt.Errorf("a single snapshot supports text and json output, not %s", format)
+	}
+}
+
+// printSnapshotDetail prints a snapshot's details, then its message in full,
+// indented as git log does
+func printSnapshotDetail(w io.Writer, snapshot *types.Snapshot) {
+	fmt.Fprintf(w, "Snapshot %s\n", snapshot.ID)
+	fmt.Fprintf(w, "Date:    %s\n", snapshot.Timestamp.Local().Format("2006-01-02 15:04:05"))
+	kind := ""
+	if snapshot.ManifestOnly {
+		kind = ", manifest only"
+	}
+	fmt.Fprintf(w, "Files:   %d (%s%s)\n", len(snapshot.Files), formatBytes(snapshot.TotalSize()), kind)
+	if len(snapshot.Labels) > 0 {
+		fmt.Fprintf(w, "Labels:  %s\n", strings.Join(snapshot.Labels, ", "))
+	}
+	fmt.Fprintf(w, "From:    %s\n", provenanceOf(snapshot))
+	if snapshot.Parent != "" {
+		fmt.Fprintf(w, "Parent:  %s\n", snapshot.Parent)
+	}
+
+	if snapshot.Message != "" {
+		fmt.Fprintln(w)
+		for _, line := range strings.Split(snapshot.Message, "\n") {
+			if line == "" {
+				fmt.Fprintln(w)
+				continue
+			}
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
+
+// runSnapshotLineage lists a snapshot and its ancestors, newest first
+func runSnapshotLineage(snapshotID string, format string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	lineage, err := engine.Lineage(snapshotID)
+	if err != nil {
+		return err
+	}
+	backups, err := engine.ListBackups()
+	if err != nil {
+		return err
+	}
+	shortIDs := types.AssignShortIDs(backups)
+
+	switch format {
+	case "json":
+		type lineageEntry struct {
+			ID        int    `json:"id,omitempty"`
+			FullID    string `json:"full_id"`
+			Timestamp string `json:"timestamp"`
+			Message   string `json:"message,omitempty"`
+			Parent    string `json:"parent,omitempty"`
+		}
+		entries := make([]lineageEntry, 0, len(lineage.Snapshots))
+		for _, snapshot := range lineage.Snapshots {
+			entries = append(entries, lineageEntry{
+				ID:        shortIDs[snapshot.ID],
+				FullID:    snapshot.ID,
+				Timestamp: snapshot.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+				Message:   snapshot.Message,
+				Parent:    snapshot.Parent,
+			})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Snapshots       []lineageEntry `json:"snapshots"`
+			MissingParent   string         `json:"missing_parent,omitempty"`
+			NewerThanLatest string         `json:"newer_than_latest,omitempty"`
+		}{entries, lineage.MissingParent, lineage.NewerThanLatest})
+	case "text":
+		printLineage(os.Stdout, lineage, shortIDs)
+		return nil
+	default:
+		return fmt.Errorf("--lineage supports text and json output, not %s", format)
+	}
+}
+
+// printLineage lists each snapshot of the chain on one line, newest first,
+// then where the chain ends
+func printLineage(w io.Writer, lineage *backup.Lineage, shortIDs map[string]int) {
+	if lineage.NewerThanLatest != "" {
+		fmt.Fprintf(w, "⚠️  The latest pointer names %s, but %s is newer\n\n", lineage.Snapshots[0].ID, lineage.NewerThanLatest)
+	}
+
+	for _, snapshot := range lineage.Snapshots {
+		shortID := "-"
+		if id, ok := shortIDs[snapshot.ID]; ok {
+			shortID = fmt.Sprintf("%d", id)
+		}
+		line := fmt.Sprintf("  %3s  %s  %s", shortID, snapshot.ID, snapshot.Timestamp.Local().Format("2006-01-02 15:04:05"))
+		if subject := messageSubject(snapshot.Message); subject != "" {
+			line += "  " + subject
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	switch {
+	case lineage.MissingParent != "":
+		fmt.Fprintf(w, "       ↳ parent %s is no longer stored\n", lineage.MissingParent)
+	case len(lineage.Snapshots) == 1:
+		fmt.Fprintln(w, "       ↳ no recorded parent")
+	}
+}
+
+func runSnapshotTree(snapshotID string, format string, depth int) error {
+	if depth < 0 {
+		return fmt.Errorf("--depth must not be negative")
+	}
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := engine.GetSnapshot(snapshotID)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+
+	tree := types.NewFileTree(snapshot.ID, snapshot.Files)
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(tree)
+	case "text":
+		printFileTree(os.Stdout, tree, depth)
+		return nil
+	default:
+		return fmt.Errorf("--tree supports text and json output, not %s", format)
+	}
+}
+
+// printFileTree draws a file tree like the tree command. Directories show their
+// file count and total size; directories at the depth limit are not expanded.
+func printFileTree(w io.Writer, tree *types.FileTree, depth int) {
+	fmt.Fprintf(w, "%s (%s)\n", tree.Name, describeTreeNode(tree))
+
+	var walk func(node *types.FileTree, prefix string, level int)
+	walk = func(node *types.FileTree, prefix string, level int) {
+		for i, child := range node.Children {
+			branch, indent := "├── ", "│   "
+			if i == len(node.Children)-1 {
+				branch, indent = "└── ", "    "
+			}
+
+			name := child.Name
+			if child.Dir {
+				name += "/"
+			}
+			fmt.Fprintf(w, "%s%s%s (%s)\n", prefix, branch, name, describeTreeNode(child))
+
+			if child.Dir && (depth == 0 || level < depth) {
+				walk(child, prefix+indent, level+1)
+			}
+		}
+	}
+	walk(tree, "", 1)
+}
+
+// describeTreeNode summarizes a tree node: size for a file, count and size for a directory
+func describeTreeNode(node *types.FileTree) string {
+	if !node.Dir {
+		return formatBytes(node.Size)
 	}
+	noun := "files"
+	if node.Files == 1 {
+		noun = "file"
+	}
+	return fmt.Sprintf("%d %s, %s", node.Files, noun, formatBytes(node.Size))
+}
+
+// snapshotProvenance is where a snapshot was taken: the path, the machine and
+// its OS, and the bulletproof version. Fields not recorded are empty.
+type snapshotProvenance struct {
+	Origin    string
+	Hostname  string
+	OS        string
+	CreatedBy string
+}
+
+func provenanceOf(snapshot *types.Snapshot) snapshotProvenance {
+	if snapshot == nil {
+		return snapshotProvenance{}
+	}
+	return snapshotProvenance{
+		Origin:    snapshotOrigin(snapshot),
+		Hostname:  snapshot.Hostname,
+		OS:        snapshot.OS,
+		CreatedBy: snapshot.CreatedBy,
+	}
+}
+
+// String renders the provenance for the text listing, e.g.
+// "/home/me/.openclaw on laptop (Linux), bulletproof 1.4.0"
+func (p snapshotProvenance) String() string {
+	origin := p.Origin
+	if origin == "" {
+		origin = "(not recorded)"
+	}
+	switch {
+	case p.Hostname != "" && p.OS != "":
+		origin += fmt.Sprintf(" on %s (%s)", p.Hostname, types.PlatformName(p.OS))
+	case p.Hostname != "":
+		origin += " on " + p.Hostname
+	case p.OS != "":
+		origin += " on " + types.PlatformName(p.OS)
+	}
+	if p.CreatedBy != "" {
+		origin += ", " + p.CreatedBy
+	}
+	return origin
+}
+
+// snapshotOrigin describes where a snapshot was taken from: its original root,
+// or its source directories for a multi-source snapshot. Snapshots taken before
+// the original root was recorded have no origin.
+func snapshotOrigin(snapshot *types.Snapshot) string {
+	if snapshot == nil {
+		return ""
+	}
+	if snapshot.OriginalRoot != "" {
+		return snapshot.OriginalRoot
+	}
+	sources := make([]string, len(snapshot.Sources))
+	for i, source := range snapshot.Sources {
+		sources[i] = source.Path
+	}
+	return strings.Join(sources, ", ")
 }
 
-func outputText(backups []*types.SnapshotInfo, shortIDs map[string]int) error {
+// formatDiffStat renders stats as a compact "+A ~M -D" column
+func formatDiffStat(s *types.ChangeStats) string {
+	if s == nil {
+		return "(initial)"
+	}
+	return fmt.Sprintf("+%d ~%d -%d", s.Added, s.Modified, s.Removed)
+}
+
+func outputText(backups []*types.SnapshotInfo, shortIDs map[string]int, stats map[string]*types.ChangeStats, origins map[string]snapshotProvenance) error {
 	fmt.Println("Available backups (ID 0 = current filesystem state):")
 	fmt.Println()
 
@@ -89,9 +550,27 @@ func outputText(backups []*types.SnapshotInfo, shortIDs map[string]int) error {
 		// Format: ID [timestamp] - message (N files)
 		msg := ""
 		if b.Message != "" {
-			msg = fmt.Sprintf(" - %s", b.Message)
+			msg = fmt.Sprintf(" - %s", messageSubject(b.Message))
+		}
+		labels := ""
+		if len(b.Labels) > 0 {
+			labels = fmt.Sprintf(" [%s]", strings.Join(b.Labels, ", "))
+		}
+		diffStat := ""
+		if stats != nil {
+			diffStat = "  " + formatDiffStat(stats[b.ID])
+		}
+		kind := ""
+		if b.SizeBytes >= 0 {
+			kind = ", " + formatBytes(b.SizeBytes)
+		}
+		if b.ManifestOnly {
+			kind += ", manifest only"
+		}
+		fmt.Printf("  [%d] %s%s (%d files%s)%s%s\n", shortID, b.Timestamp.Format("2006-01-02 15:04:05"), msg, b.FileCount, kind, labels, diffStat)
+		if origins != nil {
+			fmt.Printf("      from %s\n", origins[b.ID])
 		}
-		fmt.Printf("  [%d] %s%s (%d files)\n", shortID, b.Timestamp.Format("2006-01-02 15:04:05"), msg, b.FileCount)
 
 		// Add a blank line between entries for readability
 		if i < len(backups)-1 {
@@ -99,26 +578,70 @@ func outputText(backups []*types.SnapshotInfo, shortIDs map[string]int) error {
 		}
 	}
 
+	if total, ok := totalSize(backups); ok {
+		fmt.Println()
+		fmt.Printf("💾 %s in %d listed snapshots (unchanged files shared between snapshots use less disk)\n", formatBytes(total), len(backups))
+	}
+
 	return nil
 }
 
-func outputJSON(backups []*types.SnapshotInfo, shortIDs map[string]int) error {
+// totalSize adds up the sizes of the snapshots, if all of them are known
+func totalSize(backups []*types.SnapshotInfo) (int64, bool) {
+	var total int64
+	for _, b := range backups {
+		if b.SizeBytes < 0 {
+			return 0, false
+		}
+		total += b.SizeBytes
+	}
+	return total, true
+}
+
+func outputJSON(backups []*types.SnapshotInfo, shortIDs map[string]int, stats map[string]*types.ChangeStats, origins map[string]snapshotProvenance) error {
+	type diffStatJSON struct {
+		Added    int `json:"added"`
+		Modified int `json:"modified"`
+		Removed  int `json:"removed"`
+	}
 	type snapshotJSON struct {
-		ShortID   int    `json:"short_id"`
-		FullID    string `json:"full_id"`
-		Timestamp string `json:"timestamp"`
-		Message   string `json:"message,omitempty"`
-		FileCount int    `json:"file_count"`
+		ShortID   int           `json:"short_id"`
+		FullID    string        `json:"full_id"`
+		Timestamp string        `json:"timestamp"`
+		Message   string        `json:"message,omitempty"`
+		FileCount int           `json:"file_count"`
+		SizeBytes *int64        `json:"size_bytes,omitempty"`
+		Labels    []string      `json:"labels,omitempty"`
+		DiffStat  *diffStatJSON `json:"diff_stat,omitempty"`
+		Origin    string        `json:"original_root,omitempty"`
+		Hostname  string        `json:"hostname,omitempty"`
+		OS        string        `json:"os,omitempty"`
+		CreatedBy string        `json:"created_by,omitempty"`
+		// ManifestOnly snapshots store no files and cannot be restored
+		ManifestOnly bool `json:"manifest_only,omitempty"`
 	}
 
 	snapshots := make([]snapshotJSON, len(backups))
 	for i, b := range backups {
 		snapshots[i] = snapshotJSON{
-			ShortID:   shortIDs[b.ID],
-			FullID:    b.ID,
-			Timestamp: b.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
-			Message:   b.Message,
-			FileCount: b.FileCount,
+			ShortID:      shortIDs[b.ID],
+			FullID:       b.ID,
+			Timestamp:    b.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+			Message:      b.Message,
+			FileCount:    b.FileCount,
+			Labels:       b.Labels,
+			Origin:       origins[b.ID].Origin,
+			Hostname:     origins[b.ID].Hostname,
+			OS:           origins[b.ID].OS,
+			CreatedBy:    origins[b.ID].CreatedBy,
+			ManifestOnly: b.ManifestOnly,
+		}
+		if b.SizeBytes >= 0 {
+			size := b.SizeBytes
+			snapshots[i].SizeBytes = &size
+		}
+		if s := stats[b.ID]; s != nil {
+			snapshots[i].DiffStat = &diffStatJSON{Added: s.Added, Modified: s.Modified, Removed: s.Removed}
 		}
 	}
 
@@ -127,12 +650,24 @@ func outputJSON(backups []*types.SnapshotInfo, shortIDs map[string]int) error {
 	return encoder.Encode(snapshots)
 }
 
-func outputCSV(backups []*types.SnapshotInfo, shortIDs map[string]int) error {
+// csvHeader returns the CSV column names, with diff-stat and provenance columns if requested
+func csvHeader(diffStat bool, wide bool) []string {
+	header := []string{"short_id", "full_id", "timestamp", "message", "file_count", "size_bytes", "labels"}
+	if diffStat {
+		header = append(header, "added", "modified", "removed")
+	}
+	if wide {
+		header = append(header, "original_root", "hostname", "os", "created_by")
+	}
+	return header
+}
+
+func outputCSV(backups []*types.SnapshotInfo, shortIDs map[string]int, stats map[string]*types.ChangeStats, origins map[string]snapshotProvenance) error {
 	w := csv.NewWriter(os.Stdout)
 	defer w.Flush()
 
 	// Write header
-	if err := w.Write([]string{"short_id", "full_id", "timestamp", "message", "file_count"}); err != nil {
+	if err := w.Write(csvHeader(stats != nil, origins != nil)); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
@@ -141,8 +676,26 @@ func outputCSV(backups []*types.SnapshotInfo, shortIDs map[string]int) error {
 		shortID := fmt.Sprintf("%d", shortIDs[b.ID])
 		fileCount := fmt.Sprintf("%d", b.FileCount)
 		timestamp := b.Timestamp.Format("2006-01-02T15:04:05Z07:00")
+		size := ""
+		if b.SizeBytes >= 0 {
+			size = fmt.Sprintf("%d", b.SizeBytes)
+		}
+
+		row := []string{shortID, b.ID, timestamp, b.Message, fileCount, size, strings.Join(b.Labels, ";")}
+		if stats != nil {
+			// The initial snapshot has no predecessor, so its stat columns stay empty
+			if s := stats[b.ID]; s != nil {
+				row = append(row, fmt.Sprintf("%d", s.Added), fmt.Sprintf("%d", s.Modified), fmt.Sprintf("%d", s.Removed))
+			} else {
+				row = append(row, "", "", "")
+			}
+		}
+		if origins != nil {
+			origin := origins[b.ID]
+			row = append(row, origin.Origin, origin.Hostname, origin.OS, origin.CreatedBy)
+		}
 
-		if err := w.Write([]string{shortID, b.ID, timestamp, b.Message, fileCount}); err != nil {
+		if err := w.Write(row); err != nil {
 			return fmt.Errorf("failed to write CSV row: %w", err)
 		}
 	}
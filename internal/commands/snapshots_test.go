@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/spf13/cobra"
+)
+
+func TestPrintFileTree(t *testing.T) {
+	tree := types.NewFileTree("20260101-030000-000", map[string]*types.FileSnapshot{
+		"openclaw.json":                                             {Size: 512},
+		filepath.Join("workspace", "SOUL.md"):                       {Size: 2048},
+		filepath.Join("workspace", "skills", "weather", "SKILL.md"): {Size: 1024},
+	})
+
+	var out bytes.Buffer
+	printFileTree(&out, tree, 0)
+	want := `20260101-030000-000 (3 files, 3.5 KB)
+├── openclaw.json (512 B)
+└── workspace/ (2 files, 3.0 KB)
+    ├── SOUL.md (2.0 KB)
+    └── skills/ (1 file, 1.0 KB)
+        └── weather/ (1 file, 1.0 KB)
+            └── SKILL.md (1.0 KB)
+`
+	if out.String() != want {
+		t.Errorf("full tree:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	printFileTree(&out, tree, 1)
+	want = `20260101-030000-000 (3 files, 3.5 KB)
+├── openclaw.json (512 B)
+└── workspace/ (2 files, 3.0 KB)
+`
+	if out.String() != want {
+		t.Errorf("depth 1:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestSnapshotsFormat(t *testing.T) {
+	for _, tc := range []struct {
+		format     string
+		jsonOutput bool
+		formatSet  bool
+		want       string
+		wantErr    bool
+	}{
+		{"text", false, false, "text", false},
+		{"csv", false, true, "csv", false},
+		{"text", true, false, "json", false},
+		{"json", true, true, "json", false},
+		{"csv", true, true, "", true},
+	} {
+		got, err := snapshotsFormat(tc.format, tc.jsonOutput, tc.formatSet)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("snapshotsFormat(%q, %v, %v) = %q, %v", tc.format, tc.jsonOutput, tc.formatSet, got, err)
+		}
+	}
+}
+
+func TestSnapshotProvenanceString(t *testing.T) {
+	tests := []struct {
+		provenance snapshotProvenance
+		want       string
+	}{
+		{snapshotProvenance{Origin: "/home/me/.openclaw", Hostname: "laptop", OS: "linux", CreatedBy: "bulletproof 1.4.0"}, "/home/me/.openclaw on laptop (Linux), bulletproof 1.4.0"},
+		{snapshotProvenance{Origin: "/home/me/.openclaw", OS: "darwin"}, "/home/me/.openclaw on macOS"},
+		{snapshotProvenance{Origin: "/home/me/.openclaw"}, "/home/me/.openclaw"},
+		{snapshotProvenance{}, "(not recorded)"},
+	}
+	for _, tt := range tests {
+		if got := tt.provenance.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestPrintSnapshotDetail(t *testing.T) {
+	snapshot := &types.Snapshot{
+		ID:           "20260101-030000-000",
+		Timestamp:    time.Date(2026, 1, 1, 3, 0, 0, 0, time.Local),
+		Message:      "CI build 412\n\n# Changelog\n- Tighten safety rules",
+		Labels:       []string{"release"},
+		OriginalRoot: "/home/me/.openclaw",
+		Parent:       "20251231-030000-000",
+		Files:        map[string]*types.FileSnapshot{"SOUL.md": {Path: "SOUL.md", Size: 2048}},
+	}
+
+	// The message and parent survive the manifest unchanged
+	data, err := snapshot.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := types.FromJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Message != snapshot.Message || loaded.Parent != snapshot.Parent {
+		t.Fatalf("message or parent did not round-trip: %q, %q", loaded.Message, loaded.Parent)
+	}
+
+	var buf bytes.Buffer
+	printSnapshotDetail(&buf, loaded)
+	want := "Snapshot 20260101-030000-000\n" +
+		"Date:    2026-01-01 03:00:00\n" +
+		"Files:   1 (2.0 KB)\n" +
+		"Labels:  release\n" +
+		"From:    /home/me/.openclaw\n" +
+		"Parent:  20251231-030000-000\n" +
+		"\n" +
+		"    CI build 412\n" +
+		"\n" +
+		"    # Changelog\n" +
+		"    - Tighten safety rules\n"
+	if buf.String() != want {
+		t.Errorf("printSnapshotDetail() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestSnapshotsCommand_GlobalVerbose(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(config.ConfigPathEnv, "")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.OpenclawPath = t.TempDir()
+	cfg.Destination = config.NewDestinationConfig("local", t.TempDir())
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The root command's global -v must reach snapshots, not clash with its flags
+	var verbose bool
+	root := &cobra.Command{Use: "bulletproof"}
+	root.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "")
+	root.AddCommand(NewSnapshotsCommand())
+	root.SetArgs([]string{"snapshots", "-v"})
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+
+	if err := root.Execute(); err != nil {
+		t.Fatalf("snapshots -v failed: %v", err)
+	}
+	if !verbose {
+		t.Error("expected -v to set the global verbose flag")
+	}
+}
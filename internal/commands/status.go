@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/platform"
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// scheduleInterval is how often scheduled backups run
+const scheduleInterval = 24 * time.Hour
+
+// scheduleGrace is how long a scheduled backup may take to show up before
+// the last backup counts as overdue
+const scheduleGrace = time.Hour
+
+// NewStatusCommand creates the status command
+func NewStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show whether your backups are current",
+		Long: `Summarize backup health in one place: the configured sources and
+destination, how many snapshots there are, when the last one was taken,
+whether the agent has changed since, and whether scheduled backups are
+installed.
+
+Exits with an error when you are not protected: there are no backups yet,
+the schedule is enabled but no scheduled task is installed, or the last
+backup is older than the daily schedule. Use it from monitoring to catch
+backups that silently stopped.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// An unhealthy status is a result, not a usage mistake
+			cmd.SilenceUsage = true
+			return runStatus()
+		},
+	}
+}
+
+func runStatus() error {
+	// Track analytics
+	analytics.TrackCommand("status", nil)
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("📂 Sources:")
+	for _, source := range cfg.GetSources() {
+		fmt.Printf("   %s\n", source)
+	}
+	fmt.Printf("💾 Destination: %s (%s)\n", cfg.Destination.Location(), cfg.Destination.Type)
+
+	infos, err := engine.ListBackups()
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+	fmt.Printf("📦 Snapshots: %d\n", len(infos))
+
+	last, err := engine.Destination().GetLastSnapshot()
+	if err != nil {
+		return fmt.Errorf("failed to get last snapshot: %w", err)
+	}
+	now := time.Now()
+	if last == nil {
+		fmt.Println("🕒 Last backup: never")
+	} else {
+		fmt.Printf("🕒 Last backup: %s, %s\n", describeSnapshot(last), formatAge(now.Sub(last.Timestamp)))
+
+		diff, err := engine.ShowDiff()
+		switch {
+		case err != nil:
+			fmt.Printf("⚠️  Could not compare with the current files: %v\n", err)
+		case diff == nil || diff.IsEmpty():
+			fmt.Println("📊 Changes since last backup: none")
+		default:
+			fmt.Printf("📊 Changes since last backup: %s\n", diff.String())
+		}
+	}
+
+	installed := platform.AutoBackupInstalled()
+	switch {
+	case cfg.Schedule.Enabled && installed:
+		fmt.Printf("⏰ Schedule: daily at %s\n", cfg.Schedule.Time)
+	case cfg.Schedule.Enabled:
+		fmt.Printf("⏰ Schedule: daily at %s, but no scheduled task is installed\n", cfg.Schedule.Time)
+	case installed:
+		fmt.Println("⏰ Schedule: disabled in the config, but a scheduled task is installed")
+	default:
+		fmt.Println("⏰ Schedule: disabled")
+	}
+
+	fmt.Println()
+	problems := statusProblems(cfg.Schedule, installed, last, now)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Printf("❌ %s\n", problem)
+		}
+		fmt.Println("💡 Run 'bulletproof backup' now, and 'bulletproof schedule enable' to keep backups current")
+		fmt.Println()
+		return fmt.Errorf("%d backup problem(s) found", len(problems))
+	}
+
+	if !cfg.Schedule.Enabled {
+		fmt.Println("⚠️  Backups only happen when you run them. Enable the schedule with: bulletproof schedule enable")
+	}
+	fmt.Println("✅ You are protected")
+	return nil
+}
+
+// statusProblems lists what leaves the agent unprotected: no backup at all, an
+// enabled schedule with nothing installed to run it, or a last backup the
+// schedule should have replaced by now
+func statusProblems(schedule config.ScheduleConfig, installed bool, last *types.Snapshot, now time.Time) []string {
+	var problems []string
+	if last == nil {
+		problems = append(problems, "No backups yet")
+	}
+	if schedule.Enabled && !installed {
+		problems = append(problems, "The schedule is enabled, but no scheduled task is installed to run it")
+	}
+	if schedule.Enabled && last != nil {
+		if age := now.Sub(last.Timestamp); age > scheduleInterval+scheduleGrace {
+			problems = append(problems, fmt.Sprintf("The last backup was %s, but backups are scheduled daily", formatAge(age)))
+		}
+	}
+	return problems
+}
+
+// formatAge describes how long ago something happened
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%d minute(s) ago", int(age.Minutes()))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%d hour(s) ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%d days ago", int(age.Hours()/24))
+	}
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+func TestStatusProblems(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	daily := config.ScheduleConfig{Enabled: true, Time: "03:00"}
+	backedUp := func(age time.Duration) *types.Snapshot {
+		return &types.Snapshot{ID: "20250310-000000-000", Timestamp: now.Add(-age)}
+	}
+
+	tests := []struct {
+		name      string
+		schedule  config.ScheduleConfig
+		installed bool
+		last      *types.Snapshot
+		want      []string
+	}{
+		{"scheduled and current", daily, true, backedUp(9 * time.Hour), nil},
+		{"scheduled run still due", daily, true, backedUp(24*time.Hour + 30*time.Minute), nil},
+		{"missed scheduled runs", daily, true, backedUp(3 * 24 * time.Hour), []string{"3 days ago"}},
+		{"schedule not installed", daily, false, backedUp(time.Hour), []string{"no scheduled task"}},
+		{"never backed up", daily, true, nil, []string{"No backups yet"}},
+		{"manual backups only", config.ScheduleConfig{}, false, backedUp(30 * 24 * time.Hour), nil},
+		{"manual and never backed up", config.ScheduleConfig{}, false, nil, []string{"No backups yet"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := statusProblems(tt.schedule, tt.installed, tt.last, now)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("got problems %q, want %d", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %q should mention %q", problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	for age, want := range map[time.Duration]string{
+		-time.Minute:     "just now",
+		30 * time.Second: "just now",
+		5 * time.Minute:  "5 minute(s) ago",
+		26 * time.Hour:   "26 hour(s) ago",
+		100 * time.Hour:  "4 days ago",
+	} {
+		if got := formatAge(age); got != want {
+			t.Errorf("formatAge(%v) = %q, want %q", age, got, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/log"
+	"github.com/spf13/cobra"
+)
+
+// NewSyncCommand creates the sync command
+func NewSyncCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Push backups the git remote does not have yet",
+		Long: `Push every backup the git destination's remote is missing, oldest first.
+
+Backups made while the remote is unreachable are kept in the local clone and
+pushed by the next backup that can reach it. Run sync to reconcile right away
+after reconnecting. Each snapshot tag is pushed on its own, so an interrupted
+sync keeps what it pushed and the next run picks up the rest.
+
+Nothing is ever force-pushed. If the remote branch moved on, the local clone
+catches up when it is only behind; if both sides have new commits, the branch
+is reported as diverged and left alone, while the snapshot tags are still
+pushed.
+
+Exits with an error if any ref could not be synced.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSync()
+		},
+	}
+
+	return cmd
+}
+
+func runSync() error {
+	// Track analytics
+	analytics.TrackCommand("sync", make(map[string]string))
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	log.Println("🔄 Syncing backups with the remote...")
+	results, err := engine.SyncRemote()
+	if err != nil {
+		return fmt.Errorf("sync failed: %w", err)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("✨ Remote is up to date.")
+		return nil
+	}
+
+	synced := 0
+	for _, result := range results {
+		if result.OK() {
+			synced++
+		}
+	}
+
+	log.Println()
+	fmt.Printf("Synced %d of %d ref(s)\n", synced, len(results))
+	if failed := len(results) - synced; failed > 0 {
+		return fmt.Errorf("%d ref(s) not synced", failed)
+	}
+	fmt.Println("✅ Remote is up to date.")
+	return nil
+}
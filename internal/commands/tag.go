@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// NewTagCommand creates the tag command
+func NewTagCommand() *cobra.Command {
+	var remove bool
+
+	cmd := &cobra.Command{
+		Use:   "tag <snapshot-id> <label>...",
+		Short: "Label an existing snapshot",
+		Long: `Add labels to a snapshot after it was made, e.g. to mark it "known-good"
+once you have checked it, or "pre-incident" while investigating. With
+--remove, the labels are taken off instead.
+
+Labels are kept apart from the snapshot's message and files, which stay as
+they are. List labeled snapshots with 'bulletproof snapshots --label <name>',
+and start a bisect from the newest one with 'bulletproof bisect <pattern>
+--good <label>'. On git destinations each label is a labels/<label>/<id> tag.
+
+Labels may contain letters, digits, '.', '-' and '_', and must start and end
+with a letter or digit.
+
+Examples:
+  bulletproof tag 3 known-good
+  bulletproof tag 20250203-120000-000 pre-incident reviewed
+  bulletproof tag 3 known-good --remove`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTag(args[0], args[1:], remove)
+		},
+	}
+
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove the labels instead of adding them")
+
+	return cmd
+}
+
+func runTag(snapshotID string, labels []string, remove bool) error {
+	// Track analytics
+	analytics.TrackCommand("tag", map[string]string{
+		"remove": fmt.Sprintf("%t", remove),
+	})
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	add, drop := labels, []string(nil)
+	if remove {
+		add, drop = nil, labels
+	}
+	snapshot, err := engine.LabelSnapshot(snapshotID, add, drop)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshot.Labels) == 0 {
+		fmt.Printf("🏷️  %s has no labels\n", describeSnapshot(snapshot))
+		return nil
+	}
+	fmt.Printf("🏷️  %s\n   Labels: %s\n", describeSnapshot(snapshot), strings.Join(snapshot.Labels, ", "))
+	return nil
+}
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bulletproof-bot/backup/internal/analytics"
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/log"
+	"github.com/spf13/cobra"
+)
+
+// NewVerifyCommand creates the verify command
+func NewVerifyCommand() *cobra.Command {
+	var incremental bool
+	var sample int
+	var repair bool
+
+	cmd := &cobra.Command{
+		Use:   "verify [snapshot-id]",
+		Short: "Check stored snapshots for missing, corrupted or unexpected files",
+		Long: `Check that the files stored for each snapshot still match the hashes
+recorded when the snapshot was taken. Files stored in a snapshot that its
+manifest does not list are reported too, since a restore would bring them back.
+
+By default every snapshot is checked; name a snapshot to check only that one. With --incremental, only snapshots that
+are new, changed, or failed last time are checked, plus a sample of the least
+recently verified others. Results are recorded in the destination's
+.bulletproof directory, so running --incremental regularly (e.g. from cron)
+stays cheap and still eventually covers every snapshot.
+
+With --repair, each missing or corrupted file is replaced by an intact copy
+with the same hash from another snapshot or the current OpenClaw folder, when
+one exists. Backups store many identical files, so minor corruption can often
+be healed without a new backup. Unexpected files are left for you to inspect.
+Repair needs a local destination.
+
+Exits with an error if any checked snapshot fails verification.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snapshotID := ""
+			if len(args) > 0 {
+				snapshotID = args[0]
+			}
+			return runVerify(snapshotID, incremental, sample, repair)
+		},
+	}
+
+	cmd.Flags().BoolVar(&incremental, "incremental", false, "Only check new, changed, and failed snapshots plus a sample of older ones")
+	cmd.Flags().IntVar(&sample, "sample", backup.DefaultVerifySample, "With --incremental, number of unchanged snapshots to re-check")
+	cmd.Flags().BoolVar(&repair, "repair", false, "Replace damaged files with intact copies from other snapshots or the current state")
+
+	return cmd
+}
+
+func runVerify(snapshotID string, incremental bool, sample int, repair bool) error {
+	// Track analytics
+	flags := make(map[string]string)
+	if snapshotID != "" {
+		flags["snapshot"] = "true"
+	}
+	if incremental {
+		flags["incremental"] = "true"
+	}
+	if repair {
+		flags["repair"] = "true"
+	}
+	analytics.TrackCommand("verify", flags)
+
+	if sample < 0 {
+		return fmt.Errorf("--sample must not be negative")
+	}
+	if snapshotID != "" && incremental {
+		return fmt.Errorf("--incremental cannot be combined with a snapshot ID")
+	}
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Create backup engine
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		return err
+	}
+
+	var report *backup.VerifyReport
+	switch {
+	case snapshotID != "":
+		log.Printf("🔍 Verifying snapshot %s...\n", snapshotID)
+		report, err = engine.VerifyOne(snapshotID, repair)
+	case incremental:
+		log.Println("🔍 Verifying new and changed snapshots...")
+		report, err = engine.Verify(incremental, sample, repair)
+	default:
+		log.Println("🔍 Verifying all snapshots...")
+		report, err = engine.Verify(incremental, sample, repair)
+	}
+	if err != nil {
+		return fmt.Errorf("verify failed: %w", err)
+	}
+
+	if report.TotalSnapshots == 0 {
+		fmt.Println("No snapshots to verify.")
+		return nil
+	}
+
+	log.Println()
+	repaired := 0
+	for _, result := range report.Results {
+		repaired += len(result.Repairs)
+		if result.OK() {
+			log.Printf("  ✓ %s (%s)\n", result.SnapshotID, result.Reason)
+		} else {
+			fmt.Printf("  ✗ %s (%s)\n", result.SnapshotID, result.Reason)
+		}
+		for _, repair := range result.Repairs {
+			fmt.Printf("      repaired: %s\n", repair)
+		}
+		for _, problem := range result.Problems {
+			fmt.Printf("      %s\n", problem)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Checked %d of %d snapshots\n", len(report.Results), report.TotalSnapshots)
+	if repair {
+		fmt.Printf("Repaired %d file(s)\n", repaired)
+	}
+	if report.LastFullVerify.IsZero() {
+		fmt.Println("Last full verify: never")
+	} else {
+		fmt.Printf("Last full verify: %s\n", report.LastFullVerify.Local().Format("2006-01-02 15:04:05"))
+	}
+	if len(report.Unverified) > 0 {
+		fmt.Printf("Outstanding unverified snapshots: %d\n", len(report.Unverified))
+		for _, id := range report.Unverified {
+			fmt.Printf("  %s\n", id)
+		}
+	}
+
+	if failed := report.Failed(); len(failed) > 0 {
+		fmt.Println()
+		return fmt.Errorf("%d snapshot(s) failed verification", len(failed))
+	}
+
+	fmt.Println()
+	fmt.Println("✅ All checked snapshots are intact")
+	return nil
+}
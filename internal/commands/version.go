@@ -2,7 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/bulletproof-bot/backup/internal/config"
 	"github.com/bulletproof-bot/backup/internal/version"
 	"github.com/spf13/cobra"
 )
@@ -12,14 +14,21 @@ func NewVersionCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
 		Short: "Show version information",
-		Long:  "Display the current version of bulletproof, including build information.",
-		Run:   runVersion,
+		Long: `Display the current version of bulletproof, including build information.
+
+Also checks GitHub for a newer release unless update checks are disabled
+with BULLETPROOF_NO_UPDATE_CHECK=1 or options.check_updates: false.`,
+		Run: runVersion,
 	}
 }
 
 func runVersion(cmd *cobra.Command, args []string) {
 	fmt.Println(version.Info())
 
+	if !UpdateChecksAllowed() {
+		return
+	}
+
 	// Check for updates
 	latestVersion, downloadURL, err := version.CheckForUpdate()
 	if err != nil {
@@ -34,3 +43,48 @@ func runVersion(cmd *cobra.Command, args []string) {
 		fmt.Println("✅ You're running the latest version")
 	}
 }
+
+// UpdateChecksAllowed reports whether the environment and config permit contacting
+// GitHub for release information
+func UpdateChecksAllowed() bool {
+	if version.UpdateCheckDisabledByEnv() {
+		return false
+	}
+
+	// A missing or invalid config must not block the check
+	cfg, err := config.Load()
+	if err != nil {
+		return true
+	}
+	return cfg.Options.UpdateChecksEnabled()
+}
+
+// BackgroundUpdateCheckAllowed reports whether the post-command update notice may run
+// for cmd. It never runs for scripted use: machine-readable output, a non-terminal
+// stdout, or the version command, which checks on its own.
+func BackgroundUpdateCheckAllowed(cmd *cobra.Command) bool {
+	if cmd.Name() == "version" || machineReadableOutput(cmd) || !stdoutIsTerminal() {
+		return false
+	}
+	return UpdateChecksAllowed()
+}
+
+// machineReadableOutput reports whether cmd was asked for JSON or CSV output
+func machineReadableOutput(cmd *cobra.Command) bool {
+	if flag := cmd.Flags().Lookup("json"); flag != nil && flag.Value.String() == "true" {
+		return true
+	}
+	if flag := cmd.Flags().Lookup("format"); flag != nil && flag.Value.String() != "text" {
+		return true
+	}
+	return false
+}
+
+// stdoutIsTerminal reports whether stdout is an interactive terminal
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
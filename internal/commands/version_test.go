@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/bulletproof-bot/backup/internal/config"
+	"github.com/bulletproof-bot/backup/internal/version"
+	"github.com/spf13/cobra"
+)
+
+func TestUpdateChecksAllowed(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	t.Setenv(version.NoUpdateCheckEnv, "")
+	if !UpdateChecksAllowed() {
+		t.Error("update checks should be allowed by default")
+	}
+
+	t.Setenv(version.NoUpdateCheckEnv, "1")
+	if UpdateChecksAllowed() {
+		t.Errorf("%s=1 should disable update checks", version.NoUpdateCheckEnv)
+	}
+
+	t.Setenv(version.NoUpdateCheckEnv, "false")
+	disabled := false
+	cfg := &config.Config{
+		OpenclawPath: "/test/.openclaw",
+		Options:      config.BackupOptions{CheckUpdates: &disabled},
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	if UpdateChecksAllowed() {
+		t.Error("options.check_updates: false should disable update checks")
+	}
+}
+
+func TestMachineReadableOutput(t *testing.T) {
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "test"}
+		cmd.Flags().Bool("json", false, "")
+		cmd.Flags().String("format", "text", "")
+		return cmd
+	}
+
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"--json"}, true},
+		{[]string{"--format", "csv"}, true},
+		{[]string{"--format", "text"}, false},
+	}
+
+	for _, tt := range tests {
+		cmd := newCmd()
+		if err := cmd.ParseFlags(tt.args); err != nil {
+			t.Fatalf("ParseFlags(%v) failed: %v", tt.args, err)
+		}
+		if got := machineReadableOutput(cmd); got != tt.want {
+			t.Errorf("machineReadableOutput(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
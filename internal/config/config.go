@@ -4,32 +4,182 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/bulletproof-bot/backup/internal/errors"
+	"github.com/bulletproof-bot/backup/internal/types"
+	"github.com/bulletproof-bot/backup/internal/utils"
 	"gopkg.in/yaml.v3"
 )
 
-const ConfigVersion = "1"
+// ConfigVersion is the version of the config file format written by Save.
+// Load upgrades files of older versions through configMigrations.
+const ConfigVersion = "2"
 
-// Config represents the bulletproof configuration
+// Config represents the bulletproof configuration. OpenclawPath is the
+// OpenClaw folder of configs from before version 2, which list it as their
+// only source instead; use AgentPath and SetAgentPath rather than the field.
 type Config struct {
-	OpenclawPath string             `yaml:"openclaw_path,omitempty"`
-	Sources      []string           `yaml:"sources,omitempty"`
-	Destination  *DestinationConfig `yaml:"destination,omitempty"`
-	Schedule     ScheduleConfig     `yaml:"schedule"`
-	Options      BackupOptions      `yaml:"options"`
-	Scripts      ScriptsConfig      `yaml:"scripts,omitempty"`
-	Analytics    AnalyticsConfig    `yaml:"analytics,omitempty"`
-	Retention    RetentionPolicy    `yaml:"retention,omitempty"`
+	OpenclawPath  string              `yaml:"openclaw_path,omitempty"`
+	Sources       []SourceConfig      `yaml:"sources,omitempty"`
+	Destination   *DestinationConfig  `yaml:"destination,omitempty"`
+	Schedule      ScheduleConfig      `yaml:"schedule"`
+	Options       BackupOptions       `yaml:"options"`
+	Scripts       ScriptsConfig       `yaml:"scripts,omitempty"`
+	Analytics     AnalyticsConfig     `yaml:"analytics,omitempty"`
+	Retention     RetentionPolicy     `yaml:"retention,omitempty"`
+	Keys          KeysConfig          `yaml:"keys,omitempty"`
+	Anomaly       AnomalyConfig       `yaml:"anomaly,omitempty"`
+	Notifications NotificationsConfig `yaml:"notifications,omitempty"`
 }
 
-// DestinationConfig specifies the backup destination
+// SourceConfig is one directory (or glob of directories) to back up. In
+// multi-source snapshots each source's files are stored under a prefix, by
+// default the source's base name; set Prefix to choose it, e.g. to keep two
+// .openclaw folders from different machines apart. Exclude adds patterns
+// for this source only, after options.exclude:
+//
+//	sources:
+//	  - ~/.openclaw
+//	  - path: /mnt/laptop/.openclaw
+//	    prefix: laptop
+//	    exclude: ["*.log"]
+//
+// A plain string entry is a path with the default prefix and no excludes of
+// its own.
+type SourceConfig struct {
+	Path    string   `yaml:"path"`
+	Prefix  string   `yaml:"prefix,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+// UnmarshalYAML decodes a source from a plain path or a {path, prefix} mapping
+func (s *SourceConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = SourceConfig{Path: value.Value}
+		return nil
+	}
+	type plain SourceConfig
+	if err := value.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	if s.Path == "" {
+		return fmt.Errorf("source at line %d has no path", value.Line)
+	}
+	return validateSourcePrefix(s.Prefix)
+}
+
+// MarshalYAML writes a source without a prefix or excludes as a plain path
+func (s SourceConfig) MarshalYAML() (interface{}, error) {
+	if s.Prefix == "" && len(s.Exclude) == 0 {
+		return s.Path, nil
+	}
+	type plain SourceConfig
+	return plain(s), nil
+}
+
+// validateSourcePrefix checks that a source prefix is a single, ordinary
+// directory name. An empty prefix means the default.
+func validateSourcePrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if prefix == "." || prefix == ".." || prefix == ".bulletproof" {
+		return fmt.Errorf("source prefix %q is reserved", prefix)
+	}
+	for _, r := range prefix {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+			return fmt.Errorf("invalid source prefix %q: use letters, digits, '.', '-' and '_'", prefix)
+		}
+	}
+	return nil
+}
+
+// NewSources returns source entries for paths, with default prefixes
+func NewSources(paths ...string) []SourceConfig {
+	sources := make([]SourceConfig, len(paths))
+	for i, path := range paths {
+		sources[i] = SourceConfig{Path: path}
+	}
+	return sources
+}
+
+// DestinationConfig specifies the backup destination. Settings that only make
+// sense for one destination type live in the block named after that type:
+//
+//	destination:
+//	  type: git
+//	  git:
+//	    url: git@github.com:me/agent-backups.git
+//
+// The flat {type, path} form of older configs is still read and moved into the
+// type's block by Normalize, so code should use Location rather than Path.
 type DestinationConfig struct {
-	Type string `yaml:"type"` // 'git', 'local', or 'sync'
-	Path string `yaml:"path"`
+	Type  string                  `yaml:"type"`           // 'git', 'local', 'sync', 's3', or 'sftp'
+	Path  string                  `yaml:"path,omitempty"` // flat form (deprecated, use the type's block)
+	Local *LocalDestinationConfig `yaml:"local,omitempty"`
+	Git   *GitDestinationConfig   `yaml:"git,omitempty"`
+	Sync  *SyncDestinationConfig  `yaml:"sync,omitempty"`
+	S3    *S3DestinationConfig    `yaml:"s3,omitempty"`
+	SFTP  *SFTPDestinationConfig  `yaml:"sftp,omitempty"`
+}
+
+// LocalDestinationConfig configures a folder of timestamped snapshots
+type LocalDestinationConfig struct {
+	Path        string `yaml:"path"`
+	Deduplicate bool   `yaml:"deduplicate,omitempty"` // hardlink files unchanged since the previous snapshot
+}
+
+// GitDestinationConfig configures a git repository destination
+type GitDestinationConfig struct {
+	URL        string           `yaml:"url"`                   // remote URL, or path of a local repository
+	PushBranch *bool            `yaml:"push_branch,omitempty"` // push the branch along with snapshot tags; nil = true
+	Author     *GitAuthorConfig `yaml:"author,omitempty"`      // identity of backup commits; nil = the repository's user
+	Retries    *int             `yaml:"retries,omitempty"`     // retries of a clone, pull or push that hit a network error; nil = 3
+}
+
+// GitAuthorConfig is the identity backup commits and tags are made as. Either
+// field may be left out to take it from the repository's user.name or
+// user.email.
+type GitAuthorConfig struct {
+	Name  string `yaml:"name,omitempty"`
+	Email string `yaml:"email,omitempty"`
+}
+
+// BranchPushEnabled reports whether backups push the branch as well as their tags
+func (g *GitDestinationConfig) BranchPushEnabled() bool {
+	return g.PushBranch == nil || *g.PushBranch
+}
+
+// S3DestinationConfig configures an S3 bucket destination. Credentials and
+// region come from the standard AWS environment, never from the config.
+type S3DestinationConfig struct {
+	URL string `yaml:"url"` // s3://bucket/prefix
+}
+
+// SFTPDestinationConfig configures a folder on an SFTP server. Authentication
+// uses the SSH agent or the default keys in ~/.ssh, never the config.
+type SFTPDestinationConfig struct {
+	URL string `yaml:"url"` // sftp://user@host:port/path
+}
+
+// SyncDestinationConfig configures a folder kept in sync by a cloud sync client
+type SyncDestinationConfig struct {
+	Path   string           `yaml:"path"`
+	Verify SyncVerifyConfig `yaml:"verify,omitempty"`
+}
+
+// SyncVerifyConfig controls the check after each backup that a sync destination
+// really holds it. The written files are re-read and compared against the
+// manifest; Check can additionally ask the sync provider whether its upload is done.
+type SyncVerifyConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Attempts int           `yaml:"attempts,omitempty"` // checks before warning (default 3)
+	Interval int           `yaml:"interval,omitempty"` // seconds between checks (default 5)
+	Check    *ScriptConfig `yaml:"check,omitempty"`    // command that exits 0 once the provider has synced
 }
 
 // ScheduleConfig controls automatic backup scheduling
@@ -40,19 +190,85 @@ type ScheduleConfig struct {
 
 // BackupOptions controls backup behavior
 type BackupOptions struct {
-	IncludeAuth bool     `yaml:"include_auth"`
-	Exclude     []string `yaml:"exclude"`
+	IncludeAuth   bool     `yaml:"include_auth"`
+	Include       []string `yaml:"include,omitempty"` // when set, only matching files are backed up; excludes still apply
+	Exclude       []string `yaml:"exclude"`
+	CheckUpdates  *bool    `yaml:"check_updates,omitempty"`  // nil = enabled
+	IncludeHidden *bool    `yaml:"include_hidden,omitempty"` // back up dotfiles and dot-directories; nil = true
+
+	// Guards against backing up a source that vanished, e.g. an unmounted or
+	// wiped agent folder. A backup tripping one is refused unless forced; 0 = off.
+	MinFiles    int   `yaml:"min_files,omitempty"`     // fewest files a backup may contain
+	MinBytes    int64 `yaml:"min_bytes,omitempty"`     // fewest total bytes a backup may contain
+	MaxFileDrop int   `yaml:"max_file_drop,omitempty"` // largest percentage drop in file count since the last backup
+
+	// MaxFileSize leaves files larger than this many bytes out of backups,
+	// e.g. model checkpoints dumped into the workspace; 0 = no limit
+	MaxFileSize int64 `yaml:"max_file_size,omitempty"`
+
+	// OnReadError decides what a backup does with a file it cannot read, e.g.
+	// one owned by another user: "fail" (the default) aborts the backup, "skip"
+	// leaves the file out and lists it once the backup is done
+	OnReadError string `yaml:"on_read_error,omitempty"`
+
+	// StoreContent keeps the text of small UTF-8 files in each snapshot's
+	// manifest, so diffs show real line changes even where the stored files
+	// cannot be read back, e.g. older snapshots in a sync folder
+	StoreContent         bool  `yaml:"store_content,omitempty"`
+	StoreContentMaxBytes int64 `yaml:"store_content_max_bytes,omitempty"` // largest file whose content is kept; 0 = 64 KiB
+
+	// Compression stores the files of local and sync backups compressed:
+	// "gzip", "zstd", or "none" (the default)
+	Compression string `yaml:"compression,omitempty"`
+
+	// Encryption encrypts the files of local and sync backups at rest
+	Encryption EncryptionConfig `yaml:"encryption,omitempty"`
+}
+
+// EncryptionConfig encrypts snapshot files with AES-256-GCM. The key comes from
+// a key file, or is derived from a passphrase read from the environment; the
+// passphrase itself is never stored in the config.
+type EncryptionConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	PassphraseEnv string `yaml:"passphrase_env,omitempty"` // variable holding the passphrase; default BULLETPROOF_PASSPHRASE
+	KeyFile       string `yaml:"key_file,omitempty"`       // hex-encoded 32-byte key, used instead of a passphrase; default keys.encryption_key
+}
+
+// EncryptionKeyFile returns the key file encryption uses: options.encryption.key_file,
+// or else the key recorded by `bulletproof key` in keys.encryption_key. Empty
+// means the key is derived from a passphrase.
+func (c *Config) EncryptionKeyFile() string {
+	if c.Options.Encryption.KeyFile != "" {
+		return c.Options.Encryption.KeyFile
+	}
+	return c.Keys.EncryptionKey
+}
+
+// DefaultPassphraseEnv is the variable the encryption passphrase is read from by default
+const DefaultPassphraseEnv = "BULLETPROOF_PASSPHRASE"
+
+// PassphraseVariable returns the environment variable holding the passphrase
+func (e EncryptionConfig) PassphraseVariable() string {
+	if e.PassphraseEnv == "" {
+		return DefaultPassphraseEnv
+	}
+	return e.PassphraseEnv
 }
 
+// DefaultStoreContentMaxBytes is the largest file whose content store_content keeps by default
+const DefaultStoreContentMaxBytes = 64 * 1024
+
 // ScriptConfig represents a single script configuration
 type ScriptConfig struct {
 	Name    string `yaml:"name"`
 	Command string `yaml:"command"`
-	Timeout int    `yaml:"timeout"` // seconds, 0 = default (60s)
+	Timeout int    `yaml:"timeout"`         // seconds, 0 = default (60s)
+	Shell   string `yaml:"shell,omitempty"` // e.g. "/bin/bash -c"; empty = run the command directly
 }
 
 // ScriptsConfig controls script execution
 type ScriptsConfig struct {
+	Dir         string         `yaml:"dir,omitempty"` // where scripts are read from and bundled; default ~/.config/bulletproof/scripts
 	PreBackup   []ScriptConfig `yaml:"pre_backup,omitempty"`
 	PostRestore []ScriptConfig `yaml:"post_restore,omitempty"`
 }
@@ -71,6 +287,191 @@ type RetentionPolicy struct {
 	KeepDaily   int  `yaml:"keep_daily,omitempty"`   // Keep one snapshot per day for N days
 	KeepWeekly  int  `yaml:"keep_weekly,omitempty"`  // Keep one snapshot per week for N weeks
 	KeepMonthly int  `yaml:"keep_monthly,omitempty"` // Keep one snapshot per month for N months
+
+	// Caps applied to what the rules above keep, or to every snapshot when no
+	// rule is set. The newest snapshot is always kept.
+	MaxTotalSizeBytes int64 `yaml:"max_total_size_bytes,omitempty"` // Delete the oldest snapshots until the rest fit
+	MaxAgeDays        int   `yaml:"max_age_days,omitempty"`         // Delete snapshots older than N days
+
+	// Snapshots never deleted, whatever the rules and caps above, given by
+	// full ID or by label
+	KeepIDs []string `yaml:"keep_ids,omitempty"`
+}
+
+// HasRules reports whether the policy has any keep rule or cap set
+func (p RetentionPolicy) HasRules() bool {
+	return p.KeepLast > 0 || p.KeepDaily > 0 || p.KeepWeekly > 0 || p.KeepMonthly > 0 || p.MaxTotalSizeBytes > 0 || p.MaxAgeDays > 0
+}
+
+// KeysConfig references key files by path; key material is never stored in the config
+type KeysConfig struct {
+	EncryptionKey string `yaml:"encryption_key,omitempty"`
+}
+
+// AnomalyConfig controls change-rate anomaly detection between backups
+type AnomalyConfig struct {
+	Enabled    bool    `yaml:"enabled"`
+	Strict     bool    `yaml:"strict,omitempty"`      // refuse to back up when a spike is detected
+	Threshold  float64 `yaml:"threshold,omitempty"`   // spike = more than Threshold times the baseline (default 5)
+	MinChanges int     `yaml:"min_changes,omitempty"` // ignore spikes smaller than this many files (default 10)
+	Window     int     `yaml:"window,omitempty"`      // number of recent backups in the baseline (default 10)
+}
+
+// NotificationsConfig reports the outcome of each backup to a webhook, such as
+// a Slack or Discord incoming webhook
+type NotificationsConfig struct {
+	WebhookURL string `yaml:"webhook_url,omitempty"` // http(s) URL the outcome is POSTed to as JSON
+}
+
+// NewDestinationConfig returns a destination of the given type at location,
+// which is a folder path or, for git, a remote URL or repository path
+func NewDestinationConfig(destType, location string) *DestinationConfig {
+	d := &DestinationConfig{Type: destType, Path: location}
+	d.Normalize()
+	return d
+}
+
+// destinationTypes lists the supported destination types
+var destinationTypes = []string{"local", "git", "sync", "s3", "sftp"}
+
+// isDestinationType reports whether destType is a supported destination type
+func isDestinationType(destType string) bool {
+	for _, known := range destinationTypes {
+		if destType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalYAML decodes a destination in either the flat or the block form
+func (d *DestinationConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain DestinationConfig
+	if err := value.Decode((*plain)(d)); err != nil {
+		return err
+	}
+	return d.Normalize()
+}
+
+// Normalize moves a flat-form path into the block for the destination type and
+// rejects settings that belong to a different type. Unknown types are left as
+// they are so that creating the destination reports them.
+func (d *DestinationConfig) Normalize() error {
+	if err := d.checkBlocks(); err != nil {
+		return err
+	}
+
+	if d.Path != "" {
+		switch d.Type {
+		case "local":
+			if d.Local == nil {
+				d.Local = &LocalDestinationConfig{}
+			}
+			d.Local.Path = d.Path
+		case "git":
+			if d.Git == nil {
+				d.Git = &GitDestinationConfig{}
+			}
+			d.Git.URL = d.Path
+		case "sync":
+			if d.Sync == nil {
+				d.Sync = &SyncDestinationConfig{}
+			}
+			d.Sync.Path = d.Path
+		case "s3":
+			d.S3 = &S3DestinationConfig{URL: d.Path}
+		case "sftp":
+			d.SFTP = &SFTPDestinationConfig{URL: d.Path}
+		}
+		d.Path = ""
+	}
+
+	return nil
+}
+
+// checkBlocks rejects settings for a type other than the destination's, and a
+// flat path that contradicts the type's block
+func (d *DestinationConfig) checkBlocks() error {
+	blocks := map[string]bool{"local": d.Local != nil, "git": d.Git != nil, "sync": d.Sync != nil, "s3": d.S3 != nil, "sftp": d.SFTP != nil}
+	if _, known := blocks[d.Type]; !known {
+		return nil
+	}
+	for _, destType := range destinationTypes {
+		if blocks[destType] && destType != d.Type {
+			return fmt.Errorf("destination has %s settings but its type is %s", destType, d.Type)
+		}
+	}
+	if d.Path != "" && blocks[d.Type] && d.Location() != d.Path {
+		return fmt.Errorf("destination path %s conflicts with %s.%s", d.Path, d.Type, d.locationKey())
+	}
+	return nil
+}
+
+// Location returns where the destination stores backups: a folder path or, for
+// git, a remote URL or repository path, for s3, an s3://bucket/prefix URL, or
+// for sftp, an sftp://user@host:port/path URL
+func (d *DestinationConfig) Location() string {
+	switch {
+	case d.Type == "local" && d.Local != nil:
+		return d.Local.Path
+	case d.Type == "git" && d.Git != nil:
+		return d.Git.URL
+	case d.Type == "sync" && d.Sync != nil:
+		return d.Sync.Path
+	case d.Type == "s3" && d.S3 != nil:
+		return d.S3.URL
+	case d.Type == "sftp" && d.SFTP != nil:
+		return d.SFTP.URL
+	default:
+		return d.Path
+	}
+}
+
+// SetLocation changes where the destination stores backups, keeping its other settings
+func (d *DestinationConfig) SetLocation(location string) {
+	d.Path = location
+	switch {
+	case d.Type == "local" && d.Local != nil:
+		d.Local.Path, d.Path = location, ""
+	case d.Type == "git" && d.Git != nil:
+		d.Git.URL, d.Path = location, ""
+	case d.Type == "sync" && d.Sync != nil:
+		d.Sync.Path, d.Path = location, ""
+	case d.Type == "s3" && d.S3 != nil:
+		d.S3.URL, d.Path = location, ""
+	case d.Type == "sftp" && d.SFTP != nil:
+		d.SFTP.URL, d.Path = location, ""
+	}
+}
+
+// SetType changes the destination type, keeping its location. Settings that
+// only the old type has, such as deduplicate or sync verification, are dropped.
+func (d *DestinationConfig) SetType(destType string) error {
+	if !isDestinationType(destType) {
+		return fmt.Errorf("destination type must be %s, got %q", strings.Join(destinationTypes, ", "), destType)
+	}
+	if destType == d.Type {
+		return nil
+	}
+	location := d.Location()
+	*d = DestinationConfig{Type: destType, Path: location}
+	return d.Normalize()
+}
+
+// SyncVerify returns the post-write verification settings of a sync destination
+func (d *DestinationConfig) SyncVerify() SyncVerifyConfig {
+	if d.Type != "sync" || d.Sync == nil {
+		return SyncVerifyConfig{}
+	}
+	return d.Sync.Verify
+}
+
+// locationKey names the setting that holds the location in the type's block
+func (d *DestinationConfig) locationKey() string {
+	if d.Type == "git" || d.Type == "s3" || d.Type == "sftp" {
+		return "url"
+	}
+	return "path"
 }
 
 // IsGit returns true if the destination is a git repository
@@ -88,6 +489,16 @@ func (d *DestinationConfig) IsSync() bool {
 	return d.Type == "sync"
 }
 
+// IsS3 returns true if the destination is an S3 bucket
+func (d *DestinationConfig) IsS3() bool {
+	return d.Type == "s3"
+}
+
+// IsSFTP returns true if the destination is a folder on an SFTP server
+func (d *DestinationConfig) IsSFTP() bool {
+	return d.Type == "sftp"
+}
+
 // Hour returns the hour component of the schedule time
 func (s *ScheduleConfig) Hour() (int, error) {
 	parts := strings.Split(s.Time, ":")
@@ -106,8 +517,87 @@ func (s *ScheduleConfig) Minute() (int, error) {
 	return strconv.Atoi(parts[1])
 }
 
-// ConfigPath returns the path to the config file
+// UpdateChecksEnabled reports whether bulletproof may check GitHub for new releases
+func (o *BackupOptions) UpdateChecksEnabled() bool {
+	return o.CheckUpdates == nil || *o.CheckUpdates
+}
+
+// IncludeHiddenFiles reports whether files and directories starting with "." are backed up
+func (o *BackupOptions) IncludeHiddenFiles() bool {
+	return o.IncludeHidden == nil || *o.IncludeHidden
+}
+
+// SkipUnreadable reports whether backups leave out files they cannot read
+// instead of failing
+func (o *BackupOptions) SkipUnreadable() bool {
+	return o.OnReadError == "skip"
+}
+
+// CompressionMethod returns the method stored files are compressed with, or ""
+// for none
+func (o *BackupOptions) CompressionMethod() string {
+	if o.Compression == "none" {
+		return ""
+	}
+	return o.Compression
+}
+
+// ContentLimit returns the largest file whose content is kept in the manifest,
+// or 0 when store_content is off. Encrypted backups keep no content, since the
+// manifest itself is stored in the clear.
+func (o *BackupOptions) ContentLimit() int64 {
+	if !o.StoreContent || o.Encryption.Enabled {
+		return 0
+	}
+	if o.StoreContentMaxBytes <= 0 {
+		return DefaultStoreContentMaxBytes
+	}
+	return o.StoreContentMaxBytes
+}
+
+// ScriptsDir returns the directory scripts are read from and bundled into snapshots
+func (c *Config) ScriptsDir() (string, error) {
+	if c.Scripts.Dir != "" {
+		return utils.ExpandPath(c.Scripts.Dir)
+	}
+
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "scripts"), nil
+}
+
+// ConfigPathEnv is the environment variable naming another config file, e.g.
+// to keep a separate profile for each agent on one machine
+const ConfigPathEnv = "BULLETPROOF_CONFIG"
+
+// configPathOverride is the config file set with SetConfigPath
+var configPathOverride string
+
+// SetConfigPath makes ConfigPath return path, as the global --config flag
+// does, ahead of ConfigPathEnv. An empty path restores the usual lookup.
+func SetConfigPath(path string) {
+	configPathOverride = path
+}
+
+// ConfigPath returns the path to the config file: the one set with
+// SetConfigPath, else the one named by ConfigPathEnv, else
+// ~/.config/bulletproof/config.yaml. The directory holding it is ConfigDir, so
+// scripts, keys and state kept there follow a custom config file.
 func ConfigPath() (string, error) {
+	path := configPathOverride
+	if path == "" {
+		path = os.Getenv(ConfigPathEnv)
+	}
+	if path != "" {
+		expanded, err := utils.ExpandPath(path)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Abs(expanded)
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
@@ -177,10 +667,17 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Parse YAML
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+	config, fromVersion, err := parseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rewrite an upgraded file, keeping the original beside it. The upgrade
+	// is repeated on every load if the file cannot be written.
+	if fromVersion != ConfigVersion {
+		if err := os.WriteFile(configPath+".bak", data, 0644); err == nil {
+			config.Save()
+		}
 	}
 
 	// Set defaults if not specified
@@ -197,20 +694,107 @@ func Load() (*Config, error) {
 		config.Analytics.Enabled = true
 	}
 
-	return &config, nil
+	return config, nil
+}
+
+// Parse decodes the contents of a config file, such as the copy stored in a
+// backup, upgrading it first if it was written in an older version
+func Parse(data []byte) (*Config, error) {
+	config, _, err := parseConfig(data)
+	return config, err
+}
+
+// parseConfig decodes the contents of a config file after upgrading them to
+// ConfigVersion, and returns the version they were written in
+func parseConfig(data []byte) (*Config, string, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	fromVersion := "0"
+	if version, ok := raw["version"]; ok && version != nil {
+		fromVersion = fmt.Sprint(version)
+	}
+	if fromVersion != ConfigVersion {
+		if err := migrateConfig(raw, fromVersion); err != nil {
+			return nil, "", err
+		}
+		upgraded, err := yaml.Marshal(raw)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to upgrade config: %w", err)
+		}
+		data = upgraded
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := config.checkSourcePrefixes(); err != nil {
+		return nil, "", fmt.Errorf("invalid config: %w", err)
+	}
+	return &config, fromVersion, nil
+}
+
+// configMigrations upgrade the raw contents of a config file one version at a
+// time: entry i turns a version i file into a version i+1 one. Files without a
+// version field predate versioning and are version 0.
+var configMigrations = []func(raw map[string]interface{}) error{
+	// 0 to 1: versioning began without changing the layout
+	func(map[string]interface{}) error { return nil },
+
+	// 1 to 2: openclaw_path became the only entry of sources
+	func(raw map[string]interface{}) error {
+		path, ok := raw["openclaw_path"].(string)
+		if !ok || path == "" {
+			return nil
+		}
+		// With sources listed, openclaw_path still names the folder restores
+		// go to, so it stays
+		if sources, ok := raw["sources"].([]interface{}); ok && len(sources) > 0 {
+			return nil
+		}
+		raw["sources"] = []interface{}{path}
+		delete(raw, "openclaw_path")
+		return nil
+	},
+}
+
+// migrateConfig upgrades the raw contents of a config file written in
+// fromVersion to ConfigVersion in place
+func migrateConfig(raw map[string]interface{}, fromVersion string) error {
+	version, err := strconv.Atoi(fromVersion)
+	if err != nil || version < 0 {
+		return fmt.Errorf("invalid config version %q", fromVersion)
+	}
+	if version > len(configMigrations) {
+		return fmt.Errorf("config version %s is newer than this bulletproof supports (%s): upgrade bulletproof", fromVersion, ConfigVersion)
+	}
+
+	for ; version < len(configMigrations); version++ {
+		if err := configMigrations[version](raw); err != nil {
+			return fmt.Errorf("failed to upgrade config from version %d: %w", version, err)
+		}
+	}
+	raw["version"] = ConfigVersion
+	return nil
 }
 
 // saveConfig is the serialization wrapper that adds a version field
 type saveConfig struct {
-	Version      string             `yaml:"version"`
-	OpenclawPath string             `yaml:"openclaw_path,omitempty"`
-	Sources      []string           `yaml:"sources,omitempty"`
-	Destination  *DestinationConfig `yaml:"destination,omitempty"`
-	Schedule     ScheduleConfig     `yaml:"schedule"`
-	Options      BackupOptions      `yaml:"options"`
-	Scripts      *ScriptsConfig     `yaml:"scripts,omitempty"`
-	Analytics    AnalyticsConfig    `yaml:"analytics"`
-	Retention    *RetentionPolicy   `yaml:"retention,omitempty"`
+	Version       string               `yaml:"version"`
+	OpenclawPath  string               `yaml:"openclaw_path,omitempty"`
+	Sources       []SourceConfig       `yaml:"sources,omitempty"`
+	Destination   *DestinationConfig   `yaml:"destination,omitempty"`
+	Schedule      ScheduleConfig       `yaml:"schedule"`
+	Options       BackupOptions        `yaml:"options"`
+	Scripts       *ScriptsConfig       `yaml:"scripts,omitempty"`
+	Analytics     AnalyticsConfig      `yaml:"analytics"`
+	Retention     *RetentionPolicy     `yaml:"retention,omitempty"`
+	Keys          *KeysConfig          `yaml:"keys,omitempty"`
+	Anomaly       *AnomalyConfig       `yaml:"anomaly,omitempty"`
+	Notifications *NotificationsConfig `yaml:"notifications,omitempty"`
 }
 
 // Save saves the configuration to the config file using yaml.v3 marshaling
@@ -220,12 +804,26 @@ func (c *Config) Save() error {
 		return err
 	}
 
+	data, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+
 	// Create config directory if it doesn't exist
 	configDir := filepath.Dir(configPath)
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// Marshal renders the configuration as commented YAML, exactly as Save writes it
+func (c *Config) Marshal() ([]byte, error) {
 	sc := saveConfig{
 		Version:      ConfigVersion,
 		OpenclawPath: c.OpenclawPath,
@@ -237,19 +835,34 @@ func (c *Config) Save() error {
 	}
 
 	// Only include scripts section if any scripts are configured
-	if len(c.Scripts.PreBackup) > 0 || len(c.Scripts.PostRestore) > 0 {
+	if c.Scripts.Dir != "" || len(c.Scripts.PreBackup) > 0 || len(c.Scripts.PostRestore) > 0 {
 		sc.Scripts = &c.Scripts
 	}
 
 	// Only include retention section if any retention settings are configured
-	if c.Retention.Enabled || c.Retention.KeepLast > 0 || c.Retention.KeepDaily > 0 || c.Retention.KeepWeekly > 0 || c.Retention.KeepMonthly > 0 {
+	if c.Retention.Enabled || c.Retention.HasRules() {
 		sc.Retention = &c.Retention
 	}
 
+	// Only include keys section if any key references are configured
+	if c.Keys.EncryptionKey != "" {
+		sc.Keys = &c.Keys
+	}
+
+	// Only include anomaly section if detection has been configured
+	if c.Anomaly != (AnomalyConfig{}) {
+		sc.Anomaly = &c.Anomaly
+	}
+
+	// Only include notifications section if a webhook is configured
+	if c.Notifications != (NotificationsConfig{}) {
+		sc.Notifications = &c.Notifications
+	}
+
 	// Marshal to yaml.Node for comment support
 	var node yaml.Node
 	if err := node.Encode(sc); err != nil {
-		return fmt.Errorf("failed to encode config: %w", err)
+		return nil, fmt.Errorf("failed to encode config: %w", err)
 	}
 
 	// Add section comments
@@ -260,17 +873,13 @@ func (c *Config) Save() error {
 	enc := yaml.NewEncoder(&buf)
 	enc.SetIndent(2)
 	if err := enc.Encode(&node); err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
 	}
 	if err := enc.Close(); err != nil {
-		return fmt.Errorf("failed to finalize config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, buf.Bytes(), 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+		return nil, fmt.Errorf("failed to finalize config: %w", err)
 	}
 
-	return nil
+	return buf.Bytes(), nil
 }
 
 // addConfigComments annotates YAML mapping keys with descriptive comments
@@ -280,7 +889,7 @@ func addConfigComments(node *yaml.Node) {
 	}
 
 	comments := map[string]string{
-		"openclaw_path": "Path to OpenClaw installation (deprecated, use sources instead)",
+		"openclaw_path": "OpenClaw folder restores go to, when several sources are listed",
 		"sources":       "Source paths to back up (supports glob patterns)",
 		"destination":   "Backup destination",
 		"schedule":      "Backup schedule",
@@ -288,6 +897,9 @@ func addConfigComments(node *yaml.Node) {
 		"scripts":       "Script execution",
 		"analytics":     "Anonymous usage analytics",
 		"retention":     "Snapshot retention policy",
+		"keys":          "Key file references (key material is stored separately)",
+		"anomaly":       "Change-rate anomaly detection",
+		"notifications": "Backup outcome notifications",
 	}
 
 	for i := 0; i < len(node.Content)-1; i += 2 {
@@ -329,11 +941,50 @@ func (c *Config) String() string {
 		c.OpenclawPath, c.Destination, c.Schedule, c.Options)
 }
 
+// AgentPath returns the OpenClaw folder that restores and diffs work on:
+// OpenclawPath when set, else the only source if there is exactly one and it
+// is not a glob. It is empty when neither names one folder.
+func (c *Config) AgentPath() string {
+	if c.OpenclawPath != "" {
+		return c.OpenclawPath
+	}
+	if len(c.Sources) != 1 || strings.ContainsAny(c.Sources[0].Path, "*?[]") {
+		return ""
+	}
+	path, err := utils.ExpandPath(c.Sources[0].Path)
+	if err != nil {
+		return c.Sources[0].Path
+	}
+	return path
+}
+
+// SetAgentPath points the config at another OpenClaw folder. A config with at
+// most one source gets path as its only source, keeping the prefix and excludes
+// of the one it replaces; one with several keeps them
+// and records path as OpenclawPath, the folder restores go to.
+func (c *Config) SetAgentPath(path string) {
+	if len(c.Sources) > 1 {
+		c.OpenclawPath = path
+		return
+	}
+	source := SourceConfig{Path: path}
+	if len(c.Sources) == 1 {
+		source.Prefix = c.Sources[0].Prefix
+		source.Exclude = c.Sources[0].Exclude
+	}
+	c.Sources = []SourceConfig{source}
+	c.OpenclawPath = ""
+}
+
 // GetSources returns all source paths to back up
 // Returns Sources if configured, otherwise returns OpenclawPath for backward compatibility
 func (c *Config) GetSources() []string {
 	if len(c.Sources) > 0 {
-		return c.Sources
+		paths := make([]string, len(c.Sources))
+		for i, source := range c.Sources {
+			paths[i] = source.Path
+		}
+		return paths
 	}
 	if c.OpenclawPath != "" {
 		return []string{c.OpenclawPath}
@@ -341,9 +992,154 @@ func (c *Config) GetSources() []string {
 	return nil
 }
 
+// SourcePrefixes maps each source directory with a chosen prefix to that
+// prefix, expanding globs as the backup does. A prefix names one directory, so
+// a prefixed glob must match exactly one.
+func (c *Config) SourcePrefixes() (map[string]string, error) {
+	if err := c.checkSourcePrefixes(); err != nil {
+		return nil, err
+	}
+
+	prefixes := make(map[string]string)
+	for _, source := range c.Sources {
+		if source.Prefix == "" {
+			continue
+		}
+		paths, err := expandGlobPattern(source.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand source pattern %s: %w", source.Path, err)
+		}
+		if len(paths) > 1 {
+			return nil, fmt.Errorf("source %s matches %d directories but has a single prefix %q; list them as separate sources", source.Path, len(paths), source.Prefix)
+		}
+		for _, path := range paths {
+			prefixes[filepath.Clean(path)] = source.Prefix
+		}
+	}
+	return prefixes, nil
+}
+
+// ExcludesFor returns the exclude patterns for the source directory at path:
+// options.exclude followed by those of every source entry matching it, so a
+// source's own patterns take priority, e.g. to re-include a file with '!'
+func (c *Config) ExcludesFor(path string) []string {
+	exclude := c.Options.Exclude
+	path = filepath.Clean(path)
+	for _, source := range c.Sources {
+		if len(source.Exclude) == 0 {
+			continue
+		}
+		// A pattern that fails to expand is reported by ValidateSources
+		paths, err := expandGlobPattern(source.Path)
+		if err != nil {
+			continue
+		}
+		for _, match := range paths {
+			if filepath.Clean(match) == path {
+				exclude = append(append([]string(nil), exclude...), source.Exclude...)
+				break
+			}
+		}
+	}
+	return exclude
+}
+
+// checkSourcePrefixes rejects two sources with the same chosen prefix, whose
+// files would be stored in the same place
+func (c *Config) checkSourcePrefixes() error {
+	used := make(map[string]string)
+	for _, source := range c.Sources {
+		if source.Prefix == "" {
+			continue
+		}
+		if other, ok := used[source.Prefix]; ok {
+			return fmt.Errorf("sources %s and %s both use the prefix %q", other, source.Path, source.Prefix)
+		}
+		used[source.Prefix] = source.Path
+	}
+	return nil
+}
+
 // Validate performs comprehensive validation of the configuration
 func (c *Config) Validate() error {
-	// Validate destination
+	if err := c.ValidateDestination(); err != nil {
+		return err
+	}
+	if err := c.ValidateSources(); err != nil {
+		return err
+	}
+
+	// Validate script files exist and are executable
+	for _, script := range c.Scripts.PreBackup {
+		if err := validateScript(script); err != nil {
+			return fmt.Errorf("pre-backup script %s: %w", script.Name, err)
+		}
+	}
+	for _, script := range c.Scripts.PostRestore {
+		if err := validateScript(script); err != nil {
+			return fmt.Errorf("post-restore script %s: %w", script.Name, err)
+		}
+	}
+
+	// Validate empty-source guards
+	if c.Options.MinFiles < 0 || c.Options.MinBytes < 0 {
+		return fmt.Errorf("options min_files and min_bytes cannot be negative")
+	}
+	if c.Options.MaxFileDrop < 0 || c.Options.MaxFileDrop > 100 {
+		return fmt.Errorf("options max_file_drop must be a percentage between 0 and 100")
+	}
+	if c.Options.StoreContentMaxBytes < 0 {
+		return fmt.Errorf("options store_content_max_bytes cannot be negative")
+	}
+	if c.Options.MaxFileSize < 0 {
+		return fmt.Errorf("options max_file_size cannot be negative")
+	}
+	switch c.Options.OnReadError {
+	case "", "fail", "skip":
+	default:
+		return fmt.Errorf("options on_read_error must be fail or skip, got %s", c.Options.OnReadError)
+	}
+
+	// Validate include patterns, which must not silently match nothing
+	for _, pattern := range c.Options.Include {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("options include contains an empty pattern")
+		}
+		if err := types.ValidatePattern(pattern); err != nil {
+			return fmt.Errorf("options include pattern %q is invalid: %w", pattern, err)
+		}
+	}
+
+	// Validate compression
+	switch c.Options.Compression {
+	case "", "none", "gzip", "zstd":
+	default:
+		return fmt.Errorf("options compression must be none, gzip or zstd, got %s", c.Options.Compression)
+	}
+
+	// Validate the notification webhook
+	if url := c.Notifications.WebhookURL; url != "" && !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return fmt.Errorf("notifications webhook_url must be an http:// or https:// URL, got %s", url)
+	}
+
+	// Validate retention policy
+	if c.Retention.Enabled {
+		if c.Retention.KeepLast < 0 || c.Retention.KeepDaily < 0 || c.Retention.KeepWeekly < 0 || c.Retention.KeepMonthly < 0 ||
+			c.Retention.MaxTotalSizeBytes < 0 || c.Retention.MaxAgeDays < 0 {
+			return fmt.Errorf("retention policy values cannot be negative")
+		}
+		if !c.Retention.HasRules() {
+			return fmt.Errorf("retention policy enabled but no retention rules configured")
+		}
+	}
+
+	return nil
+}
+
+// ValidateDestination checks the destination settings: a known type, a
+// location, a writable folder for local and sync destinations, and options
+// the destination type supports
+func (c *Config) ValidateDestination() error {
 	if c.Destination == nil {
 		return errors.NewActionableError(
 			"validate configuration",
@@ -354,22 +1150,40 @@ func (c *Config) Validate() error {
 		)
 	}
 
+	if !isDestinationType(c.Destination.Type) {
+		return fmt.Errorf("destination type must be %s, got %q", strings.Join(destinationTypes, ", "), c.Destination.Type)
+	}
+	if err := c.Destination.checkBlocks(); err != nil {
+		return err
+	}
+
 	// Check if destination path exists
-	if c.Destination.Path == "" {
+	destPath := c.Destination.Location()
+	if destPath == "" {
+		if blockSet := c.Destination.Local != nil || c.Destination.Git != nil || c.Destination.Sync != nil || c.Destination.S3 != nil || c.Destination.SFTP != nil; blockSet {
+			return fmt.Errorf("destination %s.%s is empty", c.Destination.Type, c.Destination.locationKey())
+		}
 		return fmt.Errorf("destination path is empty")
 	}
 
+	if c.Destination.Type == "s3" && !strings.HasPrefix(destPath, "s3://") {
+		return fmt.Errorf("s3 destination url must look like s3://bucket/prefix, got %s", destPath)
+	}
+	if c.Destination.Type == "sftp" && !strings.HasPrefix(destPath, "sftp://") {
+		return fmt.Errorf("sftp destination url must look like sftp://user@host:port/path, got %s", destPath)
+	}
+
 	// For local and sync destinations, check if path is writable
 	if c.Destination.Type == "local" || c.Destination.Type == "sync" {
 		// Check if destination exists
-		info, err := os.Stat(c.Destination.Path)
+		info, err := os.Stat(destPath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				// Try to create it
-				if err := os.MkdirAll(c.Destination.Path, 0755); err != nil {
+				if err := os.MkdirAll(destPath, 0755); err != nil {
 					return errors.BackupDestinationError(
 						"create backup destination",
-						c.Destination.Path,
+						destPath,
 						err,
 					)
 				}
@@ -379,24 +1193,61 @@ func (c *Config) Validate() error {
 		} else if !info.IsDir() {
 			return errors.BackupDestinationError(
 				"validate backup destination",
-				c.Destination.Path,
+				destPath,
 				fmt.Errorf("path is not a directory"),
 			)
 		}
 
 		// Check write permissions by creating a test file
-		testFile := filepath.Join(c.Destination.Path, ".bulletproof_test")
+		testFile := filepath.Join(destPath, ".bulletproof_test")
 		if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
 			return errors.PermissionDenied(
 				"write to backup destination",
-				c.Destination.Path,
+				destPath,
 				err,
 			)
 		}
 		os.Remove(testFile)
 	}
 
-	// Validate sources
+	if c.Destination.Type == "git" && c.Destination.Git != nil {
+		if retries := c.Destination.Git.Retries; retries != nil && (*retries < 0 || *retries > 10) {
+			return fmt.Errorf("destination git.retries must be between 0 and 10, got %d", *retries)
+		}
+	}
+
+	// Validate sync verification
+	if verify := c.Destination.SyncVerify(); verify.Enabled {
+		if verify.Attempts < 0 || verify.Interval < 0 {
+			return fmt.Errorf("destination verify attempts and interval cannot be negative")
+		}
+		if verify.Check != nil {
+			check := *verify.Check
+			if check.Name == "" {
+				check.Name = "sync check"
+			}
+			if err := validateScript(check); err != nil {
+				return fmt.Errorf("destination verify check: %w", err)
+			}
+		}
+	}
+
+	// Compression and encryption apply where the engine writes the files itself
+	if c.Destination.Type != "local" && c.Destination.Type != "sync" {
+		if c.Options.Encryption.Enabled {
+			return fmt.Errorf("options encryption needs a local or sync destination, not %s", c.Destination.Type)
+		}
+		if c.Options.CompressionMethod() != "" {
+			return fmt.Errorf("options compression needs a local or sync destination, not %s", c.Destination.Type)
+		}
+	}
+
+	return nil
+}
+
+// ValidateSources checks that every source resolves to readable
+// directories and that their prefixes do not collide
+func (c *Config) ValidateSources() error {
 	sources := c.GetSources()
 	if len(sources) == 0 {
 		return errors.OpenClawNotFound()
@@ -464,26 +1315,8 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	// Validate script files exist and are executable
-	for _, script := range c.Scripts.PreBackup {
-		if err := validateScript(script); err != nil {
-			return fmt.Errorf("pre-backup script %s: %w", script.Name, err)
-		}
-	}
-	for _, script := range c.Scripts.PostRestore {
-		if err := validateScript(script); err != nil {
-			return fmt.Errorf("post-restore script %s: %w", script.Name, err)
-		}
-	}
-
-	// Validate retention policy
-	if c.Retention.Enabled {
-		if c.Retention.KeepLast < 0 || c.Retention.KeepDaily < 0 || c.Retention.KeepWeekly < 0 || c.Retention.KeepMonthly < 0 {
-			return fmt.Errorf("retention policy values cannot be negative")
-		}
-		if c.Retention.KeepLast == 0 && c.Retention.KeepDaily == 0 && c.Retention.KeepWeekly == 0 && c.Retention.KeepMonthly == 0 {
-			return fmt.Errorf("retention policy enabled but no retention rules configured")
-		}
+	if _, err := c.SourcePrefixes(); err != nil {
+		return err
 	}
 
 	return nil
@@ -498,6 +1331,18 @@ func validateScript(script ScriptConfig) error {
 		return fmt.Errorf("script command is empty")
 	}
 
+	// A shell interprets the command itself, so only the shell has to exist
+	if script.Shell != "" {
+		shellParts := strings.Fields(script.Shell)
+		if len(shellParts) == 0 {
+			return fmt.Errorf("script shell is empty after parsing")
+		}
+		if _, err := exec.LookPath(shellParts[0]); err != nil {
+			return fmt.Errorf("script shell not found: %s", shellParts[0])
+		}
+		return nil
+	}
+
 	// If the command is a path to a file, check if it exists and is executable
 	// Otherwise assume it's a shell command
 	parts := strings.Fields(script.Command)
@@ -3,7 +3,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoad_EmptyConfig(t *testing.T) {
@@ -54,6 +59,7 @@ func TestSave_Load_RoundTrip(t *testing.T) {
 		},
 		Options: BackupOptions{
 			IncludeAuth: true,
+			Include:     []string{"workspace/SOUL.md", "skills/*.js", "workspace/memory/"},
 			Exclude:     []string{"*.tmp", "cache/"},
 		},
 	}
@@ -78,8 +84,8 @@ func TestSave_Load_RoundTrip(t *testing.T) {
 		t.Errorf("Destination.Type: got %s, want %s", loaded.Destination.Type, cfg.Destination.Type)
 	}
 
-	if loaded.Destination.Path != cfg.Destination.Path {
-		t.Errorf("Destination.Path: got %s, want %s", loaded.Destination.Path, cfg.Destination.Path)
+	if loaded.Destination.Location() != cfg.Destination.Path {
+		t.Errorf("Destination location: got %s, want %s", loaded.Destination.Location(), cfg.Destination.Path)
 	}
 
 	if loaded.Schedule.Enabled != cfg.Schedule.Enabled {
@@ -93,6 +99,10 @@ func TestSave_Load_RoundTrip(t *testing.T) {
 	if loaded.Options.IncludeAuth != cfg.Options.IncludeAuth {
 		t.Errorf("Options.IncludeAuth: got %v, want %v", loaded.Options.IncludeAuth, cfg.Options.IncludeAuth)
 	}
+
+	if !reflect.DeepEqual(loaded.Options.Include, cfg.Options.Include) {
+		t.Errorf("Options.Include: got %v, want %v", loaded.Options.Include, cfg.Options.Include)
+	}
 }
 
 func TestSave_Load_RoundTrip_SpecialChars(t *testing.T) {
@@ -138,8 +148,8 @@ func TestSave_Load_RoundTrip_SpecialChars(t *testing.T) {
 	if loaded.OpenclawPath != cfg.OpenclawPath {
 		t.Errorf("OpenclawPath: got %q, want %q", loaded.OpenclawPath, cfg.OpenclawPath)
 	}
-	if loaded.Destination.Path != cfg.Destination.Path {
-		t.Errorf("Destination.Path: got %q, want %q", loaded.Destination.Path, cfg.Destination.Path)
+	if loaded.Destination.Location() != cfg.Destination.Path {
+		t.Errorf("Destination location: got %q, want %q", loaded.Destination.Location(), cfg.Destination.Path)
 	}
 	if len(loaded.Options.Exclude) != len(cfg.Options.Exclude) {
 		t.Fatalf("Exclude count: got %d, want %d", len(loaded.Options.Exclude), len(cfg.Options.Exclude))
@@ -248,6 +258,60 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestDetectInstallations(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv(OpenclawPathEnv, "")
+
+	root := t.TempDir()
+	t.Setenv(SearchRootsEnv, root)
+	original := DefaultSearchRoots
+	DefaultSearchRoots = nil
+	t.Cleanup(func() { DefaultSearchRoots = original })
+
+	install := func(path string) string {
+		t.Helper()
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "openclaw.json"), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	if found := DetectInstallations(); len(found) != 0 {
+		t.Fatalf("expected no installations, got %v", found)
+	}
+
+	// A folder without openclaw.json is not an installation
+	if err := os.MkdirAll(filepath.Join(root, "bob", ".openclaw"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		install(filepath.Join(home, ".openclaw")),
+		install(filepath.Join(home, ".local", "share", "openclaw")),
+		install(filepath.Join(home, "xdg-config", "openclaw")),
+		install(filepath.Join(root, ".openclaw")),
+		install(filepath.Join(root, "alice", ".openclaw")),
+	}
+	if found := DetectInstallations(); !reflect.DeepEqual(found, want) {
+		t.Errorf("DetectInstallations() = %v, want %v", found, want)
+	}
+	if got := DetectInstallation(); got != want[0] {
+		t.Errorf("DetectInstallation() = %s, want %s", got, want[0])
+	}
+
+	// The environment override skips the search
+	t.Setenv(OpenclawPathEnv, "/srv/agent")
+	if found := DetectInstallations(); !reflect.DeepEqual(found, []string{"/srv/agent"}) {
+		t.Errorf("expected only the override, got %v", found)
+	}
+}
+
 func TestConfigPath_NoHomeDir(t *testing.T) {
 	// Save original HOME
 	originalHome := os.Getenv("HOME")
@@ -271,3 +335,383 @@ func TestConfigPath_NoHomeDir(t *testing.T) {
 		t.Error("ConfigPath() should return a descriptive error message")
 	}
 }
+
+func TestConfigPath_Overrides(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ConfigPathEnv, "")
+	t.Cleanup(func() { SetConfigPath("") })
+
+	if path, err := ConfigPath(); err != nil || path != filepath.Join(home, ".config", "bulletproof", "config.yaml") {
+		t.Fatalf("default ConfigPath = %s (%v)", path, err)
+	}
+
+	// The environment variable moves the config file and the directory beside it
+	t.Setenv(ConfigPathEnv, "~/profiles/work/config.yaml")
+	if path, err := ConfigPath(); err != nil || path != filepath.Join(home, "profiles", "work", "config.yaml") {
+		t.Errorf("ConfigPath from %s = %s (%v)", ConfigPathEnv, path, err)
+	}
+	if dir, err := ConfigDir(); err != nil || dir != filepath.Join(home, "profiles", "work") {
+		t.Errorf("ConfigDir from %s = %s (%v)", ConfigPathEnv, dir, err)
+	}
+
+	// --config wins over the environment, and relative paths are made absolute
+	t.Chdir(home)
+	SetConfigPath("agent-b.yaml")
+	if path, err := ConfigPath(); err != nil || path != filepath.Join(home, "agent-b.yaml") {
+		t.Errorf("ConfigPath from SetConfigPath = %s (%v)", path, err)
+	}
+	cfg := &Config{OpenclawPath: "/agents/b"}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load()
+	if err != nil || loaded.OpenclawPath != "/agents/b" {
+		t.Errorf("expected the config saved to agent-b.yaml, got %+v (%v)", loaded, err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config", "bulletproof", "config.yaml")); !os.IsNotExist(err) {
+		t.Error("expected the default config file to be left alone")
+	}
+}
+
+func TestLoad_MigratesOldVersions(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ConfigPathEnv, "")
+	configPath := filepath.Join(home, ".config", "bulletproof", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A config from before versioning, with the flat openclaw_path
+	old := `openclaw_path: /home/me/.openclaw
+destination:
+  type: local
+  path: /backups
+schedule:
+  enabled: true
+  time: "04:00"
+`
+	if err := os.WriteFile(configPath, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.OpenclawPath != "" || !reflect.DeepEqual(cfg.Sources, NewSources("/home/me/.openclaw")) {
+		t.Errorf("expected openclaw_path moved to sources, got %q and %+v", cfg.OpenclawPath, cfg.Sources)
+	}
+	if cfg.AgentPath() != "/home/me/.openclaw" || cfg.Destination.Location() != "/backups" || cfg.Schedule.Time != "04:00" {
+		t.Errorf("expected the rest of the config kept, got agent %q, destination %q, time %q", cfg.AgentPath(), cfg.Destination.Location(), cfg.Schedule.Time)
+	}
+
+	// The file is rewritten in the current version, the original kept beside it
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "version: \""+ConfigVersion+"\"") || strings.Contains(string(data), "openclaw_path") {
+		t.Errorf("expected the file rewritten in version %s, got:\n%s", ConfigVersion, data)
+	}
+	if backup, err := os.ReadFile(configPath + ".bak"); err != nil || string(backup) != old {
+		t.Errorf("expected the original kept as config.yaml.bak, got %q (%v)", backup, err)
+	}
+
+	// With several sources, openclaw_path still names the restore target
+	cfg, err = Parse([]byte("version: \"1\"\nopenclaw_path: /a\nsources:\n  - /a\n  - /b\n"))
+	if err != nil || cfg.AgentPath() != "/a" || len(cfg.Sources) != 2 {
+		t.Errorf("expected a multi-source config unchanged, got %+v (%v)", cfg, err)
+	}
+
+	// A file from a newer bulletproof is refused rather than misread
+	if _, err := Parse([]byte("version: \"99\"\n")); err == nil || !strings.Contains(err.Error(), "upgrade bulletproof") {
+		t.Errorf("expected a newer config version to be refused, got %v", err)
+	}
+	if ConfigVersion != strconv.Itoa(len(configMigrations)) {
+		t.Errorf("ConfigVersion %s does not match the %d migrations", ConfigVersion, len(configMigrations))
+	}
+}
+
+func TestConfig_SetAgentPath(t *testing.T) {
+	cfg := &Config{OpenclawPath: "/old"}
+	cfg.SetAgentPath("/new")
+	if cfg.OpenclawPath != "" || !reflect.DeepEqual(cfg.Sources, NewSources("/new")) {
+		t.Errorf("expected the only source replaced, got %q and %+v", cfg.OpenclawPath, cfg.Sources)
+	}
+
+	cfg = &Config{Sources: []SourceConfig{{Path: "/a"}, {Path: "/b", Prefix: "b"}}}
+	cfg.SetAgentPath("/a2")
+	if cfg.AgentPath() != "/a2" || len(cfg.Sources) != 2 {
+		t.Errorf("expected sources kept and the restore target set, got %q and %+v", cfg.AgentPath(), cfg.Sources)
+	}
+	if (&Config{Sources: NewSources("~/agents/*")}).AgentPath() != "" {
+		t.Error("expected a glob source not to name the agent folder")
+	}
+}
+
+func TestMarshal_DoesNotWriteFile(t *testing.T) {
+	tempDir := t.TempDir()
+	originalPath := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalPath)
+
+	cfg := &Config{
+		OpenclawPath: "/test/openclaw",
+		Destination: &DestinationConfig{
+			Type: "local",
+			Path: "/test/backup",
+		},
+		Schedule: ScheduleConfig{Enabled: true, Time: "03:00"},
+	}
+
+	data, err := cfg.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+
+	content := string(data)
+	for _, want := range []string{"version: \"2\"", "openclaw_path: /test/openclaw", "path: /test/backup"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Marshal() output missing %q:\n%s", want, content)
+		}
+	}
+
+	exists, err := Exists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Error("Marshal() should not write the config file")
+	}
+}
+
+func TestValidateScript_Shell(t *testing.T) {
+	// A shell command need not start with an executable path
+	script := ScriptConfig{Name: "export", Command: "FOO=bar ./export.sh && echo done", Shell: "/bin/sh -c"}
+	if err := validateScript(script); err != nil {
+		t.Errorf("validateScript() with shell failed: %v", err)
+	}
+
+	script.Shell = "/nonexistent/shell -c"
+	if err := validateScript(script); err == nil {
+		t.Error("validateScript() should fail when the shell does not exist")
+	}
+}
+
+func TestDestinationConfig_FlatAndBlockForms(t *testing.T) {
+	flat := "destination:\n  type: git\n  path: git@github.com:me/backups.git\n"
+	block := "destination:\n  type: git\n  git:\n    url: git@github.com:me/backups.git\n"
+
+	for name, data := range map[string]string{"flat": flat, "block": block} {
+		var cfg Config
+		if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+			t.Fatalf("%s: Unmarshal failed: %v", name, err)
+		}
+		d := cfg.Destination
+		if d.Git == nil || d.Git.URL != "git@github.com:me/backups.git" || d.Path != "" {
+			t.Errorf("%s: expected the git block to hold the URL, got %+v", name, d)
+		}
+		if d.Location() != "git@github.com:me/backups.git" {
+			t.Errorf("%s: Location() = %q", name, d.Location())
+		}
+	}
+
+	// Saving writes the block form, which reads back the same
+	cfg := &Config{Destination: NewDestinationConfig("sync", "/sync/bulletproof")}
+	data, err := cfg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "sync:\n    path: /sync/bulletproof") {
+		t.Errorf("expected a sync block in:\n%s", data)
+	}
+
+	// S3 destinations keep their URL in the s3 block
+	var s3 Config
+	if err := yaml.Unmarshal([]byte("destination:\n  type: s3\n  path: s3://backups/agent\n"), &s3); err != nil {
+		t.Fatal(err)
+	}
+	if s3.Destination.S3 == nil || s3.Destination.Location() != "s3://backups/agent" {
+		t.Errorf("expected the s3 block to hold the URL, got %+v", s3.Destination)
+	}
+
+	// As do SFTP destinations in the sftp block
+	var sftp Config
+	if err := yaml.Unmarshal([]byte("destination:\n  type: sftp\n  path: sftp://backup@nas:2222/srv/agent\n"), &sftp); err != nil {
+		t.Fatal(err)
+	}
+	if sftp.Destination.SFTP == nil || sftp.Destination.Location() != "sftp://backup@nas:2222/srv/agent" {
+		t.Errorf("expected the sftp block to hold the URL, got %+v", sftp.Destination)
+	}
+
+	// The git block keeps its author when a flat path names the same repository
+	var authored Config
+	data = []byte("destination:\n  type: git\n  path: /repo\n  git:\n    url: /repo\n    author:\n      name: Ops Team\n      email: ops@example.com\n")
+	if err := yaml.Unmarshal(data, &authored); err != nil {
+		t.Fatal(err)
+	}
+	if a := authored.Destination.Git.Author; a == nil || a.Name != "Ops Team" || a.Email != "ops@example.com" {
+		t.Errorf("expected the git author to be kept, got %+v", authored.Destination.Git)
+	}
+
+	// Settings for a different type are rejected rather than ignored
+	var mismatched Config
+	err = yaml.Unmarshal([]byte("destination:\n  type: local\n  git:\n    url: /repo\n"), &mismatched)
+	if err == nil || !strings.Contains(err.Error(), "git settings but its type is local") {
+		t.Errorf("expected a type mismatch error, got %v", err)
+	}
+}
+
+func TestSourceConfig_PathAndPrefixForms(t *testing.T) {
+	data := "sources:\n  - ~/.openclaw\n  - path: /mnt/laptop/.openclaw\n    prefix: laptop\n"
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	expected := []SourceConfig{{Path: "~/.openclaw"}, {Path: "/mnt/laptop/.openclaw", Prefix: "laptop"}}
+	if !reflect.DeepEqual(cfg.Sources, expected) {
+		t.Errorf("Sources = %+v, want %+v", cfg.Sources, expected)
+	}
+	if paths := cfg.GetSources(); !reflect.DeepEqual(paths, []string{"~/.openclaw", "/mnt/laptop/.openclaw"}) {
+		t.Errorf("GetSources() = %v", paths)
+	}
+
+	// Sources without a prefix are saved as plain paths, so older configs read the same
+	out, err := cfg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "  - ~/.openclaw\n  - path: /mnt/laptop/.openclaw\n    prefix: laptop\n") {
+		t.Errorf("unexpected sources in:\n%s", out)
+	}
+
+	for _, bad := range []string{
+		"sources:\n  - path: /a\n    prefix: ../up\n",
+		"sources:\n  - path: /a\n    prefix: .bulletproof\n",
+		"sources:\n  - prefix: main\n",
+	} {
+		var invalid Config
+		if err := yaml.Unmarshal([]byte(bad), &invalid); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestSourceConfig_ExcludeRoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+
+	cfg := &Config{
+		Sources: []SourceConfig{
+			{Path: "~/.openclaw", Exclude: []string{"*.log"}},
+			{Path: "/srv/app", Prefix: "app", Exclude: []string{"node_modules/", "!node_modules/keep.json"}},
+			{Path: "/srv/plain"},
+		},
+		Destination: &DestinationConfig{Type: "local", Path: filepath.Join(tmpDir, "backups")},
+		Options:     BackupOptions{Exclude: []string{"*.tmp"}},
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	configPath, err := ConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "  - path: ~/.openclaw\n    exclude:\n      - '*.log'\n") || !strings.Contains(string(data), "  - /srv/plain\n") {
+		t.Errorf("unexpected sources in:\n%s", data)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Sources, cfg.Sources) {
+		t.Errorf("Sources = %+v, want %+v", loaded.Sources, cfg.Sources)
+	}
+}
+
+func TestConfig_ExcludesFor(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"one", "two"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &Config{
+		Sources: []SourceConfig{
+			{Path: filepath.Join(root, "one") + "/", Exclude: []string{"*.log"}},
+			{Path: filepath.Join(root, "t*"), Exclude: []string{"node_modules/"}},
+		},
+		Options: BackupOptions{Exclude: []string{".git/"}},
+	}
+	tests := map[string][]string{
+		filepath.Join(root, "one"):   {".git/", "*.log"},
+		filepath.Join(root, "two"):   {".git/", "node_modules/"},
+		filepath.Join(root, "other"): {".git/"},
+	}
+	for path, want := range tests {
+		if got := cfg.ExcludesFor(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("ExcludesFor(%s) = %v, want %v", path, got, want)
+		}
+	}
+	if !reflect.DeepEqual(cfg.Options.Exclude, []string{".git/"}) {
+		t.Errorf("expected the global excludes to be left alone, got %v", cfg.Options.Exclude)
+	}
+}
+
+func TestLoad_RejectsDuplicateSourcePrefixes(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	configPath, err := ConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := "sources:\n  - path: /a/.openclaw\n    prefix: main\n  - path: /b/.openclaw\n    prefix: main\n"
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), `both use the prefix "main"`) {
+		t.Errorf("expected duplicate prefixes to be rejected, got %v", err)
+	}
+}
+
+func TestSourcePrefixes(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"one", "two"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &Config{Sources: []SourceConfig{
+		{Path: filepath.Join(root, "one") + "/", Prefix: "first"},
+		{Path: filepath.Join(root, "t*"), Prefix: "second"},
+		{Path: "/elsewhere"},
+	}}
+	prefixes, err := cfg.SourcePrefixes()
+	if err != nil {
+		t.Fatalf("SourcePrefixes failed: %v", err)
+	}
+	expected := map[string]string{filepath.Join(root, "one"): "first", filepath.Join(root, "two"): "second"}
+	if !reflect.DeepEqual(prefixes, expected) {
+		t.Errorf("SourcePrefixes() = %v, want %v", prefixes, expected)
+	}
+
+	// One prefix cannot name every directory a glob matches
+	cfg.Sources = []SourceConfig{{Path: filepath.Join(root, "*"), Prefix: "all"}}
+	if _, err := cfg.SourcePrefixes(); err == nil || !strings.Contains(err.Error(), "matches 2 directories") {
+		t.Errorf("expected a prefixed glob with several matches to be rejected, got %v", err)
+	}
+}
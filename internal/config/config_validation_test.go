@@ -204,6 +204,115 @@ func TestConfig_Validate_RetentionPolicy(t *testing.T) {
 	}
 }
 
+func TestConfig_Validate_SyncVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "source")
+	destDir := filepath.Join(tmpDir, "dest")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create source directory: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		verify    SyncVerifyConfig
+		wantError bool
+	}{
+		{
+			name:      "Sync with provider check",
+			verify:    SyncVerifyConfig{Enabled: true, Check: &ScriptConfig{Command: "true"}},
+			wantError: false,
+		},
+		{
+			name:      "Negative attempts",
+			verify:    SyncVerifyConfig{Enabled: true, Attempts: -1},
+			wantError: true,
+		},
+		{
+			name:      "Missing check command",
+			verify:    SyncVerifyConfig{Enabled: true, Check: &ScriptConfig{}},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				OpenclawPath: sourceDir,
+				Destination: &DestinationConfig{
+					Type: "sync",
+					Sync: &SyncDestinationConfig{Path: destDir, Verify: tt.verify},
+				},
+			}
+
+			err := cfg.Validate()
+			if tt.wantError && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.wantError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_NotificationWebhook(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "source")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create source directory: %v", err)
+	}
+
+	for url, wantError := range map[string]bool{
+		"":                                   false,
+		"https://hooks.slack.com/services/x": false,
+		"http://localhost:8080/hook":         false,
+		"hooks.slack.com/services/x":         true,
+		"ftp://example.com/hook":             true,
+	} {
+		cfg := &Config{
+			OpenclawPath:  sourceDir,
+			Destination:   &DestinationConfig{Type: "local", Path: filepath.Join(tmpDir, "dest")},
+			Notifications: NotificationsConfig{WebhookURL: url},
+		}
+		if err := cfg.Validate(); (err != nil) != wantError {
+			t.Errorf("webhook %q: Validate() = %v, want error %v", url, err, wantError)
+		}
+	}
+}
+
+func TestConfig_Validate_GitRetries(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	for retries, wantError := range map[int]bool{0: false, 3: false, 10: false, -1: true, 11: true} {
+		cfg := &Config{
+			OpenclawPath: sourceDir,
+			Destination: &DestinationConfig{Type: "git", Git: &GitDestinationConfig{
+				URL:     "git@github.com:user/backups.git",
+				Retries: &retries,
+			}},
+		}
+		if err := cfg.Validate(); (err != nil) != wantError {
+			t.Errorf("retries %d: Validate() = %v, want error %v", retries, err, wantError)
+		}
+	}
+}
+
+func TestConfig_Validate_IncludePatterns(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	for pattern, wantError := range map[string]bool{"memory/**/*.json": false, "a(**": false, "skills/*.js": false, "skills/[a": true, " ": true} {
+		cfg := &Config{
+			OpenclawPath: sourceDir,
+			Destination:  &DestinationConfig{Type: "local", Path: t.TempDir()},
+			Options:      BackupOptions{Include: []string{pattern}},
+		}
+		if err := cfg.Validate(); (err != nil) != wantError {
+			t.Errorf("include %q: Validate() = %v, want error %v", pattern, err, wantError)
+		}
+	}
+}
+
 func TestConfig_Validate_GlobPatterns(t *testing.T) {
 	tmpDir := t.TempDir()
 	destDir := filepath.Join(tmpDir, "dest")
@@ -220,7 +329,7 @@ func TestConfig_Validate_GlobPatterns(t *testing.T) {
 
 	// Test with glob pattern
 	cfg := &Config{
-		Sources: []string{filepath.Join(tmpDir, "source*")},
+		Sources: NewSources(filepath.Join(tmpDir, "source*")),
 		Destination: &DestinationConfig{
 			Type: "local",
 			Path: destDir,
@@ -238,7 +347,7 @@ func TestConfig_Validate_GlobNoMatches(t *testing.T) {
 
 	// Glob pattern that matches nothing
 	cfg := &Config{
-		Sources: []string{filepath.Join(tmpDir, "nonexistent*")},
+		Sources: NewSources(filepath.Join(tmpDir, "nonexistent*")),
 		Destination: &DestinationConfig{
 			Type: "local",
 			Path: destDir,
@@ -261,7 +370,7 @@ func TestConfig_GetSources(t *testing.T) {
 		{
 			name: "Sources configured",
 			cfg: &Config{
-				Sources: []string{"/path1", "/path2"},
+				Sources: NewSources("/path1", "/path2"),
 			},
 			wantCount:   2,
 			description: "Should return Sources when configured",
@@ -277,7 +386,7 @@ func TestConfig_GetSources(t *testing.T) {
 		{
 			name: "Both configured - Sources takes precedence",
 			cfg: &Config{
-				Sources:      []string{"/path1", "/path2"},
+				Sources:      NewSources("/path1", "/path2"),
 				OpenclawPath: "/openclaw",
 			},
 			wantCount:   2,
@@ -1,4 +1,5 @@
 // Package config handles bulletproof configuration management and OpenClaw detection.
-// It provides YAML-based configuration at ~/.config/bulletproof/config.yaml and
-// automatic detection of OpenClaw installations.
+// It provides YAML-based configuration at ~/.config/bulletproof/config.yaml, or
+// the file named by --config or BULLETPROOF_CONFIG, and automatic detection of
+// OpenClaw installations.
 package config
@@ -6,31 +6,101 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+
+	"github.com/bulletproof-bot/backup/internal/utils"
 )
 
+// OpenclawPathEnv is the environment variable naming the OpenClaw folder.
+// When set, detection returns it without looking anywhere else.
+const OpenclawPathEnv = "BULLETPROOF_OPENCLAW_PATH"
+
+// SearchRootsEnv is the environment variable listing more folders to search
+// for OpenClaw installations, separated like PATH
+const SearchRootsEnv = "BULLETPROOF_OPENCLAW_SEARCH"
+
+// DefaultSearchRoots are the folders searched for installations besides the
+// user's own: Docker volume mounts and the homes of other users
+var DefaultSearchRoots = []string{"/data", "/app", "/home", "/Users"}
+
 // DetectInstallation detects the OpenClaw installation path
-// Returns the path if found, empty string otherwise
+// Returns the first installation DetectInstallations finds, empty string otherwise
 func DetectInstallation() string {
-	// Check default location first
-	defaultRoot := DefaultRoot()
-	if isInstalled(defaultRoot) {
-		return defaultRoot
+	if found := DetectInstallations(); len(found) > 0 {
+		return found[0]
+	}
+	return ""
+}
+
+// DetectInstallations returns every OpenClaw installation found, most likely
+// first: the default ~/.openclaw, openclaw folders under XDG_DATA_HOME and
+// XDG_CONFIG_HOME, then the roots in SearchRootsEnv and DefaultSearchRoots.
+// Each root is checked itself and for an .openclaw or openclaw folder in it or
+// in any folder directly below it, as in /home/<user>/.openclaw. If
+// OpenclawPathEnv is set, its value is returned alone.
+func DetectInstallations() []string {
+	if path := os.Getenv(OpenclawPathEnv); path != "" {
+		if expanded, err := utils.ExpandPath(path); err == nil {
+			path = expanded
+		}
+		return []string{path}
 	}
 
-	// Check common Docker volume mounts
-	dockerPaths := []string{
-		"/data/.openclaw",
-		"/openclaw",
-		"/app/.openclaw",
+	home := homeDirectory()
+	candidates := []string{DefaultRoot()}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
 	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	candidates = append(candidates, filepath.Join(dataHome, "openclaw"), filepath.Join(configHome, "openclaw"))
 
-	for _, path := range dockerPaths {
-		if isInstalled(path) {
-			return path
+	roots := filepath.SplitList(os.Getenv(SearchRootsEnv))
+	roots = append(roots, "/openclaw")
+	roots = append(roots, DefaultSearchRoots...)
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		if expanded, err := utils.ExpandPath(root); err == nil {
+			root = expanded
 		}
+		candidates = append(candidates, searchRoot(root)...)
 	}
 
-	return ""
+	var found []string
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		candidate = filepath.Clean(candidate)
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		if Validate(candidate) == nil {
+			found = append(found, candidate)
+		}
+	}
+	return found
+}
+
+// searchRoot returns the places an installation may be under root: root
+// itself, and .openclaw and openclaw folders in it and in the folders below it
+func searchRoot(root string) []string {
+	candidates := []string{root, filepath.Join(root, ".openclaw"), filepath.Join(root, "openclaw")}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return candidates
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		candidates = append(candidates, filepath.Join(dir, ".openclaw"), filepath.Join(dir, "openclaw"))
+	}
+	return candidates
 }
 
 // DefaultRoot returns the default OpenClaw root directory
@@ -39,13 +109,6 @@ func DefaultRoot() string {
 	return filepath.Join(homeDir, ".openclaw")
 }
 
-// isInstalled checks if OpenClaw is installed at the given path
-func isInstalled(path string) bool {
-	configFile := filepath.Join(path, "openclaw.json")
-	_, err := os.Stat(configFile)
-	return err == nil
-}
-
 // IsDocker checks if running inside a Docker container
 func IsDocker() bool {
 	// Check for .dockerenv file
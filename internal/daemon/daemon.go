@@ -0,0 +1,434 @@
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/types"
+)
+
+// shutdownTimeout bounds how long in-flight requests get to finish on shutdown
+const shutdownTimeout = 30 * time.Second
+
+// TokenEnv names the environment variable holding the API token. When set,
+// every request must carry it as "Authorization: Bearer <token>", and the
+// daemon may listen on addresses other than loopback.
+const TokenEnv = "BULLETPROOF_API_TOKEN"
+
+// BackupStatus describes the outcome of the most recent backup run
+type BackupStatus struct {
+	SnapshotID string    `json:"snapshot_id,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Skipped    bool      `json:"skipped"`
+	Trigger    string    `json:"trigger"`
+	Error      string    `json:"error,omitempty"`
+
+	// Anomaly is set when the backup's change rate spiked above the baseline
+	Anomaly *types.Anomaly `json:"anomaly,omitempty"`
+
+	// For skipped backups, the stored snapshot that the unchanged state matches
+	LastSnapshotID string     `json:"last_snapshot_id,omitempty"`
+	LastSnapshotAt *time.Time `json:"last_snapshot_at,omitempty"`
+}
+
+// Status is returned by GET /status
+type Status struct {
+	Running       bool          `json:"running"`
+	StartedAt     time.Time     `json:"started_at"`
+	BackupRunning bool          `json:"backup_running"`
+	LastBackup    *BackupStatus `json:"last_backup,omitempty"`
+	NextScheduled *time.Time    `json:"next_scheduled,omitempty"`
+}
+
+// Server runs the daemon API and schedule against a single backup engine
+type Server struct {
+	engine    *backup.BackupEngine
+	schedule  bool
+	token     string
+	startedAt time.Time
+
+	// engineMu serializes access to the engine; it is not safe for concurrent use
+	engineMu sync.Mutex
+
+	mu            sync.Mutex
+	backupRunning bool
+	lastBackup    *BackupStatus
+	nextScheduled *time.Time
+}
+
+// NewServer creates a daemon server for the given engine.
+// If schedule is true, the server also runs the configured backup schedule.
+func NewServer(engine *backup.BackupEngine, schedule bool) *Server {
+	return &Server{
+		engine:    engine,
+		schedule:  schedule,
+		startedAt: time.Now(),
+	}
+}
+
+// SetToken requires every API request to carry token as a bearer token.
+// An empty token leaves the API open to anyone who can reach the listener.
+func (s *Server) SetToken(token string) {
+	s.token = token
+}
+
+// Listen opens the API listener.
+// Addresses of the form "unix:/path/to.sock" or absolute paths open a Unix socket;
+// anything else is treated as a TCP address such as "127.0.0.1:7480". A TCP
+// address other than loopback is refused unless token is set, as the API can
+// trigger backups and read snapshots.
+func Listen(addr string, token string) (net.Listener, error) {
+	network, address := "tcp", addr
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		network, address = "unix", path
+	} else if strings.HasPrefix(addr, "/") {
+		network = "unix"
+	}
+
+	if network == "tcp" && token == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid listen address %s: %w", addr, err)
+		}
+		if !isLoopback(host) {
+			return nil, fmt.Errorf("refusing to listen on %s without an API token: set %s, or listen on 127.0.0.1 or a Unix socket", addr, TokenEnv)
+		}
+	}
+
+	if network == "unix" {
+		if err := removeStaleSocket(address); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	if network == "unix" {
+		// Only the owner may drive backups through the socket
+		if err := os.Chmod(address, 0600); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+		}
+	}
+
+	return listener, nil
+}
+
+// isLoopback reports whether host only reaches this machine. An empty host
+// listens on every interface.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// removeStaleSocket removes a socket left behind by a daemon that is gone.
+// A file that is not a socket, or a socket another daemon still answers on,
+// is left alone and reported.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check socket %s: %w", path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket; remove it or choose another --listen path", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("another daemon is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	return nil
+}
+
+// Serve handles API requests and runs the schedule until ctx is cancelled.
+// On shutdown it stops accepting requests, lets in-flight ones finish, and
+// waits for any running backup to complete and its notification to be sent
+// before returning.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	scheduleDone := make(chan struct{})
+	go func() {
+		defer close(scheduleDone)
+		s.runSchedule(ctx)
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("API server failed: %w", err)
+		}
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down API server: %w", err)
+	}
+
+	<-scheduleDone
+
+	// Final flush: block until a backup that is still writing has finished,
+	// then until its webhook notification is delivered, as the process
+	// exiting would cut it off
+	s.engineMu.Lock()
+	defer s.engineMu.Unlock()
+	s.engine.WaitForNotifications()
+
+	return nil
+}
+
+// Handler returns the HTTP handler for the daemon API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /backup", s.handleBackup)
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /snapshots", s.handleSnapshots)
+	mux.HandleFunc("GET /diff", s.handleDiff)
+	if s.token == "" {
+		return mux
+	}
+	return s.requireToken(mux)
+}
+
+// requireToken rejects requests that do not carry the API token
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid API token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// backupRequest is the optional JSON body of POST /backup
+type backupRequest struct {
+	Message   string `json:"message"`
+	Force     bool   `json:"force"`
+	NoScripts bool   `json:"no_scripts"`
+}
+
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	var req backupRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+
+	status := s.runBackup("api", req.Message, req.NoScripts, req.Force)
+	if status.Error != "" {
+		writeJSON(w, http.StatusInternalServerError, status)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := Status{
+		Running:       true,
+		StartedAt:     s.startedAt,
+		BackupRunning: s.backupRunning,
+		LastBackup:    s.lastBackup,
+		NextScheduled: s.nextScheduled,
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// snapshotJSON mirrors the fields of `bulletproof snapshots --format json`
+type snapshotJSON struct {
+	ShortID   int       `json:"short_id"`
+	FullID    string    `json:"full_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message,omitempty"`
+	FileCount int       `json:"file_count"`
+}
+
+func (s *Server) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	s.engineMu.Lock()
+	backups, err := s.engine.ListBackups()
+	s.engineMu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	shortIDs := types.AssignShortIDs(backups)
+	snapshots := make([]snapshotJSON, len(backups))
+	for i, b := range backups {
+		snapshots[i] = snapshotJSON{
+			ShortID:   shortIDs[b.ID],
+			FullID:    b.ID,
+			Timestamp: b.Timestamp,
+			Message:   b.Message,
+			FileCount: b.FileCount,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, snapshots)
+}
+
+// diffJSON is returned by GET /diff
+type diffJSON struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+
+	Renamed []types.PathRename `json:"renamed,omitempty"`
+}
+
+// handleDiff compares two snapshots. Both query parameters accept the same IDs
+// as the diff command; "to" defaults to 0 (current state) and "from" to the latest snapshot.
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	fromArg := r.URL.Query().Get("from")
+	if fromArg == "" {
+		fromArg = "1"
+	}
+	toArg := r.URL.Query().Get("to")
+	if toArg == "" {
+		toArg = "0"
+	}
+
+	s.engineMu.Lock()
+	from, fromErr := s.loadSnapshot(fromArg)
+	to, toErr := s.loadSnapshot(toArg)
+	s.engineMu.Unlock()
+
+	if err := errors.Join(fromErr, toErr); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	diff := to.Diff(from)
+	writeJSON(w, http.StatusOK, diffJSON{
+		From:     from.ID,
+		To:       to.ID,
+		Added:    nonNil(diff.Added),
+		Removed:  nonNil(diff.Removed),
+		Modified: nonNil(diff.Modified),
+		Renamed:  diff.Renamed,
+	})
+}
+
+// loadSnapshot returns the snapshot for an ID, where "0" is the current filesystem state
+func (s *Server) loadSnapshot(id string) (*types.Snapshot, error) {
+	if id == "0" {
+		openclawPath, err := s.engine.OpenclawPath()
+		if err != nil {
+			return nil, err
+		}
+		current, err := s.engine.ScanSource(openclawPath, "", time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create current snapshot: %w", err)
+		}
+		current.ID = "0"
+		return current, nil
+	}
+
+	fullID, err := s.engine.ResolveSnapshotID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.engine.GetSnapshot(fullID)
+}
+
+// runBackup performs a backup and records its outcome for /status
+func (s *Server) runBackup(trigger, message string, noScripts, force bool) *BackupStatus {
+	status := &BackupStatus{
+		StartedAt: time.Now(),
+		Trigger:   trigger,
+	}
+
+	s.engineMu.Lock()
+	s.setBackupRunning(true)
+	result, err := s.engine.Backup(false, message, noScripts, force)
+	s.setBackupRunning(false)
+	s.engineMu.Unlock()
+
+	status.FinishedAt = time.Now()
+	if err != nil {
+		status.Error = err.Error()
+	} else if result != nil {
+		status.Skipped = result.Skipped
+		status.Anomaly = result.Anomaly
+		if result.Snapshot != nil && !result.Skipped {
+			status.SnapshotID = result.Snapshot.ID
+		}
+		if result.Skipped && result.LastSnapshot != nil {
+			status.LastSnapshotID = result.LastSnapshot.ID
+			status.LastSnapshotAt = &result.LastSnapshot.Timestamp
+		}
+	}
+
+	s.mu.Lock()
+	s.lastBackup = status
+	s.mu.Unlock()
+
+	return status
+}
+
+func (s *Server) setBackupRunning(running bool) {
+	s.mu.Lock()
+	s.backupRunning = running
+	s.mu.Unlock()
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// nonNil keeps empty lists encoded as [] rather than null
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
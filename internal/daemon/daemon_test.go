@@ -0,0 +1,302 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/backup"
+	"github.com/bulletproof-bot/backup/internal/config"
+)
+
+func newTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+
+	agentDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte("# Soul\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		OpenclawPath: agentDir,
+		Destination: &config.DestinationConfig{
+			Type: "local",
+			Path: t.TempDir(),
+		},
+	}
+
+	engine, err := backup.NewBackupEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+
+	return NewServer(engine, false), agentDir
+}
+
+func doRequest(t *testing.T, handler http.Handler, method, target, body string, v any) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("invalid JSON response from %s %s: %v\n%s", method, target, err, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+func TestServer_BackupStatusSnapshotsDiff(t *testing.T) {
+	server, agentDir := newTestServer(t)
+	handler := server.Handler()
+
+	var backupStatus BackupStatus
+	if code := doRequest(t, handler, "POST", "/backup", `{"message":"from api"}`, &backupStatus); code != http.StatusOK {
+		t.Fatalf("POST /backup returned %d", code)
+	}
+	if backupStatus.SnapshotID == "" || backupStatus.Trigger != "api" {
+		t.Errorf("unexpected backup status: %+v", backupStatus)
+	}
+
+	var status Status
+	doRequest(t, handler, "GET", "/status", "", &status)
+	if status.LastBackup == nil || status.LastBackup.SnapshotID != backupStatus.SnapshotID {
+		t.Errorf("status should report last backup %s, got %+v", backupStatus.SnapshotID, status.LastBackup)
+	}
+
+	var snapshots []snapshotJSON
+	doRequest(t, handler, "GET", "/snapshots", "", &snapshots)
+	if len(snapshots) != 1 || snapshots[0].Message != "from api" || snapshots[0].ShortID != 1 {
+		t.Errorf("unexpected snapshots: %+v", snapshots)
+	}
+
+	if err := os.WriteFile(filepath.Join(agentDir, "NEW.md"), []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var diff diffJSON
+	if code := doRequest(t, handler, "GET", "/diff", "", &diff); code != http.StatusOK {
+		t.Fatalf("GET /diff returned %d", code)
+	}
+	if len(diff.Added) != 1 || diff.Added[0] != "NEW.md" {
+		t.Errorf("expected NEW.md to be added, got %+v", diff)
+	}
+}
+
+func TestServer_DiffUnknownSnapshot(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	var body map[string]string
+	if code := doRequest(t, server.Handler(), "GET", "/diff?from=5", "", &body); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown snapshot, got %d", code)
+	}
+	if body["error"] == "" {
+		t.Error("expected an error message")
+	}
+}
+
+func TestServe_UnixSocketGracefulShutdown(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	socketPath := filepath.Join(t.TempDir(), "bulletproof.sock")
+	listener, err := Listen("unix:"+socketPath, "")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, listener)
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not stop after cancellation")
+	}
+
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Error("socket should be removed after shutdown")
+	}
+}
+
+func TestListen_TokenRequiredOffLoopback(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:0", ":0"} {
+		if _, err := Listen(addr, ""); err == nil || !strings.Contains(err.Error(), TokenEnv) {
+			t.Errorf("Listen(%q) without a token: expected refusal naming %s, got %v", addr, TokenEnv, err)
+		}
+	}
+
+	for _, tc := range []struct{ addr, token string }{
+		{"127.0.0.1:0", ""},
+		{"localhost:0", ""},
+		{"0.0.0.0:0", "secret"},
+	} {
+		listener, err := Listen(tc.addr, tc.token)
+		if err != nil {
+			t.Errorf("Listen(%q, %q) failed: %v", tc.addr, tc.token, err)
+			continue
+		}
+		listener.Close()
+	}
+}
+
+func TestListen_ExistingSocketPath(t *testing.T) {
+	dir := t.TempDir()
+
+	// A regular file is never removed
+	filePath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(filePath, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(filePath, ""); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("expected a regular file to be refused, got %v", err)
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != "keep" {
+		t.Error("regular file was removed")
+	}
+
+	// A socket a daemon still answers on is left to it
+	socketPath := filepath.Join(dir, "bulletproof.sock")
+	live, err := Listen(socketPath, "")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	if _, err := Listen(socketPath, ""); err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("expected a live socket to be refused, got %v", err)
+	}
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("live socket was removed: %v", err)
+	}
+	conn.Close()
+
+	// A socket left behind by a daemon that is gone is replaced
+	live.(*net.UnixListener).SetUnlinkOnClose(false)
+	live.Close()
+	if _, err := os.Lstat(socketPath); err != nil {
+		t.Fatalf("expected the stale socket to remain: %v", err)
+	}
+	listener, err := Listen(socketPath, "")
+	if err != nil {
+		t.Fatalf("expected a stale socket to be replaced, got %v", err)
+	}
+	listener.Close()
+}
+
+func TestServer_Token(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.SetToken("secret")
+	handler := server.Handler()
+
+	if code := doRequest(t, handler, "GET", "/status", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", code)
+	}
+
+	for _, auth := range []string{"Bearer wrong", "secret"} {
+		req := httptest.NewRequest("GET", "/status", nil)
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 with Authorization %q, got %d", auth, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/status", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 with the token, got %d", rec.Code)
+	}
+}
+
+func TestServe_ShutdownWaitsForNotifications(t *testing.T) {
+	var delivered atomic.Bool
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		delivered.Store(true)
+	}))
+	defer webhook.Close()
+
+	agentDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(agentDir, "SOUL.md"), []byte("# Soul\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := backup.NewBackupEngine(&config.Config{
+		OpenclawPath:  agentDir,
+		Destination:   &config.DestinationConfig{Type: "local", Path: t.TempDir()},
+		Notifications: config.NotificationsConfig{WebhookURL: webhook.URL},
+	})
+	if err != nil {
+		t.Fatalf("NewBackupEngine failed: %v", err)
+	}
+	server := NewServer(engine, false)
+
+	listener, err := Listen("127.0.0.1:0", "")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, listener)
+	}()
+
+	if code := doRequest(t, server.Handler(), "POST", "/backup", "", nil); code != http.StatusOK {
+		t.Fatalf("POST /backup returned %d", code)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not stop after cancellation")
+	}
+	if !delivered.Load() {
+		t.Error("expected Serve to wait for the backup notification before returning")
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		hour   int
+		minute int
+		want   time.Time
+	}{
+		{"later today", 14, 30, time.Date(2024, 3, 10, 14, 30, 0, 0, time.UTC)},
+		{"earlier today", 3, 0, time.Date(2024, 3, 11, 3, 0, 0, 0, time.UTC)},
+		{"exactly now", 12, 0, time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextRun(now, tt.hour, tt.minute); !got.Equal(tt.want) {
+				t.Errorf("nextRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
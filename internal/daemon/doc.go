@@ -0,0 +1,4 @@
+// Package daemon implements the long-running bulletproof service.
+// It exposes a small local HTTP API for triggering backups and querying
+// status, snapshots, and drift, and runs the configured schedule in-process.
+package daemon
@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// runSchedule triggers a backup every day at the configured time until ctx is cancelled.
+// It does nothing if scheduling is disabled or the time is invalid.
+func (s *Server) runSchedule(ctx context.Context) {
+	schedule := s.engine.Config().Schedule
+	if !s.schedule || !schedule.Enabled {
+		return
+	}
+
+	hour, err := schedule.Hour()
+	if err != nil {
+		fmt.Printf("⚠️  Scheduled backups disabled: %v\n", err)
+		return
+	}
+	minute, err := schedule.Minute()
+	if err != nil {
+		fmt.Printf("⚠️  Scheduled backups disabled: %v\n", err)
+		return
+	}
+
+	for {
+		next := nextRun(time.Now(), hour, minute)
+		s.mu.Lock()
+		s.nextScheduled = &next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.runBackup("schedule", "Scheduled backup", false, false)
+		}
+	}
+}
+
+// nextRun returns the next occurrence of hour:minute strictly after now
+func nextRun(now time.Time, hour, minute int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,4 @@
+// Package keys manages encryption keys for bulletproof.
+// Key material lives in owner-only files under ~/.config/bulletproof/keys,
+// while the configuration only stores references to those files.
+package keys
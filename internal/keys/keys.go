@@ -0,0 +1,196 @@
+package keys
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bulletproof-bot/backup/internal/utils"
+)
+
+// Kind identifies what a key is used for
+type Kind string
+
+// Encryption keys are 256-bit symmetric keys for encrypting snapshot data.
+// Snapshots are not signed, so there is no signing kind.
+const Encryption Kind = "encryption"
+
+// keySize is the length of the stored key material in bytes: an AES-256 key
+const keySize = 32
+
+// Key describes a stored key without exposing its material
+type Key struct {
+	Kind        Kind
+	Path        string
+	Fingerprint string
+}
+
+// ParseKind converts a user-supplied string into a Kind
+func ParseKind(s string) (Kind, error) {
+	switch Kind(strings.ToLower(s)) {
+	case Encryption:
+		return Encryption, nil
+	case "signing":
+		return "", fmt.Errorf("signing keys are not supported: snapshots are not signed")
+	default:
+		return "", fmt.Errorf("unknown key type: %s (expected encryption)", s)
+	}
+}
+
+// Dir returns the key directory inside the given config directory
+func Dir(configDir string) string {
+	return filepath.Join(configDir, "keys")
+}
+
+// Path returns the location of the active key of the given kind
+func Path(dir string, kind Kind) string {
+	return filepath.Join(dir, string(kind)+".key")
+}
+
+// Generate creates a new random key of the given kind.
+// Fails if a key already exists; use Rotate to replace it.
+func Generate(dir string, kind Kind) (*Key, error) {
+	path := Path(dir, kind)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s key already exists at %s (use rotate to replace it)", kind, path)
+	}
+
+	material := make([]byte, keySize)
+	if _, err := rand.Read(material); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	return write(dir, kind, material)
+}
+
+// Import copies key material from srcPath into the key directory.
+// The source must contain a hex-encoded 32-byte key. Like Generate, it fails
+// if a key already exists rather than replacing the active key.
+func Import(dir string, kind Kind, srcPath string) (*Key, error) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	material, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key in %s: %w", srcPath, err)
+	}
+
+	path := Path(dir, kind)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s key already exists at %s (use rotate to replace it)", kind, path)
+	}
+
+	return write(dir, kind, material)
+}
+
+// Load reads the key material of a key file, such as one written by Generate
+func Load(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	material, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key in %s: %w", path, err)
+	}
+	return material, nil
+}
+
+// Rotate generates a new key of the given kind and retires the active one.
+// The new key is written before the active one is touched, so a failure
+// leaves the active key in place. The retired key is kept alongside the new
+// one so data protected by it stays readable; nothing is re-encrypted.
+// Returns the new key and the path of the retired key (empty if there was none).
+func Rotate(dir string, kind Kind) (*Key, string, error) {
+	path := Path(dir, kind)
+	retiredPath := ""
+
+	material := make([]byte, keySize)
+	if _, err := rand.Read(material); err != nil {
+		return nil, "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	pendingPath := path + ".new"
+	if err := writeFile(dir, pendingPath, material); err != nil {
+		return nil, "", err
+	}
+	defer os.Remove(pendingPath)
+
+	if _, err := os.Stat(path); err == nil {
+		retiredPath = filepath.Join(dir, fmt.Sprintf("%s-%s.key.retired", kind, time.Now().Format("20060102-150405")))
+		if err := os.Rename(path, retiredPath); err != nil {
+			return nil, "", fmt.Errorf("failed to retire existing key: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, "", fmt.Errorf("failed to check existing key: %w", err)
+	}
+
+	if err := os.Rename(pendingPath, path); err != nil {
+		if retiredPath != "" {
+			if restoreErr := os.Rename(retiredPath, path); restoreErr != nil {
+				return nil, "", fmt.Errorf("failed to activate new key: %w (the previous key is at %s)", err, retiredPath)
+			}
+		}
+		return nil, "", fmt.Errorf("failed to activate new key: %w", err)
+	}
+
+	return &Key{
+		Kind:        kind,
+		Path:        path,
+		Fingerprint: fingerprint(material),
+	}, retiredPath, nil
+}
+
+// write stores key material as the active key of the given kind
+func write(dir string, kind Kind, material []byte) (*Key, error) {
+	path := Path(dir, kind)
+	if err := writeFile(dir, path, material); err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		Kind:        kind,
+		Path:        path,
+		Fingerprint: fingerprint(material),
+	}, nil
+}
+
+// writeFile stores hex-encoded key material at path with owner-only permissions
+func writeFile(dir string, path string, material []byte) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	encoded := hex.EncodeToString(material) + "\n"
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file, so enforce it explicitly
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to restrict key file permissions: %w", err)
+	}
+	return nil
+}
+
+// decode parses hex-encoded key material
+func decode(data []byte) ([]byte, error) {
+	material, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("key is not hex-encoded: %w", err)
+	}
+	if len(material) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(material))
+	}
+	return material, nil
+}
+
+// fingerprint returns a short identifier that is safe to display and store
+func fingerprint(material []byte) string {
+	return utils.HashBytes(material)[:16]
+}
@@ -0,0 +1,212 @@
+package keys
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseKind(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Kind
+		wantErr bool
+	}{
+		{"encryption", Encryption, false},
+		{"Encryption", Encryption, false},
+		{"signing", "", true},
+		{"password", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseKind(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKind(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseKind(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerate_WritesOwnerOnlyFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+
+	key, err := Generate(dir, Encryption)
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	if key.Path != Path(dir, Encryption) {
+		t.Errorf("Path = %s, want %s", key.Path, Path(dir, Encryption))
+	}
+	if len(key.Fingerprint) != 16 {
+		t.Errorf("Fingerprint length = %d, want 16", len(key.Fingerprint))
+	}
+
+	data, err := os.ReadFile(key.Path)
+	if err != nil {
+		t.Fatalf("failed to read key file: %v", err)
+	}
+	if _, err := decode(data); err != nil {
+		t.Errorf("stored key does not decode: %v", err)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(key.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+		}
+	}
+}
+
+func TestGenerate_RefusesToOverwrite(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := Generate(dir, Encryption); err != nil {
+		t.Fatalf("first Generate() failed: %v", err)
+	}
+	if _, err := Generate(dir, Encryption); err == nil {
+		t.Error("second Generate() should fail when a key already exists")
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	hexKey := strings.Repeat("ab", keySize)
+
+	src := filepath.Join(t.TempDir(), "import.key")
+	if err := os.WriteFile(src, []byte(hexKey+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := Import(dir, Encryption, src)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(key.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(data)) != hexKey {
+		t.Errorf("imported key content mismatch: got %q", data)
+	}
+
+	// Invalid material is rejected
+	bad := filepath.Join(t.TempDir(), "bad.key")
+	if err := os.WriteFile(bad, []byte("not-hex"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(dir, Encryption, bad); err == nil {
+		t.Error("Import() should reject non-hex key material")
+	}
+
+	short := filepath.Join(t.TempDir(), "short.key")
+	if err := os.WriteFile(short, []byte("abcd"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(dir, Encryption, short); err == nil {
+		t.Error("Import() should reject keys of the wrong length")
+	}
+
+	// A valid key does not replace the active one
+	other := filepath.Join(t.TempDir(), "other.key")
+	if err := os.WriteFile(other, []byte(strings.Repeat("cd", keySize)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(dir, Encryption, other); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Import() should refuse to overwrite the active key, got %v", err)
+	}
+	if data, _ := os.ReadFile(key.Path); strings.TrimSpace(string(data)) != hexKey {
+		t.Errorf("active key was overwritten: %q", data)
+	}
+}
+
+func TestRotate_RetiresPreviousKey(t *testing.T) {
+	dir := t.TempDir()
+
+	original, err := Generate(dir, Encryption)
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	rotated, retiredPath, err := Rotate(dir, Encryption)
+	if err != nil {
+		t.Fatalf("Rotate() failed: %v", err)
+	}
+
+	if retiredPath == "" {
+		t.Fatal("Rotate() should report the retired key path")
+	}
+	if rotated.Fingerprint == original.Fingerprint {
+		t.Error("rotated key should differ from the original")
+	}
+
+	retired, err := os.ReadFile(retiredPath)
+	if err != nil {
+		t.Fatalf("retired key missing: %v", err)
+	}
+	material, err := decode(retired)
+	if err != nil {
+		t.Fatalf("retired key does not decode: %v", err)
+	}
+	if fingerprint(material) != original.Fingerprint {
+		t.Error("retired key should be the original key")
+	}
+}
+
+func TestRotate_WithoutExistingKey(t *testing.T) {
+	dir := t.TempDir()
+
+	key, retiredPath, err := Rotate(dir, Encryption)
+	if err != nil {
+		t.Fatalf("Rotate() failed: %v", err)
+	}
+	if retiredPath != "" {
+		t.Errorf("retiredPath = %q, want empty", retiredPath)
+	}
+	if _, err := os.Stat(key.Path); err != nil {
+		t.Errorf("new key not written: %v", err)
+	}
+}
+
+func TestRotate_FailureKeepsActiveKey(t *testing.T) {
+	dir := t.TempDir()
+
+	original, err := Generate(dir, Encryption)
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	// The new key cannot be written, so the active key must stay in place
+	if err := os.Mkdir(original.Path+".new", 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Rotate(dir, Encryption); err == nil {
+		t.Fatal("Rotate() should fail when the new key cannot be written")
+	}
+
+	data, err := os.ReadFile(original.Path)
+	if err != nil {
+		t.Fatalf("active key missing after a failed rotation: %v", err)
+	}
+	material, err := decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint(material) != original.Fingerprint {
+		t.Error("active key changed after a failed rotation")
+	}
+	if retired, _ := filepath.Glob(filepath.Join(dir, "*.retired")); len(retired) != 0 {
+		t.Errorf("expected no retired key, got %v", retired)
+	}
+}
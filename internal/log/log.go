@@ -0,0 +1,62 @@
+// Package log prints bulletproof's progress messages at the level chosen with
+// --quiet or --verbose. Final results, prompts and errors are printed
+// directly, since every level shows them.
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// Level is how much progress output commands print
+type Level int32
+
+const (
+	// LevelQuiet prints only errors and final results, e.g. for scheduled backups
+	LevelQuiet Level = iota
+	// LevelNormal also prints progress messages and warnings
+	LevelNormal
+	// LevelVerbose also prints each file as it is copied or restored
+	LevelVerbose
+)
+
+var level atomic.Int32
+
+func init() {
+	level.Store(int32(LevelNormal))
+}
+
+// SetLevel sets the level for the rest of the process
+func SetLevel(l Level) {
+	level.Store(int32(l))
+}
+
+// Quiet reports whether progress messages are silenced
+func Quiet() bool {
+	return Level(level.Load()) <= LevelQuiet
+}
+
+// Verbose reports whether per-file details are printed
+func Verbose() bool {
+	return Level(level.Load()) >= LevelVerbose
+}
+
+// Writer returns where progress messages go: stdout, or io.Discard when quiet
+func Writer() io.Writer {
+	if Quiet() {
+		return io.Discard
+	}
+	return os.Stdout
+}
+
+// Printf prints a progress message unless quiet
+func Printf(format string, args ...interface{}) {
+	fmt.Fprintf(Writer(), format, args...)
+}
+
+// Println prints a progress message unless quiet
+func Println(args ...interface{}) {
+	fmt.Fprintln(Writer(), args...)
+}
@@ -0,0 +1,25 @@
+package log
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	t.Cleanup(func() { SetLevel(LevelNormal) })
+
+	if Quiet() || Verbose() || Writer() != os.Stdout {
+		t.Error("expected progress on stdout and no details by default")
+	}
+
+	SetLevel(LevelQuiet)
+	if !Quiet() || Verbose() || Writer() != io.Discard {
+		t.Error("expected quiet to discard progress")
+	}
+
+	SetLevel(LevelVerbose)
+	if Quiet() || !Verbose() || Writer() != os.Stdout {
+		t.Error("expected verbose to print progress and details")
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
@@ -39,6 +41,136 @@ func RemoveAutoBackup() error {
 	}
 }
 
+// AutoBackupInstalled reports whether a scheduled backup service is installed
+func AutoBackupInstalled() bool {
+	return VerifyAutoBackup("").Installed
+}
+
+// AutoBackupStatus describes the scheduled backup job found on this machine
+type AutoBackupStatus struct {
+	Installed bool
+	// Kind is "systemd timer", "cron job", "launchd agent" or "scheduled task"
+	Kind string
+	// Active is false for a systemd timer that is installed but not enabled
+	Active bool
+	// Time is when the job runs (HH:MM), or empty if it could not be read
+	Time string
+	// MatchesConfig reports whether Time is the configured time
+	MatchesConfig bool
+}
+
+// VerifyAutoBackup looks up the installed scheduled backup job and compares
+// the time it runs at with backupTime, the configured schedule.time
+func VerifyAutoBackup(backupTime string) AutoBackupStatus {
+	var status AutoBackupStatus
+	switch runtime.GOOS {
+	case "linux":
+		status = linuxAutoBackupStatus()
+	case "darwin":
+		plistPath := filepath.Join(os.Getenv("HOME"), "Library", "LaunchAgents", "ai.bulletproof.backup.plist")
+		if data, err := os.ReadFile(plistPath); err == nil {
+			status = AutoBackupStatus{Installed: true, Kind: "launchd agent", Active: true, Time: launchdTime(string(data))}
+		}
+	case "windows":
+		cmd := exec.Command("powershell", "-Command", "(Get-ScheduledTask -TaskName 'BulletproofBackup' -ErrorAction Stop).Triggers[0].StartBoundary")
+		if out, err := cmd.Output(); err == nil {
+			status = AutoBackupStatus{Installed: true, Kind: "scheduled task", Active: true, Time: taskTime(string(out))}
+		}
+	}
+	status.MatchesConfig = status.Installed && status.Time != "" && status.Time == backupTime
+	return status
+}
+
+// linuxAutoBackupStatus looks for the systemd timer, then the cron entry
+func linuxAutoBackupStatus() AutoBackupStatus {
+	timerPath := filepath.Join(os.Getenv("HOME"), ".config", "systemd", "user", "bulletproof-backup.timer")
+	if hasSystemd() {
+		if data, err := os.ReadFile(timerPath); err == nil {
+			enabled := exec.Command("systemctl", "--user", "is-enabled", "--quiet", "bulletproof-backup.timer").Run() == nil
+			return AutoBackupStatus{Installed: true, Kind: "systemd timer", Active: enabled, Time: systemdTimerTime(string(data))}
+		}
+	}
+	if _, err := exec.LookPath("crontab"); err != nil {
+		return AutoBackupStatus{}
+	}
+	existingCronBytes, err := exec.Command("crontab", "-l").Output()
+	if err != nil || !strings.Contains(string(existingCronBytes), "# Bulletproof Backup") {
+		return AutoBackupStatus{}
+	}
+	return AutoBackupStatus{Installed: true, Kind: "cron job", Active: true, Time: cronTime(string(existingCronBytes))}
+}
+
+// systemdTimerTime reads HH:MM from the timer's OnCalendar=*-*-* HH:MM:00 line
+func systemdTimerTime(timer string) string {
+	for _, line := range strings.Split(timer, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "OnCalendar="); ok {
+			fields := strings.Fields(value)
+			if len(fields) == 2 && len(fields[1]) >= 5 {
+				return fields[1][:5]
+			}
+		}
+	}
+	return ""
+}
+
+// cronTime reads HH:MM from the entry following the Bulletproof Backup marker
+func cronTime(crontab string) string {
+	lines := strings.Split(crontab, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "# Bulletproof Backup") || i+1 == len(lines) {
+			continue
+		}
+		fields := strings.Fields(lines[i+1])
+		if len(fields) < 2 {
+			return ""
+		}
+		return clockTime(fields[1], fields[0])
+	}
+	return ""
+}
+
+// launchdTime reads HH:MM from the plist's StartCalendarInterval
+func launchdTime(plist string) string {
+	hour := launchdIntegerPattern("Hour").FindStringSubmatch(plist)
+	minute := launchdIntegerPattern("Minute").FindStringSubmatch(plist)
+	if hour == nil || minute == nil {
+		return ""
+	}
+	return clockTime(hour[1], minute[1])
+}
+
+func launchdIntegerPattern(key string) *regexp.Regexp {
+	return regexp.MustCompile(`<key>` + key + `</key>\s*<integer>(\d+)</integer>`)
+}
+
+// taskTime reads HH:MM from a trigger's StartBoundary, e.g. 2026-01-01T03:00:00
+func taskTime(startBoundary string) string {
+	_, clock, ok := strings.Cut(strings.TrimSpace(startBoundary), "T")
+	if !ok || len(clock) < 5 {
+		return ""
+	}
+	return clock[:5]
+}
+
+// clockTime formats an hour and minute as HH:MM, or returns empty if either
+// is not a number
+func clockTime(hour, minute string) string {
+	h, err := strconv.Atoi(hour)
+	if err != nil {
+		return ""
+	}
+	m, err := strconv.Atoi(minute)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d", h, m)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 // setupLinuxAutoBackup creates systemd timer or cron job
 func setupLinuxAutoBackup(backupTime string) error {
 	// Try systemd first
@@ -62,7 +194,7 @@ Description=Bulletproof Backup
 
 [Service]
 Type=oneshot
-ExecStart=/usr/local/bin/bulletproof backup
+ExecStart=/usr/local/bin/bulletproof backup --quiet
 `
 
 	servicePath := filepath.Join(os.Getenv("HOME"), ".config", "systemd", "user", "bulletproof-backup.service")
@@ -103,14 +235,14 @@ WantedBy=timers.target
 func setupCronJob(backupTime string) error {
 	// Parse time (HH:MM format)
 	hour := backupTime[:2]
-	minute := "00"
+	minute := backupTime[3:5]
 
 	// Get existing crontab
 	existingCronBytes, _ := exec.Command("crontab", "-l").Output()
 	existingCron := string(existingCronBytes)
 
 	// Check if entry already exists
-	cronEntry := fmt.Sprintf("# Bulletproof Backup - Auto-generated\n%s %s * * * /usr/local/bin/bulletproof backup\n", minute, hour)
+	cronEntry := fmt.Sprintf("# Bulletproof Backup - Auto-generated\n%s %s * * * /usr/local/bin/bulletproof backup --quiet\n", minute, hour)
 	newCron := existingCron
 	if newCron == "" || newCron[len(newCron)-1] != '\n' {
 		newCron += "\n"
@@ -130,7 +262,8 @@ func setupCronJob(backupTime string) error {
 // setupMacOSAutoBackup creates launchd plist
 func setupMacOSAutoBackup(backupTime string) error {
 	// Parse time
-	hour := backupTime[:2]
+	hour, _ := strconv.Atoi(backupTime[:2])
+	minute, _ := strconv.Atoi(backupTime[3:5])
 
 	plistContent := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
@@ -142,13 +275,14 @@ func setupMacOSAutoBackup(backupTime string) error {
     <array>
         <string>/usr/local/bin/bulletproof</string>
         <string>backup</string>
+        <string>--quiet</string>
     </array>
     <key>StartCalendarInterval</key>
     <dict>
         <key>Hour</key>
-        <integer>%s</integer>
+        <integer>%d</integer>
         <key>Minute</key>
-        <integer>0</integer>
+        <integer>%d</integer>
     </dict>
     <key>StandardOutPath</key>
     <string>%s/Library/Logs/bulletproof-backup.log</string>
@@ -156,7 +290,7 @@ func setupMacOSAutoBackup(backupTime string) error {
     <string>%s/Library/Logs/bulletproof-backup.log</string>
 </dict>
 </plist>
-`, hour, os.Getenv("HOME"), os.Getenv("HOME"))
+`, hour, minute, os.Getenv("HOME"), os.Getenv("HOME"))
 
 	plistPath := filepath.Join(os.Getenv("HOME"), "Library", "LaunchAgents", "ai.bulletproof.backup.plist")
 	if err := os.MkdirAll(filepath.Dir(plistPath), 0755); err != nil {
@@ -178,16 +312,13 @@ func setupMacOSAutoBackup(backupTime string) error {
 
 // setupWindowsAutoBackup creates Task Scheduler task
 func setupWindowsAutoBackup(backupTime string) error {
-	// Parse time
-	hour := backupTime[:2]
-
 	// Create scheduled task using PowerShell
 	psScript := fmt.Sprintf(`
-$action = New-ScheduledTaskAction -Execute "bulletproof.exe" -Argument "backup"
-$trigger = New-ScheduledTaskTrigger -Daily -At "%s:00"
+$action = New-ScheduledTaskAction -Execute "bulletproof.exe" -Argument "backup --quiet"
+$trigger = New-ScheduledTaskTrigger -Daily -At "%s"
 $principal = New-ScheduledTaskPrincipal -UserId "$env:USERNAME" -RunLevel Highest
 Register-ScheduledTask -TaskName "BulletproofBackup" -Action $action -Trigger $trigger -Principal $principal -Force
-`, hour)
+`, backupTime)
 
 	cmd := exec.Command("powershell", "-Command", psScript)
 	if err := cmd.Run(); err != nil {
@@ -0,0 +1,25 @@
+package platform
+
+import "testing"
+
+func TestScheduledJobTimes(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"systemd", systemdTimerTime("[Timer]\nOnCalendar=*-*-* 14:30:00\nPersistent=true\n"), "14:30"},
+		{"systemd without OnCalendar", systemdTimerTime("[Timer]\nPersistent=true\n"), ""},
+		{"cron", cronTime("0 1 * * * other\n# Bulletproof Backup - Auto-generated\n30 14 * * * /usr/local/bin/bulletproof backup --quiet\n"), "14:30"},
+		{"cron without entry", cronTime("0 1 * * * other\n"), ""},
+		{"launchd", launchdTime("<key>Hour</key>\n        <integer>3</integer>\n        <key>Minute</key>\n        <integer>5</integer>"), "03:05"},
+		{"launchd without minute", launchdTime("<key>Hour</key><integer>3</integer>"), ""},
+		{"scheduled task", taskTime("2026-01-01T14:30:00\r\n"), "14:30"},
+		{"scheduled task without trigger", taskTime(""), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Changelog summarizes the net changes between two snapshots by agent area,
+// for sharing as release notes
+type Changelog struct {
+	From *Snapshot
+	To   *Snapshot
+
+	// Skills are named by their directory (or file) under skills/
+	SkillsAdded   []string
+	SkillsRemoved []string
+	SkillsChanged []string
+
+	// Areas maps each file category to its part of the net diff
+	Areas map[string]*SnapshotDiff
+
+	// ConfigChanges lists key-level changes in modified JSON config files
+	ConfigChanges []ConfigKeyChange
+
+	// Notes explains anything the changelog could not cover, e.g. config files
+	// whose contents are no longer stored
+	Notes []string
+}
+
+// ConfigKeyChange is one changed key in a JSON config file. Key is a dotted
+// path such as "agent.model"; values are compact JSON.
+type ConfigKeyChange struct {
+	File string `json:"file"`
+	Key  string `json:"key"`
+	Kind string `json:"kind"` // "added", "removed" or "modified"
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// changelogAreas is the order areas appear in, most interesting first
+var changelogAreas = []string{CategoryPersonality, CategorySkills, CategoryDefinitions, CategoryConfig, CategoryMemory, CategoryOther}
+
+// NewChangelog groups the net diff from one snapshot to a later one by area.
+// Config key changes are filled in separately, since they need file contents.
+func NewChangelog(from, to *Snapshot) *Changelog {
+	diff := to.Diff(from)
+	changelog := &Changelog{
+		From:  from,
+		To:    to,
+		Areas: make(map[string]*SnapshotDiff),
+	}
+
+	area := func(p string) *SnapshotDiff {
+		category := FileCategory(p)
+		if changelog.Areas[category] == nil {
+			changelog.Areas[category] = &SnapshotDiff{From: diff.From, To: diff.To, Added: []string{}, Removed: []string{}, Modified: []string{}}
+		}
+		return changelog.Areas[category]
+	}
+	for _, p := range diff.Added {
+		area(p).Added = append(area(p).Added, p)
+	}
+	for _, p := range diff.Removed {
+		area(p).Removed = append(area(p).Removed, p)
+	}
+	for _, p := range diff.Modified {
+		area(p).Modified = append(area(p).Modified, p)
+	}
+	for _, r := range diff.Renamed {
+		area(r.To).Renamed = append(area(r.To).Renamed, r)
+	}
+	for _, d := range changelog.Areas {
+		sort.Strings(d.Added)
+		sort.Strings(d.Removed)
+		sort.Strings(d.Modified)
+	}
+
+	// A skill is added or removed when none of its files exist on the other side
+	fromSkills, toSkills := skillNames(from), skillNames(to)
+	changed := make(map[string]bool)
+	for _, p := range append(append(append([]string{}, diff.Added...), diff.Removed...), diff.Modified...) {
+		if name := skillName(p); name != "" {
+			changed[name] = true
+		}
+	}
+	for _, r := range diff.Renamed {
+		if name := skillName(r.To); name != "" {
+			changed[name] = true
+		}
+		// A skill renamed by case keeps existing under its old name too
+		if name := skillName(r.From); name != "" && name != skillName(r.To) {
+			changed[name] = true
+		}
+	}
+	for name := range changed {
+		switch {
+		case !fromSkills[name]:
+			changelog.SkillsAdded = append(changelog.SkillsAdded, name)
+		case !toSkills[name]:
+			changelog.SkillsRemoved = append(changelog.SkillsRemoved, name)
+		default:
+			changelog.SkillsChanged = append(changelog.SkillsChanged, name)
+		}
+	}
+	sort.Strings(changelog.SkillsAdded)
+	sort.Strings(changelog.SkillsRemoved)
+	sort.Strings(changelog.SkillsChanged)
+
+	return changelog
+}
+
+// ConfigFiles returns the modified config files whose keys can be compared
+func (c *Changelog) ConfigFiles() []string {
+	area := c.Areas[CategoryConfig]
+	if area == nil {
+		return nil
+	}
+	var files []string
+	for _, p := range area.Modified {
+		if strings.EqualFold(path.Ext(p), ".json") {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// IsEmpty reports whether nothing changed between the two snapshots
+func (c *Changelog) IsEmpty() bool {
+	return len(c.Areas) == 0
+}
+
+// Markdown renders the changelog as a markdown document
+func (c *Changelog) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Agent changelog: %s → %s\n\n", changelogSnapshotName(c.From), changelogSnapshotName(c.To))
+	fmt.Fprintf(&b, "_%s → %s_\n\n", c.From.Timestamp.Format("2006-01-02 15:04"), c.To.Timestamp.Format("2006-01-02 15:04"))
+
+	if c.IsEmpty() {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+
+	for _, category := range changelogAreas {
+		area := c.Areas[category]
+		if area == nil {
+			continue
+		}
+
+		fmt.Fprintf(&b, "## %s\n\n", changelogTitle(category))
+		switch category {
+		case CategorySkills:
+			writeMarkdownList(&b, "Added skills", c.SkillsAdded)
+			writeMarkdownList(&b, "Removed skills", c.SkillsRemoved)
+			writeMarkdownList(&b, "Updated skills", c.SkillsChanged)
+		case CategoryConfig:
+			writeMarkdownFiles(&b, area)
+			if len(c.ConfigChanges) > 0 {
+				b.WriteString("**Config keys:**\n\n")
+				for _, change := range c.ConfigChanges {
+					switch change.Kind {
+					case "added":
+						fmt.Fprintf(&b, "- `%s` %s: added `%s`\n", change.File, change.Key, change.New)
+					case "removed":
+						fmt.Fprintf(&b, "- `%s` %s: removed (was `%s`)\n", change.File, change.Key, change.Old)
+					default:
+						fmt.Fprintf(&b, "- `%s` %s: `%s` → `%s`\n", change.File, change.Key, change.Old, change.New)
+					}
+				}
+				b.WriteString("\n")
+			}
+		default:
+			writeMarkdownFiles(&b, area)
+		}
+	}
+
+	if len(c.Notes) > 0 {
+		b.WriteString("## Notes\n\n")
+		for _, note := range c.Notes {
+			fmt.Fprintf(&b, "- %s\n", note)
+		}
+		b.WriteString("\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// changelogSnapshotName names a changelog end; ID 0 is the current state
+func changelogSnapshotName(s *Snapshot) string {
+	if s.ID == "0" {
+		return "current state"
+	}
+	return s.ID
+}
+
+// changelogTitle returns the heading for a file category
+func changelogTitle(category string) string {
+	switch category {
+	case CategoryPersonality:
+		return "Personality"
+	case CategorySkills:
+		return "Skills"
+	case CategoryDefinitions:
+		return "Agent definitions"
+	case CategoryConfig:
+		return "Configuration"
+	case CategoryMemory:
+		return "Memory"
+	default:
+		return "Other files"
+	}
+}
+
+// writeMarkdownFiles lists an area's added, modified and removed files
+func writeMarkdownFiles(b *strings.Builder, area *SnapshotDiff) {
+	writeMarkdownList(b, "Added", area.Added)
+	writeMarkdownList(b, "Modified", area.Modified)
+	if len(area.Renamed) > 0 {
+		renames := make([]string, len(area.Renamed))
+		for i, r := range area.Renamed {
+			renames[i] = r.String()
+		}
+		writeMarkdownList(b, "Renamed", renames)
+	}
+	writeMarkdownList(b, "Removed", area.Removed)
+}
+
+// writeMarkdownList writes a bold label followed by a code-formatted bullet
+// list, or nothing if items is empty
+func writeMarkdownList(b *strings.Builder, label string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "**%s:**\n\n", label)
+	for _, item := range items {
+		fmt.Fprintf(b, "- `%s`\n", item)
+	}
+	b.WriteString("\n")
+}
+
+// skillName returns the skill a path belongs to: the first path element under
+// skills/, without extension for single-file skills. It returns "" for paths
+// outside skills/.
+func skillName(p string) string {
+	if FileCategory(p) != CategorySkills {
+		return ""
+	}
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if strings.EqualFold(part, "skills") && i+1 < len(parts) {
+			name := parts[i+1]
+			if i+2 == len(parts) {
+				name = strings.TrimSuffix(name, path.Ext(name))
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+// skillNames returns the set of skills present in a snapshot
+func skillNames(s *Snapshot) map[string]bool {
+	names := make(map[string]bool)
+	for p := range s.Files {
+		if name := skillName(p); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// DiffJSONKeys compares two JSON documents key by key. Nested objects are
+// walked; arrays and scalars are compared as values. Changes are sorted by key.
+func DiffJSONKeys(file string, oldData, newData []byte) ([]ConfigKeyChange, error) {
+	var oldDoc, newDoc interface{}
+	if err := json.Unmarshal(oldData, &oldDoc); err != nil {
+		return nil, fmt.Errorf("failed to parse old %s: %w", file, err)
+	}
+	if err := json.Unmarshal(newData, &newDoc); err != nil {
+		return nil, fmt.Errorf("failed to parse new %s: %w", file, err)
+	}
+
+	oldKeys, newKeys := map[string]string{}, map[string]string{}
+	flattenJSON("", oldDoc, oldKeys)
+	flattenJSON("", newDoc, newKeys)
+
+	var changes []ConfigKeyChange
+	for key, oldValue := range oldKeys {
+		newValue, exists := newKeys[key]
+		switch {
+		case !exists:
+			changes = append(changes, ConfigKeyChange{File: file, Key: key, Kind: "removed", Old: oldValue})
+		case newValue != oldValue:
+			changes = append(changes, ConfigKeyChange{File: file, Key: key, Kind: "modified", Old: oldValue, New: newValue})
+		}
+	}
+	for key, newValue := range newKeys {
+		if _, exists := oldKeys[key]; !exists {
+			changes = append(changes, ConfigKeyChange{File: file, Key: key, Kind: "added", New: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// flattenJSON records each leaf of a JSON value under its dotted key. Objects
+// are walked; anything else, including arrays, is a leaf rendered as compact JSON.
+func flattenJSON(prefix string, value interface{}, out map[string]string) {
+	if object, ok := value.(map[string]interface{}); ok && (len(object) > 0 || prefix == "") {
+		for key, child := range object {
+			childKey := key
+			if prefix != "" {
+				childKey = prefix + "." + key
+			}
+			flattenJSON(childKey, child, out)
+		}
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		data = []byte(fmt.Sprint(value))
+	}
+	if prefix == "" {
+		prefix = "(root)"
+	}
+	out[prefix] = string(data)
+}
@@ -0,0 +1,99 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewChangelog_GroupsNetChangesByArea(t *testing.T) {
+	files := func(hashes map[string]string) map[string]*FileSnapshot {
+		m := make(map[string]*FileSnapshot, len(hashes))
+		for p, h := range hashes {
+			m[p] = &FileSnapshot{Path: p, Hash: h}
+		}
+		return m
+	}
+	base := time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)
+	from := &Snapshot{ID: "20260101-030000-000", Timestamp: base, Files: files(map[string]string{
+		"workspace/SOUL.md":              "soul1",
+		"workspace/skills/weather/a.js":  "w1",
+		"workspace/skills/weather/b.js":  "w2",
+		"workspace/skills/legacy.js":     "l1",
+		"workspace/skills/search/run.js": "s1",
+		"openclaw.json":                  "c1",
+		"workspace/memory/day1.json":     "m1",
+	})}
+	to := &Snapshot{ID: "20260301-030000-000", Timestamp: base.AddDate(0, 2, 0), Files: files(map[string]string{
+		"workspace/SOUL.md":              "soul2",
+		"workspace/skills/weather/a.js":  "w1",
+		"workspace/skills/search/run.js": "s2",
+		"workspace/skills/calendar/c.js": "c1",
+		"openclaw.json":                  "c2",
+		"workspace/memory/day1.json":     "m1",
+	})}
+
+	changelog := NewChangelog(from, to)
+
+	if !reflect.DeepEqual(changelog.SkillsAdded, []string{"calendar"}) {
+		t.Errorf("SkillsAdded = %v", changelog.SkillsAdded)
+	}
+	if !reflect.DeepEqual(changelog.SkillsRemoved, []string{"legacy"}) {
+		t.Errorf("SkillsRemoved = %v", changelog.SkillsRemoved)
+	}
+	// weather lost a file but still exists, so it was updated, not removed
+	if !reflect.DeepEqual(changelog.SkillsChanged, []string{"search", "weather"}) {
+		t.Errorf("SkillsChanged = %v", changelog.SkillsChanged)
+	}
+	if area := changelog.Areas[CategoryPersonality]; area == nil || !reflect.DeepEqual(area.Modified, []string{"workspace/SOUL.md"}) {
+		t.Errorf("unexpected personality changes: %+v", area)
+	}
+	if _, ok := changelog.Areas[CategoryMemory]; ok {
+		t.Error("unchanged memory should not appear")
+	}
+	if !reflect.DeepEqual(changelog.ConfigFiles(), []string{"openclaw.json"}) {
+		t.Errorf("ConfigFiles = %v", changelog.ConfigFiles())
+	}
+
+	changelog.ConfigChanges = []ConfigKeyChange{{File: "openclaw.json", Key: "agent.model", Kind: "modified", Old: `"a"`, New: `"b"`}}
+	markdown := changelog.Markdown()
+	for _, want := range []string{
+		"# Agent changelog: 20260101-030000-000 → 20260301-030000-000",
+		"## Personality",
+		"**Added skills:**\n\n- `calendar`",
+		"**Removed skills:**\n\n- `legacy`",
+		"- `openclaw.json` agent.model: `\"a\"` → `\"b\"`",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+	if strings.Contains(markdown, "## Memory") {
+		t.Errorf("markdown should not list unchanged areas:\n%s", markdown)
+	}
+}
+
+func TestDiffJSONKeys(t *testing.T) {
+	oldData := []byte(`{"agent": {"model": "a", "temperature": 0.2}, "tools": ["x"], "legacy": true}`)
+	newData := []byte(`{"agent": {"model": "b", "temperature": 0.2, "name": "Ada"}, "tools": ["x", "y"]}`)
+
+	changes, err := DiffJSONKeys("openclaw.json", oldData, newData)
+	if err != nil {
+		t.Fatalf("DiffJSONKeys failed: %v", err)
+	}
+
+	want := []ConfigKeyChange{
+		{File: "openclaw.json", Key: "agent.model", Kind: "modified", Old: `"a"`, New: `"b"`},
+		{File: "openclaw.json", Key: "agent.name", Kind: "added", New: `"Ada"`},
+		{File: "openclaw.json", Key: "legacy", Kind: "removed", Old: "true"},
+		{File: "openclaw.json", Key: "tools", Kind: "modified", Old: `["x"]`, New: `["x","y"]`},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("DiffJSONKeys =\n%+v\nwant\n%+v", changes, want)
+	}
+
+	if _, err := DiffJSONKeys("openclaw.json", []byte("{"), newData); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
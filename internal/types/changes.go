@@ -0,0 +1,99 @@
+package types
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// File categories used to describe where changes happened
+const (
+	CategorySkills      = "skills"
+	CategoryPersonality = "personality"
+	CategoryDefinitions = "definitions"
+	CategoryMemory      = "memory"
+	CategoryConfig      = "config"
+	CategoryOther       = "other"
+)
+
+// ChangeStats summarizes the size of one backup's diff.
+// Categories counts changes per "<kind> <category>", e.g. "added skills" or "modified personality".
+type ChangeStats struct {
+	SnapshotID string         `json:"snapshot_id"`
+	Added      int            `json:"added"`
+	Removed    int            `json:"removed"`
+	Modified   int            `json:"modified"`
+	Categories map[string]int `json:"categories,omitempty"`
+}
+
+// Total returns the number of changed files
+func (c ChangeStats) Total() int {
+	return c.Added + c.Removed + c.Modified
+}
+
+// Anomaly describes a backup whose change rate spiked above the baseline
+type Anomaly struct {
+	Changes  int             `json:"changes"`
+	Baseline float64         `json:"baseline"`
+	Spikes   []CategorySpike `json:"spikes,omitempty"`
+}
+
+// CategorySpike is a file category whose changes exceeded its own baseline
+type CategorySpike struct {
+	Category string  `json:"category"`
+	Changes  int     `json:"changes"`
+	Baseline float64 `json:"baseline"`
+}
+
+// String returns a one-line summary of the anomaly
+func (a *Anomaly) String() string {
+	return fmt.Sprintf("%d files changed (baseline %.1f per backup)", a.Changes, a.Baseline)
+}
+
+// Stats summarizes the diff by change kind and file category
+func (d *SnapshotDiff) Stats() ChangeStats {
+	stats := ChangeStats{
+		SnapshotID: d.To,
+		Added:      len(d.Added),
+		Removed:    len(d.Removed),
+		Modified:   len(d.Modified) + len(d.Renamed),
+		Categories: map[string]int{},
+	}
+
+	for _, p := range d.Added {
+		stats.Categories["added "+FileCategory(p)]++
+	}
+	for _, p := range d.Removed {
+		stats.Categories["removed "+FileCategory(p)]++
+	}
+	for _, p := range d.Modified {
+		stats.Categories["modified "+FileCategory(p)]++
+	}
+	// A case-only rename changes the file as far as the agent is concerned
+	for _, r := range d.Renamed {
+		stats.Categories["modified "+FileCategory(r.To)]++
+	}
+
+	return stats
+}
+
+// FileCategory classifies a snapshot path by the part of the agent it belongs to
+func FileCategory(p string) string {
+	p = strings.ToLower(p)
+	base := path.Base(p)
+
+	switch {
+	case strings.HasPrefix(p, "skills/") || strings.Contains(p, "/skills/"):
+		return CategorySkills
+	case strings.HasPrefix(p, "memory/") || strings.Contains(p, "/memory/"):
+		return CategoryMemory
+	case base == "soul.md" || base == "identity.md":
+		return CategoryPersonality
+	case base == "agents.md" || base == "tools.md":
+		return CategoryDefinitions
+	case base == "openclaw.json":
+		return CategoryConfig
+	default:
+		return CategoryOther
+	}
+}
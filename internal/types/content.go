@@ -0,0 +1,96 @@
+package types
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// Content types recorded in FileSnapshot.ContentType
+const (
+	ContentTypeText   = "text"
+	ContentTypeBinary = "binary"
+)
+
+// Encodings recorded in FileSnapshot.Encoding for text files
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+)
+
+// sniffLen is how many leading bytes are inspected to classify content,
+// matching the heuristic git uses for binary detection
+const sniffLen = 8000
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// contentSniffer is an io.Writer that keeps the leading bytes of a stream
+// so content can be classified while it is being hashed
+type contentSniffer struct {
+	prefix []byte
+}
+
+// Write records up to sniffLen bytes and never fails
+func (s *contentSniffer) Write(p []byte) (int, error) {
+	if remaining := sniffLen - len(s.prefix); remaining > 0 {
+		if len(p) < remaining {
+			remaining = len(p)
+		}
+		s.prefix = append(s.prefix, p[:remaining]...)
+	}
+	return len(p), nil
+}
+
+// classify returns the content type and encoding of the sniffed bytes
+func (s *contentSniffer) classify() (string, string) {
+	return classifyContent(s.prefix)
+}
+
+// classifyContent detects whether content is text or binary and, for text, its encoding.
+// A UTF-16 byte order mark marks the content as UTF-16 text; otherwise content is
+// text only if it has no NUL bytes and is valid UTF-8.
+func classifyContent(prefix []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(prefix, bomUTF8):
+		prefix = prefix[len(bomUTF8):]
+	case bytes.HasPrefix(prefix, bomUTF16LE):
+		return ContentTypeText, EncodingUTF16LE
+	case bytes.HasPrefix(prefix, bomUTF16BE):
+		return ContentTypeText, EncodingUTF16BE
+	}
+
+	if bytes.IndexByte(prefix, 0) >= 0 {
+		return ContentTypeBinary, ""
+	}
+
+	// The prefix may end in the middle of a multi-byte rune; ignore that tail
+	if len(prefix) == sniffLen {
+		for i := 1; i < utf8.UTFMax && i <= len(prefix); i++ {
+			if utf8.RuneStart(prefix[len(prefix)-i]) {
+				if !utf8.FullRune(prefix[len(prefix)-i:]) {
+					prefix = prefix[:len(prefix)-i]
+				}
+				break
+			}
+		}
+	}
+
+	if !utf8.Valid(prefix) {
+		return ContentTypeBinary, ""
+	}
+	return ContentTypeText, EncodingUTF8
+}
+
+// IsBinary reports whether the file was classified as binary at snapshot time
+func (f *FileSnapshot) IsBinary() bool {
+	return f.ContentType == ContentTypeBinary
+}
+
+// IsRenderableText reports whether the file is UTF-8 text that can be shown line by line
+func (f *FileSnapshot) IsRenderableText() bool {
+	return f.ContentType == ContentTypeText && f.Encoding == EncodingUTF8
+}
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     []byte
+		contentType string
+		encoding    string
+	}{
+		{"empty", []byte{}, ContentTypeText, EncodingUTF8},
+		{"ascii", []byte("hello world\n"), ContentTypeText, EncodingUTF8},
+		{"utf-8", []byte("héllo wörld ✓\n"), ContentTypeText, EncodingUTF8},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, "hello"...), ContentTypeText, EncodingUTF8},
+		{"utf-16le bom", []byte{0xFF, 0xFE, 'h', 0, 'i', 0}, ContentTypeText, EncodingUTF16LE},
+		{"utf-16be bom", []byte{0xFE, 0xFF, 0, 'h', 0, 'i'}, ContentTypeText, EncodingUTF16BE},
+		{"nul byte", []byte("abc\x00def"), ContentTypeBinary, ""},
+		{"invalid utf-8", []byte{'a', 0xC3, 0x28, 'b'}, ContentTypeBinary, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, encoding := classifyContent(tt.content)
+			if contentType != tt.contentType || encoding != tt.encoding {
+				t.Errorf("classifyContent() = (%q, %q), want (%q, %q)", contentType, encoding, tt.contentType, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestClassifyContent_RuneSplitAtSniffBoundary(t *testing.T) {
+	// "é" is two bytes; place it so only its first byte falls inside the sniffed prefix
+	content := append(bytes.Repeat([]byte("a"), sniffLen-1), "é"...)
+
+	sniffer := &contentSniffer{}
+	if _, err := sniffer.Write(content); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if len(sniffer.prefix) != sniffLen {
+		t.Fatalf("expected %d sniffed bytes, got %d", sniffLen, len(sniffer.prefix))
+	}
+
+	contentType, encoding := sniffer.classify()
+	if contentType != ContentTypeText || encoding != EncodingUTF8 {
+		t.Errorf("expected utf-8 text, got (%q, %q)", contentType, encoding)
+	}
+}
+
+func TestFromDirectory_RecordsContentType(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("# Notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "image.bin"), []byte{0x89, 'P', 'N', 'G', 0, 0, 0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := FromDirectory(dir, nil, "")
+	if err != nil {
+		t.Fatalf("FromDirectory failed: %v", err)
+	}
+
+	notes := snapshot.Files["notes.md"]
+	if notes == nil || !notes.IsRenderableText() {
+		t.Errorf("expected notes.md to be renderable text, got %+v", notes)
+	}
+
+	image := snapshot.Files["image.bin"]
+	if image == nil || !image.IsBinary() {
+		t.Errorf("expected image.bin to be binary, got %+v", image)
+	}
+}
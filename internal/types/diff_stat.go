@@ -0,0 +1,200 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// statBarWidth is the widest +/- bar PrintStat draws; larger changes are
+// scaled down to fit, as git does
+const statBarWidth = 40
+
+// FileStat is one line of a diff stat: how many lines a change inserted and
+// deleted, or for binary files and files whose content is unavailable, the
+// size before and after
+type FileStat struct {
+	Path       string // "old => new" for renames
+	Lines      bool   // Insertions and Deletions were counted
+	Binary     bool
+	Insertions int
+	Deletions  int
+	FromSize   int64
+	ToSize     int64
+}
+
+// Stat counts the lines each changed file gained and lost, sorted by path.
+// Content stored in the manifest is used first, then the reader of each side,
+// which may be nil; files whose content is unavailable report their sizes.
+func (d *SnapshotDiff) Stat(fromReader, toReader DestinationContentReader, from, to *Snapshot) []FileStat {
+	var stats []FileStat
+	for _, path := range d.Modified {
+		stats = append(stats, fileStat(path, path, fromReader, toReader, from, to))
+	}
+	for _, path := range d.Added {
+		stats = append(stats, fileStat("", path, fromReader, toReader, from, to))
+	}
+	for _, path := range d.Removed {
+		stats = append(stats, fileStat(path, "", fromReader, toReader, from, to))
+	}
+	for _, rename := range d.Renamed {
+		stat := FileStat{Lines: true}
+		if rename.Modified {
+			stat = fileStat(rename.From, rename.To, fromReader, toReader, from, to)
+		}
+		stat.Path = rename.From + " => " + rename.To
+		stats = append(stats, stat)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Path < stats[j].Path })
+	return stats
+}
+
+// fileStat measures the change from fromPath in from to toPath in to. An
+// empty path stands for a side without the file.
+func fileStat(fromPath, toPath string, fromReader, toReader DestinationContentReader, from, to *Snapshot) FileStat {
+	stat := FileStat{Path: toPath}
+	if toPath == "" {
+		stat.Path = fromPath
+	}
+
+	var fromFile, toFile *FileSnapshot
+	if fromPath != "" {
+		fromFile = from.Files[fromPath]
+	}
+	if toPath != "" {
+		toFile = to.Files[toPath]
+	}
+	if fromFile != nil {
+		stat.FromSize = fromFile.Size
+	}
+	if toFile != nil {
+		stat.ToSize = toFile.Size
+	}
+
+	// Decide from recorded metadata first so binary files are never read
+	if (fromFile != nil && fromFile.IsBinary()) || (toFile != nil && toFile.IsBinary()) {
+		stat.Binary = true
+		return stat
+	}
+	// Unchanged content whose mode or modification time changed
+	if fromFile != nil && toFile != nil && fromFile.Hash != "" && fromFile.Hash == toFile.Hash {
+		stat.Lines = true
+		return stat
+	}
+
+	if fromFile != nil && toFile != nil {
+		diff, err := loadContentDiff(fromPath, toPath, fromReader, toReader, from, to)
+		if err != nil {
+			return stat
+		}
+		if diff.binary {
+			stat.Binary = true
+			return stat
+		}
+		stat.Lines = true
+		for _, line := range splitLines(diff.hunks) {
+			switch line[0] {
+			case '+':
+				stat.Insertions++
+			case '-':
+				stat.Deletions++
+			}
+		}
+		return stat
+	}
+
+	// An added or removed file counts every line
+	reader, snapshot, path := toReader, to, toPath
+	if toFile == nil {
+		reader, snapshot, path = fromReader, from, fromPath
+	}
+	content, err := fileContent(reader, snapshot, path)
+	if err != nil {
+		return stat
+	}
+	if isBinary(content) {
+		stat.Binary = true
+		return stat
+	}
+	stat.Lines = true
+	lines := len(splitLines(content))
+	if toFile == nil {
+		stat.Deletions = lines
+	} else {
+		stat.Insertions = lines
+	}
+	return stat
+}
+
+// PrintStat prints the diff as git diff --stat does: one line per changed
+// file with the lines it gained and lost, or its sizes when its content is
+// binary or unavailable, then the totals
+func (d *SnapshotDiff) PrintStat(fromReader, toReader DestinationContentReader, from, to *Snapshot) {
+	if d.IsEmpty() {
+		fmt.Println("No changes detected.")
+		return
+	}
+	fmt.Print(FormatStat(d.Stat(fromReader, toReader, from, to)))
+}
+
+// FormatStat renders stats as a git-style diff stat table ending with a
+// "N files changed" line
+func FormatStat(stats []FileStat) string {
+	pathWidth, maxChanges := 0, 0
+	for _, stat := range stats {
+		pathWidth = max(pathWidth, len(stat.Path))
+		if stat.Lines {
+			maxChanges = max(maxChanges, stat.Insertions+stat.Deletions)
+		}
+	}
+	countWidth := len(fmt.Sprint(maxChanges))
+
+	var b strings.Builder
+	insertions, deletions := 0, 0
+	for _, stat := range stats {
+		fmt.Fprintf(&b, " %-*s | ", pathWidth, stat.Path)
+		switch {
+		case stat.Binary:
+			fmt.Fprintf(&b, "Bin %d -> %d bytes\n", stat.FromSize, stat.ToSize)
+		case !stat.Lines:
+			fmt.Fprintf(&b, "%d -> %d bytes (%+d)\n", stat.FromSize, stat.ToSize, stat.ToSize-stat.FromSize)
+		default:
+			plus, minus := stat.Insertions, stat.Deletions
+			if maxChanges > statBarWidth {
+				// Scale down, keeping at least one mark for any change
+				plus = scaleStat(plus, maxChanges)
+				minus = scaleStat(minus, maxChanges)
+			}
+			fmt.Fprintf(&b, "%*d %s%s\n", countWidth, stat.Insertions+stat.Deletions, strings.Repeat("+", plus), strings.Repeat("-", minus))
+			insertions += stat.Insertions
+			deletions += stat.Deletions
+		}
+	}
+
+	fmt.Fprintf(&b, " %d %s changed", len(stats), plural(len(stats), "file", "files"))
+	if insertions > 0 {
+		fmt.Fprintf(&b, ", %d %s(+)", insertions, plural(insertions, "insertion", "insertions"))
+	}
+	if deletions > 0 {
+		fmt.Fprintf(&b, ", %d %s(-)", deletions, plural(deletions, "deletion", "deletions"))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// scaleStat scales n changes to the bar width, given the largest change
+func scaleStat(n, largest int) int {
+	if n == 0 {
+		return 0
+	}
+	return max(1, n*statBarWidth/largest)
+}
+
+// plural picks the singular or plural form for n
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
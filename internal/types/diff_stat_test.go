@@ -0,0 +1,79 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotDiff_Stat(t *testing.T) {
+	clear(contentDiffCache)
+	t.Cleanup(func() { clear(contentDiffCache) })
+
+	content := func(s string) *string { return &s }
+	from := &Snapshot{ID: "from", Files: map[string]*FileSnapshot{
+		"SOUL.md":     {Path: "SOUL.md", Hash: "a1", Size: 8, Content: content("one\ntwo\n")},
+		"old.md":      {Path: "old.md", Hash: "a2", Size: 4, Content: content("bye\n")},
+		"logo.png":    {Path: "logo.png", Hash: "a3", Size: 100, ContentType: "binary"},
+		"notes.md":    {Path: "notes.md", Hash: "a4", Size: 10},
+		"skills/a.js": {Path: "skills/a.js", Hash: "a5", Size: 3, Content: content("a;\n")},
+		"run.sh":      {Path: "run.sh", Hash: "a6", Size: 5, Mode: 0644},
+	}}
+	to := &Snapshot{ID: "to", Files: map[string]*FileSnapshot{
+		"SOUL.md":     {Path: "SOUL.md", Hash: "b1", Size: 12, Content: content("one\n2\nthree\n")},
+		"new.md":      {Path: "new.md", Hash: "b2", Size: 6, Content: content("a\nb\nc\n")},
+		"logo.png":    {Path: "logo.png", Hash: "b3", Size: 120, ContentType: "binary"},
+		"notes.md":    {Path: "notes.md", Hash: "b4", Size: 14},
+		"skills/b.js": {Path: "skills/b.js", Hash: "a5", Size: 3, Content: content("a;\n")},
+		"run.sh":      {Path: "run.sh", Hash: "a6", Size: 5, Mode: 0755},
+	}}
+	diff := &SnapshotDiff{
+		Added:    []string{"new.md"},
+		Removed:  []string{"old.md"},
+		Modified: []string{"SOUL.md", "logo.png", "notes.md", "run.sh"},
+		Renamed:  []PathRename{{From: "skills/a.js", To: "skills/b.js"}},
+	}
+
+	// notes.md has no stored content and no reader, so only its sizes are known
+	want := []FileStat{
+		{Path: "SOUL.md", Lines: true, Insertions: 2, Deletions: 1, FromSize: 8, ToSize: 12},
+		{Path: "logo.png", Binary: true, FromSize: 100, ToSize: 120},
+		{Path: "new.md", Lines: true, Insertions: 3, ToSize: 6},
+		{Path: "notes.md", FromSize: 10, ToSize: 14},
+		{Path: "old.md", Lines: true, Deletions: 1, FromSize: 4},
+		{Path: "run.sh", Lines: true, FromSize: 5, ToSize: 5},
+		{Path: "skills/a.js => skills/b.js", Lines: true},
+	}
+	stats := diff.Stat(nil, nil, from, to)
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("Stat() =\n%+v\nwant\n%+v", stats, want)
+	}
+
+	wantTable := strings.Join([]string{
+		" SOUL.md                    | 3 ++-",
+		" logo.png                   | Bin 100 -> 120 bytes",
+		" new.md                     | 3 +++",
+		" notes.md                   | 10 -> 14 bytes (+4)",
+		" old.md                     | 1 -",
+		" run.sh                     | 0 ",
+		" skills/a.js => skills/b.js | 0 ",
+		" 7 files changed, 5 insertions(+), 2 deletions(-)",
+		"",
+	}, "\n")
+	if got := FormatStat(stats); got != wantTable {
+		t.Errorf("FormatStat() =\n%s\nwant\n%s", got, wantTable)
+	}
+}
+
+func TestFormatStat_ScalesLargeChanges(t *testing.T) {
+	got := FormatStat([]FileStat{
+		{Path: "big.md", Lines: true, Insertions: 300, Deletions: 100},
+		{Path: "small.md", Lines: true, Insertions: 1},
+	})
+	want := " big.md   | 400 " + strings.Repeat("+", 30) + strings.Repeat("-", 10) + "\n" +
+		" small.md |   1 +\n" +
+		" 2 files changed, 301 insertions(+), 100 deletions(-)\n"
+	if got != want {
+		t.Errorf("FormatStat() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// excludeMatcher matches relative paths against exclude patterns with
+// .gitignore rules:
+//   - a pattern without a slash, such as "*.log", matches a name at any depth
+//   - a pattern with a leading or middle slash, such as "/openclaw.json" or
+//     "workspace/memory", is anchored at the source root
+//   - a trailing slash, as in "node_modules/", matches directories only
+//   - "**" spans directory levels, as in "memory/**/*.json"
+//   - a leading "!" re-includes what earlier patterns excluded; the last
+//     matching pattern wins
+//
+// As in git, a file inside an excluded directory cannot be re-included.
+// Blank patterns and those starting with "#" are ignored.
+type excludeMatcher struct {
+	matcher gitignore.Matcher
+	empty   bool
+
+	// fromFiles holds the patterns of the ignore files read so far, each
+	// anchored at its file's directory, in the order they were read.
+	// configured holds the patterns given directly, which take priority.
+	fromFiles  []gitignore.Pattern
+	configured []gitignore.Pattern
+}
+
+// IgnoreFileName is a file in a source folder, or any folder below it, that
+// lists more exclude patterns for that folder, one per line, as in .gitignore
+const IgnoreFileName = ".bulletproofignore"
+
+// newExcludeMatcher parses exclude patterns, in order of increasing priority
+func newExcludeMatcher(patterns []string) *excludeMatcher {
+	m := &excludeMatcher{configured: parsePatterns(patterns, nil)}
+	m.build()
+	return m
+}
+
+// parsePatterns parses the patterns that apply below domain, the path
+// components of a directory relative to the source root
+func parsePatterns(patterns []string, domain []string) []gitignore.Pattern {
+	var parsed []gitignore.Pattern
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		parsed = append(parsed, gitignore.ParsePattern(filepath.ToSlash(pattern), domain))
+	}
+	return parsed
+}
+
+// build rebuilds the matcher with the ignore file patterns ranked below the
+// configured ones, so a configured "!" pattern can re-include what an ignore
+// file leaves out, but an ignore file cannot re-include what is configured out
+func (m *excludeMatcher) build() {
+	patterns := make([]gitignore.Pattern, 0, len(m.fromFiles)+len(m.configured))
+	patterns = append(append(patterns, m.fromFiles...), m.configured...)
+	m.matcher = gitignore.NewMatcher(patterns)
+	m.empty = len(patterns) == 0
+}
+
+// readIgnoreFile adds the patterns of dir's ignore file, if it has one.
+// relDir is dir relative to the source root, and the patterns only apply below
+// it. Files read later, i.e. deeper ones, take priority over earlier ones.
+func (m *excludeMatcher) readIgnoreFile(dir, relDir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var domain []string
+	if relDir != "." {
+		domain = strings.Split(filepath.ToSlash(relDir), "/")
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	m.fromFiles = append(m.fromFiles, parsePatterns(lines, domain)...)
+	m.build()
+	return nil
+}
+
+// excludesDir reports whether a directory and everything below it is excluded
+func (m *excludeMatcher) excludesDir(relPath string) bool {
+	if m.empty {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := 1; i <= len(parts); i++ {
+		if m.matcher.Match(parts[:i], true) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludes reports whether a file is excluded, by its own path or by one of
+// its parent directories
+func (m *excludeMatcher) excludes(relPath string) bool {
+	if m.empty {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	if dir := strings.Join(parts[:len(parts)-1], "/"); dir != "" && m.excludesDir(dir) {
+		return true
+	}
+	return m.matcher.Match(parts, false)
+}
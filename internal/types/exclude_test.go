@@ -0,0 +1,155 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestExcludeMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		// The default excludes
+		{"extension at root", []string{"*.log"}, "debug.log", true},
+		{"extension at depth", []string{"*.log"}, "workspace/logs/run.log", true},
+		{"directory at root", []string{"node_modules/"}, "node_modules/pkg/index.js", true},
+		{"directory at depth", []string{".git/"}, "skills/tool/.git/config", true},
+		{"directory pattern skips files of that name", []string{"cache/"}, "workspace/cache", false},
+
+		// Anchoring
+		{"leading slash anchors at the root", []string{"/openclaw.json"}, "openclaw.json", true},
+		{"leading slash ignores deeper files", []string{"/openclaw.json"}, "workspace/openclaw.json", false},
+		{"name without slash matches at any depth", []string{"openclaw.json"}, "workspace/openclaw.json", true},
+		{"middle slash anchors at the root", []string{"skills/*.js"}, "skills/weather.js", true},
+		{"middle slash ignores deeper directories", []string{"skills/*.js"}, "workspace/skills/weather.js", false},
+		{"middle-of-path glob", []string{"workspace/*/draft.md"}, "workspace/notes/draft.md", true},
+		{"middle-of-path glob stays on one level", []string{"workspace/*/draft.md"}, "workspace/notes/old/draft.md", false},
+		{"anchored directory covers its contents", []string{"workspace/memory"}, "workspace/memory/2026/01.md", true},
+
+		// ** spans directories
+		{"** between directories", []string{"memory/**/*.json"}, "memory/2026/01/index.json", true},
+		{"** matches zero directories", []string{"memory/**/*.json"}, "memory/index.json", true},
+		{"** keeps the rest of the pattern", []string{"memory/**/*.json"}, "memory/2026/notes.md", false},
+		{"** ending in a directory name", []string{"workspace/**/temp"}, "workspace/a/b/temp/scratch.md", true},
+		{"** ending in a directory name needs the name", []string{"workspace/**/temp"}, "workspace/a/temporary.md", false},
+		{"leading **", []string{"**/cache/*.json"}, "workspace/tools/cache/hits.json", true},
+		{"trailing **", []string{"workspace/**"}, "workspace/a/b.md", true},
+
+		// Negation
+		{"negation re-includes a file", []string{"skills/*.js", "!skills/keep.js"}, "skills/keep.js", false},
+		{"negation leaves the others excluded", []string{"skills/*.js", "!skills/keep.js"}, "skills/drop.js", true},
+		{"the last matching pattern wins", []string{"!skills/keep.js", "skills/*.js"}, "skills/keep.js", true},
+		{"no re-including inside an excluded directory", []string{"skills/", "!skills/keep.js"}, "skills/keep.js", true},
+		{"re-including a directory below a glob", []string{"workspace/*", "!workspace/memory/"}, "workspace/memory/01.md", false},
+
+		// Comments and blanks
+		{"comments are ignored", []string{"# *.md"}, "SOUL.md", false},
+		{"blank patterns are ignored", []string{"", "  "}, "SOUL.md", false},
+		{"no patterns", nil, "SOUL.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newExcludeMatcher(tt.patterns).excludes(filepath.FromSlash(tt.path)); got != tt.want {
+				t.Errorf("excludes(%q) with %q = %v, want %v", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanDirectory_GitignoreExcludes(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{
+		"openclaw.json",
+		"workspace/openclaw.json",
+		"skills/keep.js",
+		"skills/drop.js",
+		"memory/2026/index.json",
+		"memory/2026/notes.md",
+		"node_modules/pkg/index.js",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := ScanOptions{Exclude: []string{"/openclaw.json", "skills/*.js", "!skills/keep.js", "memory/**/*.json", "node_modules/"}}
+	snapshot, err := ScanDirectory(root, opts, "", time.Now())
+	if err != nil {
+		t.Fatalf("ScanDirectory failed: %v", err)
+	}
+
+	want := []string{"memory/2026/notes.md", "skills/keep.js", "workspace/openclaw.json"}
+	if len(snapshot.Files) != len(want) {
+		t.Errorf("expected %v, got %d files", want, len(snapshot.Files))
+	}
+	for _, file := range want {
+		if _, ok := snapshot.Files[filepath.FromSlash(file)]; !ok {
+			t.Errorf("expected %s to be backed up", file)
+		}
+	}
+}
+
+func TestScanDirectory_IgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+	for file, content := range map[string]string{
+		".bulletproofignore":                  "# agent-shipped rules\r\ntmp/\n*.cache  \n!keep.cache\n",
+		"openclaw.json":                       "{}",
+		"keep.cache":                          "kept",
+		"drop.cache":                          "dropped",
+		"tmp/scratch.txt":                     "dropped",
+		"secrets.env":                         "dropped by config",
+		"skills/.bulletproofignore":           "/build\n!secrets.env\n",
+		"skills/build/out.js":                 "dropped",
+		"skills/tool.js":                      "kept",
+		"skills/secrets.env":                  "config wins over ignore files",
+		"workspace/build/notes.md":            "not under skills, kept",
+		"workspace/memory/.bulletproofignore": "*.md\n",
+		"workspace/memory/today.md":           "dropped",
+		"workspace/memory/index.json":         "kept",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := ScanDirectory(root, ScanOptions{Exclude: []string{"*.env"}}, "", time.Now())
+	if err != nil {
+		t.Fatalf("ScanDirectory failed: %v", err)
+	}
+
+	// The ignore files themselves are backed up with the agent
+	want := []string{
+		".bulletproofignore",
+		"keep.cache",
+		"openclaw.json",
+		"skills/.bulletproofignore",
+		"skills/tool.js",
+		"workspace/build/notes.md",
+		"workspace/memory/.bulletproofignore",
+		"workspace/memory/index.json",
+	}
+	var got []string
+	for path := range snapshot.Files {
+		got = append(got, filepath.ToSlash(path))
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanned %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileTree is a node in a snapshot's file hierarchy. Directory nodes total the
+// files and bytes beneath them; file nodes count themselves.
+type FileTree struct {
+	Name     string      `json:"name"`
+	Dir      bool        `json:"dir"`
+	Files    int         `json:"files"`
+	Size     int64       `json:"size"`
+	Children []*FileTree `json:"children,omitempty"`
+}
+
+// NewFileTree builds the directory hierarchy of a snapshot's files from its
+// manifest alone. The root is named name; children are sorted by name.
+func NewFileTree(name string, files map[string]*FileSnapshot) *FileTree {
+	root := &FileTree{Name: name, Dir: true}
+	dirs := map[*FileTree]map[string]*FileTree{}
+
+	child := func(parent *FileTree, name string, dir bool) *FileTree {
+		if dirs[parent] == nil {
+			dirs[parent] = make(map[string]*FileTree)
+		}
+		node := dirs[parent][name]
+		if node == nil {
+			node = &FileTree{Name: name, Dir: dir}
+			dirs[parent][name] = node
+			parent.Children = append(parent.Children, node)
+		}
+		return node
+	}
+
+	for path, file := range files {
+		parts := strings.Split(filepath.ToSlash(path), "/")
+		node := root
+		node.Files++
+		node.Size += file.Size
+		for i, part := range parts {
+			node = child(node, part, i < len(parts)-1)
+			node.Files++
+			node.Size += file.Size
+		}
+	}
+
+	root.sort()
+	return root
+}
+
+func (t *FileTree) sort() {
+	sort.Slice(t.Children, func(i, j int) bool { return t.Children[i].Name < t.Children[j].Name })
+	for _, child := range t.Children {
+		child.sort()
+	}
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFileTree(t *testing.T) {
+	files := map[string]*FileSnapshot{
+		"openclaw.json":                                         {Size: 10},
+		filepath.Join("workspace", "SOUL.md"):                   {Size: 100},
+		filepath.Join("workspace", "skills", "weather", "a.js"): {Size: 30},
+		filepath.Join("workspace", "skills", "weather", "b.js"): {Size: 20},
+	}
+
+	tree := NewFileTree("snap", files)
+
+	if tree.Name != "snap" || !tree.Dir || tree.Files != 4 || tree.Size != 160 {
+		t.Fatalf("unexpected root: %+v", tree)
+	}
+	if len(tree.Children) != 2 || tree.Children[0].Name != "openclaw.json" || tree.Children[1].Name != "workspace" {
+		t.Fatalf("expected children sorted by name, got %+v", tree.Children)
+	}
+	if file := tree.Children[0]; file.Dir || file.Files != 1 || file.Size != 10 || len(file.Children) != 0 {
+		t.Errorf("unexpected file node: %+v", file)
+	}
+
+	workspace := tree.Children[1]
+	if workspace.Files != 3 || workspace.Size != 150 {
+		t.Errorf("workspace = %d files, %d bytes; want 3 files, 150 bytes", workspace.Files, workspace.Size)
+	}
+	weather := workspace.Children[1].Children[0]
+	if weather.Name != "weather" || !weather.Dir || weather.Files != 2 || weather.Size != 50 {
+		t.Errorf("unexpected skill node: %+v", weather)
+	}
+}
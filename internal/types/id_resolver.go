@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"sort"
 	"strconv"
+	"time"
 )
 
 // IsShortID returns true if the given ID is a short numeric ID
@@ -92,3 +93,19 @@ func AssignShortIDs(snapshots []*SnapshotInfo) map[string]int {
 
 	return shortIDs
 }
+
+// SnapshotAt returns the newest snapshot taken at or before t, or nil if every
+// snapshot is newer. Snapshots taken in the same instant are told apart by ID.
+func SnapshotAt(t time.Time, snapshots []*SnapshotInfo) *SnapshotInfo {
+	var newest *SnapshotInfo
+	for _, info := range snapshots {
+		if info.Timestamp.After(t) {
+			continue
+		}
+		if newest == nil || info.Timestamp.After(newest.Timestamp) ||
+			(info.Timestamp.Equal(newest.Timestamp) && info.ID > newest.ID) {
+			newest = info
+		}
+	}
+	return newest
+}
@@ -210,3 +210,30 @@ func TestResolveID_SingleSnapshot(t *testing.T) {
 		t.Error("ResolveID(\"2\") with single snapshot should return error")
 	}
 }
+
+func TestSnapshotAt(t *testing.T) {
+	base := time.Date(2026, 2, 3, 12, 0, 0, 0, time.UTC)
+	snapshots := []*SnapshotInfo{
+		{ID: "20260203-120000-000", Timestamp: base},
+		{ID: "20260204-120000-000", Timestamp: base.Add(24 * time.Hour)},
+		{ID: "20260204-120000-500", Timestamp: base.Add(24 * time.Hour)},
+		{ID: "20260205-120000-000", Timestamp: base.Add(48 * time.Hour)},
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		{"exactly at a snapshot", base, "20260203-120000-000"},
+		{"between snapshots", base.Add(36 * time.Hour), "20260204-120000-500"},
+		{"after the newest", base.Add(72 * time.Hour), "20260205-120000-000"},
+		{"before the oldest", base.Add(-time.Hour), ""},
+	}
+	for _, tt := range tests {
+		got := SnapshotAt(tt.at, snapshots)
+		if (got == nil && tt.want != "") || (got != nil && got.ID != tt.want) {
+			t.Errorf("%s: SnapshotAt = %v, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// labelPattern restricts labels to names that are also valid git tag components
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+
+// NormalizeLabels validates snapshot labels and drops duplicates, keeping their order.
+// Labels may contain letters, digits, '.', '-' and '_', and must start and end
+// with a letter or digit.
+func NormalizeLabels(labels []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if !labelPattern.MatchString(label) || strings.Contains(label, "..") || strings.HasSuffix(label, ".lock") {
+			return nil, fmt.Errorf("invalid label %q: use letters, digits, '.', '-' and '_', starting and ending with a letter or digit", label)
+		}
+		if !seen[label] {
+			seen[label] = true
+			normalized = append(normalized, label)
+		}
+	}
+	return normalized, nil
+}
+
+// HasLabels reports whether the snapshot carries every one of labels
+func (si *SnapshotInfo) HasLabels(labels []string) bool {
+	for _, want := range labels {
+		found := false
+		for _, label := range si.Labels {
+			if label == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeLabels(t *testing.T) {
+	got, err := NormalizeLabels([]string{"release", " pre-exp ", "release", "v2.1_rc"})
+	if err != nil {
+		t.Fatalf("NormalizeLabels failed: %v", err)
+	}
+	want := []string{"release", "pre-exp", "v2.1_rc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeLabels() = %q, want %q", got, want)
+	}
+
+	for _, invalid := range []string{"", "two words", "-release", "release.", "a..b", "x.lock", "a/b"} {
+		if _, err := NormalizeLabels([]string{invalid}); err == nil {
+			t.Errorf("expected label %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestSnapshotInfoHasLabels(t *testing.T) {
+	info := &SnapshotInfo{Labels: []string{"release", "stable"}}
+
+	if !info.HasLabels(nil) {
+		t.Error("every snapshot has no required labels")
+	}
+	if !info.HasLabels([]string{"stable", "release"}) {
+		t.Error("expected both labels to match")
+	}
+	if info.HasLabels([]string{"release", "experiment"}) {
+		t.Error("expected a missing label not to match")
+	}
+}
@@ -0,0 +1,119 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// rawPathKeyPrefix marks manifest keys standing in for paths that are not valid
+// UTF-8. NUL cannot occur in a real file name, so these keys never clash.
+const rawPathKeyPrefix = "\x00raw:"
+
+// FoldPath returns the form under which a case-insensitive, Unicode-normalizing
+// filesystem (the macOS and Windows defaults) treats a path: NFC-normalized and
+// lowercased. Paths with the same folded form name the same file there.
+func FoldPath(path string) string {
+	return strings.ToLower(norm.NFC.String(path))
+}
+
+// FindPathCollisions returns groups of distinct paths that share a folded form,
+// e.g. "café.txt" spelled with a precomposed and a combining accent, or
+// "Notes.md" and "notes.md". Such files silently replace each other when written
+// to a folding filesystem. Groups and their members are sorted.
+func FindPathCollisions(paths []string) [][]string {
+	byFolded := make(map[string][]string)
+	for _, path := range paths {
+		folded := FoldPath(path)
+		byFolded[folded] = append(byFolded[folded], path)
+	}
+
+	var collisions [][]string
+	for _, group := range byFolded {
+		if len(group) > 1 {
+			sort.Strings(group)
+			collisions = append(collisions, group)
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i][0] < collisions[j][0] })
+	return collisions
+}
+
+// PathCollisions returns the groups of this snapshot's files that collide on a
+// case-insensitive or Unicode-normalizing filesystem
+func (s *Snapshot) PathCollisions() [][]string {
+	paths := make([]string, 0, len(s.Files))
+	for path := range s.Files {
+		paths = append(paths, path)
+	}
+	return FindPathCollisions(paths)
+}
+
+// MarshalJSON encodes the snapshot, recording the raw bytes of any path that is
+// not valid UTF-8. encoding/json would otherwise replace the invalid bytes, which
+// changes the name and can merge distinct files into one manifest entry.
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	type plain Snapshot
+	out := plain(s)
+
+	for path := range s.Files {
+		if utf8.ValidString(path) {
+			continue
+		}
+
+		// Only copy the file map when there is something to encode
+		out.Files = make(map[string]*FileSnapshot, len(s.Files))
+		for path, file := range s.Files {
+			if utf8.ValidString(path) {
+				out.Files[path] = file
+				continue
+			}
+			encoded := *file
+			encoded.Path = strings.ToValidUTF8(path, "\uFFFD")
+			encoded.RawPath = []byte(path)
+			out.Files[rawPathKeyPrefix+base64.StdEncoding.EncodeToString(encoded.RawPath)] = &encoded
+		}
+		break
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a snapshot, restoring paths recorded as raw bytes
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	type plain Snapshot
+	var in plain
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	for key, file := range in.Files {
+		if file == nil || file.RawPath == nil {
+			continue
+		}
+		delete(in.Files, key)
+		file.Path = string(file.RawPath)
+		file.RawPath = nil
+		in.Files[file.Path] = file
+	}
+
+	*s = Snapshot(in)
+	return nil
+}
+
+// PlatformName returns the display name of a GOOS value, e.g. "macOS" for darwin
+func PlatformName(goos string) string {
+	switch goos {
+	case "linux":
+		return "Linux"
+	case "darwin":
+		return "macOS"
+	case "windows":
+		return "Windows"
+	}
+	return goos
+}
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	cafeNFC = "caf\u00e9.txt"  // precomposed é
+	cafeNFD = "cafe\u0301.txt" // e + combining acute accent
+)
+
+func TestFindPathCollisions(t *testing.T) {
+	paths := []string{
+		"workspace/" + cafeNFC,
+		"workspace/" + cafeNFD,
+		"Notes.md",
+		"notes.md",
+		"SOUL.md",
+		"skills/other.md",
+	}
+
+	got := FindPathCollisions(paths)
+	want := [][]string{
+		{"Notes.md", "notes.md"},
+		{"workspace/" + cafeNFD, "workspace/" + cafeNFC},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindPathCollisions() = %q, want %q", got, want)
+	}
+
+	if got := FindPathCollisions([]string{"a.txt", "b.txt"}); len(got) != 0 {
+		t.Errorf("expected no collisions, got %q", got)
+	}
+}
+
+func TestSnapshotJSON_PreservesRawPathBytes(t *testing.T) {
+	// Two Latin-1 names that are invalid UTF-8 and would both decode to "caf�.txt"
+	latin1 := "caf\xe9.txt"
+	other := "caf\xe8.txt"
+
+	snapshot := &Snapshot{
+		ID: "20240101-120000-000",
+		Files: map[string]*FileSnapshot{
+			latin1:  {Path: latin1, Hash: "h1"},
+			other:   {Path: other, Hash: "h2"},
+			cafeNFC: {Path: cafeNFC, Hash: "h3"},
+			cafeNFD: {Path: cafeNFD, Hash: "h4"},
+		},
+	}
+
+	data, err := snapshot.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	decoded, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+
+	if len(decoded.Files) != len(snapshot.Files) {
+		t.Fatalf("expected %d files after round trip, got %d", len(snapshot.Files), len(decoded.Files))
+	}
+	for path, file := range snapshot.Files {
+		got, ok := decoded.Files[path]
+		if !ok {
+			t.Errorf("file %q lost in round trip", path)
+			continue
+		}
+		if got.Path != path || got.Hash != file.Hash || got.RawPath != nil {
+			t.Errorf("file %q decoded as %+v", path, got)
+		}
+	}
+
+	// The original snapshot is not modified by encoding
+	if snapshot.Files[latin1].RawPath != nil {
+		t.Error("MarshalJSON modified the snapshot")
+	}
+}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ProgressFunc reports progress through the files of a backup or restore:
+// done of total files are handled, currentPath being the last one
+type ProgressFunc func(done, total int, currentPath string)
+
+// progressInterval is how often the default printer reports
+const progressInterval = time.Second
+
+// ProgressPrinter returns a ProgressFunc that prints a counter with a
+// percentage to w, e.g. "  Copied 1200/4000 files (30%)", at most once a
+// second and once more when the last file is done. Copies that finish within
+// a second print nothing, so only slow ones show they are still moving.
+func ProgressPrinter(w io.Writer, verb string) ProgressFunc {
+	return newProgressPrinter(w, verb, progressInterval, time.Now)
+}
+
+func newProgressPrinter(w io.Writer, verb string, interval time.Duration, now func() time.Time) ProgressFunc {
+	start := now()
+	last := start
+	printed := false
+	return func(done, total int, currentPath string) {
+		if total == 0 {
+			return
+		}
+		t := now()
+		if done < total && t.Sub(last) < interval {
+			return
+		}
+		if done == total && !printed && t.Sub(start) < interval {
+			return
+		}
+		last, printed = t, true
+		fmt.Fprintf(w, "  %s %d/%d files (%d%%)\n", verb, done, total, done*100/total)
+	}
+}
+
+// FilePrinter returns a ProgressFunc that prints each file as it is handled,
+// e.g. "  Copied workspace/SOUL.md (3/40)", for --verbose
+func FilePrinter(w io.Writer, verb string) ProgressFunc {
+	return func(done, total int, currentPath string) {
+		fmt.Fprintf(w, "  %s %s (%d/%d)\n", verb, currentPath, done, total)
+	}
+}
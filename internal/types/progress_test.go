@@ -0,0 +1,41 @@
+package types
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressPrinter(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := func() time.Time { return clock }
+
+	// A copy that finishes within the interval prints nothing
+	var out strings.Builder
+	progress := newProgressPrinter(&out, "Copied", time.Second, now)
+	for done := 1; done <= 3; done++ {
+		progress(done, 3, "file")
+	}
+	if out.String() != "" {
+		t.Errorf("expected a fast copy to print nothing, got %q", out.String())
+	}
+
+	// A slow one prints at most once per interval, and the final count
+	out.Reset()
+	progress = newProgressPrinter(&out, "Copied", time.Second, now)
+	for done := 1; done <= 4; done++ {
+		clock = clock.Add(600 * time.Millisecond)
+		progress(done, 4, "file")
+	}
+	want := "  Copied 2/4 files (50%)\n  Copied 4/4 files (100%)\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+
+	// Nothing to copy, nothing to report
+	out.Reset()
+	newProgressPrinter(&out, "Copied", 0, now)(0, 0, "")
+	if out.String() != "" {
+		t.Errorf("expected no output without files, got %q", out.String())
+	}
+}
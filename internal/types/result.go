@@ -2,15 +2,40 @@ package types
 
 import (
 	"fmt"
+	"sort"
 	"time"
 )
 
 // BackupResult represents the result of a backup operation
 type BackupResult struct {
-	Snapshot *Snapshot
-	Diff     *SnapshotDiff
-	Skipped  bool
-	DryRun   bool
+	Snapshot     *Snapshot
+	Diff         *SnapshotDiff
+	Skipped      bool
+	DryRun       bool
+	Anomaly      *Anomaly         // set when the change rate spiked above the baseline
+	LastSnapshot *Snapshot        // the newest stored snapshot before this run, nil on the first backup
+	Unreadable   []UnreadableFile // files left out because they could not be read
+}
+
+// RestoreResult represents the result of a full restore
+type RestoreResult struct {
+	SnapshotID string // full ID of the restored snapshot
+	Target     string // the folder restored into
+	DryRun     bool
+	// Cancelled is set when the confirmation was declined and nothing changed
+	Cancelled bool
+	// FilesRestored counts the snapshot's files written to the target, or
+	// that would be on a dry run
+	FilesRestored int
+	// FilesRemoved counts files the restore deleted from the target because
+	// the snapshot does not hold them
+	FilesRemoved int
+	// SafetyBackupID is the snapshot of the target taken before the restore,
+	// empty when there was nothing to protect. For an alternate target it is
+	// kept in the target's own store, not among the agent's snapshots.
+	SafetyBackupID string
+	// ScriptsExecuted names the post-restore scripts that ran, in order
+	ScriptsExecuted []string
 }
 
 // SnapshotInfo provides basic information about a snapshot (for listing)
@@ -19,6 +44,11 @@ type SnapshotInfo struct {
 	Timestamp time.Time
 	Message   string
 	FileCount int
+	// SizeBytes is the combined size of the snapshot's files, or -1 when unknown
+	SizeBytes int64
+	Labels    []string
+	// ManifestOnly marks a snapshot stored without file contents
+	ManifestOnly bool
 }
 
 // String returns a string representation of snapshot info
@@ -29,3 +59,87 @@ func (si *SnapshotInfo) String() string {
 	}
 	return fmt.Sprintf("%s (%d files)%s", si.ID, si.FileCount, msg)
 }
+
+// RestorePlan describes what a restore would change in its target, without applying it
+type RestorePlan struct {
+	SnapshotID string        `json:"snapshot_id"`
+	Target     string        `json:"target"`
+	Changes    int           `json:"changes"`
+	Add        []PlannedFile `json:"add"`
+	Modify     []PlannedFile `json:"modify"`
+	Remove     []PlannedFile `json:"remove"`
+	// Collisions lists groups of restored files whose names differ only by case or
+	// Unicode normalization; a folding filesystem keeps one file per group
+	Collisions [][]string `json:"collisions,omitempty"`
+}
+
+// PlannedFile is one file affected by a restore.
+// Size is the size after the restore, or the current size for removed files.
+type PlannedFile struct {
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	CurrentSize *int64 `json:"current_size,omitempty"` // set for modified files
+}
+
+// NewRestorePlan builds the plan for restoring snapshot over current
+func NewRestorePlan(snapshot, current *Snapshot, target string) *RestorePlan {
+	plan := &RestorePlan{
+		SnapshotID: snapshot.ID,
+		Target:     target,
+		Add:        []PlannedFile{},
+		Modify:     []PlannedFile{},
+		Remove:     []PlannedFile{},
+	}
+
+	for path, file := range snapshot.Files {
+		currentFile, exists := current.Files[path]
+		if !exists {
+			plan.Add = append(plan.Add, PlannedFile{Path: path, Size: file.Size})
+		} else if file.Hash != currentFile.Hash {
+			currentSize := currentFile.Size
+			plan.Modify = append(plan.Modify, PlannedFile{Path: path, Size: file.Size, CurrentSize: &currentSize})
+		}
+	}
+	for path, currentFile := range current.Files {
+		if _, exists := snapshot.Files[path]; !exists {
+			plan.Remove = append(plan.Remove, PlannedFile{Path: path, Size: currentFile.Size})
+		}
+	}
+
+	for _, files := range [][]PlannedFile{plan.Add, plan.Modify, plan.Remove} {
+		sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	}
+	plan.Changes = len(plan.Add) + len(plan.Modify) + len(plan.Remove)
+	plan.Collisions = snapshot.PathCollisions()
+
+	return plan
+}
+
+// Outcomes of syncing one ref with a remote
+const (
+	RefPushed   = "pushed"   // the remote now has the ref
+	RefPulled   = "pulled"   // the remote was ahead and the local copy caught up
+	RefDiverged = "diverged" // both sides have commits the other lacks
+	RefRejected = "rejected" // the remote holds a different ref of the same name
+	RefFailed   = "failed"   // the push did not go through, e.g. offline
+)
+
+// RefSync reports the outcome of syncing one git ref (a branch or snapshot tag)
+type RefSync struct {
+	Ref    string
+	Status string
+	Detail string
+}
+
+// OK reports whether the ref is now in step with the remote
+func (r RefSync) OK() bool {
+	return r.Status == RefPushed || r.Status == RefPulled
+}
+
+// String returns a string representation of the ref's outcome
+func (r RefSync) String() string {
+	if r.Detail == "" {
+		return fmt.Sprintf("%s: %s", r.Ref, r.Status)
+	}
+	return fmt.Sprintf("%s: %s (%s)", r.Ref, r.Status, r.Detail)
+}
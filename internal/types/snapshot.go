@@ -1,15 +1,21 @@
 package types
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/bulletproof-bot/backup/internal/version"
 )
 
 // Snapshot represents a point-in-time backup snapshot
@@ -18,14 +24,119 @@ type Snapshot struct {
 	Timestamp time.Time                `json:"timestamp"`
 	Files     map[string]*FileSnapshot `json:"files"`
 	Message   string                   `json:"message,omitempty"`
+
+	// Parent is the ID of the snapshot that was the latest when this one was
+	// taken, so backups can be followed back as a chain. Empty for the first
+	// backup and for snapshots taken before it was recorded.
+	Parent string `json:"parent,omitempty"`
+
+	// Labels are user-assigned names such as "release", set at backup time or
+	// later with `bulletproof tag`
+	Labels []string `json:"labels,omitempty"`
+
+	// Sources maps file path prefixes to source directories for multi-source snapshots
+	Sources []SourceMapping `json:"sources,omitempty"`
+
+	// OriginalRoot is the absolute path a single-source snapshot was taken from,
+	// so a restore on another machine knows where the files used to live
+	OriginalRoot string `json:"original_root,omitempty"`
+
+	// Hostname, OS and CreatedBy record the machine, its operating system (as
+	// in runtime.GOOS) and the bulletproof version a snapshot was taken with,
+	// so a restore on another platform can warn about paths. Snapshots taken
+	// before they were recorded leave them empty.
+	Hostname  string `json:"hostname,omitempty"`
+	OS        string `json:"os,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+
+	// ChangeHistory holds the change stats of the most recent backups, newest last.
+	// It is carried forward from snapshot to snapshot as the anomaly detection baseline.
+	ChangeHistory []ChangeStats `json:"change_history,omitempty"`
+
+	// Anomaly flags a snapshot whose change rate spiked above the baseline
+	Anomaly *Anomaly `json:"anomaly,omitempty"`
+
+	// ManifestOnly marks a snapshot stored without file contents. It records
+	// hashes, sizes and times for diffs and drift checks, but cannot be restored.
+	ManifestOnly bool `json:"manifest_only,omitempty"`
+
+	// Encrypted marks a snapshot whose stored files are encrypted. Its manifest
+	// stays in the clear, with hashes of the plaintext.
+	Encrypted bool `json:"encrypted,omitempty"`
+
+	// Oversized lists the files left out for being larger than the configured
+	// maximum file size
+	Oversized []SkippedFile `json:"oversized,omitempty"`
+
+	// Unreadable lists the files left out because they could not be read
+	Unreadable []UnreadableFile `json:"unreadable,omitempty"`
+
+	// Compression names the method the stored files are compressed with, e.g.
+	// "gzip"; each is stored under its path plus the method's suffix. Empty when
+	// files are stored uncompressed. Hashes are of the uncompressed content.
+	Compression string `json:"compression,omitempty"`
+}
+
+// SkippedFile is a file a backup left out
+type SkippedFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// UnreadableFile is a file a backup left out because reading it failed
+type UnreadableFile struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// ReadError reports a file a scan could not read
+type ReadError struct {
+	Path string
+	Err  error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("cannot read %s: %v", e.Path, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// RestoreMode gives the restored copy of path under root the permission bits
+// recorded for it. Files without a recorded mode, such as those of snapshots
+// made before modes were recorded, keep the mode they were written with.
+func (s *Snapshot) RestoreMode(root, path string) error {
+	if s == nil {
+		return nil
+	}
+	file, ok := s.Files[path]
+	if !ok || file.Mode == 0 {
+		return nil
+	}
+	return os.Chmod(filepath.Join(root, path), file.Mode.Perm())
+}
+
+// StoredVerbatim reports whether the snapshot's stored files are exact copies
+// of the originals, neither compressed nor encrypted
+func (s *Snapshot) StoredVerbatim() bool {
+	return !s.Encrypted && s.Compression == ""
 }
 
 // FileSnapshot represents a single file in a snapshot
 type FileSnapshot struct {
-	Path     string    `json:"path"`
-	Hash     string    `json:"hash"`
-	Size     int64     `json:"size"`
-	Modified time.Time `json:"modified"`
+	Path        string      `json:"path"`
+	Hash        string      `json:"hash"`
+	Size        int64       `json:"size"`
+	Modified    time.Time   `json:"modified"`
+	Mode        os.FileMode `json:"mode,omitempty"`         // permission bits; zero for older snapshots
+	ContentType string      `json:"content_type,omitempty"` // "text" or "binary"; empty for older snapshots
+	Encoding    string      `json:"encoding,omitempty"`     // detected text encoding, e.g. "utf-8"
+	RawPath     []byte      `json:"raw_path,omitempty"`     // exact path bytes, recorded only when Path is not valid UTF-8
+
+	// Content is the file's text, kept in the manifest for small UTF-8 files when
+	// options.store_content is on, so diffs work after the file bytes are gone
+	Content *string `json:"content,omitempty"`
 }
 
 // SnapshotDiff represents changes between two snapshots
@@ -35,6 +146,22 @@ type SnapshotDiff struct {
 	Added    []string `json:"added"`
 	Removed  []string `json:"removed"`
 	Modified []string `json:"modified"`
+
+	// Renamed lists files whose name changed only in case or Unicode
+	// normalization and, with DiffOptions.Renames, files moved with their
+	// content unchanged. They appear here instead of in Added and Removed.
+	Renamed []PathRename `json:"renamed,omitempty"`
+}
+
+// PathRename is a file that changed name. Most keep their folded name, e.g.
+// Soul.md -> SOUL.md: a case-insensitive filesystem sees both names as the same
+// file, so applying the rename there needs an explicit rename. With
+// DiffOptions.Renames, a file moved with identical content is one too, e.g.
+// skills/analysis.js -> skills/analyzer.js.
+type PathRename struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Modified bool   `json:"modified,omitempty"` // the content changed too
 }
 
 // GenerateID generates a snapshot ID from a timestamp
@@ -52,8 +179,37 @@ func FromDirectory(path string, exclude []string, message string) (*Snapshot, er
 
 // FromDirectoryWithTimestamp creates a snapshot from a directory with a specific timestamp
 func FromDirectoryWithTimestamp(path string, exclude []string, message string, timestamp time.Time) (*Snapshot, error) {
+	return ScanDirectory(path, ScanOptions{Exclude: exclude}, message, timestamp)
+}
+
+// MetadataDirName is the directory bulletproof keeps its own metadata in. A
+// directory with this name is never scanned, so a source that overlaps a
+// destination, or holds a restored snapshot folder, never ingests bulletproof
+// metadata as agent files.
+const MetadataDirName = ".bulletproof"
+
+// ScanOptions controls which files a directory scan picks up
+type ScanOptions struct {
+	Include       []string // when set, only files matching one of these patterns are scanned
+	Exclude       []string // exclude patterns with .gitignore rules, applied after Include
+	ExcludeHidden bool     // skip files and directories whose name starts with "."
+	SkipPaths     []string // directories never scanned, e.g. a destination inside the source
+	ContentLimit  int64    // store the content of UTF-8 text files up to this size; 0 = off
+	MaxFileSize   int64    // leave out files larger than this, listing them in Oversized; 0 = no limit
+	// SkipUnreadable leaves out files and directories that cannot be read,
+	// listing them in Unreadable; otherwise the scan fails with a *ReadError
+	SkipUnreadable bool
+}
+
+// ScanDirectory creates a snapshot from a directory with a specific timestamp,
+// picking up files according to opts. The patterns of an IgnoreFileName file
+// in the directory or any folder below it are added to opts.Exclude.
+func ScanDirectory(path string, opts ScanOptions, message string, timestamp time.Time) (*Snapshot, error) {
 	id := GenerateID(timestamp)
 	files := make(map[string]*FileSnapshot)
+	var oversized []SkippedFile
+	var unreadable []UnreadableFile
+	exclude := newExcludeMatcher(opts.Exclude)
 
 	// Check if directory exists
 	info, err := os.Stat(path)
@@ -67,11 +223,24 @@ func FromDirectoryWithTimestamp(path string, exclude []string, message string, t
 	// Walk the directory tree
 	err = filepath.Walk(path, func(filePath string, fileInfo os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			if filePath == path {
+				return err
+			}
+			relativePath, relErr := filepath.Rel(path, filePath)
+			if relErr != nil {
+				return err
+			}
+			if !opts.SkipUnreadable {
+				return &ReadError{Path: relativePath, Err: err}
+			}
+			unreadable = append(unreadable, UnreadableFile{Path: relativePath, Error: err.Error()})
+			return nil
 		}
 
-		// Skip directories
-		if fileInfo.IsDir() {
+		if filePath != path && opts.skips(filePath, fileInfo) {
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -81,15 +250,41 @@ func FromDirectoryWithTimestamp(path string, exclude []string, message string, t
 			return fmt.Errorf("failed to get relative path: %w", err)
 		}
 
-		// Check exclusions
-		if shouldExclude(relativePath, exclude) {
+		// Skip directories, and everything below excluded ones. Others may
+		// hold an ignore file with more patterns for what is below them.
+		if fileInfo.IsDir() {
+			if filePath != path && exclude.excludesDir(relativePath) {
+				return filepath.SkipDir
+			}
+			if err := exclude.readIgnoreFile(filePath, relativePath); err != nil {
+				ignoreFile := filepath.Join(relativePath, IgnoreFileName)
+				if !opts.SkipUnreadable {
+					return &ReadError{Path: ignoreFile, Err: err}
+				}
+				unreadable = append(unreadable, UnreadableFile{Path: ignoreFile, Error: err.Error()})
+			}
+			return nil
+		}
+
+		// Check inclusions, then exclusions
+		if !shouldInclude(relativePath, opts.Include) || exclude.excludes(relativePath) {
+			return nil
+		}
+
+		// Oversized files are left out before they are read
+		if opts.MaxFileSize > 0 && fileInfo.Size() > opts.MaxFileSize {
+			oversized = append(oversized, SkippedFile{Path: relativePath, Size: fileInfo.Size()})
 			return nil
 		}
 
 		// Create file snapshot
-		fileSnapshot, err := fromFile(filePath, relativePath)
+		fileSnapshot, err := fromFile(filePath, relativePath, opts.ContentLimit)
 		if err != nil {
-			return fmt.Errorf("failed to snapshot file %s: %w", relativePath, err)
+			if !opts.SkipUnreadable {
+				return &ReadError{Path: relativePath, Err: err}
+			}
+			unreadable = append(unreadable, UnreadableFile{Path: relativePath, Error: err.Error()})
+			return nil
 		}
 
 		files[relativePath] = fileSnapshot
@@ -100,16 +295,59 @@ func FromDirectoryWithTimestamp(path string, exclude []string, message string, t
 		return nil, fmt.Errorf("failed to walk directory: %w", err)
 	}
 
-	return &Snapshot{
-		ID:        id,
-		Timestamp: timestamp,
-		Files:     files,
-		Message:   message,
-	}, nil
+	originalRoot, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve source path: %w", err)
+	}
+
+	snapshot := &Snapshot{
+		ID:           id,
+		Timestamp:    timestamp,
+		Files:        files,
+		Message:      message,
+		OriginalRoot: originalRoot,
+		Oversized:    oversized,
+		Unreadable:   unreadable,
+	}
+	snapshot.recordProvenance()
+	return snapshot, nil
+}
+
+// recordProvenance records the machine and version taking the snapshot. A
+// hostname that cannot be read is left empty.
+func (s *Snapshot) recordProvenance() {
+	s.Hostname, _ = os.Hostname()
+	s.OS = runtime.GOOS
+	s.CreatedBy = "bulletproof " + version.Version
+}
+
+// skips reports whether the scan leaves out a file or directory regardless of
+// the exclude patterns
+func (o ScanOptions) skips(filePath string, info os.FileInfo) bool {
+	name := info.Name()
+	if info.IsDir() && name == MetadataDirName {
+		return true
+	}
+	if o.ExcludeHidden && strings.HasPrefix(name, ".") {
+		return true
+	}
+	if len(o.SkipPaths) > 0 && info.IsDir() {
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return false
+		}
+		for _, skip := range o.SkipPaths {
+			if absSkip, err := filepath.Abs(skip); err == nil && absPath == absSkip {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-// fromFile creates a FileSnapshot from an actual file
-func fromFile(filePath string, relativePath string) (*FileSnapshot, error) {
+// fromFile creates a FileSnapshot from an actual file. UTF-8 text files of at
+// most contentLimit bytes also keep their content; 0 keeps none.
+func fromFile(filePath string, relativePath string, contentLimit int64) (*FileSnapshot, error) {
 	// Open file
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -117,12 +355,15 @@ func fromFile(filePath string, relativePath string) (*FileSnapshot, error) {
 	}
 	defer file.Close()
 
-	// Calculate SHA-256 hash
+	// Calculate SHA-256 hash, classifying the content from the same read
 	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	sniffer := &contentSniffer{}
+	kept := &limitedBuffer{limit: contentLimit}
+	if _, err := io.Copy(io.MultiWriter(hash, sniffer, kept), file); err != nil {
 		return nil, fmt.Errorf("failed to hash file: %w", err)
 	}
 	hashString := fmt.Sprintf("%x", hash.Sum(nil))
+	contentType, encoding := sniffer.classify()
 
 	// Get file info
 	fileInfo, err := file.Stat()
@@ -130,16 +371,81 @@ func fromFile(filePath string, relativePath string) (*FileSnapshot, error) {
 		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
 
-	return &FileSnapshot{
-		Path:     relativePath,
-		Hash:     hashString,
-		Size:     fileInfo.Size(),
-		Modified: fileInfo.ModTime(),
-	}, nil
+	fileSnapshot := &FileSnapshot{
+		Path:        relativePath,
+		Hash:        hashString,
+		Size:        fileInfo.Size(),
+		Modified:    fileInfo.ModTime(),
+		Mode:        fileInfo.Mode().Perm(),
+		ContentType: contentType,
+		Encoding:    encoding,
+	}
+	if contentLimit > 0 && !kept.overflow && fileSnapshot.IsRenderableText() {
+		content := kept.buf.String()
+		fileSnapshot.Content = &content
+	}
+	return fileSnapshot, nil
+}
+
+// limitedBuffer keeps what is written to it until more than limit bytes arrive,
+// then drops it all and only records the overflow
+type limitedBuffer struct {
+	limit    int64
+	buf      bytes.Buffer
+	overflow bool
 }
 
-// Diff calculates the difference between this snapshot and another
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 || b.overflow {
+		return len(p), nil
+	}
+	if int64(b.buf.Len()+len(p)) > b.limit {
+		b.overflow = true
+		b.buf = bytes.Buffer{}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// Diff calculates the changes going from other to this snapshot: Added files
+// exist only in s, Removed files only in other. Call it on the newer side, as
+// newer.Diff(older), so "+added" means present in the newer snapshot.
 func (s *Snapshot) Diff(other *Snapshot) *SnapshotDiff {
+	return s.DiffWith(other, DiffOptions{})
+}
+
+// DiffOptions chooses what makes a file present on both sides count as
+// modified. The zero value compares content hashes only, as Diff does.
+type DiffOptions struct {
+	// Mtime and Mode also count a changed modification time or permission
+	// bits, even when the content is identical
+	Mtime bool
+	Mode  bool
+	// SizeOnly compares file sizes instead of content hashes
+	SizeOnly bool
+	// Renames pairs a removed file with an added file of identical content
+	// as a rename instead of listing them as removed and added
+	Renames bool
+}
+
+// Modified reports whether a file changed between two snapshots under these
+// options. Metadata missing on either side, as in older snapshots, never counts.
+func (o DiffOptions) Modified(newer, older *FileSnapshot) bool {
+	if o.SizeOnly {
+		if newer.Size != older.Size {
+			return true
+		}
+	} else if newer.Hash != older.Hash {
+		return true
+	}
+	if o.Mtime && !newer.Modified.IsZero() && !older.Modified.IsZero() && !newer.Modified.Equal(older.Modified) {
+		return true
+	}
+	return o.Mode && newer.Mode != 0 && older.Mode != 0 && newer.Mode != older.Mode
+}
+
+// DiffWith is Diff with a choice of what counts as a modification
+func (s *Snapshot) DiffWith(other *Snapshot, opts DiffOptions) *SnapshotDiff {
 	diff := &SnapshotDiff{
 		From:     other.ID,
 		To:       s.ID,
@@ -152,7 +458,7 @@ func (s *Snapshot) Diff(other *Snapshot) *SnapshotDiff {
 	for path, file := range s.Files {
 		if otherFile, exists := other.Files[path]; !exists {
 			diff.Added = append(diff.Added, path)
-		} else if file.Hash != otherFile.Hash {
+		} else if opts.Modified(file, otherFile) {
 			diff.Modified = append(diff.Modified, path)
 		}
 	}
@@ -164,17 +470,176 @@ func (s *Snapshot) Diff(other *Snapshot) *SnapshotDiff {
 		}
 	}
 
+	diff.pairCaseRenames(s, other)
+	if opts.Renames {
+		diff.pairMovedFiles(s, other)
+	}
 	return diff
 }
 
+// pairMovedFiles moves an added and a removed path with the same content hash
+// into Renamed. Content shared by several added or removed files is ambiguous
+// and stays as it is.
+func (d *SnapshotDiff) pairMovedFiles(to, from *Snapshot) {
+	if len(d.Added) == 0 || len(d.Removed) == 0 {
+		return
+	}
+
+	byHash := func(paths []string, s *Snapshot) map[string][]string {
+		grouped := make(map[string][]string, len(paths))
+		for _, path := range paths {
+			hash := s.Files[path].Hash
+			grouped[hash] = append(grouped[hash], path)
+		}
+		return grouped
+	}
+	addedByHash, removedByHash := byHash(d.Added, to), byHash(d.Removed, from)
+
+	renamed := make(map[string]bool)
+	added := d.Added[:0]
+	for _, path := range d.Added {
+		hash := to.Files[path].Hash
+		if len(addedByHash[hash]) != 1 || len(removedByHash[hash]) != 1 {
+			added = append(added, path)
+			continue
+		}
+		oldPath := removedByHash[hash][0]
+		d.Renamed = append(d.Renamed, PathRename{From: oldPath, To: path})
+		renamed[oldPath] = true
+	}
+	d.Added = added
+
+	removed := d.Removed[:0]
+	for _, path := range d.Removed {
+		if !renamed[path] {
+			removed = append(removed, path)
+		}
+	}
+	d.Removed = removed
+
+	sort.Slice(d.Renamed, func(i, j int) bool { return d.Renamed[i].To < d.Renamed[j].To })
+}
+
+// pairCaseRenames moves an added and a removed path that fold to the same name
+// into Renamed. Folded names shared by several paths on a side are ambiguous and
+// stay as they are.
+func (d *SnapshotDiff) pairCaseRenames(to, from *Snapshot) {
+	if len(d.Added) == 0 || len(d.Removed) == 0 {
+		return
+	}
+
+	foldedCount := func(s *Snapshot) map[string]int {
+		counts := make(map[string]int, len(s.Files))
+		for path := range s.Files {
+			counts[FoldPath(path)]++
+		}
+		return counts
+	}
+	toCounts, fromCounts := foldedCount(to), foldedCount(from)
+
+	removedByFold := make(map[string]string, len(d.Removed))
+	for _, path := range d.Removed {
+		removedByFold[FoldPath(path)] = path
+	}
+
+	renamed := make(map[string]bool)
+	added := d.Added[:0]
+	for _, path := range d.Added {
+		folded := FoldPath(path)
+		oldPath, ok := removedByFold[folded]
+		if !ok || toCounts[folded] != 1 || fromCounts[folded] != 1 {
+			added = append(added, path)
+			continue
+		}
+		d.Renamed = append(d.Renamed, PathRename{
+			From:     oldPath,
+			To:       path,
+			Modified: to.Files[path].Hash != from.Files[oldPath].Hash,
+		})
+		renamed[oldPath] = true
+	}
+	d.Added = added
+
+	removed := d.Removed[:0]
+	for _, path := range d.Removed {
+		if !renamed[path] {
+			removed = append(removed, path)
+		}
+	}
+	d.Removed = removed
+
+	sort.Slice(d.Renamed, func(i, j int) bool { return d.Renamed[i].To < d.Renamed[j].To })
+}
+
+// TotalSize returns the combined size in bytes of the snapshot's files
+func (s *Snapshot) TotalSize() int64 {
+	var total int64
+	for _, file := range s.Files {
+		total += file.Size
+	}
+	return total
+}
+
+// Equal reports whether both snapshots hold the same files with the same content.
+// Unlike Diff(other).IsEmpty(), it allocates nothing and stops at the first difference.
+func (s *Snapshot) Equal(other *Snapshot) bool {
+	if s == other {
+		return true
+	}
+	if s == nil || other == nil || len(s.Files) != len(other.Files) {
+		return false
+	}
+
+	for path, file := range s.Files {
+		otherFile, exists := other.Files[path]
+		if !exists || file.Hash != otherFile.Hash {
+			return false
+		}
+	}
+	return true
+}
+
 // IsEmpty returns true if the diff has no changes
 func (d *SnapshotDiff) IsEmpty() bool {
-	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 && len(d.Renamed) == 0
+}
+
+// Filter returns the changes to files for which match is true. Renames are
+// kept when either name matches.
+func (d *SnapshotDiff) Filter(match func(path string) bool) *SnapshotDiff {
+	filtered := &SnapshotDiff{
+		From:     d.From,
+		To:       d.To,
+		Added:    []string{},
+		Removed:  []string{},
+		Modified: []string{},
+	}
+	for _, path := range d.Added {
+		if match(path) {
+			filtered.Added = append(filtered.Added, path)
+		}
+	}
+	for _, path := range d.Removed {
+		if match(path) {
+			filtered.Removed = append(filtered.Removed, path)
+		}
+	}
+	for _, path := range d.Modified {
+		if match(path) {
+			filtered.Modified = append(filtered.Modified, path)
+		}
+	}
+	for _, rename := range d.Renamed {
+		if match(rename.From) || match(rename.To) {
+			filtered.Renamed = append(filtered.Renamed, rename)
+		}
+	}
+	return filtered
 }
 
 // TotalChanges returns the total number of changes
 func (d *SnapshotDiff) TotalChanges() int {
-	return len(d.Added) + len(d.Removed) + len(d.Modified)
+	return len(d.Added) + len(d.Removed) + len(d.Modified) + len(d.Renamed)
 }
 
 // String returns a string representation of the diff
@@ -190,67 +655,169 @@ func (d *SnapshotDiff) String() string {
 	if len(d.Modified) > 0 {
 		parts = append(parts, fmt.Sprintf("~%d modified", len(d.Modified)))
 	}
+	if len(d.Renamed) > 0 {
+		parts = append(parts, fmt.Sprintf("%d renamed", len(d.Renamed)))
+	}
 	if len(d.Removed) > 0 {
 		parts = append(parts, fmt.Sprintf("-%d removed", len(d.Removed)))
 	}
 	return strings.Join(parts, ", ")
 }
 
-// PrintDetailed prints a detailed view of the diff
-func (d *SnapshotDiff) PrintDetailed() {
+// PrintDetailed prints a detailed view of the diff, from d.From to d.To, to w
+func (d *SnapshotDiff) PrintDetailed(w io.Writer) {
 	if d.IsEmpty() {
-		fmt.Println("No changes detected.")
+		fmt.Fprintln(w, "No changes detected.")
 		return
 	}
 
 	if len(d.Added) > 0 {
-		fmt.Println("\n  Added:")
+		fmt.Fprintln(w, "\n  Added:")
 		for _, f := range d.Added {
-			fmt.Printf("    + %s\n", f)
+			fmt.Fprintf(w, "    + %s\n", f)
 		}
 	}
 	if len(d.Modified) > 0 {
-		fmt.Println("\n  Modified:")
+		fmt.Fprintln(w, "\n  Modified:")
 		for _, f := range d.Modified {
-			fmt.Printf("    ~ %s\n", f)
+			fmt.Fprintf(w, "    ~ %s\n", f)
+		}
+	}
+	if len(d.Renamed) > 0 {
+		fmt.Fprintln(w, "\n  Renamed:")
+		for _, r := range d.Renamed {
+			fmt.Fprintf(w, "    ~ %s\n", r)
 		}
 	}
 	if len(d.Removed) > 0 {
-		fmt.Println("\n  Removed:")
+		fmt.Fprintln(w, "\n  Removed:")
 		for _, f := range d.Removed {
-			fmt.Printf("    - %s\n", f)
+			fmt.Fprintf(w, "    - %s\n", f)
 		}
 	}
 }
 
-// shouldExclude checks if a path should be excluded based on patterns
+// String renders the rename as "Soul.md -> SOUL.md", noting content changes
+func (r PathRename) String() string {
+	if r.Modified {
+		return fmt.Sprintf("%s -> %s (modified)", r.From, r.To)
+	}
+	return fmt.Sprintf("%s -> %s", r.From, r.To)
+}
+
+// shouldExclude checks if a path should be excluded based on patterns, with
+// .gitignore rules
 func shouldExclude(path string, patterns []string) bool {
+	return newExcludeMatcher(patterns).excludes(path)
+}
+
+// shouldInclude checks if a path is selected by include patterns; without
+// patterns every path is
+func shouldInclude(path string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
 	for _, pattern := range patterns {
-		if strings.HasSuffix(pattern, "/") {
-			// Directory pattern
-			if strings.HasPrefix(path, pattern) || strings.Contains(path, "/"+pattern) {
-				return true
-			}
-		} else if strings.HasPrefix(pattern, "*.") {
-			// Extension pattern
-			if strings.HasSuffix(path, pattern[1:]) {
+		if matchesPattern(path, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern checks a relative path against one include pattern or path
+// argument. Patterns match at any depth: "node_modules/" matches that directory
+// anywhere, and "skills/*.js" matches workspace/skills/a.js as well.
+func matchesPattern(relPath, pattern string) bool {
+	name := filepath.ToSlash(relPath)
+	if strings.HasSuffix(pattern, "/") {
+		// Directory pattern
+		return strings.HasPrefix(name, pattern) || strings.Contains(name, "/"+pattern)
+	} else if strings.HasPrefix(pattern, "*.") && !strings.Contains(pattern, "/") {
+		// Extension pattern
+		return strings.HasSuffix(name, pattern[1:])
+	} else if strings.Contains(pattern, "**") {
+		// Glob spanning directory levels
+		regex, err := globRegexp(pattern)
+		if err != nil {
+			return false
+		}
+		return regex.MatchString(name)
+	} else if strings.ContainsAny(pattern, "*?[") {
+		// Single-level glob, tried against the path and each of its tails
+		for tail := name; ; {
+			if matched, _ := path.Match(pattern, tail); matched {
 				return true
 			}
-		} else if strings.Contains(pattern, "**") {
-			// Glob pattern - simplified matching
-			regexPattern := strings.ReplaceAll(pattern, "**", ".*")
-			regexPattern = strings.ReplaceAll(regexPattern, "*", "[^/]*")
-			regex := regexp.MustCompile(regexPattern)
-			if regex.MatchString(path) {
-				return true
+			_, rest, ok := strings.Cut(tail, "/")
+			if !ok {
+				return false
 			}
-		} else if path == pattern || strings.HasSuffix(path, "/"+pattern) {
+			tail = rest
+		}
+	}
+	return name == pattern || strings.HasSuffix(name, "/"+pattern)
+}
+
+// ValidatePattern checks an include pattern or path argument, compiling it
+// the way matching does
+func ValidatePattern(pattern string) error {
+	if strings.Contains(pattern, "**") {
+		_, err := globRegexp(pattern)
+		return err
+	}
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// globRegexp compiles a pattern containing "**" into a regular expression for
+// whole relative paths, matching at any depth like the other patterns. "**/"
+// spans zero or more directories, any other "**" spans anything, and "*" and
+// "?" stay within one directory level. Everything else matches literally.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	const anyDirs, anything = "\x00", "\x01"
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*\*/`, anyDirs)
+	expr = strings.ReplaceAll(expr, `\*\*`, anything)
+	expr = strings.ReplaceAll(expr, `\*`, `[^/]*`)
+	expr = strings.ReplaceAll(expr, `\?`, `[^/]`)
+	expr = strings.ReplaceAll(expr, anyDirs, `(?:.*/)?`)
+	expr = strings.ReplaceAll(expr, anything, `.*`)
+	regex, err := regexp.Compile(`^(?:.*/)?` + expr + `$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return regex, nil
+}
+
+// MatchPath reports whether a relative file path is selected by pattern, a
+// relative path or a pattern as in options include. This is the syntax of
+// every command's path or pattern argument. A pattern that matches a folder
+// selects every file under it, so workspace/skills and workspace/skills/*
+// both select the whole subtree.
+func MatchPath(relPath, pattern string) bool {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+	for name := filepath.ToSlash(relPath); name != "." && name != "/"; name = path.Dir(name) {
+		if matchesPattern(name, pattern) {
 			return true
 		}
 	}
 	return false
 }
 
+// MatchPaths returns the sorted paths of the snapshot's files that match
+// pattern, as MatchPath does
+func (s *Snapshot) MatchPaths(pattern string) []string {
+	var matches []string
+	for relPath := range s.Files {
+		if MatchPath(relPath, pattern) {
+			matches = append(matches, relPath)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
 // String returns a string representation of the snapshot
 func (s *Snapshot) String() string {
 	return fmt.Sprintf("Snapshot(%s, %d files)", s.ID, len(s.Files))
@@ -271,10 +838,17 @@ func FromJSON(data []byte) (*Snapshot, error) {
 	return &snapshot, nil
 }
 
-// MergeWithSources combines multiple snapshots into a single snapshot
-// Each snapshot's files are prefixed with their source base name to avoid conflicts
-// For example, files from ~/.openclaw become ".openclaw/file.txt"
+// MergeWithSources combines multiple snapshots into a single snapshot.
+// Each snapshot's files are prefixed with the prefix SourceMappings assigns to its
+// source (usually the base name, so ~/.openclaw becomes ".openclaw/file.txt"), and
+// the mapping is recorded in the merged snapshot's Sources.
 func MergeWithSources(snapshots []*Snapshot, sourcePaths []string, message string, timestamp time.Time) (*Snapshot, error) {
+	return MergeWithPrefixes(snapshots, sourcePaths, nil, message, timestamp)
+}
+
+// MergeWithPrefixes is MergeWithSources with prefixes chosen for some sources,
+// as in SourceMappingsWithPrefixes
+func MergeWithPrefixes(snapshots []*Snapshot, sourcePaths []string, prefixes map[string]string, message string, timestamp time.Time) (*Snapshot, error) {
 	if len(snapshots) == 0 {
 		return nil, fmt.Errorf("no snapshots to merge")
 	}
@@ -282,30 +856,35 @@ func MergeWithSources(snapshots []*Snapshot, sourcePaths []string, message strin
 		return nil, fmt.Errorf("number of snapshots (%d) does not match number of source paths (%d)", len(snapshots), len(sourcePaths))
 	}
 
-	// Generate a single ID for the merged snapshot
-	id := GenerateID(timestamp)
+	bySource := make(map[string]*Snapshot, len(snapshots))
+	for i, snapshot := range snapshots {
+		bySource[filepath.Clean(sourcePaths[i])] = snapshot
+	}
 
 	merged := &Snapshot{
-		ID:        id,
+		ID:        GenerateID(timestamp),
 		Timestamp: timestamp,
 		Files:     make(map[string]*FileSnapshot),
 		Message:   message,
+		Sources:   SourceMappingsWithPrefixes(sourcePaths, prefixes),
 	}
-
-	// Merge all files from all snapshots
-	for i, snapshot := range snapshots {
-		// Use the base name of the source path as the prefix
-		sourceBase := filepath.Base(sourcePaths[i])
-
-		for relPath, fileSnapshot := range snapshot.Files {
-			// Prefix the path with source base name
-			prefixedPath := filepath.Join(sourceBase, relPath)
-			merged.Files[prefixedPath] = &FileSnapshot{
-				Path:     prefixedPath,
-				Hash:     fileSnapshot.Hash,
-				Size:     fileSnapshot.Size,
-				Modified: fileSnapshot.Modified,
-			}
+	merged.recordProvenance()
+
+	// Merge in source order so the result does not depend on configuration order
+	for _, source := range merged.Sources {
+		for relPath, fileSnapshot := range bySource[source.Path].Files {
+			prefixedPath := filepath.Join(source.Prefix, relPath)
+			prefixed := *fileSnapshot
+			prefixed.Path = prefixedPath
+			merged.Files[prefixedPath] = &prefixed
+		}
+		for _, skipped := range bySource[source.Path].Oversized {
+			skipped.Path = filepath.Join(source.Prefix, skipped.Path)
+			merged.Oversized = append(merged.Oversized, skipped)
+		}
+		for _, skipped := range bySource[source.Path].Unreadable {
+			skipped.Path = filepath.Join(source.Prefix, skipped.Path)
+			merged.Unreadable = append(merged.Unreadable, skipped)
 		}
 	}
 
@@ -1,6 +1,15 @@
 package types
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -122,6 +131,153 @@ func TestSnapshotDiff_RemovedFiles(t *testing.T) {
 	}
 }
 
+func TestSnapshotDiff_CaseRenames(t *testing.T) {
+	now := time.Now()
+
+	older := &Snapshot{
+		ID:        "20240101-120000",
+		Timestamp: now,
+		Files: map[string]*FileSnapshot{
+			"skills/Weather/SKILL.md": {Path: "skills/Weather/SKILL.md", Hash: "abc123"},
+			"SOUL.md":                 {Path: "SOUL.md", Hash: "def456"},
+			"notes.txt":               {Path: "notes.txt", Hash: "ghi789"},
+		},
+	}
+	newer := &Snapshot{
+		ID:        "20240101-130000",
+		Timestamp: now.Add(time.Hour),
+		Files: map[string]*FileSnapshot{
+			"skills/weather/SKILL.md": {Path: "skills/weather/SKILL.md", Hash: "abc123"},
+			"soul.md":                 {Path: "soul.md", Hash: "changed"},
+			"other.txt":               {Path: "other.txt", Hash: "ghi789"},
+		},
+	}
+
+	diff := newer.Diff(older)
+
+	want := []PathRename{
+		{From: "skills/Weather/SKILL.md", To: "skills/weather/SKILL.md"},
+		{From: "SOUL.md", To: "soul.md", Modified: true},
+	}
+	if !reflect.DeepEqual(diff.Renamed, want) {
+		t.Errorf("Renamed = %+v, want %+v", diff.Renamed, want)
+	}
+	if !reflect.DeepEqual(diff.Added, []string{"other.txt"}) {
+		t.Errorf("Added = %v, want [other.txt]", diff.Added)
+	}
+	if !reflect.DeepEqual(diff.Removed, []string{"notes.txt"}) {
+		t.Errorf("Removed = %v, want [notes.txt]", diff.Removed)
+	}
+	if diff.TotalChanges() != 4 {
+		t.Errorf("expected 4 total changes, got %d", diff.TotalChanges())
+	}
+	if got := want[1].String(); got != "SOUL.md -> soul.md (modified)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestSnapshotDiff_ContentRenames(t *testing.T) {
+	older := &Snapshot{Files: map[string]*FileSnapshot{
+		"skills/analysis.js": {Path: "skills/analysis.js", Hash: "abc123"},
+		"SOUL.md":            {Path: "SOUL.md", Hash: "def456"},
+		"a/LICENSE":          {Path: "a/LICENSE", Hash: "mit"},
+		"b/LICENSE":          {Path: "b/LICENSE", Hash: "mit"},
+	}}
+	newer := &Snapshot{Files: map[string]*FileSnapshot{
+		"skills/analyzer.js": {Path: "skills/analyzer.js", Hash: "abc123"},
+		"soul.md":            {Path: "soul.md", Hash: "changed"},
+		"c/LICENSE":          {Path: "c/LICENSE", Hash: "mit"},
+	}}
+
+	// Without the option only the case rename is paired
+	if diff := newer.Diff(older); len(diff.Renamed) != 1 || len(diff.Added) != 2 || len(diff.Removed) != 3 {
+		t.Errorf("expected Diff to pair only the case rename, got %+v", diff)
+	}
+
+	diff := newer.DiffWith(older, DiffOptions{Renames: true})
+	want := []PathRename{
+		{From: "skills/analysis.js", To: "skills/analyzer.js"},
+		{From: "SOUL.md", To: "soul.md", Modified: true},
+	}
+	if !reflect.DeepEqual(diff.Renamed, want) {
+		t.Errorf("Renamed = %+v, want %+v", diff.Renamed, want)
+	}
+	// Two removed copies of the license leave the move ambiguous
+	if !reflect.DeepEqual(diff.Added, []string{"c/LICENSE"}) {
+		t.Errorf("Added = %v, want [c/LICENSE]", diff.Added)
+	}
+	sort.Strings(diff.Removed)
+	if !reflect.DeepEqual(diff.Removed, []string{"a/LICENSE", "b/LICENSE"}) {
+		t.Errorf("Removed = %v, want [a/LICENSE b/LICENSE]", diff.Removed)
+	}
+	if got := diff.String(); !strings.Contains(got, "2 renamed") {
+		t.Errorf("String() = %q, want it to count 2 renames", got)
+	}
+}
+
+func TestSnapshotDiff_AmbiguousCaseRenames(t *testing.T) {
+	older := &Snapshot{Files: map[string]*FileSnapshot{
+		"Notes.md": {Path: "Notes.md", Hash: "a"},
+		"NOTES.md": {Path: "NOTES.md", Hash: "b"},
+	}}
+	newer := &Snapshot{Files: map[string]*FileSnapshot{
+		"notes.md": {Path: "notes.md", Hash: "a"},
+	}}
+
+	diff := newer.Diff(older)
+
+	// Two old spellings fold to the new one, so neither is paired
+	if len(diff.Renamed) != 0 {
+		t.Errorf("expected no renames, got %+v", diff.Renamed)
+	}
+	if len(diff.Added) != 1 || len(diff.Removed) != 2 {
+		t.Errorf("expected 1 added and 2 removed, got %v and %v", diff.Added, diff.Removed)
+	}
+}
+
+func TestSnapshotDiffWith(t *testing.T) {
+	now := time.Now()
+
+	older := &Snapshot{Files: map[string]*FileSnapshot{
+		"touched.md":   {Path: "touched.md", Hash: "a", Size: 10, Modified: now, Mode: 0644},
+		"chmod.sh":     {Path: "chmod.sh", Hash: "b", Size: 10, Modified: now, Mode: 0644},
+		"same-size.md": {Path: "same-size.md", Hash: "c", Size: 10, Modified: now, Mode: 0644},
+		"legacy.md":    {Path: "legacy.md", Hash: "d", Size: 10, Modified: now},
+	}}
+	newer := &Snapshot{Files: map[string]*FileSnapshot{
+		"touched.md":   {Path: "touched.md", Hash: "a", Size: 10, Modified: now.Add(time.Hour), Mode: 0644},
+		"chmod.sh":     {Path: "chmod.sh", Hash: "b", Size: 10, Modified: now, Mode: 0755},
+		"same-size.md": {Path: "same-size.md", Hash: "changed", Size: 10, Modified: now, Mode: 0644},
+		"legacy.md":    {Path: "legacy.md", Hash: "d", Size: 10, Modified: now, Mode: 0600},
+	}}
+
+	tests := []struct {
+		name string
+		opts DiffOptions
+		want []string
+	}{
+		{"content only", DiffOptions{}, []string{"same-size.md"}},
+		{"mtime", DiffOptions{Mtime: true}, []string{"same-size.md", "touched.md"}},
+		// legacy.md recorded no mode in the older snapshot, so it never counts
+		{"mode", DiffOptions{Mode: true}, []string{"chmod.sh", "same-size.md"}},
+		{"size only", DiffOptions{SizeOnly: true}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := newer.DiffWith(older, tt.opts)
+			sort.Strings(diff.Modified)
+			if !reflect.DeepEqual(diff.Modified, tt.want) {
+				t.Errorf("Modified = %v, want %v", diff.Modified, tt.want)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(newer.Diff(older), newer.DiffWith(older, DiffOptions{})) {
+		t.Error("Diff should compare content hashes only")
+	}
+}
+
 func TestGenerateID(t *testing.T) {
 	testTime := time.Date(2024, 1, 15, 14, 30, 45, 0, time.UTC)
 	id := GenerateID(testTime)
@@ -181,3 +337,505 @@ func TestShouldExclude(t *testing.T) {
 		})
 	}
 }
+
+func TestShouldInclude(t *testing.T) {
+	patterns := []string{"workspace/SOUL.md", "skills/*.js", "workspace/memory/"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"workspace/SOUL.md", true},
+		{"workspace/skills/weather.js", true},
+		{"skills/weather.js", true},
+		{"workspace/skills/weather.md", false},
+		{"workspace/skills/nested/tool.js", false},
+		{"workspace/memory/2026-01-01.md", true},
+		{"workspace/memory/archive/old.json", true},
+		{"workspace/AGENT.md", false},
+	}
+
+	for _, tt := range tests {
+		if got := shouldInclude(tt.path, patterns); got != tt.want {
+			t.Errorf("shouldInclude(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if !shouldInclude("anything.txt", nil) {
+		t.Error("expected every path to be included without patterns")
+	}
+}
+
+func TestMatchesPattern_DoubleStar(t *testing.T) {
+	tests := []struct {
+		path, pattern string
+		want          bool
+	}{
+		// Nested levels, including none
+		{"memory/a/b/c.json", "memory/**/*.json", true},
+		{"memory/a.json", "memory/**/*.json", true},
+		{"workspace/memory/a/b.json", "memory/**/*.json", true},
+		{"memory/a/b/c.md", "memory/**/*.json", false},
+		{"skills/a/b.js", "skills/**", true},
+		{"cache/x/y.json", "**/cache/*.json", false},
+		{"deep/cache/y.json", "**/cache/*.json", true},
+
+		// A directory name does not match as a prefix of another
+		{"xskills/a/b.js", "skills/**", false},
+		{"workspace/xskills/a.js", "skills/**", false},
+		{"memory/a.jsonl", "memory/**/*.json", false},
+
+		// Regular expression metacharacters match literally
+		{"a(b/c/d.txt", "a(b/**", true},
+		{"axb/c/d.txt", "a.b/**", false},
+		{"a.b/c/d.txt", "a.b/**", true},
+		{"a(/x", "a(**", true},
+		{"logs/2026+1/x.log", "logs/**/2026+1/*.log", true},
+	}
+	for _, tt := range tests {
+		if got := matchesPattern(tt.path, tt.pattern); got != tt.want {
+			t.Errorf("matchesPattern(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+		}
+	}
+
+	for _, pattern := range []string{"a(**", "memory/**/*.json", "skills/*.js"} {
+		if err := ValidatePattern(pattern); err != nil {
+			t.Errorf("ValidatePattern(%q) failed: %v", pattern, err)
+		}
+	}
+	if err := ValidatePattern("skills/[a"); err == nil {
+		t.Error("expected an unterminated character class to be invalid")
+	}
+}
+
+func TestScanDirectory_IncludeThenExclude(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{
+		"workspace/SOUL.md",
+		"workspace/AGENT.md",
+		"workspace/skills/weather.js",
+		"workspace/skills/weather.md",
+		"workspace/memory/today.md",
+		"workspace/memory/debug.log",
+	} {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := ScanDirectory(root, ScanOptions{
+		Include: []string{"workspace/SOUL.md", "skills/*.js", "workspace/memory/"},
+		Exclude: []string{"*.log"},
+	}, "", time.Now())
+	if err != nil {
+		t.Fatalf("ScanDirectory failed: %v", err)
+	}
+
+	var got []string
+	for path := range snapshot.Files {
+		got = append(got, filepath.ToSlash(path))
+	}
+	sort.Strings(got)
+	want := []string{"workspace/SOUL.md", "workspace/memory/today.md", "workspace/skills/weather.js"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanned %v, want %v", got, want)
+	}
+}
+
+func TestScanDirectory_MaxFileSize(t *testing.T) {
+	root := t.TempDir()
+	for path, size := range map[string]int{
+		"workspace/SOUL.md":           10,
+		"workspace/exactly.md":        100,
+		"workspace/checkpoints/model": 101,
+	} {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := ScanDirectory(root, ScanOptions{MaxFileSize: 100}, "", time.Now())
+	if err != nil {
+		t.Fatalf("ScanDirectory failed: %v", err)
+	}
+	if len(snapshot.Files) != 2 {
+		t.Errorf("expected the two files within the limit, got %d", len(snapshot.Files))
+	}
+	want := []SkippedFile{{Path: filepath.Join("workspace", "checkpoints", "model"), Size: 101}}
+	if !reflect.DeepEqual(snapshot.Oversized, want) {
+		t.Errorf("Oversized = %+v, want %+v", snapshot.Oversized, want)
+	}
+
+	// Multi-source snapshots prefix the skipped paths like the files
+	merged, err := MergeWithSources([]*Snapshot{snapshot}, []string{root}, "", time.Now())
+	if err != nil {
+		t.Fatalf("MergeWithSources failed: %v", err)
+	}
+	if len(merged.Oversized) != 1 || merged.Oversized[0].Path != filepath.Join(merged.Sources[0].Prefix, want[0].Path) {
+		t.Errorf("unexpected merged Oversized %+v", merged.Oversized)
+	}
+}
+
+func TestScanDirectory_SkipUnreadable(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "workspace"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "workspace", "SOUL.md"), []byte("soul"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A dangling link cannot be opened, even by root
+	broken := filepath.Join("workspace", "broken.md")
+	if err := os.Symlink(filepath.Join(root, "missing.md"), filepath.Join(root, broken)); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+
+	var readErr *ReadError
+	if _, err := ScanDirectory(root, ScanOptions{}, "", time.Now()); !errors.As(err, &readErr) || readErr.Path != broken {
+		t.Fatalf("expected a ReadError naming %s, got %v", broken, err)
+	}
+
+	snapshot, err := ScanDirectory(root, ScanOptions{SkipUnreadable: true}, "", time.Now())
+	if err != nil {
+		t.Fatalf("ScanDirectory failed: %v", err)
+	}
+	if len(snapshot.Files) != 1 {
+		t.Errorf("expected the readable file only, got %d files", len(snapshot.Files))
+	}
+	if len(snapshot.Unreadable) != 1 || snapshot.Unreadable[0].Path != broken || snapshot.Unreadable[0].Error == "" {
+		t.Errorf("unexpected Unreadable %+v", snapshot.Unreadable)
+	}
+}
+
+func TestScanDirectory_HiddenFilesAndMetadata(t *testing.T) {
+	root := t.TempDir()
+	destDir := filepath.Join(root, "backups")
+	for _, path := range []string{
+		"SOUL.md",
+		".env",
+		".vscode/settings.json",
+		"workspace/.notes.md",
+		"workspace/memory.json",
+		".bulletproof/snapshot.json",
+		"workspace/.bulletproof/config.yaml",
+		"backups/20260101-120000-000/SOUL.md",
+	} {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan := func(opts ScanOptions) []string {
+		t.Helper()
+		snapshot, err := ScanDirectory(root, opts, "", time.Now())
+		if err != nil {
+			t.Fatalf("ScanDirectory failed: %v", err)
+		}
+		var paths []string
+		for path := range snapshot.Files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		return paths
+	}
+
+	// Hidden files are included by default; bulletproof metadata never is
+	got := scan(ScanOptions{SkipPaths: []string{destDir}})
+	want := []string{".env", ".vscode/settings.json", "SOUL.md", "workspace/.notes.md", "workspace/memory.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("default scan = %v, want %v", got, want)
+	}
+
+	got = scan(ScanOptions{ExcludeHidden: true, SkipPaths: []string{destDir}})
+	want = []string{"SOUL.md", "workspace/memory.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scan without hidden files = %v, want %v", got, want)
+	}
+
+	// Without SkipPaths the destination's snapshot files would be ingested
+	got = scan(ScanOptions{ExcludeHidden: true})
+	if len(got) != 3 || got[0] != "SOUL.md" || got[1] != "backups/20260101-120000-000/SOUL.md" {
+		t.Errorf("scan without skip paths = %v", got)
+	}
+}
+
+func TestScanDirectory_StoresContent(t *testing.T) {
+	root := t.TempDir()
+	files := map[string][]byte{
+		"SOUL.md":    []byte("# Soul\nBe kind.\n"),
+		"big.md":     []byte(strings.Repeat("line\n", 100)),
+		"avatar.png": {0x89, 'P', 'N', 'G', 0x00, 0x01},
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(root, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := ScanDirectory(root, ScanOptions{ContentLimit: 64}, "", time.Now())
+	if err != nil {
+		t.Fatalf("ScanDirectory failed: %v", err)
+	}
+	if got := snapshot.Files["SOUL.md"].Content; got == nil || *got != "# Soul\nBe kind.\n" {
+		t.Errorf("expected SOUL.md content to be stored, got %v", got)
+	}
+	if snapshot.Files["big.md"].Content != nil {
+		t.Error("expected a file over the limit not to be stored")
+	}
+	if snapshot.Files["avatar.png"].Content != nil {
+		t.Error("expected a binary file not to be stored")
+	}
+	if snapshot.Files["big.md"].Hash == "" || snapshot.Files["big.md"].Size != 500 {
+		t.Errorf("expected big.md to still be hashed, got %+v", snapshot.Files["big.md"])
+	}
+
+	snapshot, err = ScanDirectory(root, ScanOptions{}, "", time.Now())
+	if err != nil {
+		t.Fatalf("ScanDirectory failed: %v", err)
+	}
+	for path, file := range snapshot.Files {
+		if file.Content != nil {
+			t.Errorf("expected no content without a limit, got %s", path)
+		}
+	}
+}
+
+func TestFileContent_PrefersStoredContent(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "SOUL.md"), []byte("on disk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stored := "stored\n"
+	snapshot := &Snapshot{ID: "1", Files: map[string]*FileSnapshot{
+		"SOUL.md":  {Path: "SOUL.md", Content: &stored},
+		"AGENT.md": {Path: "AGENT.md"},
+	}}
+
+	if got, err := fileContent(DirContentReader(dir), snapshot, "SOUL.md"); err != nil || got != stored {
+		t.Errorf("expected the stored content, got %q (%v)", got, err)
+	}
+	if _, err := fileContent(nil, snapshot, "AGENT.md"); err == nil {
+		t.Error("expected an error without stored content or a reader")
+	}
+	snapshot.Files["SOUL.md"].Content = nil
+	if got, err := fileContent(DirContentReader(dir), snapshot, "SOUL.md"); err != nil || got != "on disk\n" {
+		t.Errorf("expected the reader's content, got %q (%v)", got, err)
+	}
+}
+
+func TestFromDirectory_RecordsOriginalRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "SOUL.md"), []byte("soul"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A relative source path is recorded as absolute
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relative, err := filepath.Rel(wd, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := FromDirectoryWithTimestamp(relative, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+	}
+	if snapshot.OriginalRoot != root {
+		t.Errorf("OriginalRoot = %q, want %q", snapshot.OriginalRoot, root)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Snapshot
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.OriginalRoot != root {
+		t.Errorf("OriginalRoot not kept in the manifest: %s", data)
+	}
+}
+
+func TestFromDirectory_RecordsProvenance(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "SOUL.md"), []byte("soul"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := FromDirectoryWithTimestamp(root, nil, "", time.Now())
+	if err != nil {
+		t.Fatalf("FromDirectoryWithTimestamp failed: %v", err)
+	}
+	hostname, _ := os.Hostname()
+	if snapshot.Hostname != hostname || snapshot.OS != runtime.GOOS || !strings.HasPrefix(snapshot.CreatedBy, "bulletproof ") {
+		t.Errorf("unexpected provenance: host %q, OS %q, created by %q", snapshot.Hostname, snapshot.OS, snapshot.CreatedBy)
+	}
+
+	merged, err := MergeWithSources([]*Snapshot{snapshot}, []string{root}, "", time.Now())
+	if err != nil {
+		t.Fatalf("MergeWithSources failed: %v", err)
+	}
+	if merged.OS != runtime.GOOS {
+		t.Errorf("expected a multi-source snapshot to record its OS, got %q", merged.OS)
+	}
+}
+
+func TestSnapshotDiffStats(t *testing.T) {
+	diff := &SnapshotDiff{
+		To:       "20240101-130000-000",
+		Added:    []string{"workspace/skills/a.js", "workspace/skills/b.js"},
+		Removed:  []string{"notes.txt"},
+		Modified: []string{"workspace/SOUL.md", "workspace/memory/day1.json", "openclaw.json"},
+	}
+
+	stats := diff.Stats()
+	if stats.Total() != 6 || stats.SnapshotID != "20240101-130000-000" {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	expected := map[string]int{
+		"added skills":         2,
+		"removed other":        1,
+		"modified personality": 1,
+		"modified memory":      1,
+		"modified config":      1,
+	}
+	for category, count := range expected {
+		if stats.Categories[category] != count {
+			t.Errorf("expected %d %s, got %d", count, category, stats.Categories[category])
+		}
+	}
+}
+
+func TestSnapshotEqual(t *testing.T) {
+	now := time.Now()
+	files := func(hashes map[string]string) map[string]*FileSnapshot {
+		result := make(map[string]*FileSnapshot, len(hashes))
+		for path, hash := range hashes {
+			result[path] = &FileSnapshot{Path: path, Hash: hash, Modified: now}
+		}
+		return result
+	}
+
+	base := &Snapshot{ID: "20240101-120000", Files: files(map[string]string{"a.txt": "1", "b.txt": "2"})}
+
+	tests := []struct {
+		name  string
+		other *Snapshot
+		want  bool
+	}{
+		{"identical files, different ID", &Snapshot{ID: "20240101-130000", Files: files(map[string]string{"a.txt": "1", "b.txt": "2"})}, true},
+		{"modified file", &Snapshot{Files: files(map[string]string{"a.txt": "1", "b.txt": "changed"})}, false},
+		{"added file", &Snapshot{Files: files(map[string]string{"a.txt": "1", "b.txt": "2", "c.txt": "3"})}, false},
+		{"removed file", &Snapshot{Files: files(map[string]string{"a.txt": "1"})}, false},
+		{"renamed file", &Snapshot{Files: files(map[string]string{"a.txt": "1", "c.txt": "2"})}, false},
+		{"nil snapshot", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.Equal(tt.other); got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+			// Equal must agree with the diff it replaces
+			if tt.other != nil && base.Equal(tt.other) != base.Diff(tt.other).IsEmpty() {
+				t.Error("Equal() disagrees with Diff().IsEmpty()")
+			}
+		})
+	}
+}
+
+// largeSnapshotPair returns two identical snapshots of n files
+func largeSnapshotPair(n int) (*Snapshot, *Snapshot) {
+	a := &Snapshot{Files: make(map[string]*FileSnapshot, n)}
+	b := &Snapshot{Files: make(map[string]*FileSnapshot, n)}
+	for i := 0; i < n; i++ {
+		path := fmt.Sprintf("workspace/memory/%06d.md", i)
+		hash := fmt.Sprintf("%064x", i)
+		a.Files[path] = &FileSnapshot{Path: path, Hash: hash}
+		b.Files[path] = &FileSnapshot{Path: path, Hash: hash}
+	}
+	return a, b
+}
+
+func BenchmarkSnapshotDiffIsEmpty(b *testing.B) {
+	s1, s2 := largeSnapshotPair(50000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s1.Diff(s2).IsEmpty()
+	}
+}
+
+func BenchmarkSnapshotEqual(b *testing.B) {
+	s1, s2 := largeSnapshotPair(50000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s1.Equal(s2)
+	}
+}
+
+func TestSnapshot_MatchPaths(t *testing.T) {
+	snapshot := &Snapshot{Files: map[string]*FileSnapshot{
+		"SOUL.md":                           {},
+		"workspace/skills/a.js":             {},
+		"workspace/skills/weather/SKILL.md": {},
+		"workspace/skillset.md":             {},
+	}}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"SOUL.md", []string{"SOUL.md"}},
+		{"skills/*.js", []string{"workspace/skills/a.js"}},
+		{"workspace/skills/*", []string{"workspace/skills/a.js", "workspace/skills/weather/SKILL.md"}},
+		{"workspace/skills", []string{"workspace/skills/a.js", "workspace/skills/weather/SKILL.md"}},
+		{"./workspace/skills/", []string{"workspace/skills/a.js", "workspace/skills/weather/SKILL.md"}},
+		{"*.md", []string{"SOUL.md", "workspace/skills/weather/SKILL.md", "workspace/skillset.md"}},
+		{"skills/*.py", nil},
+	}
+	for _, tt := range tests {
+		if got := snapshot.MatchPaths(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MatchPaths(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		pattern string
+		want    bool
+	}{
+		{"workspace/SOUL.md", "workspace/SOUL.md", true},
+		{"workspace/SOUL.md", "SOUL.md", true},
+		{"workspace/SOUL.md", "S?UL.md", true},
+		{"workspace/SOUL.md", "*.md", true},
+		{"workspace/skills/a.js", "skills/*.js", true},
+		{"workspace/skills/weather/a.js", "workspace/skills", true},
+		{"workspace/skills/weather/a.js", "./workspace/skills/", true},
+		{"workspace/skills/weather/a.js", "**/weather/*.js", true},
+		{"workspace/skillset.md", "workspace/skills", false},
+		{"workspace/SOUL.md.bak", "SOUL.md", false},
+	}
+	for _, tt := range tests {
+		if got := MatchPath(tt.path, tt.pattern); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SourceMapping records which directory prefix in a merged snapshot holds which source
+type SourceMapping struct {
+	Prefix string `json:"prefix"`
+	Path   string `json:"path"`
+}
+
+// SourceMappings assigns each source path a stable, collision-free prefix.
+// Sources are ordered by path and duplicates are dropped, so the same set of
+// sources always yields the same mappings regardless of configuration order.
+// A source keeps its sanitized base name when that is unique; sources sharing a
+// base name are named after their full path instead (e.g. "home_alice_data").
+func SourceMappings(sourcePaths []string) []SourceMapping {
+	return SourceMappingsWithPrefixes(sourcePaths, nil)
+}
+
+// SourceMappingsWithPrefixes is SourceMappings with prefixes chosen for some
+// sources, keyed by cleaned path. Those are used as given, and the other
+// sources are named around them as if the chosen prefixes were base names.
+func SourceMappingsWithPrefixes(sourcePaths []string, prefixes map[string]string) []SourceMapping {
+	paths := make([]string, 0, len(sourcePaths))
+	seen := make(map[string]bool)
+	for _, p := range sourcePaths {
+		p = filepath.Clean(p)
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	baseCount := make(map[string]int)
+	used := make(map[string]bool)
+	for _, p := range paths {
+		if chosen := prefixes[p]; chosen != "" {
+			baseCount[chosen]++
+			used[chosen] = true
+		} else {
+			baseCount[sanitizePrefix(filepath.Base(p))]++
+		}
+	}
+
+	mappings := make([]SourceMapping, len(paths))
+	for i, p := range paths {
+		if chosen := prefixes[p]; chosen != "" {
+			mappings[i] = SourceMapping{Prefix: chosen, Path: p}
+			continue
+		}
+		prefix := sanitizePrefix(filepath.Base(p))
+		if baseCount[prefix] > 1 {
+			prefix = sanitizePrefix(strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(p, filepath.VolumeName(p))), "/"))
+		}
+		// Sanitizing can still map distinct paths to the same name ("a b" and "a_b")
+		for candidate, n := prefix, 2; ; n++ {
+			if !used[candidate] {
+				prefix = candidate
+				break
+			}
+			candidate = fmt.Sprintf("%s-%d", prefix, n)
+		}
+		used[prefix] = true
+		mappings[i] = SourceMapping{Prefix: prefix, Path: p}
+	}
+
+	return mappings
+}
+
+// IsBaseName reports whether the source is stored under its own base name,
+// rather than a name made unique from its full path
+func (m SourceMapping) IsBaseName() bool {
+	return m.Prefix == sanitizePrefix(filepath.Base(m.Path))
+}
+
+// sanitizePrefix turns a path into a single directory name using only
+// letters, digits, '.', '-' and '_'
+func sanitizePrefix(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+
+	prefix := sb.String()
+	if strings.Trim(prefix, "._") == "" {
+		return "source"
+	}
+	return prefix
+}
@@ -0,0 +1,96 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSourceMappings_UniqueBaseNames(t *testing.T) {
+	mappings := SourceMappings([]string{"/home/alice/.openclaw", "/data/graph exports"})
+
+	expected := []SourceMapping{
+		{Prefix: "graph_exports", Path: "/data/graph exports"},
+		{Prefix: ".openclaw", Path: "/home/alice/.openclaw"},
+	}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Errorf("SourceMappings() = %+v, want %+v", mappings, expected)
+	}
+}
+
+func TestSourceMappings_SharedBaseNames(t *testing.T) {
+	mappings := SourceMappings([]string{"/srv/b/data", "/srv/a/data", "/srv/notes"})
+
+	expected := []SourceMapping{
+		{Prefix: "srv_a_data", Path: "/srv/a/data"},
+		{Prefix: "srv_b_data", Path: "/srv/b/data"},
+		{Prefix: "notes", Path: "/srv/notes"},
+	}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Errorf("SourceMappings() = %+v, want %+v", mappings, expected)
+	}
+}
+
+func TestSourceMappings_SanitizedCollision(t *testing.T) {
+	mappings := SourceMappings([]string{"/x/a b", "/x/a_b"})
+
+	if mappings[0].Prefix == mappings[1].Prefix {
+		t.Errorf("expected distinct prefixes, got %+v", mappings)
+	}
+}
+
+func TestSourceMappings_DeterministicAndDeduplicated(t *testing.T) {
+	first := SourceMappings([]string{"/a/one", "/b/two", "/a/one/"})
+	second := SourceMappings([]string{"/b/two", "/a/one"})
+
+	if len(first) != 2 {
+		t.Fatalf("expected duplicates to be dropped, got %+v", first)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("mappings depend on order: %+v vs %+v", first, second)
+	}
+}
+
+func TestSourceMappingsWithPrefixes(t *testing.T) {
+	mappings := SourceMappingsWithPrefixes(
+		[]string{"/home/alice/.openclaw", "/mnt/laptop/.openclaw", "/srv/main"},
+		map[string]string{"/mnt/laptop/.openclaw": "laptop", "/srv/main": "main"},
+	)
+
+	// The only unprefixed .openclaw keeps its base name
+	expected := []SourceMapping{
+		{Prefix: ".openclaw", Path: "/home/alice/.openclaw"},
+		{Prefix: "laptop", Path: "/mnt/laptop/.openclaw"},
+		{Prefix: "main", Path: "/srv/main"},
+	}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Errorf("SourceMappingsWithPrefixes() = %+v, want %+v", mappings, expected)
+	}
+
+	// A source whose base name was chosen for another is named after its path
+	mappings = SourceMappingsWithPrefixes([]string{"/a/main", "/b/other"}, map[string]string{"/b/other": "main"})
+	if mappings[0].Prefix != "a_main" || mappings[0].IsBaseName() || mappings[1].Prefix != "main" {
+		t.Errorf("expected a_main and main, got %+v", mappings)
+	}
+}
+
+func TestMergeWithSources_RecordsMapping(t *testing.T) {
+	now := time.Now()
+	snapA := &Snapshot{Files: map[string]*FileSnapshot{"f.txt": {Path: "f.txt", Hash: "a"}}}
+	snapB := &Snapshot{Files: map[string]*FileSnapshot{"f.txt": {Path: "f.txt", Hash: "b"}}}
+
+	merged, err := MergeWithSources([]*Snapshot{snapB, snapA}, []string{"/srv/b/data", "/srv/a/data"}, "", now)
+	if err != nil {
+		t.Fatalf("MergeWithSources failed: %v", err)
+	}
+
+	if len(merged.Sources) != 2 || merged.Sources[0].Prefix != "srv_a_data" {
+		t.Errorf("unexpected sources: %+v", merged.Sources)
+	}
+	if f := merged.Files["srv_a_data/f.txt"]; f == nil || f.Hash != "a" {
+		t.Errorf("expected source a's file under its prefix, got %+v", f)
+	}
+	if f := merged.Files["srv_b_data/f.txt"]; f == nil || f.Hash != "b" {
+		t.Errorf("expected source b's file under its prefix, got %+v", f)
+	}
+}
@@ -1,37 +1,44 @@
 package types
 
 import (
-	"bufio"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 	"unicode/utf8"
 )
 
-// PrintUnified prints the diff in git-style unified format
-// This requires the actual snapshot objects to read file contents
-func (d *SnapshotDiff) PrintUnified(from, to *Snapshot) {
-	if d.IsEmpty() {
-		fmt.Println("No changes detected.")
-		return
-	}
+// DestinationContentReader reads the stored bytes of a file in a snapshot, for
+// destinations that can do so without restoring the whole snapshot
+type DestinationContentReader interface {
+	ReadSnapshotFile(snapshotID, path string) ([]byte, error)
+}
 
-	// Print added files
-	for _, path := range d.Added {
-		printAddedFile(path, to)
-	}
+// DirContentReader reads files from a folder holding one snapshot's files,
+// such as the live agent folder, whatever snapshot ID is asked for
+type DirContentReader string
 
-	// Print removed files
-	for _, path := range d.Removed {
-		printRemovedFile(path, from)
-	}
+// ReadSnapshotFile reads path from the folder
+func (r DirContentReader) ReadSnapshotFile(_ string, path string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(r), path))
+}
 
-	// Print modified files with line-by-line diff
-	for _, path := range d.Modified {
-		printModifiedFile(path, from, to)
+// PrintUnified prints the diff in git-style unified format. Modified files show
+// a line diff when both snapshots stored their content, and metadata otherwise.
+func (d *SnapshotDiff) PrintUnified(from, to *Snapshot) {
+	d.PrintUnifiedWithReaders(nil, nil, from, to)
+}
+
+// printRenamedFile prints a rename as git does, without content
+func printRenamedFile(rename PathRename) {
+	fmt.Printf("diff --git a/%s b/%s\n", rename.From, rename.To)
+	if !rename.Modified {
+		fmt.Println("similarity index 100%")
 	}
+	fmt.Printf("rename from %s\n", rename.From)
+	fmt.Printf("rename to %s\n", rename.To)
 }
 
 // printAddedFile prints a file that was added (all lines are new)
@@ -50,7 +57,7 @@ func printAddedFile(path string, to *Snapshot) {
 	// Since we only store hashes, we can't show actual content
 	// Show placeholder indicating file was added
 	fmt.Printf("@@ -0,0 +1,1 @@\n")
-	fmt.Printf("+[File added: %s, %d bytes]\n", path, fileSnapshot.Size)
+	fmt.Printf("+[%s added: %s, %d bytes]\n", fileKind(fileSnapshot), path, fileSnapshot.Size)
 }
 
 // printRemovedFile prints a file that was removed
@@ -66,11 +73,15 @@ func printRemovedFile(path string, from *Snapshot) {
 	}
 
 	fmt.Printf("@@ -1,1 +0,0 @@\n")
-	fmt.Printf("-[File removed: %s, %d bytes]\n", path, fileSnapshot.Size)
+	fmt.Printf("-[%s removed: %s, %d bytes]\n", fileKind(fileSnapshot), path, fileSnapshot.Size)
 }
 
 // printModifiedFile prints a unified diff for a modified file
 func printModifiedFile(path string, from, to *Snapshot) {
+	if printMetadataChange(path, from, to) {
+		return
+	}
+
 	fmt.Printf("diff --git a/%s b/%s\n", path, path)
 	fmt.Printf("--- a/%s\n", path)
 	fmt.Printf("+++ b/%s\n", path)
@@ -82,6 +93,11 @@ func printModifiedFile(path string, from, to *Snapshot) {
 		return
 	}
 
+	if fromFile.IsBinary() || toFile.IsBinary() {
+		fmt.Println("Binary files differ")
+		return
+	}
+
 	// Show hash change as a simple diff
 	// Since we don't store file contents in memory, show metadata
 	fmt.Printf("@@ -1,3 +1,3 @@\n")
@@ -95,6 +111,13 @@ func printModifiedFile(path string, from, to *Snapshot) {
 // PrintUnifiedWithContent prints unified diff with actual file content
 // This version reads file contents from the filesystem paths
 func (d *SnapshotDiff) PrintUnifiedWithContent(fromPath, toPath string, from, to *Snapshot) {
+	d.PrintUnifiedWithReaders(DirContentReader(fromPath), DirContentReader(toPath), from, to)
+}
+
+// PrintUnifiedWithReaders prints the diff with line diffs of modified text
+// files. Content stored in the manifest is used first, then the reader of each
+// side, which may be nil; files whose content is unavailable show metadata.
+func (d *SnapshotDiff) PrintUnifiedWithReaders(fromReader, toReader DestinationContentReader, from, to *Snapshot) {
 	if d.IsEmpty() {
 		fmt.Println("No changes detected.")
 		return
@@ -102,7 +125,10 @@ func (d *SnapshotDiff) PrintUnifiedWithContent(fromPath, toPath string, from, to
 
 	// Print modified files with actual content
 	for _, path := range d.Modified {
-		if err := printFileContentDiff(path, fromPath, toPath, from, to); err != nil {
+		if printMetadataChange(path, from, to) {
+			continue
+		}
+		if err := printFileContentDiff(path, fromReader, toReader, from, to); err != nil {
 			// Fall back to metadata-only diff on error
 			printModifiedFile(path, from, to)
 		}
@@ -117,49 +143,171 @@ func (d *SnapshotDiff) PrintUnifiedWithContent(fromPath, toPath string, from, to
 	for _, path := range d.Removed {
 		printRemovedFile(path, from)
 	}
+
+	for _, rename := range d.Renamed {
+		printRenamedFile(rename)
+	}
 }
 
 // printFileContentDiff prints a unified diff with actual file contents
-func printFileContentDiff(relPath, fromPath, toPath string, from, to *Snapshot) error {
+func printFileContentDiff(relPath string, fromReader, toReader DestinationContentReader, from, to *Snapshot) error {
+	// Decide from recorded metadata first so binary files are never read
+	if fromFile, toFile := from.Files[relPath], to.Files[relPath]; fromFile != nil && toFile != nil {
+		if fromFile.IsBinary() || toFile.IsBinary() {
+			printBinaryDiff(relPath)
+			return nil
+		}
+		if fromFile.ContentType != "" && toFile.ContentType != "" && (!fromFile.IsRenderableText() || !toFile.IsRenderableText()) {
+			fmt.Printf("diff --git a/%s b/%s\n", relPath, relPath)
+			fmt.Printf("--- a/%s\n", relPath)
+			fmt.Printf("+++ b/%s\n", relPath)
+			fmt.Printf("Text files differ (encoding %s -> %s)\n", fromFile.Encoding, toFile.Encoding)
+			return nil
+		}
+	}
+
+	diff, err := loadContentDiff(relPath, relPath, fromReader, toReader, from, to)
+	if err != nil {
+		return err
+	}
+	printContentDiff(relPath, diff)
+	return nil
+}
+
+// loadContentDiff returns the line diff from fromPath in from to toPath in to,
+// reading the contents through the readers of each side
+func loadContentDiff(fromPath, toPath string, fromReader, toReader DestinationContentReader, from, to *Snapshot) (contentDiff, error) {
+	// A pair of contents diffed before is neither read nor diffed again
+	var key [2]string
+	if fromFile, toFile := from.Files[fromPath], to.Files[toPath]; fromFile != nil && toFile != nil && fromFile.Hash != "" && toFile.Hash != "" {
+		key = [2]string{fromFile.Hash, toFile.Hash}
+		if diff, ok := cachedContentDiff(key); ok {
+			return diff, nil
+		}
+	}
+
 	// Read file contents
-	fromContent, err := readFileContent(filepath.Join(fromPath, relPath))
+	fromContent, err := fileContent(fromReader, from, fromPath)
 	if err != nil {
-		return fmt.Errorf("failed to read from file: %w", err)
+		return contentDiff{}, fmt.Errorf("failed to read from file: %w", err)
 	}
 
-	toContent, err := readFileContent(filepath.Join(toPath, relPath))
+	toContent, err := fileContent(toReader, to, toPath)
 	if err != nil {
-		return fmt.Errorf("failed to read to file: %w", err)
+		return contentDiff{}, fmt.Errorf("failed to read to file: %w", err)
 	}
 
-	// Check if files are binary
-	if isBinary(fromContent) || isBinary(toContent) {
-		fmt.Printf("diff --git a/%s b/%s\n", relPath, relPath)
-		fmt.Printf("--- a/%s\n", relPath)
-		fmt.Printf("+++ b/%s\n", relPath)
-		fmt.Println("Binary files differ")
-		return nil
+	// Older snapshots have no recorded content type, so inspect the content
+	diff := contentDiff{binary: isBinary(fromContent) || isBinary(toContent)}
+	if !diff.binary {
+		diff.hunks = strings.Join(generateHunks(splitLines(fromContent), splitLines(toContent)), "")
+	}
+	if key[0] != "" {
+		cacheContentDiff(key, diff)
 	}
+	return diff, nil
+}
 
-	// Generate and print unified diff
-	diff := generateUnifiedDiff(fromContent, toContent, relPath)
-	fmt.Print(diff)
-	return nil
+// contentDiff is the line diff of two file contents, apart from the header
+// naming the file
+type contentDiff struct {
+	binary bool
+	hunks  string
 }
 
-// readFileContent reads file content as a string
-func readFileContent(path string) (string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return "", err
+// maxContentDiffCache bounds the diffs kept in contentDiffCache, since serve
+// runs in one process for as long as the daemon is up
+const maxContentDiffCache = 1024
+
+var (
+	contentDiffMu sync.Mutex
+	// contentDiffCache holds the line diffs already computed, by the hashes of
+	// the two contents. Bisect diffs the same file against the same good
+	// snapshot again and again; hashes identify content, so an entry is never
+	// out of date.
+	contentDiffCache = map[[2]string]contentDiff{}
+)
+
+// cachedContentDiff returns the diff computed earlier for the contents with
+// the hashes in key
+func cachedContentDiff(key [2]string) (contentDiff, bool) {
+	contentDiffMu.Lock()
+	defer contentDiffMu.Unlock()
+	diff, ok := contentDiffCache[key]
+	return diff, ok
+}
+
+// cacheContentDiff keeps diff for the contents with the hashes in key,
+// starting over once the cache is full
+func cacheContentDiff(key [2]string, diff contentDiff) {
+	contentDiffMu.Lock()
+	defer contentDiffMu.Unlock()
+	if len(contentDiffCache) >= maxContentDiffCache {
+		clear(contentDiffCache)
+	}
+	contentDiffCache[key] = diff
+}
+
+// printContentDiff prints diff as a unified diff of relPath
+func printContentDiff(relPath string, diff contentDiff) {
+	if diff.binary {
+		printBinaryDiff(relPath)
+		return
+	}
+	fmt.Print(unifiedDiffHeader(relPath) + diff.hunks)
+}
+
+// printMetadataChange prints a file whose content is unchanged but whose mode or
+// modification time differs, git-style for modes. It reports false when the
+// content changed, leaving the file to the content diff.
+func printMetadataChange(path string, from, to *Snapshot) bool {
+	fromFile, toFile := from.Files[path], to.Files[path]
+	if fromFile == nil || toFile == nil || fromFile.Hash != toFile.Hash {
+		return false
+	}
+
+	fmt.Printf("diff --git a/%s b/%s\n", path, path)
+	if fromFile.Mode != 0 && toFile.Mode != 0 && fromFile.Mode != toFile.Mode {
+		fmt.Printf("old mode %o\n", 0100000|fromFile.Mode)
+		fmt.Printf("new mode %o\n", 0100000|toFile.Mode)
+	}
+	if !fromFile.Modified.Equal(toFile.Modified) {
+		fmt.Printf("old mtime %s\n", fromFile.Modified.Format(time.RFC3339Nano))
+		fmt.Printf("new mtime %s\n", toFile.Modified.Format(time.RFC3339Nano))
+	}
+	return true
+}
+
+// printBinaryDiff prints the header and marker for a binary file change
+func printBinaryDiff(relPath string) {
+	fmt.Printf("diff --git a/%s b/%s\n", relPath, relPath)
+	fmt.Printf("--- a/%s\n", relPath)
+	fmt.Printf("+++ b/%s\n", relPath)
+	fmt.Println("Binary files differ")
+}
+
+// fileKind returns the label used for added/removed file placeholders
+func fileKind(f *FileSnapshot) string {
+	if f.IsBinary() {
+		return "Binary file"
 	}
-	defer file.Close()
+	return "File"
+}
 
-	content, err := io.ReadAll(file)
+// fileContent returns a file's content in snapshot: the copy stored in the
+// manifest, or else what reader holds for it
+func fileContent(reader DestinationContentReader, snapshot *Snapshot, path string) (string, error) {
+	if file := snapshot.Files[path]; file != nil && file.Content != nil {
+		return *file.Content, nil
+	}
+	if reader == nil {
+		return "", fmt.Errorf("content of %s is not stored", path)
+	}
+
+	content, err := reader.ReadSnapshotFile(snapshot.ID, path)
 	if err != nil {
 		return "", err
 	}
-
 	return string(content), nil
 }
 
@@ -174,128 +322,270 @@ func isBinary(content string) bool {
 	return !utf8.ValidString(content)
 }
 
+// diffContextLines is how many unchanged lines surround each change, as in git
+const diffContextLines = 3
+
 // generateUnifiedDiff generates a proper unified diff between two text contents
 func generateUnifiedDiff(fromContent, toContent, path string) string {
 	fromLines := splitLines(fromContent)
 	toLines := splitLines(toContent)
 
 	var result strings.Builder
-	result.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", path, path))
-	result.WriteString(fmt.Sprintf("--- a/%s\n", path))
-	result.WriteString(fmt.Sprintf("+++ b/%s\n", path))
-
-	// Generate hunks using simple line-by-line comparison
-	hunks := generateHunks(fromLines, toLines)
+	result.WriteString(unifiedDiffHeader(path))
 
-	for _, hunk := range hunks {
+	for _, hunk := range generateHunks(fromLines, toLines) {
 		result.WriteString(hunk)
 	}
 
 	return result.String()
 }
 
-// generateHunks generates unified diff hunks with context
+// unifiedDiffHeader returns the lines naming path above its hunks
+func unifiedDiffHeader(path string) string {
+	return fmt.Sprintf("diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", path, path, path, path)
+}
+
+// diffOp is one line of an edit script: kept (' '), deleted from the old
+// content ('-') or inserted from the new content ('+')
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// generateHunks generates unified diff hunks with context from a shortest edit
+// script. Changes separated by no more than twice the context are joined into
+// one hunk, as git does.
 func generateHunks(fromLines, toLines []string) []string {
-	const contextLines = 3 // Number of context lines to show
+	ops := diffLines(fromLines, toLines)
 
 	var hunks []string
-	var currentHunk []string
-	var hunkFromStart, hunkToStart int
-	var hunkFromCount, hunkToCount int
-
-	// Simple algorithm: compare line by line
-	i, j := 0, 0
-	inHunk := false
-	contextAfter := 0
-
-	for i < len(fromLines) || j < len(toLines) {
-		if i < len(fromLines) && j < len(toLines) && fromLines[i] == toLines[j] {
-			// Lines match - add as context
-			if inHunk {
-				currentHunk = append(currentHunk, " "+fromLines[i])
-				hunkFromCount++
-				hunkToCount++
-				contextAfter++
-
-				// End hunk if we have enough context
-				if contextAfter >= contextLines {
-					hunks = append(hunks, formatHunk(hunkFromStart, hunkFromCount, hunkToStart, hunkToCount, currentHunk))
-					currentHunk = nil
-					inHunk = false
-					contextAfter = 0
-				}
-			}
+	fromLine, toLine := 0, 0 // lines of each side before ops[i]
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			fromLine++
+			toLine++
 			i++
-			j++
-		} else {
-			// Lines differ - start or continue hunk
-			if !inHunk {
-				// Start new hunk with context
-				hunkFromStart = max(0, i-contextLines) + 1
-				hunkToStart = max(0, j-contextLines) + 1
-				hunkFromCount = 0
-				hunkToCount = 0
-				currentHunk = nil
-
-				// Add leading context
-				for k := max(0, i-contextLines); k < i && k < len(fromLines); k++ {
-					currentHunk = append(currentHunk, " "+fromLines[k])
-					hunkFromCount++
-					hunkToCount++
-				}
+			continue
+		}
 
-				inHunk = true
-				contextAfter = 0
+		// Find where the hunk ends: after the last change followed by less
+		// than two contexts' worth of unchanged lines
+		end := i
+		for j := i; j < len(ops); {
+			if ops[j].kind != ' ' {
+				end = j + 1
+				j++
+				continue
+			}
+			k := j
+			for k < len(ops) && ops[k].kind == ' ' {
+				k++
+			}
+			if k == len(ops) || k-j > 2*diffContextLines {
+				break
 			}
+			j = k
+		}
+
+		start := max(0, i-diffContextLines)
+		stop := min(len(ops), end+diffContextLines)
+		// The leading context was counted as unchanged lines before the change
+		fromStart, toStart := fromLine-(i-start), toLine-(i-start)
 
-			// Add changed lines
-			if i < len(fromLines) && (j >= len(toLines) || fromLines[i] != toLines[j]) {
-				currentHunk = append(currentHunk, "-"+fromLines[i])
-				hunkFromCount++
-				i++
-				contextAfter = 0
+		var lines []string
+		fromCount, toCount := 0, 0
+		for _, op := range ops[start:stop] {
+			if op.kind != '+' {
+				fromCount++
 			}
-			if j < len(toLines) && (i >= len(fromLines) || (i > 0 && fromLines[i-1] != toLines[j])) {
-				currentHunk = append(currentHunk, "+"+toLines[j])
-				hunkToCount++
-				j++
-				contextAfter = 0
+			if op.kind != '-' {
+				toCount++
 			}
+			lines = append(lines, formatDiffLine(op))
 		}
-	}
+		hunks = append(hunks, formatHunk(fromStart, fromCount, toStart, toCount, lines))
 
-	// Finalize last hunk
-	if inHunk {
-		hunks = append(hunks, formatHunk(hunkFromStart, hunkFromCount, hunkToStart, hunkToCount, currentHunk))
+		// The trailing context may lead into the next change, so count it and
+		// carry on from the end of the hunk
+		for _, op := range ops[i:stop] {
+			if op.kind != '+' {
+				fromLine++
+			}
+			if op.kind != '-' {
+				toLine++
+			}
+		}
+		i = stop
 	}
 
 	return hunks
 }
 
-// formatHunk formats a single hunk with header
+// diffLines returns a shortest edit script turning a into b, found with Myers'
+// O(ND) algorithm. Within a change, deleted lines come before inserted ones.
+func diffLines(a, b []string) []diffOp {
+	// Lines shared at both ends are kept as they are, leaving the search only
+	// the part that changed
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return groupDeletionsFirst(ops)
+}
+
+// myersDiff finds a shortest edit script by searching the edit graph one edit
+// at a time, keeping the furthest point reached on each diagonal, then walking
+// back through the rounds
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	// v[k+offset] is the furthest x reached on diagonal k = x-y. trace[d] is v
+	// after round d, holding only diagonals -d..d.
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset] // down: insert b[y-1]
+			} else {
+				x = v[k-1+offset] + 1 // right: delete a[x-1]
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+offset] = x
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			if x := v[k+offset]; x >= n && x-k >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk back from the end, collecting ops in reverse
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1] // diagonals -(d-1)..d-1
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{'+', b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{'-', a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{' ', a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// groupDeletionsFirst reorders each run of changes so its deletions come before
+// its insertions, the way unified diffs show a replaced block
+func groupDeletionsFirst(ops []diffOp) []diffOp {
+	result := make([]diffOp, 0, len(ops))
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			result = append(result, ops[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].kind != ' ' {
+			j++
+		}
+		for _, kind := range []byte{'-', '+'} {
+			for _, op := range ops[i:j] {
+				if op.kind == kind {
+					result = append(result, op)
+				}
+			}
+		}
+		i = j
+	}
+	return result
+}
+
+// formatDiffLine renders an op without its line ending, marking a last line
+// that has none as git does
+func formatDiffLine(op diffOp) string {
+	line, ok := strings.CutSuffix(op.line, "\n")
+	if !ok {
+		return string(op.kind) + line + "\n\\ No newline at end of file"
+	}
+	return string(op.kind) + line
+}
+
+// formatHunk formats a single hunk with header. As in git, a count of one is
+// left out and an empty side starts at the line before it.
 func formatHunk(fromStart, fromCount, toStart, toCount int, lines []string) string {
 	var result strings.Builder
-	result.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", fromStart, fromCount, toStart, toCount))
+	result.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(fromStart, fromCount), hunkRange(toStart, toCount)))
 	for _, line := range lines {
 		result.WriteString(line + "\n")
 	}
 	return result.String()
 }
 
-// max returns the maximum of two integers
-func max(a, b int) int {
-	if a > b {
-		return a
+// hunkRange formats the lines after start (zero-based) of a hunk side
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
 	}
-	return b
 }
 
-// splitLines splits content into lines
+// splitLines splits content into lines, each keeping its line ending so a
+// missing newline at the end of the file shows in the diff
 func splitLines(content string) []string {
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
 	}
 	return lines
 }
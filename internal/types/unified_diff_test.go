@@ -0,0 +1,161 @@
+package types
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The expected hunks are what git diff prints for the same contents
+func TestGenerateUnifiedDiff_MatchesGit(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "insertion",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			to:   "1\n2\n3\n4\n5\nnew\n6\n7\n8\n9\n10\n",
+			want: "@@ -3,6 +3,7 @@\n 3\n 4\n 5\n+new\n 6\n 7\n 8\n",
+		},
+		{
+			name: "deletion",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			to:   "1\n2\n5\n6\n7\n8\n9\n10\n",
+			want: "@@ -1,7 +1,5 @@\n 1\n 2\n-3\n-4\n 5\n 6\n 7\n",
+		},
+		{
+			name: "interleaved changes share a hunk",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			to:   "1\ntwo\n3\n4\n6\n7\nseven and a half\n8\n9\n10\n",
+			want: "@@ -1,10 +1,10 @@\n 1\n-2\n+two\n 3\n 4\n-5\n 6\n 7\n+seven and a half\n 8\n 9\n 10\n",
+		},
+		{
+			name: "distant changes get separate hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n",
+			to:   "1\ntwo\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\neighteen\n19\n20\n",
+			want: "@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -15,6 +15,6 @@\n 15\n 16\n 17\n-18\n+eighteen\n 19\n 20\n",
+		},
+		{
+			name: "single line",
+			from: "old\n",
+			to:   "new\n",
+			want: "@@ -1 +1 @@\n-old\n+new\n",
+		},
+		{
+			name: "empty file",
+			from: "",
+			to:   "1\n2\n",
+			want: "@@ -0,0 +1,2 @@\n+1\n+2\n",
+		},
+		{
+			name: "missing newline at end of file",
+			from: "1\n2\n3",
+			to:   "1\n2\n3\n4\n",
+			want: "@@ -1,3 +1,4 @@\n 1\n 2\n-3\n\\ No newline at end of file\n+3\n+4\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := "diff --git a/SOUL.md b/SOUL.md\n--- a/SOUL.md\n+++ b/SOUL.md\n"
+			got := generateUnifiedDiff(tt.from, tt.to, "SOUL.md")
+			if got != header+tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, header+tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLines_FindsShortestEditScript(t *testing.T) {
+	// Matching line by line would replace every line after the first
+	from := strings.Split("a b c a b b a", " ")
+	to := strings.Split("c b a b a c", " ")
+
+	changes := 0
+	for _, op := range diffLines(from, to) {
+		if op.kind != ' ' {
+			changes++
+		}
+	}
+	if changes != 5 {
+		t.Errorf("expected 5 changed lines, got %d", changes)
+	}
+}
+
+// countingReader serves fixed contents by snapshot ID and counts reads
+type countingReader struct {
+	contents map[string]string
+	reads    int
+}
+
+func (r *countingReader) ReadSnapshotFile(snapshotID, path string) ([]byte, error) {
+	r.reads++
+	content, ok := r.contents[snapshotID]
+	if !ok {
+		return nil, fmt.Errorf("no content for %s", snapshotID)
+	}
+	return []byte(content), nil
+}
+
+func TestPrintUnifiedWithReaders_ReusesDiffOfSameContents(t *testing.T) {
+	clear(contentDiffCache)
+	t.Cleanup(func() { clear(contentDiffCache) })
+
+	good := &Snapshot{ID: "good", Files: map[string]*FileSnapshot{
+		"SOUL.md": {Path: "SOUL.md", Hash: "aaaa", Size: 4},
+	}}
+	reader := &countingReader{contents: map[string]string{"good": "old\n"}}
+	var bad []*Snapshot
+	for _, id := range []string{"bad-1", "bad-2"} {
+		bad = append(bad, &Snapshot{ID: id, Files: map[string]*FileSnapshot{
+			"SOUL.md": {Path: "SOUL.md", Hash: "bbbb", Size: 4},
+		}})
+		reader.contents[id] = "new\n"
+	}
+
+	var outputs []string
+	for _, snapshot := range bad {
+		diff := good.Diff(snapshot)
+		outputs = append(outputs, captureDiffOutput(t, func() {
+			diff.PrintUnifiedWithReaders(reader, reader, good, snapshot)
+		}))
+	}
+
+	want := "diff --git a/SOUL.md b/SOUL.md\n--- a/SOUL.md\n+++ b/SOUL.md\n@@ -1 +1 @@\n-old\n+new\n"
+	for i, output := range outputs {
+		if output != want {
+			t.Errorf("diff %d: got:\n%s\nwant:\n%s", i, output, want)
+		}
+	}
+	if reader.reads != 2 {
+		t.Errorf("expected the contents to be read once, got %d reads", reader.reads)
+	}
+}
+
+func captureDiffOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
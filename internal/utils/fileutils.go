@@ -141,6 +141,46 @@ func CopyFile(src, dst string) error {
 	return nil
 }
 
+// MatchPathCase makes the on-disk spelling of rel under root match rel exactly.
+// On case-insensitive filesystems, writing "Skill.md" over an existing
+// "skill.md" keeps the old name, and the same goes for directories. Each path
+// element whose name differs only in case is renamed through a temporary name,
+// since renaming straight to the new case is a no-op on some filesystems. Names
+// that already exist exactly are left alone, so after a copy this does nothing
+// on case-sensitive filesystems.
+func MatchPathCase(root, rel string) error {
+	dir := root
+	for _, name := range strings.Split(filepath.Clean(rel), string(filepath.Separator)) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("failed to read directory: %w", err)
+		}
+
+		current := ""
+		for _, entry := range entries {
+			if entry.Name() == name {
+				current = ""
+				break
+			}
+			if current == "" && strings.EqualFold(entry.Name(), name) {
+				current = entry.Name()
+			}
+		}
+
+		if current != "" {
+			temp := filepath.Join(dir, current+".bulletproof-rename")
+			if err := os.Rename(filepath.Join(dir, current), temp); err != nil {
+				return fmt.Errorf("failed to rename %s: %w", current, err)
+			}
+			if err := os.Rename(temp, filepath.Join(dir, name)); err != nil {
+				return fmt.Errorf("failed to rename %s to %s: %w", current, name, err)
+			}
+		}
+		dir = filepath.Join(dir, name)
+	}
+	return nil
+}
+
 // DirectorySize calculates the total size of all files in a directory
 func DirectorySize(path string) (int64, error) {
 	var size int64
@@ -242,3 +242,53 @@ func TestFormatSize(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchPathCase(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "Skills", "Weather"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "Skills", "Weather", "SKILL.md"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MatchPathCase(root, filepath.Join("skills", "weather", "SKILL.md")); err != nil {
+		t.Fatalf("MatchPathCase() failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Name() != "skills" {
+		t.Errorf("directory name = %q, want %q", entries[0].Name(), "skills")
+	}
+	if _, err := os.Stat(filepath.Join(root, "skills", "weather", "SKILL.md")); err != nil {
+		t.Errorf("file should be reachable under the new spelling: %v", err)
+	}
+}
+
+func TestMatchPathCase_ExactNameKept(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "notes.md"), []byte("lower"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// On case-sensitive filesystems this is a separate file that must survive
+	if err := os.WriteFile(filepath.Join(root, "NOTES.md"), []byte("upper"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MatchPathCase(root, "NOTES.md"); err != nil {
+		t.Fatalf("MatchPathCase() failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "NOTES.md"))
+	if err != nil {
+		t.Fatalf("NOTES.md should still exist: %v", err)
+	}
+	if string(data) != "upper" {
+		t.Errorf("NOTES.md = %q, want %q", data, "upper")
+	}
+}
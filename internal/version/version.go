@@ -3,10 +3,19 @@ package version
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 )
 
+// NoUpdateCheckEnv disables all update checks when set to anything but "", "0", or "false"
+const NoUpdateCheckEnv = "BULLETPROOF_NO_UPDATE_CHECK"
+
+// releasesURL is the only endpoint the update check contacts
+const releasesURL = "https://api.github.com/repos/bulletproof-bot/backup/releases/latest"
+
 // Version information - set by build flags
 var (
 	Version   = "dev"
@@ -33,11 +42,17 @@ func CheckForUpdate() (latestVersion, downloadURL string, err error) {
 		return "", "", nil
 	}
 
+	// Fail fast when offline or airgapped instead of waiting for the full timeout
 	client := &http.Client{
 		Timeout: 3 * time.Second,
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         (&net.Dialer{Timeout: time.Second}).DialContext,
+			TLSHandshakeTimeout: time.Second,
+		},
 	}
 
-	req, err := http.NewRequest("GET", "https://api.github.com/repos/bulletproof-bot/backup/releases/latest", nil)
+	req, err := http.NewRequest("GET", releasesURL, nil)
 	if err != nil {
 		return "", "", err
 	}
@@ -69,16 +84,46 @@ func CheckForUpdate() (latestVersion, downloadURL string, err error) {
 	return "", "", nil
 }
 
-// PrintUpdateNotice prints an update notice if a newer version is available
-func PrintUpdateNotice() {
-	latestVersion, downloadURL, err := CheckForUpdate()
-	if err != nil {
-		// Silently ignore errors - don't interrupt user workflow
+// UpdateCheckDisabledByEnv reports whether NoUpdateCheckEnv turns update checks off
+func UpdateCheckDisabledByEnv() bool {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv(NoUpdateCheckEnv)))
+	return value != "" && value != "0" && value != "false"
+}
+
+// UpdateCheck is an update check running in the background
+type UpdateCheck struct {
+	done          chan struct{}
+	latestVersion string
+	downloadURL   string
+}
+
+// StartUpdateCheck begins checking for a newer release without blocking
+func StartUpdateCheck() *UpdateCheck {
+	check := &UpdateCheck{done: make(chan struct{})}
+	go func() {
+		defer close(check.done)
+		latestVersion, downloadURL, err := CheckForUpdate()
+		if err != nil {
+			// Silently ignore errors - don't interrupt user workflow
+			return
+		}
+		check.latestVersion = latestVersion
+		check.downloadURL = downloadURL
+	}()
+	return check
+}
+
+// PrintNotice waits up to wait for the check to finish and prints a notice if a
+// newer version is available. A check still running after wait is abandoned.
+func (u *UpdateCheck) PrintNotice(wait time.Duration) {
+	select {
+	case <-u.done:
+	case <-time.After(wait):
 		return
 	}
 
-	if latestVersion != "" {
-		fmt.Printf("\n💡 New version available: %s\n", latestVersion)
-		fmt.Printf("   Download: %s\n\n", downloadURL)
+	if u.latestVersion != "" {
+		fmt.Printf("\n💡 New version available: %s\n", u.latestVersion)
+		fmt.Printf("   Download: %s\n\n", u.downloadURL)
 	}
 }